@@ -291,11 +291,11 @@ const Workloads = () => {
                                 )}
 
                                 {/* Metadata */}
-                                {selectedJob.metadata && (
+                                {selectedJob.metadata && Object.keys(selectedJob.metadata).length > 0 && (
                                     <div>
                                         <p className="text-xs text-gray-500">Metadata</p>
                                         <pre className="mt-1 p-3 bg-karbos-navy rounded text-xs text-gray-300 overflow-x-auto">
-                                            {JSON.stringify(JSON.parse(selectedJob.metadata), null, 2)}
+                                            {JSON.stringify(selectedJob.metadata, null, 2)}
                                         </pre>
                                     </div>
                                 )}
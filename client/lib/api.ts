@@ -1,13 +1,27 @@
 import axios from 'axios';
 import type {
   Job,
-  ExecutionLog,
+  JobListResponse,
+  ExecutionLogPage,
   CarbonCacheEntry,
   SubmitJobRequest,
   SubmitJobResponse,
+  BatchSubmitResponse,
+  CancelJobResponse,
+  DeleteJobResponse,
+  CreateRecurringJobRequest,
+  RecurringJob,
+  RecurringJobListResponse,
   HealthResponse,
   CarbonForecastResponse,
-  SystemHealthResponse
+  CarbonSeriesResponse,
+  CircuitBreakerStats,
+  SystemHealthResponse,
+  QueueInspectResponse,
+  SLOReport,
+  StatsResponse,
+  SimulateJob,
+  SimulateScheduleResponse
 } from './types';
 
 // Configure axios instance
@@ -29,8 +43,16 @@ export const apiClient = {
 
   // Jobs
   getJobs: async (): Promise<Job[]> => {
-    const { data } = await api.get('/api/jobs');
-    return data;
+    const { data } = await api.get<JobListResponse>('/api/jobs');
+    return data.jobs;
+  },
+
+  // Jobs carrying every one of labels
+  getJobsByLabel: async (labels: Record<string, string>): Promise<Job[]> => {
+    const params = new URLSearchParams();
+    Object.entries(labels).forEach(([key, value]) => params.append('label', `${key}:${value}`));
+    const { data } = await api.get<JobListResponse>('/api/jobs', { params });
+    return data.jobs;
   },
 
   getJob: async (jobId: string): Promise<Job> => {
@@ -44,9 +66,42 @@ export const apiClient = {
     return data;
   },
 
+  // Up to 100 jobs; each job's outcome is reported separately
+  submitJobs: async (requests: SubmitJobRequest[]): Promise<BatchSubmitResponse> => {
+    const { data } = await api.post('/api/submit/batch', requests);
+    return data;
+  },
+
+  cancelJob: async (jobId: string): Promise<CancelJobResponse> => {
+    const { data } = await api.post(`/api/jobs/${jobId}/cancel`);
+    return data;
+  },
+
+  // Soft-delete a finished job (unfinished jobs must be cancelled first)
+  deleteJob: async (jobId: string): Promise<DeleteJobResponse> => {
+    const { data } = await api.delete(`/api/jobs/${jobId}`);
+    return data;
+  },
+
+  // Recurring Jobs
+  createRecurringJob: async (request: CreateRecurringJobRequest): Promise<RecurringJob> => {
+    const { data } = await api.post('/api/recurring', request);
+    return data;
+  },
+
+  getRecurringJobs: async (userId?: string): Promise<RecurringJobListResponse> => {
+    const params = userId ? { user_id: userId } : {};
+    const { data } = await api.get('/api/recurring', { params });
+    return data;
+  },
+
+  deleteRecurringJob: async (id: string): Promise<void> => {
+    await api.delete(`/api/recurring/${id}`);
+  },
+
   // Execution Logs
-  getExecutionLog: async (jobId: string): Promise<ExecutionLog> => {
-    const { data } = await api.get(`/api/jobs/${jobId}/logs`);
+  getExecutionLogs: async (jobId: string, limit: number = 50, offset: number = 0): Promise<ExecutionLogPage> => {
+    const { data } = await api.get(`/api/jobs/${jobId}/logs`, { params: { limit, offset } });
     return data;
   },
 
@@ -57,6 +112,24 @@ export const apiClient = {
     return data;
   },
 
+  // Hourly intensity series for one region, up to 168 hours ahead
+  getCarbonSeries: async (region: string, hours = 24): Promise<CarbonSeriesResponse> => {
+    const { data } = await api.get(`/api/carbon/${encodeURIComponent(region)}/series`, { params: { hours } });
+    return data;
+  },
+
+  // Schedule simulation (nothing is saved or queued)
+  simulateSchedule: async (jobs: SimulateJob[]): Promise<SimulateScheduleResponse> => {
+    const { data } = await api.post('/api/schedule/simulate', { jobs });
+    return data;
+  },
+
+  // Carbon API circuit breaker state (resetting it needs the admin token)
+  getCarbonCircuit: async (): Promise<CircuitBreakerStats> => {
+    const { data } = await api.get('/api/carbon/circuit');
+    return data;
+  },
+
   // Carbon Cache (all entries)
   getCarbonCache: async (): Promise<CarbonCacheEntry[]> => {
     const { data } = await api.get('/api/carbon-cache');
@@ -71,6 +144,29 @@ export const apiClient = {
     return data;
   },
 
+  // Aggregate dashboard stats
+  getStats: async (): Promise<StatsResponse> => {
+    const { data } = await api.get('/api/stats');
+    return data;
+  },
+
+  // Start-time SLO compliance (window: a Go duration such as '168h')
+  getSLOReport: async (window?: string): Promise<SLOReport> => {
+    const params = window ? { window } : {};
+    const { data } = await api.get('/api/stats/slo', { params });
+    return data;
+  },
+
+  // Upcoming delayed jobs (needs the admin token)
+  inspectQueue: async (adminToken: string, limit?: number): Promise<QueueInspectResponse> => {
+    const params = limit ? { limit } : {};
+    const { data } = await api.get('/api/admin/queue/inspect', {
+      params,
+      headers: { Authorization: `Bearer ${adminToken}` },
+    });
+    return data;
+  },
+
   // System Health
   getSystemHealth: async (): Promise<SystemHealthResponse> => {
     const { data } = await api.get('/api/system/health');
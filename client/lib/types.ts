@@ -1,6 +1,6 @@
 // API Response Types matching backend models
 
-export type JobStatus = 'PENDING' | 'DELAYED' | 'RUNNING' | 'COMPLETED' | 'FAILED';
+export type JobStatus = 'PENDING' | 'DELAYED' | 'RUNNING' | 'COMPLETED' | 'FAILED' | 'CANCELLED' | 'WAITING';
 
 export interface Job {
   id: string;
@@ -15,7 +15,30 @@ export interface Job {
   deadline: string;
   estimated_duration?: number; // seconds
   region?: string;
-  metadata?: string;
+  metadata: JobMetadata;
+  carbon_opt_out: boolean; // Submitted with carbon_aware=false
+  request_id?: string; // X-Request-ID of the submission, found on its log lines
+  start_delay_seconds?: number; // How late the first run started after scheduled_time
+  slo_met?: boolean; // Whether that start met the start-time SLO
+  depends_on?: string[]; // Jobs that must complete before this one is queued
+  labels?: Record<string, string>; // Tags set at submission (only on GET /api/jobs/:id)
+  deleted: boolean; // Soft-deleted: hidden from listings, still fetchable by ID
+  deleted_at?: string;
+}
+
+// Extra keys sent at submission are returned unchanged
+export interface JobMetadata {
+  tags?: string[];
+  source?: string; // What submitted the job, e.g. 'ci'
+  notes?: string;
+  request_id?: string; // Caller's own ID for the submission
+  [key: string]: unknown;
+}
+
+export interface JobListResponse {
+  count: number;
+  jobs: Job[];
+  next_cursor: string; // Pass as ?cursor= for the next page; empty on the last page
 }
 
 export interface ExecutionLog {
@@ -29,6 +52,25 @@ export interface ExecutionLog {
   completed_at?: string;
   worker_node_id?: string;
   created_at: string;
+  peak_memory_bytes?: number; // Absent when the container exited before stats were sampled
+  cpu_seconds?: number;
+  output_truncated: boolean; // Output went over the worker's DOCKER_MAX_OUTPUT_BYTES and was cut short
+  steps?: StepRecord[]; // Multi-step jobs only, in run order
+}
+
+export interface StepRecord {
+  name: string;
+  duration_ms: number;
+  error?: string; // Set on the step that failed or timed out
+}
+
+export interface ExecutionLogPage {
+  job_id: string;
+  logs: ExecutionLog[]; // In attempt order
+  count: number;
+  total: number; // Attempts recorded for the job
+  limit: number;
+  offset: number;
 }
 
 export interface CarbonCacheEntry {
@@ -45,9 +87,42 @@ export interface SubmitJobRequest {
   user_id: string;
   docker_image: string;
   command?: string[];
+  entrypoint?: string[]; // Replaces the image's ENTRYPOINT; command becomes its arguments
+  steps?: string[][]; // Commands run in order in the job's image, instead of command
   deadline: string; // ISO 8601
-  estimated_duration?: number; // seconds
+  estimated_duration?: number; // seconds; defaults to the image's average completed run time, or 10 minutes
   region?: string;
+  carbon_aware?: boolean; // false skips carbon-aware scheduling and runs now (default true)
+  max_intensity?: number; // gCO2eq/kWh below which the job runs now (default 400, max 2000)
+  volumes?: VolumeMount[]; // Must be on the server's DOCKER_VOLUME_ALLOWLIST
+  idempotency_key?: string; // Retries with the same key return the original job (24h)
+  depends_on?: string[]; // Job IDs that must complete first; the job is WAITING until then
+  network_access?: boolean; // Run with network access; containers have none by default
+  labels?: Record<string, string>; // Up to 20 tags, e.g. { team: 'ml' }; keys may not contain ':'
+  metadata?: JobMetadata; // Up to 32 tags and 16 KB in total
+}
+
+export interface CreateRecurringJobRequest extends Omit<SubmitJobRequest, 'deadline' | 'idempotency_key'> {
+  schedule: string; // Cron expression ('0 2 * * *') or descriptor ('@hourly')
+  timezone?: string; // IANA zone, default UTC
+}
+
+export interface RecurringJob {
+  id: string;
+  user_id: string;
+  cron_expression: string;
+  timezone: string;
+  job_spec: Omit<SubmitJobRequest, 'deadline'>; // Submitted on every run; the deadline is the following run
+  next_run_at: string;
+  last_run_at?: string;
+  last_job_id?: string; // Job submitted by the last run
+  last_error?: string; // Why the last run's submission failed
+  created_at: string;
+}
+
+export interface RecurringJobListResponse {
+  count: number;
+  recurring_jobs: RecurringJob[];
 }
 
 export interface SubmitJobResponse {
@@ -58,6 +133,55 @@ export interface SubmitJobResponse {
   immediate: boolean;
   expected_intensity?: number;
   carbon_savings?: number;
+  carbon_aware: boolean;
+  deferral_capped: boolean; // Start kept within the server's max deferral of a more distant deadline
+  alternative_windows: ScheduleWindow[]; // Other windows the job could have run in
+  estimate?: ExecutionEstimate; // Dry runs only
+  outside_acceptance_window?: boolean; // Dry runs only: a real submission would be refused now
+  next_acceptance_window?: string; // Dry runs only: when submissions are next accepted
+}
+
+// Predicted cost of running a dry-run job; every field is an estimate
+export interface ExecutionEstimate {
+  estimated_duration_seconds: number;
+  duration_source: 'request' | 'history' | 'default';
+  memory_limit_mb: number; // 0 = unlimited
+  cpu_quota: number; // 100000 = one CPU; 0 = unlimited
+  timeout_seconds: number;
+  assumed_power_watts: number;
+  projected_co2_grams: number | null; // null without a gCO2eq/kWh forecast
+}
+
+export interface BatchSubmitResult {
+  index: number; // Position of the job in the submitted array
+  job?: SubmitJobResponse;
+  error?: { error: string; message: string; code: number };
+}
+
+export interface BatchSubmitResponse {
+  results: BatchSubmitResult[];
+  submitted: number;
+  failed: number;
+}
+
+export interface ScheduleWindow {
+  start_time: string;
+  end_time: string;
+  avg_intensity: number;
+  carbon_cost?: number;
+  chosen: boolean;
+}
+
+export interface CancelJobResponse {
+  job_id: string;
+  status: JobStatus; // CANCELLED, or RUNNING while the worker stops the container
+  message: string;
+}
+
+export interface DeleteJobResponse {
+  job_id: string;
+  deleted_at: string;
+  message: string;
 }
 
 export interface HealthResponse {
@@ -80,6 +204,100 @@ export interface CarbonForecastResponse {
   optimal_time?: string;
 }
 
+export interface GreenestWindow {
+  region: string;
+  start_time: string;
+  end_time: string;
+  avg_intensity: number;
+  intensity_scale: 'absolute' | 'relative';
+  computed_at: string; // when the forecast behind this window was fetched
+}
+
+export interface CarbonRecommendationResponse {
+  recommendations: GreenestWindow[];
+}
+
+export interface CarbonSeriesPoint {
+  timestamp: string;
+  intensity: number;
+  unit: string;
+  source: string;
+}
+
+export interface CarbonSeriesResponse {
+  region: string;
+  hours: number;
+  points: CarbonSeriesPoint[];
+  summary?: { min: number; max: number; avg: number }; // Absent when there are no points
+  source: 'cache' | 'live';
+}
+
+export interface VolumeMount {
+  source: string; // Absolute host path or named Docker volume
+  target: string; // Absolute path inside the container
+  read_only?: boolean;
+}
+
+export interface SimulateJob {
+  docker_image: string;
+  region?: string;
+  estimated_duration?: number; // seconds
+  deadline: string; // ISO 8601
+}
+
+export interface SimulatedJob {
+  docker_image: string;
+  region: string;
+  execution_plan?: 'immediate' | 'scheduled' | 'waiting';
+  scheduled_time?: string;
+  immediate: boolean;
+  current_intensity: number;
+  expected_intensity: number;
+  carbon_savings: number;
+  intensity_scale?: string;
+  deferral_capped: boolean; // Start kept within the server's max deferral of a more distant deadline
+  error?: string; // Set when the job couldn't be scheduled
+}
+
+export interface SimulateScheduleResponse {
+  jobs: SimulatedJob[]; // In request order
+  immediate: number;
+  scheduled: number;
+  failed: number;
+  total_carbon_savings: number; // gCO2eq/kWh; relative-index jobs are left out
+}
+
+export interface StatsResponse {
+  total_jobs: number;
+  pending: number;
+  delayed: number;
+  running: number;
+  completed: number;
+  failed: number;
+  cancelled: number;
+  waiting: number;
+  total_co2_saved_grams: number;
+  avg_duration_seconds: number; // Mean run time of completed jobs
+  carbon_cache_entries: number;
+  active_workers: number;
+  queue_depth_immediate: number;
+  queue_depth_delayed: number;
+  queue_depth_dead: number;
+  timestamp: string;
+}
+
+export interface SLOReport {
+  start_threshold_seconds: number;
+  target: number; // Fraction of jobs that must start on time
+  window_seconds: number;
+  jobs: number; // Jobs due in the window that have started
+  on_time: number;
+  late: number;
+  compliance: number; // on_time / jobs; 1 when no job started
+  met: boolean;
+  budget_left: number; // Share of the error budget left; negative once overspent
+}
+
 export interface SystemHealthResponse {
   active_workers: number;
   worker_ids: string[];
@@ -88,3 +306,33 @@ export interface SystemHealthResponse {
   redis_latency_ms: number;
   timestamp: string;
 }
+
+export interface QueuedJob {
+  job_id: string;
+  docker_image: string;
+  region?: string;
+  priority: number;
+  scheduled_time: string; // ISO 8601
+  due: boolean; // Scheduled time has passed; the promoter will move it to the immediate queue
+}
+
+export interface QueueInspectResponse {
+  items: QueuedJob[]; // Soonest scheduled first
+  count: number;
+  total: number; // All jobs in the delayed queue
+}
+
+export interface CircuitBreakerStats {
+  state: 'CLOSED' | 'OPEN' | 'HALF_OPEN';
+  failures: number;
+  max_failures: number;
+  last_fail_time: string;
+  last_state_change: string;
+  time_since_last_fail: string; // Go duration; empty if the carbon API never failed
+  timeout: string;
+  open_timeout: string; // Length of the current open period (timeout, or the API's Retry-After)
+  static_fallback: number; // gCO2eq/kWh used while the circuit is open
+  static_region: string;
+  success_count: number; // Successful probes so far while HALF_OPEN
+  success_threshold: number; // Successful probes needed to close
+}
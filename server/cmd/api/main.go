@@ -6,18 +6,28 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/acceptance"
 	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
 	"github.com/Sambit-Mondal/karbos/server/internal/config"
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
 	"github.com/Sambit-Mondal/karbos/server/internal/handlers"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 	"github.com/Sambit-Mondal/karbos/server/internal/metrics"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/recurring"
 	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/Sambit-Mondal/karbos/server/internal/version"
 	"github.com/Sambit-Mondal/karbos/server/internal/worker"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -31,7 +41,13 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	log.Println("🚀 Starting Karbos Server...")
+	// Structured logging; the log package's output goes through the same handler
+	if _, err := logging.Setup(cfg.Log.Format, cfg.Log.Level, cfg.Server.Environment); err != nil {
+		log.Fatalf("Invalid logging config: %v", err)
+	}
+
+	build := version.Get()
+	log.Printf("🚀 Starting Karbos Server %s (commit %s, built %s)...", build.Version, build.Commit, build.BuildTime)
 	log.Printf("Environment: %s", cfg.Server.Environment)
 
 	// Initialize database
@@ -48,6 +64,7 @@ func main() {
 		cfg.Redis.DB,
 		cfg.Queue.ImmediateQueueKey,
 		cfg.Queue.DelayedSetKey,
+		cfg.Queue.DeadLetterKey,
 	)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
@@ -60,12 +77,51 @@ func main() {
 
 	// Initialize carbon service
 	var carbonService carbon.CarbonService
+	var circuitBreaker *carbon.CircuitBreaker
+	carbonProvider := "none" // Reported by /api/version
 	cacheTTL, _ := time.ParseDuration(cfg.Carbon.CacheTTL)
 	if cacheTTL == 0 {
 		cacheTTL = 1 * time.Hour
 	}
 
-	if cfg.Carbon.Provider == "watttime" && cfg.Carbon.APIUsername != "" {
+	if chain := splitRegions(cfg.Carbon.Providers); len(chain) > 0 {
+		// Each API provider gets its own breaker that fails fast while open, so the
+		// chain moves straight on to the next one. The outer breaker only opens once
+		// every provider is failing, and then serves the static fallback.
+		providers := make([]carbon.NamedCarbonService, 0, len(chain))
+		providerTimeout, _ := time.ParseDuration(cfg.CircuitBreaker.Timeout)
+		for i, name := range chain {
+			baseURL := "" // CARBON_API_URL only applies to the primary
+			if i == 0 {
+				baseURL = cfg.Carbon.BaseURL
+			}
+			service, err := newCarbonProvider(name, baseURL, cfg)
+			if err != nil {
+				log.Fatalf("Invalid CARBON_PROVIDERS: %v", err)
+			}
+			if name != "csv" {
+				service = carbon.NewCircuitBreaker(service, carbon.CircuitBreakerConfig{
+					MaxFailures:      cfg.CircuitBreaker.MaxFailures,
+					Timeout:          providerTimeout,
+					SuccessThreshold: cfg.CircuitBreaker.SuccessThreshold,
+					NoStaticFallback: true,
+				})
+			}
+			providers = append(providers, carbon.NamedCarbonService{Name: name, Service: service})
+		}
+		log.Printf("✓ Using carbon provider chain: %s", strings.Join(chain, " → "))
+		circuitBreaker = wrapWithCircuitBreaker(carbon.NewFallbackCarbonService(providers...), cfg)
+		carbonService = circuitBreaker
+		carbonProvider = strings.Join(chain, ",")
+	} else if cfg.Carbon.Provider == "csv" {
+		csvClient, err := carbon.NewCSVCarbonClient(cfg.Carbon.CSVPath)
+		if err != nil {
+			log.Fatalf("Failed to load CSV carbon data: %v", err)
+		}
+		log.Printf("✓ Using CSV carbon data from %s", cfg.Carbon.CSVPath)
+		carbonService = csvClient
+		carbonProvider = "csv"
+	} else if cfg.Carbon.Provider == "watttime" && cfg.Carbon.APIUsername != "" {
 		log.Println("✓ Using WattTime carbon service")
 		wattTimeClient := carbon.NewWattTimeClient(
 			cfg.Carbon.APIUsername,
@@ -73,7 +129,9 @@ func main() {
 			cfg.Carbon.BaseURL,
 		)
 		// Wrap with circuit breaker
-		carbonService = wrapWithCircuitBreaker(wattTimeClient, cfg)
+		circuitBreaker = wrapWithCircuitBreaker(wattTimeClient, cfg)
+		carbonService = circuitBreaker
+		carbonProvider = "watttime"
 	} else if cfg.Carbon.APIKey != "" {
 		log.Println("✓ Using ElectricityMaps carbon service")
 		emClient := carbon.NewElectricityMapsClient(
@@ -81,7 +139,9 @@ func main() {
 			cfg.Carbon.BaseURL,
 		)
 		// Wrap with circuit breaker
-		carbonService = wrapWithCircuitBreaker(emClient, cfg)
+		circuitBreaker = wrapWithCircuitBreaker(emClient, cfg)
+		carbonService = circuitBreaker
+		carbonProvider = "electricitymaps"
 	} else {
 		log.Println("⚠ No carbon API configured, scheduling will use default behavior")
 	}
@@ -93,7 +153,25 @@ func main() {
 	if carbonService != nil {
 		cacheWrapper := carbon.NewDatabaseCacheWrapper(carbonCacheRepo)
 		carbonFetcher = carbon.NewCarbonFetcher(carbonService, cacheWrapper, cacheTTL)
+		if cfg.Carbon.CacheFailFastAfter > 0 {
+			carbonFetcher.SetCacheFailFast(cfg.Carbon.CacheFailFastAfter)
+			log.Printf("✓ Carbon cache fail-fast enabled (after %d consecutive errors)", cfg.Carbon.CacheFailFastAfter)
+		}
 		carbonScheduler = scheduler.NewCarbonScheduler(carbonFetcher)
+		if cfg.Carbon.SlotCap > 0 {
+			carbonScheduler.SetSlotCap(cfg.Carbon.SlotCap, redisQueue)
+			log.Printf("✓ Region slot cap enabled (%d jobs per region per slot)", cfg.Carbon.SlotCap)
+		}
+		if cfg.Scheduler.MaxDeferral != "" {
+			maxDeferral, err := time.ParseDuration(cfg.Scheduler.MaxDeferral)
+			if err != nil || maxDeferral < 0 {
+				log.Fatalf("Invalid SCHEDULER_MAX_DEFERRAL %q", cfg.Scheduler.MaxDeferral)
+			}
+			carbonScheduler.SetMaxDeferral(maxDeferral)
+			if maxDeferral > 0 {
+				log.Printf("✓ Jobs start within %s of submission", maxDeferral)
+			}
+		}
 		log.Println("✓ Carbon-aware scheduling enabled")
 	}
 
@@ -103,28 +181,152 @@ func main() {
 		promoterCheckInterval = 10 * time.Second
 	}
 	promoterService := worker.NewPromoterService(redisQueue, promoterCheckInterval)
+	promoterService.SetJobStore(jobRepo)
+	executionRepo := database.NewExecutionLogRepository(db.DB)
+	dependencyResolver := worker.NewDependencyResolver(jobRepo, redisQueue, executionRepo)
+	promoterService.SetExecutionLogs(executionRepo)
+	promoterService.SetDependencyResolver(dependencyResolver)
+
+	// Windows during which jobs are accepted and promoted (always, unless configured)
+	submissionWindows, promotionWindows := loadAcceptanceWindows(cfg.Acceptance)
+	promoterService.SetPromotionWindows(promotionWindows)
+
+	// Root context for background work and scheduling calls, cancelled on SIGINT/SIGTERM
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Log Redis outages and recoveries as they happen
+	go redisQueue.MonitorConnection(ctx, redisMonitorInterval)
 
 	// Start promoter service
-	ctx := context.Background()
 	if err := promoterService.Start(ctx); err != nil {
 		log.Fatalf("Failed to start promoter service: %v", err)
 	}
-	defer promoterService.Stop()
+
+	// Keep forecasts for the configured regions warm in the cache, along with
+	// each region's greenest upcoming window for /api/carbon/recommend
+	var greenestWindows *carbon.GreenestWindows
+	if prefetchRegions := splitRegions(cfg.Carbon.PrefetchRegions); carbonFetcher != nil && len(prefetchRegions) > 0 {
+		prefetchInterval, _ := time.ParseDuration(cfg.Carbon.PrefetchInterval)
+		prefetcher := carbon.NewPrefetcher(carbonFetcher, prefetchRegions, prefetchInterval)
+		prefetcher.SetConcurrency(cfg.Carbon.PrefetchConcurrency)
+		prefetcher.SetCircuitBreaker(circuitBreaker)
+		greenestWindows = carbon.NewGreenestWindows(time.Hour)
+		prefetcher.SetGreenestWindows(greenestWindows)
+		if cfg.Carbon.PrefetchActiveWithin != "" {
+			activeWithin, err := time.ParseDuration(cfg.Carbon.PrefetchActiveWithin)
+			if err != nil {
+				log.Fatalf("Invalid CARBON_PREFETCH_ACTIVE_WITHIN: %v", err)
+			}
+			prefetcher.SetRegionActivity(jobRepo, activeWithin)
+			log.Printf("Prefetching only regions with jobs in the last %v", activeWithin)
+		}
+
+		prefetchCtx, stopPrefetch := context.WithCancel(ctx)
+		defer stopPrefetch()
+		go prefetcher.Run(prefetchCtx)
+		log.Printf("✓ Carbon forecast prefetcher started (%d regions, concurrency %d)", len(prefetchRegions), cfg.Carbon.PrefetchConcurrency)
+	}
+
+	// Delete cache entries once they are too old to matter for scheduling
+	if cleanupInterval, err := time.ParseDuration(cfg.Carbon.CacheCleanupInterval); err != nil {
+		log.Fatalf("Invalid CARBON_CACHE_CLEANUP_INTERVAL: %v", err)
+	} else if cleanupInterval > 0 {
+		maxAge, err := time.ParseDuration(cfg.Carbon.CacheMaxAge)
+		if err != nil {
+			log.Fatalf("Invalid CARBON_CACHE_MAX_AGE: %v", err)
+		}
+		go carbon.NewCacheJanitor(carbonCacheRepo, cleanupInterval, maxAge).Run(ctx)
+		log.Printf("✓ Carbon cache cleanup started (every %v, max age %v)", cleanupInterval, maxAge)
+	}
+
+	// Hide old finished jobs from listings
+	if cfg.Server.JobArchiveAfter != "" {
+		archiveAfter, err := time.ParseDuration(cfg.Server.JobArchiveAfter)
+		if err != nil || archiveAfter <= 0 {
+			log.Fatalf("Invalid JOB_ARCHIVE_AFTER %q: must be a positive duration", cfg.Server.JobArchiveAfter)
+		}
+		go worker.NewJobArchiver(jobRepo, time.Hour, archiveAfter).Run(ctx)
+		log.Printf("✓ Job archival started (finished jobs older than %v)", archiveAfter)
+	}
+
+	// Start-time SLO reported by /api/stats/slo and karbos_slo_compliance_ratio
+	startSLO, err := slo.ParseObjective(cfg.SLO.StartThreshold, cfg.SLO.Target, cfg.SLO.Window)
+	if err != nil {
+		log.Fatalf("Invalid SLO configuration: %v", err)
+	}
 
 	// Initialize Prometheus metrics (if enabled)
 	var metricsCollector *metrics.MetricsCollector
+	var metricsUpdaterDone <-chan struct{}
 	if cfg.Metrics.Enabled {
 		metricsCollector = metrics.NewMetricsCollector(redisQueue, nil, db.DB) // workerPool will be nil (API server doesn't run workers)
+		metricsCollector.SetAssumedPowerWatts(cfg.Metrics.AssumedPowerWatts)
+		metricsCollector.SetStartSLO(jobRepo, startSLO)
 		// Start background metrics updater (every 10 seconds)
-		metricsCollector.StartBackgroundUpdater(ctx, 10*time.Second)
+		metricsUpdaterDone = metricsCollector.StartBackgroundUpdater(ctx, 10*time.Second)
 		log.Printf("✓ Prometheus metrics enabled on port %s", cfg.Metrics.Port)
 	}
 
 	// Initialize HTTP handlers
 	jobHandler := handlers.NewJobHandler(jobRepo, redisQueue, carbonScheduler)
+	jobHandler.SetLegacyCreatedStatus(cfg.Server.LegacyCreatedStatus)
+	jobHandler.SetSubmissionWindows(submissionWindows)
+	jobHandler.SetExecutionLogs(executionRepo)
+	jobHandler.SetDependencyResolver(dependencyResolver)
+	volumeAllowlist, err := docker.ParseVolumeAllowlist(cfg.Docker.VolumeAllowlist)
+	if err != nil {
+		log.Fatalf("Invalid DOCKER_VOLUME_ALLOWLIST: %v", err)
+	}
+	jobHandler.SetVolumeAllowlist(volumeAllowlist)
+	imagePolicy, err := docker.ParseImagePolicy(cfg.Docker.ImageAllowlist, cfg.Docker.ImageDenylist)
+	if err != nil {
+		log.Fatalf("Invalid DOCKER_IMAGE_ALLOWLIST or DOCKER_IMAGE_DENYLIST: %v", err)
+	}
+	jobHandler.SetImagePolicy(imagePolicy)
+	jobHandler.SetNetworkAccess(cfg.Docker.NetworkAccessMode != "")
+	// Dry runs estimate limits, timeouts and emissions from the workers' settings
+	jobTimeout, err := time.ParseDuration(cfg.Worker.JobTimeout)
+	if err != nil || jobTimeout <= 0 {
+		log.Printf("Warning: Invalid WORKER_JOB_TIMEOUT %q, dry runs will assume 10m", cfg.Worker.JobTimeout)
+		jobTimeout = 0
+	}
+	imageProfiles, err := worker.ParseImageProfiles(cfg.Worker.ImageProfiles)
+	if err != nil {
+		log.Fatalf("Invalid WORKER_IMAGE_PROFILES: %v", err)
+	}
+	jobHandler.SetExecutionDefaults(handlers.ExecutionDefaults{
+		Limits:        docker.ResourceLimits{MemoryBytes: cfg.Docker.MemoryLimit, CPUQuota: cfg.Docker.CPUQuota},
+		MaxLimits:     docker.ResourceLimits{MemoryBytes: cfg.Docker.MaxMemoryLimit, CPUQuota: cfg.Docker.MaxCPUQuota},
+		Timeout:       jobTimeout,
+		ImageProfiles: imageProfiles,
+		PowerWatts:    cfg.Metrics.AssumedPowerWatts,
+	})
+	jobHandler.SetBaseContext(ctx)
 	carbonHandler := handlers.NewCarbonHandler(carbonCacheRepo)
+	carbonHandler.SetFetcher(carbonFetcher)
+	carbonHandler.SetGreenestWindows(greenestWindows)
 	healthHandler := handlers.NewHealthHandler(db, redisQueue)
 	sysHandler := handlers.NewSystemHandler(redisQueue)
+	logStreamHandler := handlers.NewLogStreamHandler(jobRepo, redisQueue)
+	queueHandler := handlers.NewQueueHandler(redisQueue, jobRepo)
+	queueHandler.SetScheduler(carbonScheduler)
+	replayBackoff, err := time.ParseDuration(cfg.Queue.DeadLetterReplayBackoff)
+	if err != nil {
+		replayBackoff = time.Minute
+	}
+	queueHandler.SetReplayPolicy(cfg.Queue.DeadLetterMaxReplays, replayBackoff)
+	adminHandler := handlers.NewAdminHandler(circuitBreaker)
+	adminHandler.SetUserTokenSecret(cfg.Server.UserTokenSecret)
+	versionHandler := handlers.NewVersionHandler(carbonProvider, cfg.Server.Environment)
+	statsHandler := handlers.NewStatsHandler(jobRepo, carbonCacheRepo, redisQueue, startSLO)
+	scheduleHandler := handlers.NewScheduleHandler(carbonScheduler)
+	recurringRepo := database.NewRecurringJobRepository(db)
+	recurringHandler := handlers.NewRecurringHandler(recurringRepo, jobHandler)
+
+	// Submit recurring jobs as their schedules come due
+	go recurring.NewRunner(recurringRepo, jobHandler, time.Minute).Run(ctx)
+	log.Println("✓ Recurring job runner started")
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -137,6 +339,13 @@ func main() {
 		IdleTimeout:           120 * time.Second,
 	})
 
+	// Streaming routes outlive the short write timeout above
+	streamWriteTimeout, err := time.ParseDuration(cfg.Server.StreamWriteTimeout)
+	if err != nil || streamWriteTimeout <= 0 {
+		streamWriteTimeout = time.Hour
+	}
+	app.Server().HeaderReceived = handlers.StreamWriteTimeout(streamWriteTimeout)
+
 	// Middleware
 	app.Use(recover.New())
 	app.Use(requestid.New())
@@ -148,6 +357,11 @@ func main() {
 		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
 	}))
 
+	// Compress responses for clients that accept it; WebSocket log streams are left alone
+	app.Use(compress.New(compress.Config{
+		Next: websocket.IsWebSocketUpgrade,
+	}))
+
 	// Logger middleware (only in development)
 	if cfg.IsDevelopment() {
 		app.Use(logger.New(logger.Config{
@@ -157,14 +371,30 @@ func main() {
 		}))
 	}
 
+	// Rate limiting (counted in Redis so the limit holds across API replicas)
+	rateLimit, err := strconv.Atoi(cfg.Server.RateLimit)
+	if err != nil || rateLimit < 0 {
+		log.Printf("Warning: Invalid API_RATE_LIMIT %q, using 100", cfg.Server.RateLimit)
+		rateLimit = 100
+	}
+	rateLimitWindow, err := time.ParseDuration(cfg.Server.RateLimitWindow)
+	if err != nil || rateLimitWindow <= 0 {
+		rateLimitWindow = time.Minute
+	}
+	if rateLimit > 0 {
+		app.Use("/api", handlers.RateLimit(redisQueue, rateLimit, rateLimitWindow, cfg.Server.UserTokenSecret, cfg.Server.AdminToken))
+		log.Printf("✓ Rate limiting enabled (%d requests per %s per client)", rateLimit, rateLimitWindow)
+	}
+
 	// Routes
-	setupRoutes(app, jobHandler, carbonHandler, healthHandler, sysHandler, metricsCollector, cfg)
+	setupRoutes(app, jobHandler, carbonHandler, healthHandler, sysHandler, logStreamHandler, queueHandler, adminHandler, versionHandler, statsHandler, scheduleHandler, recurringHandler, metricsCollector, cfg)
 
-	// Graceful shutdown
+	// Graceful shutdown: the cancelled root context aborts in-flight carbon calls and
+	// stops the background loops while outstanding requests drain
+	shutdownDone := make(chan struct{})
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		<-sigChan
+		defer close(shutdownDone)
+		<-ctx.Done()
 
 		log.Println("\n🛑 Shutting down server gracefully...")
 
@@ -186,10 +416,33 @@ func main() {
 	log.Println("✓ All 5 Phases Operational - Production-Ready Carbon-Aware Job Scheduling System!")
 	log.Println("\n📋 Available Endpoints:")
 	log.Println("  POST   /api/submit             - Submit a new job (with carbon-aware scheduling)")
+	log.Println("  POST   /api/submit/batch       - Submit up to 100 jobs at once")
+	log.Println("  GET    /api/jobs               - List jobs (filters: status, region, user_id, since, until)")
 	log.Println("  GET    /api/jobs/:id           - Get job details")
+	log.Println("  GET    /api/jobs/:id/logs      - Get job execution logs (with worker node)")
+	log.Println("  POST   /api/jobs/:id/cancel    - Cancel a queued or running job")
+	log.Println("  DELETE /api/jobs/:id           - Soft-delete a finished job (hidden from listings)")
 	log.Println("  GET    /api/users/:id/jobs     - Get user's jobs")
+	log.Println("  GET    /api/jobs/:id/logs/stream - Stream live job output (WebSocket)")
+	log.Println("  POST   /api/recurring          - Create a recurring job on a cron schedule")
+	log.Println("  GET    /api/recurring          - List recurring jobs (filter: user_id)")
+	log.Println("  DELETE /api/recurring/:id      - Delete a recurring job")
 	log.Println("  GET    /api/carbon-forecast    - Get carbon intensity forecast data")
 	log.Println("  GET    /api/carbon-cache       - Get all carbon cache entries")
+	log.Println("  GET    /api/carbon/recommend   - Greenest upcoming window per prefetched region")
+	log.Println("  GET    /api/carbon/:region/series - Hourly intensity for the next hours with min/max/avg")
+	log.Println("  GET    /api/carbon/circuit     - Carbon API circuit breaker state and fallback")
+	log.Println("  POST   /api/schedule/simulate  - Simulate scheduling a batch of jobs (nothing is saved)")
+	log.Println("  GET    /api/stats              - Job counts, CO2 saved, cache size, workers and queue depths")
+	log.Println("  GET    /api/stats/slo          - Start-time SLO compliance over a rolling window")
+	log.Println("  GET    /api/users/:id/deadletter - List a user's dead-lettered jobs")
+	log.Println("  POST   /api/users/:id/deadletter/replay - Replay a user's dead-lettered jobs")
+	log.Println("  GET    /api/admin/circuit-breaker - Inspect carbon API circuit breaker (admin)")
+	log.Println("  POST   /api/admin/circuit-breaker/reset - Force-close the circuit breaker (admin)")
+	log.Println("  GET    /api/admin/users/:id/token - Issue a user's token for per-user endpoints (admin)")
+	log.Println("  GET    /api/admin/queue/inspect - List upcoming delayed jobs (admin)")
+	log.Println("  GET    /api/admin/queue/dead  - List dead-lettered jobs (admin)")
+	log.Println("  POST   /api/admin/queue/dead/:id/requeue - Requeue a dead-lettered job (admin)")
 	log.Println("  GET    /health                 - Health check")
 	log.Println("  GET    /ready                  - Readiness check")
 	if cfg.Metrics.Enabled {
@@ -199,10 +452,43 @@ func main() {
 	if err := app.Listen(addr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Listen returns as soon as the listener closes; wait for requests to drain and
+	// the background loops to exit before the process does
+	<-shutdownDone
+	promoterService.Stop()
+	if metricsUpdaterDone != nil {
+		<-metricsUpdaterDone
+	}
+}
+
+// newCarbonProvider creates one link of a CARBON_PROVIDERS chain, refusing providers
+// whose credentials or data file are missing rather than failing every request later
+func newCarbonProvider(name, baseURL string, cfg *config.Config) (carbon.CarbonService, error) {
+	switch name {
+	case "electricitymaps":
+		if cfg.Carbon.APIKey == "" {
+			return nil, fmt.Errorf("electricitymaps requires CARBON_API_KEY")
+		}
+		return carbon.NewElectricityMapsClient(cfg.Carbon.APIKey, baseURL), nil
+	case "watttime":
+		if cfg.Carbon.APIUsername == "" {
+			return nil, fmt.Errorf("watttime requires CARBON_API_USERNAME")
+		}
+		return carbon.NewWattTimeClient(cfg.Carbon.APIUsername, cfg.Carbon.APIPassword, baseURL), nil
+	case "csv":
+		client, err := carbon.NewCSVCarbonClient(cfg.Carbon.CSVPath)
+		if err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown carbon provider %q", name)
+	}
 }
 
 // wrapWithCircuitBreaker wraps a carbon service with circuit breaker protection
-func wrapWithCircuitBreaker(service carbon.CarbonService, cfg *config.Config) carbon.CarbonService {
+func wrapWithCircuitBreaker(service carbon.CarbonService, cfg *config.Config) *carbon.CircuitBreaker {
 	timeout, _ := time.ParseDuration(cfg.CircuitBreaker.Timeout)
 	if timeout == 0 {
 		timeout = 30 * time.Second
@@ -219,10 +505,11 @@ func wrapWithCircuitBreaker(service carbon.CarbonService, cfg *config.Config) ca
 	}
 
 	cbConfig := carbon.CircuitBreakerConfig{
-		MaxFailures:    cfg.CircuitBreaker.MaxFailures,
-		Timeout:        timeout,
-		ResetTimeout:   resetTimeout,
-		StaticFallback: staticFallback,
+		MaxFailures:      cfg.CircuitBreaker.MaxFailures,
+		Timeout:          timeout,
+		ResetTimeout:     resetTimeout,
+		StaticFallback:   staticFallback,
+		SuccessThreshold: cfg.CircuitBreaker.SuccessThreshold,
 	}
 
 	circuitBreaker := carbon.NewCircuitBreaker(service, cbConfig)
@@ -232,8 +519,14 @@ func wrapWithCircuitBreaker(service carbon.CarbonService, cfg *config.Config) ca
 	return circuitBreaker
 }
 
+// redisMonitorInterval is how often the Redis connection is checked for outages
+const redisMonitorInterval = 5 * time.Second
+
+// carbonReadMaxAge is how long clients may reuse a carbon read without revalidating it
+const carbonReadMaxAge = time.Minute
+
 // setupRoutes configures all API routes
-func setupRoutes(app *fiber.App, jobHandler *handlers.JobHandler, carbonHandler *handlers.CarbonHandler, healthHandler *handlers.HealthHandler, sysHandler *handlers.SystemHandler, metricsCollector *metrics.MetricsCollector, cfg *config.Config) {
+func setupRoutes(app *fiber.App, jobHandler *handlers.JobHandler, carbonHandler *handlers.CarbonHandler, healthHandler *handlers.HealthHandler, sysHandler *handlers.SystemHandler, logStreamHandler *handlers.LogStreamHandler, queueHandler *handlers.QueueHandler, adminHandler *handlers.AdminHandler, versionHandler *handlers.VersionHandler, statsHandler *handlers.StatsHandler, scheduleHandler *handlers.ScheduleHandler, recurringHandler *handlers.RecurringHandler, metricsCollector *metrics.MetricsCollector, cfg *config.Config) {
 	// Health checks
 	app.Get("/health", healthHandler.HealthCheck)
 	app.Get("/ready", healthHandler.ReadyCheck)
@@ -249,6 +542,7 @@ func setupRoutes(app *fiber.App, jobHandler *handlers.JobHandler, carbonHandler
 
 			// Get metrics as Prometheus text format
 			// We'll use the promhttp handler by creating an adapter
+			c.Set(fiber.HeaderContentType, metrics.PrometheusContentType)
 			return c.SendString(metricsCollector.GetPrometheusText())
 		})
 	}
@@ -258,16 +552,47 @@ func setupRoutes(app *fiber.App, jobHandler *handlers.JobHandler, carbonHandler
 
 	// Job routes
 	api.Post("/submit", jobHandler.SubmitJob)
+	api.Post("/submit/batch", jobHandler.SubmitBatch)
 	api.Get("/jobs", jobHandler.GetAllJobs) // Get all jobs
 	api.Get("/jobs/:id", jobHandler.GetJob)
+	api.Get("/jobs/:id/logs", jobHandler.GetJobLogs)
+	api.Post("/jobs/:id/cancel", jobHandler.CancelJob)
+	api.Delete("/jobs/:id", jobHandler.DeleteJob)
 	api.Get("/users/:userId/jobs", jobHandler.GetUserJobs)
-
-	// Carbon routes
-	api.Get("/carbon-forecast", carbonHandler.GetCarbonForecast)
-	api.Get("/carbon-cache", carbonHandler.GetCarbonCache)
+	api.Post("/recurring", recurringHandler.CreateRecurring)
+	api.Get("/recurring", recurringHandler.ListRecurring)
+	api.Delete("/recurring/:id", recurringHandler.DeleteRecurring)
+	api.Get("/jobs/:id/logs/stream", logStreamHandler.RequireUpgrade, websocket.New(logStreamHandler.StreamLogs))
+
+	// Carbon routes; reads of cached data answer conditional GETs from polling dashboards
+	cacheableCarbon := handlers.CacheableReads(carbonReadMaxAge)
+	api.Get("/carbon-forecast", cacheableCarbon, carbonHandler.GetCarbonForecast)
+	api.Get("/carbon-cache", cacheableCarbon, carbonHandler.GetCarbonCache)
+	api.Get("/carbon/recommend", cacheableCarbon, carbonHandler.GetRecommendation)
+	api.Get("/carbon/:region/series", cacheableCarbon, carbonHandler.GetCarbonSeries)
+	api.Get("/carbon/circuit", adminHandler.GetCircuitBreaker)
+	api.Post("/schedule/simulate", scheduleHandler.Simulate)
 
 	// System routes
 	api.Get("/system/health", sysHandler.GetSystemHealth)
+	api.Get("/version", versionHandler.GetVersion)
+	api.Get("/stats", statsHandler.GetStats)
+	api.Get("/stats/slo", statsHandler.GetSLO)
+
+	// Per-user dead-letter routes (require the user's token or ADMIN_API_TOKEN)
+	userDead := api.Group("/users/:userId/deadletter", handlers.RequireUser(cfg.Server.UserTokenSecret, cfg.Server.AdminToken))
+	userDead.Get("/", queueHandler.GetUserDeadLetters)
+	userDead.Post("/replay", queueHandler.ReplayUserDeadLetters)
+
+	// Admin routes (require ADMIN_API_TOKEN)
+	admin := api.Group("/admin", handlers.RequireAdmin(cfg.Server.AdminToken))
+	admin.Get("/circuit-breaker", adminHandler.GetCircuitBreaker)
+	admin.Post("/circuit-breaker/reset", adminHandler.ResetCircuitBreaker)
+	admin.Get("/users/:userId/token", adminHandler.GetUserToken)
+	// Every user's queued and dead-lettered jobs
+	admin.Get("/queue/inspect", queueHandler.InspectQueue)
+	admin.Get("/queue/dead", queueHandler.GetDeadLetters)
+	admin.Post("/queue/dead/:id/requeue", queueHandler.RequeueDeadLetter)
 
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -312,3 +637,37 @@ func customErrorHandler(c *fiber.Ctx, err error) error {
 		"code":    code,
 	})
 }
+
+// splitRegions parses a comma-separated region list, dropping empty entries
+func splitRegions(list string) []string {
+	var regions []string
+	for _, region := range strings.Split(list, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// loadAcceptanceWindows parses the submission and promotion schedules, exiting on
+// a malformed one rather than silently accepting jobs around the clock
+func loadAcceptanceWindows(cfg config.AcceptanceConfig) (submission, promotion *acceptance.Schedule) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Fatalf("Invalid ACCEPTANCE_TIMEZONE %q: %v", cfg.Timezone, err)
+	}
+	if submission, err = acceptance.Parse(cfg.SubmissionWindows, loc); err != nil {
+		log.Fatalf("Invalid ACCEPTANCE_SUBMISSION_WINDOWS: %v", err)
+	}
+	if promotion, err = acceptance.Parse(cfg.PromotionWindows, loc); err != nil {
+		log.Fatalf("Invalid ACCEPTANCE_PROMOTION_WINDOWS: %v", err)
+	}
+
+	if !submission.IsAlwaysOpen() {
+		log.Printf("✓ Job submissions restricted to acceptance windows (%s)", submission)
+	}
+	if !promotion.IsAlwaysOpen() {
+		log.Printf("✓ Delayed job promotion restricted to acceptance windows (%s)", promotion)
+	}
+	return submission, promotion
+}
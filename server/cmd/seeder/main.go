@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -17,12 +18,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// seederSource tags the carbon points written by the seeder so re-runs can replace them
+const seederSource = "seeder"
+
+// carbonSeedStore is the part of the carbon cache repository the seeder writes to
+type carbonSeedStore interface {
+	SaveCarbonIntensity(ctx context.Context, data database.CarbonIntensity, ttl time.Duration) error
+	DeleteCarbonIntensityRange(ctx context.Context, region string, start, end time.Time, source string) (int64, error)
+}
+
 // DemoDataSeeder handles seeding demo data into the system
 type DemoDataSeeder struct {
 	db            *database.DB
 	jobRepo       *database.JobRepository
 	executionRepo *database.ExecutionLogRepository
-	carbonRepo    *database.CarbonCacheRepository
+	carbonRepo    carbonSeedStore
 	apiURL        string
 	regions       []string
 	dockerImages  []string
@@ -104,7 +114,7 @@ func main() {
 
 	// Seed carbon cache data
 	log.Println("\n📊 Seeding carbon intensity cache...")
-	if err := seeder.seedCarbonCache(ctx); err != nil {
+	if err := seeder.seedCarbonCache(ctx, time.Now()); err != nil {
 		log.Printf("Warning: Failed to seed carbon cache: %v", err)
 	} else {
 		log.Println("✓ Seeded carbon intensity data for all regions")
@@ -142,9 +152,12 @@ func main() {
 	log.Println("   4. Watch the active jobs being processed!")
 }
 
-// seedCarbonCache populates carbon intensity cache for all regions
-func (s *DemoDataSeeder) seedCarbonCache(ctx context.Context) error {
-	now := time.Now()
+// seedCarbonCache populates carbon intensity cache for all regions.
+// Points sit on the hour and any left by an earlier run in the same window are
+// cleared first, so re-running the seeder never accumulates duplicate points.
+func (s *DemoDataSeeder) seedCarbonCache(ctx context.Context, now time.Time) error {
+	windowEnd := now.Truncate(time.Hour)
+	windowStart := windowEnd.Add(-23 * time.Hour)
 	baseIntensities := map[string]float64{
 		"US-EAST":        320.5,
 		"US-WEST":        180.2,
@@ -162,23 +175,33 @@ func (s *DemoDataSeeder) seedCarbonCache(ctx context.Context) error {
 
 	count := 0
 	for region, baseIntensity := range baseIntensities {
+		if _, err := s.carbonRepo.DeleteCarbonIntensityRange(ctx, region, windowStart, windowEnd, seederSource); err != nil {
+			return fmt.Errorf("failed to clear previous carbon data for %s: %w", region, err)
+		}
+
 		// Create cache entries for last 24 hours (hourly)
 		for i := 0; i < 24; i++ {
-			timestamp := now.Add(-time.Duration(i) * time.Hour)
+			timestamp := windowEnd.Add(-time.Duration(i) * time.Hour)
 
 			// Add some variation to intensity
 			variation := rand.Float64()*100 - 50 // -50 to +50
 			intensity := baseIntensity + variation
 
+			// Rough energy mix: cleaner grids get a higher renewable share
+			renewable := math.Max(5, math.Min(95, 100-intensity/8))
+			fossil := 100 - renewable
+
 			// Save to cache with 2-hour TTL
-			if err := s.carbonRepo.SaveCarbonIntensity(
-				ctx,
-				region,
-				timestamp,
-				intensity,
-				"gCO2eq/kWh",
-				2*time.Hour,
-			); err != nil {
+			if err := s.carbonRepo.SaveCarbonIntensity(ctx, database.CarbonIntensity{
+				Region:    region,
+				Timestamp: timestamp,
+				Intensity: intensity,
+				Unit:      "gCO2eq/kWh",
+
+				RenewablePercentage: &renewable,
+				FossilPercentage:    &fossil,
+				Source:              seederSource,
+			}, 2*time.Hour); err != nil {
 				return fmt.Errorf("failed to cache carbon data for %s: %w", region, err)
 			}
 			count++
@@ -268,17 +291,19 @@ func (s *DemoDataSeeder) seedHistoricalJobs(ctx context.Context, count int) (int
 		}
 
 		durationSeconds := int(jobType.duration.Seconds())
+		workerNodeID := fmt.Sprintf("seeder/worker-%d", rand.Intn(3)+1)
 
 		executionLog := &models.ExecutionLog{
 			ID:           uuid.New(),
 			JobID:        job.ID,
 			StartedAt:    startedAt,
 			CompletedAt:  &completedAt,
-			ExitCode:     exitCode,
+			ExitCode:     &exitCode,
 			Output:       output,
 			ErrorMessage: errorMessage,
-			Duration:     durationSeconds,
+			Duration:     &durationSeconds,
 			CreatedAt:    completedAt,
+			WorkerNodeID: &workerNodeID,
 		}
 
 		if err := s.executionRepo.CreateExecutionLog(ctx, executionLog); err != nil {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+)
+
+// fakeCarbonStore keeps every saved point, like carbon_cache does today: the upsert's
+// ON CONFLICT never matches because forecast_window is NULL for these rows
+type fakeCarbonStore struct {
+	points []database.CarbonIntensity
+}
+
+func (f *fakeCarbonStore) SaveCarbonIntensity(ctx context.Context, data database.CarbonIntensity, ttl time.Duration) error {
+	f.points = append(f.points, data)
+	return nil
+}
+
+func (f *fakeCarbonStore) DeleteCarbonIntensityRange(ctx context.Context, region string, start, end time.Time, source string) (int64, error) {
+	kept := f.points[:0]
+	var removed int64
+	for _, p := range f.points {
+		if p.Region == region && p.Source == source && !p.Timestamp.Before(start) && !p.Timestamp.After(end) {
+			removed++
+			continue
+		}
+		kept = append(kept, p)
+	}
+	f.points = kept
+	return removed, nil
+}
+
+func TestSeedCarbonCache_RerunDoesNotDuplicatePoints(t *testing.T) {
+	store := &fakeCarbonStore{}
+	seeder := &DemoDataSeeder{carbonRepo: store}
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	if err := seeder.seedCarbonCache(ctx, now); err != nil {
+		t.Fatalf("first seed failed: %v", err)
+	}
+	firstRun := len(store.points)
+
+	// Same hour, a few minutes later
+	if err := seeder.seedCarbonCache(ctx, now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("second seed failed: %v", err)
+	}
+	if len(store.points) != firstRun {
+		t.Fatalf("expected re-seeding to keep %d points, got %d", firstRun, len(store.points))
+	}
+
+	perRegion := make(map[string]map[time.Time]bool)
+	for _, p := range store.points {
+		if perRegion[p.Region] == nil {
+			perRegion[p.Region] = make(map[time.Time]bool)
+		}
+		if perRegion[p.Region][p.Timestamp] {
+			t.Errorf("duplicate point for %s at %s", p.Region, p.Timestamp)
+		}
+		perRegion[p.Region][p.Timestamp] = true
+	}
+	for region, points := range perRegion {
+		if len(points) != 24 {
+			t.Errorf("expected 24 points for %s, got %d", region, len(points))
+		}
+	}
+	if len(perRegion) != 12 {
+		t.Errorf("expected points for 12 regions, got %d", len(perRegion))
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,13 +14,19 @@ import (
 	"github.com/Sambit-Mondal/karbos/server/internal/config"
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
 	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/metrics"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/Sambit-Mondal/karbos/server/internal/version"
 	"github.com/Sambit-Mondal/karbos/server/internal/worker"
 	"github.com/google/uuid"
 )
 
 func main() {
 	log.Println("=== Karbos Worker Node Starting ===")
+	build := version.Get()
+	log.Printf("Version: %s (commit %s, built %s, %s)", build.Version, build.Commit, build.BuildTime, build.GoVersion)
 
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -26,6 +34,11 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Structured logging; the log package's output goes through the same handler
+	if _, err := logging.Setup(cfg.Log.Format, cfg.Log.Level, cfg.Server.Environment); err != nil {
+		log.Fatalf("Invalid logging config: %v", err)
+	}
+
 	log.Printf("Environment: %s", cfg.Server.Environment)
 	log.Printf("Worker Pool Size: %d", cfg.Worker.PoolSize)
 
@@ -47,6 +60,7 @@ func main() {
 		cfg.Redis.DB,
 		cfg.Queue.ImmediateQueueKey,
 		cfg.Queue.DelayedSetKey,
+		cfg.Queue.DeadLetterKey,
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize Redis queue: %v", err)
@@ -62,17 +76,54 @@ func main() {
 
 	// Initialize Docker service
 	log.Println("Connecting to Docker daemon...")
-	dockerService, err := docker.NewDockerService()
+	dockerService, err := docker.NewDockerService(
+		docker.ResourceLimits{
+			MemoryBytes: cfg.Docker.MemoryLimit,
+			CPUQuota:    cfg.Docker.CPUQuota,
+		},
+		docker.ResourceLimits{
+			MemoryBytes: cfg.Docker.MaxMemoryLimit,
+			CPUQuota:    cfg.Docker.MaxCPUQuota,
+		},
+	)
 	if err != nil {
 		log.Fatalf("Failed to initialize Docker service: %v", err)
 	}
 	defer dockerService.Close()
+	dockerService.SetMaxOutputBytes(cfg.Docker.MaxOutputBytes)
+	if err := dockerService.SetNetworkModes(cfg.Docker.NetworkMode, cfg.Docker.NetworkAccessMode); err != nil {
+		log.Fatalf("Invalid container network configuration: %v", err)
+	}
+	log.Printf("Container networks: %q by default, %q for jobs with network access", cfg.Docker.NetworkMode, cfg.Docker.NetworkAccessMode)
+
+	// Credentials for pulling job images from a private registry
+	if cfg.Docker.RegistryUsername != "" || cfg.Docker.RegistryToken != "" {
+		registryAuth := docker.RegistryAuth{
+			Host:     cfg.Docker.RegistryHost,
+			Username: cfg.Docker.RegistryUsername,
+			Password: cfg.Docker.RegistryPassword,
+			Token:    cfg.Docker.RegistryToken,
+		}
+		dockerService.SetRegistryAuth(registryAuth)
+		log.Printf("Registry auth enabled for %s", registryAuth)
+	}
+
+	// Host paths and volumes jobs may mount
+	volumeAllowlist, err := docker.ParseVolumeAllowlist(cfg.Docker.VolumeAllowlist)
+	if err != nil {
+		log.Fatalf("Invalid DOCKER_VOLUME_ALLOWLIST: %v", err)
+	}
+	if volumeAllowlist != nil {
+		dockerService.SetVolumeAllowlist(volumeAllowlist)
+		log.Printf("Volume mounts allowed from: %s", cfg.Docker.VolumeAllowlist)
+	}
 
 	// Test Docker connection
 	if err := dockerService.Ping(ctx); err != nil {
 		log.Fatalf("Failed to ping Docker daemon: %v", err)
 	}
 	log.Println("Docker daemon connected successfully")
+	log.Printf("Container defaults: memory=%d bytes, cpu_quota=%d", cfg.Docker.MemoryLimit, cfg.Docker.CPUQuota)
 
 	// Get Docker info
 	dockerInfo, err := dockerService.GetDockerInfo(ctx)
@@ -87,27 +138,133 @@ func main() {
 	jobRepo := database.NewJobRepository(db)
 	executionRepo := database.NewExecutionLogRepository(db.DB)
 
+	// Worker node ID, shared by the heartbeat, execution logs and in-flight job tracking.
+	// Generated per process unless configured, in which case a restart reclaims the
+	// jobs the previous process was running.
+	workerID := cfg.Worker.NodeID
+	if workerID == "" {
+		workerID = uuid.New().String()
+	}
+	log.Printf("Worker ID: %s", workerID)
+
 	// Create worker pool
 	log.Printf("Creating worker pool with %d workers...", cfg.Worker.PoolSize)
+	startSLO, err := slo.ParseObjective(cfg.SLO.StartThreshold, cfg.SLO.Target, cfg.SLO.Window)
+	if err != nil {
+		log.Fatalf("Invalid SLO configuration: %v", err)
+	}
+
+	jobTimeout, err := time.ParseDuration(cfg.Worker.JobTimeout)
+	if err != nil || jobTimeout <= 0 {
+		log.Printf("Warning: Invalid WORKER_JOB_TIMEOUT %q, using 10m", cfg.Worker.JobTimeout)
+		jobTimeout = 10 * time.Minute
+	}
+	var stepTimeout time.Duration
+	if cfg.Worker.StepTimeout != "" {
+		stepTimeout, err = time.ParseDuration(cfg.Worker.StepTimeout)
+		if err != nil || stepTimeout < 0 {
+			log.Printf("Warning: Invalid WORKER_STEP_TIMEOUT %q, dividing the job timeout between steps", cfg.Worker.StepTimeout)
+			stepTimeout = 0
+		}
+	}
+	pollInterval, err := time.ParseDuration(cfg.Worker.PollInterval)
+	if err != nil || pollInterval <= 0 {
+		log.Printf("Warning: Invalid WORKER_POLL_INTERVAL %q, using 2s", cfg.Worker.PollInterval)
+		pollInterval = 2 * time.Second
+	}
+	pollMaxInterval, err := time.ParseDuration(cfg.Worker.PollMaxInterval)
+	if err != nil || pollMaxInterval <= 0 {
+		log.Printf("Warning: Invalid WORKER_POLL_MAX_INTERVAL %q, using 10s", cfg.Worker.PollMaxInterval)
+		pollMaxInterval = 10 * time.Second
+	}
+	pollStrategy, err := worker.ParsePollStrategy(cfg.Worker.PollStrategy)
+	if err != nil {
+		log.Fatalf("Invalid WORKER_POLL_STRATEGY: %v", err)
+	}
+	imageProfiles, err := worker.ParseImageProfiles(cfg.Worker.ImageProfiles)
+	if err != nil {
+		log.Fatalf("Invalid WORKER_IMAGE_PROFILES: %v", err)
+	}
+
 	workerPool, err := worker.NewPool(worker.PoolConfig{
 		Size:          cfg.Worker.PoolSize,
 		Queue:         redisQueue,
 		JobRepo:       jobRepo,
 		ExecutionRepo: executionRepo,
 		DockerService: dockerService,
+		MaxRetries:    cfg.Worker.MaxRetries,
+		JobTimeout:    jobTimeout,
+		StepTimeout:   stepTimeout,
+		PrefetchDepth: cfg.Worker.PrefetchDepth,
+
+		MaxConcurrentContainers: cfg.Worker.MaxConcurrentContainers,
+
+		PollInterval:    pollInterval,
+		PollMaxInterval: pollMaxInterval,
+		PollStrategy:    pollStrategy,
+
+		SuccessOutputTailBytes: cfg.Worker.SuccessOutputTailBytes,
+		NodeID:                 workerID,
+		StartSLO:               startSLO,
+		ImageProfiles:          imageProfiles,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create worker pool: %v", err)
 	}
 
+	if cfg.Worker.PrefetchDepth > 0 {
+		log.Printf("Image prefetching enabled (depth %d)", cfg.Worker.PrefetchDepth)
+	}
+
+	if pollStrategy == worker.PollBackoff {
+		log.Printf("Idle polling backs off from %s to %s", pollInterval, pollMaxInterval)
+	} else {
+		log.Printf("Polling every %s", pollInterval)
+	}
+
+	if len(imageProfiles) > 0 {
+		log.Printf("Image profiles loaded (%d patterns)", len(imageProfiles))
+	}
+
+	if cfg.Worker.SuccessOutputTailBytes > 0 {
+		log.Printf("Successful job output trimmed to the last %d bytes", cfg.Worker.SuccessOutputTailBytes)
+	}
+
 	// Start worker pool
 	if err := workerPool.Start(); err != nil {
 		log.Fatalf("Failed to start worker pool: %v", err)
 	}
 
-	// Generate unique worker ID
-	workerID := uuid.New().String()
-	log.Printf("Worker ID: %s", workerID)
+	// Serve /healthz so orchestrators can restart a worker that lost Redis or Docker
+	var healthServer *http.Server
+	if cfg.Worker.HealthPort != "" {
+		healthServer = worker.NewHealthServer(":"+cfg.Worker.HealthPort, workerPool)
+		go func() {
+			if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Health server stopped: %v", err)
+			}
+		}()
+		log.Printf("Health check on :%s/healthz", cfg.Worker.HealthPort)
+	}
+
+	// Serve this process's pool metrics; job durations and CO2 savings come from the API's /metrics
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled && cfg.Worker.MetricsPort != "" {
+		metricsCollector := metrics.NewMetricsCollector(redisQueue, workerPool, nil)
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metricsCollector)
+		metricsServer = &http.Server{
+			Addr:              ":" + cfg.Worker.MetricsPort,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+		log.Printf("Prometheus metrics on :%s/metrics", cfg.Worker.MetricsPort)
+	}
 
 	// Start heartbeat goroutine
 	heartbeatCtx, heartbeatCancel := context.WithCancel(context.Background())
@@ -137,6 +294,9 @@ func main() {
 		}
 	}()
 
+	// Log Redis outages and recoveries as they happen
+	go redisQueue.MonitorConnection(heartbeatCtx, 5*time.Second)
+
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -152,6 +312,13 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if healthServer != nil {
+		healthServer.Shutdown(shutdownCtx)
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+
 	// Stop worker pool gracefully
 	log.Println("Stopping worker pool...")
 	workerPool.Stop()
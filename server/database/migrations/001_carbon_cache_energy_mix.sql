@@ -0,0 +1,4 @@
+-- Store the renewable/fossil generation mix alongside carbon intensity.
+-- Existing rows keep NULL (unknown) percentages.
+ALTER TABLE carbon_cache ADD COLUMN IF NOT EXISTS renewable_percentage DECIMAL(5, 2);
+ALTER TABLE carbon_cache ADD COLUMN IF NOT EXISTS fossil_percentage DECIMAL(5, 2);
@@ -0,0 +1,5 @@
+-- Track per-job CO2 savings.
+-- submission_intensity is recorded when the job is scheduled; co2_saved_grams is
+-- filled in by the metrics collector once the job has completed.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS submission_intensity DECIMAL(10, 2);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS co2_saved_grams DECIMAL(12, 4);
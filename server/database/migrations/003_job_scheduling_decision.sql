@@ -0,0 +1,5 @@
+-- Persist the scheduler's decision on each job.
+-- expected_intensity is the forecast average for the chosen window and
+-- carbon_savings is the intensity reduction versus running at submission time.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS expected_intensity DECIMAL(10, 2);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS carbon_savings DECIMAL(10, 2);
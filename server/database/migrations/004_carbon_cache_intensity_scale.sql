@@ -0,0 +1,3 @@
+-- Distinguish absolute intensities (gCO2/kWh) from relative provider indices.
+-- WattTime reports a 0-100 index that must not be treated as grams of CO2.
+ALTER TABLE carbon_cache ADD COLUMN IF NOT EXISTS intensity_scale VARCHAR(20) NOT NULL DEFAULT 'absolute';
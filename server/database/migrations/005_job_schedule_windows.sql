@@ -0,0 +1,4 @@
+-- Keep the window the scheduler chose for each job together with the near-optimal
+-- alternatives it considered, as a JSON array of {start_time, end_time, avg_intensity,
+-- carbon_cost, chosen}. NULL when the job was not carbon-scheduled.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS schedule_windows JSONB;
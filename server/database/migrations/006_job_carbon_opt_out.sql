@@ -0,0 +1,3 @@
+-- Record jobs submitted with carbon_aware=false. They skip the scheduler and run
+-- immediately, so reporting must not credit them with (or blame them for) CO2 savings.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS carbon_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Jobs can be cancelled before they run; CANCELLED is terminal like COMPLETED and FAILED.
+ALTER TYPE job_status ADD VALUE IF NOT EXISTS 'CANCELLED';
@@ -0,0 +1,3 @@
+-- Record the X-Request-ID of the API request that submitted each job, so its
+-- scheduler, queue and worker logs can be correlated with the submission.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS request_id VARCHAR(255);
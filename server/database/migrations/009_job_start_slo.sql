@@ -0,0 +1,4 @@
+-- Record how late each job first started relative to its scheduled time, and whether
+-- that met the start-time SLO, so /api/stats/slo can report rolling compliance.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS start_delay_seconds INTEGER;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS slo_met BOOLEAN;
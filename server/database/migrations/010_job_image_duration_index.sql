@@ -0,0 +1,4 @@
+-- Let the scheduler look up an image's recent completed jobs to estimate the
+-- duration of new submissions that don't give one.
+CREATE INDEX IF NOT EXISTS idx_jobs_image_completed ON jobs(docker_image, completed_at DESC)
+    WHERE status = 'COMPLETED';
@@ -0,0 +1,4 @@
+-- Record each attempt's peak memory and CPU time, sampled from Docker while the
+-- container runs, so users can right-size resource limits. NULL when no sample was taken.
+ALTER TABLE execution_logs ADD COLUMN IF NOT EXISTS peak_memory_bytes BIGINT;
+ALTER TABLE execution_logs ADD COLUMN IF NOT EXISTS cpu_seconds DOUBLE PRECISION;
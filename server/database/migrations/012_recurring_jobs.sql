@@ -0,0 +1,18 @@
+-- Recurring jobs: a cron schedule plus the job spec submitted each time it fires.
+-- job_spec is a /api/submit request body without the deadline, which is set per run
+-- to the schedule's next fire time. Replicas claim a run by advancing next_run_at.
+CREATE TABLE IF NOT EXISTS recurring_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    job_spec JSONB NOT NULL,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_job_id UUID,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_jobs_next_run_at ON recurring_jobs(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_recurring_jobs_user_id ON recurring_jobs(user_id);
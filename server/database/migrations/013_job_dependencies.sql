@@ -0,0 +1,11 @@
+-- Job dependencies: a job submitted with depends_on is held in WAITING until every job
+-- it depends on has COMPLETED, and fails if any of them fails or is cancelled.
+ALTER TYPE job_status ADD VALUE IF NOT EXISTS 'WAITING';
+
+CREATE TABLE IF NOT EXISTS job_dependencies (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    depends_on UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    PRIMARY KEY (job_id, depends_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_dependencies_depends_on ON job_dependencies(depends_on);
@@ -0,0 +1,3 @@
+-- Flag attempts whose container output went over the worker's DOCKER_MAX_OUTPUT_BYTES
+-- cap and was stored cut short
+ALTER TABLE execution_logs ADD COLUMN IF NOT EXISTS output_truncated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+-- Job labels: key/value tags set at submission (team, project, cost center) that job
+-- listings can filter on, e.g. GET /api/jobs?label=team:ml
+CREATE TABLE IF NOT EXISTS job_labels (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    key VARCHAR(63) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    PRIMARY KEY (job_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_labels_key_value ON job_labels(key, value);
@@ -0,0 +1,6 @@
+-- Soft-delete finished jobs: DELETE /api/jobs/:id and the archiver (JOB_ARCHIVE_AFTER)
+-- set deleted_at, which hides the job from listings while GET /api/jobs/:id still finds it.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at_live ON jobs(created_at DESC)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- Per-step names, durations and errors of multi-step jobs, NULL for single-command jobs
+ALTER TABLE execution_logs ADD COLUMN IF NOT EXISTS steps JSONB;
@@ -10,7 +10,9 @@ CREATE TYPE job_status AS ENUM (
     'DELAYED',
     'RUNNING',
     'COMPLETED',
-    'FAILED'
+    'FAILED',
+    'CANCELLED',
+    'WAITING'
 );
 
 -- Jobs Table
@@ -28,6 +30,16 @@ CREATE TABLE IF NOT EXISTS jobs (
     estimated_duration INTEGER, -- in seconds
     region VARCHAR(50),
     metadata JSONB DEFAULT '{}'::jsonb,
+    submission_intensity DECIMAL(10, 2), -- gCO2/kWh when the job was scheduled
+    co2_saved_grams DECIMAL(12, 4), -- set once the job completes
+    expected_intensity DECIMAL(10, 2), -- forecast gCO2/kWh for the chosen window
+    carbon_savings DECIMAL(10, 2), -- gCO2/kWh saved versus submission time
+    schedule_windows JSONB, -- chosen window and near-optimal alternatives
+    carbon_opt_out BOOLEAN NOT NULL DEFAULT FALSE, -- submitted with carbon_aware=false
+    request_id VARCHAR(255), -- X-Request-ID of the submitting API request
+    start_delay_seconds INTEGER, -- how late the first run started after scheduled_time
+    slo_met BOOLEAN, -- whether that start met the start-time SLO
+    deleted_at TIMESTAMP WITH TIME ZONE, -- soft-deleted or archived; hidden from listings
     
     -- Constraints
     CONSTRAINT jobs_deadline_future CHECK (deadline > created_at)
@@ -44,6 +56,10 @@ CREATE TABLE IF NOT EXISTS execution_logs (
     started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     completed_at TIMESTAMP WITH TIME ZONE,
     worker_node_id VARCHAR(100),
+    peak_memory_bytes BIGINT, -- NULL when no stats sample was taken
+    cpu_seconds DOUBLE PRECISION,
+    output_truncated BOOLEAN NOT NULL DEFAULT FALSE, -- Output went over the worker's cap
+    steps JSONB, -- Per-step timings of a multi-step job; NULL for single-command jobs
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     
     CONSTRAINT execution_logs_job_fk FOREIGN KEY (job_id) REFERENCES jobs(id)
@@ -57,18 +73,52 @@ CREATE TABLE IF NOT EXISTS carbon_cache (
     intensity_value DECIMAL(10, 2) NOT NULL, -- gCO2/kWh
     forecast_window INTEGER, -- hours ahead
     source VARCHAR(100),
+    renewable_percentage DECIMAL(5, 2), -- share of renewable generation, NULL if unknown
+    fossil_percentage DECIMAL(5, 2), -- share of fossil generation, NULL if unknown
+    intensity_scale VARCHAR(20) NOT NULL DEFAULT 'absolute', -- 'relative' for provider indices (WattTime)
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     
     -- Composite unique constraint for region + timestamp
     CONSTRAINT carbon_cache_unique UNIQUE (region, timestamp, forecast_window)
 );
 
+-- Job Dependencies Table
+CREATE TABLE IF NOT EXISTS job_dependencies (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    depends_on UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE, -- must COMPLETE before job_id is queued
+    PRIMARY KEY (job_id, depends_on)
+);
+
+-- Job Labels Table
+CREATE TABLE IF NOT EXISTS job_labels (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    key VARCHAR(63) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    PRIMARY KEY (job_id, key)
+);
+
+-- Recurring Jobs Table
+CREATE TABLE IF NOT EXISTS recurring_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA zone the cron expression is evaluated in
+    job_spec JSONB NOT NULL, -- /api/submit body without the deadline
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_job_id UUID, -- job submitted by the last run, NULL if it failed
+    last_error TEXT, -- why the last run's submission failed
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
 -- Indexes for performance
 CREATE INDEX idx_jobs_status ON jobs(status);
 CREATE INDEX idx_jobs_user_id ON jobs(user_id);
 CREATE INDEX idx_jobs_scheduled_time ON jobs(scheduled_time);
 CREATE INDEX idx_jobs_created_at ON jobs(created_at DESC);
 CREATE INDEX idx_jobs_deadline ON jobs(deadline);
+CREATE INDEX idx_jobs_image_completed ON jobs(docker_image, completed_at DESC) WHERE status = 'COMPLETED';
+CREATE INDEX idx_jobs_created_at_live ON jobs(created_at DESC) WHERE deleted_at IS NULL;
 
 CREATE INDEX idx_execution_logs_job_id ON execution_logs(job_id);
 CREATE INDEX idx_execution_logs_started_at ON execution_logs(started_at DESC);
@@ -76,6 +126,13 @@ CREATE INDEX idx_execution_logs_started_at ON execution_logs(started_at DESC);
 CREATE INDEX idx_carbon_cache_region_timestamp ON carbon_cache(region, timestamp DESC);
 CREATE INDEX idx_carbon_cache_region ON carbon_cache(region);
 
+CREATE INDEX idx_job_dependencies_depends_on ON job_dependencies(depends_on);
+
+CREATE INDEX idx_job_labels_key_value ON job_labels(key, value);
+
+CREATE INDEX idx_recurring_jobs_next_run_at ON recurring_jobs(next_run_at);
+CREATE INDEX idx_recurring_jobs_user_id ON recurring_jobs(user_id);
+
 -- Function to update job status timestamp
 CREATE OR REPLACE FUNCTION update_job_timestamp()
 RETURNS TRIGGER AS $$
@@ -103,8 +160,16 @@ CREATE TRIGGER job_status_timestamp_trigger
 COMMENT ON TABLE jobs IS 'Stores all job submissions with scheduling and status information';
 COMMENT ON TABLE execution_logs IS 'Stores execution logs and results for each job run';
 COMMENT ON TABLE carbon_cache IS 'Caches carbon intensity forecasts for different regions';
+COMMENT ON TABLE job_dependencies IS 'Jobs that must complete before a WAITING job is queued';
+COMMENT ON TABLE job_labels IS 'Key/value tags set at submission that job listings filter on';
+COMMENT ON TABLE recurring_jobs IS 'Cron schedules that submit a job each time they fire';
 
 COMMENT ON COLUMN jobs.scheduled_time IS 'The optimized time when the job should be executed';
 COMMENT ON COLUMN jobs.deadline IS 'The SLA deadline by which the job must complete';
 COMMENT ON COLUMN jobs.estimated_duration IS 'Estimated job duration in seconds';
-COMMENT ON COLUMN carbon_cache.intensity_value IS 'Carbon intensity in grams of CO2 per kilowatt-hour';
+COMMENT ON COLUMN jobs.co2_saved_grams IS 'Grams of CO2 saved versus running at submission time (negative if the job ran dirtier)';
+COMMENT ON COLUMN jobs.carbon_savings IS 'Intensity reduction in gCO2/kWh the scheduler expected versus running at submission time';
+COMMENT ON COLUMN jobs.carbon_opt_out IS 'The submitter skipped carbon-aware scheduling; the job ran immediately and is excluded from CO2 savings';
+COMMENT ON COLUMN jobs.schedule_windows IS 'JSON array of the chosen execution window and the near-optimal alternatives the scheduler found';
+COMMENT ON COLUMN carbon_cache.intensity_value IS 'Carbon intensity in grams of CO2 per kilowatt-hour, or a 0-100 index when intensity_scale is relative';
+COMMENT ON COLUMN carbon_cache.intensity_scale IS 'absolute (gCO2/kWh) or relative (provider index that cannot be converted to grams)';
@@ -0,0 +1,200 @@
+package acceptance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a set of weekly windows during which something (job submission, delayed
+// job promotion) is allowed. It is independent of carbon-aware scheduling.
+//
+// A schedule is written as windows separated by ";", each "<days> <HH:MM>-<HH:MM>":
+//
+//	Mon-Fri 18:00-08:00; Sat,Sun 00:00-24:00
+//
+// Days are three-letter names, ranges of them, or "*" for every day. A window whose end
+// is before its start runs past midnight into the next day. A nil or empty Schedule is
+// always open.
+type Schedule struct {
+	windows  []window
+	location *time.Location
+}
+
+// window is one weekly window; start and end are minutes after midnight
+type window struct {
+	days  [7]bool // Indexed by time.Weekday; the days a window starts on
+	start int
+	end   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parse reads a schedule in loc (UTC if nil). An empty spec yields an always-open schedule.
+func Parse(spec string, loc *time.Location) (*Schedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := &Schedule{location: loc}
+
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		w, err := parseWindow(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid acceptance window %q: %w", part, err)
+		}
+		s.windows = append(s.windows, w)
+	}
+
+	return s, nil
+}
+
+func parseWindow(spec string) (window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 2 {
+		return window{}, fmt.Errorf(`expected "<days> <HH:MM>-<HH:MM>"`)
+	}
+
+	var w window
+	if err := parseDays(fields[0], &w.days); err != nil {
+		return window{}, err
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return window{}, fmt.Errorf("expected a time range like 09:00-17:00")
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return window{}, err
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return window{}, err
+	}
+	if w.start == w.end {
+		return window{}, fmt.Errorf("window is empty")
+	}
+
+	return w, nil
+}
+
+func parseDays(spec string, days *[7]bool) error {
+	if spec == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// Ranges may wrap around the week, e.g. Fri-Mon
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses HH:MM into minutes after midnight; 24:00 is the end of the day
+func parseClock(spec string) (int, error) {
+	hh, mm, ok := strings.Cut(spec, ":")
+	hours, errH := strconv.Atoi(hh)
+	minutes, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || minutes < 0 || minutes > 59 || hours < 0 || hours > 24 ||
+		(hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q", spec)
+	}
+	return hours*60 + minutes, nil
+}
+
+// IsAlwaysOpen reports whether the schedule places no restriction
+func (s *Schedule) IsAlwaysOpen() bool {
+	return s == nil || len(s.windows) == 0
+}
+
+// Open reports whether t falls inside one of the schedule's windows
+func (s *Schedule) Open(t time.Time) bool {
+	if s.IsAlwaysOpen() {
+		return true
+	}
+
+	t = t.In(s.location)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range s.windows {
+		if w.start < w.end {
+			if w.days[today] && minute >= w.start && minute < w.end {
+				return true
+			}
+			continue
+		}
+		// Overnight: the evening part of today's window or the morning part of yesterday's
+		if (w.days[today] && minute >= w.start) || (w.days[yesterday] && minute < w.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t if the schedule is open at t, otherwise the start of the next window
+func (s *Schedule) NextOpen(t time.Time) time.Time {
+	if s.Open(t) {
+		return t
+	}
+
+	local := t.In(s.location)
+
+	var next time.Time
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, s.location)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return next
+}
+
+// String returns the number of windows and their time zone, for logging
+func (s *Schedule) String() string {
+	if s.IsAlwaysOpen() {
+		return "always open"
+	}
+	return fmt.Sprintf("%d window(s) in %s", len(s.windows), s.location)
+}
@@ -0,0 +1,99 @@
+package acceptance
+
+import (
+	"testing"
+	"time"
+)
+
+// 2025-06-02 is a Monday
+func at(day int, hour, minute int) time.Time {
+	return time.Date(2025, time.June, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse_RejectsMalformedWindows(t *testing.T) {
+	for _, spec := range []string{
+		"Mon-Fri",
+		"Mon-Fri 9-17",
+		"Funday 09:00-17:00",
+		"Mon 09:00-09:00",
+		"Mon 25:00-26:00",
+		"Mon 09:00-24:30",
+	} {
+		if _, err := Parse(spec, nil); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestSchedule_EmptyIsAlwaysOpen(t *testing.T) {
+	s, err := Parse(" ; ", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var unset *Schedule
+	for _, schedule := range []*Schedule{s, unset} {
+		if !schedule.IsAlwaysOpen() || !schedule.Open(at(2, 3, 0)) {
+			t.Errorf("expected %v to be always open", schedule)
+		}
+	}
+}
+
+func TestSchedule_Open(t *testing.T) {
+	s, err := Parse("Mon-Fri 18:00-08:00; Sat,Sun 00:00-24:00", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{"weekday business hours", at(3, 12, 0), false},
+		{"weekday evening", at(3, 18, 0), true},
+		{"weekday early morning", at(3, 7, 59), true},
+		{"window end is exclusive", at(3, 8, 0), false},
+		{"monday morning follows no overnight window", at(2, 3, 0), false},
+		{"tuesday morning runs on from monday", at(3, 3, 0), true},
+		{"saturday midday", at(7, 12, 0), true},
+		{"saturday morning runs on from friday", at(7, 7, 0), true},
+	}
+	for _, tt := range tests {
+		if got := s.Open(tt.at); got != tt.open {
+			t.Errorf("%s: Open(%s) = %v, want %v", tt.name, tt.at, got, tt.open)
+		}
+	}
+}
+
+func TestSchedule_NextOpen(t *testing.T) {
+	s, err := Parse("Mon-Fri 18:00-20:00", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got := s.NextOpen(at(3, 19, 0)); !got.Equal(at(3, 19, 0)) {
+		t.Errorf("inside a window NextOpen = %s, want now", got)
+	}
+	if got := s.NextOpen(at(3, 12, 0)); !got.Equal(at(3, 18, 0)) {
+		t.Errorf("before the window NextOpen = %s, want same day 18:00", got)
+	}
+	// Friday evening after the window: the next one is Monday
+	if got := s.NextOpen(at(6, 21, 0)); !got.Equal(at(9, 18, 0)) {
+		t.Errorf("after friday's window NextOpen = %s, want monday 18:00", got)
+	}
+}
+
+func TestSchedule_TimeZone(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s, err := Parse("* 09:00-17:00", loc)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// 08:00 UTC is 10:00 in the schedule's zone
+	if !s.Open(at(3, 8, 0)) {
+		t.Error("expected the window to be read in the schedule's time zone")
+	}
+	if s.Open(at(3, 16, 0)) {
+		t.Error("expected 18:00 local to be outside the window")
+	}
+}
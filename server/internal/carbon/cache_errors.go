@@ -0,0 +1,78 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cache operations, used as the "operation" label of CacheErrorsTotal
+const (
+	cacheOpGetIntensity = "get_intensity"
+	cacheOpGetForecast  = "get_forecast"
+	cacheOpSave         = "save"
+)
+
+// CacheErrorsTotal counts carbon cache failures by operation. Cache misses are not
+// errors and are never counted. It is registered by the metrics collector.
+var CacheErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "karbos_carbon_cache_errors_total",
+	Help: "Carbon cache operations that failed (misses are not counted)",
+}, []string{"operation"})
+
+// ErrCacheUnavailable is returned instead of calling the carbon API once the cache
+// has failed too many times in a row (see CarbonFetcher.SetCacheFailFast)
+var ErrCacheUnavailable = errors.New("carbon cache unavailable")
+
+// SetCacheFailFast makes lookups fail with ErrCacheUnavailable once the cache has
+// failed n times in a row, rather than sending every request to the carbon API while
+// the cache is down. The cache is still tried on every lookup, and one success
+// resets the count. Zero (the default) always falls back to the API.
+func (f *CarbonFetcher) SetCacheFailFast(n int) {
+	if n < 0 {
+		n = 0
+	}
+	f.cacheFailFastAfter = int64(n)
+}
+
+// cacheFailed records a failed cache read and decides whether the lookup can continue
+// to the API. It returns nil to continue, ctx's error when the caller has given up (not
+// the cache's fault, so nothing is recorded), or ErrCacheUnavailable when failing fast.
+func (f *CarbonFetcher) cacheFailed(ctx context.Context, op, region string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	streak := atomic.AddInt64(&f.cacheFailures, 1)
+	recordCacheError(op, region, streak, err)
+
+	if f.cacheFailFastAfter > 0 && streak >= f.cacheFailFastAfter {
+		return fmt.Errorf("%w after %d consecutive errors: %v", ErrCacheUnavailable, streak, err)
+	}
+	return nil
+}
+
+// cacheSucceeded resets the consecutive cache read error count
+func (f *CarbonFetcher) cacheSucceeded() {
+	atomic.StoreInt64(&f.cacheFailures, 0)
+}
+
+// cacheSaveFailed records a failed cache write. Writes never fail a request and
+// don't affect fail-fast, which only looks at reads.
+func (f *CarbonFetcher) cacheSaveFailed(ctx context.Context, region string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	recordCacheError(cacheOpSave, region, atomic.LoadInt64(&f.cacheFailures), err)
+}
+
+// recordCacheError counts a cache error and logs it with its context
+func recordCacheError(op, region string, streak int64, err error) {
+	CacheErrorsTotal.WithLabelValues(op).Inc()
+	slog.Warn("Carbon cache error", "op", op, logging.KeyRegion, region, "consecutive_read_errors", streak, logging.Err(err))
+}
@@ -2,8 +2,14 @@ package carbon
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 )
 
 // CarbonCacheEntry represents cached carbon data
@@ -14,6 +20,23 @@ type CarbonCacheEntry struct {
 	Unit      string
 	FetchedAt time.Time
 	ExpiresAt time.Time
+
+	FossilFuel      float64 // Percentage (0 when unknown)
+	RenewableEnergy float64 // Percentage (0 when unknown)
+	IntensityScale  IntensityScale
+}
+
+// toIntensity converts a cache entry back to carbon intensity data
+func (e *CarbonCacheEntry) toIntensity() CarbonIntensity {
+	return CarbonIntensity{
+		Region:          e.Region,
+		Timestamp:       e.Timestamp,
+		Intensity:       e.Intensity,
+		Unit:            e.Unit,
+		FossilFuel:      e.FossilFuel,
+		RenewableEnergy: e.RenewableEnergy,
+		IntensityScale:  e.IntensityScale,
+	}
 }
 
 // CacheRepository interface for carbon cache operations
@@ -31,6 +54,11 @@ type CarbonFetcher struct {
 	cache       CacheRepository
 	cacheTTL    time.Duration
 	maxCacheAge time.Duration
+
+	batchConcurrency int // Max concurrent API calls in GetForecastsBatch
+
+	cacheFailures      int64 // Consecutive cache read errors (accessed atomically)
+	cacheFailFastAfter int64 // Consecutive cache read errors before lookups fail fast (0 = never)
 }
 
 // NewCarbonFetcher creates a new carbon intensity fetcher with caching
@@ -43,6 +71,8 @@ func NewCarbonFetcher(service CarbonService, cache CacheRepository, cacheTTL tim
 		cache:       cache,
 		cacheTTL:    cacheTTL,
 		maxCacheAge: cacheTTL,
+
+		batchConcurrency: 4,
 	}
 }
 
@@ -51,23 +81,25 @@ func NewCarbonFetcher(service CarbonService, cache CacheRepository, cacheTTL tim
 // 2. If cache hit and fresh (< 1 hour), return cached data
 // 3. If cache miss or stale, fetch from API
 // 4. Save API response to cache
+//
+// A cache miss (no entry) goes straight to the API. A broken cache is logged and
+// counted in CacheErrorsTotal, then also falls back to the API unless fail-fast is on.
 func (f *CarbonFetcher) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
 	// Step 1: Try cache first
 	cachedEntry, err := f.cache.GetCarbonIntensity(ctx, region, timestamp)
 	if err != nil {
-		// Log error but continue to API fallback
-		fmt.Printf("Cache error (continuing to API): %v\n", err)
+		if err := f.cacheFailed(ctx, cacheOpGetIntensity, region, err); err != nil {
+			return nil, err
+		}
+	} else {
+		f.cacheSucceeded()
 	}
 
 	// Step 2: Check cache freshness
 	if cachedEntry != nil && f.cache.IsCacheFresh(cachedEntry, f.maxCacheAge) {
 		// Cache hit with fresh data
-		return &CarbonIntensity{
-			Region:    cachedEntry.Region,
-			Timestamp: cachedEntry.Timestamp,
-			Intensity: cachedEntry.Intensity,
-			Unit:      cachedEntry.Unit,
-		}, nil
+		intensity := cachedEntry.toIntensity()
+		return &intensity, nil
 	}
 
 	// Step 3: Cache miss or stale - fetch from API
@@ -75,21 +107,16 @@ func (f *CarbonFetcher) GetCarbonIntensity(ctx context.Context, region string, t
 	if err != nil {
 		// If API fails but we have stale cache data, use it as fallback
 		if cachedEntry != nil {
-			fmt.Printf("API error (using stale cache): %v\n", err)
-			return &CarbonIntensity{
-				Region:    cachedEntry.Region,
-				Timestamp: cachedEntry.Timestamp,
-				Intensity: cachedEntry.Intensity,
-				Unit:      cachedEntry.Unit,
-			}, nil
+			slog.Warn("Carbon API error, using stale cache", logging.KeyRegion, region, logging.Err(err))
+			intensity := cachedEntry.toIntensity()
+			return &intensity, nil
 		}
 		return nil, fmt.Errorf("failed to fetch carbon intensity from API: %w", err)
 	}
 
-	// Step 4: Save fresh data to cache
+	// Step 4: Save fresh data to cache (a failed save doesn't fail the request)
 	if err := f.cache.SaveCarbonIntensity(ctx, apiData, f.cacheTTL); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to save to cache: %v\n", err)
+		f.cacheSaveFailed(ctx, region, err)
 	}
 
 	return apiData, nil
@@ -97,69 +124,221 @@ func (f *CarbonFetcher) GetCarbonIntensity(ctx context.Context, region string, t
 
 // GetCarbonForecast retrieves carbon intensity forecast with cache-first logic
 func (f *CarbonFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
-	// Step 1: Try cache first
+	// Step 1 & 2: Try cache first and check coverage/freshness
+	cached, cachedEntries, hit, err := f.lookupCachedForecast(ctx, region, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if hit {
+		return cached, nil
+	}
+
+	// Step 3 & 4: Cache miss or insufficient coverage - fetch from API
+	return f.fetchForecast(ctx, region, startTime, endTime, cachedEntries)
+}
+
+// lookupCachedForecast checks the cache for a forecast covering the requested range.
+// It returns the cached forecast and true when the cache has sufficient fresh coverage,
+// otherwise the raw cache entries (possibly empty) for use as a fallback.
+// An error means the lookup must stop here: the caller's context is done, or the
+// cache is failing fast.
+func (f *CarbonFetcher) lookupCachedForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, []CarbonCacheEntry, bool, error) {
 	cachedEntries, err := f.cache.GetCarbonForecast(ctx, region, startTime, endTime)
 	if err != nil {
-		fmt.Printf("Cache error (continuing to API): %v\n", err)
+		if err := f.cacheFailed(ctx, cacheOpGetForecast, region, err); err != nil {
+			return nil, nil, false, err
+		}
+		cachedEntries = nil
+	} else {
+		f.cacheSucceeded()
 	}
 
-	// Step 2: Check if cache has sufficient coverage
 	// We need at least 80% coverage of the requested time range
 	requiredDataPoints := int(endTime.Sub(startTime).Hours())
-	if len(cachedEntries) >= int(float64(requiredDataPoints)*0.8) {
-		// Check if all cached entries are fresh
-		allFresh := true
-		for _, entry := range cachedEntries {
-			if !f.cache.IsCacheFresh(&CarbonCacheEntry{
-				FetchedAt: entry.FetchedAt,
-				ExpiresAt: entry.ExpiresAt,
-			}, f.maxCacheAge) {
-				allFresh = false
-				break
-			}
-		}
+	if len(cachedEntries) == 0 || len(cachedEntries) < int(float64(requiredDataPoints)*0.8) {
+		return nil, cachedEntries, false, nil
+	}
 
-		if allFresh {
-			// Convert cache entries to CarbonIntensity
-			var result []CarbonIntensity
-			for _, entry := range cachedEntries {
-				result = append(result, CarbonIntensity{
-					Region:    entry.Region,
-					Timestamp: entry.Timestamp,
-					Intensity: entry.Intensity,
-					Unit:      entry.Unit,
-				})
-			}
-			return result, nil
+	// Check if all cached entries are fresh
+	for _, entry := range cachedEntries {
+		if !f.cache.IsCacheFresh(&CarbonCacheEntry{
+			FetchedAt: entry.FetchedAt,
+			ExpiresAt: entry.ExpiresAt,
+		}, f.maxCacheAge) {
+			return nil, cachedEntries, false, nil
 		}
 	}
 
-	// Step 3: Cache miss or insufficient coverage - fetch from API
+	return cacheEntriesToIntensities(cachedEntries), cachedEntries, true, nil
+}
+
+// fetchForecast retrieves a forecast from the API and saves it to the cache.
+// If the API fails, any partial cache data is returned as a fallback.
+func (f *CarbonFetcher) fetchForecast(ctx context.Context, region string, startTime, endTime time.Time, cachedEntries []CarbonCacheEntry) ([]CarbonIntensity, error) {
+	forecast, fresh, err := f.fetchForecastUncached(ctx, region, startTime, endTime, cachedEntries)
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		f.saveForecast(ctx, forecast)
+	}
+	return forecast, nil
+}
+
+// fetchForecastUncached retrieves a forecast from the API without saving it.
+// fresh is false when the API failed and partial cache data was returned instead.
+func (f *CarbonFetcher) fetchForecastUncached(ctx context.Context, region string, startTime, endTime time.Time, cachedEntries []CarbonCacheEntry) ([]CarbonIntensity, bool, error) {
 	apiData, err := f.service.GetCarbonForecast(ctx, region, startTime, endTime)
 	if err != nil {
 		// If API fails but we have some cache data, use it as fallback
 		if len(cachedEntries) > 0 {
-			fmt.Printf("API error (using partial cache): %v\n", err)
-			var result []CarbonIntensity
-			for _, entry := range cachedEntries {
-				result = append(result, CarbonIntensity{
-					Region:    entry.Region,
-					Timestamp: entry.Timestamp,
-					Intensity: entry.Intensity,
-					Unit:      entry.Unit,
-				})
+			slog.Warn("Carbon API error, using partial cache", logging.KeyRegion, region, logging.Err(err))
+			return cacheEntriesToIntensities(cachedEntries), false, nil
+		}
+		return nil, false, fmt.Errorf("failed to fetch carbon forecast from API: %w", err)
+	}
+
+	// Drop duplicate timestamps (revised points) so they aren't cached twice
+	return DedupeForecast(apiData), true, nil
+}
+
+// saveForecast bulk saves fresh API data to the cache
+func (f *CarbonFetcher) saveForecast(ctx context.Context, data []CarbonIntensity) {
+	if len(data) == 0 {
+		return
+	}
+	if err := f.cache.BulkSaveCarbonIntensities(ctx, data, f.cacheTTL); err != nil {
+		region := data[0].Region
+		for _, point := range data {
+			if point.Region != region {
+				region = "multiple"
+				break
 			}
-			return result, nil
 		}
-		return nil, fmt.Errorf("failed to fetch carbon forecast from API: %w", err)
+		f.cacheSaveFailed(ctx, region, err)
 	}
+}
 
-	// Step 4: Bulk save fresh data to cache
-	if err := f.cache.BulkSaveCarbonIntensities(ctx, apiData, f.cacheTTL); err != nil {
-		fmt.Printf("Failed to save forecast to cache: %v\n", err)
+// GetCarbonForecastBatch retrieves forecasts for several regions at once, for
+// scheduling decisions that compare regions. Cache hits are served first, the
+// misses are fetched from the API concurrently, and all fresh API data is saved
+// to the cache in a single bulk write. Regions that failed are left out of the
+// map and reported together in the returned error; the other regions' forecasts
+// are still returned.
+func (f *CarbonFetcher) GetCarbonForecastBatch(ctx context.Context, regions []string, startTime, endTime time.Time) (map[string][]CarbonIntensity, error) {
+	results, errs := f.GetForecastsBatch(ctx, regions, startTime, endTime)
+	if len(errs) == 0 {
+		return results, nil
 	}
 
-	return apiData, nil
+	failed := make([]string, 0, len(errs))
+	for region := range errs {
+		failed = append(failed, region)
+	}
+	sort.Strings(failed)
+
+	joined := make([]error, 0, len(failed))
+	for _, region := range failed {
+		joined = append(joined, fmt.Errorf("%s: %w", region, errs[region]))
+	}
+	return results, errors.Join(joined...)
+}
+
+// GetForecastsBatch retrieves forecasts for several regions at once.
+// Regions with fresh cache coverage are served from the cache; the remaining
+// regions are fetched from the API concurrently (at most batchConcurrency at a time)
+// and saved to the cache together once every fetch has finished.
+// A failure for one region is reported in the returned error map and does not
+// affect the results of the other regions.
+func (f *CarbonFetcher) GetForecastsBatch(ctx context.Context, regions []string, startTime, endTime time.Time) (map[string][]CarbonIntensity, map[string]error) {
+	results := make(map[string][]CarbonIntensity, len(regions))
+	errs := make(map[string]error)
+
+	// Step 1: Serve cache hits and collect misses
+	misses := make(map[string][]CarbonCacheEntry)
+	for _, region := range regions {
+		if _, seen := results[region]; seen {
+			continue
+		}
+		if _, seen := misses[region]; seen {
+			continue
+		}
+
+		cached, cachedEntries, hit, err := f.lookupCachedForecast(ctx, region, startTime, endTime)
+		if err != nil {
+			errs[region] = err
+			continue
+		}
+		if hit {
+			results[region] = cached
+			continue
+		}
+		misses[region] = cachedEntries
+	}
+
+	if len(misses) == 0 {
+		return results, errs
+	}
+
+	// Step 2: Fetch misses concurrently with a bounded number of in-flight API calls
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var toSave []CarbonIntensity
+	sem := make(chan struct{}, f.batchConcurrency)
+
+	for region, cachedEntries := range misses {
+		wg.Add(1)
+		go func(region string, cachedEntries []CarbonCacheEntry) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				errs[region] = ctx.Err()
+				mu.Unlock()
+				return
+			}
+
+			forecast, fresh, err := f.fetchForecastUncached(ctx, region, startTime, endTime, cachedEntries)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[region] = err
+				return
+			}
+			results[region] = forecast
+			if fresh {
+				toSave = append(toSave, forecast...)
+			}
+		}(region, cachedEntries)
+	}
+
+	wg.Wait()
+
+	// Step 3: Save every region's fresh data in one round trip
+	f.saveForecast(ctx, toSave)
+
+	return results, errs
+}
+
+// SetBatchConcurrency updates the maximum number of concurrent API calls made by GetForecastsBatch
+func (f *CarbonFetcher) SetBatchConcurrency(n int) {
+	if n <= 0 {
+		n = 1
+	}
+	f.batchConcurrency = n
+}
+
+// cacheEntriesToIntensities converts cache entries to CarbonIntensity values
+func cacheEntriesToIntensities(entries []CarbonCacheEntry) []CarbonIntensity {
+	var result []CarbonIntensity
+	for _, entry := range entries {
+		result = append(result, entry.toIntensity())
+	}
+	return result
 }
 
 // GetCurrentCarbonIntensity is a convenience method to get current carbon intensity
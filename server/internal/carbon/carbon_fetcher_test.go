@@ -0,0 +1,382 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeCarbonService records API calls per region
+type fakeCarbonService struct {
+	mu        sync.Mutex
+	calls     map[string]int
+	failFor   map[string]bool
+	intensity float64
+	forecast  []CarbonIntensity // Returned verbatim by GetCarbonForecast when set
+}
+
+func newFakeCarbonService() *fakeCarbonService {
+	return &fakeCarbonService{
+		calls:     make(map[string]int),
+		failFor:   make(map[string]bool),
+		intensity: 250,
+	}
+}
+
+func (s *fakeCarbonService) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
+	s.mu.Lock()
+	s.calls[region]++
+	s.mu.Unlock()
+
+	if s.failFor[region] {
+		return nil, errors.New("provider unavailable")
+	}
+	return &CarbonIntensity{Region: region, Timestamp: timestamp, Intensity: s.intensity, Unit: "gCO2eq/kWh"}, nil
+}
+
+func (s *fakeCarbonService) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return nil, ErrHistoryUnsupported
+}
+
+func (s *fakeCarbonService) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	s.mu.Lock()
+	s.calls[region]++
+	s.mu.Unlock()
+
+	if s.failFor[region] {
+		return nil, errors.New("provider unavailable")
+	}
+	if s.forecast != nil {
+		return s.forecast, nil
+	}
+
+	var forecast []CarbonIntensity
+	for ts := startTime; ts.Before(endTime); ts = ts.Add(time.Hour) {
+		forecast = append(forecast, CarbonIntensity{Region: region, Timestamp: ts, Intensity: s.intensity, Unit: "gCO2eq/kWh"})
+	}
+	return forecast, nil
+}
+
+func (s *fakeCarbonService) callCount(region string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[region]
+}
+
+// fakeCache serves fixed entries per region and records saves
+type fakeCache struct {
+	mu      sync.Mutex
+	entries map[string][]CarbonCacheEntry
+	saved   map[string]int
+
+	bulkSaves int   // BulkSaveCarbonIntensities calls
+	readErr   error // Returned by every read when set, as a broken cache would
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		entries: make(map[string][]CarbonCacheEntry),
+		saved:   make(map[string]int),
+	}
+}
+
+func (c *fakeCache) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonCacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	if entries := c.entries[region]; len(entries) > 0 {
+		return &entries[0], nil
+	}
+	return nil, nil
+}
+
+func (c *fakeCache) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonCacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	return c.entries[region], nil
+}
+
+func (c *fakeCache) SaveCarbonIntensity(ctx context.Context, data *CarbonIntensity, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saved[data.Region]++
+	return nil
+}
+
+func (c *fakeCache) BulkSaveCarbonIntensities(ctx context.Context, data []CarbonIntensity, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bulkSaves++
+	for _, d := range data {
+		c.saved[d.Region]++
+	}
+	return nil
+}
+
+func (c *fakeCache) IsCacheFresh(entry *CarbonCacheEntry, maxAge time.Duration) bool {
+	return time.Since(entry.FetchedAt) < maxAge
+}
+
+// seedForecast fills the fake cache with fresh hourly entries for a region
+func (c *fakeCache) seedForecast(region string, startTime time.Time, hours int, intensity float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < hours; i++ {
+		c.entries[region] = append(c.entries[region], CarbonCacheEntry{
+			Region:    region,
+			Timestamp: startTime.Add(time.Duration(i) * time.Hour),
+			Intensity: intensity,
+			Unit:      "gCO2eq/kWh",
+			FetchedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+	}
+}
+
+func TestCarbonFetcher_GetForecastsBatch_OnlyMissesHitAPI(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	end := start.Add(6 * time.Hour)
+
+	service := newFakeCarbonService()
+	cache := newFakeCache()
+	cache.seedForecast("EU-NORTH", start, 6, 90)
+	cache.seedForecast("US-WEST", start, 6, 180)
+
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	fetcher.SetBatchConcurrency(2)
+
+	regions := []string{"EU-NORTH", "US-WEST", "US-EAST", "ASIA-EAST", "EU-NORTH"}
+	results, errs := fetcher.GetForecastsBatch(context.Background(), regions, start, end)
+
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected results for 4 distinct regions, got %d", len(results))
+	}
+
+	for _, region := range []string{"EU-NORTH", "US-WEST"} {
+		if n := service.callCount(region); n != 0 {
+			t.Errorf("cached region %s should not hit the API, got %d calls", region, n)
+		}
+	}
+	for _, region := range []string{"US-EAST", "ASIA-EAST"} {
+		if n := service.callCount(region); n != 1 {
+			t.Errorf("missed region %s should hit the API exactly once, got %d calls", region, n)
+		}
+		if cache.saved[region] == 0 {
+			t.Errorf("missed region %s should be saved to the cache", region)
+		}
+	}
+
+	if got := results["EU-NORTH"][0].Intensity; got != 90 {
+		t.Errorf("expected cached intensity 90 for EU-NORTH, got %.1f", got)
+	}
+	if got := results["US-EAST"][0].Intensity; got != 250 {
+		t.Errorf("expected API intensity 250 for US-EAST, got %.1f", got)
+	}
+}
+
+func TestCarbonFetcher_GetForecastsBatch_IsolatesRegionErrors(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	end := start.Add(3 * time.Hour)
+
+	service := newFakeCarbonService()
+	service.failFor["AF-SOUTH"] = true
+
+	fetcher := NewCarbonFetcher(service, newFakeCache(), time.Hour)
+
+	results, errs := fetcher.GetForecastsBatch(context.Background(), []string{"AF-SOUTH", "EU-WEST"}, start, end)
+
+	if errs["AF-SOUTH"] == nil {
+		t.Error("expected an error for AF-SOUTH")
+	}
+	if _, ok := results["AF-SOUTH"]; ok {
+		t.Error("failed region should not have a result")
+	}
+	if len(results["EU-WEST"]) == 0 {
+		t.Error("EU-WEST should still return a forecast when another region fails")
+	}
+}
+
+func TestCarbonFetcher_GetCarbonForecastBatch_SingleBulkSave(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	end := start.Add(4 * time.Hour)
+
+	service := newFakeCarbonService()
+	cache := newFakeCache()
+	cache.seedForecast("EU-NORTH", start, 4, 90)
+
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	results, err := fetcher.GetCarbonForecastBatch(context.Background(), []string{"EU-NORTH", "US-EAST", "US-WEST", "ASIA-EAST"}, start, end)
+	if err != nil {
+		t.Fatalf("GetCarbonForecastBatch returned error: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected forecasts for 4 regions, got %d", len(results))
+	}
+
+	calls := 0
+	for _, region := range []string{"EU-NORTH", "US-EAST", "US-WEST", "ASIA-EAST"} {
+		calls += service.callCount(region)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 API calls for the cache misses, got %d", calls)
+	}
+	if cache.bulkSaves != 1 {
+		t.Errorf("expected the misses to be saved in one bulk write, got %d", cache.bulkSaves)
+	}
+	if cache.saved["US-WEST"] != 4 || cache.saved["EU-NORTH"] != 0 {
+		t.Errorf("expected only fetched regions to be saved, got %v", cache.saved)
+	}
+}
+
+func TestCarbonFetcher_GetCarbonForecastBatch_ReportsFailedRegions(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+
+	service := newFakeCarbonService()
+	service.failFor["AF-SOUTH"] = true
+	cache := newFakeCache()
+
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	results, err := fetcher.GetCarbonForecastBatch(context.Background(), []string{"AF-SOUTH", "EU-WEST"}, start, start.Add(2*time.Hour))
+
+	if err == nil || !strings.Contains(err.Error(), "AF-SOUTH") {
+		t.Errorf("expected an error naming AF-SOUTH, got %v", err)
+	}
+	if len(results["EU-WEST"]) == 0 {
+		t.Error("expected EU-WEST's forecast alongside the error")
+	}
+	if cache.saved["AF-SOUTH"] != 0 || cache.saved["EU-WEST"] == 0 {
+		t.Errorf("expected only EU-WEST to be cached, got %v", cache.saved)
+	}
+}
+
+func TestCarbonFetcher_GetCarbonForecast_DedupesBeforeCaching(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	service := newFakeCarbonService()
+	service.forecast = []CarbonIntensity{
+		{Region: "US-EAST", Timestamp: start, Intensity: 300},
+		{Region: "US-EAST", Timestamp: start.Add(time.Hour), Intensity: 500},
+		{Region: "US-EAST", Timestamp: start.Add(time.Hour), Intensity: 200}, // revised point
+	}
+	cache := newFakeCache()
+
+	forecast, err := NewCarbonFetcher(service, cache, time.Hour).GetCarbonForecast(context.Background(), "US-EAST", start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCarbonForecast returned error: %v", err)
+	}
+
+	if len(forecast) != 2 {
+		t.Fatalf("expected 2 points after dedupe, got %d", len(forecast))
+	}
+	if forecast[1].Intensity != 200 {
+		t.Errorf("expected the revised value 200 to be kept, got %.1f", forecast[1].Intensity)
+	}
+	if cache.saved["US-EAST"] != 2 {
+		t.Errorf("expected 2 cached points, got %d", cache.saved["US-EAST"])
+	}
+}
+
+// cacheErrorCount reads the cache error counter for an operation
+func cacheErrorCount(t *testing.T, op string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := CacheErrorsTotal.WithLabelValues(op).Write(&m); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestCarbonFetcher_CacheMissIsNotAnError(t *testing.T) {
+	service := newFakeCarbonService()
+	fetcher := NewCarbonFetcher(service, newFakeCache(), time.Hour)
+	before := cacheErrorCount(t, cacheOpGetIntensity)
+
+	if _, err := fetcher.GetCarbonIntensity(context.Background(), "DE", time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cacheErrorCount(t, cacheOpGetIntensity) - before; got != 0 {
+		t.Errorf("cache errors = %v, want 0 for a miss", got)
+	}
+	if service.callCount("DE") != 1 {
+		t.Errorf("API calls = %d, want 1", service.callCount("DE"))
+	}
+}
+
+func TestCarbonFetcher_BrokenCacheIsCountedAndFallsBackToAPI(t *testing.T) {
+	service := newFakeCarbonService()
+	cache := newFakeCache()
+	cache.readErr = errors.New(`relation "carbon_cache" does not exist`)
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	before := cacheErrorCount(t, cacheOpGetForecast)
+
+	start := time.Now().Truncate(time.Hour)
+	forecast, err := fetcher.GetCarbonForecast(context.Background(), "DE", start, start.Add(4*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(forecast) != 4 {
+		t.Errorf("forecast points = %d, want 4 from the API", len(forecast))
+	}
+	if got := cacheErrorCount(t, cacheOpGetForecast) - before; got != 1 {
+		t.Errorf("cache errors = %v, want 1", got)
+	}
+}
+
+func TestCarbonFetcher_FailsFastWhileCacheIsDown(t *testing.T) {
+	service := newFakeCarbonService()
+	cache := newFakeCache()
+	cache.readErr = errors.New("connection refused")
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	fetcher.SetCacheFailFast(2)
+	ctx := context.Background()
+
+	if _, err := fetcher.GetCarbonIntensity(ctx, "DE", time.Now()); err != nil {
+		t.Fatalf("first error should fall back to the API, got %v", err)
+	}
+	if _, err := fetcher.GetCarbonIntensity(ctx, "DE", time.Now()); !errors.Is(err, ErrCacheUnavailable) {
+		t.Fatalf("error = %v, want ErrCacheUnavailable", err)
+	}
+	if service.callCount("DE") != 1 {
+		t.Errorf("API calls = %d, want 1 (no calls once failing fast)", service.callCount("DE"))
+	}
+
+	// One good read resets the streak
+	cache.mu.Lock()
+	cache.readErr = nil
+	cache.mu.Unlock()
+	if _, err := fetcher.GetCarbonIntensity(ctx, "DE", time.Now()); err != nil {
+		t.Fatalf("unexpected error after recovery: %v", err)
+	}
+}
+
+func TestCarbonFetcher_CancelledContextIsNotACacheError(t *testing.T) {
+	service := newFakeCarbonService()
+	cache := newFakeCache()
+	cache.readErr = context.Canceled
+	fetcher := NewCarbonFetcher(service, cache, time.Hour)
+	before := cacheErrorCount(t, cacheOpGetIntensity)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.GetCarbonIntensity(ctx, "DE", time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if got := cacheErrorCount(t, cacheOpGetIntensity) - before; got != 0 {
+		t.Errorf("cache errors = %v, want 0 when the caller gave up", got)
+	}
+	if service.callCount("DE") != 0 {
+		t.Errorf("API calls = %d, want 0 with a cancelled context", service.callCount("DE"))
+	}
+}
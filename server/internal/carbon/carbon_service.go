@@ -3,9 +3,14 @@ package carbon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,16 +18,121 @@ import (
 type CarbonService interface {
 	GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error)
 	GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error)
+	// GetCarbonHistory returns measured intensity between startTime and endTime, or
+	// ErrHistoryUnsupported when the provider has no history
+	GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error)
 }
 
+// Errors returned by carbon API clients, classified by response status
+var (
+	ErrCarbonAuth         = errors.New("carbon API rejected credentials")
+	ErrCarbonZoneNotFound = errors.New("carbon API zone not found")
+	ErrCarbonRateLimited  = errors.New("carbon API rate limit exceeded")
+	ErrHistoryUnsupported = errors.New("carbon provider has no intensity history")
+)
+
+// RateLimitError is returned for HTTP 429 responses. It matches ErrCarbonRateLimited
+// with errors.Is and carries the server's Retry-After hint (zero when absent).
+type RateLimitError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v (status %d, retry after %s): %s", ErrCarbonRateLimited, e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("%v (status %d): %s", ErrCarbonRateLimited, e.StatusCode, e.Body)
+}
+
+// Is makes errors.Is(err, ErrCarbonRateLimited) match
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrCarbonRateLimited
+}
+
+// apiStatusError wraps a non-200 response in the matching typed error so callers can
+// tell configuration problems (bad key, unknown zone) from transient outages
+func apiStatusError(resp *http.Response, body []byte) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w (status %d): %s", ErrCarbonAuth, resp.StatusCode, string(body))
+	case http.StatusNotFound:
+		return fmt.Errorf("%w (status %d): %s", ErrCarbonZoneNotFound, resp.StatusCode, string(body))
+	case http.StatusTooManyRequests:
+		return &RateLimitError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	default:
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds or as an
+// HTTP-date. It returns zero for a missing, malformed or already-passed value.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// newHTTPClient returns an HTTP client for a carbon API. Scheduling calls the provider
+// from many goroutines at once, so keep more idle connections per host than the default
+// transport's two and reuse them instead of dialing and handshaking for every request.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 20
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+}
+
+// IntensityScale says whether an intensity value is an absolute emission rate or only a
+// relative index that can be compared against itself but not converted to grams
+type IntensityScale string
+
+const (
+	IntensityScaleAbsolute IntensityScale = "absolute" // gCO2eq/kWh
+	IntensityScaleRelative IntensityScale = "relative" // 0-100 index, higher is dirtier (WattTime)
+)
+
 // CarbonIntensity represents carbon intensity data
 type CarbonIntensity struct {
 	Region          string    `json:"region"`
 	Timestamp       time.Time `json:"timestamp"`
-	Intensity       float64   `json:"intensity"`        // gCO2eq/kWh
+	Intensity       float64   `json:"intensity"`        // gCO2eq/kWh, or an index when IntensityScale is relative
 	Unit            string    `json:"unit"`             // "gCO2eq/kWh"
 	FossilFuel      float64   `json:"fossil_fuel"`      // Percentage
 	RenewableEnergy float64   `json:"renewable_energy"` // Percentage
+
+	IntensityScale IntensityScale `json:"intensity_scale,omitempty"` // Empty means absolute
+}
+
+// IsRelative reports whether Intensity is a relative index rather than gCO2eq/kWh
+func (c CarbonIntensity) IsRelative() bool {
+	return c.IntensityScale == IntensityScaleRelative
 }
 
 // ElectricityMapsClient implements CarbonService for ElectricityMaps API
@@ -38,11 +148,9 @@ func NewElectricityMapsClient(apiKey string, baseURL string) *ElectricityMapsCli
 		baseURL = "https://api.electricitymap.org/v3"
 	}
 	return &ElectricityMapsClient{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		httpClient: newHTTPClient(),
 	}
 }
 
@@ -66,6 +174,12 @@ type ElectricityMapsForecastPoint struct {
 	Datetime        string  `json:"datetime"`
 }
 
+// ElectricityMapsHistoryResponse structure for past intensity data
+type ElectricityMapsHistoryResponse struct {
+	Zone string                    `json:"zone"`
+	Data []ElectricityMapsResponse `json:"data"`
+}
+
 // GetCarbonIntensity retrieves current carbon intensity for a region
 func (c *ElectricityMapsClient) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
 	// ElectricityMaps API endpoint: /carbon-intensity/latest?zone={zone}
@@ -87,7 +201,7 @@ func (c *ElectricityMapsClient) GetCarbonIntensity(ctx context.Context, region s
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, apiStatusError(resp, body)
 	}
 
 	var apiResp ElectricityMapsResponse
@@ -132,7 +246,7 @@ func (c *ElectricityMapsClient) GetCarbonForecast(ctx context.Context, region st
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, apiStatusError(resp, body)
 	}
 
 	var apiResp ElectricityMapsForecastResponse
@@ -164,12 +278,71 @@ func (c *ElectricityMapsClient) GetCarbonForecast(ctx context.Context, region st
 	return result, nil
 }
 
+// GetCarbonHistory retrieves measured carbon intensity for a region over a past time range
+func (c *ElectricityMapsClient) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	// ElectricityMaps API endpoint: /carbon-intensity/past-range?zone={zone}&start={start}&end={end}
+	query := url.Values{}
+	query.Set("zone", region)
+	query.Set("start", startTime.UTC().Format(time.RFC3339))
+	query.Set("end", endTime.UTC().Format(time.RFC3339))
+	endpoint := fmt.Sprintf("%s/carbon-intensity/past-range?%s", c.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("auth-token", c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apiStatusError(resp, body)
+	}
+
+	var apiResp ElectricityMapsHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var result []CarbonIntensity
+	for _, point := range apiResp.Data {
+		parsedTime, err := time.Parse(time.RFC3339, point.Datetime)
+		if err != nil {
+			continue // Skip invalid timestamps
+		}
+
+		zone := point.Zone
+		if zone == "" {
+			zone = apiResp.Zone
+		}
+		result = append(result, CarbonIntensity{
+			Region:          zone,
+			Timestamp:       parsedTime,
+			Intensity:       point.CarbonIntensity,
+			Unit:            "gCO2eq/kWh",
+			RenewableEnergy: point.FossilFreePercentage,
+			FossilFuel:      100 - point.FossilFreePercentage,
+		})
+	}
+
+	return result, nil
+}
+
 // WattTimeClient implements CarbonService for WattTime API (alternative provider)
 type WattTimeClient struct {
-	username    string
-	password    string
-	baseURL     string
-	httpClient  *http.Client
+	username   string
+	password   string
+	baseURL    string
+	httpClient *http.Client
+
+	mu          sync.Mutex // Serializes logins; guards token and tokenExpiry
 	token       string
 	tokenExpiry time.Time
 }
@@ -180,67 +353,74 @@ func NewWattTimeClient(username, password, baseURL string) *WattTimeClient {
 		baseURL = "https://api2.watttime.org/v2"
 	}
 	return &WattTimeClient{
-		username: username,
-		password: password,
-		baseURL:  baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		username:   username,
+		password:   password,
+		baseURL:    baseURL,
+		httpClient: newHTTPClient(),
 	}
 }
 
-// authenticate retrieves an access token from WattTime API
-func (w *WattTimeClient) authenticate(ctx context.Context) error {
+// authenticate returns a valid WattTime access token, logging in when it has expired.
+// Concurrent callers wait for a single login rather than each starting their own.
+func (w *WattTimeClient) authenticate(ctx context.Context) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if w.token != "" && time.Now().Before(w.tokenExpiry) {
-		return nil // Token still valid
+		return w.token, nil // Token still valid
 	}
 
 	url := fmt.Sprintf("%s/login", w.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create auth request: %w", err)
+		return "", fmt.Errorf("failed to create auth request: %w", err)
 	}
 
 	req.SetBasicAuth(w.username, w.password)
 
 	resp, err := w.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to authenticate: %w", err)
+		return "", fmt.Errorf("failed to authenticate: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return "", apiStatusError(resp, body)
+		}
+		return "", fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var authResp struct {
 		Token string `json:"token"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return fmt.Errorf("failed to decode auth response: %w", err)
+		return "", fmt.Errorf("failed to decode auth response: %w", err)
 	}
 
 	w.token = authResp.Token
 	w.tokenExpiry = time.Now().Add(30 * time.Minute)
-	return nil
+	return w.token, nil
 }
 
 // GetCarbonIntensity retrieves current carbon intensity from WattTime
 func (w *WattTimeClient) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
-	if err := w.authenticate(ctx); err != nil {
+	token, err := w.authenticate(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	// WattTime uses "ba" (balancing authority) instead of zone
-	url := fmt.Sprintf("%s/index?ba=%s", w.baseURL, region)
+	// WattTime uses "ba" (balancing authority) instead of zone. style=all adds the
+	// absolute MOER on plans that include it.
+	url := fmt.Sprintf("%s/index?ba=%s&style=all", w.baseURL, region)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := w.httpClient.Do(req)
 	if err != nil {
@@ -250,13 +430,14 @@ func (w *WattTimeClient) GetCarbonIntensity(ctx context.Context, region string,
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, apiStatusError(resp, body)
 	}
 
 	var apiResp struct {
-		BA      string  `json:"ba"`
-		Percent float64 `json:"percent"` // 0-100 scale
-		Point   string  `json:"point_time"`
+		BA      string     `json:"ba"`
+		Percent flexFloat  `json:"percent"` // 0-100 scale
+		MOER    *flexFloat `json:"moer"`    // lbs CO2/MWh; absent without a MOER plan
+		Point   string     `json:"point_time"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -267,21 +448,14 @@ func (w *WattTimeClient) GetCarbonIntensity(ctx context.Context, region string,
 		parsedTime = time.Now()
 	}
 
-	// WattTime returns relative index (0-100), convert to approximate gCO2eq/kWh
-	// Assuming max intensity ~800 gCO2eq/kWh for scaling
-	intensity := (apiResp.Percent / 100.0) * 800.0
-
-	return &CarbonIntensity{
-		Region:    apiResp.BA,
-		Timestamp: parsedTime,
-		Intensity: intensity,
-		Unit:      "gCO2eq/kWh",
-	}, nil
+	result := wattTimeIntensity(apiResp.BA, parsedTime, float64(apiResp.Percent), apiResp.MOER)
+	return &result, nil
 }
 
 // GetCarbonForecast retrieves forecast data from WattTime
 func (w *WattTimeClient) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
-	if err := w.authenticate(ctx); err != nil {
+	token, err := w.authenticate(ctx)
+	if err != nil {
 		return nil, err
 	}
 
@@ -292,7 +466,7 @@ func (w *WattTimeClient) GetCarbonForecast(ctx context.Context, region string, s
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := w.httpClient.Do(req)
 	if err != nil {
@@ -302,13 +476,14 @@ func (w *WattTimeClient) GetCarbonForecast(ctx context.Context, region string, s
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, apiStatusError(resp, body)
 	}
 
 	var apiResp []struct {
-		BA      string  `json:"ba"`
-		Percent float64 `json:"percent"`
-		Point   string  `json:"point_time"`
+		BA      string     `json:"ba"`
+		Percent flexFloat  `json:"percent"`
+		Value   *flexFloat `json:"value"` // MOER in lbs CO2/MWh, when the plan includes it
+		Point   string     `json:"point_time"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -325,15 +500,53 @@ func (w *WattTimeClient) GetCarbonForecast(ctx context.Context, region string, s
 			continue
 		}
 
-		intensity := (point.Percent / 100.0) * 800.0
+		result = append(result, wattTimeIntensity(point.BA, parsedTime, float64(point.Percent), point.Value))
+	}
 
-		result = append(result, CarbonIntensity{
-			Region:    point.BA,
-			Timestamp: parsedTime,
-			Intensity: intensity,
+	return result, nil
+}
+
+// gramsPerKWhPerLbsPerMWh converts WattTime's lbs CO2/MWh to gCO2eq/kWh
+// (453.592 g per lb, 1000 kWh per MWh)
+const gramsPerKWhPerLbsPerMWh = 0.453592
+
+// wattTimeIntensity builds a data point from a WattTime response. An absolute MOER is
+// converted to gCO2eq/kWh so it compares directly with other providers; without one,
+// the 0-100 index can't be converted and is passed through marked as relative.
+func wattTimeIntensity(ba string, timestamp time.Time, percent float64, moer *flexFloat) CarbonIntensity {
+	if moer != nil {
+		return CarbonIntensity{
+			Region:    ba,
+			Timestamp: timestamp,
+			Intensity: float64(*moer) * gramsPerKWhPerLbsPerMWh,
 			Unit:      "gCO2eq/kWh",
-		})
+		}
 	}
 
-	return result, nil
+	return CarbonIntensity{
+		Region:         ba,
+		Timestamp:      timestamp,
+		Intensity:      percent,
+		Unit:           "percent",
+		IntensityScale: IntensityScaleRelative,
+	}
+}
+
+// flexFloat decodes a JSON number that WattTime sometimes sends as a string ("1104.00")
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+	*f = flexFloat(value)
+	return nil
+}
+
+// GetCarbonHistory is not supported for WattTime: its historical data needs a higher
+// plan, and its relative index couldn't be turned into grams of CO2 anyway
+func (w *WattTimeClient) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return nil, ErrHistoryUnsupported
 }
@@ -0,0 +1,294 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatusServer answers every request with the given status code
+func newStatusServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":"test"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestElectricityMapsClient_TypedErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr error
+	}{
+		{http.StatusUnauthorized, ErrCarbonAuth},
+		{http.StatusForbidden, ErrCarbonAuth},
+		{http.StatusNotFound, ErrCarbonZoneNotFound},
+		{http.StatusTooManyRequests, ErrCarbonRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			client := NewElectricityMapsClient("key", newStatusServer(t, tt.status).URL)
+
+			_, err := client.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetCarbonIntensity: expected %v, got %v", tt.wantErr, err)
+			}
+			_, err = client.GetCarbonForecast(context.Background(), "US-EAST", time.Now(), time.Now().Add(time.Hour))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetCarbonForecast: expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestElectricityMapsClient_ServerErrorIsUntyped(t *testing.T) {
+	client := NewElectricityMapsClient("key", newStatusServer(t, http.StatusBadGateway).URL)
+
+	_, err := client.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, typed := range []error{ErrCarbonAuth, ErrCarbonZoneNotFound, ErrCarbonRateLimited} {
+		if errors.Is(err, typed) {
+			t.Errorf("expected a generic error for 502, got %v", err)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"delay seconds", "120", 2 * time.Minute},
+		{"padded seconds", " 5 ", 5 * time.Second},
+		{"http date", "Sun, 01 Jun 2025 12:01:30 GMT", 90 * time.Second},
+		{"date in the past", "Sun, 01 Jun 2025 11:59:00 GMT", 0},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-3", 0},
+		{"missing", "", 0},
+		{"garbage", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClients_RateLimitCarriesRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"token":"t"}`))
+			return
+		}
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	clients := map[string]CarbonService{
+		"electricitymaps": NewElectricityMapsClient("key", server.URL),
+		"watttime":        NewWattTimeClient("user", "pass", server.URL),
+	}
+
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+
+			var rateLimited *RateLimitError
+			if !errors.As(err, &rateLimited) {
+				t.Fatalf("expected a RateLimitError, got %v", err)
+			}
+			if rateLimited.RetryAfter != 42*time.Second {
+				t.Errorf("expected RetryAfter 42s, got %v", rateLimited.RetryAfter)
+			}
+			if !errors.Is(err, ErrCarbonRateLimited) {
+				t.Error("expected the error to match ErrCarbonRateLimited")
+			}
+		})
+	}
+}
+
+func TestWattTimeClient_ReportsRelativeIndex(t *testing.T) {
+	now := time.Now().Truncate(time.Hour).UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"t"}`))
+		case "/index":
+			fmt.Fprintf(w, `{"ba":"CAISO_NORTH","percent":73,"point_time":%q}`, now.Format(time.RFC3339))
+		case "/forecast":
+			fmt.Fprintf(w, `[{"ba":"CAISO_NORTH","percent":73,"point_time":%q},{"ba":"CAISO_NORTH","percent":12,"point_time":%q}]`,
+				now.Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewWattTimeClient("user", "pass", server.URL)
+
+	current, err := client.GetCarbonIntensity(context.Background(), "CAISO_NORTH", now)
+	if err != nil {
+		t.Fatalf("GetCarbonIntensity returned error: %v", err)
+	}
+	if !current.IsRelative() || current.Intensity != 73 {
+		t.Errorf("expected the raw index 73 marked relative, got %.1f (%q)", current.Intensity, current.IntensityScale)
+	}
+
+	forecast, err := client.GetCarbonForecast(context.Background(), "CAISO_NORTH", now, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCarbonForecast returned error: %v", err)
+	}
+	if len(forecast) != 2 {
+		t.Fatalf("expected 2 forecast points, got %d", len(forecast))
+	}
+	for _, point := range forecast {
+		if !point.IsRelative() || point.Intensity > 100 {
+			t.Errorf("expected a relative 0-100 index, got %.1f (%q)", point.Intensity, point.IntensityScale)
+		}
+	}
+}
+
+func TestElectricityMapsClient_GetCarbonHistory(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/carbon-intensity/past-range" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("zone") != "DE" || query.Get("start") != "2025-06-01T00:00:00Z" || query.Get("end") != "2025-06-01T02:00:00Z" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("auth-token") != "key" {
+			t.Errorf("expected the API key to be sent")
+		}
+		w.Write([]byte(`{"zone":"DE","data":[
+			{"zone":"DE","carbonIntensity":310,"datetime":"2025-06-01T00:00:00.000Z","fossilFreePercentage":55},
+			{"zone":"DE","carbonIntensity":290,"datetime":"2025-06-01T01:00:00.000Z","fossilFreePercentage":60},
+			{"zone":"DE","carbonIntensity":0,"datetime":"not a time"}
+		]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewElectricityMapsClient("key", server.URL)
+	history, err := client.GetCarbonHistory(context.Background(), "DE", start, end)
+	if err != nil {
+		t.Fatalf("GetCarbonHistory returned error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history points, got %d", len(history))
+	}
+	if !history[1].Timestamp.Equal(start.Add(time.Hour)) || history[1].Intensity != 290 || history[1].RenewableEnergy != 60 {
+		t.Errorf("unexpected second point %+v", history[1])
+	}
+}
+
+func TestWattTimeClient_HistoryUnsupported(t *testing.T) {
+	client := NewWattTimeClient("user", "pass", newStatusServer(t, http.StatusOK).URL)
+	if _, err := client.GetCarbonHistory(context.Background(), "CAISO_NORTH", time.Now().Add(-time.Hour), time.Now()); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Errorf("expected ErrHistoryUnsupported, got %v", err)
+	}
+}
+
+func TestWattTimeClient_ConcurrentCallsLogInOnce(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			logins.Add(1)
+			time.Sleep(10 * time.Millisecond) // Widen the window for racing logins
+			w.Write([]byte(`{"token":"t"}`))
+		case "/index":
+			if r.Header.Get("Authorization") != "Bearer t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"ba":"CAISO_NORTH","percent":50,"point_time":"2025-06-01T00:00:00Z"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewWattTimeClient("user", "pass", server.URL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetCarbonIntensity(context.Background(), "CAISO_NORTH", time.Now()); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("GetCarbonIntensity returned error: %v", err)
+	}
+	if got := logins.Load(); got != 1 {
+		t.Errorf("expected a single login, got %d", got)
+	}
+}
+
+func TestWattTimeClient_ConvertsMOERToGramsPerKWh(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"t"}`))
+		case "/index":
+			if r.URL.Query().Get("style") != "all" {
+				t.Errorf("expected style=all to request the MOER, got %s", r.URL.RawQuery)
+			}
+			// Sample v2 index response: the MOER comes as a string
+			w.Write([]byte(`{"ba":"CAISO_NORTH","freq":"300","market":"RTM","moer":"1104.00","percent":"73","point_time":"2025-06-01T12:00:00Z"}`))
+		case "/forecast":
+			w.Write([]byte(`[{"ba":"CAISO_NORTH","point_time":"2025-06-01T12:00:00Z","value":800},{"ba":"CAISO_NORTH","point_time":"2025-06-01T13:00:00Z","value":1000.5}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewWattTimeClient("user", "pass", server.URL)
+
+	current, err := client.GetCarbonIntensity(context.Background(), "CAISO_NORTH", now)
+	if err != nil {
+		t.Fatalf("GetCarbonIntensity returned error: %v", err)
+	}
+	if current.IsRelative() || current.Unit != "gCO2eq/kWh" || math.Abs(current.Intensity-500.76) > 0.01 {
+		t.Errorf("expected 1104 lbs/MWh as 500.76 gCO2eq/kWh, got %.2f %s (%q)", current.Intensity, current.Unit, current.IntensityScale)
+	}
+
+	forecast, err := client.GetCarbonForecast(context.Background(), "CAISO_NORTH", now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetCarbonForecast returned error: %v", err)
+	}
+	want := []float64{362.87, 453.82}
+	if len(forecast) != len(want) {
+		t.Fatalf("expected %d forecast points, got %d", len(want), len(forecast))
+	}
+	for i, point := range forecast {
+		if point.IsRelative() || math.Abs(point.Intensity-want[i]) > 0.01 {
+			t.Errorf("point %d: expected %.2f gCO2eq/kWh, got %.2f (%q)", i, want[i], point.Intensity, point.IntensityScale)
+		}
+	}
+}
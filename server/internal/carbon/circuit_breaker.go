@@ -2,9 +2,12 @@ package carbon
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 )
 
 // CircuitState represents the state of the circuit breaker
@@ -34,11 +37,17 @@ func (s CircuitState) String() string {
 
 // CircuitBreakerConfig holds configuration for the circuit breaker
 type CircuitBreakerConfig struct {
-	MaxFailures    int           // Number of failures before opening circuit
-	Timeout        time.Duration // How long to wait before trying again (open -> half-open)
-	ResetTimeout   time.Duration // How long to stay in half-open before closing
-	StaticFallback float64       // Static carbon intensity value when circuit is open (gCO2eq/kWh)
-	StaticRegion   string        // Default region for static fallback
+	MaxFailures      int           // Number of failures before opening circuit
+	Timeout          time.Duration // How long to wait before trying again (open -> half-open)
+	ResetTimeout     time.Duration // How long to stay in half-open before closing
+	SuccessThreshold int           // Consecutive half-open successes needed to close the circuit
+	StaticFallback   float64       // Static carbon intensity value when circuit is open (gCO2eq/kWh)
+	StaticRegion     string        // Default region for static fallback
+
+	// NoStaticFallback returns ErrCircuitOpen while open and passes failures through
+	// instead of answering with StaticFallback, so a FallbackCarbonService can move on
+	// to the next provider
+	NoStaticFallback bool
 }
 
 // CircuitBreaker wraps a CarbonService with circuit breaker pattern
@@ -51,7 +60,9 @@ type CircuitBreaker struct {
 	failures      int
 	lastFailTime  time.Time
 	lastStateTime time.Time
-	successCount  int // Track successes in half-open state
+	successCount  int           // Track successes in half-open state
+	probes        int           // Half-open requests still awaiting a result
+	openTimeout   time.Duration // How long the current open period lasts (config.Timeout or a Retry-After hint)
 }
 
 // NewCircuitBreaker creates a new circuit breaker for carbon service
@@ -65,6 +76,9 @@ func NewCircuitBreaker(service CarbonService, config CircuitBreakerConfig) *Circ
 	if config.ResetTimeout == 0 {
 		config.ResetTimeout = 10 * time.Second // Default: 10 seconds
 	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = 1 // Default: first successful probe closes the circuit
+	}
 	if config.StaticFallback == 0 {
 		config.StaticFallback = 400.0 // Default: 400 gCO2eq/kWh (global average)
 	}
@@ -77,6 +91,7 @@ func NewCircuitBreaker(service CarbonService, config CircuitBreakerConfig) *Circ
 		config:        config,
 		state:         StateClosed,
 		lastStateTime: time.Now(),
+		openTimeout:   config.Timeout,
 	}
 }
 
@@ -84,6 +99,9 @@ func NewCircuitBreaker(service CarbonService, config CircuitBreakerConfig) *Circ
 func (cb *CircuitBreaker) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
 	// Check circuit state
 	if !cb.canAttempt() {
+		if cb.config.NoStaticFallback {
+			return nil, ErrCircuitOpen
+		}
 		// Circuit is open - return static fallback
 		return cb.fallbackIntensity(region, timestamp), nil
 	}
@@ -91,8 +109,16 @@ func (cb *CircuitBreaker) GetCarbonIntensity(ctx context.Context, region string,
 	// Attempt to call underlying service
 	result, err := cb.service.GetCarbonIntensity(ctx, region, timestamp)
 
+	if err != nil && !isTransient(err) {
+		// The API answered; a bad key or unknown zone won't be fixed by waiting
+		cb.releaseProbe()
+		return nil, err
+	}
 	if err != nil {
 		cb.recordFailure(err)
+		if cb.config.NoStaticFallback {
+			return nil, err
+		}
 		// Return fallback on error
 		return cb.fallbackIntensity(region, timestamp), nil
 	}
@@ -106,6 +132,9 @@ func (cb *CircuitBreaker) GetCarbonIntensity(ctx context.Context, region string,
 func (cb *CircuitBreaker) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
 	// Check circuit state
 	if !cb.canAttempt() {
+		if cb.config.NoStaticFallback {
+			return nil, ErrCircuitOpen
+		}
 		// Circuit is open - return static fallback forecast
 		return cb.fallbackForecast(region, startTime, endTime), nil
 	}
@@ -113,8 +142,15 @@ func (cb *CircuitBreaker) GetCarbonForecast(ctx context.Context, region string,
 	// Attempt to call underlying service
 	result, err := cb.service.GetCarbonForecast(ctx, region, startTime, endTime)
 
+	if err != nil && !isTransient(err) {
+		cb.releaseProbe()
+		return nil, err
+	}
 	if err != nil {
 		cb.recordFailure(err)
+		if cb.config.NoStaticFallback {
+			return nil, err
+		}
 		// Return fallback on error
 		return cb.fallbackForecast(region, startTime, endTime), nil
 	}
@@ -124,6 +160,37 @@ func (cb *CircuitBreaker) GetCarbonForecast(ctx context.Context, region string,
 	return result, nil
 }
 
+// GetCarbonHistory retrieves past carbon intensity with circuit breaker protection. There
+// is no static fallback for history: made-up values would defeat measuring what the
+// intensity actually was, so ErrCircuitOpen is returned instead.
+func (cb *CircuitBreaker) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	if !cb.canAttempt() {
+		return nil, ErrCircuitOpen
+	}
+
+	result, err := cb.service.GetCarbonHistory(ctx, region, startTime, endTime)
+
+	if err != nil && !isTransient(err) {
+		cb.releaseProbe()
+		return nil, err
+	}
+	if err != nil {
+		cb.recordFailure(err)
+		return nil, err
+	}
+
+	cb.recordSuccess()
+	return result, nil
+}
+
+// isTransient reports whether an error may clear up on its own (5xx, timeouts, rate
+// limiting) and so should count toward opening the circuit. Auth and unknown-zone
+// errors are configuration problems, and a provider without history won't grow one, so
+// these are passed through to the caller instead.
+func isTransient(err error) bool {
+	return !errors.Is(err, ErrCarbonAuth) && !errors.Is(err, ErrCarbonZoneNotFound) && !errors.Is(err, ErrHistoryUnsupported)
+}
+
 // canAttempt checks if a request can be attempted based on circuit state
 func (cb *CircuitBreaker) canAttempt() bool {
 	cb.mu.Lock()
@@ -138,19 +205,25 @@ func (cb *CircuitBreaker) canAttempt() bool {
 
 	case StateOpen:
 		// Check if timeout has elapsed
-		if now.Sub(cb.lastStateTime) >= cb.config.Timeout {
+		if now.Sub(cb.lastStateTime) >= cb.openTimeout {
 			// Transition to half-open
 			cb.state = StateHalfOpen
 			cb.lastStateTime = now
 			cb.successCount = 0
-			fmt.Printf("🔧 Circuit breaker transitioning to HALF_OPEN (will test service recovery)\n")
+			cb.probes = 1
+			slog.Info("Circuit breaker transitioning to HALF_OPEN, testing service recovery",
+				"state", cb.state.String(), "failures", cb.failures, "success_threshold", cb.config.SuccessThreshold)
 			return true
 		}
 		// Still in timeout - reject request
 		return false
 
 	case StateHalfOpen:
-		// In half-open state - allow single request to test
+		// Allow only as many probes as successes are still needed to close
+		if cb.successCount+cb.probes >= cb.config.SuccessThreshold {
+			return false
+		}
+		cb.probes++
 		return true
 
 	default:
@@ -167,24 +240,47 @@ func (cb *CircuitBreaker) recordFailure(err error) {
 	cb.failures++
 	cb.lastFailTime = now
 
+	// A rate-limited API told us when to come back; stay open until then rather than
+	// probing early and risking a ban
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > 0 {
+		cb.state = StateOpen
+		cb.lastStateTime = now
+		cb.openTimeout = rateLimited.RetryAfter
+		cb.probes = 0
+		slog.Error("Circuit breaker OPENED: carbon API rate limited",
+			"state", cb.state.String(), "failures", cb.failures, "retry_after", rateLimited.RetryAfter)
+		return
+	}
+	cb.openTimeout = cb.config.Timeout
+
 	switch cb.state {
 	case StateClosed:
 		if cb.failures >= cb.config.MaxFailures {
 			// Open the circuit
 			cb.state = StateOpen
 			cb.lastStateTime = now
-			fmt.Printf("🚨 Circuit breaker OPENED after %d failures (last error: %v)\n", cb.failures, err)
-			fmt.Printf("⚠️  Using static fallback: %.2f gCO2eq/kWh for next %v\n", cb.config.StaticFallback, cb.config.Timeout)
+			if cb.config.NoStaticFallback {
+				slog.Error("Circuit breaker OPENED, failing fast",
+					"state", cb.state.String(), "failures", cb.failures, logging.Err(err), "timeout", cb.config.Timeout)
+			} else {
+				slog.Error("Circuit breaker OPENED, using static fallback",
+					"state", cb.state.String(), "failures", cb.failures, logging.Err(err),
+					"static_fallback", cb.config.StaticFallback, "timeout", cb.config.Timeout)
+			}
 		} else {
-			fmt.Printf("⚠️  Carbon API failure %d/%d: %v\n", cb.failures, cb.config.MaxFailures, err)
+			slog.Warn("Carbon API failure",
+				"state", cb.state.String(), "failures", cb.failures, "max_failures", cb.config.MaxFailures, logging.Err(err))
 		}
 
 	case StateHalfOpen:
-		// Failed in half-open - back to open
+		// Any failed probe - back to open
 		cb.state = StateOpen
 		cb.lastStateTime = now
+		cb.probes = 0
 		cb.failures = cb.config.MaxFailures // Reset to max
-		fmt.Printf("🚨 Circuit breaker back to OPEN (service still failing)\n")
+		slog.Error("Circuit breaker back to OPEN, service still failing",
+			"state", cb.state.String(), "failures", cb.failures, logging.Err(err))
 	}
 }
 
@@ -197,17 +293,38 @@ func (cb *CircuitBreaker) recordSuccess() {
 	case StateClosed:
 		// Already closed - reset failure count
 		if cb.failures > 0 {
-			fmt.Printf("✓ Carbon API recovered (resetting failure count: %d → 0)\n", cb.failures)
+			slog.Info("Carbon API recovered, resetting failure count", "state", cb.state.String(), "failures", cb.failures)
 			cb.failures = 0
 		}
 
 	case StateHalfOpen:
 		cb.successCount++
-		// After successful request in half-open, close the circuit
+		if cb.probes > 0 {
+			cb.probes--
+		}
+		if cb.successCount < cb.config.SuccessThreshold {
+			slog.Info("Circuit breaker half-open probe succeeded", "state", cb.state.String(),
+				"successes", cb.successCount, "success_threshold", cb.config.SuccessThreshold)
+			return
+		}
+		// Enough consecutive successes in half-open, close the circuit
 		cb.state = StateClosed
 		cb.failures = 0
+		cb.probes = 0
 		cb.lastStateTime = time.Now()
-		fmt.Printf("✓ Circuit breaker CLOSED (service recovered after half-open test)\n")
+		slog.Info("Circuit breaker CLOSED, service recovered after half-open test",
+			"state", cb.state.String(), "failures", cb.failures)
+	}
+}
+
+// releaseProbe frees the half-open probe slot of a request that neither succeeded nor
+// counted as a failure (a non-transient error), so another request can probe instead
+func (cb *CircuitBreaker) releaseProbe() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateHalfOpen && cb.probes > 0 {
+		cb.probes--
 	}
 }
 
@@ -259,6 +376,11 @@ func (cb *CircuitBreaker) GetStats() map[string]interface{} {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 
+	sinceLastFail := "" // Never failed
+	if !cb.lastFailTime.IsZero() {
+		sinceLastFail = time.Since(cb.lastFailTime).Round(time.Second).String()
+	}
+
 	return map[string]interface{}{
 		"state":                cb.state.String(),
 		"failures":             cb.failures,
@@ -266,9 +388,12 @@ func (cb *CircuitBreaker) GetStats() map[string]interface{} {
 		"last_fail_time":       cb.lastFailTime,
 		"last_state_change":    cb.lastStateTime,
 		"timeout":              cb.config.Timeout.String(),
+		"open_timeout":         cb.openTimeout.String(),
 		"static_fallback":      cb.config.StaticFallback,
+		"static_region":        cb.config.StaticRegion,
 		"success_count":        cb.successCount,
-		"time_since_last_fail": time.Since(cb.lastFailTime).String(),
+		"success_threshold":    cb.config.SuccessThreshold,
+		"time_since_last_fail": sinceLastFail,
 	}
 }
 
@@ -277,9 +402,13 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	previous := cb.state
 	cb.state = StateClosed
 	cb.failures = 0
 	cb.successCount = 0
+	cb.probes = 0
 	cb.lastStateTime = time.Now()
-	fmt.Println("✓ Circuit breaker manually reset to CLOSED state")
+	cb.openTimeout = cb.config.Timeout
+	slog.Info("Circuit breaker manually reset to CLOSED state",
+		"state", cb.state.String(), "failures", cb.failures, "previous_state", previous.String())
 }
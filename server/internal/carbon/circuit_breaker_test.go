@@ -0,0 +1,335 @@
+package carbon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_CountsOnlyTransientErrors(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantOpen      bool
+		wantErr       error
+		wantFallbacks bool
+	}{
+		{http.StatusUnauthorized, false, ErrCarbonAuth, false},
+		{http.StatusNotFound, false, ErrCarbonZoneNotFound, false},
+		{http.StatusTooManyRequests, true, nil, true},
+		{http.StatusServiceUnavailable, true, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			client := NewElectricityMapsClient("key", newStatusServer(t, tt.status).URL)
+			breaker := NewCircuitBreaker(client, CircuitBreakerConfig{MaxFailures: 2, Timeout: time.Hour})
+
+			for i := 0; i < 3; i++ {
+				result, err := breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("call %d: expected %v to pass through, got %v", i, tt.wantErr, err)
+				}
+				if tt.wantFallbacks && (err != nil || result == nil || result.Intensity != 400) {
+					t.Fatalf("call %d: expected static fallback, got %+v (err %v)", i, result, err)
+				}
+			}
+
+			if open := breaker.GetState() == StateOpen; open != tt.wantOpen {
+				t.Errorf("expected open=%v, got state %s", tt.wantOpen, breaker.GetState())
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_ForecastPassesThroughZoneNotFound(t *testing.T) {
+	client := NewElectricityMapsClient("key", newStatusServer(t, http.StatusNotFound).URL)
+	breaker := NewCircuitBreaker(client, CircuitBreakerConfig{MaxFailures: 1})
+
+	_, err := breaker.GetCarbonForecast(context.Background(), "XX", time.Now(), time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrCarbonZoneNotFound) {
+		t.Errorf("expected zone-not-found error, got %v", err)
+	}
+	if breaker.GetFailures() != 0 {
+		t.Errorf("expected no failures recorded, got %d", breaker.GetFailures())
+	}
+}
+
+// rateLimitedService fails every call with a rate-limit error and counts calls
+type rateLimitedService struct {
+	calls      int
+	retryAfter time.Duration
+}
+
+func (s *rateLimitedService) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
+	s.calls++
+	return nil, &RateLimitError{StatusCode: http.StatusTooManyRequests, RetryAfter: s.retryAfter}
+}
+
+func (s *rateLimitedService) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	s.calls++
+	return nil, &RateLimitError{StatusCode: http.StatusTooManyRequests, RetryAfter: s.retryAfter}
+}
+
+func (s *rateLimitedService) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	s.calls++
+	return nil, &RateLimitError{StatusCode: http.StatusTooManyRequests, RetryAfter: s.retryAfter}
+}
+
+func TestCircuitBreaker_OpensForRetryAfter(t *testing.T) {
+	service := &rateLimitedService{retryAfter: time.Hour}
+	breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 5, Timeout: time.Millisecond})
+
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if breaker.GetState() != StateOpen {
+		t.Fatalf("expected a single Retry-After response to open the circuit, got %s", breaker.GetState())
+	}
+
+	// Well past the configured timeout, but still inside Retry-After
+	time.Sleep(5 * time.Millisecond)
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if service.calls != 1 {
+		t.Errorf("expected no calls during Retry-After, got %d", service.calls)
+	}
+	if got := breaker.GetStats()["open_timeout"]; got != "1h0m0s" {
+		t.Errorf("expected open_timeout 1h0m0s, got %v", got)
+	}
+
+	breaker.Reset()
+	if got := breaker.GetStats()["open_timeout"]; got != "1ms" {
+		t.Errorf("expected reset to restore the configured timeout, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_RateLimitWithoutHintUsesThreshold(t *testing.T) {
+	service := &rateLimitedService{}
+	breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 2, Timeout: time.Hour})
+
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if breaker.GetState() != StateClosed {
+		t.Errorf("expected circuit to stay closed below the threshold, got %s", breaker.GetState())
+	}
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if breaker.GetState() != StateOpen {
+		t.Errorf("expected circuit to open at the threshold, got %s", breaker.GetState())
+	}
+}
+
+// scriptedService answers each call with the next outcome: true succeeds, false fails
+type scriptedService struct {
+	outcomes []bool
+	calls    int
+	release  chan struct{} // Optional: each call waits for a value before answering
+}
+
+func (s *scriptedService) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	ok := s.outcomes[s.calls]
+	s.calls++
+	if !ok {
+		return nil, errors.New("carbon API unavailable")
+	}
+	return &CarbonIntensity{Region: region, Timestamp: timestamp, Intensity: 123, Unit: "gCO2eq/kWh"}, nil
+}
+
+func (s *scriptedService) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (s *scriptedService) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	if !s.outcomes[s.calls] {
+		s.calls++
+		return nil, errors.New("carbon API unavailable")
+	}
+	s.calls++
+	return []CarbonIntensity{{Region: region, Timestamp: startTime, Intensity: 123, Unit: "gCO2eq/kWh"}}, nil
+}
+
+func TestCircuitBreaker_SuccessThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		outcomes  []bool // Half-open calls, after the circuit first opens
+		want      []CircuitState
+	}{
+		{
+			name:      "default closes on first success",
+			threshold: 0,
+			outcomes:  []bool{true},
+			want:      []CircuitState{StateClosed},
+		},
+		{
+			name:      "three successes close",
+			threshold: 3,
+			outcomes:  []bool{true, true, true},
+			want:      []CircuitState{StateHalfOpen, StateHalfOpen, StateClosed},
+		},
+		{
+			name:      "failure after successes reopens",
+			threshold: 3,
+			outcomes:  []bool{true, true, false, true, true, true},
+			want:      []CircuitState{StateHalfOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateHalfOpen, StateClosed},
+		},
+		{
+			name:      "first probe failing reopens",
+			threshold: 3,
+			outcomes:  []bool{false, true, false, true, true, true},
+			want:      []CircuitState{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateHalfOpen, StateClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &scriptedService{outcomes: append([]bool{false}, tt.outcomes...)}
+			breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Millisecond, SuccessThreshold: tt.threshold})
+
+			breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+			for i, want := range tt.want {
+				if breaker.GetState() == StateOpen {
+					time.Sleep(2 * time.Millisecond) // Let the open period end
+				}
+				breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+				if got := breaker.GetState(); got != want {
+					t.Fatalf("call %d: expected %s, got %s", i, want, got)
+				}
+			}
+			if service.calls != len(tt.outcomes)+1 {
+				t.Errorf("expected every half-open call to reach the service, got %d calls", service.calls)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_LimitsConcurrentProbes(t *testing.T) {
+	service := &scriptedService{outcomes: []bool{false, true, true, true}}
+	breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Millisecond, SuccessThreshold: 3})
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	time.Sleep(2 * time.Millisecond)
+
+	// Hold three probes at the service, then send a fourth request
+	service.release = make(chan struct{})
+	done := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+			done <- struct{}{}
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for breaker.probeCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("probes did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	result, err := breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if err != nil || result.Intensity != 400 {
+		t.Errorf("expected static fallback beyond the probe limit, got %+v (err %v)", result, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		service.release <- struct{}{}
+		<-done
+	}
+	if breaker.GetState() != StateClosed {
+		t.Errorf("expected three successful probes to close the circuit, got %s", breaker.GetState())
+	}
+	if service.calls != 4 {
+		t.Errorf("expected the extra request not to reach the service, got %d calls", service.calls)
+	}
+}
+
+// probeCount returns the half-open requests still awaiting a result
+func (cb *CircuitBreaker) probeCount() int {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.probes
+}
+
+func TestCircuitBreaker_LogsTransitions(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	service := &scriptedService{outcomes: []bool{false, false, true}}
+	breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 2, Timeout: time.Millisecond})
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	time.Sleep(2 * time.Millisecond)
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	breaker.Reset()
+
+	records := map[string]map[string]any{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("log output is not JSON: %v", err)
+		}
+		records[record["msg"].(string)] = record
+	}
+
+	tests := []struct {
+		msg      string
+		level    string
+		state    string
+		failures float64
+	}{
+		{"Carbon API failure", "WARN", "CLOSED", 1},
+		{"Circuit breaker OPENED, using static fallback", "ERROR", "OPEN", 2},
+		{"Circuit breaker transitioning to HALF_OPEN, testing service recovery", "INFO", "HALF_OPEN", 2},
+		{"Circuit breaker CLOSED, service recovered after half-open test", "INFO", "CLOSED", 0},
+		{"Circuit breaker manually reset to CLOSED state", "INFO", "CLOSED", 0},
+	}
+	for _, tt := range tests {
+		record, ok := records[tt.msg]
+		if !ok {
+			t.Errorf("expected a %q log record", tt.msg)
+			continue
+		}
+		if record["level"] != tt.level || record["state"] != tt.state || record["failures"] != tt.failures {
+			t.Errorf("%q: expected level %s, state %s, failures %v; got %v", tt.msg, tt.level, tt.state, tt.failures, record)
+		}
+	}
+}
+
+func TestCircuitBreaker_WrapsHistory(t *testing.T) {
+	service := &scriptedService{outcomes: []bool{false, true}}
+	breaker := NewCircuitBreaker(service, CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Millisecond})
+	start := time.Now().Add(-time.Hour)
+
+	if _, err := breaker.GetCarbonHistory(context.Background(), "US-EAST", start, time.Now()); err == nil {
+		t.Fatal("expected the provider's error, not made-up history")
+	}
+	if breaker.GetState() != StateOpen {
+		t.Fatalf("expected a history failure to open the circuit, got %s", breaker.GetState())
+	}
+	if _, err := breaker.GetCarbonHistory(context.Background(), "US-EAST", start, time.Now()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen while open, got %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	history, err := breaker.GetCarbonHistory(context.Background(), "US-EAST", start, time.Now())
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected history from the recovered provider, got %v (err %v)", history, err)
+	}
+	if breaker.GetState() != StateClosed {
+		t.Errorf("expected a successful history probe to close the circuit, got %s", breaker.GetState())
+	}
+
+	unsupported := NewCircuitBreaker(&fakeCarbonService{}, CircuitBreakerConfig{MaxFailures: 1})
+	if _, err := unsupported.GetCarbonHistory(context.Background(), "US-EAST", start, time.Now()); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Errorf("expected ErrHistoryUnsupported to pass through, got %v", err)
+	}
+	if unsupported.GetFailures() != 0 {
+		t.Errorf("expected a provider without history not to count as a failure, got %d", unsupported.GetFailures())
+	}
+}
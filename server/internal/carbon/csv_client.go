@@ -0,0 +1,188 @@
+package carbon
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVCarbonClient implements CarbonService from a static CSV file, for offline demos,
+// air-gapped deployments and deterministic testing.
+//
+// The file has a header row followed by one row per region and timestamp:
+//
+//	region,timestamp,intensity[,renewable_percentage,fossil_percentage]
+//	US-EAST,2025-06-01T00:00:00Z,420
+//	US-EAST,2025-06-01T01:00:00Z,380,35.5,64.5
+//
+// timestamp is RFC 3339 and intensity is in gCO2eq/kWh. The percentage columns are
+// optional and may be left empty. Rows are usually hourly but need not be evenly
+// spaced; values between rows are linearly interpolated, and times outside the
+// covered range take the nearest row's values.
+type CSVCarbonClient struct {
+	path    string
+	regions map[string][]CarbonIntensity // Sorted by timestamp
+}
+
+// NewCSVCarbonClient loads carbon intensity data from the CSV file at path
+func NewCSVCarbonClient(path string) (*CSVCarbonClient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open carbon CSV: %w", err)
+	}
+	defer file.Close()
+
+	regions, err := parseCarbonCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load carbon CSV %s: %w", path, err)
+	}
+
+	return &CSVCarbonClient{
+		path:    path,
+		regions: regions,
+	}, nil
+}
+
+// parseCarbonCSV reads rows grouped by region and sorted by timestamp
+func parseCarbonCSV(r io.Reader) (map[string][]CarbonIntensity, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Percentage columns are optional
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if len(header) < 3 || strings.TrimSpace(header[0]) != "region" {
+		return nil, fmt.Errorf("expected header region,timestamp,intensity[,renewable_percentage,fossil_percentage], got %q", strings.Join(header, ","))
+	}
+
+	regions := make(map[string][]CarbonIntensity)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 columns, got %d", line, len(record))
+		}
+
+		region := strings.TrimSpace(record[0])
+		timestamp, err := time.Parse(time.RFC3339, strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp: %w", line, err)
+		}
+		intensity, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid intensity: %w", line, err)
+		}
+
+		point := CarbonIntensity{
+			Region:    region,
+			Timestamp: timestamp,
+			Intensity: intensity,
+			Unit:      "gCO2eq/kWh",
+		}
+		if point.RenewableEnergy, err = optionalPercentage(record, 3); err != nil {
+			return nil, fmt.Errorf("line %d: invalid renewable_percentage: %w", line, err)
+		}
+		if point.FossilFuel, err = optionalPercentage(record, 4); err != nil {
+			return nil, fmt.Errorf("line %d: invalid fossil_percentage: %w", line, err)
+		}
+
+		regions[region] = append(regions[region], point)
+	}
+
+	if len(regions) == 0 {
+		return nil, errors.New("no data rows")
+	}
+
+	// Sort each region and let later rows override earlier ones for the same timestamp
+	for region, points := range regions {
+		regions[region] = DedupeForecast(points)
+	}
+
+	return regions, nil
+}
+
+// optionalPercentage parses column i, treating a missing or empty column as zero
+func optionalPercentage(record []string, i int) (float64, error) {
+	if i >= len(record) || strings.TrimSpace(record[i]) == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+}
+
+// GetCarbonIntensity returns the interpolated carbon intensity for a region at timestamp
+func (c *CSVCarbonClient) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
+	points, ok := c.regions[region]
+	if !ok {
+		return nil, fmt.Errorf("region %s not found in %s", region, c.path)
+	}
+
+	point := interpolateIntensity(points, timestamp)
+	return &point, nil
+}
+
+// GetCarbonForecast returns hourly interpolated intensities from startTime through endTime
+func (c *CSVCarbonClient) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	points, ok := c.regions[region]
+	if !ok {
+		return nil, fmt.Errorf("region %s not found in %s", region, c.path)
+	}
+
+	var forecast []CarbonIntensity
+	for t := startTime; !t.After(endTime); t = t.Add(time.Hour) {
+		forecast = append(forecast, interpolateIntensity(points, t))
+	}
+
+	return forecast, nil
+}
+
+// GetCarbonHistory returns the file's hourly intensities from startTime through endTime;
+// the CSV holds no distinction between measured and forecast values
+func (c *CSVCarbonClient) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return c.GetCarbonForecast(ctx, region, startTime, endTime)
+}
+
+// interpolateIntensity linearly interpolates sorted points at t, clamping outside their range
+func interpolateIntensity(points []CarbonIntensity, t time.Time) CarbonIntensity {
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Timestamp.Before(t)
+	})
+
+	var point CarbonIntensity
+	switch {
+	case i == 0:
+		point = points[0]
+	case i == len(points):
+		point = points[len(points)-1]
+	case points[i].Timestamp.Equal(t):
+		point = points[i]
+	default:
+		before, after := points[i-1], points[i]
+		frac := float64(t.Sub(before.Timestamp)) / float64(after.Timestamp.Sub(before.Timestamp))
+		point = before
+		point.Intensity = before.Intensity + frac*(after.Intensity-before.Intensity)
+		point.RenewableEnergy = before.RenewableEnergy + frac*(after.RenewableEnergy-before.RenewableEnergy)
+		point.FossilFuel = before.FossilFuel + frac*(after.FossilFuel-before.FossilFuel)
+	}
+
+	point.Timestamp = t
+	return point
+}
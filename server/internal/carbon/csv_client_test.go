@@ -0,0 +1,102 @@
+package carbon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCSVCarbonClient_GetCarbonIntensity(t *testing.T) {
+	client, err := NewCSVCarbonClient("testdata/carbon_fixture.csv")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		region    string
+		at        time.Time
+		intensity float64
+		renewable float64
+	}{
+		{"exact row", "US-EAST", start, 400, 20},
+		{"between rows", "US-EAST", start.Add(30 * time.Minute), 350, 10},
+		{"rows out of order in file", "US-EAST", start.Add(90 * time.Minute), 250, 30},
+		{"before range clamps", "EU-NORTH", start.Add(-time.Hour), 90, 85},
+		{"after range clamps", "EU-NORTH", start.Add(5 * time.Hour), 60, 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.GetCarbonIntensity(context.Background(), tt.region, tt.at)
+			if err != nil {
+				t.Fatalf("GetCarbonIntensity returned error: %v", err)
+			}
+			if got.Intensity != tt.intensity || got.RenewableEnergy != tt.renewable {
+				t.Errorf("expected %.0f gCO2eq/kWh (%.0f%% renewable), got %.0f (%.0f%%)",
+					tt.intensity, tt.renewable, got.Intensity, got.RenewableEnergy)
+			}
+			if !got.Timestamp.Equal(tt.at) || got.Region != tt.region {
+				t.Errorf("expected %s at %s, got %s at %s", tt.region, tt.at, got.Region, got.Timestamp)
+			}
+		})
+	}
+}
+
+func TestCSVCarbonClient_GetCarbonForecast(t *testing.T) {
+	client, err := NewCSVCarbonClient("testdata/carbon_fixture.csv")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	start := time.Date(2025, 6, 1, 0, 30, 0, 0, time.UTC)
+
+	forecast, err := client.GetCarbonForecast(context.Background(), "US-EAST", start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCarbonForecast returned error: %v", err)
+	}
+
+	want := []float64{350, 250, 200}
+	if len(forecast) != len(want) {
+		t.Fatalf("expected %d hourly points, got %d", len(want), len(forecast))
+	}
+	for i, point := range forecast {
+		if point.Intensity != want[i] {
+			t.Errorf("point %d: expected %.0f, got %.0f", i, want[i], point.Intensity)
+		}
+		if !point.Timestamp.Equal(start.Add(time.Duration(i) * time.Hour)) {
+			t.Errorf("point %d: unexpected timestamp %s", i, point.Timestamp)
+		}
+	}
+
+	if _, err := client.GetCarbonForecast(context.Background(), "AP-SOUTH", start, start.Add(time.Hour)); err == nil {
+		t.Error("expected an error for a region missing from the file")
+	}
+}
+
+func TestNewCSVCarbonClient_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"missing header", "US-EAST,2025-06-01T00:00:00Z,400\n"},
+		{"no rows", "region,timestamp,intensity\n"},
+		{"bad timestamp", "region,timestamp,intensity\nUS-EAST,yesterday,400\n"},
+		{"bad intensity", "region,timestamp,intensity\nUS-EAST,2025-06-01T00:00:00Z,high\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "carbon.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewCSVCarbonClient(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -27,14 +27,8 @@ func (w *DatabaseCacheWrapper) GetCarbonIntensity(ctx context.Context, region st
 		return nil, nil
 	}
 
-	return &CarbonCacheEntry{
-		Region:    dbEntry.Region,
-		Timestamp: dbEntry.Timestamp,
-		Intensity: dbEntry.IntensityValue,
-		Unit:      "gCO2/kWh",
-		FetchedAt: dbEntry.CreatedAt,
-		ExpiresAt: dbEntry.CreatedAt.Add(24 * time.Hour), // Default 24h expiry
-	}, nil
+	entry := fromDBEntry(dbEntry)
+	return &entry, nil
 }
 
 // GetCarbonForecast retrieves cached forecast data
@@ -46,14 +40,7 @@ func (w *DatabaseCacheWrapper) GetCarbonForecast(ctx context.Context, region str
 
 	var entries []CarbonCacheEntry
 	for _, dbEntry := range dbEntries {
-		entries = append(entries, CarbonCacheEntry{
-			Region:    dbEntry.Region,
-			Timestamp: dbEntry.Timestamp,
-			Intensity: dbEntry.IntensityValue,
-			Unit:      "gCO2/kWh",
-			FetchedAt: dbEntry.CreatedAt,
-			ExpiresAt: dbEntry.CreatedAt.Add(24 * time.Hour), // Default 24h expiry
-		})
+		entries = append(entries, fromDBEntry(&dbEntry))
 	}
 
 	return entries, nil
@@ -61,19 +48,14 @@ func (w *DatabaseCacheWrapper) GetCarbonForecast(ctx context.Context, region str
 
 // SaveCarbonIntensity saves carbon intensity data to cache
 func (w *DatabaseCacheWrapper) SaveCarbonIntensity(ctx context.Context, data *CarbonIntensity, ttl time.Duration) error {
-	return w.repo.SaveCarbonIntensity(ctx, data.Region, data.Timestamp, data.Intensity, data.Unit, ttl)
+	return w.repo.SaveCarbonIntensity(ctx, toDBIntensity(data), ttl)
 }
 
 // BulkSaveCarbonIntensities saves multiple carbon intensity records
 func (w *DatabaseCacheWrapper) BulkSaveCarbonIntensities(ctx context.Context, data []CarbonIntensity, ttl time.Duration) error {
 	dbData := make([]database.CarbonIntensity, len(data))
-	for i, entry := range data {
-		dbData[i] = database.CarbonIntensity{
-			Region:    entry.Region,
-			Timestamp: entry.Timestamp,
-			Intensity: entry.Intensity,
-			Unit:      entry.Unit,
-		}
+	for i := range data {
+		dbData[i] = toDBIntensity(&data[i])
 	}
 	return w.repo.BulkSaveCarbonIntensities(ctx, dbData, ttl)
 }
@@ -85,3 +67,49 @@ func (w *DatabaseCacheWrapper) IsCacheFresh(entry *CarbonCacheEntry, maxAge time
 	}
 	return w.repo.IsCacheFresh(dbEntry, maxAge)
 }
+
+// toDBIntensity converts carbon data to the repository type. Energy-mix percentages are
+// stored as NULL when the provider didn't report them (both zero).
+func toDBIntensity(data *CarbonIntensity) database.CarbonIntensity {
+	dbData := database.CarbonIntensity{
+		Region:    data.Region,
+		Timestamp: data.Timestamp,
+		Intensity: data.Intensity,
+		Unit:      data.Unit,
+
+		IntensityScale: string(IntensityScaleAbsolute),
+	}
+	if data.IsRelative() {
+		dbData.IntensityScale = string(IntensityScaleRelative)
+	}
+	if data.RenewableEnergy != 0 || data.FossilFuel != 0 {
+		renewable, fossil := data.RenewableEnergy, data.FossilFuel
+		dbData.RenewablePercentage = &renewable
+		dbData.FossilPercentage = &fossil
+	}
+	return dbData
+}
+
+// fromDBEntry converts a repository cache row to a cache entry
+func fromDBEntry(dbEntry *database.CarbonCacheEntry) CarbonCacheEntry {
+	entry := CarbonCacheEntry{
+		Region:    dbEntry.Region,
+		Timestamp: dbEntry.Timestamp,
+		Intensity: dbEntry.IntensityValue,
+		Unit:      "gCO2/kWh",
+		FetchedAt: dbEntry.CreatedAt,
+		ExpiresAt: dbEntry.CreatedAt.Add(24 * time.Hour), // Default 24h expiry
+
+		IntensityScale: IntensityScale(dbEntry.IntensityScale),
+	}
+	if entry.IntensityScale == IntensityScaleRelative {
+		entry.Unit = "percent"
+	}
+	if dbEntry.RenewablePercentage != nil {
+		entry.RenewableEnergy = *dbEntry.RenewablePercentage
+	}
+	if dbEntry.FossilPercentage != nil {
+		entry.FossilFuel = *dbEntry.FossilPercentage
+	}
+	return entry
+}
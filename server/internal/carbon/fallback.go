@@ -0,0 +1,83 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+)
+
+// ErrNoCarbonProviders is returned by a FallbackCarbonService with an empty chain
+var ErrNoCarbonProviders = errors.New("no carbon providers configured")
+
+// NamedCarbonService is one link of a FallbackCarbonService chain
+type NamedCarbonService struct {
+	Name    string // Provider name used in logs, e.g. "electricitymaps"
+	Service CarbonService
+}
+
+// FallbackCarbonService asks each provider in priority order and returns the first
+// answer. Wrap API providers in a CircuitBreaker with NoStaticFallback so a provider
+// whose circuit is open is skipped without waiting for it to time out again.
+type FallbackCarbonService struct {
+	providers []NamedCarbonService
+}
+
+// NewFallbackCarbonService creates a chain that tries providers in the given order
+func NewFallbackCarbonService(providers ...NamedCarbonService) *FallbackCarbonService {
+	return &FallbackCarbonService{providers: providers}
+}
+
+// GetCarbonIntensity returns the intensity from the first provider that answers
+func (f *FallbackCarbonService) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonIntensity, error) {
+	return tryInOrder(ctx, f.providers, func(s CarbonService) (*CarbonIntensity, error) {
+		return s.GetCarbonIntensity(ctx, region, timestamp)
+	})
+}
+
+// GetCarbonForecast returns the forecast from the first provider that answers
+func (f *FallbackCarbonService) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return tryInOrder(ctx, f.providers, func(s CarbonService) ([]CarbonIntensity, error) {
+		return s.GetCarbonForecast(ctx, region, startTime, endTime)
+	})
+}
+
+// GetCarbonHistory returns history from the first provider that has it. Providers
+// without history return ErrHistoryUnsupported and are skipped like any other failure.
+func (f *FallbackCarbonService) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	return tryInOrder(ctx, f.providers, func(s CarbonService) ([]CarbonIntensity, error) {
+		return s.GetCarbonHistory(ctx, region, startTime, endTime)
+	})
+}
+
+// tryInOrder calls each provider until one succeeds. Every error moves on to the next
+// provider: a zone one provider doesn't know may be covered by another. If all fail, the
+// last error is returned so a CircuitBreaker around the chain classifies it as usual.
+func tryInOrder[T any](ctx context.Context, providers []NamedCarbonService, call func(CarbonService) (T, error)) (T, error) {
+	var zero T
+	if len(providers) == 0 {
+		return zero, ErrNoCarbonProviders
+	}
+
+	var lastErr error
+	for i, p := range providers {
+		result, err := call(p.Service)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return zero, err
+		}
+		if i+1 < len(providers) {
+			slog.Warn("Carbon provider failed, trying next",
+				"provider", p.Name, "next", providers[i+1].Name, logging.Err(err))
+		}
+	}
+
+	return zero, fmt.Errorf("all carbon providers failed: %w", lastErr)
+}
@@ -0,0 +1,107 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFallbackCarbonService_UsesSecondaryWhenPrimaryFails(t *testing.T) {
+	primary := newFakeCarbonService()
+	primary.failFor["US-EAST"] = true
+	secondary := newFakeCarbonService()
+	secondary.intensity = 180
+
+	chain := NewFallbackCarbonService(
+		NamedCarbonService{Name: "primary", Service: primary},
+		NamedCarbonService{Name: "secondary", Service: secondary},
+	)
+
+	result, err := chain.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if err != nil {
+		t.Fatalf("expected secondary to answer, got %v", err)
+	}
+	if result.Intensity != 180 {
+		t.Errorf("expected secondary's intensity 180, got %.1f", result.Intensity)
+	}
+
+	start := time.Now().Truncate(time.Hour)
+	forecast, err := chain.GetCarbonForecast(context.Background(), "US-EAST", start, start.Add(3*time.Hour))
+	if err != nil || len(forecast) != 3 || forecast[0].Intensity != 180 {
+		t.Fatalf("expected secondary's 3-point forecast, got %+v (err %v)", forecast, err)
+	}
+	if primary.calls["US-EAST"] != 2 || secondary.calls["US-EAST"] != 2 {
+		t.Errorf("expected each provider called twice, got primary=%d secondary=%d",
+			primary.calls["US-EAST"], secondary.calls["US-EAST"])
+	}
+}
+
+func TestFallbackCarbonService_PrimaryAnswersFirst(t *testing.T) {
+	primary := newFakeCarbonService()
+	secondary := newFakeCarbonService()
+
+	chain := NewFallbackCarbonService(
+		NamedCarbonService{Name: "primary", Service: primary},
+		NamedCarbonService{Name: "secondary", Service: secondary},
+	)
+
+	if _, err := chain.GetCarbonIntensity(context.Background(), "US-EAST", time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secondary.calls["US-EAST"] != 0 {
+		t.Errorf("expected secondary untouched while primary answers, got %d calls", secondary.calls["US-EAST"])
+	}
+}
+
+func TestFallbackCarbonService_OpenPrimarySkipsToSecondary(t *testing.T) {
+	primary := newFakeCarbonService()
+	primary.failFor["US-EAST"] = true
+	breaker := NewCircuitBreaker(primary, CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Hour, NoStaticFallback: true})
+	secondary := newFakeCarbonService()
+	secondary.intensity = 180
+
+	chain := NewFallbackCarbonService(
+		NamedCarbonService{Name: "primary", Service: breaker},
+		NamedCarbonService{Name: "secondary", Service: secondary},
+	)
+
+	for i := 0; i < 3; i++ {
+		result, err := chain.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+		if err != nil || result.Intensity != 180 {
+			t.Fatalf("call %d: expected secondary's value, got %+v (err %v)", i, result, err)
+		}
+	}
+
+	if breaker.GetState() != StateOpen {
+		t.Fatalf("expected primary circuit open, got %s", breaker.GetState())
+	}
+	if primary.calls["US-EAST"] != 1 {
+		t.Errorf("expected open circuit to skip the primary after its first failure, got %d calls", primary.calls["US-EAST"])
+	}
+}
+
+func TestFallbackCarbonService_AllProvidersFail(t *testing.T) {
+	primary := newFakeCarbonService()
+	primary.failFor["US-EAST"] = true
+	failing := newFakeCarbonService()
+	failing.failFor["US-EAST"] = true
+	secondary := NewCircuitBreaker(failing, CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Hour, NoStaticFallback: true})
+
+	chain := NewFallbackCarbonService(
+		NamedCarbonService{Name: "primary", Service: primary},
+		NamedCarbonService{Name: "secondary", Service: secondary},
+	)
+
+	if _, err := chain.GetCarbonIntensity(context.Background(), "US-EAST", time.Now()); err == nil {
+		t.Fatal("expected an error when every provider fails")
+	}
+	// The secondary's circuit is now open, and its error is the one reported
+	if _, err := chain.GetCarbonIntensity(context.Background(), "US-EAST", time.Now()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the last provider's error, got %v", err)
+	}
+
+	if _, err := NewFallbackCarbonService().GetCarbonIntensity(context.Background(), "US-EAST", time.Now()); !errors.Is(err, ErrNoCarbonProviders) {
+		t.Errorf("expected ErrNoCarbonProviders for an empty chain, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package carbon
+
+import "sort"
+
+// DedupeForecast removes points that share a timestamp, keeping the one that appears
+// last in the input (providers list revised values after the originals). The result
+// is sorted by timestamp. The input slice is not modified.
+func DedupeForecast(points []CarbonIntensity) []CarbonIntensity {
+	if len(points) < 2 {
+		return points
+	}
+
+	index := make(map[int64]int, len(points)) // UnixNano -> position in result
+	result := make([]CarbonIntensity, 0, len(points))
+	for _, point := range points {
+		key := point.Timestamp.UnixNano()
+		if i, seen := index[key]; seen {
+			result[i] = point
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, point)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	return result
+}
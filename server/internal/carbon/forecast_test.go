@@ -0,0 +1,31 @@
+package carbon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupeForecast(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	points := []CarbonIntensity{
+		{Timestamp: start.Add(2 * time.Hour), Intensity: 150},
+		{Timestamp: start, Intensity: 300},
+		{Timestamp: start.Add(time.Hour), Intensity: 400},
+		{Timestamp: start.In(time.FixedZone("CET", 3600)), Intensity: 280}, // same instant, other zone
+	}
+
+	got := DedupeForecast(points)
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 unique timestamps, got %d", len(got))
+	}
+	want := []float64{280, 400, 150}
+	for i, point := range got {
+		if point.Intensity != want[i] {
+			t.Errorf("point %d: expected intensity %.0f, got %.0f", i, want[i], point.Intensity)
+		}
+	}
+	if points[1].Intensity != 300 {
+		t.Error("input slice should not be modified")
+	}
+}
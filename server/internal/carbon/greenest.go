@@ -0,0 +1,112 @@
+package carbon
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GreenestWindow is the cleanest upcoming window found in a region's forecast
+type GreenestWindow struct {
+	Region         string         `json:"region"`
+	StartTime      time.Time      `json:"start_time"`
+	EndTime        time.Time      `json:"end_time"`
+	AvgIntensity   float64        `json:"avg_intensity"`
+	IntensityScale IntensityScale `json:"intensity_scale"`
+	ComputedAt     time.Time      `json:"computed_at"` // When the forecast behind this window was fetched
+}
+
+// GreenestWindows keeps each region's greenest upcoming window, recomputed whenever a fresh
+// forecast arrives (the Prefetcher refreshes it), so readers never scan forecasts themselves
+type GreenestWindows struct {
+	mu         sync.RWMutex
+	windowSize time.Duration
+	byRegion   map[string]GreenestWindow
+}
+
+// NewGreenestWindows creates an empty view of windowSize-long windows (default one hour)
+func NewGreenestWindows(windowSize time.Duration) *GreenestWindows {
+	if windowSize <= 0 {
+		windowSize = time.Hour
+	}
+	return &GreenestWindows{
+		windowSize: windowSize,
+		byRegion:   make(map[string]GreenestWindow),
+	}
+}
+
+// Update recomputes a region's greenest window from its forecast. Points before the
+// current hour are ignored; the region keeps its previous window if nothing is left.
+func (g *GreenestWindows) Update(region string, forecast []CarbonIntensity, now time.Time) (GreenestWindow, bool) {
+	window, ok := greenestWindow(forecast, now.Truncate(time.Hour), g.windowSize)
+	if !ok {
+		return GreenestWindow{}, false
+	}
+	window.Region = region
+	window.ComputedAt = now
+
+	g.mu.Lock()
+	g.byRegion[region] = window
+	g.mu.Unlock()
+	return window, true
+}
+
+// Get returns a region's greenest window, if one has been computed
+func (g *GreenestWindows) Get(region string) (GreenestWindow, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	window, ok := g.byRegion[region]
+	return window, ok
+}
+
+// All returns every region's greenest window, ordered by region
+func (g *GreenestWindows) All() []GreenestWindow {
+	g.mu.RLock()
+	windows := make([]GreenestWindow, 0, len(g.byRegion))
+	for _, window := range g.byRegion {
+		windows = append(windows, window)
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Region < windows[j].Region })
+	return windows
+}
+
+// greenestWindow finds the run of consecutive hourly points from `from` onward, covering
+// windowSize, with the lowest average intensity. Ties go to the earliest window.
+func greenestWindow(forecast []CarbonIntensity, from time.Time, windowSize time.Duration) (GreenestWindow, bool) {
+	points := make([]CarbonIntensity, 0, len(forecast))
+	for _, point := range forecast {
+		if !point.Timestamp.Before(from) {
+			points = append(points, point)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	span := int(math.Ceil(windowSize.Hours()))
+	if span < 1 {
+		span = 1
+	}
+	if len(points) < span {
+		return GreenestWindow{}, false
+	}
+
+	best := GreenestWindow{AvgIntensity: math.Inf(1)}
+	for i := 0; i+span <= len(points); i++ {
+		var sum float64
+		for _, point := range points[i : i+span] {
+			sum += point.Intensity
+		}
+		if avg := sum / float64(span); avg < best.AvgIntensity {
+			best.StartTime = points[i].Timestamp
+			best.EndTime = points[i].Timestamp.Add(windowSize)
+			best.AvgIntensity = avg
+			best.IntensityScale = points[i].IntensityScale
+		}
+	}
+	if best.IntensityScale == "" {
+		best.IntensityScale = IntensityScaleAbsolute
+	}
+	return best, true
+}
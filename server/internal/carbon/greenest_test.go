@@ -0,0 +1,80 @@
+package carbon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGreenestWindowsPicksLowestUpcomingWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 20, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+	forecast := []CarbonIntensity{
+		{Timestamp: hour.Add(-time.Hour), Intensity: 10}, // Already past, never recommended
+		{Timestamp: hour.Add(2 * time.Hour), Intensity: 90},
+		{Timestamp: hour, Intensity: 300},
+		{Timestamp: hour.Add(time.Hour), Intensity: 120},
+		{Timestamp: hour.Add(3 * time.Hour), Intensity: 80},
+	}
+
+	view := NewGreenestWindows(2 * time.Hour)
+	window, ok := view.Update("DE", forecast, now)
+	if !ok {
+		t.Fatal("expected a window")
+	}
+
+	if !window.StartTime.Equal(hour.Add(2 * time.Hour)) {
+		t.Errorf("start = %v, want %v", window.StartTime, hour.Add(2*time.Hour))
+	}
+	if !window.EndTime.Equal(hour.Add(4 * time.Hour)) {
+		t.Errorf("end = %v, want %v", window.EndTime, hour.Add(4*time.Hour))
+	}
+	if window.AvgIntensity != 85 {
+		t.Errorf("avg intensity = %v, want 85", window.AvgIntensity)
+	}
+	if window.IntensityScale != IntensityScaleAbsolute {
+		t.Errorf("scale = %q, want absolute", window.IntensityScale)
+	}
+	if !window.ComputedAt.Equal(now) {
+		t.Errorf("computed_at = %v, want %v", window.ComputedAt, now)
+	}
+	if got, _ := view.Get("DE"); got != window {
+		t.Errorf("Get = %+v, want %+v", got, window)
+	}
+}
+
+func TestGreenestWindowsKeepsPreviousWindowWithoutForecast(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	view := NewGreenestWindows(time.Hour)
+	first, _ := view.Update("FR", []CarbonIntensity{{Timestamp: now, Intensity: 50}}, now)
+
+	if _, ok := view.Update("FR", nil, now.Add(time.Minute)); ok {
+		t.Error("expected no window from an empty forecast")
+	}
+	if got, _ := view.Get("FR"); got != first {
+		t.Errorf("window = %+v, want the previous %+v", got, first)
+	}
+}
+
+func TestPrefetcherRefreshesGreenestWindows(t *testing.T) {
+	fetcher := &prefetchFetcher{failing: map[string]bool{"BAD": true}}
+	view := NewGreenestWindows(time.Hour)
+	p := NewPrefetcher(fetcher, []string{"A", "B", "BAD"}, time.Minute)
+	p.SetGreenestWindows(view)
+
+	before := time.Now()
+	p.PrefetchOnce(context.Background())
+
+	windows := view.All()
+	if len(windows) != 2 || windows[0].Region != "A" || windows[1].Region != "B" {
+		t.Fatalf("windows = %+v, want A and B", windows)
+	}
+	for _, window := range windows {
+		if window.AvgIntensity != 100 {
+			t.Errorf("%s avg intensity = %v, want 100", window.Region, window.AvgIntensity)
+		}
+		if window.ComputedAt.Before(before) {
+			t.Errorf("%s computed_at = %v, want after %v", window.Region, window.ComputedAt, before)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package carbon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+)
+
+// expiredEntryDeleter removes cache entries for times more than maxAge in the past
+type expiredEntryDeleter interface {
+	DeleteExpiredEntries(ctx context.Context, maxAge time.Duration) (int64, error)
+}
+
+// CacheJanitor periodically removes stale entries from the carbon cache table, which
+// otherwise grows with every forecast fetched
+type CacheJanitor struct {
+	cache    expiredEntryDeleter
+	interval time.Duration
+	maxAge   time.Duration
+}
+
+// NewCacheJanitor creates a janitor that deletes entries older than maxAge every interval
+func NewCacheJanitor(cache expiredEntryDeleter, interval, maxAge time.Duration) *CacheJanitor {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if maxAge <= 0 {
+		maxAge = 7 * 24 * time.Hour
+	}
+	return &CacheJanitor{
+		cache:    cache,
+		interval: interval,
+		maxAge:   maxAge,
+	}
+}
+
+// CleanOnce deletes the expired entries and returns how many were removed
+func (j *CacheJanitor) CleanOnce(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return j.cache.DeleteExpiredEntries(ctx, j.maxAge)
+}
+
+// Run cleans immediately and then on every interval until ctx is cancelled
+func (j *CacheJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := j.CleanOnce(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Carbon cache cleanup failed", logging.Err(err))
+			}
+		} else {
+			slog.Info("Cleaned up carbon cache", "removed", removed, "max_age", j.maxAge)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
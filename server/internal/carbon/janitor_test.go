@@ -0,0 +1,83 @@
+package carbon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryCache holds cache entry timestamps and deletes them like the repository
+type memoryCache struct {
+	mu      sync.Mutex
+	entries []time.Time
+	runs    int
+}
+
+func (m *memoryCache) DeleteExpiredEntries(ctx context.Context, maxAge time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runs++
+	cutoff := time.Now().Add(-maxAge)
+	var kept []time.Time
+	for _, timestamp := range m.entries {
+		if !timestamp.Before(cutoff) {
+			kept = append(kept, timestamp)
+		}
+	}
+	removed := int64(len(m.entries) - len(kept))
+	m.entries = kept
+	return removed, nil
+}
+
+func TestCacheJanitor_RemovesOnlyOldEntries(t *testing.T) {
+	now := time.Now()
+	recent, forecast := now.Add(-time.Hour), now.Add(3*time.Hour)
+	cache := &memoryCache{entries: []time.Time{now.Add(-30 * 24 * time.Hour), recent, now.Add(-8 * 24 * time.Hour), forecast}}
+
+	janitor := NewCacheJanitor(cache, time.Hour, 7*24*time.Hour)
+	removed, err := janitor.CleanOnce(context.Background())
+	if err != nil {
+		t.Fatalf("CleanOnce returned error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 old entries removed, got %d", removed)
+	}
+	if len(cache.entries) != 2 || !cache.entries[0].Equal(recent) || !cache.entries[1].Equal(forecast) {
+		t.Errorf("expected the recent entry and the forecast to be kept, got %v", cache.entries)
+	}
+}
+
+func TestCacheJanitor_RunCleansOnEachTickUntilCancelled(t *testing.T) {
+	cache := &memoryCache{}
+	janitor := NewCacheJanitor(cache, 10*time.Millisecond, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cache.mu.Lock()
+		runs := cache.runs
+		cache.mu.Unlock()
+		if runs >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected repeated cleanups, got %d", runs)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
@@ -0,0 +1,216 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrCircuitOpen is reported for regions skipped because the circuit breaker is open
+var ErrCircuitOpen = errors.New("carbon circuit breaker is open")
+
+// forecastGetter is the subset of CarbonFetcher used by the Prefetcher
+type forecastGetter interface {
+	GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error)
+}
+
+// circuitState reports the state of the circuit breaker guarding the carbon API
+type circuitState interface {
+	GetState() CircuitState
+}
+
+// regionActivity reports the regions jobs were submitted for since a time
+type regionActivity interface {
+	GetActiveRegions(ctx context.Context, since time.Time) ([]string, error)
+}
+
+// Prefetcher periodically warms the carbon forecast cache for a fixed set of regions.
+// Regions are fetched concurrently with at most concurrency in-flight calls; a slow or
+// failing region only occupies its own slot and never cancels the others.
+type Prefetcher struct {
+	fetcher       forecastGetter
+	breaker       circuitState
+	regions       []string
+	interval      time.Duration
+	horizon       time.Duration
+	concurrency   int
+	regionTimeout time.Duration
+	greenest      *GreenestWindows // Optional: recomputed from each region's fresh forecast
+
+	activity     regionActivity // Optional: regions without recent jobs are skipped
+	activeWithin time.Duration
+}
+
+// NewPrefetcher creates a new forecast prefetcher
+func NewPrefetcher(fetcher forecastGetter, regions []string, interval time.Duration) *Prefetcher {
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	return &Prefetcher{
+		fetcher:       fetcher,
+		regions:       regions,
+		interval:      interval,
+		horizon:       24 * time.Hour,
+		concurrency:   4,
+		regionTimeout: 30 * time.Second,
+	}
+}
+
+// SetConcurrency updates the maximum number of regions fetched at the same time
+func (p *Prefetcher) SetConcurrency(n int) {
+	if n <= 0 {
+		n = 1
+	}
+	p.concurrency = n
+}
+
+// SetRegionTimeout updates how long a single region's fetch may take
+func (p *Prefetcher) SetRegionTimeout(d time.Duration) {
+	if d > 0 {
+		p.regionTimeout = d
+	}
+}
+
+// SetCircuitBreaker makes the prefetcher skip regions while the breaker is open,
+// so fallback forecasts are not written over real cached data
+func (p *Prefetcher) SetCircuitBreaker(breaker *CircuitBreaker) {
+	if breaker == nil {
+		// Avoid storing a typed nil in the interface
+		p.breaker = nil
+		return
+	}
+	p.breaker = breaker
+}
+
+// SetGreenestWindows makes the prefetcher refresh each region's greenest window
+// whenever it fetches the region's forecast
+func (p *Prefetcher) SetGreenestWindows(greenest *GreenestWindows) {
+	p.greenest = greenest
+}
+
+// SetRegionActivity makes the prefetcher skip regions no job was submitted for within
+// the last window, saving API quota on regions nobody uses. A zero window prefetches
+// every region.
+func (p *Prefetcher) SetRegionActivity(activity regionActivity, window time.Duration) {
+	p.activity = activity
+	p.activeWithin = window
+}
+
+// activeRegions returns the configured regions that had a job within the activity
+// window. If recent activity can't be looked up, every region is returned.
+func (p *Prefetcher) activeRegions(ctx context.Context) []string {
+	if p.activity == nil || p.activeWithin <= 0 {
+		return p.regions
+	}
+
+	recent, err := p.activity.GetActiveRegions(ctx, time.Now().Add(-p.activeWithin))
+	if err != nil {
+		slog.Warn("Failed to look up active regions, prefetching all of them", logging.Err(err))
+		return p.regions
+	}
+	active := make(map[string]bool, len(recent))
+	for _, region := range recent {
+		active[region] = true
+	}
+
+	var regions []string
+	for _, region := range p.regions {
+		if active[region] {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// PrefetchOnce fetches the forecast horizon for every active region and returns the
+// per-region errors (empty when every region succeeded)
+func (p *Prefetcher) PrefetchOnce(ctx context.Context) map[string]error {
+	return p.prefetch(ctx, p.activeRegions(ctx))
+}
+
+// prefetch fetches the forecast horizon for regions
+func (p *Prefetcher) prefetch(ctx context.Context, regions []string) map[string]error {
+	start := time.Now().UTC().Truncate(time.Hour)
+	end := start.Add(p.horizon)
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	var g errgroup.Group
+	g.SetLimit(p.concurrency)
+
+	for _, region := range regions {
+		region := region
+		g.Go(func() error {
+			if err := p.prefetchRegion(ctx, region, start, end); err != nil {
+				mu.Lock()
+				errs[region] = err
+				mu.Unlock()
+			}
+			// Failures are recorded per region rather than returned so one region
+			// never affects the outcome of the others
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return errs
+}
+
+// prefetchRegion fetches one region's forecast under its own timeout
+func (p *Prefetcher) prefetchRegion(ctx context.Context, region string, start, end time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.breaker != nil && p.breaker.GetState() == StateOpen {
+		return ErrCircuitOpen
+	}
+
+	regionCtx, cancel := context.WithTimeout(ctx, p.regionTimeout)
+	defer cancel()
+
+	forecast, err := p.fetcher.GetCarbonForecast(regionCtx, region, start, end)
+	if err != nil {
+		return err
+	}
+	if err := regionCtx.Err(); err != nil {
+		return err
+	}
+
+	if p.greenest != nil {
+		p.greenest.Update(region, forecast, time.Now())
+	}
+	return nil
+}
+
+// Run prefetches immediately and then on every interval until ctx is cancelled
+func (p *Prefetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		regions := p.activeRegions(ctx)
+		p.logResult(len(regions), p.prefetch(ctx, regions))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Prefetcher) logResult(fetched int, errs map[string]error) {
+	if len(errs) == 0 {
+		slog.Info("Prefetched carbon forecasts", "regions", fetched, "idle_regions", len(p.regions)-fetched)
+		return
+	}
+	for region, err := range errs {
+		slog.Warn("Carbon forecast prefetch failed", logging.KeyRegion, region, logging.Err(err))
+	}
+}
@@ -0,0 +1,179 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type prefetchFetcher struct {
+	mu       sync.Mutex
+	inFlight int32
+	maxSeen  int32
+	delay    time.Duration
+	slow     map[string]bool
+	failing  map[string]bool
+	fetched  []string
+}
+
+func (f *prefetchFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonIntensity, error) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&f.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&f.maxSeen, seen, n) {
+			break
+		}
+	}
+
+	if f.slow[region] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(f.delay)
+	if f.failing[region] {
+		return nil, errors.New("upstream error")
+	}
+
+	f.mu.Lock()
+	f.fetched = append(f.fetched, region)
+	f.mu.Unlock()
+	return []CarbonIntensity{{Region: region, Timestamp: startTime, Intensity: 100}}, nil
+}
+
+func TestPrefetcherBoundsConcurrency(t *testing.T) {
+	fetcher := &prefetchFetcher{delay: 20 * time.Millisecond}
+	regions := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	p := NewPrefetcher(fetcher, regions, time.Minute)
+	p.SetConcurrency(3)
+
+	if errs := p.PrefetchOnce(context.Background()); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if got := atomic.LoadInt32(&fetcher.maxSeen); got > 3 {
+		t.Errorf("max in-flight fetches = %d, want at most 3", got)
+	}
+	if len(fetcher.fetched) != len(regions) {
+		t.Errorf("fetched %d regions, want %d", len(fetcher.fetched), len(regions))
+	}
+}
+
+func TestPrefetcherIsolatesFailingAndSlowRegions(t *testing.T) {
+	fetcher := &prefetchFetcher{
+		slow:    map[string]bool{"SLOW": true},
+		failing: map[string]bool{"BAD": true},
+	}
+	p := NewPrefetcher(fetcher, []string{"SLOW", "BAD", "A", "B"}, time.Minute)
+	p.SetConcurrency(2)
+	p.SetRegionTimeout(50 * time.Millisecond)
+
+	errs := p.PrefetchOnce(context.Background())
+
+	if !errors.Is(errs["SLOW"], context.DeadlineExceeded) {
+		t.Errorf("SLOW error = %v, want deadline exceeded", errs["SLOW"])
+	}
+	if errs["BAD"] == nil {
+		t.Error("expected an error for BAD")
+	}
+	if len(errs) != 2 {
+		t.Errorf("errors = %v, want only SLOW and BAD", errs)
+	}
+	if len(fetcher.fetched) != 2 {
+		t.Errorf("fetched = %v, want A and B", fetcher.fetched)
+	}
+}
+
+func TestPrefetcherSkipsRegionsWhileCircuitOpen(t *testing.T) {
+	fetcher := &prefetchFetcher{}
+	p := NewPrefetcher(fetcher, []string{"A"}, time.Minute)
+	p.breaker = stubCircuit(StateOpen)
+
+	errs := p.PrefetchOnce(context.Background())
+	if !errors.Is(errs["A"], ErrCircuitOpen) {
+		t.Errorf("error = %v, want ErrCircuitOpen", errs["A"])
+	}
+	if len(fetcher.fetched) != 0 {
+		t.Errorf("fetched = %v, want nothing while the circuit is open", fetcher.fetched)
+	}
+}
+
+// stubActivity reports fixed recently active regions
+type stubActivity struct {
+	regions []string
+	err     error
+	since   time.Time
+}
+
+func (s *stubActivity) GetActiveRegions(ctx context.Context, since time.Time) ([]string, error) {
+	s.since = since
+	return s.regions, s.err
+}
+
+func TestPrefetcherSkipsIdleRegions(t *testing.T) {
+	fetcher := &prefetchFetcher{}
+	activity := &stubActivity{regions: []string{"B", "ELSEWHERE"}}
+	p := NewPrefetcher(fetcher, []string{"A", "B", "C"}, time.Minute)
+	p.SetRegionActivity(activity, 6*time.Hour)
+
+	if errs := p.PrefetchOnce(context.Background()); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(fetcher.fetched) != 1 || fetcher.fetched[0] != "B" {
+		t.Errorf("fetched = %v, want only the active region B", fetcher.fetched)
+	}
+	if window := time.Since(activity.since); window < 6*time.Hour || window > 6*time.Hour+time.Minute {
+		t.Errorf("expected activity over the last 6h, looked since %v", activity.since)
+	}
+
+	// Without activity data, every region stays warm
+	fetcher.fetched = nil
+	activity.err = errors.New("database down")
+	p.PrefetchOnce(context.Background())
+	if len(fetcher.fetched) != 3 {
+		t.Errorf("fetched = %v, want all regions when activity can't be looked up", fetcher.fetched)
+	}
+}
+
+func TestPrefetcherRunFetchesEveryRegionOnEachTick(t *testing.T) {
+	fetcher := &prefetchFetcher{}
+	p := NewPrefetcher(fetcher, []string{"A", "B"}, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	// The first round runs at once; wait for two more ticks
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		fetcher.mu.Lock()
+		counts := map[string]int{}
+		for _, region := range fetcher.fetched {
+			counts[region]++
+		}
+		fetcher.mu.Unlock()
+		if counts["A"] >= 3 && counts["B"] >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected each region fetched on every tick, got %v", counts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+type stubCircuit CircuitState
+
+func (s stubCircuit) GetState() CircuitState { return CircuitState(s) }
@@ -17,43 +17,108 @@ type Config struct {
 	Worker         WorkerConfig
 	Docker         DockerConfig
 	Carbon         CarbonConfig
+	Scheduler      SchedulerConfig
 	Promoter       PromoterConfig
+	Acceptance     AcceptanceConfig
 	CircuitBreaker CircuitBreakerConfig
 	Metrics        MetricsConfig
+	SLO            SLOConfig
+	Log            LogConfig
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
 	Port        string
 	Environment string
-	RateLimit   string
+	RateLimit   string // Requests allowed per client (API key, else IP) per RateLimitWindow; "0" disables
 	Timeout     string
+	AdminToken  string // Bearer token for /api/admin endpoints (empty disables them)
+
+	RateLimitWindow string // Fixed window the rate limit applies to (default "1m")
+
+	StreamWriteTimeout string // Write timeout for streaming routes (paths ending in /stream); others keep 10s
+
+	UserTokenSecret string // Signs per-user bearer tokens for /api/users/:userId endpoints (empty disables them)
+
+	LegacyCreatedStatus bool // Answer deferred submissions with 201 instead of 202
+
+	JobArchiveAfter string // Soft-delete finished jobs created longer ago than this, checked hourly ("" = keep them listed)
 }
 
 // WorkerConfig holds worker pool configuration
 type WorkerConfig struct {
-	PoolSize     int
-	PollInterval string
-	JobTimeout   string
-	MaxRetries   int
+	PoolSize      int
+	PollInterval  string
+	JobTimeout    string
+	StepTimeout   string // Run time allowed per step of a multi-step job ("" or "0" = job timeout / step count)
+	MaxRetries    int
+	PrefetchDepth int // Upcoming jobs whose images a busy worker pre-pulls (0 = off)
+
+	MaxConcurrentContainers int // Containers run at once however many consumers poll (0 = one per consumer)
+
+	PollMaxInterval string // Longest wait between polls of an empty queue with the backoff strategy (default "10s")
+	PollStrategy    string // "backoff" (default) or "fixed" polling while the queue is empty
+
+	SuccessOutputTailBytes int // Store only this many trailing bytes of output for successful jobs (0 = all)
+
+	NodeID string // Stable ID of this worker node ("" = random per process)
+
+	ImageProfiles string // JSON array of per-image defaults (pattern, command, timeout, memory_limit_mb, cpu_quota)
+
+	HealthPort  string // Port serving GET /healthz ("" = no health server)
+	MetricsPort string // Port serving the worker's GET /metrics when metrics are enabled ("" = off)
 }
 
 // DockerConfig holds Docker daemon configuration
 type DockerConfig struct {
-	Host        string
-	MemoryLimit int64
-	CPUQuota    int64
+	Host           string
+	MemoryLimit    int64 // Default container memory limit in bytes
+	CPUQuota       int64 // Default container CPU quota (100000 = one CPU)
+	MaxMemoryLimit int64 // Upper bound for per-job memory overrides
+	MaxCPUQuota    int64 // Upper bound for per-job CPU overrides
+	MaxOutputBytes int64 // Container output kept per run; the rest is dropped (0 = unbounded)
+
+	RegistryHost     string // Private registry the credentials below apply to ("docker.io" for Docker Hub)
+	RegistryUsername string
+	RegistryPassword string
+	RegistryToken    string // Bearer token, used instead of username/password
+
+	VolumeAllowlist string // Comma-separated host directories and volume names jobs may mount (empty = no mounts)
+
+	NetworkMode       string // Network of jobs without network access (default "none": no network at all)
+	NetworkAccessMode string // Network of jobs submitted with network_access (default "bridge"; empty refuses them)
+
+	ImageAllowlist string // Comma-separated registries and repository globs jobs may run (empty = any)
+	ImageDenylist  string // Comma-separated registries and repository globs jobs may never run
 }
 
 // CarbonConfig holds carbon service configuration
 type CarbonConfig struct {
-	Provider    string // "electricitymaps" or "watttime"
+	Provider    string // "electricitymaps", "watttime" or "csv"
+	Providers   string // Comma-separated fallback chain in priority order ("" = Provider alone)
 	APIKey      string
 	APIUsername string // For WattTime
 	APIPassword string // For WattTime
 	BaseURL     string
 	CacheTTL    string // Cache time-to-live (default "1h")
 	Region      string // Default region
+	SlotCap     int    // Max delayed jobs per region and time slot (0 = unlimited)
+	CSVPath     string // Intensity data file for the "csv" provider
+
+	CacheFailFastAfter int // Consecutive cache errors before lookups stop falling back to the API (0 = never)
+
+	CacheCleanupInterval string // How often expired cache entries are deleted (default "1h", "0" = never)
+	CacheMaxAge          string // Entries for times further in the past are deleted (default "168h")
+
+	PrefetchRegions      string // Comma-separated regions whose forecasts are kept warm ("" = disabled)
+	PrefetchInterval     string // How often to refresh prefetched forecasts (default "30m")
+	PrefetchConcurrency  int    // Max regions fetched at the same time (default 4)
+	PrefetchActiveWithin string // Skip regions without a job submitted this recently ("" = prefetch every region)
+}
+
+// SchedulerConfig holds carbon-aware scheduling limits
+type SchedulerConfig struct {
+	MaxDeferral string // Longest a job may wait to start after submission, whatever its deadline ("" = until the deadline)
 }
 
 // PromoterConfig holds delayed job promoter configuration
@@ -61,18 +126,43 @@ type PromoterConfig struct {
 	CheckInterval string // How often to check for ready jobs (default "10s")
 }
 
+// AcceptanceConfig holds the weekly windows during which jobs are accepted and promoted,
+// e.g. "Mon-Fri 18:00-08:00; Sat,Sun 00:00-24:00" ("" = always)
+type AcceptanceConfig struct {
+	SubmissionWindows string // When POST /api/submit accepts jobs
+	PromotionWindows  string // When the promoter moves due delayed jobs to the immediate queue
+	Timezone          string // IANA time zone the windows are written in (default "UTC")
+}
+
 // CircuitBreakerConfig holds circuit breaker configuration
 type CircuitBreakerConfig struct {
-	MaxFailures    int    // Number of failures before opening circuit (default 5)
-	Timeout        string // How long to wait before trying again (default "30s")
-	ResetTimeout   string // How long to stay in half-open before closing (default "10s")
-	StaticFallback string // Static carbon intensity value when circuit is open (default "400.0")
+	MaxFailures      int    // Number of failures before opening circuit (default 5)
+	Timeout          string // How long to wait before trying again (default "30s")
+	ResetTimeout     string // How long to stay in half-open before closing (default "10s")
+	SuccessThreshold int    // Consecutive half-open successes needed to close the circuit (default 1)
+	StaticFallback   string // Static carbon intensity value when circuit is open (default "400.0")
 }
 
 // MetricsConfig holds metrics exposure configuration
 type MetricsConfig struct {
 	Enabled bool   // Enable Prometheus metrics (default true)
 	Port    string // Metrics endpoint port (default "9090")
+
+	AssumedPowerWatts int // Power draw assumed per job when estimating CO2 savings (default 50W)
+}
+
+// SLOConfig holds the start-time service level objective: Target of the jobs due within
+// Window start no later than StartThreshold after their scheduled time
+type SLOConfig struct {
+	StartThreshold string // Allowed start delay (default "1m")
+	Target         string // Fraction (0.99) or percentage (99) of jobs that must start on time (default "0.99")
+	Window         string // Rolling window compliance is computed over (default "24h")
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Format string // "json" or "text" ("" = text in development, JSON otherwise)
+	Level  string // debug, info, warn or error (default "info")
 }
 
 // DatabaseConfig holds database connection configuration
@@ -92,6 +182,10 @@ type RedisConfig struct {
 type QueueConfig struct {
 	ImmediateQueueKey string
 	DelayedSetKey     string
+	DeadLetterKey     string
+
+	DeadLetterMaxReplays    int    // Replays before a dead-letter job is excluded from bulk replay (0 = unlimited)
+	DeadLetterReplayBackoff string // Delay before a job's second replay, doubling after each further replay
 }
 
 // LoadConfig loads configuration from environment variables
@@ -107,6 +201,17 @@ func LoadConfig() (*Config, error) {
 			Environment: getEnv("ENV", "development"),
 			RateLimit:   getEnv("API_RATE_LIMIT", "100"),
 			Timeout:     getEnv("API_TIMEOUT", "30s"),
+			AdminToken:  getEnv("ADMIN_API_TOKEN", ""),
+
+			RateLimitWindow: getEnv("API_RATE_LIMIT_WINDOW", "1m"),
+
+			StreamWriteTimeout: getEnv("API_STREAM_WRITE_TIMEOUT", "1h"),
+
+			UserTokenSecret: getEnv("USER_TOKEN_SECRET", ""),
+
+			LegacyCreatedStatus: getEnvAsBool("API_LEGACY_CREATED_STATUS", false),
+
+			JobArchiveAfter: getEnv("JOB_ARCHIVE_AFTER", ""),
 		},
 		Database: DatabaseConfig{
 			URL: getEnv("DATABASE_URL", ""),
@@ -120,39 +225,108 @@ func LoadConfig() (*Config, error) {
 		Queue: QueueConfig{
 			ImmediateQueueKey: getEnv("IMMEDIATE_QUEUE_KEY", "karbos:queue:immediate"),
 			DelayedSetKey:     getEnv("DELAYED_SET_KEY", "karbos:queue:delayed"),
+			DeadLetterKey:     getEnv("DEAD_LETTER_QUEUE_KEY", "karbos:queue:dead"),
+
+			DeadLetterMaxReplays:    getEnvAsInt("DEAD_LETTER_MAX_REPLAYS", 3),
+			DeadLetterReplayBackoff: getEnv("DEAD_LETTER_REPLAY_BACKOFF", "1m"),
 		},
 		Worker: WorkerConfig{
-			PoolSize:     getEnvAsInt("WORKER_POOL_SIZE", 5),
-			PollInterval: getEnv("WORKER_POLL_INTERVAL", "2s"),
-			JobTimeout:   getEnv("WORKER_JOB_TIMEOUT", "10m"),
-			MaxRetries:   getEnvAsInt("WORKER_MAX_RETRIES", 3),
+			PoolSize:      getEnvAsInt("WORKER_POOL_SIZE", 5),
+			PollInterval:  getEnv("WORKER_POLL_INTERVAL", "2s"),
+			JobTimeout:    getEnv("WORKER_JOB_TIMEOUT", "10m"),
+			StepTimeout:   getEnv("WORKER_STEP_TIMEOUT", ""),
+			MaxRetries:    getEnvAsInt("WORKER_MAX_RETRIES", 3),
+			PrefetchDepth: getEnvAsInt("WORKER_PREFETCH_DEPTH", 0),
+
+			MaxConcurrentContainers: getEnvAsInt("WORKER_MAX_CONCURRENT_CONTAINERS", 0),
+
+			PollMaxInterval: getEnv("WORKER_POLL_MAX_INTERVAL", "10s"),
+			PollStrategy:    getEnv("WORKER_POLL_STRATEGY", "backoff"),
+
+			SuccessOutputTailBytes: getEnvAsInt("WORKER_SUCCESS_OUTPUT_TAIL_BYTES", 0),
+
+			NodeID: getEnv("WORKER_NODE_ID", ""),
+
+			ImageProfiles: getEnv("WORKER_IMAGE_PROFILES", ""),
+
+			HealthPort:  getEnv("WORKER_HEALTH_PORT", "8081"),
+			MetricsPort: getEnv("WORKER_METRICS_PORT", "9091"),
 		},
 		Docker: DockerConfig{
-			Host:        getEnv("DOCKER_HOST", ""),
-			MemoryLimit: getEnvAsInt64("DOCKER_MEMORY_LIMIT", 536870912), // 512MB
-			CPUQuota:    getEnvAsInt64("DOCKER_CPU_QUOTA", 50000),        // 50% of one CPU
+			Host:           getEnv("DOCKER_HOST", ""),
+			MemoryLimit:    getEnvAsInt64("DOCKER_MEMORY_LIMIT", 536870912),      // 512MB
+			CPUQuota:       getEnvAsInt64("DOCKER_CPU_QUOTA", 50000),             // 50% of one CPU
+			MaxMemoryLimit: getEnvAsInt64("DOCKER_MAX_MEMORY_LIMIT", 2147483648), // 2GB
+			MaxCPUQuota:    getEnvAsInt64("DOCKER_MAX_CPU_QUOTA", 200000),        // Two CPUs
+			MaxOutputBytes: getEnvAsInt64("DOCKER_MAX_OUTPUT_BYTES", 1048576),    // 1MB
+
+			RegistryHost:     getEnv("DOCKER_REGISTRY_HOST", ""),
+			RegistryUsername: getEnv("DOCKER_REGISTRY_USERNAME", ""),
+			RegistryPassword: getEnv("DOCKER_REGISTRY_PASSWORD", ""),
+			RegistryToken:    getEnv("DOCKER_REGISTRY_TOKEN", ""),
+
+			VolumeAllowlist: getEnv("DOCKER_VOLUME_ALLOWLIST", ""),
+
+			NetworkMode:       getEnv("DOCKER_NETWORK_MODE", "none"),
+			NetworkAccessMode: getEnv("DOCKER_NETWORK_ACCESS_MODE", "bridge"),
+
+			ImageAllowlist: getEnv("DOCKER_IMAGE_ALLOWLIST", ""),
+			ImageDenylist:  getEnv("DOCKER_IMAGE_DENYLIST", ""),
 		},
 		Carbon: CarbonConfig{
 			Provider:    getEnv("CARBON_PROVIDER", "electricitymaps"),
+			Providers:   getEnv("CARBON_PROVIDERS", ""),
 			APIKey:      getEnv("CARBON_API_KEY", ""),
 			APIUsername: getEnv("CARBON_API_USERNAME", ""),
 			APIPassword: getEnv("CARBON_API_PASSWORD", ""),
 			BaseURL:     getEnv("CARBON_API_URL", ""),
 			CacheTTL:    getEnv("CARBON_CACHE_TTL", "1h"),
 			Region:      getEnv("CARBON_DEFAULT_REGION", "US-EAST"),
+			SlotCap:     getEnvAsInt("CARBON_REGION_SLOT_CAP", 0),
+			CSVPath:     getEnv("CARBON_CSV_PATH", ""),
+
+			CacheFailFastAfter: getEnvAsInt("CARBON_CACHE_FAIL_FAST_AFTER", 0),
+
+			CacheCleanupInterval: getEnv("CARBON_CACHE_CLEANUP_INTERVAL", "1h"),
+			CacheMaxAge:          getEnv("CARBON_CACHE_MAX_AGE", "168h"),
+
+			PrefetchRegions:      getEnv("CARBON_PREFETCH_REGIONS", ""),
+			PrefetchInterval:     getEnv("CARBON_PREFETCH_INTERVAL", "30m"),
+			PrefetchConcurrency:  getEnvAsInt("CARBON_PREFETCH_CONCURRENCY", 4),
+			PrefetchActiveWithin: getEnv("CARBON_PREFETCH_ACTIVE_WITHIN", ""),
+		},
+		Scheduler: SchedulerConfig{
+			MaxDeferral: getEnv("SCHEDULER_MAX_DEFERRAL", ""),
 		},
 		Promoter: PromoterConfig{
 			CheckInterval: getEnv("PROMOTER_CHECK_INTERVAL", "10s"),
 		},
+		Acceptance: AcceptanceConfig{
+			SubmissionWindows: getEnv("ACCEPTANCE_SUBMISSION_WINDOWS", ""),
+			PromotionWindows:  getEnv("ACCEPTANCE_PROMOTION_WINDOWS", ""),
+			Timezone:          getEnv("ACCEPTANCE_TIMEZONE", "UTC"),
+		},
 		CircuitBreaker: CircuitBreakerConfig{
-			MaxFailures:    getEnvAsInt("CIRCUIT_BREAKER_MAX_FAILURES", 5),
-			Timeout:        getEnv("CIRCUIT_BREAKER_TIMEOUT", "30s"),
-			ResetTimeout:   getEnv("CIRCUIT_BREAKER_RESET_TIMEOUT", "10s"),
-			StaticFallback: getEnv("CIRCUIT_BREAKER_STATIC_FALLBACK", "400.0"),
+			MaxFailures:      getEnvAsInt("CIRCUIT_BREAKER_MAX_FAILURES", 5),
+			Timeout:          getEnv("CIRCUIT_BREAKER_TIMEOUT", "30s"),
+			ResetTimeout:     getEnv("CIRCUIT_BREAKER_RESET_TIMEOUT", "10s"),
+			SuccessThreshold: getEnvAsInt("CIRCUIT_BREAKER_SUCCESS_THRESHOLD", 1),
+			StaticFallback:   getEnv("CIRCUIT_BREAKER_STATIC_FALLBACK", "400.0"),
 		},
 		Metrics: MetricsConfig{
 			Enabled: getEnvAsBool("METRICS_ENABLED", true),
 			Port:    getEnv("METRICS_PORT", "9090"),
+
+			AssumedPowerWatts: getEnvAsInt("METRICS_ASSUMED_POWER_WATTS", 50),
+		},
+		SLO: SLOConfig{
+			StartThreshold: getEnv("SLO_START_THRESHOLD", "1m"),
+			Target:         getEnv("SLO_TARGET", "0.99"),
+			Window:         getEnv("SLO_WINDOW", "24h"),
+		},
+		Log: LogConfig{
+			Format: getEnv("LOG_FORMAT", ""),
+			Level:  getEnv("LOG_LEVEL", "info"),
 		},
 	}
 
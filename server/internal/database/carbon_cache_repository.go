@@ -28,6 +28,10 @@ type CarbonCacheEntry struct {
 	ForecastWindow *int      `json:"forecast_window,omitempty"`
 	Source         *string   `json:"source,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
+
+	RenewablePercentage *float64 `json:"renewable_percentage,omitempty"` // nil when the provider doesn't report it
+	FossilPercentage    *float64 `json:"fossil_percentage,omitempty"`
+	IntensityScale      string   `json:"intensity_scale"` // "absolute" (gCO2/kWh) or "relative" (provider index)
 }
 
 // CarbonIntensity is a local type for saving data (avoids circular import)
@@ -36,28 +40,77 @@ type CarbonIntensity struct {
 	Timestamp time.Time
 	Intensity float64
 	Unit      string
+
+	RenewablePercentage *float64
+	FossilPercentage    *float64
+	IntensityScale      string // "absolute" or "relative"
+	Source              string // Where the point came from (default "api")
 }
 
-// SaveCarbonIntensity saves carbon intensity data to cache
-func (r *CarbonCacheRepository) SaveCarbonIntensity(ctx context.Context, region string, timestamp time.Time, intensity float64, unit string, ttl time.Duration) error {
-	query := `
-		INSERT INTO carbon_cache (id, region, timestamp, intensity_value, source)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (region, timestamp, forecast_window) 
-		DO UPDATE SET 
-			intensity_value = EXCLUDED.intensity_value,
-			source = EXCLUDED.source
-	`
+// upsertCarbonCacheQuery inserts a cache row or refreshes an existing one
+const upsertCarbonCacheQuery = `
+	INSERT INTO carbon_cache (id, region, timestamp, intensity_value, source, renewable_percentage, fossil_percentage, intensity_scale)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (region, timestamp, forecast_window) 
+	DO UPDATE SET 
+		intensity_value = EXCLUDED.intensity_value,
+		source = EXCLUDED.source,
+		renewable_percentage = EXCLUDED.renewable_percentage,
+		fossil_percentage = EXCLUDED.fossil_percentage,
+		intensity_scale = EXCLUDED.intensity_scale
+`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCarbonCacheEntry scans a carbon_cache row in the column order used by the SELECT queries
+func scanCarbonCacheEntry(row rowScanner, entry *CarbonCacheEntry) error {
+	return row.Scan(
+		&entry.ID,
+		&entry.Region,
+		&entry.Timestamp,
+		&entry.IntensityValue,
+		&entry.ForecastWindow,
+		&entry.Source,
+		&entry.CreatedAt,
+		&entry.RenewablePercentage,
+		&entry.FossilPercentage,
+		&entry.IntensityScale,
+	)
+}
+
+// intensityScaleOrDefault treats an unset scale as absolute gCO2/kWh
+func intensityScaleOrDefault(scale string) string {
+	if scale == "" {
+		return "absolute"
+	}
+	return scale
+}
 
+// sourceOrDefault treats an unset source as the carbon API
+func sourceOrDefault(source string) string {
+	if source == "" {
+		return "api"
+	}
+	return source
+}
+
+// SaveCarbonIntensity saves carbon intensity data to cache
+func (r *CarbonCacheRepository) SaveCarbonIntensity(ctx context.Context, data CarbonIntensity, ttl time.Duration) error {
 	id := uuid.New()
-	source := "api"
+	source := sourceOrDefault(data.Source)
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.db.ExecContext(ctx, upsertCarbonCacheQuery,
 		id,
-		region,
-		timestamp,
-		intensity,
+		data.Region,
+		data.Timestamp,
+		data.Intensity,
 		&source,
+		data.RenewablePercentage,
+		data.FossilPercentage,
+		intensityScaleOrDefault(data.IntensityScale),
 	)
 
 	if err != nil {
@@ -70,7 +123,8 @@ func (r *CarbonCacheRepository) SaveCarbonIntensity(ctx context.Context, region
 // GetCarbonIntensity retrieves cached carbon intensity data
 func (r *CarbonCacheRepository) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*CarbonCacheEntry, error) {
 	query := `
-		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at
+		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at,
+			renewable_percentage, fossil_percentage, intensity_scale
 		FROM carbon_cache
 		WHERE region = $1 
 			AND timestamp >= $2 - INTERVAL '15 minutes'
@@ -80,15 +134,7 @@ func (r *CarbonCacheRepository) GetCarbonIntensity(ctx context.Context, region s
 	`
 
 	var entry CarbonCacheEntry
-	err := r.db.QueryRowContext(ctx, query, region, timestamp).Scan(
-		&entry.ID,
-		&entry.Region,
-		&entry.Timestamp,
-		&entry.IntensityValue,
-		&entry.ForecastWindow,
-		&entry.Source,
-		&entry.CreatedAt,
-	)
+	err := scanCarbonCacheEntry(r.db.QueryRowContext(ctx, query, region, timestamp), &entry)
 
 	if err == sql.ErrNoRows {
 		return nil, nil // Cache miss
@@ -104,7 +150,8 @@ func (r *CarbonCacheRepository) GetCarbonIntensity(ctx context.Context, region s
 // GetCarbonForecast retrieves cached forecast data within a time range
 func (r *CarbonCacheRepository) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonCacheEntry, error) {
 	query := `
-		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at
+		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at,
+			renewable_percentage, fossil_percentage, intensity_scale
 		FROM carbon_cache
 		WHERE region = $1 
 			AND timestamp BETWEEN $2 AND $3
@@ -120,16 +167,7 @@ func (r *CarbonCacheRepository) GetCarbonForecast(ctx context.Context, region st
 	var entries []CarbonCacheEntry
 	for rows.Next() {
 		var entry CarbonCacheEntry
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Region,
-			&entry.Timestamp,
-			&entry.IntensityValue,
-			&entry.ForecastWindow,
-			&entry.Source,
-			&entry.CreatedAt,
-		)
-		if err != nil {
+		if err := scanCarbonCacheEntry(rows, &entry); err != nil {
 			return nil, fmt.Errorf("failed to scan carbon cache entry: %w", err)
 		}
 		entries = append(entries, entry)
@@ -143,11 +181,13 @@ func (r *CarbonCacheRepository) IsCacheFresh(entry *CarbonCacheEntry, maxAge tim
 	return time.Since(entry.CreatedAt) < maxAge
 }
 
-// DeleteExpiredEntries removes expired cache entries older than maxAge
+// DeleteExpiredEntries removes cache entries for times more than maxAge in the past.
+// Entries are aged by their timestamp rather than created_at, which refreshes don't
+// move, so forecasts that are still being updated are never removed.
 func (r *CarbonCacheRepository) DeleteExpiredEntries(ctx context.Context, maxAge time.Duration) (int64, error) {
-	query := `DELETE FROM carbon_cache WHERE created_at <= NOW() - $1::INTERVAL`
+	query := `DELETE FROM carbon_cache WHERE timestamp < $1`
 
-	result, err := r.db.ExecContext(ctx, query, maxAge)
+	result, err := r.db.ExecContext(ctx, query, time.Now().Add(-maxAge))
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired cache entries: %w", err)
 	}
@@ -160,6 +200,29 @@ func (r *CarbonCacheRepository) DeleteExpiredEntries(ctx context.Context, maxAge
 	return rowsAffected, nil
 }
 
+// DeleteCarbonIntensityRange removes a region's entries from source with timestamps
+// between start and end (inclusive), returning how many were removed
+func (r *CarbonCacheRepository) DeleteCarbonIntensityRange(ctx context.Context, region string, start, end time.Time, source string) (int64, error) {
+	query := `
+		DELETE FROM carbon_cache
+		WHERE region = $1
+			AND timestamp BETWEEN $2 AND $3
+			AND source = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, region, start, end, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete carbon intensity range: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
 // GetCacheStats returns cache statistics
 func (r *CarbonCacheRepository) GetCacheStats(ctx context.Context) (map[string]interface{}, error) {
 	var totalEntries, validEntries, expiredEntries int
@@ -186,6 +249,15 @@ func (r *CarbonCacheRepository) GetCacheStats(ctx context.Context) (map[string]i
 	return stats, nil
 }
 
+// CountEntries returns the number of entries in the carbon cache
+func (r *CarbonCacheRepository) CountEntries(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM carbon_cache`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count carbon cache entries: %w", err)
+	}
+	return count, nil
+}
+
 // BulkSaveCarbonIntensities saves multiple carbon intensity records
 func (r *CarbonCacheRepository) BulkSaveCarbonIntensities(ctx context.Context, data []CarbonIntensity, ttl time.Duration) error {
 	if len(data) == 0 {
@@ -198,31 +270,24 @@ func (r *CarbonCacheRepository) BulkSaveCarbonIntensities(ctx context.Context, d
 	}
 	defer tx.Rollback()
 
-	query := `
-		INSERT INTO carbon_cache (id, region, timestamp, intensity_value, source)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (region, timestamp, forecast_window) 
-		DO UPDATE SET 
-			intensity_value = EXCLUDED.intensity_value,
-			source = EXCLUDED.source
-	`
-
-	stmt, err := tx.PrepareContext(ctx, query)
+	stmt, err := tx.PrepareContext(ctx, upsertCarbonCacheQuery)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	source := "api"
-
 	for _, entry := range data {
 		id := uuid.New()
+		source := sourceOrDefault(entry.Source)
 		_, err := stmt.ExecContext(ctx,
 			id,
 			entry.Region,
 			entry.Timestamp,
 			entry.Intensity,
 			&source,
+			entry.RenewablePercentage,
+			entry.FossilPercentage,
+			intensityScaleOrDefault(entry.IntensityScale),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to save entry: %w", err)
@@ -239,7 +304,8 @@ func (r *CarbonCacheRepository) BulkSaveCarbonIntensities(ctx context.Context, d
 // GetRecentEntries retrieves all carbon cache entries from the last N duration
 func (r *CarbonCacheRepository) GetRecentEntries(ctx context.Context, duration time.Duration) ([]CarbonCacheEntry, error) {
 	query := `
-		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at
+		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at,
+			renewable_percentage, fossil_percentage, intensity_scale
 		FROM carbon_cache
 		WHERE timestamp >= NOW() - $1::interval
 		ORDER BY timestamp DESC
@@ -255,16 +321,7 @@ func (r *CarbonCacheRepository) GetRecentEntries(ctx context.Context, duration t
 	var entries []CarbonCacheEntry
 	for rows.Next() {
 		var entry CarbonCacheEntry
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Region,
-			&entry.Timestamp,
-			&entry.IntensityValue,
-			&entry.ForecastWindow,
-			&entry.Source,
-			&entry.CreatedAt,
-		)
-		if err != nil {
+		if err := scanCarbonCacheEntry(rows, &entry); err != nil {
 			return nil, fmt.Errorf("failed to scan carbon cache entry: %w", err)
 		}
 		entries = append(entries, entry)
@@ -276,7 +333,8 @@ func (r *CarbonCacheRepository) GetRecentEntries(ctx context.Context, duration t
 // GetCarbonIntensityRange retrieves carbon intensity data for a specific region within a time range
 func (r *CarbonCacheRepository) GetCarbonIntensityRange(ctx context.Context, region string, startTime, endTime time.Time) ([]CarbonCacheEntry, error) {
 	query := `
-		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at
+		SELECT id, region, timestamp, intensity_value, forecast_window, source, created_at,
+			renewable_percentage, fossil_percentage, intensity_scale
 		FROM carbon_cache
 		WHERE region = $1 
 			AND timestamp BETWEEN $2 AND $3
@@ -292,16 +350,7 @@ func (r *CarbonCacheRepository) GetCarbonIntensityRange(ctx context.Context, reg
 	var entries []CarbonCacheEntry
 	for rows.Next() {
 		var entry CarbonCacheEntry
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Region,
-			&entry.Timestamp,
-			&entry.IntensityValue,
-			&entry.ForecastWindow,
-			&entry.Source,
-			&entry.CreatedAt,
-		)
-		if err != nil {
+		if err := scanCarbonCacheEntry(rows, &entry); err != nil {
 			return nil, fmt.Errorf("failed to scan carbon cache entry: %w", err)
 		}
 		entries = append(entries, entry)
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCarbonCacheRepository_DeleteExpiredEntries(t *testing.T) {
+	repo := NewCarbonCacheRepository(&DB{openFakeDB(t)})
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Hour)
+	for _, timestamp := range []time.Time{
+		now.Add(-10 * 24 * time.Hour), // Old
+		now.Add(-8 * 24 * time.Hour),  // Old
+		now.Add(-time.Hour),
+		now.Add(6 * time.Hour), // Forecast
+	} {
+		if err := repo.SaveCarbonIntensity(ctx, CarbonIntensity{Region: "US-EAST", Timestamp: timestamp, Intensity: 300}, time.Hour); err != nil {
+			t.Fatalf("SaveCarbonIntensity returned error: %v", err)
+		}
+	}
+
+	removed, err := repo.DeleteExpiredEntries(ctx, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("DeleteExpiredEntries returned error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected the 2 old entries removed, got %d", removed)
+	}
+
+	remaining, err := repo.GetCarbonIntensityRange(ctx, "US-EAST", now.Add(-30*24*time.Hour), now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCarbonIntensityRange returned error: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected 2 entries left, got %d", len(remaining))
+	}
+	for _, entry := range remaining {
+		if entry.Timestamp.Before(now.Add(-7 * 24 * time.Hour)) {
+			t.Errorf("old entry for %v was kept", entry.Timestamp)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -25,9 +26,10 @@ func NewExecutionLogRepository(db *sql.DB) *ExecutionLogRepository {
 func (r *ExecutionLogRepository) CreateExecutionLog(ctx context.Context, log *models.ExecutionLog) error {
 	query := `
 		INSERT INTO execution_logs (
-			id, job_id, output, error_message, exit_code, 
-			duration, started_at, completed_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			id, job_id, output, error_output, exit_code,
+			duration, started_at, completed_at, worker_node_id,
+			peak_memory_bytes, cpu_seconds, output_truncated, steps
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at
 	`
 
@@ -39,6 +41,16 @@ func (r *ExecutionLogRepository) CreateExecutionLog(ctx context.Context, log *mo
 		log.ID = uuid.New()
 	}
 
+	var steps *string
+	if len(log.Steps) > 0 {
+		encoded, err := json.Marshal(log.Steps)
+		if err != nil {
+			return fmt.Errorf("failed to encode execution log steps: %w", err)
+		}
+		stepsJSON := string(encoded)
+		steps = &stepsJSON
+	}
+
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
@@ -50,6 +62,11 @@ func (r *ExecutionLogRepository) CreateExecutionLog(ctx context.Context, log *mo
 		log.Duration,
 		log.StartedAt,
 		log.CompletedAt,
+		log.WorkerNodeID,
+		log.PeakMemoryBytes,
+		log.CPUSeconds,
+		log.OutputTruncated,
+		steps,
 	).Scan(&log.ID, &log.CreatedAt)
 
 	if err != nil {
@@ -59,12 +76,78 @@ func (r *ExecutionLogRepository) CreateExecutionLog(ctx context.Context, log *mo
 	return nil
 }
 
+// executionLogColumns is the column list read by every execution log query, in scan order
+const executionLogColumns = `id, job_id, output, error_output, exit_code,
+			duration, started_at, completed_at, created_at, worker_node_id,
+			peak_memory_bytes, cpu_seconds, output_truncated, steps`
+
+// scanExecutionLog reads one execution_logs row selected with executionLogColumns
+func scanExecutionLog(row rowScanner) (*models.ExecutionLog, error) {
+	log := &models.ExecutionLog{}
+	var output, errorOutput, workerNodeID, steps sql.NullString
+	var exitCode, duration, peakMemory sql.NullInt64
+	var cpuSeconds sql.NullFloat64
+	var outputTruncated sql.NullBool
+	var completedAt sql.NullTime
+
+	if err := row.Scan(
+		&log.ID,
+		&log.JobID,
+		&output,
+		&errorOutput,
+		&exitCode,
+		&duration,
+		&log.StartedAt,
+		&completedAt,
+		&log.CreatedAt,
+		&workerNodeID,
+		&peakMemory,
+		&cpuSeconds,
+		&outputTruncated,
+		&steps,
+	); err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	log.Output = output.String
+	if errorOutput.Valid {
+		log.ErrorMessage = &errorOutput.String
+	}
+	if exitCode.Valid {
+		code := int(exitCode.Int64)
+		log.ExitCode = &code
+	}
+	if duration.Valid {
+		seconds := int(duration.Int64)
+		log.Duration = &seconds
+	}
+	if completedAt.Valid {
+		log.CompletedAt = &completedAt.Time
+	}
+	if workerNodeID.Valid {
+		log.WorkerNodeID = &workerNodeID.String
+	}
+	if peakMemory.Valid {
+		log.PeakMemoryBytes = &peakMemory.Int64
+	}
+	if cpuSeconds.Valid {
+		log.CPUSeconds = &cpuSeconds.Float64
+	}
+	log.OutputTruncated = outputTruncated.Bool
+	if steps.Valid {
+		if err := json.Unmarshal([]byte(steps.String), &log.Steps); err != nil {
+			return nil, fmt.Errorf("failed to decode execution log steps: %w", err)
+		}
+	}
+
+	return log, nil
+}
+
 // GetExecutionLogByJobID retrieves the execution log for a specific job
 func (r *ExecutionLogRepository) GetExecutionLogByJobID(ctx context.Context, jobID uuid.UUID) (*models.ExecutionLog, error) {
 	query := `
-		SELECT 
-			id, job_id, output, error_message, exit_code, 
-			duration, started_at, completed_at, created_at
+		SELECT ` + executionLogColumns + `
 		FROM execution_logs
 		WHERE job_id = $1
 		ORDER BY created_at DESC
@@ -74,22 +157,7 @@ func (r *ExecutionLogRepository) GetExecutionLogByJobID(ctx context.Context, job
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	log := &models.ExecutionLog{}
-	var errorMessage sql.NullString
-	var completedAt sql.NullTime
-
-	err := r.db.QueryRowContext(ctx, query, jobID).Scan(
-		&log.ID,
-		&log.JobID,
-		&log.Output,
-		&errorMessage,
-		&log.ExitCode,
-		&log.Duration,
-		&log.StartedAt,
-		&completedAt,
-		&log.CreatedAt,
-	)
-
+	log, err := scanExecutionLog(r.db.QueryRowContext(ctx, query, jobID))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("execution log not found for job %s", jobID)
 	}
@@ -97,84 +165,70 @@ func (r *ExecutionLogRepository) GetExecutionLogByJobID(ctx context.Context, job
 		return nil, fmt.Errorf("failed to get execution log: %w", err)
 	}
 
-	// Handle nullable fields
-	if errorMessage.Valid {
-		log.ErrorMessage = &errorMessage.String
-	}
-	if completedAt.Valid {
-		log.CompletedAt = &completedAt.Time
-	}
-
 	return log, nil
 }
 
-// GetAllExecutionLogsByJobID retrieves all execution logs for a job (in case of retries)
-func (r *ExecutionLogRepository) GetAllExecutionLogsByJobID(ctx context.Context, jobID uuid.UUID) ([]*models.ExecutionLog, error) {
+// GetAllExecutionLogsByJobID retrieves one page of a job's execution logs (one per attempt),
+// in attempt order, together with the total number of attempts recorded for the job
+func (r *ExecutionLogRepository) GetAllExecutionLogsByJobID(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]*models.ExecutionLog, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM execution_logs
+		WHERE job_id = $1
+	`
 	query := `
-		SELECT 
-			id, job_id, output, error_message, exit_code, 
-			duration, started_at, completed_at, created_at
+		SELECT ` + executionLogColumns + `
 		FROM execution_logs
 		WHERE job_id = $1
-		ORDER BY created_at DESC
+		ORDER BY started_at, created_at, id
+		LIMIT $2 OFFSET $3
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	rows, err := r.db.QueryContext(ctx, query, jobID)
+	var total int
+	if err := r.db.QueryRowContext(ctx, countQuery, jobID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count execution logs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, jobID, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query execution logs: %w", err)
+		return nil, 0, fmt.Errorf("failed to query execution logs: %w", err)
 	}
 	defer rows.Close()
 
 	var logs []*models.ExecutionLog
 
 	for rows.Next() {
-		log := &models.ExecutionLog{}
-		var errorMessage sql.NullString
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&log.ID,
-			&log.JobID,
-			&log.Output,
-			&errorMessage,
-			&log.ExitCode,
-			&log.Duration,
-			&log.StartedAt,
-			&completedAt,
-			&log.CreatedAt,
-		)
+		log, err := scanExecutionLog(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan execution log: %w", err)
-		}
-
-		// Handle nullable fields
-		if errorMessage.Valid {
-			log.ErrorMessage = &errorMessage.String
+			return nil, 0, fmt.Errorf("failed to scan execution log: %w", err)
 		}
-		if completedAt.Valid {
-			log.CompletedAt = &completedAt.Time
-		}
-
 		logs = append(logs, log)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating execution logs: %w", err)
+		return nil, 0, fmt.Errorf("error iterating execution logs: %w", err)
 	}
 
-	return logs, nil
+	return logs, total, nil
 }
 
 // UpdateExecutionLog updates an existing execution log (for streaming updates)
 func (r *ExecutionLogRepository) UpdateExecutionLog(ctx context.Context, log *models.ExecutionLog) error {
 	query := `
 		UPDATE execution_logs
-		SET 
+		SET
 			output = $1,
-			error_message = $2,
+			error_output = $2,
 			exit_code = $3,
 			duration = $4,
 			completed_at = $5
@@ -233,9 +287,7 @@ func (r *ExecutionLogRepository) GetRecentExecutionLogs(ctx context.Context, lim
 	}
 
 	query := `
-		SELECT 
-			id, job_id, output, error_message, exit_code, 
-			duration, started_at, completed_at, created_at
+		SELECT ` + executionLogColumns + `
 		FROM execution_logs
 		ORDER BY created_at DESC
 		LIMIT $1
@@ -253,33 +305,10 @@ func (r *ExecutionLogRepository) GetRecentExecutionLogs(ctx context.Context, lim
 	var logs []*models.ExecutionLog
 
 	for rows.Next() {
-		log := &models.ExecutionLog{}
-		var errorMessage sql.NullString
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&log.ID,
-			&log.JobID,
-			&log.Output,
-			&errorMessage,
-			&log.ExitCode,
-			&log.Duration,
-			&log.StartedAt,
-			&completedAt,
-			&log.CreatedAt,
-		)
+		log, err := scanExecutionLog(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan execution log: %w", err)
 		}
-
-		// Handle nullable fields
-		if errorMessage.Valid {
-			log.ErrorMessage = &errorMessage.String
-		}
-		if completedAt.Valid {
-			log.CompletedAt = &completedAt.Time
-		}
-
 		logs = append(logs, log)
 	}
 
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestExecutionLogRepository_WorkerNodeIDRoundTrip(t *testing.T) {
+	repo := NewExecutionLogRepository(openFakeDB(t))
+	ctx := context.Background()
+
+	jobID := uuid.New()
+	workerNodeID := "3f1c9a52-7d4e-4b8a-9c61-0e2f5d7a8b90/worker-2"
+	completed := time.Now()
+	entry := &models.ExecutionLog{
+		JobID:        jobID,
+		Output:       "done",
+		StartedAt:    completed.Add(-time.Minute),
+		CompletedAt:  &completed,
+		WorkerNodeID: &workerNodeID,
+	}
+	if err := repo.CreateExecutionLog(ctx, entry); err != nil {
+		t.Fatalf("CreateExecutionLog returned error: %v", err)
+	}
+	if err := repo.CreateExecutionLog(ctx, &models.ExecutionLog{JobID: uuid.New(), StartedAt: completed}); err != nil {
+		t.Fatalf("CreateExecutionLog returned error: %v", err)
+	}
+
+	latest, err := repo.GetExecutionLogByJobID(ctx, jobID)
+	if err != nil {
+		t.Fatalf("GetExecutionLogByJobID returned error: %v", err)
+	}
+	if latest.WorkerNodeID == nil || *latest.WorkerNodeID != workerNodeID {
+		t.Errorf("expected worker_node_id %q, got %v", workerNodeID, latest.WorkerNodeID)
+	}
+
+	all, total, err := repo.GetAllExecutionLogsByJobID(ctx, jobID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAllExecutionLogsByJobID returned error: %v", err)
+	}
+	if total != 1 || len(all) != 1 || all[0].WorkerNodeID == nil || *all[0].WorkerNodeID != workerNodeID {
+		t.Errorf("expected one log from %q, got %+v", workerNodeID, all)
+	}
+
+	recent, err := repo.GetRecentExecutionLogs(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRecentExecutionLogs returned error: %v", err)
+	}
+	for _, entry := range recent {
+		if entry.JobID != jobID && entry.WorkerNodeID != nil {
+			t.Errorf("expected a log without a worker to read back as nil, got %q", *entry.WorkerNodeID)
+		}
+	}
+}
+
+func TestExecutionLogRepository_PaginatesAttemptsInOrder(t *testing.T) {
+	repo := NewExecutionLogRepository(openFakeDB(t))
+	ctx := context.Background()
+
+	// Attempts are inserted out of order; the repository must return them by start time
+	jobID := uuid.New()
+	const attempts = 120
+	first := time.Now().Add(-time.Duration(attempts) * time.Minute)
+	for i := attempts - 1; i >= 0; i-- {
+		entry := &models.ExecutionLog{
+			JobID:     jobID,
+			Output:    fmt.Sprintf("attempt %d", i+1),
+			StartedAt: first.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.CreateExecutionLog(ctx, entry); err != nil {
+			t.Fatalf("CreateExecutionLog returned error: %v", err)
+		}
+	}
+	if err := repo.CreateExecutionLog(ctx, &models.ExecutionLog{JobID: uuid.New(), StartedAt: first}); err != nil {
+		t.Fatalf("CreateExecutionLog returned error: %v", err)
+	}
+
+	var seen []string
+	for offset := 0; offset < attempts; offset += 50 {
+		page, total, err := repo.GetAllExecutionLogsByJobID(ctx, jobID, 50, offset)
+		if err != nil {
+			t.Fatalf("GetAllExecutionLogsByJobID(offset %d) returned error: %v", offset, err)
+		}
+		if total != attempts {
+			t.Errorf("offset %d: expected total %d, got %d", offset, attempts, total)
+		}
+		if want := min(50, attempts-offset); len(page) != want {
+			t.Errorf("offset %d: expected %d logs, got %d", offset, want, len(page))
+		}
+		for _, entry := range page {
+			seen = append(seen, entry.Output)
+		}
+	}
+
+	if len(seen) != attempts {
+		t.Fatalf("expected %d logs across all pages, got %d", attempts, len(seen))
+	}
+	for i, output := range seen {
+		if want := fmt.Sprintf("attempt %d", i+1); output != want {
+			t.Fatalf("expected log %d to be %q, got %q", i, want, output)
+		}
+	}
+
+	past, total, err := repo.GetAllExecutionLogsByJobID(ctx, jobID, 50, attempts)
+	if err != nil {
+		t.Fatalf("GetAllExecutionLogsByJobID returned error: %v", err)
+	}
+	if len(past) != 0 || total != attempts {
+		t.Errorf("expected an empty page past the end with total %d, got %d logs and total %d", attempts, len(past), total)
+	}
+}
+
+func TestExecutionLogRepository_RoundTripsEveryColumn(t *testing.T) {
+	repo := NewExecutionLogRepository(openFakeDB(t))
+	ctx := context.Background()
+
+	started := time.Now().Add(-time.Minute)
+	completed := time.Now()
+	errorMessage := "container exited with code 0 but wrote to stderr"
+	exitCode := 0
+	duration := 60
+	workerNodeID := "node-a/worker-1"
+	peakMemory := int64(64 << 20)
+	cpuSeconds := 1.5
+
+	// Every field is set explicitly so adding a column to the model without
+	// updating this test (and the repository) is noticed at review time
+	entry := &models.ExecutionLog{
+		JobID:        uuid.New(),
+		Output:       "hello",
+		ErrorMessage: &errorMessage,
+		ExitCode:     &exitCode,
+		Duration:     &duration,
+		StartedAt:    started,
+		CompletedAt:  &completed,
+		WorkerNodeID: &workerNodeID,
+
+		PeakMemoryBytes: &peakMemory,
+		CPUSeconds:      &cpuSeconds,
+		OutputTruncated: true,
+
+		Steps: []models.StepRecord{
+			{Name: "go build", DurationMs: 1200},
+			{Name: "go test", DurationMs: 30000, Error: &errorMessage},
+		},
+	}
+	if err := repo.CreateExecutionLog(ctx, entry); err != nil {
+		t.Fatalf("CreateExecutionLog returned error: %v", err)
+	}
+	if entry.ID == uuid.Nil || entry.CreatedAt.IsZero() {
+		t.Fatalf("expected ID and CreatedAt to be filled in, got %s / %v", entry.ID, entry.CreatedAt)
+	}
+
+	got, err := repo.GetExecutionLogByJobID(ctx, entry.JobID)
+	if err != nil {
+		t.Fatalf("GetExecutionLogByJobID returned error: %v", err)
+	}
+	if got.ID != entry.ID || got.Output != "hello" || !got.StartedAt.Equal(started) {
+		t.Errorf("unexpected log %+v", got)
+	}
+	if got.ErrorMessage == nil || *got.ErrorMessage != errorMessage {
+		t.Errorf("expected error message %q, got %v", errorMessage, got.ErrorMessage)
+	}
+	if got.ExitCode == nil || *got.ExitCode != 0 {
+		t.Errorf("expected exit code 0 to survive the round trip, got %v", got.ExitCode)
+	}
+	if got.Duration == nil || *got.Duration != duration {
+		t.Errorf("expected duration %d, got %v", duration, got.Duration)
+	}
+	if got.CompletedAt == nil || !got.CompletedAt.Equal(completed) {
+		t.Errorf("expected completed_at %v, got %v", completed, got.CompletedAt)
+	}
+	if got.PeakMemoryBytes == nil || *got.PeakMemoryBytes != peakMemory || got.CPUSeconds == nil || *got.CPUSeconds != cpuSeconds {
+		t.Errorf("expected peak memory %d and %v CPU seconds, got %v / %v", peakMemory, cpuSeconds, got.PeakMemoryBytes, got.CPUSeconds)
+	}
+	if !got.OutputTruncated {
+		t.Error("expected output_truncated to survive the round trip")
+	}
+	if len(got.Steps) != 2 || got.Steps[0].Name != "go build" || got.Steps[1].DurationMs != 30000 ||
+		got.Steps[0].Error != nil || got.Steps[1].Error == nil || *got.Steps[1].Error != errorMessage {
+		t.Errorf("expected both steps to survive the round trip, got %+v", got.Steps)
+	}
+
+	// A job rejected before its container started has no exit code or duration
+	rejected := &models.ExecutionLog{JobID: uuid.New(), StartedAt: completed, ErrorMessage: &errorMessage}
+	if err := repo.CreateExecutionLog(ctx, rejected); err != nil {
+		t.Fatalf("CreateExecutionLog returned error: %v", err)
+	}
+	got, err = repo.GetExecutionLogByJobID(ctx, rejected.JobID)
+	if err != nil {
+		t.Fatalf("GetExecutionLogByJobID returned error: %v", err)
+	}
+	if got.ExitCode != nil || got.Duration != nil || got.Output != "" || got.PeakMemoryBytes != nil || got.CPUSeconds != nil || got.OutputTruncated || got.Steps != nil {
+		t.Errorf("expected NULL exit_code, duration, output and usage to read back empty, got %+v", got)
+	}
+}
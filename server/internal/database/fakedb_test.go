@@ -0,0 +1,594 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-memory database/sql driver that understands just enough of the
+// repositories' SQL to round-trip rows by column name: INSERT (... ON CONFLICT DO NOTHING
+// ... RETURNING), UPDATE ... SET, DELETE,
+// and SELECT [DISTINCT] with AND-ed "<column> <op> $n", "<column> IS [NOT] NULL" and
+// "<column> IN ($n, ...)" conditions, ORDER BY, LIMIT and OFFSET (or
+// COUNT(*) over the same conditions). A condition may also be a correlated
+// "EXISTS (SELECT 1 FROM <table> WHERE <column> = <outer>.<column> AND ...)". Transactions
+// roll back to a snapshot. Columns are checked against database/schema.sql so
+// repository SQL cannot drift from the real tables.
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]driver.Value
+	schema map[string]map[string]bool
+}
+
+var (
+	insertTablePattern = regexp.MustCompile(`INSERT INTO (\w+)`)
+	deleteTablePattern = regexp.MustCompile(`DELETE FROM (\w+)`)
+	conflictPattern    = regexp.MustCompile(`ON CONFLICT \(([\w, ]+)\) DO NOTHING`)
+	updateTablePattern = regexp.MustCompile(`UPDATE (\w+)`)
+	selectTablePattern = regexp.MustCompile(`FROM (\w+)`)
+	conditionPattern   = regexp.MustCompile(`(\w+) (=|>=|<=|<|>) \$(\d+)`)
+	nullPattern        = regexp.MustCompile(`(\w+) IS (NOT )?NULL`)
+	inPattern          = regexp.MustCompile(`(\w+) IN \(((?:\$\d+(?:, )?)+)\)`)
+	tuplePattern       = regexp.MustCompile(`\((\w+), (\w+)\) (<|>) \(\$(\d+), \$(\d+)\)`)
+	existsPattern      = regexp.MustCompile(`EXISTS \(SELECT 1 FROM (\w+) WHERE (\w+) = \w+\.(\w+)((?: AND \w+ (?:=|>=|<=|<|>) \$\d+)*)\)`)
+	orderPattern       = regexp.MustCompile(`ORDER BY ([\w, ]+?)\s*(?:LIMIT|OFFSET|$)`)
+	limitPattern       = regexp.MustCompile(`LIMIT (\$?\d+)`)
+	offsetPattern      = regexp.MustCompile(`OFFSET (\$?\d+)`)
+	createTablePattern = regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`)
+)
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+// Begin snapshots every table; rolling back restores the snapshot. Transactions aren't
+// isolated from each other, which the tests don't need.
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	d := c.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := make(map[string][]map[string]driver.Value, len(d.tables))
+	for table, rows := range d.tables {
+		copied := make([]map[string]driver.Value, len(rows))
+		for i, row := range rows {
+			copied[i] = make(map[string]driver.Value, len(row))
+			for column, value := range row {
+				copied[i][column] = value
+			}
+		}
+		snapshot[table] = copied
+	}
+	return &fakeTx{driver: d, snapshot: snapshot}, nil
+}
+
+type fakeTx struct {
+	driver   *fakeDriver
+	snapshot map[string][]map[string]driver.Value
+}
+
+func (tx *fakeTx) Commit() error { return nil }
+
+func (tx *fakeTx) Rollback() error {
+	tx.driver.mu.Lock()
+	defer tx.driver.mu.Unlock()
+	tx.driver.tables = tx.snapshot
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if insertTablePattern.MatchString(s.query) {
+		row, err := s.insert(args)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return driver.RowsAffected(0), nil
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	if match := deleteTablePattern.FindStringSubmatch(s.query); match != nil {
+		return s.delete(match[1], args)
+	}
+
+	match := updateTablePattern.FindStringSubmatch(s.query)
+	if match == nil {
+		return nil, errors.New("only INSERT, UPDATE and DELETE are supported by Exec")
+	}
+
+	table := match[1]
+	assignments := conditionPattern.FindAllStringSubmatch(between(s.query, "SET", "WHERE"), -1)
+	conditions := conditionPattern.FindAllStringSubmatch(whereClause(s.query), -1)
+	for _, clause := range append(assignments, conditions...) {
+		if err := d.checkColumns(table, []string{clause[1]}); err != nil {
+			return nil, err
+		}
+	}
+	membership, err := d.membershipConditions(table, whereClause(s.query))
+	if err != nil {
+		return nil, err
+	}
+
+	var affected int64
+rows:
+	for _, row := range d.tables[table] {
+		for _, condition := range conditions {
+			index, _ := strconv.Atoi(condition[3])
+			if !compareMatches(row[condition[1]], condition[2], args[index-1]) {
+				continue rows
+			}
+		}
+		if !membership(row, args) {
+			continue
+		}
+		for _, assignment := range assignments {
+			index, _ := strconv.Atoi(assignment[3])
+			row[assignment[1]] = args[index-1]
+		}
+		affected++
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+// delete removes the rows of table matching the statement's conditions; the driver lock
+// must be held
+func (s *fakeStmt) delete(table string, args []driver.Value) (driver.Result, error) {
+	d := s.conn.driver
+	conditions := conditionPattern.FindAllStringSubmatch(whereClause(s.query), -1)
+	for _, condition := range conditions {
+		if err := d.checkColumns(table, []string{condition[1]}); err != nil {
+			return nil, err
+		}
+	}
+
+	var kept []map[string]driver.Value
+rows:
+	for _, row := range d.tables[table] {
+		for _, condition := range conditions {
+			index, _ := strconv.Atoi(condition[3])
+			if !compareMatches(row[condition[1]], condition[2], args[index-1]) {
+				kept = append(kept, row)
+				continue rows
+			}
+		}
+	}
+	affected := int64(len(d.tables[table]) - len(kept))
+	d.tables[table] = kept
+	return driver.RowsAffected(affected), nil
+}
+
+// insert adds the row of an INSERT statement to its table; the driver lock must be held.
+// Under ON CONFLICT ... DO NOTHING, a row clashing with an existing one isn't inserted
+// and nil is returned.
+func (s *fakeStmt) insert(args []driver.Value) (map[string]driver.Value, error) {
+	d := s.conn.driver
+	table := insertTablePattern.FindStringSubmatch(s.query)[1]
+	columns := splitColumns(between(s.query, "(", ")"))
+	if err := d.checkColumns(table, columns); err != nil {
+		return nil, err
+	}
+	row := map[string]driver.Value{"created_at": time.Now()} // Column default
+	for i, column := range columns {
+		row[column] = args[i]
+	}
+
+	if match := conflictPattern.FindStringSubmatch(s.query); match != nil {
+		keys := splitColumns(match[1])
+	existing:
+		for _, other := range d.tables[table] {
+			for _, key := range keys {
+				if c, ok := compareValues(other[key], row[key]); !ok || c != 0 {
+					continue existing
+				}
+			}
+			return nil, nil
+		}
+	}
+
+	d.tables[table] = append(d.tables[table], row)
+	return row, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if insertTablePattern.MatchString(s.query) {
+		row, err := s.insert(args)
+		if err != nil {
+			return nil, err
+		}
+		returning := splitColumns(s.query[strings.Index(s.query, "RETURNING")+len("RETURNING"):])
+		if row == nil {
+			return &fakeRows{columns: returning}, nil
+		}
+		return &fakeRows{columns: returning, rows: []map[string]driver.Value{row}}, nil
+	}
+
+	selected := strings.TrimSpace(between(s.query, "SELECT", "FROM"))
+	distinct := strings.HasPrefix(selected, "DISTINCT ")
+	columns := splitColumns(strings.TrimPrefix(selected, "DISTINCT "))
+	table := selectTablePattern.FindStringSubmatch(s.query)[1]
+	count := len(columns) == 1 && columns[0] == "COUNT(*)"
+	if !count {
+		if err := d.checkColumns(table, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	where := whereClause(s.query)
+	exists := existsPattern.FindAllStringSubmatch(where, -1)
+	for _, subquery := range exists {
+		if err := d.checkColumns(table, []string{subquery[3]}); err != nil {
+			return nil, err
+		}
+		if err := d.checkColumns(subquery[1], []string{subquery[2]}); err != nil {
+			return nil, err
+		}
+		for _, condition := range conditionPattern.FindAllStringSubmatch(subquery[4], -1) {
+			if err := d.checkColumns(subquery[1], []string{condition[1]}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	where = existsPattern.ReplaceAllString(where, "")
+	conditions := conditionPattern.FindAllStringSubmatch(where, -1)
+	tuples := tuplePattern.FindAllStringSubmatch(where, -1)
+	for _, condition := range conditions {
+		if err := d.checkColumns(table, []string{condition[1]}); err != nil {
+			return nil, err
+		}
+	}
+	for _, tuple := range tuples {
+		if err := d.checkColumns(table, tuple[1:3]); err != nil {
+			return nil, err
+		}
+	}
+	membership, err := d.membershipConditions(table, where)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []map[string]driver.Value
+rows:
+	for _, row := range d.tables[table] {
+		for _, condition := range conditions {
+			index, _ := strconv.Atoi(condition[3])
+			if !compareMatches(row[condition[1]], condition[2], args[index-1]) {
+				continue rows
+			}
+		}
+		for _, tuple := range tuples {
+			// (a, b) < ($n, $m) compares lexicographically, as in PostgreSQL
+			first, _ := strconv.Atoi(tuple[4])
+			second, _ := strconv.Atoi(tuple[5])
+			cmp, _ := compareValues(row[tuple[1]], args[first-1])
+			if cmp == 0 {
+				cmp, _ = compareValues(row[tuple[2]], args[second-1])
+			}
+			if (tuple[3] == "<" && cmp >= 0) || (tuple[3] == ">" && cmp <= 0) {
+				continue rows
+			}
+		}
+		for _, subquery := range exists {
+			if !d.exists(row[subquery[3]], subquery, args) {
+				continue rows
+			}
+		}
+		if !membership(row, args) {
+			continue
+		}
+		matched = append(matched, row)
+	}
+
+	if count {
+		row := map[string]driver.Value{columns[0]: int64(len(matched))}
+		return &fakeRows{columns: columns, rows: []map[string]driver.Value{row}}, nil
+	}
+	if distinct {
+		seen := make(map[string]bool)
+		unique := matched[:0:0]
+		for _, row := range matched {
+			key := ""
+			for _, column := range columns {
+				key += fmt.Sprintf("%v\x00", row[column])
+			}
+			if !seen[key] {
+				seen[key] = true
+				unique = append(unique, row)
+			}
+		}
+		matched = unique
+	}
+
+	if order := orderPattern.FindStringSubmatch(s.query); order != nil {
+		terms := strings.Split(order[1], ",")
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, term := range terms {
+				fields := strings.Fields(term)
+				cmp, _ := compareValues(matched[i][fields[0]], matched[j][fields[0]])
+				if cmp == 0 {
+					continue
+				}
+				if len(fields) > 1 && fields[1] == "DESC" {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+			return false
+		})
+	}
+	if offset := offsetPattern.FindStringSubmatch(s.query); offset != nil {
+		n := intArgument(offset[1], args)
+		if n > len(matched) {
+			n = len(matched)
+		}
+		matched = matched[n:]
+	}
+	if limit := limitPattern.FindStringSubmatch(s.query); limit != nil {
+		if n := intArgument(limit[1], args); n < len(matched) {
+			matched = matched[:n]
+		}
+	}
+
+	return &fakeRows{columns: columns, rows: matched}, nil
+}
+
+// exists reports whether an EXISTS subquery matched by existsPattern finds a row whose
+// correlated column equals outer; the driver lock must be held
+func (d *fakeDriver) exists(outer driver.Value, subquery []string, args []driver.Value) bool {
+	conditions := conditionPattern.FindAllStringSubmatch(subquery[4], -1)
+rows:
+	for _, row := range d.tables[subquery[1]] {
+		if !compareMatches(row[subquery[2]], "=", outer) {
+			continue
+		}
+		for _, condition := range conditions {
+			index, _ := strconv.Atoi(condition[3])
+			if !compareMatches(row[condition[1]], condition[2], args[index-1]) {
+				continue rows
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// membershipConditions checks the IS [NOT] NULL and IN conditions of a WHERE clause
+// against table and returns a matcher for them
+func (d *fakeDriver) membershipConditions(table, where string) (func(row map[string]driver.Value, args []driver.Value) bool, error) {
+	nulls := nullPattern.FindAllStringSubmatch(where, -1)
+	ins := inPattern.FindAllStringSubmatch(where, -1)
+	for _, clause := range append(nulls, ins...) {
+		if err := d.checkColumns(table, []string{clause[1]}); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(row map[string]driver.Value, args []driver.Value) bool {
+		for _, null := range nulls {
+			if (row[null[1]] == nil) != (null[2] == "") {
+				return false
+			}
+		}
+	in:
+		for _, clause := range ins {
+			for _, placeholder := range splitColumns(clause[2]) {
+				index, _ := strconv.Atoi(placeholder[1:])
+				if compareMatches(row[clause[1]], "=", args[index-1]) {
+					continue in
+				}
+			}
+			return false
+		}
+		return true
+	}, nil
+}
+
+// intArgument resolves a LIMIT/OFFSET operand, either a literal or a $n placeholder
+func intArgument(operand string, args []driver.Value) int {
+	if strings.HasPrefix(operand, "$") {
+		index, _ := strconv.Atoi(operand[1:])
+		return int(args[index-1].(int64))
+	}
+	n, _ := strconv.Atoi(operand)
+	return n
+}
+
+// whereClause returns the conditions between WHERE and the ORDER BY/LIMIT/OFFSET suffix
+func whereClause(query string) string {
+	i := strings.Index(query, "WHERE")
+	if i < 0 {
+		return ""
+	}
+	clause := query[i+len("WHERE"):]
+	for _, suffix := range []string{"ORDER BY", "LIMIT", "OFFSET"} {
+		if j := strings.Index(clause, suffix); j >= 0 {
+			clause = clause[:j]
+		}
+	}
+	return clause
+}
+
+// compareMatches reports whether "value op arg" holds
+func compareMatches(value driver.Value, op string, arg driver.Value) bool {
+	cmp, ok := compareValues(value, arg)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return false
+}
+
+// compareValues orders two driver values of the same kind; ok is false for NULLs and mixed kinds
+func compareValues(a, b driver.Value) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case int64:
+		if b, ok := b.(int64); ok {
+			return int(a - b), true
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+type fakeRows struct {
+	columns []string
+	rows    []map[string]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, column := range r.columns {
+		dest[i] = r.rows[0][column]
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// checkColumns rejects columns that schema.sql does not declare for table
+func (d *fakeDriver) checkColumns(table string, columns []string) error {
+	known, ok := d.schema[table]
+	if !ok {
+		return fmt.Errorf("table %s is not declared in schema.sql", table)
+	}
+	for _, column := range columns {
+		if !known[column] {
+			return fmt.Errorf("column %s.%s is not declared in schema.sql", table, column)
+		}
+	}
+	return nil
+}
+
+// loadSchema returns the columns of every table created by database/schema.sql
+func loadSchema(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	data, err := os.ReadFile("../../database/schema.sql")
+	if err != nil {
+		t.Fatalf("failed to read schema.sql: %v", err)
+	}
+
+	schema := make(map[string]map[string]bool)
+	for _, match := range createTablePattern.FindAllStringSubmatch(string(data), -1) {
+		columns := make(map[string]bool)
+		for _, line := range strings.Split(match[2], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] == "CONSTRAINT" || strings.HasPrefix(fields[0], "--") {
+				continue
+			}
+			columns[fields[0]] = true
+		}
+		schema[match[1]] = columns
+	}
+	return schema
+}
+
+// between returns the text between the first start marker and the next end marker
+func between(s, start, end string) string {
+	s = s[strings.Index(s, start)+len(start):]
+	return s[:strings.Index(s, end)]
+}
+
+func splitColumns(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		columns = append(columns, strings.TrimSpace(column))
+	}
+	return columns
+}
+
+// openFakeDB opens a fresh in-memory database for one test
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	name := "fakedb-" + t.Name()
+	sql.Register(name, &fakeDriver{
+		tables: make(map[string][]map[string]driver.Value),
+		schema: loadSchema(t),
+	})
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -3,7 +3,11 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Sambit-Mondal/karbos/server/internal/models"
@@ -20,16 +24,86 @@ func NewJobRepository(db *DB) *JobRepository {
 	return &JobRepository{db: db}
 }
 
-// CreateJob inserts a new job into the database
+// createJobQuery inserts one job, returning its ID and creation time. A job whose ID
+// already exists is left alone and no row is returned.
+const createJobQuery = `
+	INSERT INTO jobs (
+		id, user_id, docker_image, command, status, 
+		deadline, estimated_duration, region, metadata, created_at,
+		scheduled_time, submission_intensity, expected_intensity, carbon_savings, carbon_opt_out,
+		request_id
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	ON CONFLICT (id) DO NOTHING
+	RETURNING id, created_at
+`
+
+// CreateJob inserts a new job into the database. Creation is idempotent on the job ID:
+// if a job with the caller-supplied ID already exists, job is replaced with the stored one.
 func (r *JobRepository) CreateJob(ctx context.Context, job *models.Job) error {
-	query := `
-		INSERT INTO jobs (
-			id, user_id, docker_image, command, status, 
-			deadline, estimated_duration, region, metadata, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, created_at
-	`
+	err := r.db.QueryRowContext(ctx, createJobQuery, createJobArgs(job)...).Scan(&job.ID, &job.CreatedAt)
+	if err == sql.ErrNoRows {
+		return r.loadExistingJob(ctx, job)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return nil
+}
 
+// loadExistingJob replaces job with the stored job of the same ID, after an insert that
+// conflicted with it. The ID of another user's job is refused rather than handing it out.
+func (r *JobRepository) loadExistingJob(ctx context.Context, job *models.Job) error {
+	existing, err := r.GetJobByID(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load existing job %s: %w", job.ID, err)
+	}
+	if existing.UserID != job.UserID {
+		return fmt.Errorf("job %s already exists for another user", job.ID)
+	}
+
+	*job = *existing
+	return nil
+}
+
+// CreateJobsBatch inserts jobs in a single transaction, so either all of them are
+// created or none is. Jobs whose IDs already exist are loaded as CreateJob does.
+func (r *JobRepository) CreateJobsBatch(ctx context.Context, jobs []*models.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, createJobQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, job := range jobs {
+		err := stmt.QueryRowContext(ctx, createJobArgs(job)...).Scan(&job.ID, &job.CreatedAt)
+		if err == sql.ErrNoRows {
+			err = r.loadExistingJob(ctx, job)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create job %s: %w", job.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// createJobArgs fills in a new job's defaults and returns the arguments of createJobQuery
+func createJobArgs(job *models.Job) []interface{} {
 	// Generate UUID if not provided
 	if job.ID == uuid.Nil {
 		job.ID = uuid.New()
@@ -45,14 +119,7 @@ func (r *JobRepository) CreateJob(ctx context.Context, job *models.Job) error {
 		job.CreatedAt = time.Now()
 	}
 
-	// Set default metadata if empty
-	if job.Metadata == "" {
-		job.Metadata = "{}"
-	}
-
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
+	return []interface{}{
 		job.ID,
 		job.UserID,
 		job.DockerImage,
@@ -63,13 +130,13 @@ func (r *JobRepository) CreateJob(ctx context.Context, job *models.Job) error {
 		job.Region,
 		job.Metadata,
 		job.CreatedAt,
-	).Scan(&job.ID, &job.CreatedAt)
-
-	if err != nil {
-		return fmt.Errorf("failed to create job: %w", err)
+		job.ScheduledTime,
+		job.SubmissionIntensity,
+		job.ExpectedIntensity,
+		job.CarbonSavings,
+		job.CarbonOptOut,
+		job.RequestID,
 	}
-
-	return nil
 }
 
 // GetJobByID retrieves a job by its ID
@@ -78,7 +145,10 @@ func (r *JobRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*models.J
 		SELECT 
 			id, user_id, docker_image, command, status, scheduled_time,
 			created_at, started_at, completed_at, deadline, 
-			estimated_duration, region, metadata
+			estimated_duration, region, metadata,
+			submission_intensity, co2_saved_grams,
+			expected_intensity, carbon_savings, carbon_opt_out, request_id,
+			start_delay_seconds, slo_met, deleted_at
 		FROM jobs
 		WHERE id = $1
 	`
@@ -98,6 +168,15 @@ func (r *JobRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*models.J
 		&job.EstimatedDuration,
 		&job.Region,
 		&job.Metadata,
+		&job.SubmissionIntensity,
+		&job.CO2SavedGrams,
+		&job.ExpectedIntensity,
+		&job.CarbonSavings,
+		&job.CarbonOptOut,
+		&job.RequestID,
+		&job.StartDelaySeconds,
+		&job.SLOMet,
+		&job.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -106,10 +185,59 @@ func (r *JobRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*models.J
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
+	job.Deleted = job.DeletedAt != nil
 
 	return job, nil
 }
 
+// SoftDelete hides a finished job from listings; it can still be fetched by ID. It
+// returns false if the job wasn't deleted: it doesn't exist, hasn't finished, or was
+// deleted already.
+func (r *JobRepository) SoftDelete(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET deleted_at = $1
+		WHERE id = $2 AND deleted_at IS NULL AND status IN ($3, $4, $5)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id,
+		models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ArchiveOlderThan soft-deletes the finished jobs created more than age ago and
+// returns how many were archived
+func (r *JobRepository) ArchiveOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	query := `
+		UPDATE jobs
+		SET deleted_at = $1
+		WHERE deleted_at IS NULL AND status IN ($2, $3, $4) AND created_at < $5
+	`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query, now,
+		models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled, now.Add(-age))
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive jobs: %w", err)
+	}
+
+	archived, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return archived, nil
+}
+
 // UpdateJobStatus updates the status of a job
 func (r *JobRepository) UpdateJobStatus(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
 	query := `
@@ -135,71 +263,451 @@ func (r *JobRepository) UpdateJobStatus(ctx context.Context, id uuid.UUID, statu
 	return nil
 }
 
-// GetJobsByStatus retrieves jobs by status
-func (r *JobRepository) GetJobsByStatus(ctx context.Context, status models.JobStatus, limit int) ([]*models.Job, error) {
+// UpdateJobStatusChecked moves a job to status only if models.CanTransition allows it from
+// the job's current status. Illegal moves, including a concurrent change between the read
+// and the write, return an error wrapping models.ErrIllegalTransition.
+func (r *JobRepository) UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
+	var current models.JobStatus
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM jobs WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("job not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get job status: %w", err)
+	}
+
+	return r.TransitionJobStatus(ctx, id, current, status)
+}
+
+// TransitionJobStatus moves a job from status from to status to, only if the job is still
+// in from and models.CanTransition allows the move. A job found in any other status, e.g.
+// one a worker started after the caller read it, returns an error wrapping
+// models.ErrIllegalTransition and is left unchanged.
+func (r *JobRepository) TransitionJobStatus(ctx context.Context, id uuid.UUID, from, to models.JobStatus) error {
+	if !models.CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, from, to)
+	}
+
+	// Only write if nobody changed the status since it was read
 	query := `
-		SELECT 
-			id, user_id, docker_image, command, status, scheduled_time,
-			created_at, started_at, completed_at, deadline, 
-			estimated_duration, region, metadata
+		UPDATE jobs
+		SET status = $1
+		WHERE id = $2 AND status = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, to, id, from)
+	if err != nil {
+		return fmt.Errorf("failed to update job status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s -> %s (status changed concurrently)", models.ErrIllegalTransition, from, to)
+	}
+
+	return nil
+}
+
+// ReleaseWaitingJob moves a WAITING job to PENDING, due at scheduledTime. It returns
+// false if the job was no longer WAITING, e.g. because another resolver released it.
+func (r *JobRepository) ReleaseWaitingJob(ctx context.Context, id uuid.UUID, scheduledTime time.Time) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET status = $1, scheduled_time = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, models.JobStatusPending, scheduledTime, id, models.JobStatusWaiting)
+	if err != nil {
+		return false, fmt.Errorf("failed to release waiting job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReopenFailedJob moves a FAILED job back to PENDING, due by deadline, for a dead-letter
+// requeue or replay, the one way out of a terminal status. It returns false if the job was
+// not FAILED, e.g. because a worker has yet to record the failure of its last attempt.
+func (r *JobRepository) ReopenFailedJob(ctx context.Context, id uuid.UUID, deadline time.Time) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET status = $1, deadline = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, models.JobStatusPending, deadline, id, models.JobStatusFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to reopen failed job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RecordStartSLO stores how late a job's first run started and whether that met the
+// start-time SLO. started_at itself is maintained by the status trigger.
+func (r *JobRepository) RecordStartSLO(ctx context.Context, id uuid.UUID, delay time.Duration, met bool) error {
+	query := `
+		UPDATE jobs
+		SET start_delay_seconds = $1, slo_met = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, int(delay.Seconds()), met, id)
+	if err != nil {
+		return fmt.Errorf("failed to record start SLO: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("job not found")
+	}
+
+	return nil
+}
+
+// CountStartSLO counts the jobs scheduled since the given time that have started,
+// and how many of them met the start-time SLO
+func (r *JobRepository) CountStartSLO(ctx context.Context, since time.Time) (onTime, total int, err error) {
+	query := `
+		SELECT COUNT(*)
+		FROM jobs
+		WHERE scheduled_time >= $1 AND slo_met = $2
+	`
+
+	var late int
+	if err := r.db.QueryRowContext(ctx, query, since, true).Scan(&onTime); err != nil {
+		return 0, 0, fmt.Errorf("failed to count on-time jobs: %w", err)
+	}
+	if err := r.db.QueryRowContext(ctx, query, since, false).Scan(&late); err != nil {
+		return 0, 0, fmt.Errorf("failed to count late jobs: %w", err)
+	}
+
+	return onTime, onTime + late, nil
+}
+
+// jobStatuses lists every status CountByStatus reports, including those with no jobs
+var jobStatuses = []models.JobStatus{
+	models.JobStatusPending,
+	models.JobStatusDelayed,
+	models.JobStatusRunning,
+	models.JobStatusCompleted,
+	models.JobStatusFailed,
+	models.JobStatusCancelled,
+	models.JobStatusWaiting,
+}
+
+// CountByStatus returns the number of jobs in each status
+func (r *JobRepository) CountByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	query := `
+		SELECT COUNT(*)
 		FROM jobs
 		WHERE status = $1
-		ORDER BY created_at DESC
-		LIMIT $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, status, limit)
+	counts := make(map[models.JobStatus]int, len(jobStatuses))
+	for _, status := range jobStatuses {
+		var count int
+		if err := r.db.QueryRowContext(ctx, query, status).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s jobs: %w", status, err)
+		}
+		counts[status] = count
+	}
+
+	return counts, nil
+}
+
+// GetJobTotals returns the CO2 saved across all jobs (as recorded by the metrics
+// collector) and the mean run time of completed jobs, or 0 when none have completed
+func (r *JobRepository) GetJobTotals(ctx context.Context) (co2SavedGrams float64, avgDuration time.Duration, err error) {
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(co2_saved_grams), 0) FROM jobs`).Scan(&co2SavedGrams); err != nil {
+		return 0, 0, fmt.Errorf("failed to sum CO2 savings: %w", err)
+	}
+
+	query := `
+		SELECT COALESCE(AVG(EXTRACT(EPOCH FROM (completed_at - started_at))), 0)
+		FROM jobs
+		WHERE status = $1 AND started_at IS NOT NULL AND completed_at >= started_at
+	`
+	var avgSeconds float64
+	if err := r.db.QueryRowContext(ctx, query, models.JobStatusCompleted).Scan(&avgSeconds); err != nil {
+		return 0, 0, fmt.Errorf("failed to average job duration: %w", err)
+	}
+
+	return co2SavedGrams, time.Duration(avgSeconds * float64(time.Second)), nil
+}
+
+// SaveScheduleWindows stores the scheduler's chosen window and alternatives for a job
+func (r *JobRepository) SaveScheduleWindows(ctx context.Context, id uuid.UUID, windows []models.ScheduleWindow) error {
+	data, err := json.Marshal(windows)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get jobs by status: %w", err)
+		return fmt.Errorf("failed to marshal schedule windows: %w", err)
+	}
+
+	query := `
+		UPDATE jobs
+		SET schedule_windows = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, data, id)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule windows: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("job not found")
+	}
+
+	return nil
+}
+
+// GetScheduleWindows returns the windows stored for a job, or nil when none were saved
+func (r *JobRepository) GetScheduleWindows(ctx context.Context, id uuid.UUID) ([]models.ScheduleWindow, error) {
+	query := `
+		SELECT schedule_windows
+		FROM jobs
+		WHERE id = $1
+	`
+
+	var data []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule windows: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var windows []models.ScheduleWindow
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule windows: %w", err)
+	}
+	return windows, nil
+}
+
+// durationHistorySize is how many of an image's most recent completed jobs are
+// averaged, so the estimate follows an image whose workload changes over time
+const durationHistorySize = 100
+
+// AddJobDependencies records the jobs that must complete before jobID is queued
+func (r *JobRepository) AddJobDependencies(ctx context.Context, jobID uuid.UUID, dependsOn []uuid.UUID) error {
+	query := `
+		INSERT INTO job_dependencies (job_id, depends_on)
+		VALUES ($1, $2)
+	`
+
+	for _, dependency := range dependsOn {
+		if _, err := r.db.ExecContext(ctx, query, jobID, dependency); err != nil {
+			return fmt.Errorf("failed to add job dependency: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetJobDependencies returns the jobs jobID depends on
+func (r *JobRepository) GetJobDependencies(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	return r.queryJobIDs(ctx, `SELECT depends_on FROM job_dependencies WHERE job_id = $1`, jobID)
+}
+
+// GetDependentJobs returns the jobs that depend on jobID
+func (r *JobRepository) GetDependentJobs(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	return r.queryJobIDs(ctx, `SELECT job_id FROM job_dependencies WHERE depends_on = $1`, jobID)
+}
+
+// AddJobLabels records the labels jobID was submitted with
+func (r *JobRepository) AddJobLabels(ctx context.Context, jobID uuid.UUID, labels map[string]string) error {
+	query := `
+		INSERT INTO job_labels (job_id, key, value)
+		VALUES ($1, $2, $3)
+	`
+
+	for _, key := range sortedKeys(labels) {
+		if _, err := r.db.ExecContext(ctx, query, jobID, key, labels[key]); err != nil {
+			return fmt.Errorf("failed to add job label: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetJobLabels returns jobID's labels, or nil when it has none
+func (r *JobRepository) GetJobLabels(ctx context.Context, jobID uuid.UUID) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT key, value FROM job_labels WHERE job_id = $1`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job labels: %w", err)
 	}
 	defer rows.Close()
 
-	var jobs []*models.Job
+	var labels map[string]string
 	for rows.Next() {
-		job := &models.Job{}
-		err := rows.Scan(
-			&job.ID,
-			&job.UserID,
-			&job.DockerImage,
-			&job.Command,
-			&job.Status,
-			&job.ScheduledTime,
-			&job.CreatedAt,
-			&job.StartedAt,
-			&job.CompletedAt,
-			&job.Deadline,
-			&job.EstimatedDuration,
-			&job.Region,
-			&job.Metadata,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan job: %w", err)
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan job label: %w", err)
 		}
-		jobs = append(jobs, job)
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job labels: %w", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	return labels, nil
+}
+
+// GetJobsByLabel retrieves the newest jobs carrying every one of labels
+func (r *JobRepository) GetJobsByLabel(ctx context.Context, labels map[string]string, limit int) ([]*models.Job, error) {
+	return r.QueryJobs(ctx, JobFilter{Labels: labels, Limit: limit})
+}
+
+// sortedKeys returns the keys of labels in order, so statements are built the same way every time
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	return keys
+}
 
-	return jobs, nil
+// queryJobIDs runs a query selecting a single column of job IDs
+func (r *JobRepository) queryJobIDs(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan job dependency: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job dependencies: %w", err)
+	}
+
+	return ids, nil
 }
 
-// GetAllJobs retrieves all jobs with optional limit
-func (r *JobRepository) GetAllJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+// GetActiveRegions returns the regions jobs have been submitted for since a time
+func (r *JobRepository) GetActiveRegions(ctx context.Context, since time.Time) ([]string, error) {
+	query := `SELECT DISTINCT region FROM jobs WHERE created_at >= $1`
+
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active regions: %w", err)
+	}
+	defer rows.Close()
+
+	var regions []string
+	for rows.Next() {
+		var region sql.NullString
+		if err := rows.Scan(&region); err != nil {
+			return nil, fmt.Errorf("failed to scan region: %w", err)
+		}
+		if region.Valid && region.String != "" {
+			regions = append(regions, region.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating active regions: %w", err)
+	}
+
+	return regions, nil
+}
+
+// GetAverageDurationByImage returns the mean run time of the most recent completed jobs
+// that used image, or 0 when none have completed yet. A job's run time spans its final
+// run, from started_at to completed_at (both set by the status trigger).
+func (r *JobRepository) GetAverageDurationByImage(ctx context.Context, image string) (time.Duration, error) {
+	query := `
+		SELECT started_at, completed_at
+		FROM jobs
+		WHERE docker_image = $1 AND status = $2
+		ORDER BY completed_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, image, models.JobStatusCompleted, durationHistorySize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query completed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var total time.Duration
+	var count int
+	for rows.Next() {
+		var startedAt, completedAt sql.NullTime
+		if err := rows.Scan(&startedAt, &completedAt); err != nil {
+			return 0, fmt.Errorf("failed to scan completed job: %w", err)
+		}
+		if !startedAt.Valid || !completedAt.Valid || completedAt.Time.Before(startedAt.Time) {
+			continue
+		}
+		total += completedAt.Time.Sub(startedAt.Time)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating completed jobs: %w", err)
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total / time.Duration(count), nil
+}
+
+// GetJobsByStatus retrieves jobs by status, leaving out soft-deleted jobs
+func (r *JobRepository) GetJobsByStatus(ctx context.Context, status models.JobStatus, limit int) ([]*models.Job, error) {
 	query := `
 		SELECT 
 			id, user_id, docker_image, command, status, scheduled_time,
 			created_at, started_at, completed_at, deadline, 
-			estimated_duration, region, metadata
+			estimated_duration, region, metadata,
+			submission_intensity, co2_saved_grams,
+			expected_intensity, carbon_savings, carbon_opt_out, request_id,
+			start_delay_seconds, slo_met
 		FROM jobs
+		WHERE status = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
-		LIMIT $1
+		LIMIT $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	rows, err := r.db.QueryContext(ctx, query, status, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all jobs: %w", err)
+		return nil, fmt.Errorf("failed to get jobs by status: %w", err)
 	}
 	defer rows.Close()
 
@@ -220,6 +728,14 @@ func (r *JobRepository) GetAllJobs(ctx context.Context, limit int) ([]*models.Jo
 			&job.EstimatedDuration,
 			&job.Region,
 			&job.Metadata,
+			&job.SubmissionIntensity,
+			&job.CO2SavedGrams,
+			&job.ExpectedIntensity,
+			&job.CarbonSavings,
+			&job.CarbonOptOut,
+			&job.RequestID,
+			&job.StartDelaySeconds,
+			&job.SLOMet,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan job: %w", err)
@@ -234,22 +750,116 @@ func (r *JobRepository) GetAllJobs(ctx context.Context, limit int) ([]*models.Jo
 	return jobs, nil
 }
 
-// GetJobsByUserID retrieves jobs by user ID
-func (r *JobRepository) GetJobsByUserID(ctx context.Context, userID string, limit int) ([]*models.Job, error) {
-	query := `
-		SELECT 
+// JobFilter narrows a job listing; zero-valued fields are not filtered on
+type JobFilter struct {
+	Status models.JobStatus
+	Region string
+	UserID string
+	Labels map[string]string // Jobs must carry every one of these labels
+	Since  time.Time         // Inclusive lower bound on created_at
+	Until  time.Time         // Exclusive upper bound on created_at
+	After  *JobCursor        // Only jobs older than this position (keyset pagination)
+	Limit  int
+}
+
+// JobCursor is a position in a job listing ordered by (created_at, id) descending.
+// Keyset positions stay valid across inserts: new jobs sort before any cursor.
+type JobCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorAfter returns the cursor that continues a listing after job
+func CursorAfter(job *models.Job) *JobCursor {
+	return &JobCursor{CreatedAt: job.CreatedAt, ID: job.ID}
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c *JobCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeJobCursor parses a token produced by JobCursor.Encode
+func DecodeJobCursor(token string) (*JobCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	cursor := &JobCursor{}
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return cursor, nil
+}
+
+// GetAllJobs retrieves all jobs with optional limit
+func (r *JobRepository) GetAllJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+	return r.QueryJobs(ctx, JobFilter{Limit: limit})
+}
+
+// QueryJobs retrieves the newest jobs matching filter, leaving out soft-deleted jobs
+func (r *JobRepository) QueryJobs(ctx context.Context, filter JobFilter) ([]*models.Job, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.Region != "" {
+		addCondition("region = $%d", filter.Region)
+	}
+	if filter.UserID != "" {
+		addCondition("user_id = $%d", filter.UserID)
+	}
+	for _, key := range sortedKeys(filter.Labels) {
+		args = append(args, key, filter.Labels[key])
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM job_labels WHERE job_id = jobs.id AND key = $%d AND value = $%d)", len(args)-1, len(args)))
+	}
+	if !filter.Since.IsZero() {
+		addCondition("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		addCondition("created_at < $%d", filter.Until)
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT
 			id, user_id, docker_image, command, status, scheduled_time,
-			created_at, started_at, completed_at, deadline, 
-			estimated_duration, region, metadata
+			created_at, started_at, completed_at, deadline,
+			estimated_duration, region, metadata,
+			submission_intensity, co2_saved_grams,
+			expected_intensity, carbon_savings, carbon_opt_out, request_id,
+			start_delay_seconds, slo_met
 		FROM jobs
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
-	`
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get jobs by user: %w", err)
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
 	}
 	defer rows.Close()
 
@@ -270,6 +880,14 @@ func (r *JobRepository) GetJobsByUserID(ctx context.Context, userID string, limi
 			&job.EstimatedDuration,
 			&job.Region,
 			&job.Metadata,
+			&job.SubmissionIntensity,
+			&job.CO2SavedGrams,
+			&job.ExpectedIntensity,
+			&job.CarbonSavings,
+			&job.CarbonOptOut,
+			&job.RequestID,
+			&job.StartDelaySeconds,
+			&job.SLOMet,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan job: %w", err)
@@ -283,3 +901,8 @@ func (r *JobRepository) GetJobsByUserID(ctx context.Context, userID string, limi
 
 	return jobs, nil
 }
+
+// GetJobsByUserID retrieves jobs by user ID
+func (r *JobRepository) GetJobsByUserID(ctx context.Context, userID string, limit int) ([]*models.Job, error) {
+	return r.QueryJobs(ctx, JobFilter{UserID: userID, Limit: limit})
+}
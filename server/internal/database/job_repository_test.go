@@ -0,0 +1,824 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/google/uuid"
+)
+
+func newFakeJobRepository(t *testing.T) *JobRepository {
+	t.Helper()
+
+	return NewJobRepository(&DB{openFakeDB(t)})
+}
+
+func TestJobRepository_GetActiveRegions(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	region := func(r string) *string { return &r }
+	for _, job := range []*models.Job{
+		{UserID: "user-1", DockerImage: "alpine:latest", Region: region("US-EAST"), CreatedAt: time.Now().Add(-time.Hour)},
+		{UserID: "user-1", DockerImage: "alpine:latest", Region: region("US-EAST"), CreatedAt: time.Now().Add(-2 * time.Hour)},
+		{UserID: "user-2", DockerImage: "alpine:latest", Region: region("EU-NORTH"), CreatedAt: time.Now().Add(-3 * time.Hour)},
+		{UserID: "user-2", DockerImage: "alpine:latest", Region: region("AP-SOUTH"), CreatedAt: time.Now().Add(-48 * time.Hour)},
+		{UserID: "user-3", DockerImage: "alpine:latest", CreatedAt: time.Now()}, // No region
+	} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	regions, err := repo.GetActiveRegions(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetActiveRegions returned error: %v", err)
+	}
+	sort.Strings(regions)
+	if strings.Join(regions, ",") != "EU-NORTH,US-EAST" {
+		t.Errorf("expected EU-NORTH and US-EAST, got %v", regions)
+	}
+}
+
+func TestJobRepository_CreateJobIsIdempotentOnID(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	id := uuid.New()
+	first := &models.Job{ID: id, UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	if err := repo.CreateJob(ctx, first); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+	if first.ID != id {
+		t.Fatalf("expected the supplied ID %s to be kept, got %s", id, first.ID)
+	}
+
+	// A retry with the same ID gets the stored job back instead of a duplicate
+	retry := &models.Job{ID: id, UserID: "user-1", DockerImage: "python:3.12", Deadline: time.Now().Add(2 * time.Hour)}
+	if err := repo.CreateJob(ctx, retry); err != nil {
+		t.Fatalf("CreateJob retry returned error: %v", err)
+	}
+	if retry.ID != id || retry.DockerImage != "alpine:latest" || !retry.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("expected the original job back, got %+v", retry)
+	}
+
+	jobs, err := repo.GetJobsByUserID(ctx, "user-1", 10)
+	if err != nil {
+		t.Fatalf("GetJobsByUserID returned error: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("expected a single stored job, got %d", len(jobs))
+	}
+
+	// Another user can't claim the ID
+	if err := repo.CreateJob(ctx, &models.Job{ID: id, UserID: "user-2", DockerImage: "alpine:latest"}); err == nil {
+		t.Error("expected an error for another user's job ID")
+	}
+}
+
+func TestJobRepository_CreateJobsBatch(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	region := "EU-NORTH"
+	jobs := []*models.Job{
+		{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)},
+		{UserID: "user-1", DockerImage: "python:3.12", Deadline: time.Now().Add(2 * time.Hour), Region: &region, Status: models.JobStatusWaiting},
+	}
+	if err := repo.CreateJobsBatch(ctx, jobs); err != nil {
+		t.Fatalf("CreateJobsBatch returned error: %v", err)
+	}
+
+	for _, job := range jobs {
+		if job.ID == uuid.Nil || job.CreatedAt.IsZero() {
+			t.Fatalf("expected an ID and creation time to be assigned, got %+v", job)
+		}
+		got, err := repo.GetJobByID(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("GetJobByID(%s) returned error: %v", job.ID, err)
+		}
+		if got.DockerImage != job.DockerImage || got.Status != job.Status {
+			t.Errorf("expected %s %s, got %s %s", job.DockerImage, job.Status, got.DockerImage, got.Status)
+		}
+	}
+	if jobs[0].Status != models.JobStatusPending {
+		t.Errorf("expected the default status PENDING, got %s", jobs[0].Status)
+	}
+
+	if err := repo.CreateJobsBatch(ctx, nil); err != nil {
+		t.Errorf("expected an empty batch to be a no-op, got %v", err)
+	}
+}
+
+func TestJobRepository_SchedulingDecisionRoundTrip(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	scheduled := time.Now().Add(3 * time.Hour).Truncate(time.Second)
+	submission, expected, savings := 520.0, 180.5, 339.5
+	job := &models.Job{
+		UserID:              "user-1",
+		DockerImage:         "alpine:latest",
+		Deadline:            time.Now().Add(12 * time.Hour),
+		ScheduledTime:       &scheduled,
+		SubmissionIntensity: &submission,
+		ExpectedIntensity:   &expected,
+		CarbonSavings:       &savings,
+	}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+
+	got, err := repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.ScheduledTime == nil || !got.ScheduledTime.Equal(scheduled) {
+		t.Errorf("expected scheduled_time %s, got %v", scheduled, got.ScheduledTime)
+	}
+	if got.ExpectedIntensity == nil || *got.ExpectedIntensity != expected {
+		t.Errorf("expected expected_intensity %.1f, got %v", expected, got.ExpectedIntensity)
+	}
+	if got.CarbonSavings == nil || *got.CarbonSavings != savings {
+		t.Errorf("expected carbon_savings %.1f, got %v", savings, got.CarbonSavings)
+	}
+
+	jobs, err := repo.GetJobsByUserID(ctx, "user-1", 10)
+	if err != nil {
+		t.Fatalf("GetJobsByUserID returned error: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].CarbonSavings == nil || *jobs[0].CarbonSavings != savings {
+		t.Errorf("expected user listing to carry carbon_savings %.1f, got %+v", savings, jobs)
+	}
+}
+
+func TestJobRepository_ImmediateJobWithoutDecision(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+
+	got, err := repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.ExpectedIntensity != nil || got.CarbonSavings != nil {
+		t.Errorf("expected no scheduling decision, got expected=%v savings=%v", got.ExpectedIntensity, got.CarbonSavings)
+	}
+}
+
+func TestJobRepository_RequestIDRoundTrip(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	requestID := "req-7f3a"
+	tagged := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour), RequestID: &requestID}
+	untagged := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	for _, job := range []*models.Job{tagged, untagged} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	got, err := repo.GetJobByID(ctx, tagged.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.RequestID == nil || *got.RequestID != requestID {
+		t.Errorf("expected request_id %q, got %v", requestID, got.RequestID)
+	}
+
+	got, err = repo.GetJobByID(ctx, untagged.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.RequestID != nil {
+		t.Errorf("expected a job submitted without a request ID to read back nil, got %q", *got.RequestID)
+	}
+}
+
+func TestJobRepository_CountStartSLO(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+	objective := slo.Objective{StartThreshold: time.Minute, Target: 0.9, Window: 24 * time.Hour}
+	now := time.Now()
+
+	// 18 jobs in the window start on time, 2 start late, one outside the window is late
+	// and one in the window hasn't started yet
+	seed := func(scheduled time.Time, delay time.Duration, started bool) {
+		job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: now.Add(time.Hour), ScheduledTime: &scheduled}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		if !started {
+			return
+		}
+		if err := repo.RecordStartSLO(ctx, job.ID, delay, objective.Met(delay)); err != nil {
+			t.Fatalf("RecordStartSLO returned error: %v", err)
+		}
+		got, err := repo.GetJobByID(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("GetJobByID returned error: %v", err)
+		}
+		if got.StartDelaySeconds == nil || *got.StartDelaySeconds != int(delay.Seconds()) || got.SLOMet == nil || *got.SLOMet != objective.Met(delay) {
+			t.Fatalf("expected delay %v recorded on the job, got %v / %v", delay, got.StartDelaySeconds, got.SLOMet)
+		}
+	}
+	for i := 0; i < 18; i++ {
+		seed(now.Add(-time.Duration(i+1)*time.Hour), time.Duration(i)*3*time.Second, true)
+	}
+	seed(now.Add(-2*time.Hour), 5*time.Minute, true)
+	seed(now.Add(-3*time.Hour), 61*time.Second, true)
+	seed(now.Add(-48*time.Hour), time.Hour, true)
+	seed(now.Add(-time.Minute), 0, false)
+
+	onTime, total, err := repo.CountStartSLO(ctx, now.Add(-objective.Window))
+	if err != nil {
+		t.Fatalf("CountStartSLO returned error: %v", err)
+	}
+	if onTime != 18 || total != 20 {
+		t.Fatalf("expected 18 of 20 jobs on time, got %d of %d", onTime, total)
+	}
+
+	report := objective.Report(onTime, total)
+	if report.Compliance != 0.9 || !report.Met {
+		t.Errorf("expected 90%% compliance meeting the 90%% target, got %+v", report)
+	}
+}
+
+func TestJobRepository_GetAverageDurationByImage(t *testing.T) {
+	db := openFakeDB(t)
+	repo := NewJobRepository(&DB{db})
+	ctx := context.Background()
+	now := time.Now()
+
+	// seed sets started_at and completed_at directly, standing in for the status trigger
+	seed := func(image string, status models.JobStatus, runTime time.Duration, completedAgo time.Duration) {
+		job := &models.Job{UserID: "user-1", DockerImage: image, Deadline: now.Add(time.Hour)}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		completed := now.Add(-completedAgo)
+		if _, err := db.ExecContext(ctx, `UPDATE jobs SET status = $1, started_at = $2, completed_at = $3 WHERE id = $4`,
+			status, completed.Add(-runTime), completed, job.ID); err != nil {
+			t.Fatalf("failed to finish job: %v", err)
+		}
+	}
+
+	if avg, err := repo.GetAverageDurationByImage(ctx, "python:3.12"); err != nil || avg != 0 {
+		t.Fatalf("expected no estimate without history, got %v (err %v)", avg, err)
+	}
+
+	seed("python:3.12", models.JobStatusCompleted, 2*time.Minute, time.Hour)
+	seed("python:3.12", models.JobStatusCompleted, 4*time.Minute, 2*time.Hour)
+	seed("python:3.12", models.JobStatusCompleted, 6*time.Minute, 3*time.Hour)
+	seed("python:3.12", models.JobStatusFailed, time.Hour, time.Hour) // Failed runs don't count
+	seed("alpine:latest", models.JobStatusCompleted, 30*time.Minute, time.Hour)
+
+	avg, err := repo.GetAverageDurationByImage(ctx, "python:3.12")
+	if err != nil {
+		t.Fatalf("GetAverageDurationByImage returned error: %v", err)
+	}
+	if avg != 4*time.Minute {
+		t.Errorf("expected the mean of 2, 4 and 6 minutes, got %v", avg)
+	}
+
+	// Only the most recent completed jobs are averaged
+	for i := 0; i < durationHistorySize; i++ {
+		seed("python:3.12", models.JobStatusCompleted, 10*time.Minute, time.Duration(i)*time.Second)
+	}
+	if avg, err := repo.GetAverageDurationByImage(ctx, "python:3.12"); err != nil || avg != 10*time.Minute {
+		t.Errorf("expected older runs to fall out of the average, got %v (err %v)", avg, err)
+	}
+}
+
+func TestJobRepository_UpdateJobStatusChecked(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+
+	for _, status := range []models.JobStatus{models.JobStatusRunning, models.JobStatusCompleted} {
+		if err := repo.UpdateJobStatusChecked(ctx, job.ID, status); err != nil {
+			t.Fatalf("UpdateJobStatusChecked(%s) returned error: %v", status, err)
+		}
+	}
+
+	// A duplicate message must not flip the finished job back to RUNNING
+	err := repo.UpdateJobStatusChecked(ctx, job.ID, models.JobStatusRunning)
+	if !errors.Is(err, models.ErrIllegalTransition) {
+		t.Fatalf("expected ErrIllegalTransition, got %v", err)
+	}
+	got, err := repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.Status != models.JobStatusCompleted {
+		t.Errorf("expected status to stay COMPLETED, got %s", got.Status)
+	}
+
+	if err := repo.UpdateJobStatusChecked(ctx, uuid.New(), models.JobStatusRunning); err == nil || errors.Is(err, models.ErrIllegalTransition) {
+		t.Errorf("expected not found for an unknown job, got %v", err)
+	}
+}
+
+func TestJobRepository_TransitionJobStatus(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+	if err := repo.UpdateJobStatusChecked(ctx, job.ID, models.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateJobStatusChecked returned error: %v", err)
+	}
+
+	// A caller that read the job as PENDING must not cancel it now that it runs, even
+	// though RUNNING -> CANCELLED is itself legal
+	err := repo.TransitionJobStatus(ctx, job.ID, models.JobStatusPending, models.JobStatusCancelled)
+	if !errors.Is(err, models.ErrIllegalTransition) {
+		t.Fatalf("expected ErrIllegalTransition, got %v", err)
+	}
+	got, err := repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.Status != models.JobStatusRunning {
+		t.Errorf("expected status to stay RUNNING, got %s", got.Status)
+	}
+
+	if err := repo.TransitionJobStatus(ctx, job.ID, models.JobStatusRunning, models.JobStatusCancelled); err != nil {
+		t.Errorf("expected RUNNING -> CANCELLED to apply, got %v", err)
+	}
+}
+
+func TestJobRepository_ReopenFailedJob(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+
+	// Only a FAILED job can be reopened
+	if reopened, err := repo.ReopenFailedJob(ctx, job.ID, job.Deadline); err != nil || reopened {
+		t.Fatalf("expected a PENDING job not to be reopened, got %v (err %v)", reopened, err)
+	}
+	if err := repo.UpdateJobStatusChecked(ctx, job.ID, models.JobStatusFailed); err != nil {
+		t.Fatalf("UpdateJobStatusChecked returned error: %v", err)
+	}
+	deadline := time.Now().Add(6 * time.Hour).Truncate(time.Second)
+	if reopened, err := repo.ReopenFailedJob(ctx, job.ID, deadline); err != nil || !reopened {
+		t.Fatalf("expected the FAILED job to be reopened, got %v (err %v)", reopened, err)
+	}
+
+	got, err := repo.GetJobByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if got.Status != models.JobStatusPending || !got.Deadline.Equal(deadline) {
+		t.Errorf("expected status PENDING due by %v, got %s due by %v", deadline, got.Status, got.Deadline)
+	}
+}
+
+func TestJobRepository_QueryJobsFilters(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	seed := []struct {
+		name    string
+		userID  string
+		region  string
+		status  models.JobStatus
+		created time.Time
+	}{
+		{"a", "alice", "US-EAST", models.JobStatusPending, base},
+		{"b", "alice", "EU-WEST", models.JobStatusCompleted, base.Add(1 * time.Hour)},
+		{"c", "bob", "US-EAST", models.JobStatusCompleted, base.Add(2 * time.Hour)},
+		{"d", "bob", "EU-WEST", models.JobStatusFailed, base.Add(3 * time.Hour)},
+	}
+	names := make(map[string]string)
+	for _, s := range seed {
+		region := s.region
+		job := &models.Job{
+			UserID:      s.userID,
+			DockerImage: "alpine:latest",
+			Status:      s.status,
+			Region:      &region,
+			CreatedAt:   s.created,
+			Deadline:    s.created.Add(24 * time.Hour),
+		}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		names[job.ID.String()] = s.name
+	}
+
+	tests := []struct {
+		name   string
+		filter JobFilter
+		want   []string
+	}{
+		{"no filter", JobFilter{}, []string{"d", "c", "b", "a"}},
+		{"status", JobFilter{Status: models.JobStatusCompleted}, []string{"c", "b"}},
+		{"region", JobFilter{Region: "EU-WEST"}, []string{"d", "b"}},
+		{"user", JobFilter{UserID: "alice"}, []string{"b", "a"}},
+		{"since", JobFilter{Since: base.Add(2 * time.Hour)}, []string{"d", "c"}},
+		{"until", JobFilter{Until: base.Add(2 * time.Hour)}, []string{"b", "a"}},
+		{"since and until", JobFilter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"c", "b"}},
+		{"status and region", JobFilter{Status: models.JobStatusCompleted, Region: "US-EAST"}, []string{"c"}},
+		{"user, region and window", JobFilter{UserID: "bob", Region: "EU-WEST", Since: base}, []string{"d"}},
+		{"unknown region", JobFilter{Region: "MARS-1"}, nil},
+		{"limit", JobFilter{Limit: 2}, []string{"d", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			if filter.Limit == 0 {
+				filter.Limit = 100
+			}
+			jobs, err := repo.QueryJobs(ctx, filter)
+			if err != nil {
+				t.Fatalf("QueryJobs returned error: %v", err)
+			}
+			var got []string
+			for _, job := range jobs {
+				got = append(got, names[job.ID.String()])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got jobs %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobRepository_QueryJobsPagesWithCursor(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	// Pairs of jobs share a created_at so the id tiebreak is exercised
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	seeded := make(map[string]bool)
+	for i := 0; i < 150; i++ {
+		created := base.Add(time.Duration(i/2) * time.Minute)
+		job := &models.Job{UserID: "tenant", DockerImage: "alpine:latest", CreatedAt: created, Deadline: created.Add(time.Hour)}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		seeded[job.ID.String()] = true
+	}
+
+	seen := make(map[string]bool)
+	var cursor *JobCursor
+	var previous *models.Job
+	pages := 0
+	for {
+		jobs, err := repo.QueryJobs(ctx, JobFilter{UserID: "tenant", After: cursor, Limit: 40})
+		if err != nil {
+			t.Fatalf("QueryJobs returned error: %v", err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		pages++
+
+		for _, job := range jobs {
+			if seen[job.ID.String()] {
+				t.Fatalf("job %s returned twice", job.ID)
+			}
+			seen[job.ID.String()] = true
+			if previous != nil && previous.CreatedAt.Before(job.CreatedAt) {
+				t.Fatalf("jobs out of order: %v before %v", previous.CreatedAt, job.CreatedAt)
+			}
+			previous = job
+		}
+
+		// A job submitted mid-listing sorts before the cursor and must not shift later pages
+		if pages == 1 {
+			late := &models.Job{UserID: "tenant", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour)}
+			if err := repo.CreateJob(ctx, late); err != nil {
+				t.Fatalf("CreateJob returned error: %v", err)
+			}
+		}
+
+		// Round-trip the cursor through its opaque form, as a client would
+		cursor, err = DecodeJobCursor(CursorAfter(jobs[len(jobs)-1]).Encode())
+		if err != nil {
+			t.Fatalf("DecodeJobCursor returned error: %v", err)
+		}
+	}
+
+	if pages != 4 {
+		t.Errorf("expected 4 pages of at most 40, got %d", pages)
+	}
+	if len(seen) != len(seeded) {
+		t.Errorf("paged through %d jobs, want %d", len(seen), len(seeded))
+	}
+	for id := range seeded {
+		if !seen[id] {
+			t.Errorf("seeded job %s was never returned", id)
+		}
+	}
+}
+
+func TestDecodeJobCursor_RejectsMalformedTokens(t *testing.T) {
+	for _, token := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", "eWVzdGVyZGF5fDEyMw"} {
+		if _, err := DecodeJobCursor(token); err == nil {
+			t.Errorf("expected %q to be rejected", token)
+		}
+	}
+}
+
+func TestJobRepository_ScheduleWindowsRoundTrip(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", ScheduledTime: &start, Deadline: start.Add(12 * time.Hour)}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob returned error: %v", err)
+	}
+
+	windows, err := repo.GetScheduleWindows(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetScheduleWindows returned error: %v", err)
+	}
+	if windows != nil {
+		t.Errorf("expected no windows before any were saved, got %+v", windows)
+	}
+
+	saved := []models.ScheduleWindow{
+		{StartTime: start, EndTime: start.Add(time.Hour), AvgIntensity: 110, CarbonCost: 110, Chosen: true},
+		{StartTime: start.Add(time.Hour), EndTime: start.Add(2 * time.Hour), AvgIntensity: 115, CarbonCost: 115},
+		{StartTime: start.Add(-time.Hour), EndTime: start, AvgIntensity: 118.5, CarbonCost: 118.5},
+	}
+	if err := repo.SaveScheduleWindows(ctx, job.ID, saved); err != nil {
+		t.Fatalf("SaveScheduleWindows returned error: %v", err)
+	}
+
+	windows, err = repo.GetScheduleWindows(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetScheduleWindows returned error: %v", err)
+	}
+	if len(windows) != len(saved) {
+		t.Fatalf("expected %d windows, got %+v", len(saved), windows)
+	}
+	for i, window := range windows {
+		want := saved[i]
+		if !window.StartTime.Equal(want.StartTime) || !window.EndTime.Equal(want.EndTime) ||
+			window.AvgIntensity != want.AvgIntensity || window.Chosen != want.Chosen {
+			t.Errorf("window %d = %+v, want %+v", i, window, want)
+		}
+	}
+
+	if err := repo.SaveScheduleWindows(ctx, uuid.New(), saved); err == nil {
+		t.Error("expected saving windows for an unknown job to fail")
+	}
+}
+
+func TestJobRepository_CountByStatus(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	for _, status := range []models.JobStatus{models.JobStatusPending, models.JobStatusPending, models.JobStatusCompleted, models.JobStatusFailed} {
+		job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Status: status, Deadline: time.Now().Add(time.Hour)}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	counts, err := repo.CountByStatus(ctx)
+	if err != nil {
+		t.Fatalf("CountByStatus returned error: %v", err)
+	}
+	want := map[models.JobStatus]int{
+		models.JobStatusPending:   2,
+		models.JobStatusDelayed:   0,
+		models.JobStatusRunning:   0,
+		models.JobStatusCompleted: 1,
+		models.JobStatusFailed:    1,
+		models.JobStatusCancelled: 0,
+		models.JobStatusWaiting:   0,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("counts = %v, want every status reported", counts)
+	}
+	for status, n := range want {
+		if counts[status] != n {
+			t.Errorf("%s count = %d, want %d", status, counts[status], n)
+		}
+	}
+}
+
+func TestJobRepository_Dependencies(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	newJob := func(status models.JobStatus) *models.Job {
+		job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Status: status, Deadline: time.Now().Add(12 * time.Hour)}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		return job
+	}
+	first, second := newJob(models.JobStatusRunning), newJob(models.JobStatusRunning)
+	waiting := newJob(models.JobStatusWaiting)
+
+	if err := repo.AddJobDependencies(ctx, waiting.ID, []uuid.UUID{first.ID, second.ID}); err != nil {
+		t.Fatalf("AddJobDependencies returned error: %v", err)
+	}
+
+	dependsOn, err := repo.GetJobDependencies(ctx, waiting.ID)
+	if err != nil || len(dependsOn) != 2 {
+		t.Fatalf("GetJobDependencies = %v, %v; want both dependencies", dependsOn, err)
+	}
+	dependents, err := repo.GetDependentJobs(ctx, first.ID)
+	if err != nil || len(dependents) != 1 || dependents[0] != waiting.ID {
+		t.Fatalf("GetDependentJobs = %v, %v; want the waiting job", dependents, err)
+	}
+
+	releasedAt := time.Now().Truncate(time.Second)
+	released, err := repo.ReleaseWaitingJob(ctx, waiting.ID, releasedAt)
+	if err != nil || !released {
+		t.Fatalf("ReleaseWaitingJob = %v, %v; want released", released, err)
+	}
+	got, _ := repo.GetJobByID(ctx, waiting.ID)
+	if got.Status != models.JobStatusPending || got.ScheduledTime == nil || !got.ScheduledTime.Equal(releasedAt) {
+		t.Errorf("released job = %s due %v, want PENDING due %v", got.Status, got.ScheduledTime, releasedAt)
+	}
+
+	// Only a WAITING job can be released
+	if released, err := repo.ReleaseWaitingJob(ctx, waiting.ID, releasedAt); err != nil || released {
+		t.Errorf("second ReleaseWaitingJob = %v, %v; want not released", released, err)
+	}
+}
+
+func TestJobRepository_Labels(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	seed := []struct {
+		name   string
+		labels map[string]string
+	}{
+		{"a", map[string]string{"team": "ml", "project": "vision"}},
+		{"b", map[string]string{"team": "ml", "project": "speech"}},
+		{"c", map[string]string{"team": "data", "project": "vision"}},
+		{"d", nil},
+	}
+	names := make(map[string]string)
+	var first uuid.UUID
+	for i, s := range seed {
+		created := base.Add(time.Duration(i) * time.Hour)
+		job := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", CreatedAt: created, Deadline: created.Add(24 * time.Hour)}
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		if err := repo.AddJobLabels(ctx, job.ID, s.labels); err != nil {
+			t.Fatalf("AddJobLabels returned error: %v", err)
+		}
+		names[job.ID.String()] = s.name
+		if i == 0 {
+			first = job.ID
+		}
+	}
+
+	labels, err := repo.GetJobLabels(ctx, first)
+	if err != nil || len(labels) != 2 || labels["team"] != "ml" || labels["project"] != "vision" {
+		t.Errorf("GetJobLabels = %v, %v; want team=ml, project=vision", labels, err)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{"one label", map[string]string{"team": "ml"}, []string{"b", "a"}},
+		{"other key", map[string]string{"project": "vision"}, []string{"c", "a"}},
+		{"labels are AND-ed", map[string]string{"team": "ml", "project": "vision"}, []string{"a"}},
+		{"no job has both", map[string]string{"team": "data", "project": "speech"}, nil},
+		{"value must match", map[string]string{"team": "ops"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := repo.GetJobsByLabel(ctx, tt.labels, 100)
+			if err != nil {
+				t.Fatalf("GetJobsByLabel returned error: %v", err)
+			}
+			var got []string
+			for _, job := range jobs {
+				got = append(got, names[job.ID.String()])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got jobs %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Labels combine with the other filters
+	jobs, err := repo.QueryJobs(ctx, JobFilter{Labels: map[string]string{"project": "vision"}, Since: base.Add(time.Hour), Limit: 100})
+	if err != nil || len(jobs) != 1 || names[jobs[0].ID.String()] != "c" {
+		t.Errorf("QueryJobs with a label and since = %v, %v; want only c", jobs, err)
+	}
+}
+
+func TestJobRepository_SoftDelete(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	finished := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Status: models.JobStatusCompleted, Deadline: time.Now().Add(time.Hour)}
+	pending := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Status: models.JobStatusPending, Deadline: time.Now().Add(time.Hour)}
+	for _, job := range []*models.Job{finished, pending} {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	if deleted, err := repo.SoftDelete(ctx, pending.ID); err != nil || deleted {
+		t.Errorf("expected an unfinished job not to be deleted, got %v (err %v)", deleted, err)
+	}
+	if deleted, err := repo.SoftDelete(ctx, finished.ID); err != nil || !deleted {
+		t.Fatalf("expected the finished job to be deleted, got %v (err %v)", deleted, err)
+	}
+	if deleted, _ := repo.SoftDelete(ctx, finished.ID); deleted {
+		t.Error("expected a second delete to report nothing deleted")
+	}
+
+	// Gone from listings...
+	jobs, err := repo.GetJobsByUserID(ctx, "user-1", 10)
+	if err != nil {
+		t.Fatalf("GetJobsByUserID returned error: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != pending.ID {
+		t.Errorf("expected only the pending job to be listed, got %d jobs", len(jobs))
+	}
+	if completed, _ := repo.GetJobsByStatus(ctx, models.JobStatusCompleted, 10); len(completed) != 0 {
+		t.Errorf("expected no completed jobs to be listed, got %d", len(completed))
+	}
+
+	// ...but still fetchable by ID, flagged as deleted
+	got, err := repo.GetJobByID(ctx, finished.ID)
+	if err != nil {
+		t.Fatalf("GetJobByID returned error: %v", err)
+	}
+	if !got.Deleted || got.DeletedAt == nil {
+		t.Errorf("expected the job to be flagged as deleted, got %+v", got)
+	}
+	if got, _ := repo.GetJobByID(ctx, pending.ID); got.Deleted {
+		t.Error("expected the pending job not to be flagged as deleted")
+	}
+}
+
+func TestJobRepository_ArchiveOlderThan(t *testing.T) {
+	repo := newFakeJobRepository(t)
+	ctx := context.Background()
+
+	job := func(status models.JobStatus, age time.Duration) *models.Job {
+		j := &models.Job{UserID: "user-1", DockerImage: "alpine:latest", Status: status, Deadline: time.Now().Add(time.Hour), CreatedAt: time.Now().Add(-age)}
+		if err := repo.CreateJob(ctx, j); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+		return j
+	}
+	job(models.JobStatusCompleted, 40*24*time.Hour)
+	job(models.JobStatusCancelled, 40*24*time.Hour)
+	recent := job(models.JobStatusFailed, 24*time.Hour)
+	running := job(models.JobStatusRunning, 40*24*time.Hour) // Unfinished jobs are never archived
+
+	archived, err := repo.ArchiveOlderThan(ctx, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveOlderThan returned error: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("expected 2 archived jobs, got %d", archived)
+	}
+
+	jobs, err := repo.GetAllJobs(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetAllJobs returned error: %v", err)
+	}
+	ids := map[uuid.UUID]bool{}
+	for _, j := range jobs {
+		ids[j.ID] = true
+	}
+	if len(jobs) != 2 || !ids[recent.ID] || !ids[running.ID] {
+		t.Errorf("expected the recent and running jobs to remain listed, got %d jobs", len(jobs))
+	}
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// RecurringJobRepository handles database operations for recurring jobs
+type RecurringJobRepository struct {
+	db *DB
+}
+
+// NewRecurringJobRepository creates a new recurring job repository
+func NewRecurringJobRepository(db *DB) *RecurringJobRepository {
+	return &RecurringJobRepository{db: db}
+}
+
+// recurringJobColumns are selected, in order, by scanRecurringJob
+const recurringJobColumns = `id, user_id, cron_expression, timezone, job_spec, next_run_at, last_run_at, last_job_id, last_error, created_at`
+
+// CreateRecurringJob inserts a new recurring job
+func (r *RecurringJobRepository) CreateRecurringJob(ctx context.Context, job *models.RecurringJob) error {
+	spec, err := json.Marshal(job.JobSpec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job spec: %w", err)
+	}
+
+	query := `
+		INSERT INTO recurring_jobs (id, user_id, cron_expression, timezone, job_spec, next_run_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+
+	err = r.db.QueryRowContext(ctx, query,
+		job.ID, job.UserID, job.CronExpression, job.Timezone, spec, job.NextRunAt, job.CreatedAt,
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recurring job: %w", err)
+	}
+
+	return nil
+}
+
+// ListRecurringJobs returns recurring jobs, newest first, limited to userID's when it
+// isn't empty
+func (r *RecurringJobRepository) ListRecurringJobs(ctx context.Context, userID string, limit int) ([]*models.RecurringJob, error) {
+	query := `SELECT ` + recurringJobColumns + ` FROM recurring_jobs ORDER BY created_at DESC LIMIT $1`
+	args := []interface{}{limit}
+	if userID != "" {
+		query = `SELECT ` + recurringJobColumns + ` FROM recurring_jobs WHERE user_id = $2 ORDER BY created_at DESC LIMIT $1`
+		args = append(args, userID)
+	}
+
+	return r.queryRecurringJobs(ctx, query, args...)
+}
+
+// GetDueRecurringJobs returns up to limit recurring jobs whose next run is at or before
+// now, most overdue first
+func (r *RecurringJobRepository) GetDueRecurringJobs(ctx context.Context, now time.Time, limit int) ([]*models.RecurringJob, error) {
+	query := `SELECT ` + recurringJobColumns + ` FROM recurring_jobs WHERE next_run_at <= $1 ORDER BY next_run_at LIMIT $2`
+	return r.queryRecurringJobs(ctx, query, now, limit)
+}
+
+// ClaimRecurringRun advances a recurring job's next run from due to next, recording
+// ranAt as its last run. It returns false if the run was already claimed (by another
+// API replica), in which case nothing changes.
+func (r *RecurringJobRepository) ClaimRecurringRun(ctx context.Context, id uuid.UUID, due, next, ranAt time.Time) (bool, error) {
+	query := `
+		UPDATE recurring_jobs
+		SET next_run_at = $1, last_run_at = $2
+		WHERE id = $3 AND next_run_at = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, next, ranAt, id, due)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim recurring run: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RecordRecurringRun stores the outcome of a claimed run: the job it submitted, or
+// why the submission failed
+func (r *RecurringJobRepository) RecordRecurringRun(ctx context.Context, id uuid.UUID, jobID *uuid.UUID, runErr *string) error {
+	query := `
+		UPDATE recurring_jobs
+		SET last_job_id = $1, last_error = $2
+		WHERE id = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, jobID, runErr, id); err != nil {
+		return fmt.Errorf("failed to record recurring run: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecurringJob removes a recurring job. Jobs it already submitted are kept.
+func (r *RecurringJobRepository) DeleteRecurringJob(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM recurring_jobs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete recurring job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("recurring job not found")
+	}
+
+	return nil
+}
+
+// queryRecurringJobs runs a SELECT of recurringJobColumns
+func (r *RecurringJobRepository) queryRecurringJobs(ctx context.Context, query string, args ...interface{}) ([]*models.RecurringJob, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recurring jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.RecurringJob
+	for rows.Next() {
+		job, err := scanRecurringJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recurring jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// scanRecurringJob reads one row of recurringJobColumns
+func scanRecurringJob(rows *sql.Rows) (*models.RecurringJob, error) {
+	var job models.RecurringJob
+	var spec []byte
+	var lastRunAt sql.NullTime
+	var lastJobID uuid.NullUUID
+	var lastError sql.NullString
+
+	if err := rows.Scan(
+		&job.ID, &job.UserID, &job.CronExpression, &job.Timezone, &spec,
+		&job.NextRunAt, &lastRunAt, &lastJobID, &lastError, &job.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan recurring job: %w", err)
+	}
+
+	if err := json.Unmarshal(spec, &job.JobSpec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job spec of recurring job %s: %w", job.ID, err)
+	}
+	if lastRunAt.Valid {
+		job.LastRunAt = &lastRunAt.Time
+	}
+	if lastJobID.Valid {
+		job.LastJobID = &lastJobID.UUID
+	}
+	if lastError.Valid {
+		job.LastError = &lastError.String
+	}
+
+	return &job, nil
+}
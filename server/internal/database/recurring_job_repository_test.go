@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestRecurringJobRepository_DueAndClaim(t *testing.T) {
+	repo := NewRecurringJobRepository(&DB{openFakeDB(t)})
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	due := &models.RecurringJob{
+		UserID:         "user-1",
+		CronExpression: "0 * * * *",
+		Timezone:       "UTC",
+		JobSpec:        models.SubmitJobRequest{DockerImage: "alpine:latest", Command: []string{"date"}},
+		NextRunAt:      now.Add(-time.Minute),
+	}
+	later := &models.RecurringJob{
+		UserID:         "user-2",
+		CronExpression: "0 2 * * *",
+		Timezone:       "UTC",
+		JobSpec:        models.SubmitJobRequest{DockerImage: "alpine:latest"},
+		NextRunAt:      now.Add(time.Hour),
+	}
+	for _, job := range []*models.RecurringJob{due, later} {
+		if err := repo.CreateRecurringJob(ctx, job); err != nil {
+			t.Fatalf("CreateRecurringJob failed: %v", err)
+		}
+	}
+
+	dueJobs, err := repo.GetDueRecurringJobs(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("GetDueRecurringJobs failed: %v", err)
+	}
+	if len(dueJobs) != 1 || dueJobs[0].ID != due.ID {
+		t.Fatalf("expected only the due job, got %d jobs", len(dueJobs))
+	}
+	if got := dueJobs[0].JobSpec; got.DockerImage != "alpine:latest" || len(got.Command) != 1 || got.Command[0] != "date" {
+		t.Errorf("job spec did not round-trip: %+v", got)
+	}
+
+	next := now.Add(time.Hour)
+	claimed, err := repo.ClaimRecurringRun(ctx, due.ID, due.NextRunAt, next, now)
+	if err != nil || !claimed {
+		t.Fatalf("expected first claim to succeed, got claimed=%v err=%v", claimed, err)
+	}
+
+	// A second replica working from the same due time loses the claim
+	claimed, err = repo.ClaimRecurringRun(ctx, due.ID, due.NextRunAt, next, now)
+	if err != nil || claimed {
+		t.Errorf("expected second claim to fail, got claimed=%v err=%v", claimed, err)
+	}
+
+	jobID := uuid.New()
+	if err := repo.RecordRecurringRun(ctx, due.ID, &jobID, nil); err != nil {
+		t.Fatalf("RecordRecurringRun failed: %v", err)
+	}
+
+	jobs, err := repo.ListRecurringJobs(ctx, "user-1", 10)
+	if err != nil {
+		t.Fatalf("ListRecurringJobs failed: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job for user-1, got %d", len(jobs))
+	}
+	if !jobs[0].NextRunAt.Equal(next) || jobs[0].LastJobID == nil || *jobs[0].LastJobID != jobID {
+		t.Errorf("expected next run %v and last job %s, got %v and %v", next, jobID, jobs[0].NextRunAt, jobs[0].LastJobID)
+	}
+}
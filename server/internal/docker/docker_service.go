@@ -1,21 +1,51 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// ErrTimeoutExceeded is returned when the job's context ends before its container does.
+// The container has been stopped by then, so it doesn't keep running in Docker.
+var ErrTimeoutExceeded = errors.New("timeout exceeded")
+
+// ErrCancelled is returned when the job's context is cancelled before its container
+// finishes; the container has been stopped.
+var ErrCancelled = errors.New("context cancelled while waiting for container")
+
+// stopGracePeriod is how long a timed-out container gets to exit before it is killed
+const stopGracePeriod = 5 * time.Second
+
 // Service handles Docker container operations
 type Service struct {
-	client *client.Client
+	client    client.APIClient
+	defaults  ResourceLimits // Limits applied when a job doesn't request its own
+	maxLimits ResourceLimits // Upper bound for per-job overrides (zero means unbounded)
+
+	registryAuth *RegistryAuth    // Optional: credentials for pulls from a private registry
+	volumes      *VolumeAllowlist // Optional: host paths and volumes jobs may mount
+
+	maxOutputBytes int64 // Output kept per container, stdout and stderr together (zero means unbounded)
+
+	networkMode       string // Network of jobs without network access ("" = Docker's default)
+	networkAccessMode string // Network of jobs submitted with network access ("" refuses them)
+}
+
+// ResourceLimits holds the memory and CPU limits applied to a container
+type ResourceLimits struct {
+	MemoryBytes int64 // Memory limit in bytes (swap is disabled)
+	CPUQuota    int64 // CPU quota in microseconds per 100ms period (100000 = one CPU)
 }
 
 // ContainerResult holds the output and metadata from container execution
@@ -24,17 +54,35 @@ type ContainerResult struct {
 	ExitCode  int
 	Duration  int // in seconds
 	StartedAt time.Time
+	OOMKilled bool           // Container was killed for exceeding its memory limit
+	Truncated bool           // Output went over the service's cap and was cut short
+	Usage     *ResourceUsage // Peak memory and CPU time; nil when no stats sample was taken
 	Error     error
 }
 
 // NewDockerService creates a new Docker service instance
-func NewDockerService() (*Service, error) {
+// defaults are applied to every container; per-job overrides are clamped to maxLimits.
+func NewDockerService(defaults, maxLimits ResourceLimits) (*Service, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
-	return &Service{client: cli}, nil
+	return &Service{
+		client:    cli,
+		defaults:  defaults,
+		maxLimits: maxLimits,
+
+		networkMode:       DefaultNetworkMode,
+		networkAccessMode: DefaultNetworkAccessMode,
+	}, nil
+}
+
+// SetMaxOutputBytes caps how much of a container's output is kept in memory and returned.
+// Output past the cap is read and discarded, and a marker noting how much was left out
+// ends the result's Output. Zero keeps everything.
+func (s *Service) SetMaxOutputBytes(n int64) {
+	s.maxOutputBytes = n
 }
 
 // Close closes the Docker client connection
@@ -63,8 +111,13 @@ func (s *Service) PullImage(ctx context.Context, imageName string) error {
 		return nil
 	}
 
+	registryAuth, err := s.registryAuthFor(imageName)
+	if err != nil {
+		return err
+	}
+
 	// Pull the image
-	reader, err := s.client.ImagePull(ctx, imageName, image.PullOptions{})
+	reader, err := s.client.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: registryAuth})
 	if err != nil {
 		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
@@ -80,114 +133,428 @@ func (s *Service) PullImage(ctx context.Context, imageName string) error {
 }
 
 // RunContainer runs a Docker container and captures its output
-// This is the main function that executes user code
-func (s *Service) RunContainer(ctx context.Context, imageName string, command []string) (*ContainerResult, error) {
+// This is the main function that executes user code. volumes must pass the service's
+// allowlist (see SetVolumeAllowlist); networkAccess picks the network (see SetNetworkModes).
+// A non-empty entrypoint replaces the image's ENTRYPOINT, with command as its arguments.
+func (s *Service) RunContainer(ctx context.Context, imageName string, command, entrypoint []string, limits *ResourceLimits, volumes []models.VolumeMount, networkAccess bool) (*ContainerResult, error) {
 	result := &ContainerResult{
 		StartedAt: time.Now(),
 	}
 
-	// Pull image if needed
-	if err := s.PullImage(ctx, imageName); err != nil {
+	containerID, err := s.startContainer(ctx, imageName, command, entrypoint, limits, volumes, networkAccess)
+	if containerID != "" {
+		// Ensure cleanup
+		defer s.removeContainer(containerID)
+	}
+	if err != nil {
+		result.Error = err
+		return result, result.Error
+	}
+
+	// Sample resource usage until the container is done
+	stopStats := s.sampleStats(ctx, containerID)
+	defer func() { result.Usage = stopStats() }()
+
+	// Wait for container to finish
+	if err := s.waitContainer(ctx, containerID, result); err != nil {
+		return result, err
+	}
+
+	// Capture logs
+	logOptions := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: false,
+		Follow:     false,
+	}
+
+	logs, err := s.client.ContainerLogs(ctx, containerID, logOptions)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to get container logs: %w", err)
+		return result, result.Error
+	}
+	defer logs.Close()
+
+	// Read stdout and stderr, keeping no more than the output cap
+	var stdout, stderr strings.Builder
+	limit := newOutputLimit(s.maxOutputBytes)
+	_, err = stdcopy.StdCopy(limit.writer(&stdout), limit.writer(&stderr), logs)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to read container logs: %w", err)
+		return result, result.Error
+	}
+
+	// Combine stdout and stderr
+	result.Output = limit.finish(combineOutput(stdout.String(), stderr.String()), result)
+
+	// Calculate duration
+	result.Duration = int(time.Since(result.StartedAt).Seconds())
+
+	return result, nil
+}
+
+// RunContainerStreaming runs a Docker container like RunContainer, but follows the
+// container's logs while it runs and sends each output line to lines as it is produced.
+// The output is still collected on the result, up to the output cap; every line is sent
+// either way. The lines channel is closed when the function returns.
+func (s *Service) RunContainerStreaming(ctx context.Context, imageName string, command, entrypoint []string, limits *ResourceLimits, volumes []models.VolumeMount, networkAccess bool, lines chan<- string) (*ContainerResult, error) {
+	defer close(lines)
+
+	result := &ContainerResult{
+		StartedAt: time.Now(),
+	}
+
+	containerID, err := s.startContainer(ctx, imageName, command, entrypoint, limits, volumes, networkAccess)
+	if containerID != "" {
+		// Ensure cleanup
+		defer s.removeContainer(containerID)
+	}
+	if err != nil {
 		result.Error = err
+		return result, result.Error
+	}
+
+	// Sample resource usage until the container is done
+	stopStats := s.sampleStats(ctx, containerID)
+	defer func() { result.Usage = stopStats() }()
+
+	// Follow logs while the container runs
+	logOptions := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: false,
+		Follow:     true,
+	}
+
+	logs, err := s.client.ContainerLogs(ctx, containerID, logOptions)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to follow container logs: %w", err)
+		return result, result.Error
+	}
+
+	limit := newOutputLimit(s.maxOutputBytes)
+	stdout := newLineWriter(ctx, lines, limit)
+	stderr := newLineWriter(ctx, lines, limit)
+	copyDone := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, logs)
+		copyDone <- err
+	}()
+
+	// Stop following before lines is closed, on every return path
+	copyFinished := false
+	defer func() {
+		logs.Close()
+		if !copyFinished {
+			<-copyDone
+		}
+	}()
+
+	// Wait for container to finish
+	if err := s.waitContainer(ctx, containerID, result); err != nil {
 		return result, err
 	}
 
+	// The log stream ends once the container exits; drain what is left
+	select {
+	case err := <-copyDone:
+		copyFinished = true
+		if err != nil {
+			result.Error = fmt.Errorf("failed to read container logs: %w", err)
+			return result, result.Error
+		}
+	case <-ctx.Done():
+		result.Error = fmt.Errorf("context cancelled while reading container logs")
+		return result, result.Error
+	}
+
+	stdout.Flush()
+	stderr.Flush()
+
+	// Combine stdout and stderr
+	result.Output = limit.finish(combineOutput(stdout.String(), stderr.String()), result)
+
+	// Calculate duration
+	result.Duration = int(time.Since(result.StartedAt).Seconds())
+
+	return result, nil
+}
+
+// startContainer pulls the image, then creates and starts the container.
+// The container ID is returned whenever a container was created, even on error,
+// so the caller can remove it.
+func (s *Service) startContainer(ctx context.Context, imageName string, command, entrypoint []string, limits *ResourceLimits, volumes []models.VolumeMount, networkAccess bool) (string, error) {
+	// Refuse disallowed mounts and network access before pulling anything
+	mounts, err := s.volumes.Resolve(volumes)
+	if err != nil {
+		return "", err
+	}
+	networkMode, err := s.networkModeFor(networkAccess)
+	if err != nil {
+		return "", err
+	}
+
+	// Pull image if needed
+	if err := s.PullImage(ctx, imageName); err != nil {
+		return "", err
+	}
+
 	// Create container configuration
 	containerConfig := &container.Config{
 		Image:        imageName,
 		Cmd:          command,
+		Entrypoint:   entrypoint, // Empty keeps the image's own entrypoint
 		AttachStdout: true,
 		AttachStderr: true,
 		Tty:          false,
 	}
 
 	// Host configuration (resource limits, etc.)
-	hostConfig := &container.HostConfig{
+	hostConfig := s.buildHostConfig(limits)
+	hostConfig.Mounts = mounts
+	hostConfig.NetworkMode = container.NetworkMode(networkMode)
+
+	// Create container
+	resp, err := s.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	// Start container
+	if err := s.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return resp.ID, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+// buildHostConfig creates the container host configuration.
+// The configured defaults apply unless the job overrides them; overrides are
+// clamped to the configured maximum so a job can't request unbounded resources.
+func (s *Service) buildHostConfig(override *ResourceLimits) *container.HostConfig {
+	limits := s.ResolveLimits(override)
+
+	return &container.HostConfig{
 		AutoRemove: false, // We'll remove manually after capturing logs
 		Resources: container.Resources{
 			// Add resource limits to prevent abuse
-			Memory:     512 * 1024 * 1024, // 512MB
-			MemorySwap: 512 * 1024 * 1024, // No swap
-			CPUQuota:   50000,             // 50% of one CPU
+			Memory:     limits.MemoryBytes,
+			MemorySwap: limits.MemoryBytes, // No swap
+			CPUQuota:   limits.CPUQuota,
 		},
 	}
+}
 
-	// Create container
-	resp, err := s.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
-	if err != nil {
-		result.Error = fmt.Errorf("failed to create container: %w", err)
-		return result, result.Error
-	}
-	containerID := resp.ID
+// ResolveLimits layers a per-job override on top of the configured defaults
+func (s *Service) ResolveLimits(override *ResourceLimits) ResourceLimits {
+	return ClampLimits(s.defaults, s.maxLimits, override)
+}
 
-	// Ensure cleanup
-	defer func() {
-		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		s.client.ContainerRemove(cleanupCtx, containerID, container.RemoveOptions{
-			Force: true,
-		})
-	}()
+// ClampLimits layers a per-job override on top of defaults and caps the result at
+// maxLimits, the way a Service configured with them resolves a container's limits
+func ClampLimits(defaults, maxLimits ResourceLimits, override *ResourceLimits) ResourceLimits {
+	limits := defaults
 
-	// Start container
-	if err := s.client.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
-		result.Error = fmt.Errorf("failed to start container: %w", err)
-		return result, result.Error
+	if override != nil {
+		if override.MemoryBytes > 0 {
+			limits.MemoryBytes = override.MemoryBytes
+		}
+		if override.CPUQuota > 0 {
+			limits.CPUQuota = override.CPUQuota
+		}
 	}
 
-	// Wait for container to finish
+	if maxLimits.MemoryBytes > 0 && limits.MemoryBytes > maxLimits.MemoryBytes {
+		limits.MemoryBytes = maxLimits.MemoryBytes
+	}
+	if maxLimits.CPUQuota > 0 && limits.CPUQuota > maxLimits.CPUQuota {
+		limits.CPUQuota = maxLimits.CPUQuota
+	}
+
+	return limits
+}
+
+// waitContainer blocks until the container stops and records its exit code on result
+func (s *Service) waitContainer(ctx context.Context, containerID string, result *ContainerResult) error {
 	statusCh, errCh := s.client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
+		if ctx.Err() != nil {
+			// The wait was cut short by the job's context, not by the container
+			return s.abortContainer(ctx, containerID, result)
+		}
 		if err != nil {
 			result.Error = fmt.Errorf("error waiting for container: %w", err)
-			return result, result.Error
+			return result.Error
 		}
 	case status := <-statusCh:
 		result.ExitCode = int(status.StatusCode)
 	case <-ctx.Done():
-		result.Error = fmt.Errorf("context cancelled while waiting for container")
-		return result, result.Error
+		return s.abortContainer(ctx, containerID, result)
 	}
 
-	// Capture logs
-	logOptions := container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Timestamps: false,
-		Follow:     false,
+	// Check whether the container hit its memory limit
+	if result.ExitCode != 0 {
+		if info, err := s.client.ContainerInspect(ctx, containerID); err == nil && info.State != nil {
+			result.OOMKilled = info.State.OOMKilled
+		}
 	}
+	return nil
+}
 
-	logs, err := s.client.ContainerLogs(ctx, containerID, logOptions)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to get container logs: %w", err)
-		return result, result.Error
+// abortContainer stops a container whose job context ended while it was still running.
+// Cancelling the context only stops us waiting; Docker would keep the container running.
+func (s *Service) abortContainer(ctx context.Context, containerID string, result *ContainerResult) error {
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopGracePeriod+10*time.Second)
+	defer cancel()
+
+	stopErr := s.StopContainer(stopCtx, containerID)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Error = ErrTimeoutExceeded
+	} else {
+		result.Error = ErrCancelled
 	}
-	defer logs.Close()
+	if stopErr != nil {
+		result.Error = fmt.Errorf("%w (%v)", result.Error, stopErr)
+	}
+	result.Duration = int(time.Since(result.StartedAt).Seconds())
+	return result.Error
+}
 
-	// Read stdout and stderr
-	var stdout, stderr strings.Builder
-	_, err = stdcopy.StdCopy(&stdout, &stderr, logs)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to read container logs: %w", err)
-		return result, result.Error
+// StopContainer stops a running container, killing it if it hasn't exited after a short grace period
+func (s *Service) StopContainer(ctx context.Context, containerID string) error {
+	timeout := int(stopGracePeriod.Seconds())
+	if err := s.client.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", containerID, err)
 	}
+	return nil
+}
 
-	// Combine stdout and stderr
-	if stdout.Len() > 0 {
-		result.Output = stdout.String()
+// removeContainer force-removes a container using a fresh context
+func (s *Service) removeContainer(containerID string) {
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.client.ContainerRemove(cleanupCtx, containerID, container.RemoveOptions{
+		Force: true,
+	})
+}
+
+// combineOutput joins stdout and stderr into a single output string
+func combineOutput(stdout, stderr string) string {
+	output := stdout
+	if stderr != "" {
+		if output != "" {
+			output += "\n--- STDERR ---\n"
+		}
+		output += stderr
+	}
+	return output
+}
+
+// outputLimit is the output budget shared by a container's stdout and stderr. Writes
+// past it are counted and dropped, so memory stays bounded however much a container prints.
+type outputLimit struct {
+	remaining int64 // Bytes that may still be kept; negative means unbounded
+	omitted   int64
+}
+
+func newOutputLimit(maxBytes int64) *outputLimit {
+	if maxBytes <= 0 {
+		return &outputLimit{remaining: -1}
 	}
-	if stderr.Len() > 0 {
-		if result.Output != "" {
-			result.Output += "\n--- STDERR ---\n"
+	return &outputLimit{remaining: maxBytes}
+}
+
+// keep returns the part of p that fits in the budget and counts the rest as omitted
+func (l *outputLimit) keep(p []byte) []byte {
+	if l.remaining < 0 {
+		return p
+	}
+	if int64(len(p)) > l.remaining {
+		l.omitted += int64(len(p)) - l.remaining
+		p = p[:l.remaining]
+	}
+	l.remaining -= int64(len(p))
+	return p
+}
+
+// writer returns an io.Writer keeping only what fits in the budget in w
+func (l *outputLimit) writer(w io.Writer) io.Writer {
+	return limitedWriter{w: w, limit: l}
+}
+
+// finish marks output as truncated on result when anything was left out
+func (l *outputLimit) finish(output string, result *ContainerResult) string {
+	if l.omitted == 0 {
+		return output
+	}
+	result.Truncated = true
+	return output + fmt.Sprintf("\n... [output truncated, %d bytes omitted]", l.omitted)
+}
+
+// limitedWriter writes to w what fits in an output budget and drops the rest
+type limitedWriter struct {
+	w     io.Writer
+	limit *outputLimit
+}
+
+// Write implements io.Writer; dropped bytes are reported as written so copying carries on
+func (w limitedWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(w.limit.keep(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// lineWriter collects written output, up to its limit, and forwards each complete line to a channel
+type lineWriter struct {
+	ctx     context.Context
+	lines   chan<- string
+	limit   *outputLimit
+	output  strings.Builder
+	pending []byte
+}
+
+func newLineWriter(ctx context.Context, lines chan<- string, limit *outputLimit) *lineWriter {
+	return &lineWriter{ctx: ctx, lines: lines, limit: limit}
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(w.limit.keep(p))
+	w.pending = append(w.pending, p...)
+
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
 		}
-		result.Output += stderr.String()
+		w.send(string(w.pending[:idx]))
+		w.pending = w.pending[idx+1:]
 	}
 
-	// Calculate duration
-	result.Duration = int(time.Since(result.StartedAt).Seconds())
+	return len(p), nil
+}
 
-	return result, nil
+// Flush sends any trailing output that did not end with a newline
+func (w *lineWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.send(string(w.pending))
+		w.pending = nil
+	}
+}
+
+// String returns everything written so far
+func (w *lineWriter) String() string {
+	return w.output.String()
+}
+
+func (w *lineWriter) send(line string) {
+	select {
+	case w.lines <- line:
+	case <-w.ctx.Done():
+	}
 }
 
 // ListRunningContainers returns the count of currently running containers
@@ -0,0 +1,48 @@
+package docker
+
+import "testing"
+
+func TestBuildHostConfig_UsesConfiguredDefaults(t *testing.T) {
+	s := &Service{
+		defaults:  ResourceLimits{MemoryBytes: 256 * 1024 * 1024, CPUQuota: 25000},
+		maxLimits: ResourceLimits{MemoryBytes: 1024 * 1024 * 1024, CPUQuota: 100000},
+	}
+
+	hostConfig := s.buildHostConfig(nil)
+
+	if hostConfig.Resources.Memory != 256*1024*1024 {
+		t.Errorf("expected configured memory limit 256MB, got %d", hostConfig.Resources.Memory)
+	}
+	if hostConfig.Resources.MemorySwap != hostConfig.Resources.Memory {
+		t.Errorf("expected swap to equal memory limit, got %d", hostConfig.Resources.MemorySwap)
+	}
+	if hostConfig.Resources.CPUQuota != 25000 {
+		t.Errorf("expected configured CPU quota 25000, got %d", hostConfig.Resources.CPUQuota)
+	}
+}
+
+func TestResolveLimits_OverridesAreBounded(t *testing.T) {
+	s := &Service{
+		defaults:  ResourceLimits{MemoryBytes: 512 * 1024 * 1024, CPUQuota: 50000},
+		maxLimits: ResourceLimits{MemoryBytes: 1024 * 1024 * 1024, CPUQuota: 100000},
+	}
+
+	tests := []struct {
+		name     string
+		override *ResourceLimits
+		want     ResourceLimits
+	}{
+		{"no override", nil, ResourceLimits{MemoryBytes: 512 * 1024 * 1024, CPUQuota: 50000}},
+		{"memory only", &ResourceLimits{MemoryBytes: 128 * 1024 * 1024}, ResourceLimits{MemoryBytes: 128 * 1024 * 1024, CPUQuota: 50000}},
+		{"cpu only", &ResourceLimits{CPUQuota: 80000}, ResourceLimits{MemoryBytes: 512 * 1024 * 1024, CPUQuota: 80000}},
+		{"clamped to max", &ResourceLimits{MemoryBytes: 8 * 1024 * 1024 * 1024, CPUQuota: 400000}, ResourceLimits{MemoryBytes: 1024 * 1024 * 1024, CPUQuota: 100000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.ResolveLimits(tt.override); got != tt.want {
+				t.Errorf("ResolveLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// configRecordingDockerClient runs containers that exit at once, remembering the
+// config each one was created with
+type configRecordingDockerClient struct {
+	*networkRecordingDockerClient
+	configs []*container.Config
+}
+
+func (f *configRecordingDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.configs = append(f.configs, config)
+	return f.networkRecordingDockerClient.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func TestRunContainer_Entrypoint(t *testing.T) {
+	fake := &configRecordingDockerClient{networkRecordingDockerClient: &networkRecordingDockerClient{sleepingDockerClient: newSleepingDockerClient()}}
+	s := &Service{client: fake}
+
+	if _, err := s.RunContainer(context.Background(), "alpine/git", []string{"hello"}, []string{"echo"}, nil, nil, false); err != nil {
+		t.Fatalf("RunContainer returned error: %v", err)
+	}
+	if _, err := s.RunContainer(context.Background(), "alpine/git", []string{"--version"}, nil, nil, nil, false); err != nil {
+		t.Fatalf("RunContainer returned error: %v", err)
+	}
+
+	if len(fake.configs) != 2 {
+		t.Fatalf("created %d containers, want 2", len(fake.configs))
+	}
+	if got := fake.configs[0]; !slices.Equal(got.Entrypoint, []string{"echo"}) || !slices.Equal(got.Cmd, []string{"hello"}) {
+		t.Errorf("overridden container: entrypoint %q, cmd %q, want [echo] [hello]", got.Entrypoint, got.Cmd)
+	}
+	if got := fake.configs[1]; len(got.Entrypoint) != 0 {
+		t.Errorf("expected the image's entrypoint to be kept without an override, got %q", got.Entrypoint)
+	}
+}
+
+// TestRunContainer_EntrypointOverride runs a real container, so it needs a Docker daemon;
+// set KARBOS_DOCKER_TESTS=1 to run it
+func TestRunContainer_EntrypointOverride(t *testing.T) {
+	if os.Getenv("KARBOS_DOCKER_TESTS") == "" {
+		t.Skip("set KARBOS_DOCKER_TESTS=1 to run containers on the local Docker daemon")
+	}
+
+	s, err := NewDockerService(ResourceLimits{}, ResourceLimits{})
+	if err != nil {
+		t.Fatalf("NewDockerService returned error: %v", err)
+	}
+	defer s.Close()
+
+	// alpine/git's own entrypoint is git, which would reject "overridden" as a subcommand
+	result, err := s.RunContainer(context.Background(), "alpine/git:latest", []string{"overridden"}, []string{"echo"}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("RunContainer returned error: %v", err)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(result.Output) != "overridden" {
+		t.Errorf("exit code %d, output %q; want 0 and the echoed argument", result.ExitCode, result.Output)
+	}
+}
@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+var (
+	// ErrInvalidImage is returned for an image name that isn't a valid Docker reference
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrImageNotAllowed is returned for an image the image policy refuses
+	ErrImageNotAllowed = errors.New("image not allowed")
+)
+
+// ImagePolicy restricts which images jobs may run. Each entry is either a registry
+// host ("ghcr.io", "docker.io" for Docker Hub, "registry.internal:5000") or a glob over
+// the full repository name, where * doesn't cross "/" ("docker.io/library/*",
+// "ghcr.io/acme/*"). Denied images are refused even when allowed; with an allowlist,
+// only images matching it are accepted. A nil policy accepts every valid image.
+type ImagePolicy struct {
+	allow []string
+	deny  []string
+}
+
+// ParseImagePolicy reads comma-separated allow and deny lists, as in
+// DOCKER_IMAGE_ALLOWLIST and DOCKER_IMAGE_DENYLIST. Two empty lists return nil.
+func ParseImagePolicy(allow, deny string) (*ImagePolicy, error) {
+	p := &ImagePolicy{}
+	var err error
+	if p.allow, err = parseImagePatterns(allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = parseImagePatterns(deny); err != nil {
+		return nil, err
+	}
+
+	if len(p.allow) == 0 && len(p.deny) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// parseImagePatterns splits and checks one list of registries and repository globs
+func parseImagePatterns(entries string) ([]string, error) {
+	var patterns []string
+	for _, entry := range strings.Split(entries, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			patterns = append(patterns, normalizeRegistryHost(entry))
+			continue
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			return nil, fmt.Errorf("image pattern %q: %w", entry, err)
+		}
+		// Repository names start with the canonical host, e.g. docker.io for Docker Hub
+		host, repository, _ := strings.Cut(entry, "/")
+		patterns = append(patterns, normalizeRegistryHost(host)+"/"+repository)
+	}
+	return patterns, nil
+}
+
+// Check validates an image reference and applies the policy to it. The reference is
+// checked even on a nil policy, so malformed names are refused before they are queued.
+func (p *ImagePolicy) Check(image string) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a valid image reference: %v", ErrInvalidImage, image, err)
+	}
+	if p == nil {
+		return nil
+	}
+
+	registry := normalizeRegistryHost(reference.Domain(named))
+	if matchImage(p.deny, registry, named.Name()) {
+		return fmt.Errorf("%w: %s is on DOCKER_IMAGE_DENYLIST", ErrImageNotAllowed, reference.FamiliarString(named))
+	}
+	if len(p.allow) > 0 && !matchImage(p.allow, registry, named.Name()) {
+		return fmt.Errorf("%w: %s is not on DOCKER_IMAGE_ALLOWLIST", ErrImageNotAllowed, reference.FamiliarString(named))
+	}
+	return nil
+}
+
+// matchImage reports whether any pattern names the image's registry or matches its repository
+func matchImage(patterns []string, registry, repository string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if pattern == registry {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, repository); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestImagePolicy_CheckRejectsMalformedReferences(t *testing.T) {
+	var p *ImagePolicy // No policy still validates the reference
+
+	for _, image := range []string{
+		"alpine",
+		"alpine:3.19",
+		"python:3.12-slim",
+		"library/ubuntu:22.04",
+		"ghcr.io/acme/trainer:v1.2.0",
+		"registry.internal:5000/team/job",
+		"alpine@sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b",
+	} {
+		if err := p.Check(image); err != nil {
+			t.Errorf("%s: expected a valid reference, got %v", image, err)
+		}
+	}
+
+	for _, image := range []string{
+		"",
+		"Alpine",            // Repository names are lowercase
+		"alpine:",           // Empty tag
+		"alpine:3.19:extra", // Two tags
+		"alpine:bad tag",
+		"alpine:-leading-dash",
+		"ghcr.io/acme/trainer@sha256:short",
+		"../etc/passwd",
+	} {
+		if err := p.Check(image); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("%q: expected ErrInvalidImage, got %v", image, err)
+		}
+	}
+}
+
+func TestImagePolicy_Check(t *testing.T) {
+	p, err := ParseImagePolicy("docker.io/library/*, ghcr.io", "ghcr.io/untrusted/*, registry-1.docker.io/library/busybox")
+	if err != nil {
+		t.Fatalf("ParseImagePolicy returned error: %v", err)
+	}
+
+	tests := []struct {
+		image string
+		want  error
+	}{
+		{"alpine:3.19", nil},                            // Official Docker Hub image
+		{"docker.io/library/python:3.12", nil},          // Same, written in full
+		{"ghcr.io/acme/trainer:v1", nil},                // Allowed registry
+		{"busybox", ErrImageNotAllowed},                 // Denied by repository, via a Docker Hub alias
+		{"ghcr.io/untrusted/miner", ErrImageNotAllowed}, // Denied pattern within an allowed registry
+		{"someuser/tool:latest", ErrImageNotAllowed},    // Docker Hub, but not an official image
+		{"quay.io/acme/job", ErrImageNotAllowed},        // Registry not on the allowlist
+	}
+	for _, tt := range tests {
+		if err := p.Check(tt.image); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.image, err, tt.want)
+		}
+	}
+}
+
+func TestImagePolicy_DenylistOnly(t *testing.T) {
+	p, err := ParseImagePolicy("", "quay.io")
+	if err != nil {
+		t.Fatalf("ParseImagePolicy returned error: %v", err)
+	}
+
+	if err := p.Check("quay.io/acme/job:latest"); !errors.Is(err, ErrImageNotAllowed) {
+		t.Errorf("expected an image from a denied registry to be refused, got %v", err)
+	}
+	if err := p.Check("ghcr.io/acme/job:latest"); err != nil {
+		t.Errorf("expected other registries to be allowed, got %v", err)
+	}
+}
+
+func TestParseImagePolicy(t *testing.T) {
+	if p, err := ParseImagePolicy(" , ", ""); p != nil || err != nil {
+		t.Errorf("expected empty lists to give no policy, got %+v (err %v)", p, err)
+	}
+	if _, err := ParseImagePolicy("ghcr.io/acme/[", ""); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
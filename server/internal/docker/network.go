@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNetworkNotAllowed is returned for a job that asks for network access where none is configured
+var ErrNetworkNotAllowed = errors.New("network access not allowed")
+
+// Default container networks: jobs are isolated unless they ask for network access
+const (
+	DefaultNetworkMode       = "none"
+	DefaultNetworkAccessMode = "bridge"
+)
+
+// SetNetworkModes sets the Docker network jobs run on: isolated for ordinary jobs ("none"
+// cuts them off entirely) and access for jobs submitted with network access, such as
+// "bridge" or a user-defined network with restricted egress. An empty access mode refuses
+// those jobs. Host networking and joining another container's network are not allowed.
+func (s *Service) SetNetworkModes(isolated, access string) error {
+	for _, mode := range []string{isolated, access} {
+		if mode == "host" || strings.HasPrefix(mode, "container:") {
+			return fmt.Errorf("network mode %q would share a network namespace with the host or another container", mode)
+		}
+	}
+	s.networkMode = isolated
+	s.networkAccessMode = access
+	return nil
+}
+
+// networkModeFor returns the network a job's container joins
+func (s *Service) networkModeFor(networkAccess bool) (string, error) {
+	if !networkAccess {
+		return s.networkMode, nil
+	}
+	if s.networkAccessMode == "" {
+		return "", fmt.Errorf("%w: DOCKER_NETWORK_ACCESS_MODE is empty", ErrNetworkNotAllowed)
+	}
+	return s.networkAccessMode, nil
+}
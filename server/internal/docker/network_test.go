@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// networkRecordingDockerClient runs containers that exit at once, remembering the
+// network each one was created on
+type networkRecordingDockerClient struct {
+	*sleepingDockerClient
+	networkModes []container.NetworkMode
+}
+
+func (f *networkRecordingDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.networkModes = append(f.networkModes, hostConfig.NetworkMode)
+	return container.CreateResponse{ID: "recorded"}, nil
+}
+
+func (f *networkRecordingDockerClient) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	statusCh := make(chan container.WaitResponse, 1)
+	statusCh <- container.WaitResponse{StatusCode: 0}
+	return statusCh, make(chan error)
+}
+
+func TestRunContainer_NetworkModes(t *testing.T) {
+	fake := &networkRecordingDockerClient{sleepingDockerClient: newSleepingDockerClient()}
+	s := &Service{client: fake}
+	if err := s.SetNetworkModes("none", "karbos-egress"); err != nil {
+		t.Fatalf("SetNetworkModes returned error: %v", err)
+	}
+
+	for _, networkAccess := range []bool{false, true} {
+		if _, err := s.RunContainer(context.Background(), "alpine", []string{"true"}, nil, nil, nil, networkAccess); err != nil {
+			t.Fatalf("RunContainer(networkAccess=%v) returned error: %v", networkAccess, err)
+		}
+	}
+	if len(fake.networkModes) != 2 || fake.networkModes[0] != "none" || fake.networkModes[1] != "karbos-egress" {
+		t.Errorf("containers created on %v, want [none karbos-egress]", fake.networkModes)
+	}
+
+	// Without an access mode, jobs asking for the network are refused before a container exists
+	if err := s.SetNetworkModes("none", ""); err != nil {
+		t.Fatalf("SetNetworkModes returned error: %v", err)
+	}
+	if _, err := s.RunContainer(context.Background(), "alpine", []string{"true"}, nil, nil, nil, true); !errors.Is(err, ErrNetworkNotAllowed) {
+		t.Errorf("expected ErrNetworkNotAllowed, got %v", err)
+	}
+	if len(fake.networkModes) != 2 {
+		t.Errorf("expected no container to be created for a refused job")
+	}
+}
+
+func TestSetNetworkModes_RefusesSharedNamespaces(t *testing.T) {
+	s := &Service{}
+	for _, modes := range [][2]string{{"host", "bridge"}, {"none", "host"}, {"none", "container:db"}} {
+		if err := s.SetNetworkModes(modes[0], modes[1]); err == nil {
+			t.Errorf("%v: expected an error", modes)
+		}
+	}
+}
+
+// TestRunContainer_NetworkIsolation runs real containers, so it needs a Docker daemon with
+// internet access; set KARBOS_DOCKER_TESTS=1 to run it
+func TestRunContainer_NetworkIsolation(t *testing.T) {
+	if os.Getenv("KARBOS_DOCKER_TESTS") == "" {
+		t.Skip("set KARBOS_DOCKER_TESTS=1 to run containers on the local Docker daemon")
+	}
+
+	s, err := NewDockerService(ResourceLimits{}, ResourceLimits{})
+	if err != nil {
+		t.Fatalf("NewDockerService returned error: %v", err)
+	}
+	defer s.Close()
+
+	fetch := []string{"wget", "-q", "-T", "10", "-O", "/dev/null", "http://example.com"}
+
+	isolated, err := s.RunContainer(context.Background(), "alpine:latest", fetch, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("isolated run returned error: %v", err)
+	}
+	if isolated.ExitCode == 0 {
+		t.Errorf("expected a container without network access to fail to reach example.com, output: %s", isolated.Output)
+	}
+
+	networked, err := s.RunContainer(context.Background(), "alpine:latest", fetch, nil, nil, nil, true)
+	if err != nil {
+		t.Fatalf("networked run returned error: %v", err)
+	}
+	if networked.ExitCode != 0 {
+		t.Errorf("expected a container with network access to reach example.com, exit code %d, output: %s", networked.ExitCode, networked.Output)
+	}
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// chattyDockerClient runs a container that exits at once after printing stdout and stderr
+type chattyDockerClient struct {
+	*allocatingDockerClient
+	stdout, stderr string
+}
+
+func (f *chattyDockerClient) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	var logs bytes.Buffer
+	stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte(f.stdout))
+	stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte(f.stderr))
+	return io.NopCloser(&logs), nil
+}
+
+func newChattyService(stdout, stderr string, maxOutputBytes int64) *Service {
+	fake := &chattyDockerClient{
+		allocatingDockerClient: &allocatingDockerClient{sleepingDockerClient: newSleepingDockerClient()},
+		stdout:                 stdout,
+		stderr:                 stderr,
+	}
+	s := &Service{client: fake}
+	s.SetMaxOutputBytes(maxOutputBytes)
+	return s
+}
+
+func TestRunContainer_TruncatesOutputOverCap(t *testing.T) {
+	stdout := strings.Repeat("a", 60)
+	stderr := strings.Repeat("b", 60)
+
+	tests := []struct {
+		name          string
+		maxBytes      int64
+		wantOutput    string
+		wantTruncated bool
+	}{
+		{"under cap", 1000, stdout + "\n--- STDERR ---\n" + stderr, false},
+		{"unbounded", 0, stdout + "\n--- STDERR ---\n" + stderr, false},
+		{"stdout fills cap", 50, strings.Repeat("a", 50) + "\n... [output truncated, 70 bytes omitted]", true},
+		{"cap shared with stderr", 100, stdout + "\n--- STDERR ---\n" + strings.Repeat("b", 40) + "\n... [output truncated, 20 bytes omitted]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newChattyService(stdout, stderr, tt.maxBytes)
+
+			result, err := s.RunContainer(context.Background(), "alpine", []string{"yes"}, nil, nil, nil, false)
+			if err != nil {
+				t.Fatalf("RunContainer returned error: %v", err)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", result.Output, tt.wantOutput)
+			}
+			if result.Truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", result.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestRunContainerStreaming_TruncatesOutputButStreamsEveryLine(t *testing.T) {
+	stdout := strings.Repeat("line\n", 100)
+	s := newChattyService(stdout, "", 50)
+
+	lines := make(chan string, 200)
+	result, err := s.RunContainerStreaming(context.Background(), "alpine", []string{"yes"}, nil, nil, nil, false, lines)
+	if err != nil {
+		t.Fatalf("RunContainerStreaming returned error: %v", err)
+	}
+
+	streamed := 0
+	for range lines {
+		streamed++
+	}
+	if streamed != 100 {
+		t.Errorf("expected every line to be streamed, got %d", streamed)
+	}
+
+	want := strings.Repeat("line\n", 10) + "\n... [output truncated, 450 bytes omitted]"
+	if !result.Truncated || result.Output != want {
+		t.Errorf("expected output cut at 50 bytes, got truncated=%v output %q", result.Truncated, result.Output)
+	}
+}
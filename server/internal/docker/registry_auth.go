@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubHost is the registry domain of unqualified images like "alpine:latest"
+const dockerHubHost = "docker.io"
+
+// RegistryAuth holds credentials for a private registry. They are only sent when
+// pulling images hosted on Host, and are never logged.
+type RegistryAuth struct {
+	Host     string // Registry the credentials belong to, e.g. "ghcr.io" ("docker.io" for Docker Hub)
+	Username string
+	Password string
+	Token    string // Registry bearer token, used instead of username/password
+}
+
+// String describes the credentials without revealing them
+func (a RegistryAuth) String() string {
+	return fmt.Sprintf("registry %s (credentials redacted)", normalizeRegistryHost(a.Host))
+}
+
+// SetRegistryAuth enables authenticated pulls from auth.Host
+func (s *Service) SetRegistryAuth(auth RegistryAuth) {
+	auth.Host = normalizeRegistryHost(auth.Host)
+	s.registryAuth = &auth
+}
+
+// registryAuthFor returns the encoded credentials for pulling imageName, or ""
+// when no credentials are configured for the image's registry
+func (s *Service) registryAuthFor(imageName string) (string, error) {
+	if s.registryAuth == nil {
+		return "", nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", imageName, err)
+	}
+	if normalizeRegistryHost(reference.Domain(named)) != s.registryAuth.Host {
+		return "", nil
+	}
+
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      s.registryAuth.Username,
+		Password:      s.registryAuth.Password,
+		RegistryToken: s.registryAuth.Token,
+		ServerAddress: s.registryAuth.Host,
+	})
+	if err != nil {
+		// Don't wrap: the error could quote the credentials
+		return "", fmt.Errorf("failed to encode credentials for %s", s.registryAuth.Host)
+	}
+	return encoded, nil
+}
+
+// normalizeRegistryHost maps Docker Hub's aliases to the domain image references resolve to
+func normalizeRegistryHost(host string) string {
+	switch host {
+	case "", "index.docker.io", "registry-1.docker.io", "https://index.docker.io/v1/":
+		return dockerHubHost
+	}
+	return host
+}
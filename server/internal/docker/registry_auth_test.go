@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+// pullRecordingClient has no images locally and records every pull
+type pullRecordingClient struct {
+	client.APIClient // Unused methods panic
+
+	pulls []image.PullOptions
+}
+
+func (f *pullRecordingClient) ImageInspectWithRaw(ctx context.Context, imageName string) (image.InspectResponse, []byte, error) {
+	return image.InspectResponse{}, nil, errors.New("no such image")
+}
+
+func (f *pullRecordingClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, options)
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func TestPullImage_SendsRegistryAuthForMatchingHost(t *testing.T) {
+	fake := &pullRecordingClient{}
+	s := &Service{client: fake}
+	s.SetRegistryAuth(RegistryAuth{Host: "ghcr.io", Username: "bot", Password: "hunter2"})
+
+	if err := s.PullImage(context.Background(), "ghcr.io/acme/private-job:1.0"); err != nil {
+		t.Fatalf("PullImage returned error: %v", err)
+	}
+	if len(fake.pulls) != 1 {
+		t.Fatalf("expected one pull, got %d", len(fake.pulls))
+	}
+
+	auth, err := registry.DecodeAuthConfig(fake.pulls[0].RegistryAuth)
+	if err != nil {
+		t.Fatalf("failed to decode RegistryAuth %q: %v", fake.pulls[0].RegistryAuth, err)
+	}
+	if auth.Username != "bot" || auth.Password != "hunter2" || auth.ServerAddress != "ghcr.io" {
+		t.Errorf("unexpected auth config %+v", auth)
+	}
+}
+
+func TestPullImage_NoRegistryAuthForOtherHosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		auth  *RegistryAuth
+		image string
+	}{
+		{"no credentials configured", nil, "ghcr.io/acme/private-job:1.0"},
+		{"docker hub image", &RegistryAuth{Host: "ghcr.io", Token: "t0ken"}, "alpine:latest"},
+		{"other registry", &RegistryAuth{Host: "ghcr.io", Token: "t0ken"}, "quay.io/acme/job"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &pullRecordingClient{}
+			s := &Service{client: fake}
+			if tt.auth != nil {
+				s.SetRegistryAuth(*tt.auth)
+			}
+
+			if err := s.PullImage(context.Background(), tt.image); err != nil {
+				t.Fatalf("PullImage returned error: %v", err)
+			}
+			if len(fake.pulls) != 1 || fake.pulls[0].RegistryAuth != "" {
+				t.Errorf("expected an anonymous pull, got %+v", fake.pulls)
+			}
+		})
+	}
+}
+
+func TestRegistryAuthFor_DockerHubAliases(t *testing.T) {
+	s := &Service{}
+	s.SetRegistryAuth(RegistryAuth{Host: "index.docker.io", Token: "t0ken"})
+
+	encoded, err := s.registryAuthFor("library/alpine:latest")
+	if err != nil {
+		t.Fatalf("registryAuthFor returned error: %v", err)
+	}
+	auth, err := registry.DecodeAuthConfig(encoded)
+	if err != nil || auth.RegistryToken != "t0ken" {
+		t.Errorf("expected the token to be sent to Docker Hub, got %+v (%v)", auth, err)
+	}
+}
+
+func TestRegistryAuth_StringRedactsCredentials(t *testing.T) {
+	auth := RegistryAuth{Host: "ghcr.io", Username: "bot", Password: "hunter2", Token: "t0ken"}
+
+	for _, formatted := range []string{auth.String(), fmt.Sprintf("%v", auth), fmt.Sprintf("%s", auth)} {
+		for _, secret := range []string{"bot", "hunter2", "t0ken"} {
+			if strings.Contains(formatted, secret) {
+				t.Errorf("expected %q to be redacted from %q", secret, formatted)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ResourceUsage is what a container consumed, as sampled from Docker's stats stream
+type ResourceUsage struct {
+	PeakMemoryBytes int64   // Highest memory use seen, excluding reclaimable page cache
+	CPUSeconds      float64 // CPU time used up to the last sample
+}
+
+// sampleStats follows a container's stats stream in the background. The returned stop
+// function ends sampling and returns the usage seen, or nil when no sample arrived,
+// as happens for containers that exit before Docker reports their first stats.
+func (s *Service) sampleStats(ctx context.Context, containerID string) (stop func() *ResourceUsage) {
+	statsCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var usage *ResourceUsage
+
+	go func() {
+		defer close(done)
+
+		stats, err := s.client.ContainerStats(statsCtx, containerID, true)
+		if err != nil {
+			return // Usage is best effort; the job runs regardless
+		}
+		defer stats.Body.Close()
+
+		decoder := json.NewDecoder(stats.Body)
+		for {
+			var sample container.StatsResponse
+			if err := decoder.Decode(&sample); err != nil {
+				return
+			}
+			usage = addSample(usage, sample)
+		}
+	}()
+
+	return func() *ResourceUsage {
+		cancel()
+		<-done
+		return usage
+	}
+}
+
+// addSample folds a stats sample into the usage seen so far. Samples of a container
+// that isn't running report nothing and are skipped.
+func addSample(usage *ResourceUsage, sample container.StatsResponse) *ResourceUsage {
+	memory := memoryUsage(sample.MemoryStats)
+	cpuNanos := sample.CPUStats.CPUUsage.TotalUsage
+	if memory == 0 && cpuNanos == 0 {
+		return usage
+	}
+
+	if usage == nil {
+		usage = &ResourceUsage{}
+	}
+	if memory > usage.PeakMemoryBytes {
+		usage.PeakMemoryBytes = memory
+	}
+	// Total CPU usage is cumulative, so the latest sample holds the running total
+	if seconds := float64(cpuNanos) / 1e9; seconds > usage.CPUSeconds {
+		usage.CPUSeconds = seconds
+	}
+	return usage
+}
+
+// memoryUsage is the container's memory use less its inactive page cache, matching
+// what `docker stats` reports (cgroup v2 names the counter inactive_file, v1 total_inactive_file)
+func memoryUsage(stats container.MemoryStats) int64 {
+	used := stats.Usage
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if cache, ok := stats.Stats[key]; ok && cache < used {
+			used -= cache
+			break
+		}
+	}
+	return int64(used)
+}
@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// allocatingDockerClient runs a container that exits at once, reporting the given stats samples
+type allocatingDockerClient struct {
+	*sleepingDockerClient
+	samples []container.StatsResponse
+}
+
+func (f *allocatingDockerClient) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	statusCh := make(chan container.WaitResponse, 1)
+	statusCh <- container.WaitResponse{StatusCode: 0}
+	return statusCh, make(chan error)
+}
+
+func (f *allocatingDockerClient) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, sample := range f.samples {
+		encoder.Encode(sample)
+	}
+	return container.StatsResponseReader{Body: io.NopCloser(&body)}, nil
+}
+
+// memorySample is a stats sample with the given memory use, page cache and cumulative CPU time
+func memorySample(usage, inactiveFile, cpuNanos uint64) container.StatsResponse {
+	var sample container.StatsResponse
+	sample.MemoryStats.Usage = usage
+	sample.MemoryStats.Stats = map[string]uint64{"inactive_file": inactiveFile}
+	sample.CPUStats.CPUUsage.TotalUsage = cpuNanos
+	return sample
+}
+
+func TestRunContainer_RecordsPeakMemory(t *testing.T) {
+	// A container allocating 64MB and then freeing half of it, with 4MB of page cache
+	fake := &allocatingDockerClient{
+		sleepingDockerClient: newSleepingDockerClient(),
+		samples: []container.StatsResponse{
+			memorySample(12<<20, 4<<20, 200_000_000),
+			memorySample(68<<20, 4<<20, 900_000_000),
+			memorySample(36<<20, 4<<20, 1_500_000_000),
+		},
+	}
+	s := &Service{client: fake}
+
+	result, err := s.RunContainer(context.Background(), "python:3.12", []string{"python", "-c", "x = bytearray(64 << 20)"}, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("RunContainer returned error: %v", err)
+	}
+	if result.Usage == nil {
+		t.Fatal("expected resource usage to be sampled")
+	}
+	if result.Usage.PeakMemoryBytes != 64<<20 {
+		t.Errorf("expected a 64MB peak excluding page cache, got %d bytes", result.Usage.PeakMemoryBytes)
+	}
+	if result.Usage.CPUSeconds != 1.5 {
+		t.Errorf("expected 1.5 CPU seconds from the last sample, got %v", result.Usage.CPUSeconds)
+	}
+}
+
+func TestRunContainer_ShortLivedContainerHasNoUsage(t *testing.T) {
+	// The container exits before Docker reports anything but an empty sample
+	fake := &allocatingDockerClient{
+		sleepingDockerClient: newSleepingDockerClient(),
+		samples:              []container.StatsResponse{{}},
+	}
+	s := &Service{client: fake}
+
+	result, err := s.RunContainer(context.Background(), "alpine:latest", []string{"true"}, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("RunContainer returned error: %v", err)
+	}
+	if result.Usage != nil {
+		t.Errorf("expected no usage without a stats sample, got %+v", result.Usage)
+	}
+}
@@ -0,0 +1,204 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// sleepingDockerClient runs a single container that never exits on its own
+// (like `sleep 3600`) until it is stopped
+type sleepingDockerClient struct {
+	client.APIClient // Unused methods panic
+
+	mu      sync.Mutex
+	stopped chan struct{}
+	stops   int
+	removed bool
+}
+
+func newSleepingDockerClient() *sleepingDockerClient {
+	return &sleepingDockerClient{stopped: make(chan struct{})}
+}
+
+func (f *sleepingDockerClient) ImageInspectWithRaw(ctx context.Context, imageName string) (image.InspectResponse, []byte, error) {
+	return image.InspectResponse{}, nil, nil
+}
+
+func (f *sleepingDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	return container.CreateResponse{ID: "sleeper"}, nil
+}
+
+func (f *sleepingDockerClient) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	return nil
+}
+
+func (f *sleepingDockerClient) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	statusCh := make(chan container.WaitResponse, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		select {
+		case <-f.stopped:
+			statusCh <- container.WaitResponse{StatusCode: 137}
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+		}
+	}()
+	return statusCh, errCh
+}
+
+func (f *sleepingDockerClient) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (f *sleepingDockerClient) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+	return container.StatsResponseReader{Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func (f *sleepingDockerClient) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stops == 0 {
+		close(f.stopped)
+	}
+	f.stops++
+	return nil
+}
+
+func (f *sleepingDockerClient) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = true
+	return nil
+}
+
+func (f *sleepingDockerClient) isRunning() bool {
+	select {
+	case <-f.stopped:
+		return false
+	default:
+		return true
+	}
+}
+
+func TestRunContainer_TimeoutStopsContainer(t *testing.T) {
+	fake := newSleepingDockerClient()
+	s := &Service{client: fake}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := s.RunContainer(ctx, "alpine:latest", []string{"sleep", "3600"}, nil, nil, nil, false)
+	if !errors.Is(err, ErrTimeoutExceeded) {
+		t.Fatalf("expected ErrTimeoutExceeded, got %v", err)
+	}
+	if !errors.Is(result.Error, ErrTimeoutExceeded) {
+		t.Errorf("expected the result to record the timeout, got %v", result.Error)
+	}
+	if fake.isRunning() {
+		t.Error("expected the container to be stopped after the timeout")
+	}
+	if !fake.removed {
+		t.Error("expected the container to still be removed")
+	}
+}
+
+func TestRunContainerStreaming_TimeoutStopsContainer(t *testing.T) {
+	fake := newSleepingDockerClient()
+	s := &Service{client: fake}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	lines := make(chan string, 8)
+	_, err := s.RunContainerStreaming(ctx, "alpine:latest", []string{"sleep", "3600"}, nil, nil, nil, false, lines)
+	if !errors.Is(err, ErrTimeoutExceeded) {
+		t.Fatalf("expected ErrTimeoutExceeded, got %v", err)
+	}
+	if fake.isRunning() {
+		t.Error("expected the container to be stopped after the timeout")
+	}
+	if !fake.removed {
+		t.Error("expected the container to still be removed")
+	}
+}
+
+func TestWaitContainer_CancelledContextIsNotATimeout(t *testing.T) {
+	fake := newSleepingDockerClient()
+	s := &Service{client: fake}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.waitContainer(ctx, "sleeper", &ContainerResult{StartedAt: time.Now()})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if fake.isRunning() {
+		t.Error("expected the container to be stopped on cancellation too")
+	}
+}
+
+func TestRunContainerStreaming_CancelStopsRunningContainer(t *testing.T) {
+	fake := newSleepingDockerClient()
+	s := &Service{client: fake}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel) // Cancelled mid-run, as by a cancel request
+
+	start := time.Now()
+	lines := make(chan string, 8)
+	_, err := s.RunContainerStreaming(ctx, "alpine:latest", []string{"sleep", "3600"}, nil, nil, nil, false, lines)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancel took %v to stop the container", elapsed)
+	}
+	if fake.isRunning() {
+		t.Error("expected the container to be stopped")
+	}
+}
+
+// TestRunContainer_CancelStopsSleepPromptly runs a real long sleep, so it needs a Docker
+// daemon; set KARBOS_DOCKER_TESTS=1 to run it
+func TestRunContainer_CancelStopsSleepPromptly(t *testing.T) {
+	if os.Getenv("KARBOS_DOCKER_TESTS") == "" {
+		t.Skip("set KARBOS_DOCKER_TESTS=1 to run containers on the local Docker daemon")
+	}
+
+	s, err := NewDockerService(ResourceLimits{}, ResourceLimits{})
+	if err != nil {
+		t.Fatalf("NewDockerService returned error: %v", err)
+	}
+	defer s.Close()
+	if err := s.PullImage(context.Background(), "alpine:latest"); err != nil {
+		t.Fatalf("PullImage returned error: %v", err)
+	}
+
+	// Cancelled a second in, as when the worker receives a cancel request
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Second, cancel)
+
+	start := time.Now()
+	lines := make(chan string, 8)
+	_, err = s.RunContainerStreaming(ctx, "alpine:latest", []string{"sleep", "3600"}, nil, nil, nil, false, lines)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	// sleep ignores SIGTERM as PID 1, so it is killed once the grace period is up
+	if elapsed := time.Since(start); elapsed > stopGracePeriod+5*time.Second {
+		t.Errorf("cancelled container took %v to stop", elapsed)
+	}
+}
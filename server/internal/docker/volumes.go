@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// ErrVolumeNotAllowed is returned for a mount whose source isn't on the allowlist, or
+// that tries to escape it
+var ErrVolumeNotAllowed = errors.New("volume mount not allowed")
+
+// MaxVolumesPerJob bounds how many mounts a single job may request
+const MaxVolumesPerJob = 10
+
+// volumeNamePattern matches Docker volume names
+var volumeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// VolumeAllowlist lists the host directories and named volumes jobs may mount. A host
+// path is allowed when it is one of the directories or lies beneath one, both as
+// written and after following symlinks, so a link inside an allowed directory can't
+// point a job at the rest of the host. A nil allowlist allows nothing.
+type VolumeAllowlist struct {
+	hostPaths []string        // Cleaned absolute directories
+	volumes   map[string]bool // Named volumes
+}
+
+// ParseVolumeAllowlist reads a comma-separated allowlist of absolute host directories
+// and volume names, e.g. "/srv/karbos/inputs,karbos-data". An empty list returns nil.
+func ParseVolumeAllowlist(entries string) (*VolumeAllowlist, error) {
+	a := &VolumeAllowlist{volumes: make(map[string]bool)}
+	for _, entry := range strings.Split(entries, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case filepath.IsAbs(entry):
+			cleaned := filepath.Clean(entry)
+			if cleaned == string(filepath.Separator) {
+				return nil, fmt.Errorf("volume allowlist entry %q would expose the whole host", entry)
+			}
+			a.hostPaths = append(a.hostPaths, cleaned)
+		case volumeNamePattern.MatchString(entry):
+			a.volumes[entry] = true
+		default:
+			return nil, fmt.Errorf("volume allowlist entry %q is neither an absolute path nor a volume name", entry)
+		}
+	}
+
+	if len(a.hostPaths) == 0 && len(a.volumes) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// Check validates mounts without touching the filesystem, so it also works where the
+// host paths don't exist, such as on the API server. Workers resolve symlinks as well
+// before mounting anything.
+func (a *VolumeAllowlist) Check(mounts []models.VolumeMount) error {
+	if len(mounts) == 0 {
+		return nil
+	}
+	if a == nil {
+		return fmt.Errorf("%w: volume mounts are disabled (DOCKER_VOLUME_ALLOWLIST is empty)", ErrVolumeNotAllowed)
+	}
+	if len(mounts) > MaxVolumesPerJob {
+		return fmt.Errorf("%w: at most %d volumes per job", ErrVolumeNotAllowed, MaxVolumesPerJob)
+	}
+
+	targets := make(map[string]bool, len(mounts))
+	for _, m := range mounts {
+		if err := checkTarget(m.Target); err != nil {
+			return err
+		}
+		target := filepath.Clean(m.Target)
+		if targets[target] {
+			return fmt.Errorf("%w: target %s is mounted twice", ErrVolumeNotAllowed, target)
+		}
+		targets[target] = true
+
+		if !filepath.IsAbs(m.Source) {
+			if !a.volumes[m.Source] {
+				return fmt.Errorf("%w: volume %q is not on the allowlist", ErrVolumeNotAllowed, m.Source)
+			}
+			continue
+		}
+		if hasDotDot(m.Source) {
+			return fmt.Errorf("%w: source %s contains '..'", ErrVolumeNotAllowed, m.Source)
+		}
+		if !within(filepath.Clean(m.Source), a.hostPaths) {
+			return fmt.Errorf("%w: %s is outside the allowed host paths", ErrVolumeNotAllowed, m.Source)
+		}
+	}
+	return nil
+}
+
+// Resolve checks mounts and converts them to Docker mounts. Host paths are resolved
+// through any symlinks and must still lie within an allowed directory (itself resolved);
+// the resolved path is what gets mounted.
+func (a *VolumeAllowlist) Resolve(mounts []models.VolumeMount) ([]mount.Mount, error) {
+	if err := a.Check(mounts); err != nil {
+		return nil, err
+	}
+	if len(mounts) == 0 {
+		return nil, nil
+	}
+
+	var roots []string
+	for _, root := range a.hostPaths {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			roots = append(roots, resolved)
+		}
+	}
+
+	resolved := make([]mount.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		if !filepath.IsAbs(m.Source) {
+			resolved = append(resolved, mount.Mount{Type: mount.TypeVolume, Source: m.Source, Target: filepath.Clean(m.Target), ReadOnly: m.ReadOnly})
+			continue
+		}
+
+		source, err := filepath.EvalSymlinks(m.Source)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%w: %s does not exist", ErrVolumeNotAllowed, m.Source)
+			}
+			return nil, fmt.Errorf("failed to resolve volume source %s: %w", m.Source, err)
+		}
+		if !within(source, roots) {
+			return nil, fmt.Errorf("%w: %s resolves to %s, outside the allowed host paths", ErrVolumeNotAllowed, m.Source, source)
+		}
+		resolved = append(resolved, mount.Mount{Type: mount.TypeBind, Source: source, Target: filepath.Clean(m.Target), ReadOnly: m.ReadOnly})
+	}
+	return resolved, nil
+}
+
+// checkTarget validates a container mount point
+func checkTarget(target string) error {
+	if !filepath.IsAbs(target) || hasDotDot(target) {
+		return fmt.Errorf("%w: target %q must be an absolute container path without '..'", ErrVolumeNotAllowed, target)
+	}
+	if filepath.Clean(target) == string(filepath.Separator) {
+		return fmt.Errorf("%w: cannot mount over the container's root", ErrVolumeNotAllowed)
+	}
+	return nil
+}
+
+// hasDotDot reports whether a path has a ".." element
+func hasDotDot(path string) bool {
+	for _, element := range strings.Split(filepath.ToSlash(path), "/") {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is one of roots or lies beneath one
+func within(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetVolumeAllowlist allows jobs to mount the allowlist's host paths and volumes.
+// Without it, jobs that request mounts fail.
+func (s *Service) SetVolumeAllowlist(allowlist *VolumeAllowlist) {
+	s.volumes = allowlist
+}
@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/docker/docker/api/types/mount"
+)
+
+func TestParseVolumeAllowlist(t *testing.T) {
+	a, err := ParseVolumeAllowlist(" /srv/karbos/inputs/ , karbos-data,")
+	if err != nil {
+		t.Fatalf("ParseVolumeAllowlist returned error: %v", err)
+	}
+	if len(a.hostPaths) != 1 || a.hostPaths[0] != "/srv/karbos/inputs" || !a.volumes["karbos-data"] {
+		t.Errorf("unexpected allowlist %+v", a)
+	}
+
+	if a, err := ParseVolumeAllowlist(""); a != nil || err != nil {
+		t.Errorf("expected an empty allowlist to be nil, got %+v (err %v)", a, err)
+	}
+	for _, entries := range []string{"/", "relative/path", "bad volume"} {
+		if _, err := ParseVolumeAllowlist(entries); err == nil {
+			t.Errorf("%q: expected an error", entries)
+		}
+	}
+}
+
+func TestVolumeAllowlist_Check(t *testing.T) {
+	a, err := ParseVolumeAllowlist("/srv/inputs,karbos-data")
+	if err != nil {
+		t.Fatalf("ParseVolumeAllowlist returned error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mount   models.VolumeMount
+		allowed bool
+	}{
+		{"allowed directory", models.VolumeMount{Source: "/srv/inputs", Target: "/data"}, true},
+		{"beneath allowed directory", models.VolumeMount{Source: "/srv/inputs/run-1", Target: "/data", ReadOnly: true}, true},
+		{"allowed volume", models.VolumeMount{Source: "karbos-data", Target: "/cache"}, true},
+		{"outside allowlist", models.VolumeMount{Source: "/etc", Target: "/data"}, false},
+		{"sibling sharing a prefix", models.VolumeMount{Source: "/srv/inputs-secret", Target: "/data"}, false},
+		{"dot-dot escape", models.VolumeMount{Source: "/srv/inputs/../../etc", Target: "/data"}, false},
+		{"dot-dot that stays inside", models.VolumeMount{Source: "/srv/inputs/a/../b", Target: "/data"}, false},
+		{"unknown volume", models.VolumeMount{Source: "other-data", Target: "/data"}, false},
+		{"relative target", models.VolumeMount{Source: "/srv/inputs", Target: "data"}, false},
+		{"target over root", models.VolumeMount{Source: "/srv/inputs", Target: "/"}, false},
+		{"target escape", models.VolumeMount{Source: "/srv/inputs", Target: "/data/../../host"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Check([]models.VolumeMount{tt.mount})
+			if tt.allowed && err != nil {
+				t.Errorf("expected mount to be allowed, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrVolumeNotAllowed) {
+				t.Errorf("expected ErrVolumeNotAllowed, got %v", err)
+			}
+		})
+	}
+
+	duplicate := []models.VolumeMount{{Source: "/srv/inputs", Target: "/data"}, {Source: "karbos-data", Target: "/data/"}}
+	if err := a.Check(duplicate); !errors.Is(err, ErrVolumeNotAllowed) {
+		t.Errorf("expected two mounts on one target to be rejected, got %v", err)
+	}
+
+	var disabled *VolumeAllowlist
+	if err := disabled.Check([]models.VolumeMount{{Source: "/srv/inputs", Target: "/data"}}); !errors.Is(err, ErrVolumeNotAllowed) {
+		t.Errorf("expected mounts to be refused without an allowlist, got %v", err)
+	}
+	if err := disabled.Check(nil); err != nil {
+		t.Errorf("expected no mounts to pass without an allowlist, got %v", err)
+	}
+}
+
+func TestVolumeAllowlist_ResolveFollowsSymlinks(t *testing.T) {
+	root := t.TempDir()
+	allowed := filepath.Join(root, "inputs")
+	secret := filepath.Join(root, "secret")
+	for _, dir := range []string{filepath.Join(allowed, "run-1"), secret} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A link inside the allowed directory pointing out of it, and one that stays inside
+	if err := os.Symlink(secret, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(allowed, "run-1"), filepath.Join(allowed, "latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ParseVolumeAllowlist(allowed + ",karbos-data")
+	if err != nil {
+		t.Fatalf("ParseVolumeAllowlist returned error: %v", err)
+	}
+	realAllowed, _ := filepath.EvalSymlinks(allowed)
+
+	mounts, err := a.Resolve([]models.VolumeMount{
+		{Source: filepath.Join(allowed, "latest"), Target: "/data", ReadOnly: true},
+		{Source: "karbos-data", Target: "/cache"},
+	})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	want := []mount.Mount{
+		{Type: mount.TypeBind, Source: filepath.Join(realAllowed, "run-1"), Target: "/data", ReadOnly: true},
+		{Type: mount.TypeVolume, Source: "karbos-data", Target: "/cache"},
+	}
+	if len(mounts) != len(want) {
+		t.Fatalf("expected %d mounts, got %+v", len(want), mounts)
+	}
+	for i := range want {
+		if mounts[i].Type != want[i].Type || mounts[i].Source != want[i].Source || mounts[i].Target != want[i].Target || mounts[i].ReadOnly != want[i].ReadOnly {
+			t.Errorf("mount %d = %+v, want %+v", i, mounts[i], want[i])
+		}
+	}
+
+	for name, source := range map[string]string{
+		"symlink out of the allowlist": filepath.Join(allowed, "escape"),
+		"missing path":                 filepath.Join(allowed, "missing"),
+		"path outside the allowlist":   secret,
+	} {
+		if _, err := a.Resolve([]models.VolumeMount{{Source: source, Target: "/data"}}); !errors.Is(err, ErrVolumeNotAllowed) {
+			t.Errorf("%s: expected ErrVolumeNotAllowed, got %v", name, err)
+		}
+	}
+}
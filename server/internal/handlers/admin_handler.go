@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandler handles operator-only endpoints
+type AdminHandler struct {
+	breaker *carbon.CircuitBreaker // nil when no carbon API is configured
+
+	userTokenSecret string // Signs per-user tokens (empty disables issuing them)
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(breaker *carbon.CircuitBreaker) *AdminHandler {
+	return &AdminHandler{
+		breaker: breaker,
+	}
+}
+
+// SetUserTokenSecret enables issuing per-user tokens from GET /api/admin/users/:userId/token
+func (h *AdminHandler) SetUserTokenSecret(secret string) {
+	h.userTokenSecret = secret
+}
+
+// RequireAdmin rejects requests without a matching "Authorization: Bearer <token>" header.
+// When no admin token is configured the admin endpoints are disabled entirely.
+func RequireAdmin(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "admin_disabled",
+				Message: "Admin endpoints are disabled (ADMIN_API_TOKEN is not set)",
+				Code:    fiber.StatusForbidden,
+			})
+		}
+
+		provided := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "Valid admin token required",
+				Code:    fiber.StatusUnauthorized,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// GetCircuitBreaker handles GET /api/carbon/circuit and GET /api/admin/circuit-breaker
+// Reports the carbon API circuit state, failure count and the fallback used while it's open
+func (h *AdminHandler) GetCircuitBreaker(c *fiber.Ctx) error {
+	if h.breaker == nil {
+		return h.breakerNotConfigured(c)
+	}
+
+	return c.JSON(h.breaker.GetStats())
+}
+
+// ResetCircuitBreaker handles POST /api/admin/circuit-breaker/reset
+// Closes the circuit once an upstream outage is resolved, instead of waiting for the timeout
+func (h *AdminHandler) ResetCircuitBreaker(c *fiber.Ctx) error {
+	if h.breaker == nil {
+		return h.breakerNotConfigured(c)
+	}
+
+	previous := h.breaker.GetState()
+	h.breaker.Reset()
+	slog.Info("Circuit breaker reset by admin", "previous_state", previous)
+
+	return c.JSON(fiber.Map{
+		"previous_state": previous.String(),
+		"stats":          h.breaker.GetStats(),
+	})
+}
+
+// GetUserToken handles GET /api/admin/users/:userId/token
+func (h *AdminHandler) GetUserToken(c *fiber.Ctx) error {
+	if h.userTokenSecret == "" {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "not_configured",
+			Message: "Per-user tokens are disabled (USER_TOKEN_SECRET is not set)",
+			Code:    fiber.StatusNotFound,
+		})
+	}
+
+	userID := c.Params("userId")
+	return c.JSON(fiber.Map{
+		"user_id": userID,
+		"token":   UserToken(h.userTokenSecret, userID),
+	})
+}
+
+// breakerNotConfigured responds when the API runs without a carbon service
+func (h *AdminHandler) breakerNotConfigured(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+		Error:   "not_configured",
+		Message: "No carbon API circuit breaker is configured",
+		Code:    fiber.StatusNotFound,
+	})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/gofiber/fiber/v2"
+)
+
+// failingCarbonService always fails, tripping the circuit breaker
+type failingCarbonService struct{}
+
+func (failingCarbonService) GetCarbonIntensity(ctx context.Context, region string, timestamp time.Time) (*carbon.CarbonIntensity, error) {
+	return nil, errors.New("carbon API unavailable")
+}
+
+func (failingCarbonService) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	return nil, errors.New("carbon API unavailable")
+}
+
+func (failingCarbonService) GetCarbonHistory(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	return nil, errors.New("carbon API unavailable")
+}
+
+func newAdminTestApp(breaker *carbon.CircuitBreaker, token string) *fiber.App {
+	app := fiber.New()
+	h := NewAdminHandler(breaker)
+	admin := app.Group("/api/admin", RequireAdmin(token))
+	admin.Get("/circuit-breaker", h.GetCircuitBreaker)
+	admin.Post("/circuit-breaker/reset", h.ResetCircuitBreaker)
+	return app
+}
+
+func TestAdminHandler_ResetClosesOpenCircuit(t *testing.T) {
+	breaker := carbon.NewCircuitBreaker(failingCarbonService{}, carbon.CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Hour})
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+	if breaker.GetState() != carbon.StateOpen {
+		t.Fatalf("expected circuit to be open, got %s", breaker.GetState())
+	}
+
+	app := newAdminTestApp(breaker, "s3cret")
+
+	req := httptest.NewRequest("POST", "/api/admin/circuit-breaker/reset", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		PreviousState string `json:"previous_state"`
+		Stats         struct {
+			State    string `json:"state"`
+			Failures int    `json:"failures"`
+		} `json:"stats"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if body.PreviousState != "OPEN" || body.Stats.State != "CLOSED" || body.Stats.Failures != 0 {
+		t.Errorf("expected OPEN -> CLOSED with 0 failures, got %+v", body)
+	}
+	if breaker.GetState() != carbon.StateClosed {
+		t.Errorf("expected breaker to be closed after reset, got %s", breaker.GetState())
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	breaker := carbon.NewCircuitBreaker(failingCarbonService{}, carbon.CircuitBreakerConfig{})
+
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", token: "s3cret", header: "Bearer s3cret", wantStatus: fiber.StatusOK},
+		{name: "wrong token", token: "s3cret", header: "Bearer nope", wantStatus: fiber.StatusUnauthorized},
+		{name: "missing header", token: "s3cret", header: "", wantStatus: fiber.StatusUnauthorized},
+		{name: "admin disabled", token: "", header: "Bearer ", wantStatus: fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newAdminTestApp(breaker, tt.token)
+			req := httptest.NewRequest("GET", "/api/admin/circuit-breaker", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_GetUserTokenMatchesRequireUser(t *testing.T) {
+	h := NewAdminHandler(nil)
+	h.SetUserTokenSecret("user-secret")
+
+	app := fiber.New()
+	app.Get("/api/admin/users/:userId/token", RequireAdmin("s3cret"), h.GetUserToken)
+
+	req := httptest.NewRequest("GET", "/api/admin/users/user-1/token", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Token == "" || body.Token != UserToken("user-secret", "user-1") {
+		t.Errorf("expected the issued token to be user-1's, got %q", body.Token)
+	}
+}
+
+func TestAdminHandler_CarbonCircuitRoutes(t *testing.T) {
+	breaker := carbon.NewCircuitBreaker(failingCarbonService{}, carbon.CircuitBreakerConfig{MaxFailures: 1, Timeout: time.Hour, StaticFallback: 350})
+	breaker.GetCarbonIntensity(context.Background(), "US-EAST", time.Now())
+
+	app := newAdminTestApp(breaker, "s3cret")
+	app.Get("/api/carbon/circuit", NewAdminHandler(breaker).GetCircuitBreaker)
+
+	getState := func() map[string]interface{} {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/carbon/circuit", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var stats map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return stats
+	}
+
+	stats := getState()
+	if stats["state"] != "OPEN" || stats["failures"] != float64(1) || stats["static_fallback"] != float64(350) {
+		t.Errorf("expected OPEN with 1 failure and a 350 fallback, got %v", stats)
+	}
+	if stats["time_since_last_fail"] == "" {
+		t.Error("expected time since the last failure to be reported")
+	}
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/admin/circuit-breaker/reset", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected reset without a token to be rejected, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("POST", "/api/admin/circuit-breaker/reset", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	if stats := getState(); stats["state"] != "CLOSED" || stats["failures"] != float64(0) {
+		t.Errorf("expected CLOSED with 0 failures after reset, got %v", stats)
+	}
+}
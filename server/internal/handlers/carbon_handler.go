@@ -2,17 +2,40 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"sort"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/gofiber/fiber/v2"
 )
 
+// carbonCacheReader reads cached carbon intensity rows
+type carbonCacheReader interface {
+	GetCarbonIntensityRange(ctx context.Context, region string, startTime, endTime time.Time) ([]database.CarbonCacheEntry, error)
+	GetRecentEntries(ctx context.Context, duration time.Duration) ([]database.CarbonCacheEntry, error)
+}
+
+// forecastFetcher retrieves a live carbon forecast
+type forecastFetcher interface {
+	GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error)
+}
+
+// greenestWindowReader serves precomputed greenest windows
+type greenestWindowReader interface {
+	Get(region string) (carbon.GreenestWindow, bool)
+	All() []carbon.GreenestWindow
+}
+
 // CarbonHandler handles carbon-related HTTP requests
 type CarbonHandler struct {
-	carbonRepo *database.CarbonCacheRepository
+	carbonRepo carbonCacheReader
+	fetcher    forecastFetcher      // Optional: live fallback when the cache is empty for a region
+	greenest   greenestWindowReader // Optional: serves GET /api/carbon/recommend
 }
 
 // NewCarbonHandler creates a new carbon handler
@@ -22,12 +45,35 @@ func NewCarbonHandler(carbonRepo *database.CarbonCacheRepository) *CarbonHandler
 	}
 }
 
+// SetFetcher enables the live forecast fallback for regions with no cached data
+func (h *CarbonHandler) SetFetcher(fetcher *carbon.CarbonFetcher) {
+	if fetcher != nil {
+		h.fetcher = fetcher
+	}
+}
+
+// SetGreenestWindows enables GET /api/carbon/recommend, served from the prefetcher's view
+func (h *CarbonHandler) SetGreenestWindows(greenest *carbon.GreenestWindows) {
+	if greenest != nil {
+		h.greenest = greenest
+	}
+}
+
+// CarbonRecommendationResponse lists each region's greenest upcoming window
+type CarbonRecommendationResponse struct {
+	Recommendations []carbon.GreenestWindow `json:"recommendations"`
+}
+
 // CarbonForecastEntry represents a single forecast entry for the API
 type CarbonForecastEntry struct {
 	Region         string  `json:"region"`
 	Timestamp      string  `json:"timestamp"`
 	IntensityValue float64 `json:"intensity_value"`
-	Unit           string  `json:"unit"`
+	Unit           string  `json:"unit"`            // "gCO2/kWh", or "percent" for a relative index
+	IntensityScale string  `json:"intensity_scale"` // "absolute" or "relative"
+
+	RenewablePercentage *float64 `json:"renewable_percentage,omitempty"`
+	FossilPercentage    *float64 `json:"fossil_percentage,omitempty"`
 }
 
 // CarbonForecastResponse represents the carbon forecast API response
@@ -36,6 +82,7 @@ type CarbonForecastResponse struct {
 	Forecasts        []CarbonForecastEntry `json:"forecasts"`
 	CurrentIntensity *float64              `json:"current_intensity,omitempty"`
 	OptimalTime      *string               `json:"optimal_time,omitempty"`
+	Source           string                `json:"source"` // "cache" or "live"
 }
 
 // GetCarbonForecast handles GET /api/carbon-forecast
@@ -56,7 +103,7 @@ func (h *CarbonHandler) GetCarbonForecast(c *fiber.Ctx) error {
 
 		cacheEntries, err = h.carbonRepo.GetCarbonIntensityRange(ctx, region, now, endTime)
 		if err != nil {
-			log.Printf("Failed to get carbon forecast for region %s: %v", region, err)
+			slog.Error("Failed to get carbon forecast", logging.KeyRegion, region, logging.Err(err))
 			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 				Error:   "database_error",
 				Message: "Failed to fetch carbon forecast",
@@ -67,7 +114,7 @@ func (h *CarbonHandler) GetCarbonForecast(c *fiber.Ctx) error {
 		// Get all recent carbon cache entries (last 24 hours across all regions)
 		cacheEntries, err = h.carbonRepo.GetRecentEntries(ctx, 24*time.Hour)
 		if err != nil {
-			log.Printf("Failed to get recent carbon cache entries: %v", err)
+			slog.Error("Failed to get recent carbon cache entries", logging.Err(err))
 			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 				Error:   "database_error",
 				Message: "Failed to fetch carbon cache data",
@@ -79,11 +126,25 @@ func (h *CarbonHandler) GetCarbonForecast(c *fiber.Ctx) error {
 	// Convert to API response format
 	forecasts := make([]CarbonForecastEntry, len(cacheEntries))
 	for i, entry := range cacheEntries {
-		forecasts[i] = CarbonForecastEntry{
-			Region:         entry.Region,
-			Timestamp:      entry.Timestamp.Format(time.RFC3339),
-			IntensityValue: entry.IntensityValue,
-			Unit:           "gCO2/kWh",
+		forecasts[i] = toForecastEntry(entry)
+	}
+
+	// Freshly started systems have an empty cache: fetch the region's forecast live
+	source := "cache"
+	if region != "" && len(forecasts) == 0 && h.fetcher != nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		live, err := h.fetcher.GetCarbonForecast(fetchCtx, region, now, now.Add(24*time.Hour))
+		if err != nil {
+			slog.Warn("Live carbon forecast fallback failed", logging.KeyRegion, region, logging.Err(err))
+		} else {
+			forecasts = make([]CarbonForecastEntry, len(live))
+			for i, point := range live {
+				forecasts[i] = liveForecastEntry(point)
+			}
+			source = "live"
 		}
 	}
 
@@ -109,11 +170,158 @@ func (h *CarbonHandler) GetCarbonForecast(c *fiber.Ctx) error {
 		Forecasts:        forecasts,
 		CurrentIntensity: currentIntensity,
 		OptimalTime:      optimalTime,
+		Source:           source,
+	}
+
+	return c.JSON(response)
+}
+
+// Series window bounds, in hours
+const (
+	defaultSeriesHours = 24
+	maxSeriesHours     = 168
+)
+
+// CarbonSeriesPoint is one point of a region's intensity series
+type CarbonSeriesPoint struct {
+	Timestamp string  `json:"timestamp"` // RFC 3339
+	Intensity float64 `json:"intensity"`
+	Unit      string  `json:"unit"`   // "gCO2/kWh", or "percent" for a relative index
+	Source    string  `json:"source"` // Where the cached point came from ("api", "csv", ...), or "live"
+}
+
+// CarbonSeriesSummary sums up the intensities of a series
+type CarbonSeriesSummary struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// CarbonSeriesResponse is a region's intensity over the coming hours, oldest point first
+type CarbonSeriesResponse struct {
+	Region  string               `json:"region"`
+	Hours   int                  `json:"hours"`
+	Points  []CarbonSeriesPoint  `json:"points"`
+	Summary *CarbonSeriesSummary `json:"summary,omitempty"` // Omitted when there are no points
+	Source  string               `json:"source"`            // "cache" or "live"
+}
+
+// GetCarbonSeries handles GET /api/carbon/:region/series
+// Query params: hours (default 24, max 168), counted from the start of the current hour.
+// The live forecast is used when nothing is cached for the window.
+func (h *CarbonHandler) GetCarbonSeries(c *fiber.Ctx) error {
+	ctx := context.Background()
+	region := c.Params("region")
+
+	hours := c.QueryInt("hours", defaultSeriesHours)
+	if hours <= 0 || hours > maxSeriesHours {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_hours",
+			Message: fmt.Sprintf("hours must be between 1 and %d", maxSeriesHours),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	start := time.Now().Truncate(time.Hour)
+	end := start.Add(time.Duration(hours) * time.Hour)
+
+	entries, err := h.carbonRepo.GetCarbonIntensityRange(ctx, region, start, end)
+	if err != nil {
+		slog.Error("Failed to get carbon series", logging.KeyRegion, region, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to fetch carbon intensity series",
+			Code:    fiber.StatusInternalServerError,
+		})
 	}
 
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+
+	response := CarbonSeriesResponse{Region: region, Hours: hours, Points: []CarbonSeriesPoint{}, Source: "cache"}
+	for _, entry := range entries {
+		source := "api"
+		if entry.Source != nil && *entry.Source != "" {
+			source = *entry.Source
+		}
+		response.Points = append(response.Points, CarbonSeriesPoint{
+			Timestamp: entry.Timestamp.Format(time.RFC3339),
+			Intensity: entry.IntensityValue,
+			Unit:      intensityUnit(entry.IntensityScale),
+			Source:    source,
+		})
+	}
+
+	if len(response.Points) == 0 && h.fetcher != nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		live, err := h.fetcher.GetCarbonForecast(fetchCtx, region, start, end)
+		if err != nil {
+			slog.Warn("Live carbon series fallback failed", logging.KeyRegion, region, logging.Err(err))
+		} else {
+			sort.SliceStable(live, func(i, j int) bool { return live[i].Timestamp.Before(live[j].Timestamp) })
+			for _, point := range live {
+				response.Points = append(response.Points, CarbonSeriesPoint{
+					Timestamp: point.Timestamp.Format(time.RFC3339),
+					Intensity: point.Intensity,
+					Unit:      intensityUnit(string(point.IntensityScale)),
+					Source:    "live",
+				})
+			}
+			response.Source = "live"
+		}
+	}
+
+	response.Summary = summarizeSeries(response.Points)
+
 	return c.JSON(response)
 }
 
+// summarizeSeries returns the min, max and mean intensity of points, or nil without points
+func summarizeSeries(points []CarbonSeriesPoint) *CarbonSeriesSummary {
+	if len(points) == 0 {
+		return nil
+	}
+
+	summary := &CarbonSeriesSummary{Min: points[0].Intensity, Max: points[0].Intensity}
+	var total float64
+	for _, point := range points {
+		summary.Min = min(summary.Min, point.Intensity)
+		summary.Max = max(summary.Max, point.Intensity)
+		total += point.Intensity
+	}
+	summary.Avg = total / float64(len(points))
+	return summary
+}
+
+// GetRecommendation handles GET /api/carbon/recommend. Windows are precomputed whenever
+// the prefetcher refreshes a region, so this never touches the cache or the carbon API;
+// each window's computed_at tells clients how fresh it is.
+func (h *CarbonHandler) GetRecommendation(c *fiber.Ctx) error {
+	if h.greenest == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+			Error:   "recommendations_unavailable",
+			Message: "Recommendations need forecast prefetching (CARBON_PREFETCH_REGIONS is not set)",
+			Code:    fiber.StatusServiceUnavailable,
+		})
+	}
+
+	region := c.Query("region", "")
+	if region == "" {
+		return c.JSON(CarbonRecommendationResponse{Recommendations: h.greenest.All()})
+	}
+
+	window, ok := h.greenest.Get(region)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "not_computed",
+			Message: "No greenest window computed for region " + region + " (is it in CARBON_PREFETCH_REGIONS?)",
+			Code:    fiber.StatusNotFound,
+		})
+	}
+	return c.JSON(CarbonRecommendationResponse{Recommendations: []carbon.GreenestWindow{window}})
+}
+
 // GetCarbonCache handles GET /api/carbon-cache
 func (h *CarbonHandler) GetCarbonCache(c *fiber.Ctx) error {
 	ctx := context.Background()
@@ -121,7 +329,7 @@ func (h *CarbonHandler) GetCarbonCache(c *fiber.Ctx) error {
 	// Get all recent entries (last 48 hours)
 	cacheEntries, err := h.carbonRepo.GetRecentEntries(ctx, 48*time.Hour)
 	if err != nil {
-		log.Printf("Failed to get carbon cache entries: %v", err)
+		slog.Error("Failed to get carbon cache entries", logging.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "database_error",
 			Message: "Failed to fetch carbon cache",
@@ -132,13 +340,54 @@ func (h *CarbonHandler) GetCarbonCache(c *fiber.Ctx) error {
 	// Convert to API response format
 	forecasts := make([]CarbonForecastEntry, len(cacheEntries))
 	for i, entry := range cacheEntries {
-		forecasts[i] = CarbonForecastEntry{
-			Region:         entry.Region,
-			Timestamp:      entry.Timestamp.Format(time.RFC3339),
-			IntensityValue: entry.IntensityValue,
-			Unit:           "gCO2/kWh",
-		}
+		forecasts[i] = toForecastEntry(entry)
 	}
 
 	return c.JSON(forecasts)
 }
+
+// toForecastEntry converts a cache row to the API format
+func toForecastEntry(entry database.CarbonCacheEntry) CarbonForecastEntry {
+	return CarbonForecastEntry{
+		Region:              entry.Region,
+		Timestamp:           entry.Timestamp.Format(time.RFC3339),
+		IntensityValue:      entry.IntensityValue,
+		Unit:                intensityUnit(entry.IntensityScale),
+		IntensityScale:      intensityScaleOrAbsolute(entry.IntensityScale),
+		RenewablePercentage: entry.RenewablePercentage,
+		FossilPercentage:    entry.FossilPercentage,
+	}
+}
+
+// liveForecastEntry converts a live forecast point to the API format
+func liveForecastEntry(point carbon.CarbonIntensity) CarbonForecastEntry {
+	entry := CarbonForecastEntry{
+		Region:         point.Region,
+		Timestamp:      point.Timestamp.Format(time.RFC3339),
+		IntensityValue: point.Intensity,
+		Unit:           intensityUnit(string(point.IntensityScale)),
+		IntensityScale: intensityScaleOrAbsolute(string(point.IntensityScale)),
+	}
+	if point.RenewableEnergy != 0 || point.FossilFuel != 0 {
+		renewable, fossil := point.RenewableEnergy, point.FossilFuel
+		entry.RenewablePercentage = &renewable
+		entry.FossilPercentage = &fossil
+	}
+	return entry
+}
+
+// intensityUnit returns the unit to report for an intensity scale
+func intensityUnit(scale string) string {
+	if scale == string(carbon.IntensityScaleRelative) {
+		return "percent"
+	}
+	return "gCO2/kWh"
+}
+
+// intensityScaleOrAbsolute treats an unset scale as absolute
+func intensityScaleOrAbsolute(scale string) string {
+	if scale == "" {
+		return string(carbon.IntensityScaleAbsolute)
+	}
+	return scale
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/gofiber/fiber/v2"
+)
+
+// fakeCarbonCache serves cache rows per region
+type fakeCarbonCache struct {
+	entries map[string][]database.CarbonCacheEntry
+}
+
+func (f *fakeCarbonCache) GetCarbonIntensityRange(ctx context.Context, region string, startTime, endTime time.Time) ([]database.CarbonCacheEntry, error) {
+	return f.entries[region], nil
+}
+
+func (f *fakeCarbonCache) GetRecentEntries(ctx context.Context, duration time.Duration) ([]database.CarbonCacheEntry, error) {
+	var all []database.CarbonCacheEntry
+	for _, entries := range f.entries {
+		all = append(all, entries...)
+	}
+	return all, nil
+}
+
+// fakeForecastFetcher returns a fixed live forecast and counts calls
+type fakeForecastFetcher struct {
+	calls int
+}
+
+func (f *fakeForecastFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	f.calls++
+	return []carbon.CarbonIntensity{
+		{Region: region, Timestamp: startTime, Intensity: 320},
+		{Region: region, Timestamp: startTime.Add(time.Hour), Intensity: 140, RenewableEnergy: 82, FossilFuel: 18},
+	}, nil
+}
+
+func newCarbonTestApp(h *CarbonHandler) *fiber.App {
+	app := fiber.New()
+	app.Get("/api/carbon-forecast", h.GetCarbonForecast)
+	return app
+}
+
+func getForecast(t *testing.T, app *fiber.App, url string) CarbonForecastResponse {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body CarbonForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return body
+}
+
+func TestCarbonHandler_GetCarbonForecast_RegionFromCache(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	cache := &fakeCarbonCache{entries: map[string][]database.CarbonCacheEntry{
+		"EU-NORTH": {
+			{Region: "EU-NORTH", Timestamp: now, IntensityValue: 90},
+			{Region: "EU-NORTH", Timestamp: now.Add(time.Hour), IntensityValue: 60},
+		},
+	}}
+	fetcher := &fakeForecastFetcher{}
+	app := newCarbonTestApp(&CarbonHandler{carbonRepo: cache, fetcher: fetcher})
+
+	body := getForecast(t, app, "/api/carbon-forecast?region=EU-NORTH")
+
+	if body.Source != "cache" || len(body.Forecasts) != 2 {
+		t.Fatalf("expected 2 cached forecasts, got %d from %q", len(body.Forecasts), body.Source)
+	}
+	if fetcher.calls != 0 {
+		t.Error("live fetcher should not be called when the cache has data")
+	}
+	if body.CurrentIntensity == nil || *body.CurrentIntensity != 90 {
+		t.Errorf("expected current intensity 90, got %v", body.CurrentIntensity)
+	}
+	if body.OptimalTime == nil || *body.OptimalTime != now.Add(time.Hour).Format(time.RFC3339) {
+		t.Errorf("expected optimal time at the 60 gCO2/kWh slot, got %v", body.OptimalTime)
+	}
+}
+
+func TestCarbonHandler_GetCarbonForecast_RegionFallsBackToFetcher(t *testing.T) {
+	fetcher := &fakeForecastFetcher{}
+	app := newCarbonTestApp(&CarbonHandler{carbonRepo: &fakeCarbonCache{}, fetcher: fetcher})
+
+	body := getForecast(t, app, "/api/carbon-forecast?region=US-WEST")
+
+	if fetcher.calls != 1 {
+		t.Fatalf("expected one live fetch, got %d", fetcher.calls)
+	}
+	if body.Source != "live" || len(body.Forecasts) != 2 {
+		t.Fatalf("expected 2 live forecasts, got %d from %q", len(body.Forecasts), body.Source)
+	}
+	if p := body.Forecasts[1].RenewablePercentage; p == nil || *p != 82 {
+		t.Errorf("expected 82%% renewable on the second point, got %v", p)
+	}
+}
+
+func TestCarbonHandler_GetCarbonForecast_AllRegions(t *testing.T) {
+	now := time.Now()
+	cache := &fakeCarbonCache{entries: map[string][]database.CarbonCacheEntry{
+		"EU-NORTH": {{Region: "EU-NORTH", Timestamp: now, IntensityValue: 90}},
+		"US-EAST":  {{Region: "US-EAST", Timestamp: now, IntensityValue: 410}},
+	}}
+	fetcher := &fakeForecastFetcher{}
+	app := newCarbonTestApp(&CarbonHandler{carbonRepo: cache, fetcher: fetcher})
+
+	body := getForecast(t, app, "/api/carbon-forecast")
+
+	if len(body.Forecasts) != 2 {
+		t.Fatalf("expected entries for both regions, got %d", len(body.Forecasts))
+	}
+	if body.CurrentIntensity != nil {
+		t.Error("current intensity is only reported for a single region")
+	}
+	if fetcher.calls != 0 {
+		t.Error("all-regions requests should not trigger a live fetch")
+	}
+}
+
+func TestCarbonHandler_GetCarbonForecast_EmptyWithoutFetcher(t *testing.T) {
+	app := newCarbonTestApp(&CarbonHandler{carbonRepo: &fakeCarbonCache{}})
+
+	body := getForecast(t, app, "/api/carbon-forecast?region=US-WEST")
+	if len(body.Forecasts) != 0 || body.Source != "cache" {
+		t.Errorf("expected an empty cached response, got %d entries from %q", len(body.Forecasts), body.Source)
+	}
+}
+
+func TestCarbonHandler_GetCarbonForecast_RelativeUnit(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	cache := &fakeCarbonCache{entries: map[string][]database.CarbonCacheEntry{
+		"CAISO_NORTH": {{Region: "CAISO_NORTH", Timestamp: now, IntensityValue: 73, IntensityScale: "relative"}},
+		"EU-NORTH":    {{Region: "EU-NORTH", Timestamp: now, IntensityValue: 42, IntensityScale: "absolute"}},
+	}}
+	app := newCarbonTestApp(&CarbonHandler{carbonRepo: cache})
+
+	relative := getForecast(t, app, "/api/carbon-forecast?region=CAISO_NORTH").Forecasts[0]
+	if relative.Unit != "percent" || relative.IntensityScale != "relative" {
+		t.Errorf("expected a relative index in percent, got %q (%q)", relative.Unit, relative.IntensityScale)
+	}
+
+	absolute := getForecast(t, app, "/api/carbon-forecast?region=EU-NORTH").Forecasts[0]
+	if absolute.Unit != "gCO2/kWh" || absolute.IntensityScale != "absolute" {
+		t.Errorf("expected gCO2/kWh, got %q (%q)", absolute.Unit, absolute.IntensityScale)
+	}
+}
+
+func TestCarbonHandler_GetRecommendation_ServedFromView(t *testing.T) {
+	now := time.Now()
+	view := carbon.NewGreenestWindows(time.Hour)
+	view.Update("DE", []carbon.CarbonIntensity{
+		{Timestamp: now.Truncate(time.Hour), Intensity: 300},
+		{Timestamp: now.Truncate(time.Hour).Add(time.Hour), Intensity: 110},
+	}, now)
+
+	cache := &fakeCarbonCache{}
+	fetcher := &fakeForecastFetcher{}
+	h := &CarbonHandler{carbonRepo: cache, fetcher: fetcher}
+	h.SetGreenestWindows(view)
+
+	app := fiber.New()
+	app.Get("/api/carbon/recommend", h.GetRecommendation)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/carbon/recommend?region=DE", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body CarbonRecommendationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Recommendations) != 1 || body.Recommendations[0].AvgIntensity != 110 {
+		t.Fatalf("recommendations = %+v, want DE at 110", body.Recommendations)
+	}
+	if body.Recommendations[0].ComputedAt.IsZero() {
+		t.Error("expected computed_at to be set")
+	}
+	if fetcher.calls != 0 {
+		t.Errorf("fetcher called %d times, want the view only", fetcher.calls)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/api/carbon/recommend?region=FR", nil))
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown region status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func getSeries(t *testing.T, app *fiber.App, url string) CarbonSeriesResponse {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body CarbonSeriesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return body
+}
+
+func newSeriesTestApp(h *CarbonHandler) *fiber.App {
+	app := fiber.New()
+	app.Get("/api/carbon/:region/series", h.GetCarbonSeries)
+	return app
+}
+
+func TestCarbonHandler_GetCarbonSeries_FromCache(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	csv := "csv"
+	cache := &fakeCarbonCache{entries: map[string][]database.CarbonCacheEntry{
+		"EU-NORTH": { // Out of order, as a cache may return them
+			{Region: "EU-NORTH", Timestamp: now.Add(2 * time.Hour), IntensityValue: 60},
+			{Region: "EU-NORTH", Timestamp: now, IntensityValue: 90, Source: &csv},
+			{Region: "EU-NORTH", Timestamp: now.Add(time.Hour), IntensityValue: 120},
+		},
+	}}
+	fetcher := &fakeForecastFetcher{}
+	app := newSeriesTestApp(&CarbonHandler{carbonRepo: cache, fetcher: fetcher})
+
+	body := getSeries(t, app, "/api/carbon/EU-NORTH/series?hours=6")
+
+	if body.Source != "cache" || body.Hours != 6 || len(body.Points) != 3 {
+		t.Fatalf("expected 3 cached points over 6 hours, got %d from %q over %d", len(body.Points), body.Source, body.Hours)
+	}
+	if fetcher.calls != 0 {
+		t.Error("live fetcher should not be called when the cache has data")
+	}
+	for i, want := range []float64{90, 120, 60} {
+		if body.Points[i].Intensity != want {
+			t.Errorf("point %d: got %.0f, want %.0f", i, body.Points[i].Intensity, want)
+		}
+	}
+	if body.Points[0].Source != "csv" || body.Points[1].Source != "api" || body.Points[0].Unit != "gCO2/kWh" {
+		t.Errorf("unexpected point sources or unit: %+v", body.Points)
+	}
+	if s := body.Summary; s == nil || s.Min != 60 || s.Max != 120 || s.Avg != 90 {
+		t.Errorf("expected min 60, max 120, avg 90, got %+v", s)
+	}
+}
+
+func TestCarbonHandler_GetCarbonSeries_FallsBackToForecast(t *testing.T) {
+	fetcher := &fakeForecastFetcher{}
+	app := newSeriesTestApp(&CarbonHandler{carbonRepo: &fakeCarbonCache{}, fetcher: fetcher})
+
+	body := getSeries(t, app, "/api/carbon/US-WEST/series")
+
+	if fetcher.calls != 1 {
+		t.Fatalf("expected one live fetch, got %d", fetcher.calls)
+	}
+	if body.Source != "live" || body.Hours != 24 || len(body.Points) != 2 {
+		t.Fatalf("expected 2 live points over 24 hours, got %d from %q over %d", len(body.Points), body.Source, body.Hours)
+	}
+	if body.Points[0].Source != "live" || body.Points[0].Intensity != 320 {
+		t.Errorf("unexpected first point %+v", body.Points[0])
+	}
+	if s := body.Summary; s == nil || s.Min != 140 || s.Max != 320 || s.Avg != 230 {
+		t.Errorf("expected min 140, max 320, avg 230, got %+v", s)
+	}
+}
+
+func TestCarbonHandler_GetCarbonSeries_Empty(t *testing.T) {
+	app := newSeriesTestApp(&CarbonHandler{carbonRepo: &fakeCarbonCache{}})
+
+	body := getSeries(t, app, "/api/carbon/US-WEST/series")
+	if len(body.Points) != 0 || body.Summary != nil {
+		t.Errorf("expected no points and no summary, got %+v", body)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/api/carbon/US-WEST/series?hours=500", nil))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for hours=500, got %d", resp.StatusCode)
+	}
+}
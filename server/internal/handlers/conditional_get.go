@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CacheableReads lets clients poll read-only endpoints cheaply. Successful GET responses
+// get an ETag and a Cache-Control max-age, and a request whose If-None-Match holds the
+// current ETag is answered 304 Not Modified without a body. The ETag hashes the response,
+// so it changes as soon as the cached carbon data behind it is refreshed, or the window
+// of a forecast moves past an entry; an ETag taken from the cache's write times alone
+// would miss both. Other methods pass straight through.
+func CacheableReads(maxAge time.Duration) fiber.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+
+		// Weak, since compression changes the bytes but not what they represent
+		sum := sha256.Sum256(c.Response().Body())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Set(fiber.HeaderETag, etag)
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+
+		if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+			c.Response().ResetBody()
+			c.Status(fiber.StatusNotModified)
+		}
+		return nil
+	}
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing weakly as
+// RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
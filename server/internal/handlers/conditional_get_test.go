@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCacheableReads_ConditionalGet(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	cache := &fakeCarbonCache{entries: map[string][]database.CarbonCacheEntry{
+		"US-EAST": {{Region: "US-EAST", Timestamp: now, IntensityValue: 300}},
+	}}
+	h := &CarbonHandler{carbonRepo: cache}
+
+	app := fiber.New()
+	app.Get("/api/carbon/:region/series", CacheableReads(time.Minute), h.GetCarbonSeries)
+	app.Post("/api/carbon/:region/series", CacheableReads(time.Minute), func(c *fiber.Ctx) error {
+		return c.SendString("changed")
+	})
+
+	get := func(ifNoneMatch string) (int, string, []byte) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/carbon/US-EAST/series", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if cacheControl := resp.Header.Get(fiber.HeaderCacheControl); resp.StatusCode < 400 && cacheControl != "public, max-age=60" {
+			t.Errorf("Cache-Control = %q, want public, max-age=60", cacheControl)
+		}
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderETag), body
+	}
+
+	status, etag, body := get("")
+	if status != fiber.StatusOK || etag == "" || len(body) == 0 {
+		t.Fatalf("first request: got %d with ETag %q and %d bytes, want 200 with an ETag and a body", status, etag, len(body))
+	}
+
+	status, again, body := get(etag)
+	if status != fiber.StatusNotModified || len(body) != 0 {
+		t.Errorf("conditional request: got %d with %d bytes, want 304 without a body", status, len(body))
+	}
+	if again != etag {
+		t.Errorf("304 ETag = %q, want %q", again, etag)
+	}
+
+	// A refreshed cache entry changes the response, and so the ETag
+	cache.entries["US-EAST"][0].IntensityValue = 180
+	if status, refreshed, _ := get(etag); status != fiber.StatusOK || refreshed == etag {
+		t.Errorf("after a refresh: got %d with ETag %q, want 200 with a new ETag", status, refreshed)
+	}
+
+	// Mutating requests are never cached
+	req := httptest.NewRequest("POST", "/api/carbon/US-EAST/series", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, "*")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderETag) != "" || resp.Header.Get(fiber.HeaderCacheControl) != "" {
+		t.Errorf("POST: got %d with ETag %q and Cache-Control %q, want 200 without either", resp.StatusCode, resp.Header.Get(fiber.HeaderETag), resp.Header.Get(fiber.HeaderCacheControl))
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for _, header := range []string{`W/"abc"`, `"abc"`, `"xyz", W/"abc"`, `*`} {
+		if !etagMatches(header, etag) {
+			t.Errorf("etagMatches(%q) = false, want true", header)
+		}
+	}
+	for _, header := range []string{"", `"abcd"`, `W/"xyz"`} {
+		if etagMatches(header, etag) {
+			t.Errorf("etagMatches(%q) = true, want false", header)
+		}
+	}
+}
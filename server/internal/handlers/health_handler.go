@@ -34,10 +34,14 @@ func (h *HealthHandler) HealthCheck(c *fiber.Ctx) error {
 		dbHealthy = false
 	}
 
-	// Check Redis
+	// Check Redis, timing the round trip
 	redisHealthy := true
-	if err := h.queue.HealthCheck(ctx); err != nil {
+	var redisLatencyMs *float64
+	if latency, err := h.queue.Ping(ctx); err != nil {
 		redisHealthy = false
+	} else {
+		ms := float64(latency.Microseconds()) / 1000
+		redisLatencyMs = &ms
 	}
 
 	// Get queue stats
@@ -59,7 +63,8 @@ func (h *HealthHandler) HealthCheck(c *fiber.Ctx) error {
 			"immediate": immediateQueueLength,
 			"delayed":   delayedQueueLength,
 		},
-		"timestamp": time.Now().Format(time.RFC3339),
+		"redis_latency_ms": redisLatencyMs, // null when Redis is unreachable
+		"timestamp":        time.Now().Format(time.RFC3339),
 	})
 }
 
@@ -2,44 +2,314 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/acceptance"
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
 	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/Sambit-Mondal/karbos/server/internal/worker"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
+// jobStore persists and reads jobs
+type jobStore interface {
+	CreateJob(ctx context.Context, job *models.Job) error
+	CreateJobsBatch(ctx context.Context, jobs []*models.Job) error
+	GetJobByID(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	QueryJobs(ctx context.Context, filter database.JobFilter) ([]*models.Job, error)
+	SaveScheduleWindows(ctx context.Context, id uuid.UUID, windows []models.ScheduleWindow) error
+	UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error
+	TransitionJobStatus(ctx context.Context, id uuid.UUID, from, to models.JobStatus) error
+	SoftDelete(ctx context.Context, id uuid.UUID) (bool, error)
+	GetAverageDurationByImage(ctx context.Context, image string) (time.Duration, error)
+	AddJobDependencies(ctx context.Context, jobID uuid.UUID, dependsOn []uuid.UUID) error
+	GetJobDependencies(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error)
+	AddJobLabels(ctx context.Context, jobID uuid.UUID, labels map[string]string) error
+	GetJobLabels(ctx context.Context, jobID uuid.UUID) (map[string]string, error)
+}
+
+// defaultEstimatedDuration is assumed for jobs without an estimate or run history
+const defaultEstimatedDuration = 10 * time.Minute
+
+// jobQueue routes submitted jobs to the immediate or delayed queue
+type jobQueue interface {
+	EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error
+	EnqueueDelayed(ctx context.Context, item *queue.QueueItem) error
+}
+
+// cancelQueue withdraws delayed jobs and signals workers to stop running ones
+type cancelQueue interface {
+	RemoveFromDelayed(ctx context.Context, jobID string) error
+	PublishJobCancel(ctx context.Context, jobID string) (int64, error)
+}
+
+// waitingJobs holds the queue items of jobs waiting on dependencies
+type waitingJobs interface {
+	HoldWaiting(ctx context.Context, item *queue.QueueItem) error
+	TakeWaiting(ctx context.Context, jobID string) (*queue.QueueItem, error)
+}
+
+// dependencyResolver queues or fails WAITING jobs as the jobs they depend on finish
+type dependencyResolver interface {
+	Check(ctx context.Context, jobID uuid.UUID) error
+	JobFinished(ctx context.Context, jobID uuid.UUID) error
+}
+
+// maxDependencies caps the jobs one submission may depend on
+const maxDependencies = 50
+
+// Job label limits, matching the job_labels columns
+const (
+	maxLabels           = 20
+	maxLabelValueLength = 255
+)
+
+// labelKeyPattern matches label keys (e.g. "team", "cost-center", "app.kubernetes.io")
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// validateLabels checks the labels of a submission
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("labels may have at most %d entries", maxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("label key %q must be 1-63 letters, digits, '_', '.' or '-', starting with a letter or digit", key)
+		}
+		if value == "" || utf8.RuneCountInString(value) > maxLabelValueLength {
+			return fmt.Errorf("label %q must have a value of 1-%d characters", key, maxLabelValueLength)
+		}
+	}
+	return nil
+}
+
+// idempotencyStore remembers submissions by their client-supplied idempotency key
+type idempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, userID, key string, record *queue.IdempotencyRecord, ttl time.Duration) (*queue.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, record *queue.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+}
+
+// Idempotency keys are remembered for a day, which covers any sane retry policy
+const (
+	idempotencyTTL          = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+)
+
+// executionLogReader reads a job's execution history
+type executionLogReader interface {
+	GetAllExecutionLogsByJobID(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]*models.ExecutionLog, int, error)
+}
+
 // JobHandler handles job-related HTTP requests
 type JobHandler struct {
-	jobRepo   *database.JobRepository
-	queue     *queue.RedisQueue
-	scheduler *scheduler.CarbonScheduler
+	jobRepo       jobStore
+	queue         jobQueue
+	cancels       cancelQueue
+	waiting       waitingJobs
+	dependencies  dependencyResolver // Optional: settles WAITING jobs the API finds ready, or whose dependency it cancels
+	idempotency   idempotencyStore   // Optional: replays submissions that reuse an Idempotency-Key
+	scheduler     *scheduler.CarbonScheduler
+	executionLogs executionLogReader // Optional: serves GET /api/jobs/:id/logs
+
+	submissionWindows *acceptance.Schedule    // Optional: submissions are refused outside these windows
+	volumes           *docker.VolumeAllowlist // Optional: host paths and volumes jobs may mount; nil refuses all mounts
+	images            *docker.ImagePolicy     // Optional: registries and images jobs may run; nil allows any valid image
+	networkAccess     bool                    // Jobs may ask for network access
+	execution         ExecutionDefaults       // Worker settings dry runs estimate limits and emissions with
+
+	legacyCreatedStatus bool // Always answer submissions with 201, even when deferred
+
+	baseCtx context.Context // Optional: parent of scheduling calls, cancelled on shutdown
+}
+
+// explainedSubmitResponse is a dry-run response with the scheduler's decision trace attached.
+// Explain is null when no scheduler is configured or scheduling failed.
+type explainedSubmitResponse struct {
+	models.SubmitJobResponse
+	Explain *scheduler.DecisionTrace `json:"explain"`
+}
+
+// jobDetailResponse is a job with its stored command decoded into arguments
+type jobDetailResponse struct {
+	*models.Job
+	CommandArgs  []string `json:"command_args,omitempty"`
+	CommandError string   `json:"command_error,omitempty"`
 }
 
 // NewJobHandler creates a new job handler
 func NewJobHandler(jobRepo *database.JobRepository, queue *queue.RedisQueue, scheduler *scheduler.CarbonScheduler) *JobHandler {
 	return &JobHandler{
-		jobRepo:   jobRepo,
-		queue:     queue,
-		scheduler: scheduler,
+		jobRepo:     jobRepo,
+		queue:       queue,
+		cancels:     queue,
+		waiting:     queue,
+		idempotency: queue,
+		scheduler:   scheduler,
+	}
+}
+
+// SetLegacyCreatedStatus makes SubmitJob return 201 Created for deferred jobs too,
+// for clients that predate the 202 Accepted response
+func (h *JobHandler) SetLegacyCreatedStatus(enabled bool) {
+	h.legacyCreatedStatus = enabled
+}
+
+// SetExecutionLogs enables GET /api/jobs/:id/logs
+func (h *JobHandler) SetExecutionLogs(repo *database.ExecutionLogRepository) {
+	if repo != nil {
+		h.executionLogs = repo
+	}
+}
+
+// SetSubmissionWindows makes SubmitJob refuse jobs outside the schedule's windows
+func (h *JobHandler) SetSubmissionWindows(schedule *acceptance.Schedule) {
+	h.submissionWindows = schedule
+}
+
+// SetVolumeAllowlist lets submissions request the allowlist's mounts. Workers check
+// the same list again, following symlinks, before mounting anything.
+func (h *JobHandler) SetVolumeAllowlist(allowlist *docker.VolumeAllowlist) {
+	h.volumes = allowlist
+}
+
+// SetNetworkAccess lets submissions ask for network access. Workers refuse such jobs
+// too unless they have a network access mode configured.
+func (h *JobHandler) SetNetworkAccess(allowed bool) {
+	h.networkAccess = allowed
+}
+
+// ExecutionDefaults are the worker settings a dry run's estimate assumes. The API can't
+// see the workers, so these should mirror their configuration.
+type ExecutionDefaults struct {
+	Limits        docker.ResourceLimits // DOCKER_MEMORY_LIMIT and DOCKER_CPU_QUOTA
+	MaxLimits     docker.ResourceLimits // DOCKER_MAX_MEMORY_LIMIT and DOCKER_MAX_CPU_QUOTA
+	Timeout       time.Duration         // WORKER_JOB_TIMEOUT (0 = the workers' 10m default)
+	ImageProfiles worker.ImageProfiles  // WORKER_IMAGE_PROFILES
+	PowerWatts    int                   // Assumed draw of a running job (0 = 50W)
+}
+
+// Defaults assumed by dry-run estimates when ExecutionDefaults leaves them unset
+const (
+	defaultJobTimeout = 10 * time.Minute
+	defaultPowerWatts = 50
+)
+
+// SetExecutionDefaults sets the worker settings dry runs estimate a job's limits,
+// timeout and emissions with
+func (h *JobHandler) SetExecutionDefaults(defaults ExecutionDefaults) {
+	h.execution = defaults
+}
+
+// SetImagePolicy restricts the images submissions may run to those the policy allows
+func (h *JobHandler) SetImagePolicy(policy *docker.ImagePolicy) {
+	h.images = policy
+}
+
+// SetDependencyResolver lets the API release jobs whose dependencies finished while they
+// were being submitted, and fail the dependents of jobs it cancels
+func (h *JobHandler) SetDependencyResolver(resolver *worker.DependencyResolver) {
+	if resolver != nil {
+		h.dependencies = resolver
 	}
 }
 
+// SetBaseContext makes carbon scheduling calls children of ctx, so cancelling it on
+// shutdown aborts outstanding carbon API calls instead of waiting out their timeout
+func (h *JobHandler) SetBaseContext(ctx context.Context) {
+	h.baseCtx = ctx
+}
+
+// baseContext returns the context scheduling calls derive from
+func (h *JobHandler) baseContext() context.Context {
+	if h.baseCtx == nil {
+		return context.Background()
+	}
+	return h.baseCtx
+}
+
+// submission is a validated job submission with its parsed options
+type submission struct {
+	req          *models.SubmitJobRequest
+	jobID        uuid.UUID
+	requestID    string // X-Request-ID of the submission; empty for server-made submissions
+	deadline     time.Time
+	priority     int
+	maxIntensity float64
+	dryRun       bool // Schedule only; nothing is saved or queued
+	explain      bool // Keep the scheduler's decision trace (dry runs only)
+	dependsOn    []uuid.UUID
+	metadata     models.JobMetadata // Parsed and normalized from req.Metadata
+}
+
+// submitResult is the outcome of a placed submission
+type submitResult struct {
+	response   models.SubmitJobResponse
+	statusCode int
+	trace      *scheduler.DecisionTrace
+}
+
+// invalidCommandShape is returned for a command that isn't an array of strings, such as
+// a bare string or an array holding numbers
+var invalidCommandShape = models.ErrorResponse{
+	Error:   "invalid_command",
+	Message: "command must be a non-empty array of strings, e.g. [\"echo\", \"hello\"]",
+	Code:    fiber.StatusBadRequest,
+}
+
+// isCommandTypeError reports whether a request body failed to parse because of a job's
+// command. The field path is e.g. "command", "command.1", or "0.command" in a batch.
+func isCommandTypeError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr) && slices.Contains(strings.Split(typeErr.Field, "."), "command")
+}
+
 // SubmitJob handles POST /api/submit
 func (h *JobHandler) SubmitJob(c *fiber.Ctx) error {
 	var req models.SubmitJobRequest
 
+	// Everything logged for this submission, here and in the scheduler, carries its request ID
+	requestID := c.GetRespHeader(fiber.HeaderXRequestID)
+	reqCtx := logging.WithRequestID(context.Background(), requestID)
+
 	// Check for dry-run mode
 	dryRun := c.Query("dry_run") == "true"
+	// Explain mode attaches the scheduler's decision trace (dry runs only)
+	explain := dryRun && c.Query("explain") == "true"
+
+	// Refuse jobs outside the acceptance windows, telling the client when to come back.
+	// Dry runs save nothing, so they are still answered and report the closed window.
+	now := time.Now()
+	windowClosed := !h.submissionWindows.Open(now)
+	if windowClosed && !dryRun {
+		return outsideAcceptanceWindow(c, h.submissionWindows.NextOpen(now), now)
+	}
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
-		log.Printf("Failed to parse request body: %v", err)
+		slog.WarnContext(reqCtx, "Failed to parse request body", logging.Err(err))
+		if isCommandTypeError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidCommandShape)
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
 			Error:   "invalid_request",
 			Message: "Invalid request body",
@@ -47,34 +317,485 @@ func (h *JobHandler) SubmitJob(c *fiber.Ctx) error {
 		})
 	}
 
+	sub, errResp := h.validateSubmission(&req)
+	if errResp != nil {
+		return c.Status(errResp.Code).JSON(errResp)
+	}
+	sub.requestID = requestID
+	sub.dryRun = dryRun
+	sub.explain = explain
+
+	// Retries carrying the same Idempotency-Key get the original job instead of a new one
+	idempotencyKey := c.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = req.IdempotencyKey
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "validation_error",
+			Message: fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	var idempotencyRecord *queue.IdempotencyRecord // Set while this submission holds its key
+	if idempotencyKey != "" && !dryRun && h.idempotency != nil {
+		record := &queue.IdempotencyRecord{JobID: sub.jobID.String(), Fingerprint: requestFingerprint(req)}
+		existing, err := h.idempotency.ClaimIdempotencyKey(reqCtx, req.UserID, idempotencyKey, record, idempotencyTTL)
+		switch {
+		case err != nil:
+			slog.WarnContext(reqCtx, "Idempotency check failed, submitting without it", logging.Err(err))
+		case existing != nil:
+			return replaySubmission(c, existing, record.Fingerprint)
+		default:
+			idempotencyRecord = record
+		}
+	}
+
+	result, errResp := h.submit(reqCtx, sub)
+	if errResp != nil {
+		if idempotencyRecord != nil {
+			if err := h.idempotency.ReleaseIdempotencyKey(reqCtx, req.UserID, idempotencyKey); err != nil {
+				slog.WarnContext(reqCtx, "Failed to release idempotency key", logging.Err(err))
+			}
+		}
+		return c.Status(errResp.Code).JSON(errResp)
+	}
+
+	if dryRun {
+		if windowClosed {
+			result.response.OutsideAcceptanceWindow = true
+			if next := h.submissionWindows.NextOpen(now); !next.IsZero() {
+				result.response.NextAcceptanceWindow = &next
+			}
+		}
+		if explain {
+			return c.JSON(explainedSubmitResponse{SubmitJobResponse: result.response, Explain: result.trace})
+		}
+		return c.JSON(result.response)
+	}
+
+	// Remember the response for retries with the same key
+	if idempotencyRecord != nil {
+		idempotencyRecord.Response, _ = json.Marshal(result.response)
+		if err := h.idempotency.CompleteIdempotencyKey(reqCtx, req.UserID, idempotencyKey, idempotencyRecord); err != nil {
+			slog.WarnContext(reqCtx, "Failed to record idempotent response", logging.KeyJobID, result.response.JobID, logging.Err(err))
+		}
+	}
+
+	return c.Status(result.statusCode).JSON(result.response)
+}
+
+// Batch submission limits
+const (
+	maxBatchSize             = 100 // Jobs per POST /api/submit/batch
+	batchScheduleConcurrency = 8   // Jobs of a batch scheduled at once
+)
+
+// SubmitBatch handles POST /api/submit/batch
+// The body is an array of job specs as for POST /api/submit. Each job is validated and
+// scheduled on its own and the accepted ones are saved in a single transaction, so one
+// rejected job doesn't fail the rest; every job's outcome is reported in order.
+func (h *JobHandler) SubmitBatch(c *fiber.Ctx) error {
+	requestID := c.GetRespHeader(fiber.HeaderXRequestID)
+	reqCtx := logging.WithRequestID(context.Background(), requestID)
+
+	if now := time.Now(); !h.submissionWindows.Open(now) {
+		return outsideAcceptanceWindow(c, h.submissionWindows.NextOpen(now), now)
+	}
+
+	var reqs []models.SubmitJobRequest
+	if err := c.BodyParser(&reqs); err != nil {
+		slog.WarnContext(reqCtx, "Failed to parse batch request body", logging.Err(err))
+		if isCommandTypeError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidCommandShape)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Request body must be an array of jobs",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+	if len(reqs) == 0 || len(reqs) > maxBatchSize {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_batch",
+			Message: fmt.Sprintf("A batch must contain between 1 and %d jobs", maxBatchSize),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	results := make([]models.BatchSubmitResult, len(reqs))
+	plans := make([]*plannedJob, len(reqs))
+
+	// Schedule the valid jobs concurrently, a bounded number at a time
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchScheduleConcurrency)
+	for i := range reqs {
+		results[i].Index = i
+		if reqs[i].IdempotencyKey != "" {
+			results[i].Error = &models.ErrorResponse{
+				Error:   "validation_error",
+				Message: "idempotency_key is not supported in batch submissions",
+				Code:    fiber.StatusBadRequest,
+			}
+			continue
+		}
+		sub, errResp := h.validateSubmission(&reqs[i])
+		if errResp != nil {
+			results[i].Error = errResp
+			continue
+		}
+		sub.requestID = requestID
+
+		wg.Add(1)
+		go func(i int, sub *submission) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			plans[i], results[i].Error = h.plan(reqCtx, sub)
+		}(i, sub)
+	}
+	wg.Wait()
+
+	// Save every scheduled job at once
+	var jobs []*models.Job
+	for _, p := range plans {
+		if p != nil {
+			jobs = append(jobs, p.job)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(reqCtx, 10*time.Second)
+	defer cancel()
+
+	if err := h.jobRepo.CreateJobsBatch(ctx, jobs); err != nil {
+		slog.ErrorContext(reqCtx, "Failed to create batch jobs in database", "jobs", len(jobs), logging.Err(err))
+		for i, p := range plans {
+			if p != nil {
+				plans[i] = nil
+				results[i].Error = &models.ErrorResponse{
+					Error:   "database_error",
+					Message: "Failed to create job",
+					Code:    fiber.StatusInternalServerError,
+				}
+			}
+		}
+	}
+
+	response := models.BatchSubmitResponse{Results: results}
+	for i, p := range plans {
+		if p != nil {
+			result, errResp := h.place(reqCtx, p)
+			if errResp != nil {
+				results[i].Error = errResp
+			} else {
+				results[i].Job = &result.response
+			}
+		}
+		if results[i].Error != nil {
+			response.Failed++
+		} else {
+			response.Submitted++
+		}
+	}
+
+	slog.InfoContext(reqCtx, "Batch submitted", "submitted", response.Submitted, "failed", response.Failed)
+
+	return c.JSON(response)
+}
+
+// Submit places a job the way POST /api/submit does, for submissions the server makes
+// itself (recurring schedules). A rejected submission is returned as an error.
+func (h *JobHandler) Submit(ctx context.Context, req *models.SubmitJobRequest) (*models.SubmitJobResponse, error) {
+	sub, errResp := h.validateSubmission(req)
+	if errResp != nil {
+		return nil, fmt.Errorf("%s: %s", errResp.Error, errResp.Message)
+	}
+
+	result, errResp := h.submit(ctx, sub)
+	if errResp != nil {
+		return nil, fmt.Errorf("%s: %s", errResp.Error, errResp.Message)
+	}
+	return &result.response, nil
+}
+
+// validateSubmission checks a submission's fields, returning the 400 response to send
+// for an invalid one
+func (h *JobHandler) validateSubmission(req *models.SubmitJobRequest) (*submission, *models.ErrorResponse) {
 	// Validate required fields
 	if req.UserID == "" || req.DockerImage == "" || req.Deadline == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Error:   "validation_error",
 			Message: "user_id, docker_image, and deadline are required",
 			Code:    fiber.StatusBadRequest,
-		})
+		}
+	}
+
+	// Refuse malformed or disallowed images now rather than on a failed pull
+	if err := h.images.Check(req.DockerImage); err != nil {
+		code := "invalid_image"
+		if errors.Is(err, docker.ErrImageNotAllowed) {
+			code = "image_not_allowed"
+		}
+		return nil, &models.ErrorResponse{
+			Error:   code,
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	if err := models.ValidateCommand(req.Command); err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_command",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+	if err := models.ValidateEntrypoint(req.Entrypoint); err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+	if err := models.ValidateSteps(req.Steps, req.Command); err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_command",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	metadata, err := models.ParseJobMetadata(req.Metadata)
+	if err == nil {
+		err = metadata.Normalize()
+	}
+	if err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_metadata",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
 	}
 
 	// Parse deadline
 	deadline, err := time.Parse(time.RFC3339, req.Deadline)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Error:   "invalid_deadline",
 			Message: "Deadline must be in ISO 8601 format (e.g., 2025-12-05T18:00:00Z)",
 			Code:    fiber.StatusBadRequest,
-		})
+		}
 	}
 
 	// Validate deadline is in the future
 	if deadline.Before(time.Now()) {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Error:   "invalid_deadline",
 			Message: "Deadline must be in the future",
 			Code:    fiber.StatusBadRequest,
-		})
+		}
 	}
 
+	// Validate optional resource limits
+	if req.MemoryLimitMB != nil && *req.MemoryLimitMB <= 0 {
+		return nil, &models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "memory_limit_mb must be a positive number of megabytes",
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+	if req.CPUQuota != nil && *req.CPUQuota <= 0 {
+		return nil, &models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "cpu_quota must be positive (100000 = one CPU)",
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	if req.SuccessOutputTailBytes != nil && *req.SuccessOutputTailBytes < 0 {
+		return nil, &models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "success_output_tail_bytes must be zero (keep all output) or a positive number of bytes",
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	if err := h.volumes.Check(req.Volumes); err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_volume",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	if req.NetworkAccess && !h.networkAccess {
+		return nil, &models.ErrorResponse{
+			Error:   "network_not_allowed",
+			Message: "network_access is disabled (DOCKER_NETWORK_ACCESS_MODE is empty)",
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	// Validate optional priority
+	priority := 0
+	if req.Priority != nil {
+		if *req.Priority < queue.MinPriority || *req.Priority > queue.MaxPriority {
+			return nil, &models.ErrorResponse{
+				Error:   "validation_error",
+				Message: fmt.Sprintf("priority must be between %d and %d", queue.MinPriority, queue.MaxPriority),
+				Code:    fiber.StatusBadRequest,
+			}
+		}
+		priority = *req.Priority
+	}
+
+	// Validate optional per-job intensity threshold
+	var maxIntensity float64
+	if req.MaxIntensity != nil {
+		if *req.MaxIntensity <= 0 || *req.MaxIntensity > scheduler.MaxIntensityCeiling {
+			return nil, &models.ErrorResponse{
+				Error:   "validation_error",
+				Message: fmt.Sprintf("max_intensity must be greater than 0 and at most %.0f gCO2eq/kWh", scheduler.MaxIntensityCeiling),
+				Code:    fiber.StatusBadRequest,
+			}
+		}
+		maxIntensity = *req.MaxIntensity
+	}
+
+	// Validate optional dependencies; they are looked up when the job is placed
+	if len(req.DependsOn) > maxDependencies {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_dependency",
+			Message: fmt.Sprintf("depends_on may list at most %d jobs", maxDependencies),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+	var dependsOn []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(req.DependsOn))
+	for _, raw := range req.DependsOn {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, &models.ErrorResponse{
+				Error:   "invalid_dependency",
+				Message: fmt.Sprintf("depends_on entry %q is not a job ID", raw),
+				Code:    fiber.StatusBadRequest,
+			}
+		}
+		if !seen[id] {
+			seen[id] = true
+			dependsOn = append(dependsOn, id)
+		}
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		return nil, &models.ErrorResponse{
+			Error:   "invalid_label",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	return &submission{
+		req:          req,
+		jobID:        uuid.New(),
+		deadline:     deadline,
+		priority:     priority,
+		maxIntensity: maxIntensity,
+		dependsOn:    dependsOn,
+		metadata:     metadata,
+	}, nil
+}
+
+// submit schedules a validated submission and, unless it is a dry run, saves and queues
+// the job. The error response is returned when the job couldn't be created.
+func (h *JobHandler) submit(reqCtx context.Context, sub *submission) (*submitResult, *models.ErrorResponse) {
+	p, errResp := h.plan(reqCtx, sub)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	// If dry-run mode, return prediction without saving
+	if sub.dryRun {
+		response := p.response()
+		response.Message = "Dry run - job not created"
+		response.Estimate = h.estimateExecution(p)
+
+		slog.InfoContext(reqCtx, "Dry run completed", logging.KeyRegion, p.region, "immediate", p.immediate, "savings", p.carbonSavings)
+		return &submitResult{response: response, statusCode: fiber.StatusOK, trace: p.trace}, nil
+	}
+
+	// Save to database
+	ctx, cancel := context.WithTimeout(reqCtx, 5*time.Second)
+	defer cancel()
+
+	if err := h.jobRepo.CreateJob(ctx, p.job); err != nil {
+		slog.ErrorContext(reqCtx, "Failed to create job in database", logging.Err(err))
+		return nil, &models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to create job",
+			Code:    fiber.StatusInternalServerError,
+		}
+	}
+
+	slog.InfoContext(reqCtx, "Created job in database", logging.KeyJobID, p.job.ID)
+
+	return h.place(reqCtx, p)
+}
+
+// plannedJob is a scheduled submission whose job is ready to be saved
+type plannedJob struct {
+	sub       *submission
+	job       *models.Job
+	region    string
+	waiting   bool // Held until its dependencies complete
+	immediate bool
+
+	expectedIntensity float64
+	carbonSavings     float64
+	intensityScale    string
+	carbonAware       bool
+	deferralCapped    bool // Scheduled within the max deferral rather than by the deadline
+	trace             *scheduler.DecisionTrace
+	windows           []models.ScheduleWindow // Chosen window and alternatives, persisted with the job
+
+	duration       time.Duration // Run time the job was scheduled for
+	durationSource string        // "request", "history" or "default"
+}
+
+// response describes the planned job to the client
+func (p *plannedJob) response() models.SubmitJobResponse {
+	plan := executionPlan(p.immediate)
+	if p.waiting {
+		plan = models.ExecutionPlanWaiting
+	}
+
+	return models.SubmitJobResponse{
+		JobID:             p.job.ID.String(),
+		Status:            p.job.Status,
+		CreatedAt:         p.job.CreatedAt,
+		ExecutionPlan:     plan,
+		ScheduledTime:     p.job.ScheduledTime.Format(time.RFC3339),
+		Immediate:         p.immediate,
+		ExpectedIntensity: p.expectedIntensity,
+		CarbonSavings:     p.carbonSavings,
+		IntensityScale:    p.intensityScale,
+		CarbonAware:       p.carbonAware,
+		DeferralCapped:    p.deferralCapped,
+		Message:           "Job submitted successfully",
+
+		AlternativeWindows: alternativeWindows(p.windows),
+	}
+}
+
+// plan checks a submission's dependencies and schedules it, building the job to save
+func (h *JobHandler) plan(reqCtx context.Context, sub *submission) (*plannedJob, *models.ErrorResponse) {
+	req := sub.req
+
+	// Urgent jobs can opt out of carbon-aware scheduling and run right away
+	carbonAware := req.CarbonAware == nil || *req.CarbonAware
+
 	// Set default region if not provided
 	region := "US-EAST" // Default region
 	if req.Region != nil && *req.Region != "" {
@@ -83,10 +804,17 @@ func (h *JobHandler) SubmitJob(c *fiber.Ctx) error {
 
 	// Determine estimated duration
 	var estimatedDuration time.Duration
+	durationSource := "request"
 	if req.EstimatedDuration != nil && *req.EstimatedDuration > 0 {
 		estimatedDuration = time.Duration(*req.EstimatedDuration) * time.Second
 	} else {
-		estimatedDuration = 10 * time.Minute // Default 10 minutes
+		estimatedDuration, durationSource = h.learnedDuration(reqCtx, req.DockerImage)
+	}
+
+	// A job with unfinished dependencies waits for them instead of being scheduled
+	waiting, errResp := h.checkDependencies(reqCtx, sub)
+	if errResp != nil {
+		return nil, errResp
 	}
 
 	// Carbon-aware scheduling
@@ -94,158 +822,546 @@ func (h *JobHandler) SubmitJob(c *fiber.Ctx) error {
 	var immediate bool = true
 	var expectedIntensity float64 = 0
 	var carbonSavings float64 = 0
+	var submissionIntensity *float64
+	var decisionIntensity, decisionSavings *float64 // Persisted only when the scheduler made the call
+	var intensityScale string
+	var deferralCapped bool
+	var trace *scheduler.DecisionTrace
+	var windows []models.ScheduleWindow // Chosen window and alternatives, persisted with the job
 
-	// Create context for scheduling
-	schedCtx, schedCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Create context for scheduling. It hangs off the base context so shutdown cuts
+	// the carbon calls short; the job is then saved for immediate execution as usual
+	schedCtx, schedCancel := context.WithTimeout(logging.WithRequestID(h.baseContext(), sub.requestID), 5*time.Second)
 	defer schedCancel()
 
-	if h.scheduler != nil {
+	if waiting {
+		immediate = false
+		slog.InfoContext(reqCtx, "Job waits for its dependencies, scheduling skipped", "dependencies", len(sub.dependsOn))
+	} else if !carbonAware {
+		slog.InfoContext(reqCtx, "Carbon-aware scheduling skipped (carbon_aware=false), running immediately")
+	} else if h.scheduler != nil {
 		// Create scheduling request
 		schedReq := &scheduler.ScheduleRequest{
 			Region:     region,
 			Duration:   estimatedDuration,
-			Deadline:   deadline,
+			Deadline:   sub.deadline,
 			WindowSize: 24 * time.Hour,
+			Explain:    sub.explain,
+
+			MaxIntensity: sub.maxIntensity,
 		}
 
 		// Get scheduling recommendation
 		schedResult, err := h.scheduler.Schedule(schedCtx, schedReq)
 		if err != nil {
-			log.Printf("⚠ Scheduling failed, defaulting to immediate: %v", err)
+			slog.WarnContext(reqCtx, "Scheduling failed, defaulting to immediate", logging.KeyRegion, region, logging.Err(err))
 			// Continue with immediate execution
 		} else {
 			scheduledTime = schedResult.ScheduledTime
 			immediate = schedResult.Immediate
 			expectedIntensity = schedResult.ExpectedIntensity
 			carbonSavings = schedResult.CarbonSavings
+			intensityScale = string(schedResult.IntensityScale)
+			deferralCapped = schedResult.DeferralCapped
+			trace = schedResult.Trace
+			windows = scheduleWindows(schedResult, estimatedDuration)
+
+			// A relative index (WattTime) isn't gCO2eq/kWh, so keep it out of the
+			// job's stored intensities and the CO2 savings built on them
+			if schedResult.IntensityScale != carbon.IntensityScaleRelative {
+				submissionIntensity = &schedResult.CurrentIntensity
+				decisionIntensity = &schedResult.ExpectedIntensity
+				decisionSavings = &schedResult.CarbonSavings
+			}
+
+			slog.InfoContext(reqCtx, "Carbon scheduling decided", logging.KeyRegion, region, "immediate", immediate,
+				"scheduled_time", scheduledTime, "savings", carbonSavings, "intensity_scale", intensityScale)
+		}
+	}
+
+	// If no scheduled time determined, use now
+	if scheduledTime.IsZero() {
+		scheduledTime = time.Now()
+	}
+
+	// Serialize command array to JSON string for database storage
+	var commandStr *string
+	if len(req.Command) > 0 {
+		cmdJSON, err := json.Marshal(req.Command)
+		if err != nil {
+			slog.ErrorContext(reqCtx, "Failed to serialize command", logging.Err(err))
+			return nil, &models.ErrorResponse{
+				Error:   "invalid_command",
+				Message: "Failed to process command",
+				Code:    fiber.StatusBadRequest,
+			}
+		}
+		cmdJSONStr := string(cmdJSON)
+		commandStr = &cmdJSONStr
+	}
+
+	status := models.JobStatusPending
+	if waiting {
+		status = models.JobStatusWaiting
+	}
+
+	// Create job object
+	job := &models.Job{
+		ID:                sub.jobID,
+		UserID:            req.UserID,
+		DockerImage:       req.DockerImage,
+		Command:           commandStr,
+		Status:            status,
+		Deadline:          sub.deadline,
+		EstimatedDuration: req.EstimatedDuration,
+		Region:            &region,
+		ScheduledTime:     &scheduledTime,
+		CreatedAt:         time.Now(),
+		Metadata:          sub.metadata,
+
+		SubmissionIntensity: submissionIntensity,
+		ExpectedIntensity:   decisionIntensity,
+		CarbonSavings:       decisionSavings,
+		CarbonOptOut:        !carbonAware,
+	}
+	if sub.requestID != "" {
+		job.RequestID = &sub.requestID
+	}
+
+	return &plannedJob{
+		sub:               sub,
+		job:               job,
+		region:            region,
+		waiting:           waiting,
+		immediate:         immediate,
+		expectedIntensity: expectedIntensity,
+		carbonSavings:     carbonSavings,
+		intensityScale:    intensityScale,
+		carbonAware:       carbonAware,
+		deferralCapped:    deferralCapped,
+		trace:             trace,
+		windows:           windows,
+		duration:          estimatedDuration,
+		durationSource:    durationSource,
+	}, nil
+}
+
+// place records the extras of a saved job and queues it, or holds it for its dependencies
+func (h *JobHandler) place(reqCtx context.Context, p *plannedJob) (*submitResult, *models.ErrorResponse) {
+	sub, job, req := p.sub, p.job, p.sub.req
+	scheduledTime := *job.ScheduledTime
+
+	ctx, cancel := context.WithTimeout(reqCtx, 5*time.Second)
+	defer cancel()
+
+	if len(p.windows) > 0 {
+		if err := h.jobRepo.SaveScheduleWindows(ctx, job.ID, p.windows); err != nil {
+			slog.WarnContext(reqCtx, "Failed to save schedule windows", logging.KeyJobID, job.ID, logging.Err(err))
+		}
+	}
+	if len(req.Labels) > 0 {
+		if err := h.jobRepo.AddJobLabels(ctx, job.ID, req.Labels); err != nil {
+			slog.WarnContext(reqCtx, "Failed to save job labels", logging.KeyJobID, job.ID, logging.Err(err))
+		}
+	}
+
+	// Create queue item
+	queueItem := &queue.QueueItem{
+		JobID:         job.ID.String(),
+		UserID:        job.UserID,
+		DockerImage:   job.DockerImage,
+		Command:       job.Command,
+		Entrypoint:    req.Entrypoint,
+		Steps:         req.Steps,
+		ScheduledTime: scheduledTime,
+		Deadline:      job.Deadline,
+		Priority:      sub.priority,
+		Region:        p.region,
+		RequestID:     sub.requestID,
+
+		SuccessOutputTailBytes: req.SuccessOutputTailBytes,
+		Volumes:                req.Volumes,
+		NetworkAccess:          req.NetworkAccess,
+	}
+	if req.MemoryLimitMB != nil {
+		queueItem.MemoryLimitMB = *req.MemoryLimitMB
+	}
+	if req.CPUQuota != nil {
+		queueItem.CPUQuota = *req.CPUQuota
+	}
+
+	// Hold a waiting job until its dependencies complete, else route to the
+	// appropriate queue based on the scheduling decision
+	if p.waiting {
+		if err := h.holdWaiting(ctx, sub, queueItem); err != nil {
+			slog.ErrorContext(reqCtx, "Failed to record job dependencies", logging.KeyJobID, job.ID, logging.Err(err))
+			if err := h.jobRepo.UpdateJobStatusChecked(ctx, job.ID, models.JobStatusFailed); err != nil {
+				slog.WarnContext(reqCtx, "Failed to fail job without dependencies", logging.KeyJobID, job.ID, logging.Err(err))
+			}
+			return nil, &models.ErrorResponse{
+				Error:   "database_error",
+				Message: "Failed to record job dependencies",
+				Code:    fiber.StatusInternalServerError,
+			}
+		}
+	} else if p.immediate {
+		// Push to Redis immediate queue (FIFO List)
+		if err := h.queue.EnqueueImmediate(ctx, queueItem); err != nil {
+			slog.ErrorContext(reqCtx, "Failed to enqueue immediate job", logging.KeyJobID, job.ID, logging.Err(err))
+		} else {
+			slog.InfoContext(reqCtx, "Job queued for immediate execution", logging.KeyJobID, job.ID)
+		}
+	} else {
+		// Push to Redis delayed queue (Sorted Set with scheduled_time as score)
+		if err := h.queue.EnqueueDelayed(ctx, queueItem); err != nil {
+			slog.ErrorContext(reqCtx, "Failed to enqueue delayed job", logging.KeyJobID, job.ID, logging.Err(err))
+		} else {
+			slog.InfoContext(reqCtx, "Job scheduled for later execution", logging.KeyJobID, job.ID, "scheduled_time", scheduledTime)
+		}
+	}
+
+	// Prepare response
+	response := p.response()
+
+	// 201 when the job is queued to run now; 202 when it has only been accepted for later
+	statusCode := fiber.StatusCreated
+	if !p.immediate {
+		response.Message = "Job scheduled for optimal carbon efficiency"
+		if p.waiting {
+			response.Message = "Job waiting for its dependencies to complete"
+		}
+		if !h.legacyCreatedStatus {
+			statusCode = fiber.StatusAccepted
+		}
+	}
+
+	slog.InfoContext(reqCtx, "Job submitted", logging.KeyJobID, job.ID, "user_id", job.UserID, "image", job.DockerImage, logging.KeyRegion, p.region)
+
+	return &submitResult{response: response, statusCode: statusCode}, nil
+}
+
+// checkDependencies looks up a submission's dependencies and reports whether any of
+// them has yet to complete. Unknown dependencies, those of other users, failed ones and
+// cycles are rejected.
+func (h *JobHandler) checkDependencies(reqCtx context.Context, sub *submission) (bool, *models.ErrorResponse) {
+	if len(sub.dependsOn) == 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(reqCtx, 5*time.Second)
+	defer cancel()
+
+	waiting := false
+	for _, id := range sub.dependsOn {
+		dependency, err := h.jobRepo.GetJobByID(ctx, id)
+		if err != nil && err.Error() != "job not found" {
+			slog.ErrorContext(reqCtx, "Failed to look up dependency", logging.KeyJobID, id, logging.Err(err))
+			return false, &models.ErrorResponse{
+				Error:   "database_error",
+				Message: "Failed to look up dependencies",
+				Code:    fiber.StatusInternalServerError,
+			}
+		}
+		// Other users' jobs are reported as missing rather than revealed
+		if err != nil || dependency.UserID != sub.req.UserID {
+			return false, &models.ErrorResponse{
+				Error:   "invalid_dependency",
+				Message: fmt.Sprintf("Dependency %s not found", id),
+				Code:    fiber.StatusBadRequest,
+			}
+		}
+
+		switch dependency.Status {
+		case models.JobStatusCompleted:
+		case models.JobStatusFailed, models.JobStatusCancelled:
+			return false, &models.ErrorResponse{
+				Error:   "dependency_failed",
+				Message: fmt.Sprintf("Dependency %s already finished with status %s", id, dependency.Status),
+				Code:    fiber.StatusConflict,
+			}
+		default:
+			waiting = true
+		}
+	}
+
+	cyclic, err := h.dependsOnItself(ctx, sub.jobID, sub.dependsOn)
+	if err != nil {
+		slog.ErrorContext(reqCtx, "Failed to check dependencies for cycles", logging.Err(err))
+		return false, &models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to look up dependencies",
+			Code:    fiber.StatusInternalServerError,
+		}
+	}
+	if cyclic {
+		return false, &models.ErrorResponse{
+			Error:   "dependency_cycle",
+			Message: "depends_on would make the job wait on itself",
+			Code:    fiber.StatusBadRequest,
+		}
+	}
+
+	return waiting, nil
+}
+
+// dependsOnItself walks the dependency graph from dependsOn and reports whether it leads
+// back to jobID
+func (h *JobHandler) dependsOnItself(ctx context.Context, jobID uuid.UUID, dependsOn []uuid.UUID) (bool, error) {
+	visited := make(map[uuid.UUID]bool)
+	pending := append([]uuid.UUID(nil), dependsOn...)
+
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
 
-			log.Printf("✓ Carbon scheduling: immediate=%v, scheduled=%v, savings=%.2f gCO2eq/kWh",
-				immediate, scheduledTime.Format(time.RFC3339), carbonSavings)
+		if id == jobID {
+			return true, nil
 		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		next, err := h.jobRepo.GetJobDependencies(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		pending = append(pending, next...)
 	}
 
-	// If no scheduled time determined, use now
-	if scheduledTime.IsZero() {
-		scheduledTime = time.Now()
+	return false, nil
+}
+
+// holdWaiting records a waiting job's dependencies and holds its queue item. A
+// dependency that finished in the meantime has already looked for dependents, so the
+// job is checked once more here.
+func (h *JobHandler) holdWaiting(ctx context.Context, sub *submission, item *queue.QueueItem) error {
+	if err := h.jobRepo.AddJobDependencies(ctx, sub.jobID, sub.dependsOn); err != nil {
+		return err
+	}
+	if err := h.waiting.HoldWaiting(ctx, item); err != nil {
+		return err
 	}
 
-	// Serialize command array to JSON string for database storage
-	var commandStr *string
-	if len(req.Command) > 0 {
-		cmdJSON, err := json.Marshal(req.Command)
-		if err != nil {
-			log.Printf("Failed to serialize command: %v", err)
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-				Error:   "invalid_command",
-				Message: "Failed to process command",
-				Code:    fiber.StatusBadRequest,
-			})
+	if h.dependencies != nil {
+		if err := h.dependencies.Check(ctx, sub.jobID); err != nil {
+			slog.WarnContext(ctx, "Failed to check dependencies of waiting job", logging.KeyJobID, sub.jobID, logging.Err(err))
 		}
-		cmdJSONStr := string(cmdJSON)
-		commandStr = &cmdJSONStr
 	}
+	return nil
+}
 
-	// Create job object
-	job := &models.Job{
-		ID:                uuid.New(),
-		UserID:            req.UserID,
-		DockerImage:       req.DockerImage,
-		Command:           commandStr,
-		Status:            models.JobStatusPending,
-		Deadline:          deadline,
-		EstimatedDuration: req.EstimatedDuration,
-		Region:            &region,
-		ScheduledTime:     &scheduledTime,
-		CreatedAt:         time.Now(),
-		Metadata:          "{}",
+// requestFingerprint identifies a submission's payload, so that a reused idempotency
+// key can be told apart from a retry
+func requestFingerprint(req models.SubmitJobRequest) string {
+	req.IdempotencyKey = ""
+	payload, _ := json.Marshal(req)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// replaySubmission answers a submission whose idempotency key is already taken: with
+// the original response for a retry, or 409 when the key was used for another payload
+// or its submission hasn't finished
+func replaySubmission(c *fiber.Ctx, existing *queue.IdempotencyRecord, fingerprint string) error {
+	if existing.Fingerprint != fingerprint {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "idempotency_key_reused",
+			Message: "Idempotency-Key was already used for a different job submission",
+			Code:    fiber.StatusConflict,
+		})
+	}
+	if len(existing.Response) == 0 {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "idempotency_in_progress",
+			Message: "A submission with this Idempotency-Key is still being processed",
+			Code:    fiber.StatusConflict,
+		})
 	}
 
-	// If dry-run mode, return prediction without saving
-	if dryRun {
-		response := models.SubmitJobResponse{
-			JobID:             job.ID.String(),
-			Status:            models.JobStatusPending,
-			CreatedAt:         job.CreatedAt,
-			ScheduledTime:     scheduledTime.Format(time.RFC3339),
-			Immediate:         immediate,
-			ExpectedIntensity: expectedIntensity,
-			CarbonSavings:     carbonSavings,
-			Message:           "Dry run - job not created",
-		}
+	c.Set("Idempotent-Replayed", "true")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Status(fiber.StatusOK).Send(existing.Response)
+}
 
-		log.Printf("✓ Dry run completed: immediate=%v, savings=%.2f gCO2eq/kWh", immediate, carbonSavings)
-		return c.JSON(response)
+// executionPlan names the plan reported for a scheduling decision
+func executionPlan(immediate bool) string {
+	if immediate {
+		return models.ExecutionPlanImmediate
 	}
+	return models.ExecutionPlanScheduled
+}
 
-	// Save to database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetJob handles GET /api/jobs/:id
+func (h *JobHandler) GetJob(c *fiber.Ctx) error {
+	// Parse job ID from URL params
+	idParam := c.Params("id")
+	jobID, err := uuid.Parse(idParam)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	// Retrieve job from database
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	if err := h.jobRepo.CreateJob(ctx, job); err != nil {
-		log.Printf("Failed to create job in database: %v", err)
+	job, err := h.jobRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		if err.Error() == "job not found" {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "not_found",
+				Message: "Job not found",
+				Code:    fiber.StatusNotFound,
+			})
+		}
+
+		slog.Error("Failed to get job", logging.KeyJobID, jobID, logging.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "database_error",
-			Message: "Failed to create job",
+			Message: "Failed to retrieve job",
 			Code:    fiber.StatusInternalServerError,
 		})
 	}
 
-	log.Printf("✓ Created job in database: %s", job.ID)
-
-	// Create queue item
-	queueItem := &queue.QueueItem{
-		JobID:         job.ID.String(),
-		DockerImage:   job.DockerImage,
-		Command:       job.Command,
-		ScheduledTime: scheduledTime,
-		Priority:      0,
+	if dependsOn, err := h.jobRepo.GetJobDependencies(ctx, jobID); err != nil {
+		slog.Warn("Failed to get job dependencies", logging.KeyJobID, jobID, logging.Err(err))
+	} else {
+		job.DependsOn = dependsOn
 	}
-
-	// Route to appropriate queue based on scheduling decision
-	if immediate {
-		// Push to Redis immediate queue (FIFO List)
-		if err := h.queue.EnqueueImmediate(ctx, queueItem); err != nil {
-			log.Printf("Failed to enqueue immediate job: %v", err)
-		} else {
-			log.Printf("✓ Job queued for immediate execution: %s", job.ID)
-		}
+	if labels, err := h.jobRepo.GetJobLabels(ctx, jobID); err != nil {
+		slog.Warn("Failed to get job labels", logging.KeyJobID, jobID, logging.Err(err))
 	} else {
-		// Push to Redis delayed queue (Sorted Set with scheduled_time as score)
-		if err := h.queue.EnqueueDelayed(ctx, queueItem); err != nil {
-			log.Printf("Failed to enqueue delayed job: %v", err)
-		} else {
-			log.Printf("✓ Job scheduled for later execution at %s: %s",
-				scheduledTime.Format(time.RFC3339), job.ID)
-		}
+		job.Labels = labels
 	}
 
-	// Prepare response
-	response := models.SubmitJobResponse{
-		JobID:             job.ID.String(),
-		Status:            job.Status,
-		CreatedAt:         job.CreatedAt,
-		ScheduledTime:     scheduledTime.Format(time.RFC3339),
-		Immediate:         immediate,
-		ExpectedIntensity: expectedIntensity,
-		CarbonSavings:     carbonSavings,
-		Message:           "Job submitted successfully",
+	// Include the decoded command for display; legacy rows may be double-encoded
+	response := jobDetailResponse{Job: job}
+	if args, err := job.CommandArgs(); err != nil {
+		response.CommandError = err.Error()
+	} else {
+		response.CommandArgs = args
 	}
 
-	if !immediate {
-		response.Message = "Job scheduled for optimal carbon efficiency"
+	return c.JSON(response)
+}
+
+// CancelJob handles POST /api/jobs/:id/cancel. Queued jobs are cancelled on the spot.
+// For a running job the request is published to the worker running it, which stops the
+// container and marks the job CANCELLED; the response is 202 and the job stays RUNNING
+// until then. A job that finishes as the request arrives keeps its own final status.
+func (h *JobHandler) CancelJob(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
 	}
 
-	log.Printf("✓ Job submitted successfully: %s (UserID: %s, Image: %s)",
-		job.ID, job.UserID, job.DockerImage)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// A queued job may start running between the read and the write. The write only
+	// applies to the status that was read, so look again once and take the RUNNING path.
+	for attempt := 0; ; attempt++ {
+		job, err := h.jobRepo.GetJobByID(ctx, jobID)
+		if err != nil {
+			if err.Error() == "job not found" {
+				return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+					Error:   "not_found",
+					Message: "Job not found",
+					Code:    fiber.StatusNotFound,
+				})
+			}
+			slog.Error("Failed to get job", logging.KeyJobID, jobID, logging.Err(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "database_error",
+				Message: "Failed to retrieve job",
+				Code:    fiber.StatusInternalServerError,
+			})
+		}
+
+		status := job.Status
+		switch status {
+		case models.JobStatusPending, models.JobStatusDelayed, models.JobStatusWaiting:
+			err := h.jobRepo.TransitionJobStatus(ctx, jobID, status, models.JobStatusCancelled)
+			if errors.Is(err, models.ErrIllegalTransition) && attempt == 0 {
+				continue
+			}
+			if err != nil {
+				slog.Error("Failed to cancel job", logging.KeyJobID, jobID, logging.Err(err))
+				return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+					Error:   "not_cancellable",
+					Message: "Job changed state while being cancelled",
+					Code:    fiber.StatusConflict,
+				})
+			}
+
+			// Workers skip cancelled jobs, so a delayed entry left behind is harmless
+			if status == models.JobStatusDelayed {
+				if err := h.cancels.RemoveFromDelayed(ctx, jobID.String()); err != nil {
+					slog.Warn("Failed to remove cancelled job from the delayed queue", logging.KeyJobID, jobID, logging.Err(err))
+				}
+			}
+			if status == models.JobStatusWaiting {
+				if _, err := h.waiting.TakeWaiting(ctx, jobID.String()); err != nil {
+					slog.Warn("Failed to drop cancelled job's held queue entry", logging.KeyJobID, jobID, logging.Err(err))
+				}
+			}
+
+			// Jobs waiting on this one can no longer run
+			if h.dependencies != nil {
+				if err := h.dependencies.JobFinished(ctx, jobID); err != nil {
+					slog.Warn("Failed to fail jobs depending on cancelled job", logging.KeyJobID, jobID, logging.Err(err))
+				}
+			}
+
+			return c.JSON(models.CancelJobResponse{
+				JobID:   jobID.String(),
+				Status:  models.JobStatusCancelled,
+				Message: "Job cancelled",
+			})
+
+		case models.JobStatusRunning:
+			receivers, err := h.cancels.PublishJobCancel(ctx, jobID.String())
+			if err != nil {
+				slog.Error("Failed to publish cancel", logging.KeyJobID, jobID, logging.Err(err))
+				return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+					Error:   "queue_unavailable",
+					Message: "Failed to reach the worker running the job",
+					Code:    fiber.StatusServiceUnavailable,
+				})
+			}
+			if receivers == 0 {
+				slog.Warn("No worker received the cancel for running job", logging.KeyJobID, jobID)
+			}
+
+			return c.Status(fiber.StatusAccepted).JSON(models.CancelJobResponse{
+				JobID:   jobID.String(),
+				Status:  models.JobStatusRunning,
+				Message: "Cancellation requested; the worker is stopping the container",
+			})
 
-	return c.Status(fiber.StatusCreated).JSON(response)
+		default:
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+				Error:   "not_cancellable",
+				Message: fmt.Sprintf("Job already finished with status %s", status),
+				Code:    fiber.StatusConflict,
+			})
+		}
+	}
 }
 
-// GetJob handles GET /api/jobs/:id
-func (h *JobHandler) GetJob(c *fiber.Ctx) error {
-	// Parse job ID from URL params
-	idParam := c.Params("id")
-	jobID, err := uuid.Parse(idParam)
+// DeleteJob handles DELETE /api/jobs/:id
+// Soft-deletes a finished job: it disappears from listings but GET /api/jobs/:id still
+// returns it, flagged as deleted. Unfinished jobs must be cancelled first.
+func (h *JobHandler) DeleteJob(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
 			Error:   "invalid_id",
@@ -254,7 +1370,6 @@ func (h *JobHandler) GetJob(c *fiber.Ctx) error {
 		})
 	}
 
-	// Retrieve job from database
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -267,8 +1382,7 @@ func (h *JobHandler) GetJob(c *fiber.Ctx) error {
 				Code:    fiber.StatusNotFound,
 			})
 		}
-
-		log.Printf("Failed to get job: %v", err)
+		slog.Error("Failed to get job", logging.KeyJobID, jobID, logging.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "database_error",
 			Message: "Failed to retrieve job",
@@ -276,10 +1390,207 @@ func (h *JobHandler) GetJob(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(job)
+	// Deleting twice is harmless
+	if job.DeletedAt != nil {
+		return c.JSON(models.DeleteJobResponse{JobID: jobID.String(), DeletedAt: *job.DeletedAt, Message: "Job already deleted"})
+	}
+	if !job.Status.IsTerminal() {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "not_deletable",
+			Message: fmt.Sprintf("Job is %s; cancel it before deleting it", job.Status),
+			Code:    fiber.StatusConflict,
+		})
+	}
+
+	deleted, err := h.jobRepo.SoftDelete(ctx, jobID)
+	if err != nil {
+		slog.Error("Failed to delete job", logging.KeyJobID, jobID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to delete job",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "not_deletable",
+			Message: "Job changed state while being deleted",
+			Code:    fiber.StatusConflict,
+		})
+	}
+
+	slog.Info("Job deleted", logging.KeyJobID, jobID)
+	return c.JSON(models.DeleteJobResponse{JobID: jobID.String(), DeletedAt: time.Now(), Message: "Job deleted"})
+}
+
+// GetJobLogs handles GET /api/jobs/:id/logs
+// Returns a page of execution attempts in attempt order, including the worker that ran each.
+// Page with ?limit= (default 50, max 500) and ?offset=; total is the number of attempts.
+func (h *JobHandler) GetJobLogs(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	if h.executionLogs == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "not_configured",
+			Message: "Execution logs are not available",
+			Code:    fiber.StatusNotFound,
+		})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logs, total, err := h.executionLogs.GetAllExecutionLogsByJobID(ctx, jobID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get execution logs", logging.KeyJobID, jobID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to retrieve execution logs",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	if logs == nil {
+		logs = []*models.ExecutionLog{}
+	}
+
+	return c.JSON(fiber.Map{
+		"job_id": jobID.String(),
+		"logs":   logs,
+		"count":  len(logs),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// learnedDuration estimates a job's run time from the image's recently completed jobs,
+// falling back to defaultEstimatedDuration when the image has no history. The source
+// of the estimate, "history" or "default", is returned with it.
+func (h *JobHandler) learnedDuration(ctx context.Context, image string) (time.Duration, string) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	avg, err := h.jobRepo.GetAverageDurationByImage(lookupCtx, image)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to estimate duration from history, using default", "image", image, logging.Err(err))
+		return defaultEstimatedDuration, "default"
+	}
+	if avg <= 0 {
+		return defaultEstimatedDuration, "default"
+	}
+	slog.DebugContext(ctx, "Estimated duration from image history", "image", image, logging.KeyDuration, avg)
+	return avg, "history"
+}
+
+// estimateExecution previews a planned job's run: the limits and timeout a worker
+// would apply, and the CO2 it would emit at the expected intensity. Emissions are left
+// out when the scheduler gave no intensity or only a relative index.
+func (h *JobHandler) estimateExecution(p *plannedJob) *models.ExecutionEstimate {
+	req := p.sub.req
+	profile := h.execution.ImageProfiles.Match(req.DockerImage)
+
+	item := &queue.QueueItem{DockerImage: req.DockerImage}
+	if req.MemoryLimitMB != nil {
+		item.MemoryLimitMB = *req.MemoryLimitMB
+	}
+	if req.CPUQuota != nil {
+		item.CPUQuota = *req.CPUQuota
+	}
+	limits := docker.ClampLimits(h.execution.Limits, h.execution.MaxLimits, worker.ResourceLimits(item, profile))
+
+	timeout := profile.RunTimeout()
+	if timeout <= 0 {
+		timeout = h.execution.Timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultJobTimeout
+	}
+
+	watts := h.execution.PowerWatts
+	if watts <= 0 {
+		watts = defaultPowerWatts
+	}
+
+	estimate := &models.ExecutionEstimate{
+		EstimatedDurationSeconds: int(p.duration.Seconds()),
+		DurationSource:           p.durationSource,
+		MemoryLimitMB:            limits.MemoryBytes / (1024 * 1024),
+		CPUQuota:                 limits.CPUQuota,
+		TimeoutSeconds:           int(timeout.Seconds()),
+		AssumedPowerWatts:        watts,
+	}
+
+	intensity := p.expectedIntensity
+	if p.immediate && len(p.windows) > 0 {
+		intensity = p.windows[0].AvgIntensity // Running now, at the current intensity
+	}
+	if intensity > 0 && p.intensityScale != string(carbon.IntensityScaleRelative) {
+		grams := intensity * float64(watts) / 1000 * p.duration.Hours()
+		estimate.ProjectedCO2Grams = &grams
+	}
+	return estimate
+}
+
+// scheduleWindows lists the window a job was scheduled into followed by the
+// near-optimal alternatives the scheduler found
+func scheduleWindows(result *scheduler.ScheduleResult, duration time.Duration) []models.ScheduleWindow {
+	chosenIntensity := result.ExpectedIntensity
+	if result.Immediate {
+		chosenIntensity = result.CurrentIntensity
+	}
+
+	windows := []models.ScheduleWindow{{
+		StartTime:    result.ScheduledTime,
+		EndTime:      result.ScheduledTime.Add(duration),
+		AvgIntensity: chosenIntensity,
+		Chosen:       true,
+	}}
+	for _, alt := range result.AlternativeWindows {
+		windows = append(windows, models.ScheduleWindow{
+			StartTime:    alt.StartTime,
+			EndTime:      alt.EndTime,
+			AvgIntensity: alt.AvgIntensity,
+			CarbonCost:   alt.CarbonCost,
+		})
+	}
+	return windows
+}
+
+// alternativeWindows returns the windows other than the chosen one, never nil so the
+// response lists them as [] rather than null
+func alternativeWindows(windows []models.ScheduleWindow) []models.ScheduleWindow {
+	alternatives := make([]models.ScheduleWindow, 0, len(windows))
+	for _, window := range windows {
+		if !window.Chosen {
+			alternatives = append(alternatives, window)
+		}
+	}
+	return alternatives
 }
 
+// regionPattern matches well-formed region codes (e.g. "US-EAST", "DE", "CAISO_NORTH")
+var regionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
 // GetAllJobs handles GET /api/jobs
+// Optional filters: ?status=, ?region=, ?user_id=, and RFC3339 ?since= / ?until= bounds on created_at.
+// ?label=key:value may be repeated; jobs must carry every label given.
+// Pass the returned next_cursor as ?cursor= to fetch the following page.
 func (h *JobHandler) GetAllJobs(c *fiber.Ctx) error {
 	// Get limit from query params (default: 100)
 	limit := c.QueryInt("limit", 100)
@@ -287,13 +1598,28 @@ func (h *JobHandler) GetAllJobs(c *fiber.Ctx) error {
 		limit = 100
 	}
 
+	filter, err := parseJobFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_filter",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+	if filter.After, err = parseCursor(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_cursor",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Get all jobs
-	jobs, err := h.jobRepo.GetAllJobs(ctx, limit)
+	jobs, nextCursor, err := h.queryJobPage(ctx, filter, limit)
 	if err != nil {
-		log.Printf("Failed to get all jobs: %v", err)
+		slog.Error("Failed to get all jobs", logging.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "database_error",
 			Message: "Failed to retrieve jobs",
@@ -301,7 +1627,96 @@ func (h *JobHandler) GetAllJobs(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(jobs)
+	return c.JSON(fiber.Map{
+		"count":       len(jobs),
+		"jobs":        jobs,
+		"next_cursor": nextCursor,
+	})
+}
+
+// queryJobPage fetches one page of jobs and the cursor for the next page
+// (empty when this is the last page)
+func (h *JobHandler) queryJobPage(ctx context.Context, filter database.JobFilter, limit int) ([]*models.Job, string, error) {
+	// Ask for one extra job to learn whether another page follows
+	filter.Limit = limit + 1
+	jobs, err := h.jobRepo.QueryJobs(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+		nextCursor = database.CursorAfter(jobs[limit-1]).Encode()
+	}
+	if jobs == nil {
+		jobs = []*models.Job{}
+	}
+	return jobs, nextCursor, nil
+}
+
+// parseCursor reads the optional ?cursor= pagination token
+func parseCursor(c *fiber.Ctx) (*database.JobCursor, error) {
+	token := c.Query("cursor")
+	if token == "" {
+		return nil, nil
+	}
+	return database.DecodeJobCursor(token)
+}
+
+// parseJobFilter reads the job listing filters from the query string
+func parseJobFilter(c *fiber.Ctx) (database.JobFilter, error) {
+	filter := database.JobFilter{
+		Region: c.Query("region"),
+		UserID: c.Query("user_id"),
+	}
+
+	if status := c.Query("status"); status != "" {
+		filter.Status = models.JobStatus(strings.ToUpper(status))
+		if !filter.Status.IsValid() {
+			return filter, fmt.Errorf("unknown status %q", status)
+		}
+	}
+	if filter.Region != "" && !regionPattern.MatchString(filter.Region) {
+		return filter, fmt.Errorf("invalid region %q", filter.Region)
+	}
+
+	for _, raw := range c.Context().QueryArgs().PeekMulti("label") {
+		key, value, ok := strings.Cut(string(raw), ":")
+		if !ok || !labelKeyPattern.MatchString(key) || value == "" {
+			return filter, fmt.Errorf("label must be key:value, got %q", raw)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		if existing, ok := filter.Labels[key]; ok && existing != value {
+			return filter, fmt.Errorf("label %q is given more than once with different values", key)
+		}
+		filter.Labels[key] = value
+	}
+	if len(filter.Labels) > maxLabels {
+		return filter, fmt.Errorf("at most %d labels may be filtered on", maxLabels)
+	}
+
+	for _, bound := range []struct {
+		name string
+		dest *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC3339 timestamp", bound.name)
+		}
+		*bound.dest = parsed
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return filter, fmt.Errorf("until must be after since")
+	}
+
+	return filter, nil
 }
 
 // GetUserJobs handles GET /api/users/:userId/jobs
@@ -321,12 +1736,21 @@ func (h *JobHandler) GetUserJobs(c *fiber.Ctx) error {
 		limit = 50
 	}
 
+	cursor, err := parseCursor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_cursor",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	jobs, err := h.jobRepo.GetJobsByUserID(ctx, userID, limit)
+	jobs, nextCursor, err := h.queryJobPage(ctx, database.JobFilter{UserID: userID, After: cursor}, limit)
 	if err != nil {
-		log.Printf("Failed to get user jobs: %v", err)
+		slog.Error("Failed to get user jobs", "user_id", userID, logging.Err(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "database_error",
 			Message: "Failed to retrieve jobs",
@@ -335,8 +1759,28 @@ func (h *JobHandler) GetUserJobs(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"user_id": userID,
-		"count":   len(jobs),
-		"jobs":    jobs,
+		"user_id":     userID,
+		"count":       len(jobs),
+		"jobs":        jobs,
+		"next_cursor": nextCursor,
+	})
+}
+
+// outsideAcceptanceWindow answers a submission made outside the acceptance windows
+// with 503 and a Retry-After header pointing at the next window
+func outsideAcceptanceWindow(c *fiber.Ctx, next, now time.Time) error {
+	message := "Job submissions are not accepted at this time"
+	if !next.IsZero() {
+		retryAfter := int(math.Ceil(next.Sub(now).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		message += ", next acceptance window opens at " + next.UTC().Format(time.RFC3339)
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+		Error:   "outside_acceptance_window",
+		Message: message,
+		Code:    fiber.StatusServiceUnavailable,
 	})
 }
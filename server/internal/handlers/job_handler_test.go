@@ -0,0 +1,1927 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/acceptance"
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/Sambit-Mondal/karbos/server/internal/worker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+)
+
+// fakeJobStore keeps created jobs in memory
+type fakeJobStore struct {
+	jobs       map[uuid.UUID]*models.Job
+	windows    map[uuid.UUID][]models.ScheduleWindow
+	lastFilter database.JobFilter
+	durations  map[string]time.Duration // Average completed-job duration by image
+
+	dependencies map[uuid.UUID][]uuid.UUID
+	labels       map[uuid.UUID]map[string]string
+
+	batches int // CreateJobsBatch calls
+
+	beforeTransition func(job *models.Job) // Runs inside TransitionJobStatus before the compare, to simulate a race
+}
+
+func newFakeJobStore() *fakeJobStore {
+	return &fakeJobStore{jobs: make(map[uuid.UUID]*models.Job), windows: make(map[uuid.UUID][]models.ScheduleWindow)}
+}
+
+func (f *fakeJobStore) CreateJob(ctx context.Context, job *models.Job) error {
+	f.jobs[job.ID] = job
+	return nil
+}
+
+func (f *fakeJobStore) CreateJobsBatch(ctx context.Context, jobs []*models.Job) error {
+	f.batches++
+	for _, job := range jobs {
+		f.jobs[job.ID] = job
+	}
+	return nil
+}
+
+func (f *fakeJobStore) GetJobByID(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+func (f *fakeJobStore) UpdateJobStatus(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
+	job, ok := f.jobs[id]
+	if !ok {
+		return errors.New("job not found")
+	}
+	job.Status = status
+	return nil
+}
+
+func (f *fakeJobStore) UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
+	job, ok := f.jobs[id]
+	if !ok {
+		return errors.New("job not found")
+	}
+	if !models.CanTransition(job.Status, status) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, job.Status, status)
+	}
+	job.Status = status
+	return nil
+}
+
+func (f *fakeJobStore) TransitionJobStatus(ctx context.Context, id uuid.UUID, from, to models.JobStatus) error {
+	job, ok := f.jobs[id]
+	if !ok {
+		return errors.New("job not found")
+	}
+	if f.beforeTransition != nil {
+		f.beforeTransition(job)
+	}
+	if !models.CanTransition(from, to) || job.Status != from {
+		return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, job.Status, to)
+	}
+	job.Status = to
+	return nil
+}
+
+func (f *fakeJobStore) ReopenFailedJob(ctx context.Context, id uuid.UUID, deadline time.Time) (bool, error) {
+	job, ok := f.jobs[id]
+	if !ok || job.Status != models.JobStatusFailed {
+		return false, nil
+	}
+	job.Status, job.Deadline = models.JobStatusPending, deadline
+	return true, nil
+}
+
+func (f *fakeJobStore) SoftDelete(ctx context.Context, id uuid.UUID) (bool, error) {
+	job, ok := f.jobs[id]
+	if !ok || job.DeletedAt != nil || !job.Status.IsTerminal() {
+		return false, nil
+	}
+	now := time.Now()
+	job.DeletedAt, job.Deleted = &now, true
+	return true, nil
+}
+
+func (f *fakeJobStore) SaveScheduleWindows(ctx context.Context, id uuid.UUID, windows []models.ScheduleWindow) error {
+	if _, ok := f.jobs[id]; !ok {
+		return errors.New("job not found")
+	}
+	f.windows[id] = windows
+	return nil
+}
+
+func (f *fakeJobStore) GetAverageDurationByImage(ctx context.Context, image string) (time.Duration, error) {
+	return f.durations[image], nil
+}
+
+func (f *fakeJobStore) AddJobDependencies(ctx context.Context, jobID uuid.UUID, dependsOn []uuid.UUID) error {
+	if f.dependencies == nil {
+		f.dependencies = make(map[uuid.UUID][]uuid.UUID)
+	}
+	f.dependencies[jobID] = append(f.dependencies[jobID], dependsOn...)
+	return nil
+}
+
+func (f *fakeJobStore) GetJobDependencies(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	return f.dependencies[jobID], nil
+}
+
+func (f *fakeJobStore) AddJobLabels(ctx context.Context, jobID uuid.UUID, labels map[string]string) error {
+	if f.labels == nil {
+		f.labels = make(map[uuid.UUID]map[string]string)
+	}
+	f.labels[jobID] = labels
+	return nil
+}
+
+func (f *fakeJobStore) GetJobLabels(ctx context.Context, jobID uuid.UUID) (map[string]string, error) {
+	return f.labels[jobID], nil
+}
+
+func (f *fakeJobStore) QueryJobs(ctx context.Context, filter database.JobFilter) ([]*models.Job, error) {
+	f.lastFilter = filter
+	var jobs []*models.Job
+	for _, job := range f.jobs {
+		if job.DeletedAt != nil {
+			continue
+		}
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		if filter.UserID != "" && job.UserID != filter.UserID {
+			continue
+		}
+		if filter.After != nil && !sortsAfter(job, filter.After) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return sortsAfter(jobs[j], database.CursorAfter(jobs[i])) })
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+// sortsAfter reports whether job is listed after cursor in (created_at, id) descending order
+func sortsAfter(job *models.Job, cursor *database.JobCursor) bool {
+	if !job.CreatedAt.Equal(cursor.CreatedAt) {
+		return job.CreatedAt.Before(cursor.CreatedAt)
+	}
+	return job.ID.String() < cursor.ID.String()
+}
+
+// fakeJobQueue records which queue each job was routed to, and cancellations
+type fakeJobQueue struct {
+	immediate []*queue.QueueItem
+	delayed   []*queue.QueueItem
+
+	removedDelayed []string
+	cancelsSent    []string
+
+	waiting map[string]*queue.QueueItem // Jobs held for their dependencies
+}
+
+func (f *fakeJobQueue) RemoveFromDelayed(ctx context.Context, jobID string) error {
+	f.removedDelayed = append(f.removedDelayed, jobID)
+	return nil
+}
+
+func (f *fakeJobQueue) PublishJobCancel(ctx context.Context, jobID string) (int64, error) {
+	f.cancelsSent = append(f.cancelsSent, jobID)
+	return 1, nil
+}
+
+func (f *fakeJobQueue) EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error {
+	f.immediate = append(f.immediate, item)
+	return nil
+}
+
+func (f *fakeJobQueue) EnqueueDelayed(ctx context.Context, item *queue.QueueItem) error {
+	f.delayed = append(f.delayed, item)
+	return nil
+}
+
+func (f *fakeJobQueue) HoldWaiting(ctx context.Context, item *queue.QueueItem) error {
+	if f.waiting == nil {
+		f.waiting = make(map[string]*queue.QueueItem)
+	}
+	f.waiting[item.JobID] = item
+	return nil
+}
+
+func (f *fakeJobQueue) TakeWaiting(ctx context.Context, jobID string) (*queue.QueueItem, error) {
+	item := f.waiting[jobID]
+	delete(f.waiting, jobID)
+	return item, nil
+}
+
+// dirtyNowFetcher forecasts a dirty grid now and a clean one two hours out
+type dirtyNowFetcher struct{}
+
+func (dirtyNowFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	intensities := []float64{600, 550, 120, 110, 500}
+	forecast := make([]carbon.CarbonIntensity, len(intensities))
+	for i, intensity := range intensities {
+		forecast[i] = carbon.CarbonIntensity{Region: region, Timestamp: startTime.Add(time.Duration(i) * time.Hour), Intensity: intensity}
+	}
+	return forecast, nil
+}
+
+func (dirtyNowFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	return &carbon.CarbonIntensity{Region: region, Timestamp: time.Now(), Intensity: 600}, nil
+}
+
+func newJobTestApp(h *JobHandler) *fiber.App {
+	app := fiber.New()
+	app.Post("/api/submit", h.SubmitJob)
+	app.Post("/api/submit/batch", h.SubmitBatch)
+	return app
+}
+
+func submitJob(t *testing.T, app *fiber.App) (int, models.SubmitJobResponse) {
+	t.Helper()
+
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:      "user-1",
+		DockerImage: "alpine:latest",
+		Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+	})
+	req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var body models.SubmitJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestJobHandler_SubmitJob_ImmediateIsCreated(t *testing.T) {
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: newFakeJobStore(), queue: q})
+
+	status, body := submitJob(t, app)
+
+	if status != fiber.StatusCreated {
+		t.Errorf("expected 201, got %d", status)
+	}
+	if body.ExecutionPlan != models.ExecutionPlanImmediate || !body.Immediate {
+		t.Errorf("expected immediate plan, got %q (immediate=%v)", body.ExecutionPlan, body.Immediate)
+	}
+	if _, err := time.Parse(time.RFC3339, body.ScheduledTime); err != nil {
+		t.Errorf("expected RFC 3339 scheduled_time, got %q", body.ScheduledTime)
+	}
+	if len(q.immediate) != 1 || len(q.delayed) != 0 {
+		t.Errorf("expected job on immediate queue, got %d immediate / %d delayed", len(q.immediate), len(q.delayed))
+	}
+}
+
+func TestJobHandler_SubmitJob_ScheduledIsAccepted(t *testing.T) {
+	q := &fakeJobQueue{}
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     q,
+		scheduler: scheduler.NewCarbonScheduler(dirtyNowFetcher{}),
+	})
+
+	status, body := submitJob(t, app)
+
+	if status != fiber.StatusAccepted {
+		t.Errorf("expected 202, got %d", status)
+	}
+	if body.ExecutionPlan != models.ExecutionPlanScheduled || body.Immediate {
+		t.Errorf("expected scheduled plan, got %q (immediate=%v)", body.ExecutionPlan, body.Immediate)
+	}
+	start, err := time.Parse(time.RFC3339, body.ScheduledTime)
+	if err != nil {
+		t.Fatalf("expected RFC 3339 scheduled_time, got %q", body.ScheduledTime)
+	}
+	if !start.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected start in the clean window, got %s", body.ScheduledTime)
+	}
+	if len(q.delayed) != 1 || len(q.immediate) != 0 {
+		t.Errorf("expected job on delayed queue, got %d immediate / %d delayed", len(q.immediate), len(q.delayed))
+	}
+
+	job := store.jobs[uuid.MustParse(body.JobID)]
+	if job == nil || job.ExpectedIntensity == nil || job.CarbonSavings == nil {
+		t.Fatalf("expected the scheduling decision to be persisted, got %+v", job)
+	}
+	if *job.ExpectedIntensity != body.ExpectedIntensity || *job.CarbonSavings != body.CarbonSavings {
+		t.Errorf("expected persisted %.1f/%.1f to match response %.1f/%.1f",
+			*job.ExpectedIntensity, *job.CarbonSavings, body.ExpectedIntensity, body.CarbonSavings)
+	}
+}
+
+func TestJobHandler_SubmitJob_LegacyCreatedStatus(t *testing.T) {
+	h := &JobHandler{
+		jobRepo:   newFakeJobStore(),
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(dirtyNowFetcher{}),
+	}
+	h.SetLegacyCreatedStatus(true)
+
+	status, body := submitJob(t, newJobTestApp(h))
+
+	if status != fiber.StatusCreated {
+		t.Errorf("expected 201 in legacy mode, got %d", status)
+	}
+	if body.ExecutionPlan != models.ExecutionPlanScheduled {
+		t.Errorf("expected scheduled plan in legacy mode, got %q", body.ExecutionPlan)
+	}
+}
+
+// relativeIndexFetcher forecasts a WattTime-style index that is dirty now and clean later
+type relativeIndexFetcher struct{}
+
+func (relativeIndexFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	forecast, _ := dirtyNowFetcher{}.GetCarbonForecast(ctx, region, startTime, endTime)
+	for i := range forecast {
+		forecast[i].Intensity /= 10 // 60, 55, 12, 11, 50
+		forecast[i].IntensityScale = carbon.IntensityScaleRelative
+	}
+	return forecast, nil
+}
+
+func (relativeIndexFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	return &carbon.CarbonIntensity{Region: region, Timestamp: time.Now(), Intensity: 60, IntensityScale: carbon.IntensityScaleRelative}, nil
+}
+
+func TestJobHandler_SubmitJob_RelativeIndexNotPersistedAsGrams(t *testing.T) {
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(relativeIndexFetcher{}),
+	})
+
+	status, body := submitJob(t, app)
+
+	if status != fiber.StatusAccepted {
+		t.Errorf("expected the dirty index to defer the job with 202, got %d", status)
+	}
+	if body.IntensityScale != string(carbon.IntensityScaleRelative) {
+		t.Errorf("expected relative intensity_scale in the response, got %q", body.IntensityScale)
+	}
+
+	job := store.jobs[uuid.MustParse(body.JobID)]
+	if job == nil {
+		t.Fatal("expected the job to be stored")
+	}
+	if job.SubmissionIntensity != nil || job.ExpectedIntensity != nil || job.CarbonSavings != nil {
+		t.Errorf("expected no gCO2eq/kWh figures stored for a relative index, got %v/%v/%v",
+			job.SubmissionIntensity, job.ExpectedIntensity, job.CarbonSavings)
+	}
+}
+
+// fakeExecutionLogs serves stored execution logs per job
+type fakeExecutionLogs map[uuid.UUID][]*models.ExecutionLog
+
+func (f fakeExecutionLogs) GetAllExecutionLogsByJobID(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]*models.ExecutionLog, int, error) {
+	logs := f[jobID]
+	if offset > len(logs) {
+		offset = len(logs)
+	}
+	page := logs[offset:]
+	if limit < len(page) {
+		page = page[:limit]
+	}
+	return page, len(logs), nil
+}
+
+func TestJobHandler_SubmitJob_RecordsRequestID(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: store, queue: q}
+
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Post("/api/submit", h.SubmitJob)
+	app.Get("/api/jobs/:id", h.GetJob)
+
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:      "user-1",
+		DockerImage: "alpine:latest",
+		Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+	})
+	req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(fiber.HeaderXRequestID, "req-7f3a")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var submitted models.SubmitJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&submitted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(q.immediate) != 1 || q.immediate[0].RequestID != "req-7f3a" {
+		t.Fatalf("expected the queued job to carry request ID req-7f3a, got %+v", q.immediate)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/jobs/"+submitted.JobID, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var job struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.RequestID != "req-7f3a" {
+		t.Errorf("expected GET /api/jobs/:id to report request_id req-7f3a, got %q", job.RequestID)
+	}
+}
+
+func TestJobHandler_GetJobLogs_IncludesWorkerNodeID(t *testing.T) {
+	jobID := uuid.New()
+	worker := "3f1c9a52-7d4e-4b8a-9c61-0e2f5d7a8b90/worker-1"
+	exitCode := 1
+	h := &JobHandler{executionLogs: fakeExecutionLogs{
+		jobID: {{ID: uuid.New(), JobID: jobID, ExitCode: &exitCode, WorkerNodeID: &worker}},
+	}}
+
+	app := fiber.New()
+	app.Get("/api/jobs/:id/logs", h.GetJobLogs)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs/"+jobID.String()+"/logs", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Logs []struct {
+			WorkerNodeID string `json:"worker_node_id"`
+		} `json:"logs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Logs) != 1 || body.Logs[0].WorkerNodeID != worker {
+		t.Errorf("expected one log from %q, got %+v", worker, body.Logs)
+	}
+}
+
+func TestJobHandler_GetJobLogs_Paginates(t *testing.T) {
+	jobID := uuid.New()
+	var logs []*models.ExecutionLog
+	for i := 0; i < 120; i++ {
+		logs = append(logs, &models.ExecutionLog{ID: uuid.New(), JobID: jobID, Output: fmt.Sprintf("attempt %d", i+1)})
+	}
+	h := &JobHandler{executionLogs: fakeExecutionLogs{jobID: logs}}
+
+	app := fiber.New()
+	app.Get("/api/jobs/:id/logs", h.GetJobLogs)
+
+	tests := []struct {
+		query       string
+		wantLimit   int
+		wantOffset  int
+		wantOutputs []string
+	}{
+		{"", 50, 0, []string{"attempt 1", "attempt 50"}},
+		{"?limit=50&offset=100", 50, 100, []string{"attempt 101", "attempt 120"}},
+		{"?limit=1&offset=119", 1, 119, []string{"attempt 120", "attempt 120"}},
+		{"?limit=10&offset=500", 10, 500, nil},
+		{"?limit=1000&offset=-5", 50, 0, []string{"attempt 1", "attempt 50"}}, // Out-of-range values fall back to defaults
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs/"+jobID.String()+"/logs"+tt.query, nil))
+		if err != nil {
+			t.Fatalf("%q: request failed: %v", tt.query, err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", tt.query, resp.StatusCode)
+		}
+
+		var body struct {
+			Logs []struct {
+				Output string `json:"output"`
+			} `json:"logs"`
+			Count  int `json:"count"`
+			Total  int `json:"total"`
+			Limit  int `json:"limit"`
+			Offset int `json:"offset"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+
+		if body.Total != 120 || body.Limit != tt.wantLimit || body.Offset != tt.wantOffset {
+			t.Errorf("%q: expected total 120, limit %d, offset %d, got %d/%d/%d",
+				tt.query, tt.wantLimit, tt.wantOffset, body.Total, body.Limit, body.Offset)
+		}
+		if body.Count != len(body.Logs) {
+			t.Errorf("%q: count %d does not match %d logs", tt.query, body.Count, len(body.Logs))
+		}
+		if tt.wantOutputs == nil {
+			if len(body.Logs) != 0 {
+				t.Errorf("%q: expected an empty page, got %d logs", tt.query, len(body.Logs))
+			}
+			continue
+		}
+		if len(body.Logs) == 0 || body.Logs[0].Output != tt.wantOutputs[0] || body.Logs[len(body.Logs)-1].Output != tt.wantOutputs[1] {
+			t.Errorf("%q: expected logs %s..%s, got %+v", tt.query, tt.wantOutputs[0], tt.wantOutputs[1], body.Logs)
+		}
+	}
+}
+
+func TestJobHandler_GetAllJobs_Filters(t *testing.T) {
+	store := newFakeJobStore()
+	h := &JobHandler{jobRepo: store}
+
+	app := fiber.New()
+	app.Get("/api/jobs", h.GetAllJobs)
+
+	req := httptest.NewRequest("GET", "/api/jobs?status=completed&region=EU-WEST&user_id=alice&since=2026-03-01T00:00:00Z&until=2026-03-02T00:00:00Z&label=team:ml&label=project:vision&limit=20", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	want := database.JobFilter{
+		Status: models.JobStatusCompleted,
+		Region: "EU-WEST",
+		UserID: "alice",
+		Since:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:  time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		Limit:  21, // One extra row to detect a next page
+	}
+	got := store.lastFilter
+	if got.Status != want.Status || got.Region != want.Region || got.UserID != want.UserID ||
+		!got.Since.Equal(want.Since) || !got.Until.Equal(want.Until) || got.Limit != want.Limit {
+		t.Errorf("filter = %+v, want %+v", got, want)
+	}
+	if len(got.Labels) != 2 || got.Labels["team"] != "ml" || got.Labels["project"] != "vision" {
+		t.Errorf("label filter = %v, want team=ml and project=vision", got.Labels)
+	}
+}
+
+func TestJobHandler_GetAllJobs_RejectsBadFilters(t *testing.T) {
+	h := &JobHandler{jobRepo: newFakeJobStore()}
+
+	app := fiber.New()
+	app.Get("/api/jobs", h.GetAllJobs)
+
+	for _, query := range []string{
+		"status=EXPLODED",
+		"region=us%20east;drop",
+		"since=yesterday",
+		"since=2026-03-02T00:00:00Z&until=2026-03-01T00:00:00Z",
+		"label=team",
+		"label=:ml",
+		"label=team:ml&label=team:data",
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs?"+query, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestJobHandler_GetUserJobs_PagesWithCursor(t *testing.T) {
+	store := newFakeJobStore()
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		job := &models.Job{ID: uuid.New(), UserID: "alice", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		store.jobs[job.ID] = job
+	}
+	h := &JobHandler{jobRepo: store}
+
+	app := fiber.New()
+	app.Get("/api/users/:userId/jobs", h.GetUserJobs)
+
+	type page struct {
+		Count      int           `json:"count"`
+		Jobs       []*models.Job `json:"jobs"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	fetch := func(query string) page {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/users/alice/jobs?limit=2"+query, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var p page
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return p
+	}
+
+	var counts []int
+	p := fetch("")
+	for {
+		counts = append(counts, p.Count)
+		if p.NextCursor == "" {
+			break
+		}
+		p = fetch("&cursor=" + p.NextCursor)
+	}
+	if fmt.Sprint(counts) != "[2 2 1]" {
+		t.Errorf("page sizes = %v, want [2 2 1]", counts)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/users/alice/jobs?cursor=bogus", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed cursor, got %d", resp.StatusCode)
+	}
+}
+
+// nearTieFetcher forecasts a dirty grid now and three clean hours within a few grams of each other
+type nearTieFetcher struct{ dirtyNowFetcher }
+
+func (nearTieFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	intensities := []float64{600, 110, 115, 118, 500}
+	forecast := make([]carbon.CarbonIntensity, len(intensities))
+	for i, intensity := range intensities {
+		forecast[i] = carbon.CarbonIntensity{Region: region, Timestamp: startTime.Add(time.Duration(i) * time.Hour), Intensity: intensity}
+	}
+	return forecast, nil
+}
+
+func TestJobHandler_SubmitJob_PersistsScheduleWindows(t *testing.T) {
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(nearTieFetcher{}),
+	})
+
+	status, body := submitJob(t, app)
+	if status != fiber.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+
+	windows := store.windows[uuid.MustParse(body.JobID)]
+	if len(windows) != 3 {
+		t.Fatalf("expected the chosen window and 2 alternatives, got %+v", windows)
+	}
+	chosen := windows[0]
+	if !chosen.Chosen || chosen.AvgIntensity != 110 || chosen.StartTime.Format(time.RFC3339) != body.ScheduledTime {
+		t.Errorf("unexpected chosen window %+v for scheduled time %s", chosen, body.ScheduledTime)
+	}
+	if !chosen.EndTime.Equal(chosen.StartTime.Add(10 * time.Minute)) {
+		t.Errorf("expected the chosen window to span the default 10m duration, got %s - %s", chosen.StartTime, chosen.EndTime)
+	}
+	for _, alt := range windows[1:] {
+		if alt.Chosen || (alt.AvgIntensity != 115 && alt.AvgIntensity != 118) {
+			t.Errorf("unexpected alternative %+v", alt)
+		}
+	}
+}
+
+func TestJobHandler_SubmitJob_DryRunReturnsAlternativeWindows(t *testing.T) {
+	dryRun := func(h *JobHandler) (map[string]json.RawMessage, models.SubmitJobResponse) {
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "alpine:latest",
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		})
+		req := httptest.NewRequest("POST", "/api/submit?dry_run=true", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := newJobTestApp(h).Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		var raw map[string]json.RawMessage
+		var body models.SubmitJobResponse
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return raw, body
+	}
+
+	store := newFakeJobStore()
+	_, body := dryRun(&JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(nearTieFetcher{}),
+	})
+	if len(store.jobs) != 0 {
+		t.Fatalf("expected a dry run not to create the job, got %d jobs", len(store.jobs))
+	}
+	if len(body.AlternativeWindows) != 2 {
+		t.Fatalf("expected 2 alternative windows, got %+v", body.AlternativeWindows)
+	}
+	for _, alt := range body.AlternativeWindows {
+		if alt.Chosen || (alt.AvgIntensity != 115 && alt.AvgIntensity != 118) || !alt.EndTime.After(alt.StartTime) {
+			t.Errorf("unexpected alternative %+v", alt)
+		}
+	}
+
+	// Without a scheduler there are no alternatives, listed as [] rather than null
+	raw, _ := dryRun(&JobHandler{jobRepo: newFakeJobStore(), queue: &fakeJobQueue{}})
+	if got := string(raw["alternative_windows"]); got != "[]" {
+		t.Errorf("expected alternative_windows to be [], got %s", got)
+	}
+}
+
+func TestJobHandler_SubmitJob_DryRunEstimatesExecution(t *testing.T) {
+	profiles, err := worker.ParseImageProfiles(`[{"pattern": "alpine:*", "timeout": "30m", "cpu_quota": 100000}]`)
+	if err != nil {
+		t.Fatalf("ParseImageProfiles returned error: %v", err)
+	}
+	store := newFakeJobStore()
+	store.durations = map[string]time.Duration{"alpine:latest": 30 * time.Minute}
+	h := &JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(nearTieFetcher{}),
+	}
+	h.SetExecutionDefaults(ExecutionDefaults{
+		Limits:        docker.ResourceLimits{MemoryBytes: 512 << 20, CPUQuota: 50000},
+		MaxLimits:     docker.ResourceLimits{MemoryBytes: 1024 << 20},
+		ImageProfiles: profiles,
+		PowerWatts:    100,
+	})
+
+	memory := 4096 // Over the maximum, so clamped to 1024MB
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:        "user-1",
+		DockerImage:   "alpine:latest",
+		Deadline:      time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		MemoryLimitMB: &memory,
+	})
+	req := httptest.NewRequest("POST", "/api/submit?dry_run=true", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := newJobTestApp(h).Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var body models.SubmitJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	estimate := body.Estimate
+	if estimate == nil {
+		t.Fatal("expected a dry run to include an execution estimate")
+	}
+	if estimate.EstimatedDurationSeconds != 1800 || estimate.DurationSource != "history" {
+		t.Errorf("expected the image's 30m history, got %ds from %q", estimate.EstimatedDurationSeconds, estimate.DurationSource)
+	}
+	if estimate.MemoryLimitMB != 1024 || estimate.CPUQuota != 100000 || estimate.TimeoutSeconds != 1800 {
+		t.Errorf("unexpected limits %+v", estimate)
+	}
+	// 110 gCO2eq/kWh × 0.1 kW × 0.5 h
+	if estimate.ProjectedCO2Grams == nil || *estimate.ProjectedCO2Grams <= 0 || math.Abs(*estimate.ProjectedCO2Grams-5.5) > 1e-9 {
+		t.Errorf("expected 5.5g of projected CO2, got %v", estimate.ProjectedCO2Grams)
+	}
+
+	// Submissions that aren't dry runs carry no estimate
+	status, created := submitJob(t, newJobTestApp(h))
+	if status != fiber.StatusAccepted || created.Estimate != nil {
+		t.Errorf("expected a created job without an estimate, got %d %+v", status, created.Estimate)
+	}
+}
+
+func TestJobHandler_SubmitJob_EstimatesDurationFromImageHistory(t *testing.T) {
+	store := newFakeJobStore()
+	store.durations = map[string]time.Duration{"alpine:latest": 45 * time.Minute}
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(nearTieFetcher{}),
+	})
+
+	status, body := submitJob(t, app)
+	if status != fiber.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+	windows := store.windows[uuid.MustParse(body.JobID)]
+	if len(windows) == 0 {
+		t.Fatal("expected schedule windows to be persisted")
+	}
+	if chosen := windows[0]; !chosen.EndTime.Equal(chosen.StartTime.Add(45 * time.Minute)) {
+		t.Errorf("expected the chosen window to span the image's 45m average, got %s - %s", chosen.StartTime, chosen.EndTime)
+	}
+
+	// An image without history falls back to the 10m default
+	store.durations = map[string]time.Duration{"python:3.12": 45 * time.Minute}
+	status, body = submitJob(t, app)
+	if status != fiber.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+	windows = store.windows[uuid.MustParse(body.JobID)]
+	if len(windows) == 0 || !windows[0].EndTime.Equal(windows[0].StartTime.Add(defaultEstimatedDuration)) {
+		t.Errorf("expected the default duration without history, got %+v", windows)
+	}
+}
+
+// spyFetcher records how often the scheduler asked for carbon data
+type spyFetcher struct {
+	dirtyNowFetcher
+	calls int
+}
+
+func (f *spyFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	f.calls++
+	return f.dirtyNowFetcher.GetCarbonForecast(ctx, region, startTime, endTime)
+}
+
+func (f *spyFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	f.calls++
+	return f.dirtyNowFetcher.GetCurrentCarbonIntensity(ctx, region)
+}
+
+func TestJobHandler_SubmitJob_CarbonAwareOptOutSkipsScheduler(t *testing.T) {
+	fetcher := &spyFetcher{}
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     q,
+		scheduler: scheduler.NewCarbonScheduler(fetcher),
+	})
+
+	optOut := false
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:      "user-1",
+		DockerImage: "alpine:latest",
+		Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		CarbonAware: &optOut,
+	})
+	req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var body models.SubmitJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if fetcher.calls != 0 {
+		t.Errorf("expected the scheduler not to fetch carbon data, got %d calls", fetcher.calls)
+	}
+	if resp.StatusCode != fiber.StatusCreated || !body.Immediate || body.CarbonAware {
+		t.Errorf("expected an immediate opted-out job, got %d %+v", resp.StatusCode, body)
+	}
+	if len(q.immediate) != 1 || len(q.delayed) != 0 {
+		t.Errorf("expected job on immediate queue, got %d immediate / %d delayed", len(q.immediate), len(q.delayed))
+	}
+
+	job := store.jobs[uuid.MustParse(body.JobID)]
+	if job == nil || !job.CarbonOptOut {
+		t.Fatalf("expected the opt-out to be recorded, got %+v", job)
+	}
+	if job.SubmissionIntensity != nil || job.ExpectedIntensity != nil || job.CarbonSavings != nil {
+		t.Errorf("expected no carbon figures for an opted-out job, got %+v", job)
+	}
+	if len(store.windows[job.ID]) != 0 {
+		t.Errorf("expected no schedule windows, got %+v", store.windows[job.ID])
+	}
+}
+
+func TestJobHandler_SubmitJob_CarbonAwareByDefault(t *testing.T) {
+	fetcher := &spyFetcher{}
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   store,
+		queue:     &fakeJobQueue{},
+		scheduler: scheduler.NewCarbonScheduler(fetcher),
+	})
+
+	_, body := submitJob(t, app)
+
+	if fetcher.calls == 0 {
+		t.Error("expected the scheduler to fetch carbon data")
+	}
+	if !body.CarbonAware || store.jobs[uuid.MustParse(body.JobID)].CarbonOptOut {
+		t.Errorf("expected a carbon-aware job, got %+v", body)
+	}
+}
+
+// moderateNowFetcher forecasts a moderate grid now, below the default threshold, and a clean hour after
+type moderateNowFetcher struct{ dirtyNowFetcher }
+
+func (moderateNowFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	intensities := []float64{300, 100, 320}
+	forecast := make([]carbon.CarbonIntensity, len(intensities))
+	for i, intensity := range intensities {
+		forecast[i] = carbon.CarbonIntensity{Region: region, Timestamp: startTime.Add(time.Duration(i) * time.Hour), Intensity: intensity}
+	}
+	return forecast, nil
+}
+
+func TestJobHandler_SubmitJob_MaxIntensity(t *testing.T) {
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{
+		jobRepo:   newFakeJobStore(),
+		queue:     q,
+		scheduler: scheduler.NewCarbonScheduler(moderateNowFetcher{}),
+	})
+
+	submit := func(maxIntensity *float64) (int, models.SubmitJobResponse) {
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:       "user-1",
+			DockerImage:  "alpine:latest",
+			Deadline:     time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+			MaxIntensity: maxIntensity,
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body models.SubmitJobResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.StatusCode, body
+	}
+
+	// 300 gCO2eq/kWh is below the default 400 threshold
+	if status, body := submit(nil); status != fiber.StatusCreated || !body.Immediate {
+		t.Errorf("expected the default threshold to run the job now, got %d %+v", status, body)
+	}
+
+	strict := 200.0
+	status, body := submit(&strict)
+	if status != fiber.StatusAccepted || body.Immediate {
+		t.Errorf("expected a strict threshold to defer the job, got %d %+v", status, body)
+	}
+	if len(q.delayed) != 1 {
+		t.Errorf("expected the strict job on the delayed queue, got %d delayed", len(q.delayed))
+	}
+
+	for _, invalid := range []float64{0, -50, scheduler.MaxIntensityCeiling + 1} {
+		if status, _ := submit(&invalid); status != fiber.StatusBadRequest {
+			t.Errorf("max_intensity %v: expected 400, got %d", invalid, status)
+		}
+	}
+}
+
+func TestJobHandler_SubmitJob_Volumes(t *testing.T) {
+	allowlist, err := docker.ParseVolumeAllowlist("/srv/karbos/inputs,karbos-data")
+	if err != nil {
+		t.Fatalf("ParseVolumeAllowlist returned error: %v", err)
+	}
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: newFakeJobStore(), queue: q}
+	h.SetVolumeAllowlist(allowlist)
+	app := newJobTestApp(h)
+
+	submit := func(volumes []models.VolumeMount) (int, models.ErrorResponse) {
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "alpine:latest",
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+			Volumes:     volumes,
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.StatusCode, body
+	}
+
+	volumes := []models.VolumeMount{
+		{Source: "/srv/karbos/inputs/batch-7", Target: "/data", ReadOnly: true},
+		{Source: "karbos-data", Target: "/cache"},
+	}
+	if status, _ := submit(volumes); status != fiber.StatusCreated {
+		t.Fatalf("expected allowed mounts to be accepted, got %d", status)
+	}
+	if len(q.immediate) != 1 || len(q.immediate[0].Volumes) != 2 || q.immediate[0].Volumes[0] != volumes[0] {
+		t.Errorf("expected the mounts on the queue item, got %+v", q.immediate)
+	}
+
+	for _, source := range []string{"/etc", "/srv/karbos/inputs/../../../etc", "/srv/karbos/inputs-other", "host-data"} {
+		status, body := submit([]models.VolumeMount{{Source: source, Target: "/data"}})
+		if status != fiber.StatusBadRequest || body.Error != "invalid_volume" {
+			t.Errorf("%s: expected 400 invalid_volume, got %d %+v", source, status, body)
+		}
+	}
+	if len(q.immediate) != 1 {
+		t.Errorf("expected rejected jobs not to be queued, got %d", len(q.immediate))
+	}
+
+	// Without an allowlist no mount is accepted
+	app = newJobTestApp(&JobHandler{jobRepo: newFakeJobStore(), queue: &fakeJobQueue{}})
+	if status, _ := submit(volumes); status != fiber.StatusBadRequest {
+		t.Errorf("expected mounts to be refused without an allowlist, got %d", status)
+	}
+}
+
+func TestJobHandler_CancelJob(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: store, queue: q, cancels: q, waiting: q}
+	app := fiber.New()
+	app.Post("/api/jobs/:id/cancel", h.CancelJob)
+
+	tests := []struct {
+		status     models.JobStatus
+		wantCode   int
+		wantStatus models.JobStatus
+	}{
+		{models.JobStatusPending, fiber.StatusOK, models.JobStatusCancelled},
+		{models.JobStatusDelayed, fiber.StatusOK, models.JobStatusCancelled},
+		{models.JobStatusWaiting, fiber.StatusOK, models.JobStatusCancelled},
+		{models.JobStatusRunning, fiber.StatusAccepted, models.JobStatusRunning}, // The worker finishes the cancel
+		{models.JobStatusCompleted, fiber.StatusConflict, models.JobStatusCompleted},
+	}
+	for _, tt := range tests {
+		job := &models.Job{ID: uuid.New(), Status: tt.status}
+		store.jobs[job.ID] = job
+		if tt.status == models.JobStatusWaiting {
+			q.HoldWaiting(context.Background(), &queue.QueueItem{JobID: job.ID.String()})
+		}
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/jobs/"+job.ID.String()+"/cancel", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s: status code = %d, want %d", tt.status, resp.StatusCode, tt.wantCode)
+		}
+		if job.Status != tt.wantStatus {
+			t.Errorf("%s: job status = %s, want %s", tt.status, job.Status, tt.wantStatus)
+		}
+
+		switch tt.status {
+		case models.JobStatusDelayed:
+			if len(q.removedDelayed) != 1 || q.removedDelayed[0] != job.ID.String() {
+				t.Errorf("removed from delayed queue = %v, want %s", q.removedDelayed, job.ID)
+			}
+		case models.JobStatusWaiting:
+			if len(q.waiting) != 0 {
+				t.Errorf("held jobs = %d, want the cancelled job dropped", len(q.waiting))
+			}
+		case models.JobStatusRunning:
+			if len(q.cancelsSent) != 1 || q.cancelsSent[0] != job.ID.String() {
+				t.Errorf("cancels published = %v, want %s", q.cancelsSent, job.ID)
+			}
+		}
+	}
+
+	resp, _ := app.Test(httptest.NewRequest("POST", "/api/jobs/"+uuid.NewString()+"/cancel", nil))
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown job status code = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestJobHandler_CancelJob_StartedWhileCancelling(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: store, queue: q, cancels: q, waiting: q}
+	app := fiber.New()
+	app.Post("/api/jobs/:id/cancel", h.CancelJob)
+
+	job := &models.Job{ID: uuid.New(), Status: models.JobStatusPending}
+	store.jobs[job.ID] = job
+	// A worker claims the job after the handler read it as PENDING
+	store.beforeTransition = func(job *models.Job) {
+		job.Status = models.JobStatusRunning
+		store.beforeTransition = nil
+	}
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/jobs/"+job.ID.String()+"/cancel", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Errorf("status code = %d, want 202 from the running-job path", resp.StatusCode)
+	}
+	if job.Status != models.JobStatusRunning {
+		t.Errorf("job status = %s, want RUNNING until the worker stops it", job.Status)
+	}
+	if len(q.cancelsSent) != 1 || q.cancelsSent[0] != job.ID.String() {
+		t.Errorf("cancels published = %v, want %s", q.cancelsSent, job.ID)
+	}
+}
+
+func TestJobHandler_DeleteJob(t *testing.T) {
+	store := newFakeJobStore()
+	h := &JobHandler{jobRepo: store}
+	app := fiber.New()
+	app.Delete("/api/jobs/:id", h.DeleteJob)
+	app.Get("/api/jobs/:id", h.GetJob)
+	app.Get("/api/jobs", h.GetAllJobs)
+
+	finished := &models.Job{ID: uuid.New(), Status: models.JobStatusCompleted, CreatedAt: time.Now()}
+	running := &models.Job{ID: uuid.New(), Status: models.JobStatusRunning, CreatedAt: time.Now()}
+	store.jobs[finished.ID] = finished
+	store.jobs[running.ID] = running
+
+	del := func(id uuid.UUID) int {
+		resp, err := app.Test(httptest.NewRequest("DELETE", "/api/jobs/"+id.String(), nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+	if code := del(running.ID); code != fiber.StatusConflict {
+		t.Errorf("running job: status code = %d, want 409", code)
+	}
+	if code := del(finished.ID); code != fiber.StatusOK {
+		t.Fatalf("finished job: status code = %d, want 200", code)
+	}
+	if code := del(finished.ID); code != fiber.StatusOK {
+		t.Errorf("deleting again: status code = %d, want 200", code)
+	}
+	if code := del(uuid.New()); code != fiber.StatusNotFound {
+		t.Errorf("unknown job: status code = %d, want 404", code)
+	}
+	if running.Status != models.JobStatusRunning {
+		t.Errorf("expected deletion to leave the running job alone, got %s", running.Status)
+	}
+
+	// The deleted job is no longer listed...
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var list struct {
+		Jobs []models.Job `json:"jobs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Jobs) != 1 || list.Jobs[0].ID != running.ID {
+		t.Errorf("expected only the running job to be listed, got %d jobs", len(list.Jobs))
+	}
+
+	// ...but can still be fetched by ID, flagged as deleted
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/jobs/"+finished.ID.String(), nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var fetched models.Job
+	if err := json.NewDecoder(resp.Body).Decode(&fetched); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || !fetched.Deleted || fetched.DeletedAt == nil {
+		t.Errorf("expected the deleted job to be returned with its flag, got %d %+v", resp.StatusCode, fetched)
+	}
+}
+
+func TestJobHandler_SubmitJob_AcceptanceWindows(t *testing.T) {
+	// A daily window opening two hours from now, so submissions are refused until then
+	now := time.Now().UTC()
+	closed, err := acceptance.Parse(fmt.Sprintf("* %s-%s",
+		now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04")), nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: newFakeJobStore(), queue: q}
+	h.SetSubmissionWindows(closed)
+	app := newJobTestApp(h)
+
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:      "user-1",
+		DockerImage: "alpine:latest",
+		Deadline:    now.Add(12 * time.Hour).Format(time.RFC3339),
+	})
+	req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable || body.Error != "outside_acceptance_window" {
+		t.Fatalf("expected 503 outside_acceptance_window, got %d %q", resp.StatusCode, body.Error)
+	}
+	retryAfter, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	if err != nil || retryAfter < 3600 || retryAfter > 2*3600 {
+		t.Errorf("expected Retry-After until the window opens, got %q", resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+	if len(q.immediate)+len(q.delayed) != 0 {
+		t.Error("expected no job to be queued outside the window")
+	}
+
+	// A dry run is still answered, reporting the closed window instead of refusing it
+	req = httptest.NewRequest("POST", "/api/submit?dry_run=true", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var preview models.SubmitJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&preview); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || !preview.OutsideAcceptanceWindow {
+		t.Fatalf("expected a 200 dry run flagged outside the window, got %d %+v", resp.StatusCode, preview)
+	}
+	if preview.NextAcceptanceWindow == nil || preview.NextAcceptanceWindow.Before(now.Add(time.Hour)) {
+		t.Errorf("expected the next window to be reported, got %v", preview.NextAcceptanceWindow)
+	}
+	if len(q.immediate)+len(q.delayed) != 0 {
+		t.Error("expected a dry run to queue nothing")
+	}
+
+	// Inside a window the submission goes through as usual
+	open, _ := acceptance.Parse("* 00:00-24:00", nil)
+	h.SetSubmissionWindows(open)
+	if status, _ := submitJob(t, app); status != fiber.StatusCreated {
+		t.Errorf("expected 201 inside the window, got %d", status)
+	}
+	if len(q.immediate) != 1 {
+		t.Errorf("expected the job to be queued, got %d", len(q.immediate))
+	}
+}
+
+// blockingFetcher holds every carbon call until its context is cancelled
+type blockingFetcher struct {
+	started chan struct{}
+}
+
+func (f blockingFetcher) wait(ctx context.Context) {
+	select {
+	case f.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+}
+
+func (f blockingFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	f.wait(ctx)
+	return nil, ctx.Err()
+}
+
+func (f blockingFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	f.wait(ctx)
+	return nil, ctx.Err()
+}
+
+func TestJobHandler_SubmitJob_BaseContextCancelAbortsScheduling(t *testing.T) {
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := blockingFetcher{started: make(chan struct{}, 1)}
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: newFakeJobStore(), queue: q, scheduler: scheduler.NewCarbonScheduler(fetcher)}
+	h.SetBaseContext(baseCtx)
+	app := newJobTestApp(h)
+
+	// Shut down as soon as the scheduler is waiting on the carbon API
+	go func() {
+		<-fetcher.started
+		cancel()
+	}()
+
+	start := time.Now()
+	status, body := submitJob(t, app)
+
+	// Well inside the 5s scheduling timeout, the job falls back to running now
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected cancellation to abort scheduling promptly, took %v", elapsed)
+	}
+	if status != fiber.StatusCreated || !body.Immediate {
+		t.Errorf("expected immediate 201 fallback, got %d (immediate=%v)", status, body.Immediate)
+	}
+	if len(q.immediate) != 1 {
+		t.Errorf("expected the job to still be queued, got %d immediate items", len(q.immediate))
+	}
+}
+
+// fakeIdempotencyStore keeps idempotency records in memory
+type fakeIdempotencyStore struct {
+	records map[string]*queue.IdempotencyRecord
+}
+
+func (f *fakeIdempotencyStore) ClaimIdempotencyKey(ctx context.Context, userID, key string, record *queue.IdempotencyRecord, ttl time.Duration) (*queue.IdempotencyRecord, error) {
+	if existing, ok := f.records[userID+"/"+key]; ok {
+		copied := *existing
+		return &copied, nil
+	}
+	copied := *record
+	f.records[userID+"/"+key] = &copied
+	return nil, nil
+}
+
+func (f *fakeIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, userID, key string, record *queue.IdempotencyRecord) error {
+	copied := *record
+	f.records[userID+"/"+key] = &copied
+	return nil
+}
+
+func (f *fakeIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	delete(f.records, userID+"/"+key)
+	return nil
+}
+
+func TestJobHandler_SubmitJob_IdempotencyKey(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: q, idempotency: &fakeIdempotencyStore{records: make(map[string]*queue.IdempotencyRecord)}})
+
+	deadline := time.Now().Add(12 * time.Hour).Format(time.RFC3339)
+	submit := func(image string) (*http.Response, models.SubmitJobResponse) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{UserID: "user-1", DockerImage: image, Deadline: deadline})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "retry-me")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		var body models.SubmitJobResponse
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	first, original := submit("alpine:latest")
+	if first.StatusCode != fiber.StatusCreated || original.JobID == "" {
+		t.Fatalf("first submission = %d (job %q), want 201 with a job", first.StatusCode, original.JobID)
+	}
+
+	replay, replayed := submit("alpine:latest")
+	if replay.StatusCode != fiber.StatusOK {
+		t.Errorf("replay status = %d, want 200", replay.StatusCode)
+	}
+	if replayed.JobID != original.JobID || replayed.ScheduledTime != original.ScheduledTime {
+		t.Errorf("replay = %+v, want the original response %+v", replayed, original)
+	}
+	if replay.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("expected the replay to be flagged with Idempotent-Replayed")
+	}
+	if len(store.jobs) != 1 || len(q.immediate) != 1 {
+		t.Errorf("expected one job created and queued, got %d jobs and %d queued", len(store.jobs), len(q.immediate))
+	}
+
+	reused, _ := submit("busybox:latest")
+	if reused.StatusCode != fiber.StatusConflict {
+		t.Errorf("reused key with another payload = %d, want 409", reused.StatusCode)
+	}
+	if len(store.jobs) != 1 {
+		t.Errorf("expected no job for the conflicting submission, got %d jobs", len(store.jobs))
+	}
+}
+
+func submitWithDependencies(t *testing.T, app *fiber.App, dependsOn ...string) (int, []byte) {
+	t.Helper()
+
+	payload, _ := json.Marshal(models.SubmitJobRequest{
+		UserID:      "user-1",
+		DockerImage: "alpine:latest",
+		Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		DependsOn:   dependsOn,
+	})
+	req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body
+}
+
+func TestJobHandler_SubmitJob_WaitsOnDependencies(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: q, waiting: q})
+
+	running := &models.Job{ID: uuid.New(), UserID: "user-1", Status: models.JobStatusRunning}
+	completed := &models.Job{ID: uuid.New(), UserID: "user-1", Status: models.JobStatusCompleted}
+	store.jobs[running.ID] = running
+	store.jobs[completed.ID] = completed
+
+	status, body := submitWithDependencies(t, app, running.ID.String(), completed.ID.String())
+	if status != fiber.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", status, body)
+	}
+
+	var resp models.SubmitJobResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != models.JobStatusWaiting || resp.ExecutionPlan != models.ExecutionPlanWaiting {
+		t.Errorf("expected WAITING with the waiting plan, got %s / %s", resp.Status, resp.ExecutionPlan)
+	}
+
+	jobID := uuid.MustParse(resp.JobID)
+	if got := store.dependencies[jobID]; len(got) != 2 {
+		t.Errorf("expected 2 recorded dependencies, got %v", got)
+	}
+	if len(q.immediate) != 0 || len(q.delayed) != 0 || q.waiting[resp.JobID] == nil {
+		t.Errorf("expected the job held rather than queued, got %d immediate / %d delayed / %d held",
+			len(q.immediate), len(q.delayed), len(q.waiting))
+	}
+
+	// Dependencies that have all completed don't hold the job
+	status, _ = submitWithDependencies(t, app, completed.ID.String())
+	if status != fiber.StatusCreated || len(q.immediate) != 1 {
+		t.Errorf("expected a job with completed dependencies to be queued, got %d", status)
+	}
+}
+
+func TestJobHandler_SubmitJob_RejectsBadDependencies(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: q, waiting: q})
+
+	failed := &models.Job{ID: uuid.New(), UserID: "user-1", Status: models.JobStatusFailed}
+	otherUsers := &models.Job{ID: uuid.New(), UserID: "user-2", Status: models.JobStatusPending}
+	store.jobs[failed.ID] = failed
+	store.jobs[otherUsers.ID] = otherUsers
+
+	tests := []struct {
+		name      string
+		dependsOn []string
+		wantCode  int
+		wantError string
+	}{
+		{"not an ID", []string{"job-1"}, fiber.StatusBadRequest, "invalid_dependency"},
+		{"unknown job", []string{uuid.NewString()}, fiber.StatusBadRequest, "invalid_dependency"},
+		{"other user's job", []string{otherUsers.ID.String()}, fiber.StatusBadRequest, "invalid_dependency"},
+		{"failed dependency", []string{failed.ID.String()}, fiber.StatusConflict, "dependency_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := submitWithDependencies(t, app, tt.dependsOn...)
+			var errResp models.ErrorResponse
+			json.Unmarshal(body, &errResp)
+			if status != tt.wantCode || errResp.Error != tt.wantError {
+				t.Errorf("got %d %q, want %d %q", status, errResp.Error, tt.wantCode, tt.wantError)
+			}
+		})
+	}
+
+	if len(store.jobs) != 2 {
+		t.Errorf("expected no jobs created, have %d", len(store.jobs))
+	}
+}
+
+func TestJobHandler_SubmitJob_ChecksImage(t *testing.T) {
+	policy, err := docker.ParseImagePolicy("", "quay.io")
+	if err != nil {
+		t.Fatalf("ParseImagePolicy returned error: %v", err)
+	}
+	store := newFakeJobStore()
+	h := &JobHandler{jobRepo: store, queue: &fakeJobQueue{}}
+	h.SetImagePolicy(policy)
+	app := newJobTestApp(h)
+
+	tests := []struct {
+		image     string
+		wantCode  int
+		wantError string
+	}{
+		{"python:3.12-slim", fiber.StatusCreated, ""},
+		{"python:3.12:slim", fiber.StatusBadRequest, "invalid_image"},
+		{"Python", fiber.StatusBadRequest, "invalid_image"},
+		{"quay.io/acme/job:latest", fiber.StatusBadRequest, "image_not_allowed"},
+	}
+	for _, tt := range tests {
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: tt.image,
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		if resp.StatusCode != tt.wantCode || (tt.wantError != "" && errResp.Error != tt.wantError) {
+			t.Errorf("%s: got %d %q, want %d %q", tt.image, resp.StatusCode, errResp.Error, tt.wantCode, tt.wantError)
+		}
+	}
+
+	if len(store.jobs) != 1 {
+		t.Errorf("expected only the valid image to create a job, have %d", len(store.jobs))
+	}
+}
+
+func TestJobHandler_SubmitBatch_MixedBatch(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: q})
+
+	deadline := time.Now().Add(12 * time.Hour).Format(time.RFC3339)
+	payload, _ := json.Marshal([]models.SubmitJobRequest{
+		{UserID: "user-1", DockerImage: "alpine:latest", Deadline: deadline},
+		{UserID: "user-1", DockerImage: "alpine:latest", Deadline: "tomorrow"},
+		{UserID: "user-1", DockerImage: "python:3.12", Deadline: deadline},
+	})
+	req := httptest.NewRequest("POST", "/api/submit/batch", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body models.BatchSubmitResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Submitted != 2 || body.Failed != 1 || len(body.Results) != 3 {
+		t.Fatalf("expected 2 submitted and 1 failed of 3, got %+v", body)
+	}
+	for i, result := range body.Results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+	}
+	if bad := body.Results[1]; bad.Job != nil || bad.Error == nil || bad.Error.Error != "invalid_deadline" {
+		t.Errorf("expected the second job to fail with invalid_deadline, got %+v", bad)
+	}
+	for _, i := range []int{0, 2} {
+		if body.Results[i].Error != nil || body.Results[i].Job == nil {
+			t.Errorf("expected job %d to be submitted, got %+v", i, body.Results[i])
+		}
+	}
+
+	if store.batches != 1 || len(store.jobs) != 2 {
+		t.Errorf("expected the 2 valid jobs saved in one batch, got %d jobs in %d batches", len(store.jobs), store.batches)
+	}
+	if len(q.immediate) != 2 {
+		t.Errorf("expected 2 queued jobs, got %d", len(q.immediate))
+	}
+}
+
+func TestJobHandler_SubmitBatch_Size(t *testing.T) {
+	app := newJobTestApp(&JobHandler{jobRepo: newFakeJobStore(), queue: &fakeJobQueue{}})
+
+	for _, size := range []int{0, maxBatchSize + 1} {
+		payload, _ := json.Marshal(make([]models.SubmitJobRequest, size))
+		req := httptest.NewRequest("POST", "/api/submit/batch", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		if resp.StatusCode != fiber.StatusBadRequest || errResp.Error != "invalid_batch" {
+			t.Errorf("batch of %d: got %d %q, want 400 invalid_batch", size, resp.StatusCode, errResp.Error)
+		}
+	}
+}
+
+func TestJobHandler_SubmitJob_NetworkAccess(t *testing.T) {
+	q := &fakeJobQueue{}
+	h := &JobHandler{jobRepo: newFakeJobStore(), queue: q}
+	app := newJobTestApp(h)
+
+	submit := func() (int, models.ErrorResponse) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:        "user-1",
+			DockerImage:   "alpine:latest",
+			Deadline:      time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+			NetworkAccess: true,
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, errResp
+	}
+
+	if status, errResp := submit(); status != fiber.StatusBadRequest || errResp.Error != "network_not_allowed" {
+		t.Errorf("with network access disabled: got %d %q, want 400 network_not_allowed", status, errResp.Error)
+	}
+
+	h.SetNetworkAccess(true)
+	if status, _ := submit(); status != fiber.StatusCreated {
+		t.Fatalf("with network access enabled: expected 201, got %d", status)
+	}
+	if len(q.immediate) != 1 || !q.immediate[0].NetworkAccess {
+		t.Errorf("expected the queued job to carry network access, got %+v", q.immediate)
+	}
+}
+
+func TestJobHandler_SubmitJob_CommandShape(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: q})
+	app.Post("/api/submit/batch", (&JobHandler{jobRepo: store, queue: q}).SubmitBatch)
+	deadline := time.Now().Add(12 * time.Hour).Format(time.RFC3339)
+
+	post := func(path, body string) (int, models.ErrorResponse) {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, errResp
+	}
+	job := func(command string) string {
+		return fmt.Sprintf(`{"user_id": "user-1", "docker_image": "alpine:latest", "deadline": %q, "command": %s}`, deadline, command)
+	}
+
+	if status, _ := post("/api/submit", job(`["echo", "hello"]`)); status != fiber.StatusCreated {
+		t.Fatalf("string array command: expected 201, got %d", status)
+	}
+	if len(q.immediate) != 1 || q.immediate[0].Command == nil || *q.immediate[0].Command != `["echo","hello"]` {
+		t.Errorf("expected the command stored as a JSON array, got %+v", q.immediate)
+	}
+
+	for _, command := range []string{`"echo hello"`, `["echo", 42]`, `{"run": "echo"}`, `[]`} {
+		if status, errResp := post("/api/submit", job(command)); status != fiber.StatusBadRequest || errResp.Error != "invalid_command" {
+			t.Errorf("command %s: got %d %q, want 400 invalid_command", command, status, errResp.Error)
+		}
+	}
+	if status, errResp := post("/api/submit/batch", "["+job(`"echo hello"`)+"]"); status != fiber.StatusBadRequest || errResp.Error != "invalid_command" {
+		t.Errorf("batch with a string command: got %d %q, want 400 invalid_command", status, errResp.Error)
+	}
+	if len(q.immediate) != 1 {
+		t.Errorf("expected malformed commands not to be queued, queue has %d jobs", len(q.immediate))
+	}
+}
+
+func TestJobHandler_SubmitJob_Entrypoint(t *testing.T) {
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: newFakeJobStore(), queue: q})
+
+	submit := func(entrypoint, command []string) (int, models.ErrorResponse) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "alpine/git:latest",
+			Entrypoint:  entrypoint,
+			Command:     command,
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, errResp
+	}
+
+	if status, errResp := submit([]string{""}, []string{"hello"}); status != fiber.StatusBadRequest || errResp.Error != "validation_error" {
+		t.Errorf("empty entrypoint executable: got %d %q, want 400 validation_error", status, errResp.Error)
+	}
+	if len(q.immediate) != 0 {
+		t.Fatalf("expected the malformed job not to be queued, got %+v", q.immediate)
+	}
+
+	if status, _ := submit([]string{"/bin/sh", "-c"}, []string{"echo hello"}); status != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+	if len(q.immediate) != 1 || len(q.immediate[0].Entrypoint) != 2 || q.immediate[0].Entrypoint[0] != "/bin/sh" {
+		t.Errorf("expected the queued job to carry the entrypoint, got %+v", q.immediate)
+	}
+}
+
+func TestJobHandler_SubmitJob_Steps(t *testing.T) {
+	q := &fakeJobQueue{}
+	app := newJobTestApp(&JobHandler{jobRepo: newFakeJobStore(), queue: q})
+
+	submit := func(steps [][]string, command []string) (int, models.ErrorResponse) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "golang:1.22",
+			Steps:       steps,
+			Command:     command,
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var errResp models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return resp.StatusCode, errResp
+	}
+
+	if status, errResp := submit([][]string{{"go", "build"}}, []string{"echo"}); status != fiber.StatusBadRequest || errResp.Error != "invalid_command" {
+		t.Errorf("steps with a command: got %d %q, want 400 invalid_command", status, errResp.Error)
+	}
+	if status, errResp := submit([][]string{{"go", "build"}, {}}, nil); status != fiber.StatusBadRequest || errResp.Error != "invalid_command" {
+		t.Errorf("empty step: got %d %q, want 400 invalid_command", status, errResp.Error)
+	}
+	if len(q.immediate) != 0 {
+		t.Fatalf("expected the malformed jobs not to be queued, got %+v", q.immediate)
+	}
+
+	if status, _ := submit([][]string{{"go", "build", "./..."}, {"go", "test", "./..."}}, nil); status != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+	if len(q.immediate) != 1 || len(q.immediate[0].Steps) != 2 || q.immediate[0].Steps[1][1] != "test" {
+		t.Errorf("expected the queued job to carry its steps, got %+v", q.immediate)
+	}
+}
+
+func TestJobHandler_SubmitJob_Labels(t *testing.T) {
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: &fakeJobQueue{}})
+	app.Get("/api/jobs/:id", (&JobHandler{jobRepo: store}).GetJob)
+
+	submit := func(labels map[string]string) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "alpine:latest",
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+			Labels:      labels,
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, body := submit(map[string]string{"team": "ml", "cost-center": "cc-42"})
+	if status != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, body)
+	}
+	var created models.SubmitJobResponse
+	json.Unmarshal(body, &created)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs/"+created.JobID, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var job models.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	if len(job.Labels) != 2 || job.Labels["team"] != "ml" || job.Labels["cost-center"] != "cc-42" {
+		t.Errorf("job labels = %v, want team=ml and cost-center=cc-42", job.Labels)
+	}
+
+	for name, labels := range map[string]map[string]string{
+		"key with a colon": {"team:ml": "x"},
+		"empty value":      {"team": ""},
+		"value too long":   {"team": strings.Repeat("a", maxLabelValueLength+1)},
+	} {
+		status, body := submit(labels)
+		var errResp models.ErrorResponse
+		json.Unmarshal(body, &errResp)
+		if status != fiber.StatusBadRequest || errResp.Error != "invalid_label" {
+			t.Errorf("%s: got %d %q, want 400 invalid_label", name, status, errResp.Error)
+		}
+	}
+}
+
+func TestJobHandler_SubmitJob_Metadata(t *testing.T) {
+	store := newFakeJobStore()
+	app := newJobTestApp(&JobHandler{jobRepo: store, queue: &fakeJobQueue{}})
+	app.Get("/api/jobs/:id", (&JobHandler{jobRepo: store}).GetJob)
+
+	submit := func(metadata string) (int, []byte) {
+		t.Helper()
+		payload, _ := json.Marshal(models.SubmitJobRequest{
+			UserID:      "user-1",
+			DockerImage: "alpine:latest",
+			Deadline:    time.Now().Add(12 * time.Hour).Format(time.RFC3339),
+			Metadata:    json.RawMessage(metadata),
+		})
+		req := httptest.NewRequest("POST", "/api/submit", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, body := submit(`{"tags": [" nightly ", "nightly", ""], "source": "ci", "build": 42}`)
+	if status != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, body)
+	}
+	var created models.SubmitJobResponse
+	json.Unmarshal(body, &created)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/jobs/"+created.JobID, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var job struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	json.NewDecoder(resp.Body).Decode(&job)
+	tags, _ := job.Metadata["tags"].([]interface{})
+	if len(tags) != 1 || tags[0] != "nightly" || job.Metadata["source"] != "ci" || job.Metadata["build"] != float64(42) {
+		t.Errorf("job metadata = %v, want normalized tags, source and the unknown build field", job.Metadata)
+	}
+
+	for name, metadata := range map[string]string{
+		"not an object":   `"build 42"`,
+		"tags not a list": `{"tags": "nightly"}`,
+		"too many tags":   `{"tags": [` + manyTags(40) + `]}`,
+	} {
+		status, body := submit(metadata)
+		var errResp models.ErrorResponse
+		json.Unmarshal(body, &errResp)
+		if status != fiber.StatusBadRequest || errResp.Error != "invalid_metadata" {
+			t.Errorf("%s: got %d %q, want 400 invalid_metadata", name, status, errResp.Error)
+		}
+	}
+}
+
+// manyTags returns n distinct quoted tags joined with commas
+func manyTags(n int) string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("%q", fmt.Sprintf("tag-%d", i))
+	}
+	return strings.Join(tags, ",")
+}
+
+func TestJobHandler_DependsOnItself(t *testing.T) {
+	store := newFakeJobStore()
+	h := &JobHandler{jobRepo: store}
+	ctx := context.Background()
+
+	newJob, a, b := uuid.New(), uuid.New(), uuid.New()
+	store.AddJobDependencies(ctx, a, []uuid.UUID{b})
+
+	if cyclic, err := h.dependsOnItself(ctx, newJob, []uuid.UUID{a}); err != nil || cyclic {
+		t.Errorf("chain a -> b: cyclic=%v err=%v, want no cycle", cyclic, err)
+	}
+
+	store.AddJobDependencies(ctx, b, []uuid.UUID{newJob})
+	if cyclic, err := h.dependsOnItself(ctx, newJob, []uuid.UUID{a}); err != nil || !cyclic {
+		t.Errorf("chain a -> b -> new job: cyclic=%v err=%v, want a cycle", cyclic, err)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// LogStreamHandler streams live container output over WebSocket
+type LogStreamHandler struct {
+	jobRepo      *database.JobRepository
+	queue        *queue.RedisQueue
+	pollInterval time.Duration
+}
+
+// NewLogStreamHandler creates a new log stream handler
+func NewLogStreamHandler(jobRepo *database.JobRepository, queue *queue.RedisQueue) *LogStreamHandler {
+	return &LogStreamHandler{
+		jobRepo:      jobRepo,
+		queue:        queue,
+		pollInterval: 2 * time.Second, // How often to re-check the status of a job that isn't running yet
+	}
+}
+
+// RequireUpgrade rejects requests that are not WebSocket upgrades
+func (h *LogStreamHandler) RequireUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(models.ErrorResponse{
+			Error:   "upgrade_required",
+			Message: "This endpoint requires a WebSocket connection",
+			Code:    fiber.StatusUpgradeRequired,
+		})
+	}
+
+	if _, err := uuid.Parse(c.Params("id")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	return c.Next()
+}
+
+// StreamLogs handles GET /api/jobs/:id/logs/stream (WebSocket)
+// Messages are JSON objects: {"type":"status","status":...} while waiting for the job
+// to start, {"type":"line","line":...} for each output line, and {"type":"end","status":...}
+// when the job finishes.
+func (h *LogStreamHandler) StreamLogs(conn *websocket.Conn) {
+	jobID, _ := uuid.Parse(conn.Params("id")) // Validated in RequireUpgrade
+	jobIDStr := jobID.String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Detect client disconnects: the read loop fails once the connection closes
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Subscribe before checking status so no lines are missed once the job starts
+	messages, unsubscribe, err := h.queue.SubscribeJobLogs(ctx, jobIDStr)
+	if err != nil {
+		slog.Error("Failed to subscribe to job logs", logging.KeyJobID, jobIDStr, logging.Err(err))
+		h.writeError(conn, "Failed to subscribe to job logs")
+		return
+	}
+	defer unsubscribe()
+
+	// Report the current status, finishing right away if the job is already done
+	lastStatus, done := h.checkStatus(ctx, conn, jobID, "")
+	if done {
+		return
+	}
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Log stream client disconnected", logging.KeyJobID, jobIDStr)
+			return
+
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+			if msg.Type == queue.LogMessageEnd {
+				return
+			}
+
+		case <-ticker.C:
+			// Keep polling until the job is running; afterwards output arrives via pub/sub
+			if lastStatus == models.JobStatusRunning {
+				continue
+			}
+			if lastStatus, done = h.checkStatus(ctx, conn, jobID, lastStatus); done {
+				return
+			}
+		}
+	}
+}
+
+// checkStatus sends a status message when the job status changed since lastStatus.
+// It returns done=true when the stream should end (job finished, missing, or write failed).
+func (h *LogStreamHandler) checkStatus(ctx context.Context, conn *websocket.Conn, jobID uuid.UUID, lastStatus models.JobStatus) (models.JobStatus, bool) {
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	job, err := h.jobRepo.GetJobByID(queryCtx, jobID)
+	if err != nil {
+		if err.Error() == "job not found" {
+			h.writeError(conn, "Job not found")
+			return lastStatus, true
+		}
+		slog.Error("Failed to check job status", logging.KeyJobID, jobID, logging.Err(err))
+		return lastStatus, false
+	}
+
+	if job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed {
+		conn.WriteJSON(queue.LogMessage{Type: queue.LogMessageEnd, Status: string(job.Status)})
+		return job.Status, true
+	}
+
+	if job.Status != lastStatus {
+		if err := conn.WriteJSON(fiber.Map{"type": "status", "status": job.Status}); err != nil {
+			return job.Status, true
+		}
+	}
+
+	return job.Status, false
+}
+
+// writeError sends an error message to the client
+func (h *LogStreamHandler) writeError(conn *websocket.Conn, message string) {
+	conn.WriteJSON(fiber.Map{"type": "error", "message": message})
+}
@@ -0,0 +1,573 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// deadLetterQueue reads and recovers dead-lettered jobs
+type deadLetterQueue interface {
+	PeekDead(ctx context.Context, limit int64) ([]*queue.DeadLetterItem, error)
+	ListDead(ctx context.Context) ([]*queue.DeadLetterItem, error)
+	GetDeadQueueLength(ctx context.Context) (int64, error)
+	RequeueDead(ctx context.Context, jobID string) (*queue.QueueItem, error)
+	RemoveDead(ctx context.Context, jobID string) (*queue.DeadLetterItem, error)
+	EnqueueDead(ctx context.Context, item *queue.QueueItem, reason string) error
+	EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error
+	EnqueueDelayed(ctx context.Context, item *queue.QueueItem) error
+}
+
+// delayedQueueReader lists jobs waiting in the delayed queue
+type delayedQueueReader interface {
+	PeekDelayed(ctx context.Context, limit int64) ([]*queue.QueueItem, error)
+	GetDelayedQueueLength(ctx context.Context) (int64, error)
+}
+
+// deadLetterJobStore resolves and resets the jobs behind dead-letter entries
+type deadLetterJobStore interface {
+	GetJobByID(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	ReopenFailedJob(ctx context.Context, id uuid.UUID, deadline time.Time) (bool, error)
+	TransitionJobStatus(ctx context.Context, id uuid.UUID, from, to models.JobStatus) error
+}
+
+// QueueHandler exposes queue inspection and recovery endpoints
+type QueueHandler struct {
+	queue     deadLetterQueue
+	delayed   delayedQueueReader
+	jobRepo   deadLetterJobStore
+	scheduler *scheduler.CarbonScheduler // Optional: reschedules replayed jobs against the current forecast
+
+	maxReplays    int           // Replays before a job is poisoned (0 = unlimited)
+	replayBackoff time.Duration // Delay before a job's second replay, doubling after each further replay
+}
+
+// NewQueueHandler creates a new queue handler
+func NewQueueHandler(queue *queue.RedisQueue, jobRepo *database.JobRepository) *QueueHandler {
+	return &QueueHandler{
+		queue:         queue,
+		delayed:       queue,
+		jobRepo:       jobRepo,
+		maxReplays:    3,
+		replayBackoff: time.Minute,
+	}
+}
+
+// SetReplayPolicy configures dead-letter replay: jobs replayed maxReplays times are excluded
+// from bulk replay unless forced (0 = unlimited), and each replay after the first waits
+// backoff, doubling every time, so a job that keeps failing can't cause a replay storm
+func (h *QueueHandler) SetReplayPolicy(maxReplays int, backoff time.Duration) {
+	if maxReplays >= 0 {
+		h.maxReplays = maxReplays
+	}
+	if backoff >= 0 {
+		h.replayBackoff = backoff
+	}
+}
+
+// SetScheduler enables carbon-aware scheduling of replayed dead-letter jobs
+func (h *QueueHandler) SetScheduler(s *scheduler.CarbonScheduler) {
+	if s != nil {
+		h.scheduler = s
+	}
+}
+
+// GetDeadLetters handles GET /api/admin/queue/dead
+// Query params: limit (default 50, max 500)
+func (h *QueueHandler) GetDeadLetters(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_limit",
+			Message: "limit must be between 1 and 500",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	items, err := h.queue.PeekDead(ctx, int64(limit))
+	if err != nil {
+		slog.Error("Failed to read dead-letter queue", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "queue_error",
+			Message: "Failed to read dead-letter queue",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	h.markPoisoned(items)
+
+	total, err := h.queue.GetDeadQueueLength(ctx)
+	if err != nil {
+		total = int64(len(items))
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"count": len(items),
+		"total": total,
+	})
+}
+
+// InspectedItem is an upcoming job in the delayed queue
+type InspectedItem struct {
+	JobID         string `json:"job_id"`
+	DockerImage   string `json:"docker_image"`
+	Region        string `json:"region,omitempty"`
+	Priority      int    `json:"priority"`
+	ScheduledTime string `json:"scheduled_time"` // RFC 3339
+	Due           bool   `json:"due"`            // Scheduled time has passed; the promoter will move it shortly
+}
+
+// InspectQueue handles GET /api/admin/queue/inspect
+// Lists delayed jobs, soonest first. Query params: limit (default 50, max 500)
+func (h *QueueHandler) InspectQueue(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_limit",
+			Message: "limit must be between 1 and 500",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	queued, err := h.delayed.PeekDelayed(ctx, int64(limit))
+	if err != nil {
+		slog.Error("Failed to read delayed queue", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "queue_error",
+			Message: "Failed to read delayed queue",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	now := time.Now()
+	items := make([]InspectedItem, 0, len(queued))
+	for _, item := range queued {
+		items = append(items, InspectedItem{
+			JobID:         item.JobID,
+			DockerImage:   item.DockerImage,
+			Region:        item.Region,
+			Priority:      item.Priority,
+			ScheduledTime: item.ScheduledTime.Format(time.RFC3339),
+			Due:           !item.ScheduledTime.After(now),
+		})
+	}
+
+	total, err := h.delayed.GetDelayedQueueLength(ctx)
+	if err != nil {
+		total = int64(len(items))
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"count": len(items),
+		"total": total,
+	})
+}
+
+// RequeueDeadLetter handles POST /api/admin/queue/dead/:id/requeue
+// An operator requeue of a single job runs it immediately, even if it is poisoned. The job
+// is reset to PENDING before it is queued, so a worker claiming it at once runs it rather
+// than skipping it as already failed.
+func (h *QueueHandler) RequeueDeadLetter(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	job, err := h.jobRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		if err.Error() == "job not found" {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "not_found",
+				Message: "Job not found",
+				Code:    fiber.StatusNotFound,
+			})
+		}
+		slog.Error("Failed to get dead-letter job", logging.KeyJobID, jobID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to retrieve job",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	reopened, err := h.jobRepo.ReopenFailedJob(ctx, jobID, job.Deadline)
+	if err != nil {
+		slog.Error("Failed to reset status for requeued job", logging.KeyJobID, jobID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to reset job status",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	if !reopened {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "not_requeueable",
+			Message: "Only FAILED jobs can be requeued; job is " + string(job.Status),
+			Code:    fiber.StatusConflict,
+		})
+	}
+
+	item, err := h.queue.RequeueDead(ctx, jobID.String())
+	if err != nil {
+		// Nothing was queued, so the job is still failed
+		h.restoreFailed(ctx, jobID)
+		if strings.Contains(err.Error(), "not found in dead-letter queue") {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "not_found",
+				Message: "Job not found in dead-letter queue",
+				Code:    fiber.StatusNotFound,
+			})
+		}
+		slog.Error("Failed to requeue dead-letter job", logging.KeyJobID, jobID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "queue_error",
+			Message: "Failed to requeue job",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	slog.Info("Requeued dead-letter job", logging.KeyJobID, jobID)
+
+	return c.JSON(fiber.Map{
+		"job_id":  item.JobID,
+		"status":  models.JobStatusPending,
+		"message": "Job requeued for execution",
+	})
+}
+
+// GetUserDeadLetters handles GET /api/users/:userId/deadletter
+func (h *QueueHandler) GetUserDeadLetters(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID := c.Params("userId")
+	items, err := h.userDeadLetters(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to read dead-letter queue for user", "user_id", userID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "queue_error",
+			Message: "Failed to read dead-letter queue",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	h.markPoisoned(items)
+
+	return c.JSON(fiber.Map{
+		"user_id": userID,
+		"items":   items,
+		"count":   len(items),
+	})
+}
+
+// ReplayDeadLettersRequest selects which of a user's dead-letter jobs to replay.
+// An empty request replays all of them except poisoned ones.
+type ReplayDeadLettersRequest struct {
+	JobIDs         []string `json:"job_ids,omitempty"`         // Only these jobs
+	ReasonContains string   `json:"reason_contains,omitempty"` // Only jobs whose failure reason contains this text
+	Force          bool     `json:"force,omitempty"`           // Also replay poisoned jobs
+	Deadline       string   `json:"deadline,omitempty"`        // RFC 3339; replaces the deadline of jobs whose own has passed
+}
+
+// ReplayedJob reports where a replayed dead-letter job was sent
+type ReplayedJob struct {
+	JobID         string `json:"job_id"`
+	ExecutionPlan string `json:"execution_plan"`
+	ScheduledTime string `json:"scheduled_time"` // RFC 3339
+	Replays       int    `json:"replays"`        // Times the job has now been replayed
+}
+
+// ReplayUserDeadLetters handles POST /api/users/:userId/deadletter/replay
+// Each selected job is removed from the dead-letter queue and scheduled afresh against the
+// current carbon forecast, so a replay may run now or be deferred to a greener window.
+// A job whose deadline has passed would only fail again on reaching a worker, so unless
+// the request gives a new deadline for such jobs, nothing is replayed and it returns 409.
+func (h *QueueHandler) ReplayUserDeadLetters(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var req ReplayDeadLettersRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request body",
+				Code:    fiber.StatusBadRequest,
+			})
+		}
+	}
+
+	var newDeadline time.Time
+	if req.Deadline != "" {
+		deadline, err := time.Parse(time.RFC3339, req.Deadline)
+		if err != nil || !deadline.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_deadline",
+				Message: "Deadline must be a future time in ISO 8601 format (e.g., 2025-12-05T18:00:00Z)",
+				Code:    fiber.StatusBadRequest,
+			})
+		}
+		newDeadline = deadline
+	}
+
+	userID := c.Params("userId")
+	items, err := h.userDeadLetters(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to read dead-letter queue for user", "user_id", userID, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "queue_error",
+			Message: "Failed to read dead-letter queue",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	wanted := make(map[string]bool, len(req.JobIDs))
+	for _, id := range req.JobIDs {
+		wanted[id] = true
+	}
+
+	poisoned := []string{}
+	selected := []*queue.DeadLetterItem{}
+	jobs := make(map[string]*models.Job)
+	expired := []string{}
+	now := time.Now()
+	for _, entry := range items {
+		if len(wanted) > 0 && !wanted[entry.Item.JobID] {
+			continue
+		}
+		if req.ReasonContains != "" && !strings.Contains(entry.Reason, req.ReasonContains) {
+			continue
+		}
+		if h.isPoisoned(entry) && !req.Force {
+			poisoned = append(poisoned, entry.Item.JobID)
+			continue
+		}
+
+		selected = append(selected, entry)
+		if jobID, err := uuid.Parse(entry.Item.JobID); err == nil {
+			if job, err := h.jobRepo.GetJobByID(ctx, jobID); err == nil {
+				jobs[entry.Item.JobID] = job
+				if !job.Deadline.After(now) && newDeadline.IsZero() {
+					expired = append(expired, entry.Item.JobID)
+				}
+			}
+		}
+	}
+
+	if len(expired) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "deadline_passed",
+			Message: "These jobs are past their deadline; replay them with a new deadline: " + strings.Join(expired, ", "),
+			Code:    fiber.StatusConflict,
+		})
+	}
+
+	replayed := []ReplayedJob{}
+	failed := []string{}
+	for _, entry := range selected {
+		job, err := h.replayDeadLetter(ctx, entry, jobs[entry.Item.JobID], newDeadline)
+		if err != nil {
+			slog.Error("Failed to replay dead-letter job", logging.KeyJobID, entry.Item.JobID, logging.Err(err))
+			failed = append(failed, entry.Item.JobID)
+			continue
+		}
+		replayed = append(replayed, job)
+	}
+
+	slog.Info("Replayed dead-letter jobs for user", "user_id", userID, "replayed", len(replayed), "failed", len(failed), "poisoned", len(poisoned))
+
+	return c.JSON(fiber.Map{
+		"user_id":  userID,
+		"replayed": replayed,
+		"count":    len(replayed),
+		"failed":   failed,
+		"poisoned": poisoned,
+	})
+}
+
+// isPoisoned reports whether a job has been replayed too often to be included in bulk replay
+func (h *QueueHandler) isPoisoned(entry *queue.DeadLetterItem) bool {
+	return h.maxReplays > 0 && entry.Item.Replays >= h.maxReplays
+}
+
+// markPoisoned flags poisoned entries for API consumers
+func (h *QueueHandler) markPoisoned(items []*queue.DeadLetterItem) {
+	for _, entry := range items {
+		entry.Poisoned = h.isPoisoned(entry)
+	}
+}
+
+// replayDelay returns how long a job that has already been replayed prior times waits
+// before running again: nothing for the first replay, then replayBackoff doubling each time
+func (h *QueueHandler) replayDelay(prior int) time.Duration {
+	if prior <= 0 || h.replayBackoff <= 0 {
+		return 0
+	}
+	if prior > 10 {
+		prior = 10 // Cap the doubling at 512x the base backoff
+	}
+	return h.replayBackoff << (prior - 1)
+}
+
+// userDeadLetters returns the dead-letter entries that belong to userID
+func (h *QueueHandler) userDeadLetters(ctx context.Context, userID string) ([]*queue.DeadLetterItem, error) {
+	items, err := h.queue.ListDead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := []*queue.DeadLetterItem{}
+	for _, entry := range items {
+		if h.ownerOf(ctx, entry) == userID {
+			owned = append(owned, entry)
+		}
+	}
+	return owned, nil
+}
+
+// ownerOf returns the user who submitted a dead-lettered job. Entries queued before
+// queue items carried a user ID are resolved through the jobs table.
+func (h *QueueHandler) ownerOf(ctx context.Context, entry *queue.DeadLetterItem) string {
+	if entry.Item.UserID != "" {
+		return entry.Item.UserID
+	}
+
+	jobID, err := uuid.Parse(entry.Item.JobID)
+	if err != nil {
+		return ""
+	}
+	job, err := h.jobRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return ""
+	}
+	return job.UserID
+}
+
+// replayDeadLetter moves one entry out of the dead-letter queue and schedules it again.
+// The job is reset to PENDING first, taking newDeadline if its own has passed, so a
+// worker claiming it as soon as it is queued runs it rather than skipping it as failed.
+func (h *QueueHandler) replayDeadLetter(ctx context.Context, entry *queue.DeadLetterItem, job *models.Job, newDeadline time.Time) (ReplayedJob, error) {
+	if job == nil {
+		return ReplayedJob{}, fmt.Errorf("job %s not found", entry.Item.JobID)
+	}
+	deadline := job.Deadline
+	if !deadline.After(time.Now()) {
+		if newDeadline.IsZero() {
+			return ReplayedJob{}, fmt.Errorf("job %s is past its deadline", job.ID)
+		}
+		deadline = newDeadline
+	}
+
+	reopened, err := h.jobRepo.ReopenFailedJob(ctx, job.ID, deadline)
+	if err != nil {
+		return ReplayedJob{}, err
+	}
+	if !reopened {
+		return ReplayedJob{}, fmt.Errorf("job %s is %s, not FAILED", job.ID, job.Status)
+	}
+	job.Deadline = deadline
+
+	removed, err := h.queue.RemoveDead(ctx, entry.Item.JobID)
+	if err != nil {
+		h.restoreFailed(ctx, job.ID)
+		return ReplayedJob{}, err
+	}
+
+	item := removed.Item
+	item.Attempts = 0
+	item.Replays++
+	item.Deadline = deadline
+
+	scheduledTime, immediate := h.replaySchedule(ctx, &item, job)
+	if delay := h.replayDelay(removed.Item.Replays); delay > 0 {
+		// Back off a job that already failed after an earlier replay
+		if notBefore := time.Now().Add(delay); immediate || scheduledTime.Before(notBefore) {
+			scheduledTime, immediate = notBefore, false
+		}
+	}
+	item.ScheduledTime = scheduledTime
+
+	if immediate {
+		err = h.queue.EnqueueImmediate(ctx, &item)
+	} else {
+		err = h.queue.EnqueueDelayed(ctx, &item)
+	}
+	if err != nil {
+		// Put it back so the job isn't lost
+		if restoreErr := h.queue.EnqueueDead(ctx, &removed.Item, removed.Reason); restoreErr != nil {
+			slog.Warn("Failed to restore dead-letter job", logging.KeyJobID, item.JobID, logging.Err(restoreErr))
+		}
+		h.restoreFailed(ctx, job.ID)
+		return ReplayedJob{}, err
+	}
+
+	return ReplayedJob{
+		JobID:         item.JobID,
+		ExecutionPlan: executionPlan(immediate),
+		ScheduledTime: scheduledTime.Format(time.RFC3339),
+		Replays:       item.Replays,
+	}, nil
+}
+
+// restoreFailed puts a job reset for a requeue or replay back to FAILED when it could not
+// be queued after all
+func (h *QueueHandler) restoreFailed(ctx context.Context, jobID uuid.UUID) {
+	if err := h.jobRepo.TransitionJobStatus(ctx, jobID, models.JobStatusPending, models.JobStatusFailed); err != nil {
+		slog.Warn("Failed to restore status of job that could not be queued", logging.KeyJobID, jobID, logging.Err(err))
+	}
+}
+
+// replaySchedule decides when a replayed job runs. Jobs whose deadline leaves no room to
+// wait, or that can't be scheduled, run immediately.
+func (h *QueueHandler) replaySchedule(ctx context.Context, item *queue.QueueItem, job *models.Job) (time.Time, bool) {
+	now := time.Now()
+	if h.scheduler == nil || job == nil || item.Region == "" {
+		return now, true
+	}
+
+	duration := defaultEstimatedDuration // Jobs without an estimate assume the default
+	if job.EstimatedDuration != nil && *job.EstimatedDuration > 0 {
+		duration = time.Duration(*job.EstimatedDuration) * time.Second
+	}
+	if !job.Deadline.After(now.Add(duration)) {
+		return now, true
+	}
+
+	result, err := h.scheduler.Schedule(ctx, &scheduler.ScheduleRequest{
+		Region:     item.Region,
+		Duration:   duration,
+		Deadline:   job.Deadline,
+		WindowSize: 24 * time.Hour,
+	})
+	if err != nil {
+		slog.Warn("Rescheduling replayed job failed, running immediately", logging.KeyJobID, item.JobID, logging.Err(err))
+		return now, true
+	}
+	if result.Immediate {
+		return now, true
+	}
+	return result.ScheduledTime, false
+}
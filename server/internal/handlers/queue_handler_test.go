@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakeDeadLetterQueue keeps dead-letter entries in memory, most recent first
+type fakeDeadLetterQueue struct {
+	fakeJobQueue
+	dead []*queue.DeadLetterItem
+}
+
+func (f *fakeDeadLetterQueue) PeekDead(ctx context.Context, limit int64) ([]*queue.DeadLetterItem, error) {
+	if int64(len(f.dead)) < limit {
+		limit = int64(len(f.dead))
+	}
+	return f.dead[:limit], nil
+}
+
+func (f *fakeDeadLetterQueue) ListDead(ctx context.Context) ([]*queue.DeadLetterItem, error) {
+	return append([]*queue.DeadLetterItem(nil), f.dead...), nil
+}
+
+func (f *fakeDeadLetterQueue) GetDeadQueueLength(ctx context.Context) (int64, error) {
+	return int64(len(f.dead)), nil
+}
+
+func (f *fakeDeadLetterQueue) RequeueDead(ctx context.Context, jobID string) (*queue.QueueItem, error) {
+	entry, err := f.RemoveDead(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &entry.Item, f.EnqueueImmediate(ctx, &entry.Item)
+}
+
+func (f *fakeDeadLetterQueue) RemoveDead(ctx context.Context, jobID string) (*queue.DeadLetterItem, error) {
+	for i, entry := range f.dead {
+		if entry.Item.JobID == jobID {
+			f.dead = append(f.dead[:i], f.dead[i+1:]...)
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s not found in dead-letter queue", jobID)
+}
+
+func (f *fakeDeadLetterQueue) EnqueueDead(ctx context.Context, item *queue.QueueItem, reason string) error {
+	f.dead = append([]*queue.DeadLetterItem{{Item: *item, Reason: reason, Attempts: item.Attempts}}, f.dead...)
+	return nil
+}
+
+const testUserTokenSecret = "user-secret"
+
+// deadLetterFixture stores a job and dead-letters it. legacy entries carry no user ID
+// and must be resolved through the job store.
+func deadLetterFixture(store *fakeJobStore, q *fakeDeadLetterQueue, userID string, deadline time.Time, reason string, legacy bool) string {
+	region := "US-EAST"
+	job := &models.Job{ID: uuid.New(), UserID: userID, DockerImage: "alpine:latest", Status: models.JobStatusFailed, Deadline: deadline, Region: &region}
+	store.jobs[job.ID] = job
+
+	item := queue.QueueItem{JobID: job.ID.String(), UserID: userID, DockerImage: job.DockerImage, Region: region, Attempts: 3}
+	if legacy {
+		item.UserID = ""
+	}
+	q.EnqueueDead(context.Background(), &item, reason)
+	return job.ID.String()
+}
+
+func newDeadLetterTestApp(h *QueueHandler) *fiber.App {
+	app := fiber.New()
+	userDead := app.Group("/api/users/:userId/deadletter", RequireUser(testUserTokenSecret, "admin-token"))
+	userDead.Get("/", h.GetUserDeadLetters)
+	userDead.Post("/replay", h.ReplayUserDeadLetters)
+	return app
+}
+
+func deadLetterRequest(t *testing.T, app *fiber.App, method, url, token string, body interface{}) (int, map[string]json.RawMessage) {
+	t.Helper()
+
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var decoded map[string]json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestQueueHandler_GetUserDeadLetters_OnlyOwnJobs(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeDeadLetterQueue{}
+	deadline := time.Now().Add(12 * time.Hour)
+	mine := deadLetterFixture(store, q, "user-1", deadline, "exit code 1", false)
+	legacy := deadLetterFixture(store, q, "user-1", deadline, "exit code 137", true)
+	deadLetterFixture(store, q, "user-2", deadline, "exit code 1", false)
+
+	app := newDeadLetterTestApp(&QueueHandler{queue: q, jobRepo: store})
+
+	status, body := deadLetterRequest(t, app, "GET", "/api/users/user-1/deadletter", UserToken(testUserTokenSecret, "user-1"), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	var items []queue.DeadLetterItem
+	json.Unmarshal(body["items"], &items)
+	if len(items) != 2 {
+		t.Fatalf("expected user-1's 2 dead-letter jobs, got %d", len(items))
+	}
+	got := map[string]bool{items[0].Item.JobID: true, items[1].Item.JobID: true}
+	if !got[mine] || !got[legacy] {
+		t.Errorf("expected %s and %s, got %v", mine, legacy, got)
+	}
+}
+
+func TestQueueHandler_UserDeadLetters_RequireUserToken(t *testing.T) {
+	app := newDeadLetterTestApp(&QueueHandler{queue: &fakeDeadLetterQueue{}, jobRepo: newFakeJobStore()})
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"missing token", "", fiber.StatusUnauthorized},
+		{"another user's token", UserToken(testUserTokenSecret, "user-2"), fiber.StatusUnauthorized},
+		{"own token", UserToken(testUserTokenSecret, "user-1"), fiber.StatusOK},
+		{"admin token", "admin-token", fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if status, _ := deadLetterRequest(t, app, "GET", "/api/users/user-1/deadletter", tc.token, nil); status != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, status)
+			}
+		})
+	}
+
+	disabled := fiber.New()
+	disabled.Get("/api/users/:userId/deadletter", RequireUser("", "admin-token"), func(c *fiber.Ctx) error { return nil })
+	if status, _ := deadLetterRequest(t, disabled, "GET", "/api/users/user-1/deadletter", "admin-token", nil); status != fiber.StatusForbidden {
+		t.Errorf("expected 403 without a user token secret, got %d", status)
+	}
+}
+
+func TestQueueHandler_ReplayUserDeadLetters_ReschedulesOwnJobs(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeDeadLetterQueue{}
+	deferrable := deadLetterFixture(store, q, "user-1", time.Now().Add(12*time.Hour), "exit code 1", false)
+	overdue := deadLetterFixture(store, q, "user-1", time.Now().Add(-time.Hour), "exit code 1", true)
+	other := deadLetterFixture(store, q, "user-2", time.Now().Add(12*time.Hour), "exit code 1", false)
+
+	h := &QueueHandler{queue: q, jobRepo: store}
+	h.SetScheduler(scheduler.NewCarbonScheduler(dirtyNowFetcher{}))
+	app := newDeadLetterTestApp(h)
+	token := UserToken(testUserTokenSecret, "user-1")
+
+	// The overdue job would fail again as soon as a worker saw it, so nothing is replayed
+	status, body := deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", token, nil)
+	if status != fiber.StatusConflict {
+		t.Fatalf("expected 409 for an overdue job without a new deadline, got %d", status)
+	}
+	if len(q.dead) != 3 || len(q.immediate)+len(q.delayed) != 0 {
+		t.Fatalf("expected nothing replayed, got %d dead / %d queued", len(q.dead), len(q.immediate)+len(q.delayed))
+	}
+	if status, _ := deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", token, ReplayDeadLettersRequest{Deadline: "yesterday"}); status != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for an unparsable deadline, got %d", status)
+	}
+
+	// A new deadline close enough that there's no time to wait for a greener window
+	newDeadline := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	status, body = deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", token,
+		ReplayDeadLettersRequest{Deadline: newDeadline.Format(time.RFC3339)})
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	var replayed []ReplayedJob
+	json.Unmarshal(body["replayed"], &replayed)
+	if len(replayed) != 2 {
+		t.Fatalf("expected user-1's 2 jobs to be replayed, got %+v", replayed)
+	}
+	plans := map[string]string{}
+	for _, job := range replayed {
+		plans[job.JobID] = job.ExecutionPlan
+	}
+	if plans[deferrable] != models.ExecutionPlanScheduled {
+		t.Errorf("expected the job with time to spare to wait for the clean window, got %q", plans[deferrable])
+	}
+	if plans[overdue] != models.ExecutionPlanImmediate {
+		t.Errorf("expected the overdue job to run now, got %q", plans[overdue])
+	}
+	if got := store.jobs[uuid.MustParse(overdue)].Deadline; !got.Equal(newDeadline) {
+		t.Errorf("expected the overdue job to take the new deadline %v, got %v", newDeadline, got)
+	}
+	if len(q.immediate) == 1 && !q.immediate[0].Deadline.Equal(newDeadline) {
+		t.Errorf("expected the queued item to carry the new deadline, got %v", q.immediate[0].Deadline)
+	}
+
+	if len(q.delayed) != 1 || len(q.immediate) != 1 {
+		t.Errorf("expected 1 delayed and 1 immediate replay, got %d / %d", len(q.delayed), len(q.immediate))
+	}
+	for _, item := range append(q.delayed, q.immediate...) {
+		if item.Attempts != 0 {
+			t.Errorf("expected attempts reset on replay, got %d for %s", item.Attempts, item.JobID)
+		}
+	}
+	if len(q.dead) != 1 || q.dead[0].Item.JobID != other {
+		t.Errorf("expected only user-2's job left dead-lettered, got %+v", q.dead)
+	}
+	if store.jobs[uuid.MustParse(deferrable)].Status != models.JobStatusPending {
+		t.Error("expected the replayed job to be reset to PENDING")
+	}
+	if store.jobs[uuid.MustParse(other)].Status != models.JobStatusFailed {
+		t.Error("another user's job should be untouched")
+	}
+}
+
+func TestQueueHandler_ReplayUserDeadLetters_Filtered(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeDeadLetterQueue{}
+	deadline := time.Now().Add(12 * time.Hour)
+	oom := deadLetterFixture(store, q, "user-1", deadline, "exit code 137", false)
+	deadLetterFixture(store, q, "user-1", deadline, "exit code 1", false)
+	otherUsers := deadLetterFixture(store, q, "user-2", deadline, "exit code 137", false)
+
+	app := newDeadLetterTestApp(&QueueHandler{queue: q, jobRepo: store})
+
+	// Naming another user's job doesn't let it through
+	status, body := deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", UserToken(testUserTokenSecret, "user-1"),
+		ReplayDeadLettersRequest{JobIDs: []string{oom, otherUsers}, ReasonContains: "137"})
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	var replayed []ReplayedJob
+	json.Unmarshal(body["replayed"], &replayed)
+	if len(replayed) != 1 || replayed[0].JobID != oom {
+		t.Fatalf("expected only %s to be replayed, got %+v", oom, replayed)
+	}
+	if len(q.dead) != 2 {
+		t.Errorf("expected 2 entries left dead-lettered, got %d", len(q.dead))
+	}
+}
+
+func TestQueueHandler_ReplayUserDeadLetters_BacksOffAndPoisons(t *testing.T) {
+	store := newFakeJobStore()
+	q := &fakeDeadLetterQueue{}
+	jobID := deadLetterFixture(store, q, "user-1", time.Now().Add(12*time.Hour), "exit code 1", false)
+
+	h := &QueueHandler{queue: q, jobRepo: store}
+	h.SetReplayPolicy(2, time.Minute)
+	app := newDeadLetterTestApp(h)
+	token := UserToken(testUserTokenSecret, "user-1")
+	url := "/api/users/user-1/deadletter/replay"
+
+	// failAgain simulates the worker running the replayed job and dead-lettering it again
+	failAgain := func() *queue.QueueItem {
+		t.Helper()
+		queued := append(q.immediate, q.delayed...)
+		if len(queued) != 1 {
+			t.Fatalf("expected exactly one replayed job in flight, got %d", len(queued))
+		}
+		item := queued[0]
+		q.immediate, q.delayed = nil, nil
+		q.EnqueueDead(context.Background(), item, "exit code 1")
+		store.jobs[uuid.MustParse(jobID)].Status = models.JobStatusFailed
+		return item
+	}
+
+	// First replay runs at once
+	status, body := deadLetterRequest(t, app, "POST", url, token, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(q.immediate) != 1 {
+		t.Fatalf("expected the first replay to run immediately, got %d immediate / %d delayed", len(q.immediate), len(q.delayed))
+	}
+	if item := failAgain(); item.Replays != 1 {
+		t.Errorf("expected replay count 1, got %d", item.Replays)
+	}
+
+	// Second replay backs off
+	before := time.Now()
+	deadLetterRequest(t, app, "POST", url, token, nil)
+	if len(q.delayed) != 1 {
+		t.Fatalf("expected the second replay to be delayed, got %d immediate / %d delayed", len(q.immediate), len(q.delayed))
+	}
+	if wait := q.delayed[0].ScheduledTime.Sub(before); wait < time.Minute {
+		t.Errorf("expected at least a 1m backoff, got %s", wait)
+	}
+	if item := failAgain(); item.Replays != 2 {
+		t.Errorf("expected replay count 2, got %d", item.Replays)
+	}
+
+	// The job has hit the max: listings flag it and bulk replay skips it
+	_, body = deadLetterRequest(t, app, "GET", "/api/users/user-1/deadletter", token, nil)
+	var items []*queue.DeadLetterItem
+	json.Unmarshal(body["items"], &items)
+	if len(items) != 1 || !items[0].Poisoned {
+		t.Fatalf("expected the job to be listed as poisoned, got %+v", items)
+	}
+
+	_, body = deadLetterRequest(t, app, "POST", url, token, nil)
+	var poisoned []string
+	json.Unmarshal(body["poisoned"], &poisoned)
+	if len(poisoned) != 1 || poisoned[0] != jobID {
+		t.Errorf("expected %s to be reported as poisoned, got %v", jobID, poisoned)
+	}
+	if len(q.immediate)+len(q.delayed) != 0 || len(q.dead) != 1 {
+		t.Fatalf("expected bulk replay to leave the poisoned job in the dead-letter queue")
+	}
+
+	// Forcing replays it anyway, with a doubled backoff
+	before = time.Now()
+	_, body = deadLetterRequest(t, app, "POST", url, token, ReplayDeadLettersRequest{Force: true})
+	var replayed []ReplayedJob
+	json.Unmarshal(body["replayed"], &replayed)
+	if len(replayed) != 1 || replayed[0].Replays != 3 {
+		t.Fatalf("expected a forced replay counted as the third, got %+v", replayed)
+	}
+	if len(q.delayed) != 1 || q.delayed[0].ScheduledTime.Sub(before) < 2*time.Minute {
+		t.Errorf("expected the forced replay to back off at least 2m")
+	}
+}
+
+// statusRecordingDeadQueue notes the job's stored status at the moment it is requeued
+type statusRecordingDeadQueue struct {
+	*fakeDeadLetterQueue
+	store      *fakeJobStore
+	atEnqueue  []models.JobStatus
+	requeueErr error
+}
+
+func (f *statusRecordingDeadQueue) RequeueDead(ctx context.Context, jobID string) (*queue.QueueItem, error) {
+	f.atEnqueue = append(f.atEnqueue, f.store.jobs[uuid.MustParse(jobID)].Status)
+	if f.requeueErr != nil {
+		return nil, f.requeueErr
+	}
+	return f.fakeDeadLetterQueue.RequeueDead(ctx, jobID)
+}
+
+func (f *statusRecordingDeadQueue) EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error {
+	f.atEnqueue = append(f.atEnqueue, f.store.jobs[uuid.MustParse(item.JobID)].Status)
+	if f.requeueErr != nil {
+		return f.requeueErr
+	}
+	return f.fakeDeadLetterQueue.EnqueueImmediate(ctx, item)
+}
+
+func TestQueueHandler_ReplayUserDeadLetters_ResetsStatusBeforeQueueing(t *testing.T) {
+	store := newFakeJobStore()
+	q := &statusRecordingDeadQueue{fakeDeadLetterQueue: &fakeDeadLetterQueue{}, store: store}
+	jobID := deadLetterFixture(store, q.fakeDeadLetterQueue, "user-1", time.Now().Add(12*time.Hour), "exit code 1", false)
+	app := newDeadLetterTestApp(&QueueHandler{queue: q, jobRepo: store})
+	token := UserToken(testUserTokenSecret, "user-1")
+
+	// A failed enqueue puts the entry back and leaves the job FAILED
+	q.requeueErr = errors.New("redis down")
+	_, body := deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", token, nil)
+	var failed []string
+	json.Unmarshal(body["failed"], &failed)
+	if len(failed) != 1 || len(q.dead) != 1 || store.jobs[uuid.MustParse(jobID)].Status != models.JobStatusFailed {
+		t.Fatalf("expected the job reported failed, still dead-lettered and FAILED; got %v, %d dead, %s",
+			failed, len(q.dead), store.jobs[uuid.MustParse(jobID)].Status)
+	}
+
+	q.requeueErr = nil
+	deadLetterRequest(t, app, "POST", "/api/users/user-1/deadletter/replay", token, nil)
+	if len(q.atEnqueue) != 2 || q.atEnqueue[1] != models.JobStatusPending || len(q.immediate) != 1 {
+		t.Errorf("expected the job PENDING when queued, got %v with %d queued", q.atEnqueue, len(q.immediate))
+	}
+}
+
+func TestQueueHandler_RequeueDeadLetter_ResetsStatusBeforeQueueing(t *testing.T) {
+	store := newFakeJobStore()
+	q := &statusRecordingDeadQueue{fakeDeadLetterQueue: &fakeDeadLetterQueue{}, store: store}
+	jobID := deadLetterFixture(store, q.fakeDeadLetterQueue, "user-1", time.Now().Add(12*time.Hour), "exit code 1", false)
+
+	app := fiber.New()
+	app.Post("/api/admin/queue/dead/:id/requeue", (&QueueHandler{queue: q, jobRepo: store}).RequeueDeadLetter)
+	requeue := func(id string) int {
+		t.Helper()
+		status, _ := deadLetterRequest(t, app, "POST", "/api/admin/queue/dead/"+id+"/requeue", "", nil)
+		return status
+	}
+
+	// A failed enqueue leaves the job FAILED and dead-lettered
+	q.requeueErr = errors.New("redis down")
+	if status := requeue(jobID); status != fiber.StatusInternalServerError {
+		t.Fatalf("expected 500 when the enqueue fails, got %d", status)
+	}
+	if got := store.jobs[uuid.MustParse(jobID)].Status; got != models.JobStatusFailed {
+		t.Errorf("expected the status rolled back to FAILED, got %s", got)
+	}
+
+	q.requeueErr = nil
+	if status := requeue(jobID); status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	// A worker claiming the job as soon as it is queued must find it PENDING
+	if len(q.atEnqueue) != 2 || q.atEnqueue[1] != models.JobStatusPending {
+		t.Errorf("expected the job PENDING when queued, got %v", q.atEnqueue)
+	}
+	if len(q.immediate) != 1 || len(q.dead) != 0 {
+		t.Errorf("expected the job moved to the immediate queue, got %d immediate / %d dead", len(q.immediate), len(q.dead))
+	}
+
+	// It is now PENDING, so requeueing again is refused without touching the queue
+	if status := requeue(jobID); status != fiber.StatusConflict {
+		t.Errorf("expected 409 for a job that isn't FAILED, got %d", status)
+	}
+	if status := requeue(uuid.NewString()); status != fiber.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", status)
+	}
+	if len(q.atEnqueue) != 2 {
+		t.Errorf("expected refused requeues not to reach the queue, got %d", len(q.atEnqueue))
+	}
+}
+
+// fakeDelayedQueue holds delayed jobs in memory, soonest scheduled first
+type fakeDelayedQueue struct {
+	items []*queue.QueueItem
+}
+
+func (f *fakeDelayedQueue) PeekDelayed(ctx context.Context, limit int64) ([]*queue.QueueItem, error) {
+	if int64(len(f.items)) < limit {
+		limit = int64(len(f.items))
+	}
+	return f.items[:limit], nil
+}
+
+func (f *fakeDelayedQueue) GetDelayedQueueLength(ctx context.Context) (int64, error) {
+	return int64(len(f.items)), nil
+}
+
+func TestQueueHandler_InspectQueue(t *testing.T) {
+	now := time.Now()
+	delayed := &fakeDelayedQueue{items: []*queue.QueueItem{
+		{JobID: "due", DockerImage: "alpine:latest", Region: "US-EAST", Priority: 5, ScheduledTime: now.Add(-time.Minute)},
+		{JobID: "later", DockerImage: "python:3.12", Region: "EU-NORTH", ScheduledTime: now.Add(2 * time.Hour)},
+		{JobID: "latest", DockerImage: "alpine:latest", Region: "EU-NORTH", ScheduledTime: now.Add(5 * time.Hour)},
+	}}
+
+	app := fiber.New()
+	app.Get("/api/admin/queue/inspect", (&QueueHandler{delayed: delayed}).InspectQueue)
+
+	status, body := deadLetterRequest(t, app, "GET", "/api/admin/queue/inspect?limit=2", "", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	var items []InspectedItem
+	var total int
+	json.Unmarshal(body["items"], &items)
+	json.Unmarshal(body["total"], &total)
+	if len(items) != 2 || total != 3 {
+		t.Fatalf("expected 2 of 3 delayed jobs, got %d of %d", len(items), total)
+	}
+	if items[0].JobID != "due" || items[0].Region != "US-EAST" || items[0].Priority != 5 || !items[0].Due {
+		t.Errorf("unexpected first item %+v", items[0])
+	}
+	if items[1].JobID != "later" || items[1].Region != "EU-NORTH" || items[1].Due {
+		t.Errorf("unexpected second item %+v", items[1])
+	}
+	if scheduled, err := time.Parse(time.RFC3339, items[1].ScheduledTime); err != nil || scheduled.Unix() != now.Add(2*time.Hour).Unix() {
+		t.Errorf("expected scheduled time %v, got %q", now.Add(2*time.Hour), items[1].ScheduledTime)
+	}
+
+	if status, _ := deadLetterRequest(t, app, "GET", "/api/admin/queue/inspect?limit=0", "", nil); status != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for limit=0, got %d", status)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// rateCounter counts requests per key in a fixed window shared by all API replicas
+type rateCounter interface {
+	IncrRateLimit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+}
+
+// RateLimit allows each client limit requests per window and answers further requests
+// with 429 and a Retry-After header. Clients presenting a valid admin or user token are
+// identified by that token (hashed, so tokens never reach Redis); everyone else,
+// including clients sending tokens that don't validate, is identified by their IP.
+// A limit of zero or less disables rate limiting. If the counter is unavailable the
+// request is let through rather than taking the API down with Redis.
+func RateLimit(counter rateCounter, limit int, window time.Duration, userTokenSecret, adminToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limit <= 0 || window <= 0 {
+			return c.Next()
+		}
+
+		count, ttl, err := counter.IncrRateLimit(c.UserContext(), rateLimitKey(c, userTokenSecret, adminToken), window)
+		if err != nil {
+			slog.Warn("Rate limiter unavailable, allowing request", logging.Err(err))
+			return c.Next()
+		}
+
+		remaining := int64(limit) - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+
+		if count > int64(limit) {
+			retryAfter := int(math.Ceil(ttl.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Error:   "rate_limited",
+				Message: "Rate limit exceeded, retry after " + strconv.Itoa(retryAfter) + "s",
+				Code:    fiber.StatusTooManyRequests,
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// rateLimitKey identifies the client a request is counted against. A bearer token only
+// earns its own budget once it validates, otherwise a client could bypass the limit by
+// sending a fresh random token with every request.
+func rateLimitKey(c *fiber.Ctx, userTokenSecret, adminToken string) string {
+	token := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if token != "" && validToken(c.Path(), token, userTokenSecret, adminToken) {
+		sum := sha256.Sum256([]byte(token))
+		return "key:" + hex.EncodeToString(sum[:])
+	}
+	return "ip:" + c.IP()
+}
+
+// validToken reports whether token is the admin token or the user token for the
+// /api/users/:userId route being requested. The limiter runs before routing, so the
+// user ID is taken from the path rather than the route parameters.
+func validToken(path, token, userTokenSecret, adminToken string) bool {
+	if adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+		return true
+	}
+	if userTokenSecret == "" {
+		return false
+	}
+	rest, ok := strings.CutPrefix(path, "/api/users/")
+	if !ok {
+		return false
+	}
+	userID, _, _ := strings.Cut(rest, "/")
+	if userID == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(UserToken(userTokenSecret, userID))) == 1
+}
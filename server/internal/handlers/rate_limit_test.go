@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newRateLimitTestApp(t *testing.T, server *miniredis.Miniredis, limit int) *fiber.App {
+	t.Helper()
+
+	q, err := queue.NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+
+	app := fiber.New()
+	app.Use(RateLimit(q, limit, time.Minute, testUserTokenSecret, "admin-token"))
+	app.Get("/api/jobs", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/api/users/:userId/deadletter", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	return app
+}
+
+func rateLimitRequest(t *testing.T, app *fiber.App, token string) int {
+	t.Helper()
+	return rateLimitRequestPath(t, app, "/api/jobs", token)
+}
+
+func rateLimitRequestPath(t *testing.T, app *fiber.App, path, token string) int {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode == fiber.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header on 429")
+	}
+	return resp.StatusCode
+}
+
+func TestRateLimit_RejectsRequestOverLimit(t *testing.T) {
+	const limit = 5
+	app := newRateLimitTestApp(t, miniredis.RunT(t), limit)
+
+	for i := 1; i <= limit; i++ {
+		if status := rateLimitRequest(t, app, "admin-token"); status != fiber.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, status)
+		}
+	}
+	if status := rateLimitRequest(t, app, "admin-token"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("request %d: expected 429, got %d", limit+1, status)
+	}
+
+	// Valid user tokens and anonymous clients have their own budgets
+	userPath := "/api/users/user-1/deadletter"
+	if status := rateLimitRequestPath(t, app, userPath, UserToken(testUserTokenSecret, "user-1")); status != fiber.StatusOK {
+		t.Errorf("expected a user token to be allowed, got %d", status)
+	}
+	if status := rateLimitRequest(t, app, ""); status != fiber.StatusOK {
+		t.Errorf("expected an anonymous client to be allowed, got %d", status)
+	}
+}
+
+func TestRateLimit_SharedAcrossReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	replicaA := newRateLimitTestApp(t, server, 2)
+	replicaB := newRateLimitTestApp(t, server, 2)
+
+	rateLimitRequest(t, replicaA, "admin-token")
+	rateLimitRequest(t, replicaB, "admin-token")
+	if status := rateLimitRequest(t, replicaA, "admin-token"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("expected the limit to span replicas, got %d", status)
+	}
+
+	server.FastForward(time.Minute)
+	if status := rateLimitRequest(t, replicaB, "admin-token"); status != fiber.StatusOK {
+		t.Errorf("expected the limit to reset after the window, got %d", status)
+	}
+}
+
+func TestRateLimit_UnvalidatedTokensShareIPBudget(t *testing.T) {
+	const limit = 3
+	app := newRateLimitTestApp(t, miniredis.RunT(t), limit)
+
+	// A fresh random token on every request must not earn a fresh budget
+	for i := 1; i <= limit; i++ {
+		token := "random-" + strconv.Itoa(i)
+		if status := rateLimitRequest(t, app, token); status != fiber.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, status)
+		}
+	}
+	if status := rateLimitRequest(t, app, "random-next"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("expected unvalidated tokens to share the IP budget, got %d", status)
+	}
+	if status := rateLimitRequest(t, app, ""); status != fiber.StatusTooManyRequests {
+		t.Errorf("expected anonymous requests from the same IP to share the budget, got %d", status)
+	}
+
+	// Another user's token is not valid on this user's routes
+	otherToken := UserToken(testUserTokenSecret, "user-2")
+	if status := rateLimitRequestPath(t, app, "/api/users/user-1/deadletter", otherToken); status != fiber.StatusTooManyRequests {
+		t.Errorf("expected a token for another user to fall back to the IP budget, got %d", status)
+	}
+	if status := rateLimitRequestPath(t, app, "/api/users/user-2/deadletter", otherToken); status != fiber.StatusOK {
+		t.Errorf("expected a valid user token to have its own budget, got %d", status)
+	}
+}
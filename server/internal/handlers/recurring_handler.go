@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/recurring"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// recurringStore persists recurring job schedules
+type recurringStore interface {
+	CreateRecurringJob(ctx context.Context, job *models.RecurringJob) error
+	ListRecurringJobs(ctx context.Context, userID string, limit int) ([]*models.RecurringJob, error)
+	DeleteRecurringJob(ctx context.Context, id uuid.UUID) error
+}
+
+// RecurringHandler handles recurring job endpoints
+type RecurringHandler struct {
+	store recurringStore
+	jobs  *JobHandler // Validates job specs the way /api/submit does
+}
+
+// NewRecurringHandler creates a recurring job handler
+func NewRecurringHandler(repo *database.RecurringJobRepository, jobs *JobHandler) *RecurringHandler {
+	return &RecurringHandler{
+		store: repo,
+		jobs:  jobs,
+	}
+}
+
+// CreateRecurring handles POST /api/recurring
+// Stores a cron schedule with a job spec. Each run is submitted with its deadline set
+// to the schedule's next fire time, so the carbon scheduler places it within the interval.
+func (h *RecurringHandler) CreateRecurring(c *fiber.Ctx) error {
+	var req models.CreateRecurringJobRequest
+	if err := c.BodyParser(&req); err != nil {
+		if isCommandTypeError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(invalidCommandShape)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	if strings.TrimSpace(req.Schedule) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "schedule is required",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+	if req.Deadline != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "deadline is set per run to the schedule's next fire time; leave it out",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	schedule, err := recurring.ParseSchedule(req.Schedule, req.Timezone)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_schedule",
+			Message: err.Error(),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	// Check the spec as the first run will submit it
+	nextRun := schedule.Next(time.Now())
+	spec := req.SubmitJobRequest
+	spec.Deadline = schedule.Next(nextRun).UTC().Format(time.RFC3339)
+	if _, errResp := h.jobs.validateSubmission(&spec); errResp != nil {
+		return c.Status(errResp.Code).JSON(errResp)
+	}
+	spec.Deadline = ""
+	spec.IdempotencyKey = ""
+
+	job := &models.RecurringJob{
+		UserID:         spec.UserID,
+		CronExpression: strings.TrimSpace(req.Schedule),
+		Timezone:       schedule.Location().String(),
+		JobSpec:        spec,
+		NextRunAt:      nextRun,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.store.CreateRecurringJob(ctx, job); err != nil {
+		slog.Error("Failed to create recurring job", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to create recurring job",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	slog.Info("Recurring job created",
+		"recurring_job_id", job.ID,
+		"schedule", job.CronExpression,
+		"timezone", job.Timezone,
+		"next_run_at", job.NextRunAt)
+
+	return c.Status(fiber.StatusCreated).JSON(job)
+}
+
+// ListRecurring handles GET /api/recurring
+// Lists recurring jobs, newest first, optionally for one user (?user_id=)
+func (h *RecurringHandler) ListRecurring(c *fiber.Ctx) error {
+	userID := c.Query("user_id")
+	limit := c.QueryInt("limit", 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	jobs, err := h.store.ListRecurringJobs(ctx, userID, limit)
+	if err != nil {
+		slog.Error("Failed to list recurring jobs", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to list recurring jobs",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+	if jobs == nil {
+		jobs = []*models.RecurringJob{}
+	}
+
+	return c.JSON(fiber.Map{
+		"count":          len(jobs),
+		"recurring_jobs": jobs,
+	})
+}
+
+// DeleteRecurring handles DELETE /api/recurring/:id
+// Stops future runs of a schedule; jobs it already submitted are left alone
+func (h *RecurringHandler) DeleteRecurring(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_id",
+			Message: "Invalid recurring job ID format",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.store.DeleteRecurringJob(ctx, id); err != nil {
+		if err.Error() == "recurring job not found" {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "not_found",
+				Message: "Recurring job not found",
+				Code:    fiber.StatusNotFound,
+			})
+		}
+		slog.Error("Failed to delete recurring job", "recurring_job_id", id, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to delete recurring job",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakeRecurringStore keeps recurring jobs in memory
+type fakeRecurringStore struct {
+	jobs []*models.RecurringJob
+}
+
+func (f *fakeRecurringStore) CreateRecurringJob(ctx context.Context, job *models.RecurringJob) error {
+	job.ID = uuid.New()
+	job.CreatedAt = time.Now()
+	f.jobs = append(f.jobs, job)
+	return nil
+}
+
+func (f *fakeRecurringStore) ListRecurringJobs(ctx context.Context, userID string, limit int) ([]*models.RecurringJob, error) {
+	var jobs []*models.RecurringJob
+	for _, job := range f.jobs {
+		if userID == "" || job.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (f *fakeRecurringStore) DeleteRecurringJob(ctx context.Context, id uuid.UUID) error {
+	for i, job := range f.jobs {
+		if job.ID == id {
+			f.jobs = append(f.jobs[:i], f.jobs[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("recurring job not found")
+}
+
+func newRecurringTestApp(store *fakeRecurringStore) *fiber.App {
+	h := &RecurringHandler{store: store, jobs: &JobHandler{}}
+	app := fiber.New()
+	app.Post("/api/recurring", h.CreateRecurring)
+	app.Get("/api/recurring", h.ListRecurring)
+	app.Delete("/api/recurring/:id", h.DeleteRecurring)
+	return app
+}
+
+func createRecurring(t *testing.T, app *fiber.App, req models.CreateRecurringJobRequest) (int, []byte) {
+	t.Helper()
+
+	payload, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest("POST", "/api/recurring", bytes.NewReader(payload))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(httpReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	return resp.StatusCode, body.Bytes()
+}
+
+func TestRecurringHandler_CreateListDelete(t *testing.T) {
+	store := &fakeRecurringStore{}
+	app := newRecurringTestApp(store)
+
+	status, body := createRecurring(t, app, models.CreateRecurringJobRequest{
+		SubmitJobRequest: models.SubmitJobRequest{UserID: "user-1", DockerImage: "alpine:latest"},
+		Schedule:         "0 2 * * *",
+		Timezone:         "Europe/Berlin",
+	})
+	if status != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, body)
+	}
+
+	var created models.RecurringJob
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Timezone != "Europe/Berlin" || created.JobSpec.DockerImage != "alpine:latest" {
+		t.Errorf("unexpected recurring job: %+v", created)
+	}
+	if !created.NextRunAt.After(time.Now()) || created.NextRunAt.In(time.UTC).Minute() != 0 {
+		t.Errorf("expected next_run_at on the hour in the future, got %v", created.NextRunAt)
+	}
+	if created.JobSpec.Deadline != "" {
+		t.Errorf("expected no stored deadline, got %q", created.JobSpec.Deadline)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/recurring?user_id=user-1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var list struct {
+		Count         int                   `json:"count"`
+		RecurringJobs []models.RecurringJob `json:"recurring_jobs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if list.Count != 1 || list.RecurringJobs[0].ID != created.ID {
+		t.Errorf("expected the created job listed, got %+v", list)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/api/recurring/"+created.ID.String(), nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/api/recurring/"+created.ID.String(), nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("expected 404 for a deleted job, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecurringHandler_CreateRejectsInvalidRequests(t *testing.T) {
+	app := newRecurringTestApp(&fakeRecurringStore{})
+	spec := models.SubmitJobRequest{UserID: "user-1", DockerImage: "alpine:latest"}
+
+	tests := []struct {
+		name      string
+		req       models.CreateRecurringJobRequest
+		wantError string
+	}{
+		{"missing schedule", models.CreateRecurringJobRequest{SubmitJobRequest: spec}, "validation_error"},
+		{"bad cron", models.CreateRecurringJobRequest{SubmitJobRequest: spec, Schedule: "every day"}, "invalid_schedule"},
+		{"bad timezone", models.CreateRecurringJobRequest{SubmitJobRequest: spec, Schedule: "@daily", Timezone: "Nowhere/Town"}, "invalid_schedule"},
+		{"missing image", models.CreateRecurringJobRequest{SubmitJobRequest: models.SubmitJobRequest{UserID: "user-1"}, Schedule: "@daily"}, "validation_error"},
+		{"fixed deadline", models.CreateRecurringJobRequest{
+			SubmitJobRequest: models.SubmitJobRequest{UserID: "user-1", DockerImage: "alpine:latest", Deadline: time.Now().Add(time.Hour).Format(time.RFC3339)},
+			Schedule:         "@daily",
+		}, "validation_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := createRecurring(t, app, tt.req)
+			if status != fiber.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", status, body)
+			}
+			var errResp models.ErrorResponse
+			if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error != tt.wantError {
+				t.Errorf("expected error %q, got %s", tt.wantError, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	maxSimulateJobs     = 100 // Largest batch a simulation accepts
+	simulateConcurrency = 8   // Jobs scheduled at once during a simulation
+)
+
+// ScheduleHandler simulates scheduling decisions without persisting anything
+type ScheduleHandler struct {
+	scheduler *scheduler.CarbonScheduler
+}
+
+// NewScheduleHandler creates a schedule handler; scheduler may be nil when carbon-aware
+// scheduling is disabled
+func NewScheduleHandler(scheduler *scheduler.CarbonScheduler) *ScheduleHandler {
+	return &ScheduleHandler{scheduler: scheduler}
+}
+
+// simulatedRequest is a validated simulation job
+type simulatedRequest struct {
+	image    string
+	region   string
+	duration time.Duration
+	deadline time.Time
+}
+
+// Simulate handles POST /api/schedule/simulate
+// Schedules each hypothetical job as SubmitJob would and reports the decisions and their
+// combined carbon savings. Nothing is stored or queued.
+func (h *ScheduleHandler) Simulate(c *fiber.Ctx) error {
+	if h.scheduler == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+			Error:   "scheduler_unavailable",
+			Message: "Carbon-aware scheduling is not configured",
+			Code:    fiber.StatusServiceUnavailable,
+		})
+	}
+
+	var req models.SimulateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request body",
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+	if len(req.Jobs) == 0 || len(req.Jobs) > maxSimulateJobs {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "validation_error",
+			Message: fmt.Sprintf("jobs must list between 1 and %d jobs", maxSimulateJobs),
+			Code:    fiber.StatusBadRequest,
+		})
+	}
+
+	requests := make([]simulatedRequest, len(req.Jobs))
+	for i, job := range req.Jobs {
+		parsed, err := parseSimulateJob(job)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "validation_error",
+				Message: fmt.Sprintf("jobs[%d]: %v", i, err),
+				Code:    fiber.StatusBadRequest,
+			})
+		}
+		requests[i] = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), 15*time.Second)
+	defer cancel()
+
+	// Each goroutine writes only its own slot, so the results need no lock
+	results := make([]models.SimulatedJob, len(requests))
+	var g errgroup.Group
+	g.SetLimit(simulateConcurrency)
+	for i, r := range requests {
+		i, r := i, r
+		g.Go(func() error {
+			results[i] = h.simulate(ctx, r)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	response := models.SimulateScheduleResponse{Jobs: results}
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			response.Failed++
+		case result.Immediate:
+			response.Immediate++
+		default:
+			response.Scheduled++
+		}
+		if result.Error == "" && result.IntensityScale != string(carbon.IntensityScaleRelative) {
+			response.TotalCarbonSavings += result.CarbonSavings
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// simulate schedules one job, reporting a scheduling failure on the job itself
+func (h *ScheduleHandler) simulate(ctx context.Context, r simulatedRequest) models.SimulatedJob {
+	result := models.SimulatedJob{DockerImage: r.image, Region: r.region}
+
+	decision, err := h.scheduler.Schedule(ctx, &scheduler.ScheduleRequest{
+		Region:     r.region,
+		Duration:   r.duration,
+		Deadline:   r.deadline,
+		WindowSize: 24 * time.Hour,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "Simulated scheduling failed", logging.KeyRegion, r.region, logging.Err(err))
+		result.Error = err.Error()
+		return result
+	}
+
+	result.ExecutionPlan = executionPlan(decision.Immediate)
+	result.ScheduledTime = decision.ScheduledTime.Format(time.RFC3339)
+	result.Immediate = decision.Immediate
+	result.CurrentIntensity = decision.CurrentIntensity
+	result.ExpectedIntensity = decision.ExpectedIntensity
+	result.CarbonSavings = decision.CarbonSavings
+	result.IntensityScale = string(decision.IntensityScale)
+	result.DeferralCapped = decision.DeferralCapped
+	return result
+}
+
+// parseSimulateJob validates a simulation job, applying SubmitJob's defaults
+func parseSimulateJob(job models.SimulateJob) (simulatedRequest, error) {
+	if job.DockerImage == "" || job.Deadline == "" {
+		return simulatedRequest{}, errors.New("docker_image and deadline are required")
+	}
+
+	deadline, err := time.Parse(time.RFC3339, job.Deadline)
+	if err != nil {
+		return simulatedRequest{}, errors.New("deadline must be in ISO 8601 format (e.g., 2025-12-05T18:00:00Z)")
+	}
+	if deadline.Before(time.Now()) {
+		return simulatedRequest{}, errors.New("deadline must be in the future")
+	}
+
+	region := "US-EAST" // Default region, as at submission
+	if job.Region != nil && *job.Region != "" {
+		if !regionPattern.MatchString(*job.Region) {
+			return simulatedRequest{}, fmt.Errorf("region %q is not a valid region code", *job.Region)
+		}
+		region = *job.Region
+	}
+
+	duration := defaultEstimatedDuration
+	if job.EstimatedDuration != nil {
+		if *job.EstimatedDuration <= 0 {
+			return simulatedRequest{}, errors.New("estimated_duration must be a positive number of seconds")
+		}
+		duration = time.Duration(*job.EstimatedDuration) * time.Second
+	}
+
+	return simulatedRequest{image: job.DockerImage, region: region, duration: duration, deadline: deadline}, nil
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+)
+
+// regionalFetcher forecasts a clean grid in CLEAN, a dirty grid now in DIRTY, and fails elsewhere
+type regionalFetcher struct{}
+
+func (regionalFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	var intensities []float64
+	switch region {
+	case "CLEAN":
+		intensities = []float64{100, 120, 130}
+	case "DIRTY":
+		intensities = []float64{600, 550, 120, 110, 500}
+	default:
+		return nil, errors.New("no forecast for region")
+	}
+	forecast := make([]carbon.CarbonIntensity, len(intensities))
+	for i, intensity := range intensities {
+		forecast[i] = carbon.CarbonIntensity{Region: region, Timestamp: startTime.Add(time.Duration(i) * time.Hour), Intensity: intensity}
+	}
+	return forecast, nil
+}
+
+func (regionalFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	return nil, errors.New("not used")
+}
+
+func TestScheduleHandler_Simulate(t *testing.T) {
+	app := fiber.New()
+	app.Post("/api/schedule/simulate", NewScheduleHandler(scheduler.NewCarbonScheduler(regionalFetcher{})).Simulate)
+
+	simulate := func(jobs []models.SimulateJob) (int, models.SimulateScheduleResponse) {
+		payload, _ := json.Marshal(models.SimulateScheduleRequest{Jobs: jobs})
+		req := httptest.NewRequest("POST", "/api/schedule/simulate", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body models.SimulateScheduleResponse
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode, body
+	}
+
+	deadline := time.Now().Add(12 * time.Hour).Format(time.RFC3339)
+	region := func(r string) *string { return &r }
+	oneHour := 3600
+
+	status, body := simulate([]models.SimulateJob{
+		{DockerImage: "alpine:latest", Region: region("CLEAN"), Deadline: deadline},
+		{DockerImage: "python:3.12", Region: region("DIRTY"), Deadline: deadline, EstimatedDuration: &oneHour},
+		{DockerImage: "alpine:latest", Region: region("DIRTY"), Deadline: deadline},
+		{DockerImage: "alpine:latest", Region: region("NOWHERE"), Deadline: deadline},
+	})
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(body.Jobs) != 4 || body.Immediate != 1 || body.Scheduled != 2 || body.Failed != 1 {
+		t.Fatalf("expected 1 immediate, 2 scheduled and 1 failed job, got %+v", body)
+	}
+
+	clean, dirty := body.Jobs[0], body.Jobs[1]
+	if !clean.Immediate || clean.ExecutionPlan != models.ExecutionPlanImmediate || clean.Region != "CLEAN" {
+		t.Errorf("expected the clean-grid job to run now, got %+v", clean)
+	}
+	if dirty.Immediate || dirty.ExecutionPlan != models.ExecutionPlanScheduled || dirty.CarbonSavings != 490 || dirty.DockerImage != "python:3.12" {
+		t.Errorf("expected the dirty-grid job deferred to save 490, got %+v", dirty)
+	}
+	if body.Jobs[3].Error == "" {
+		t.Errorf("expected the unschedulable job to report its error, got %+v", body.Jobs[3])
+	}
+	if want := clean.CarbonSavings + dirty.CarbonSavings + body.Jobs[2].CarbonSavings; body.TotalCarbonSavings != want {
+		t.Errorf("expected total savings %.1f, got %.1f", want, body.TotalCarbonSavings)
+	}
+
+	for name, jobs := range map[string][]models.SimulateJob{
+		"empty batch":      nil,
+		"missing deadline": {{DockerImage: "alpine:latest"}},
+		"past deadline":    {{DockerImage: "alpine:latest", Deadline: time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+		"bad region":       {{DockerImage: "alpine:latest", Region: region("us east!"), Deadline: deadline}},
+	} {
+		if status, _ := simulate(jobs); status != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// startSLOCounter counts the jobs due since a time that started on time and in total
+type startSLOCounter interface {
+	CountStartSLO(ctx context.Context, since time.Time) (onTime, total int, err error)
+}
+
+// jobTotals summarizes all jobs for the stats dashboard
+type jobTotals interface {
+	CountByStatus(ctx context.Context) (map[models.JobStatus]int, error)
+	GetJobTotals(ctx context.Context) (co2SavedGrams float64, avgDuration time.Duration, err error)
+}
+
+// cacheCounter counts carbon cache entries
+type cacheCounter interface {
+	CountEntries(ctx context.Context) (int, error)
+}
+
+// queueStats reports queue depths and live workers
+type queueStats interface {
+	GetImmediateQueueLength(ctx context.Context) (int64, error)
+	GetDelayedQueueLength(ctx context.Context) (int64, error)
+	GetDeadQueueLength(ctx context.Context) (int64, error)
+	GetActiveWorkers(ctx context.Context) ([]string, error)
+}
+
+// StatsHandler reports service level statistics
+type StatsHandler struct {
+	jobs      startSLOCounter
+	totals    jobTotals
+	cache     cacheCounter
+	queue     queueStats
+	objective slo.Objective
+}
+
+// NewStatsHandler creates a stats handler judging job starts against objective
+func NewStatsHandler(jobRepo *database.JobRepository, cacheRepo *database.CarbonCacheRepository, queue *queue.RedisQueue, objective slo.Objective) *StatsHandler {
+	return &StatsHandler{
+		jobs:      jobRepo,
+		totals:    jobRepo,
+		cache:     cacheRepo,
+		queue:     queue,
+		objective: objective,
+	}
+}
+
+// GetStats handles GET /api/stats
+// Returns job counts by status, CO2 saved, average run time, carbon cache size, active
+// workers and queue depths in one response, for dashboards.
+func (h *StatsHandler) GetStats(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := h.collectStats(ctx)
+	if err != nil {
+		slog.Error("Failed to collect stats", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "stats_error",
+			Message: "Failed to collect stats",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// collectStats assembles the stats response from the repositories and the queue
+func (h *StatsHandler) collectStats(ctx context.Context) (*models.StatsResponse, error) {
+	counts, err := h.totals.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	co2Saved, avgDuration, err := h.totals.GetJobTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cacheEntries, err := h.cache.CountEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.StatsResponse{
+		Pending:   counts[models.JobStatusPending],
+		Delayed:   counts[models.JobStatusDelayed],
+		Running:   counts[models.JobStatusRunning],
+		Completed: counts[models.JobStatusCompleted],
+		Failed:    counts[models.JobStatusFailed],
+		Cancelled: counts[models.JobStatusCancelled],
+		Waiting:   counts[models.JobStatusWaiting],
+
+		TotalCO2SavedGrams: co2Saved,
+		AvgDurationSeconds: avgDuration.Seconds(),
+		CarbonCacheEntries: cacheEntries,
+		Timestamp:          time.Now(),
+	}
+	for _, count := range counts {
+		response.TotalJobs += count
+	}
+
+	workers, err := h.queue.GetActiveWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response.ActiveWorkers = len(workers)
+	if response.QueueDepthImmediate, err = h.queue.GetImmediateQueueLength(ctx); err != nil {
+		return nil, err
+	}
+	if response.QueueDepthDelayed, err = h.queue.GetDelayedQueueLength(ctx); err != nil {
+		return nil, err
+	}
+	if response.QueueDepthDead, err = h.queue.GetDeadQueueLength(ctx); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetSLO handles GET /api/stats/slo
+// Returns start-time SLO compliance over the objective's rolling window, or over ?window=
+// (a duration such as 1h or 168h) when given.
+func (h *StatsHandler) GetSLO(c *fiber.Ctx) error {
+	objective := h.objective
+	if param := c.Query("window"); param != "" {
+		window, err := time.ParseDuration(param)
+		if err != nil || window <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_window",
+				Message: "window must be a positive duration such as 1h or 168h",
+				Code:    fiber.StatusBadRequest,
+			})
+		}
+		objective.Window = window
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	onTime, total, err := h.jobs.CountStartSLO(ctx, time.Now().Add(-objective.Window))
+	if err != nil {
+		slog.Error("Failed to compute SLO compliance", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to compute SLO compliance",
+			Code:    fiber.StatusInternalServerError,
+		})
+	}
+
+	return c.JSON(objective.Report(onTime, total))
+}
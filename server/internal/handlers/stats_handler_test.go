@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+)
+
+// jobStart is a started job's scheduled time and whether it started on time
+type jobStart struct {
+	scheduled time.Time
+	onTime    bool
+}
+
+// fakeJobStarts counts started jobs in memory
+type fakeJobStarts []jobStart
+
+func (f fakeJobStarts) CountStartSLO(ctx context.Context, since time.Time) (onTime, total int, err error) {
+	for _, job := range f {
+		if job.scheduled.Before(since) {
+			continue
+		}
+		total++
+		if job.onTime {
+			onTime++
+		}
+	}
+	return onTime, total, nil
+}
+
+func TestStatsHandler_GetSLO(t *testing.T) {
+	now := time.Now()
+	objective := slo.Objective{StartThreshold: time.Minute, Target: 0.99, Window: 24 * time.Hour}
+
+	// Within the last day 297 of 300 jobs started on time; a week ago 20 more were late
+	var starts fakeJobStarts
+	for i := 0; i < 300; i++ {
+		starts = append(starts, jobStart{now.Add(-time.Duration(i) * 4 * time.Minute), i%100 != 0})
+	}
+	for i := 0; i < 20; i++ {
+		starts = append(starts, jobStart{now.Add(-6 * 24 * time.Hour), false})
+	}
+
+	app := fiber.New()
+	app.Get("/api/stats/slo", (&StatsHandler{jobs: starts, objective: objective}).GetSLO)
+
+	get := func(query string) (int, slo.Report) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/stats/slo"+query, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var report slo.Report
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode, report
+	}
+
+	status, report := get("")
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if report.Jobs != 300 || report.OnTime != 297 || report.Late != 3 {
+		t.Errorf("expected 297 of 300 jobs on time over the last day, got %+v", report)
+	}
+	if math.Abs(report.Compliance-0.99) > 1e-9 || !report.Met || report.WindowSeconds != 86400 || report.StartThresholdSeconds != 60 {
+		t.Errorf("expected 99%% compliance meeting the objective, got %+v", report)
+	}
+
+	// Over a week the old late jobs break the objective
+	status, report = get("?window=168h")
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if report.Jobs != 320 || report.OnTime != 297 || report.Met || report.BudgetLeft >= 0 {
+		t.Errorf("expected the weekly window to miss the objective, got %+v", report)
+	}
+
+	for _, query := range []string{"?window=soon", "?window=-1h"} {
+		if status, _ := get(query); status != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, status)
+		}
+	}
+}
+
+// fakeJobTotals returns seeded job counts and totals
+type fakeJobTotals struct {
+	counts      map[models.JobStatus]int
+	co2Saved    float64
+	avgDuration time.Duration
+}
+
+func (f fakeJobTotals) CountByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	return f.counts, nil
+}
+
+func (f fakeJobTotals) GetJobTotals(ctx context.Context) (float64, time.Duration, error) {
+	return f.co2Saved, f.avgDuration, nil
+}
+
+// fakeCacheCounter reports a fixed number of cache entries
+type fakeCacheCounter int
+
+func (f fakeCacheCounter) CountEntries(ctx context.Context) (int, error) {
+	return int(f), nil
+}
+
+func TestStatsHandler_GetStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisQueue, err := queue.NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { redisQueue.Close() })
+
+	ctx := context.Background()
+	redisQueue.EnqueueImmediate(ctx, &queue.QueueItem{JobID: "job-1", DockerImage: "alpine:latest"})
+	redisQueue.EnqueueDelayed(ctx, &queue.QueueItem{JobID: "job-2", DockerImage: "alpine:latest", ScheduledTime: time.Now().Add(time.Hour)})
+	redisQueue.EnqueueDelayed(ctx, &queue.QueueItem{JobID: "job-3", DockerImage: "alpine:latest", ScheduledTime: time.Now().Add(time.Hour)})
+	redisQueue.SetWorkerHeartbeat(ctx, "node-a", 30)
+
+	h := &StatsHandler{
+		totals: fakeJobTotals{
+			counts: map[models.JobStatus]int{
+				models.JobStatusPending:   2,
+				models.JobStatusDelayed:   3,
+				models.JobStatusRunning:   1,
+				models.JobStatusCompleted: 10,
+				models.JobStatusFailed:    4,
+				models.JobStatusCancelled: 0,
+			},
+			co2Saved:    1234.5,
+			avgDuration: 90 * time.Second,
+		},
+		cache: fakeCacheCounter(288),
+		queue: redisQueue,
+	}
+	app := fiber.New()
+	app.Get("/api/stats", h.GetStats)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/stats", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var stats models.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if stats.TotalJobs != 20 || stats.Pending != 2 || stats.Delayed != 3 || stats.Running != 1 || stats.Completed != 10 || stats.Failed != 4 {
+		t.Errorf("job counts = %+v, want 20 total split 2/3/1/10/4", stats)
+	}
+	if stats.TotalCO2SavedGrams != 1234.5 || stats.AvgDurationSeconds != 90 {
+		t.Errorf("totals = %v g saved, %vs average; want 1234.5 and 90", stats.TotalCO2SavedGrams, stats.AvgDurationSeconds)
+	}
+	if stats.CarbonCacheEntries != 288 {
+		t.Errorf("cache entries = %d, want 288", stats.CarbonCacheEntries)
+	}
+	if stats.ActiveWorkers != 1 || stats.QueueDepthImmediate != 1 || stats.QueueDepthDelayed != 2 || stats.QueueDepthDead != 0 {
+		t.Errorf("workers/queues = %d/%d/%d/%d, want 1/1/2/0",
+			stats.ActiveWorkers, stats.QueueDepthImmediate, stats.QueueDepthDelayed, stats.QueueDepthDead)
+	}
+}
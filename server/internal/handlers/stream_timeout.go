@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// IsStreamingPath reports whether path is a streaming route. Streaming endpoints
+// (WebSocket, SSE, exports) are registered under paths ending in "/stream".
+func IsStreamingPath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(path, "/"), "/stream")
+}
+
+// StreamWriteTimeout returns a fasthttp HeaderReceived hook that gives streaming routes
+// streamTimeout to write their response, while every other route keeps the server's
+// short WriteTimeout as protection against slow clients.
+// fasthttp applies the write deadline to the whole streamed body, so without this an
+// SSE or export stream would be cut off once the server's WriteTimeout elapses.
+// (WebSocket upgrades already clear the deadline when the connection is hijacked.)
+func StreamWriteTimeout(streamTimeout time.Duration) func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+		if streamTimeout > 0 && IsStreamingPath(path) {
+			return fasthttp.RequestConfig{WriteTimeout: streamTimeout}
+		}
+		// Zero values keep the server defaults
+		return fasthttp.RequestConfig{}
+	}
+}
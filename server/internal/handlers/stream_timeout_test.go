@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	testWriteTimeout = 200 * time.Millisecond
+	testTicks        = 10
+	testTickInterval = 50 * time.Millisecond // testTicks*testTickInterval outlasts testWriteTimeout
+)
+
+// startStreamServer serves an SSE-style stream that outlives the write timeout and
+// returns the base URL
+func startStreamServer(t *testing.T, hook func(*fasthttp.RequestHeader) fasthttp.RequestConfig) string {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true, WriteTimeout: testWriteTimeout})
+	app.Server().HeaderReceived = hook
+	app.Get("/api/test/stream", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			for i := 0; i < testTicks; i++ {
+				time.Sleep(testTickInterval)
+				w.WriteString("data: tick\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "http://" + ln.Addr().String()
+}
+
+// countTicks reads the stream until it ends and returns how many events arrived
+func countTicks(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	ticks := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data:") {
+			ticks++
+		}
+	}
+	return ticks
+}
+
+func TestStreamWriteTimeout_StreamOutlivesWriteTimeout(t *testing.T) {
+	url := startStreamServer(t, StreamWriteTimeout(time.Minute))
+
+	if got := countTicks(t, url+"/api/test/stream"); got != testTicks {
+		t.Errorf("received %d events, want all %d", got, testTicks)
+	}
+}
+
+func TestStreamWriteTimeout_DefaultTimeoutCutsStreamOff(t *testing.T) {
+	// Control: without the hook the same stream is killed by the server's write timeout
+	url := startStreamServer(t, nil)
+
+	if got := countTicks(t, url+"/api/test/stream"); got >= testTicks {
+		t.Errorf("received all %d events; expected the write timeout to cut the stream short", got)
+	}
+}
+
+func TestStreamWriteTimeout_OnlyStreamingPaths(t *testing.T) {
+	hook := StreamWriteTimeout(time.Hour)
+
+	for path, want := range map[string]time.Duration{
+		"/api/jobs/123/logs/stream":         time.Hour,
+		"/api/jobs/123/logs/stream?token=x": time.Hour,
+		"/api/jobs/123/logs":                0,
+		"/api/jobs":                         0,
+		"/api/streamers":                    0,
+	} {
+		var header fasthttp.RequestHeader
+		header.SetRequestURI(path)
+		if got := hook(&header).WriteTimeout; got != want {
+			t.Errorf("%s: write timeout = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserToken returns the bearer token that authenticates userID: the hex-encoded
+// HMAC-SHA256 of the user ID keyed with the server's user token secret
+func UserToken(secret, userID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequireUser rejects requests unless their "Authorization: Bearer <token>" header carries
+// the token for the :userId route parameter, or the admin token. When no user token
+// secret is configured the per-user endpoints are disabled entirely.
+func RequireUser(secret, adminToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if secret == "" {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "user_auth_disabled",
+				Message: "Per-user endpoints are disabled (USER_TOKEN_SECRET is not set)",
+				Code:    fiber.StatusForbidden,
+			})
+		}
+
+		provided := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		expected := UserToken(secret, c.Params("userId"))
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1 {
+			return c.Next()
+		}
+		if adminToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1 {
+			return c.Next()
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Valid token for this user required",
+			Code:    fiber.StatusUnauthorized,
+		})
+	}
+}
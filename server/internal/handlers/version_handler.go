@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/Sambit-Mondal/karbos/server/internal/version"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VersionHandler reports which build is running and how it is configured
+type VersionHandler struct {
+	carbonProvider string
+	environment    string
+}
+
+// VersionResponse is the build information plus the deployment's runtime configuration
+type VersionResponse struct {
+	version.BuildInfo
+	CarbonProvider string `json:"carbon_provider"`
+	Environment    string `json:"environment"`
+}
+
+// NewVersionHandler creates a new version handler
+func NewVersionHandler(carbonProvider, environment string) *VersionHandler {
+	return &VersionHandler{
+		carbonProvider: carbonProvider,
+		environment:    environment,
+	}
+}
+
+// GetVersion handles GET /api/version
+func (h *VersionHandler) GetVersion(c *fiber.Ctx) error {
+	return c.JSON(VersionResponse{
+		BuildInfo:      version.Get(),
+		CarbonProvider: h.carbonProvider,
+		Environment:    h.environment,
+	})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestVersionHandler_GetVersion(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/version", NewVersionHandler("csv", "production").GetVersion)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/version", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	for _, field := range []string{"version", "commit", "build_time", "go_version", "carbon_provider", "environment"} {
+		if body[field] == "" {
+			t.Errorf("expected %q to be present, got %v", field, body)
+		}
+	}
+	if body["carbon_provider"] != "csv" || body["environment"] != "production" {
+		t.Errorf("expected configured provider and environment, got %v", body)
+	}
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Output formats for LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Common attribute keys, so every package logs the same names
+const (
+	KeyJobID    = "job_id"
+	KeyWorkerID = "worker_id"
+	KeyRegion   = "region"
+	KeyDuration = "duration"
+	KeyError    = "error"
+
+	KeyRequestID = "request_id" // Originating API request, from the X-Request-ID header
+)
+
+// Setup installs a process-wide slog logger writing to stderr. format is "json" or
+// "text"; empty picks text in development and JSON everywhere else. The standard log
+// package is routed through the same handler, so remaining log.Printf calls come out
+// in the chosen format too.
+func Setup(format, level, environment string) (*slog.Logger, error) {
+	logger, err := newLogger(os.Stderr, format, level, environment)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+func newLogger(w io.Writer, format, level, environment string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = FormatJSON
+		if environment == "development" {
+			format = FormatText
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(ContextHandler(slog.NewJSONHandler(w, opts))), nil
+	case FormatText:
+		return slog.New(ContextHandler(slog.NewTextHandler(w, opts))), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %q or %q)", format, FormatJSON, FormatText)
+	}
+}
+
+// ParseLevel parses debug, info, warn or error (empty means info)
+func ParseLevel(level string) (slog.Level, error) {
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+	return lvl, nil
+}
+
+// Err returns err as an attribute under the common "error" key
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLogger_JSONHasStructuredFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, FormatJSON, "info", "production")
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+
+	logger.Info("Job completed",
+		KeyJobID, "job-1",
+		KeyWorkerID, "node-a/worker-1",
+		KeyRegion, "EU-NORTH",
+		KeyDuration, 1500*time.Millisecond,
+		Err(errors.New("boom")),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":     "INFO",
+		"msg":       "Job completed",
+		KeyJobID:    "job-1",
+		KeyWorkerID: "node-a/worker-1",
+		KeyRegion:   "EU-NORTH",
+		KeyDuration: float64(1500 * time.Millisecond),
+		KeyError:    "boom",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+func TestNewLogger_DefaultFormatFollowsEnvironment(t *testing.T) {
+	var dev, prod bytes.Buffer
+	devLogger, _ := newLogger(&dev, "", "", "development")
+	prodLogger, _ := newLogger(&prod, "", "", "production")
+
+	devLogger.Info("hello", KeyJobID, "job-1")
+	prodLogger.Info("hello", KeyJobID, "job-1")
+
+	if !strings.Contains(dev.String(), "job_id=job-1") || json.Valid(dev.Bytes()) {
+		t.Errorf("expected text output in development, got %q", dev.String())
+	}
+	if !json.Valid(prod.Bytes()) {
+		t.Errorf("expected JSON output outside development, got %q", prod.String())
+	}
+}
+
+func TestNewLogger_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, FormatJSON, "warn", "production")
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	if strings.Contains(buf.String(), "dropped") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("expected only warnings and above, got %q", buf.String())
+	}
+}
+
+func TestNewLogger_RejectsUnknownSettings(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "xml", "info", ""); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, FormatJSON, "loud", ""); err == nil {
+		t.Error("expected an unknown level to be rejected")
+	}
+	if level, err := ParseLevel("DEBUG"); err != nil || level != slog.LevelDebug {
+		t.Errorf("ParseLevel(DEBUG) = %v, %v", level, err)
+	}
+}
+
+func TestNewLogger_AddsRequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, FormatJSON, "info", "production")
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.With(KeyJobID, "job-1").InfoContext(ctx, "Processing job")
+	logger.InfoContext(context.Background(), "No request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, got %q", buf.String())
+	}
+	var withID, withoutID map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &withID); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &withoutID); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[1], err)
+	}
+	if withID[KeyRequestID] != "req-123" || withID[KeyJobID] != "job-1" {
+		t.Errorf("expected request and job IDs on the record, got %v", withID)
+	}
+	if _, ok := withoutID[KeyRequestID]; ok {
+		t.Errorf("expected no request_id without one in the context, got %v", withoutID)
+	}
+}
@@ -1,26 +1,35 @@
 package metrics
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
 	"github.com/Sambit-Mondal/karbos/server/internal/worker"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 )
 
 // MetricsCollector handles Prometheus metrics collection
 type MetricsCollector struct {
 	// Prometheus metrics
 	jobsPending    prometheus.Gauge
+	queueDepth     *prometheus.GaugeVec
 	jobsRunning    prometheus.Gauge
-	co2SavedTotal  prometheus.Counter
+	co2SavedTotal  prometheus.Gauge // Net savings can decrease when a job runs dirtier than at submission
+	jobDuration    *prometheus.HistogramVec
+	sloCompliance  prometheus.Gauge
 	metricsHandler http.Handler
 
 	// Data sources
@@ -31,8 +40,25 @@ type MetricsCollector struct {
 	// Control
 	mu      sync.RWMutex
 	enabled bool
+
+	// durationWatermark is the created_at of the newest execution log already observed
+	durationWatermark time.Time
+
+	assumedPowerKW float64 // Per-job power draw used for CO2 savings
+	co2Initialized bool    // co2SavedTotal has been loaded from recorded savings
+
+	jobStarts startSLOCounter // Source of start-time SLO compliance; nil leaves the gauge unset
+	objective slo.Objective
 }
 
+// startSLOCounter counts the jobs due since a time that started on time and in total
+type startSLOCounter interface {
+	CountStartSLO(ctx context.Context, since time.Time) (onTime, total int, err error)
+}
+
+// jobDurationBuckets spans 1 second to 1 hour
+var jobDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
 // NewMetricsCollector creates a new Prometheus metrics collector
 func NewMetricsCollector(queue *queue.RedisQueue, workerPool *worker.Pool, db *sql.DB) *MetricsCollector {
 	// Create Prometheus metrics
@@ -41,30 +67,57 @@ func NewMetricsCollector(queue *queue.RedisQueue, workerPool *worker.Pool, db *s
 		Help: "Number of jobs waiting in queue (immediate + delayed)",
 	})
 
+	queueDepth := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "karbos_queue_depth",
+		Help: "Number of jobs waiting in each queue by region and priority",
+	}, []string{"queue", "region", "priority"})
+
 	jobsRunning := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "karbos_jobs_running",
 		Help: "Number of jobs currently being executed by workers",
 	})
 
-	co2SavedTotal := prometheus.NewCounter(prometheus.CounterOpts{
+	co2SavedTotal := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "karbos_co2_saved_total_grams",
-		Help: "Total grams of CO2 saved through carbon-aware scheduling",
+		Help: "Net grams of CO2 saved through carbon-aware scheduling",
+	})
+
+	jobDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "karbos_job_duration_seconds",
+		Help:    "Job container execution duration in seconds",
+		Buckets: jobDurationBuckets,
+	}, []string{"status", "region"})
+
+	sloCompliance := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "karbos_slo_compliance_ratio",
+		Help: "Fraction of jobs due within the SLO window that started within the SLO start threshold",
 	})
 
 	// Register metrics with Prometheus
 	prometheus.MustRegister(jobsPending)
+	prometheus.MustRegister(queueDepth)
 	prometheus.MustRegister(jobsRunning)
 	prometheus.MustRegister(co2SavedTotal)
+	prometheus.MustRegister(jobDuration)
+	prometheus.MustRegister(sloCompliance)
+	prometheus.MustRegister(carbon.CacheErrorsTotal)
+	prometheus.MustRegister(worker.JobsProcessedTotal)
+	prometheus.MustRegister(worker.ContainersInFlight)
+	prometheus.MustRegister(worker.ContainerLimit)
 
 	collector := &MetricsCollector{
 		jobsPending:    jobsPending,
+		queueDepth:     queueDepth,
 		jobsRunning:    jobsRunning,
 		co2SavedTotal:  co2SavedTotal,
+		jobDuration:    jobDuration,
+		sloCompliance:  sloCompliance,
 		metricsHandler: promhttp.Handler(),
 		queue:          queue,
 		workerPool:     workerPool,
 		db:             db,
 		enabled:        true,
+		assumedPowerKW: 0.05, // 50W
 	}
 
 	log.Println("✓ Prometheus metrics collector initialized")
@@ -85,6 +138,11 @@ func (m *MetricsCollector) UpdateMetrics(ctx context.Context) error {
 		log.Printf("Warning: Failed to update jobs_pending metric: %v", err)
 	}
 
+	// Update queue_depth (pending jobs by queue, region and priority)
+	if err := m.updateQueueDepth(ctx); err != nil {
+		log.Printf("Warning: Failed to update queue_depth metric: %v", err)
+	}
+
 	// Update jobs_running (active containers)
 	// Skip if worker pool not configured (e.g., API server)
 	if err := m.updateJobsRunning(); err != nil {
@@ -94,9 +152,22 @@ func (m *MetricsCollector) UpdateMetrics(ctx context.Context) error {
 		}
 	}
 
-	// Update co2_saved_total (cumulative savings)
-	if err := m.updateCO2Saved(ctx); err != nil {
-		log.Printf("Warning: Failed to update co2_saved_total metric: %v", err)
+	// Durations and savings come from the database, which the worker process's collector doesn't use
+	if m.db != nil {
+		// Observe durations of jobs finished since the last update
+		if err := m.updateJobDurations(ctx); err != nil {
+			log.Printf("Warning: Failed to update job_duration_seconds metric: %v", err)
+		}
+
+		// Update co2_saved_total (cumulative savings)
+		if err := m.updateCO2Saved(ctx); err != nil {
+			log.Printf("Warning: Failed to update co2_saved_total metric: %v", err)
+		}
+	}
+
+	// Update slo_compliance_ratio over the objective's rolling window
+	if err := m.updateSLOCompliance(ctx); err != nil {
+		log.Printf("Warning: Failed to update slo_compliance_ratio metric: %v", err)
 	}
 
 	return nil
@@ -126,6 +197,30 @@ func (m *MetricsCollector) updateJobsPending(ctx context.Context) error {
 	return nil
 }
 
+// updateQueueDepth breaks queued jobs down by queue, region and priority. Series for
+// combinations that no longer have queued jobs are dropped.
+func (m *MetricsCollector) updateQueueDepth(ctx context.Context) error {
+	if m.queue == nil {
+		return fmt.Errorf("queue not configured")
+	}
+
+	depths, err := m.queue.GetQueueDepths(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get queue depths: %w", err)
+	}
+
+	m.queueDepth.Reset()
+	for _, depth := range depths {
+		region := depth.Region
+		if region == "" {
+			region = "unknown"
+		}
+		m.queueDepth.WithLabelValues(depth.Queue, region, strconv.Itoa(depth.Priority)).Set(float64(depth.Count))
+	}
+
+	return nil
+}
+
 // updateJobsRunning counts currently active jobs from worker pool
 func (m *MetricsCollector) updateJobsRunning() error {
 	if m.workerPool == nil {
@@ -138,46 +233,191 @@ func (m *MetricsCollector) updateJobsRunning() error {
 	return nil
 }
 
-// updateCO2Saved calculates total CO2 savings from completed jobs
+// updateJobDurations observes execution logs written since the last update.
+// Workers run in a separate process, so finished jobs are picked up from the database.
+func (m *MetricsCollector) updateJobDurations(ctx context.Context) error {
+	if m.db == nil {
+		return fmt.Errorf("database not configured")
+	}
+
+	// Start from the newest existing log so history isn't re-observed after a restart
+	if m.durationWatermark.IsZero() {
+		err := m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(created_at), NOW()) FROM execution_logs`).Scan(&m.durationWatermark)
+		if err != nil {
+			return fmt.Errorf("failed to initialize duration watermark: %w", err)
+		}
+		return nil
+	}
+
+	// The outcome comes from the log row itself: workers write the log before the job's
+	// final status, so jobs.status may still read RUNNING when the log is picked up.
+	// Workers record an error for every attempt that didn't complete.
+	query := `
+		SELECT
+			CASE WHEN el.exit_code = 0 AND el.error_output IS NULL THEN 'COMPLETED' ELSE 'FAILED' END,
+			COALESCE(j.region, ''),
+			el.duration,
+			el.created_at
+		FROM execution_logs el
+		JOIN jobs j ON j.id = el.job_id
+		WHERE el.created_at > $1 AND el.duration IS NOT NULL
+		ORDER BY el.created_at ASC
+		LIMIT 1000
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, m.durationWatermark)
+	if err != nil {
+		return fmt.Errorf("failed to query execution logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status, region string
+		var durationSeconds int
+		var createdAt time.Time
+		if err := rows.Scan(&status, &region, &durationSeconds, &createdAt); err != nil {
+			return fmt.Errorf("failed to scan execution log: %w", err)
+		}
+
+		m.ObserveJobDuration(status, region, time.Duration(durationSeconds)*time.Second)
+		m.durationWatermark = createdAt
+	}
+
+	return rows.Err()
+}
+
+// ObserveJobDuration records the execution duration of a finished job
+func (m *MetricsCollector) ObserveJobDuration(status, region string, duration time.Duration) {
+	if region == "" {
+		region = "unknown"
+	}
+	m.jobDuration.WithLabelValues(status, region).Observe(duration.Seconds())
+}
+
+// updateCO2Saved computes savings for newly completed jobs and adds them to the running total.
+// Each job's savings are persisted so it is only ever counted once, even across restarts.
 func (m *MetricsCollector) updateCO2Saved(ctx context.Context) error {
 	if m.db == nil {
 		return fmt.Errorf("database not configured")
 	}
 
-	// Calculate CO2 savings based on execution logs
-	// Estimation: average power usage (50W) * duration * carbon intensity difference
-	// For now, we'll track completed jobs count as a proxy
-	// TODO: Implement actual CO2 calculation based on carbon intensity data
+	// Resume from the savings already recorded before this process started
+	if !m.co2Initialized {
+		var total float64
+		err := m.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(co2_saved_grams), 0) FROM jobs`).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to load recorded CO2 savings: %w", err)
+		}
+		m.co2SavedTotal.Set(total)
+		m.co2Initialized = true
+	}
+
+	// Compare the intensity at submission with the cached intensity when the final run started.
+	// Jobs without cached data for their execution time are picked up once it arrives.
+	// Relative-index rows (WattTime) aren't gCO2/kWh and are never used here, and jobs
+	// that opted out of carbon-aware scheduling aren't credited with savings.
 	query := `
-		SELECT COUNT(*) as completed_jobs
-		FROM jobs
-		WHERE status = 'COMPLETED'
+		SELECT j.id, j.submission_intensity, el.duration, ci.intensity_value
+		FROM jobs j
+		JOIN LATERAL (
+			SELECT duration, started_at
+			FROM execution_logs
+			WHERE job_id = j.id AND duration IS NOT NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		) el ON TRUE
+		JOIN LATERAL (
+			SELECT intensity_value
+			FROM carbon_cache
+			WHERE region = j.region AND timestamp <= el.started_at
+				AND intensity_scale = 'absolute'
+			ORDER BY timestamp DESC
+			LIMIT 1
+		) ci ON TRUE
+		WHERE j.status = 'COMPLETED'
+			AND j.co2_saved_grams IS NULL
+			AND j.submission_intensity IS NOT NULL
+			AND NOT j.carbon_opt_out
+		LIMIT 500
 	`
 
-	var completedJobs int
-	err := m.db.QueryRowContext(ctx, query).Scan(&completedJobs)
+	rows, err := m.db.QueryContext(ctx, query)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			completedJobs = 0
-		} else {
-			return fmt.Errorf("failed to query completed jobs: %w", err)
+		return fmt.Errorf("failed to query completed jobs: %w", err)
+	}
+
+	type jobSavings struct {
+		id    string
+		grams float64
+	}
+	var pending []jobSavings
+	for rows.Next() {
+		var id string
+		var submissionIntensity, executionIntensity float64
+		var durationSeconds int
+		if err := rows.Scan(&id, &submissionIntensity, &durationSeconds, &executionIntensity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan completed job: %w", err)
 		}
+		grams := co2SavedGrams(submissionIntensity, executionIntensity, m.assumedPowerKW, time.Duration(durationSeconds)*time.Second)
+		pending = append(pending, jobSavings{id: id, grams: grams})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating completed jobs: %w", err)
 	}
 
-	// Estimate CO2 saved: assume each job saves ~100g CO2 on average
-	// This is a placeholder - actual calculation would need:
-	// - Job execution duration from execution_logs
-	// - Carbon intensity at scheduling time vs execution time
-	// - Estimated power consumption
-	estimatedCO2Saved := float64(completedJobs) * 100.0
+	for _, job := range pending {
+		// The IS NULL guard keeps concurrent collectors from counting a job twice
+		result, err := m.db.ExecContext(ctx,
+			`UPDATE jobs SET co2_saved_grams = $1 WHERE id = $2 AND co2_saved_grams IS NULL`,
+			job.grams, job.id)
+		if err != nil {
+			return fmt.Errorf("failed to record CO2 savings for job %s: %w", job.id, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 1 {
+			m.co2SavedTotal.Add(job.grams)
+		}
+	}
 
-	// Note: This is cumulative, so we set it directly
-	// In a real implementation, we'd track incremental changes
-	m.co2SavedTotal.Add(estimatedCO2Saved)
+	return nil
+}
+
+// co2SavedGrams estimates the CO2 saved by running a job at executionIntensity instead of
+// submissionIntensity (both gCO2eq/kWh). The result is negative if the job ran dirtier.
+func co2SavedGrams(submissionIntensity, executionIntensity, powerKW float64, duration time.Duration) float64 {
+	return (submissionIntensity - executionIntensity) * powerKW * duration.Hours()
+}
+
+// updateSLOCompliance sets the start-time SLO compliance over the objective's window
+func (m *MetricsCollector) updateSLOCompliance(ctx context.Context) error {
+	if m.jobStarts == nil {
+		return nil // SLO tracking not configured
+	}
+
+	onTime, total, err := m.jobStarts.CountStartSLO(ctx, time.Now().Add(-m.objective.Window))
+	if err != nil {
+		return err
+	}
+	m.sloCompliance.Set(m.objective.Report(onTime, total).Compliance)
 
 	return nil
 }
 
+// SetStartSLO enables the SLO compliance gauge, reading job starts from jobStarts
+func (m *MetricsCollector) SetStartSLO(jobStarts startSLOCounter, objective slo.Objective) {
+	m.jobStarts = jobStarts
+	m.objective = objective
+}
+
+// SetAssumedPowerWatts updates the per-job power draw used to estimate CO2 savings
+func (m *MetricsCollector) SetAssumedPowerWatts(watts int) {
+	if watts <= 0 {
+		return
+	}
+	m.assumedPowerKW = float64(watts) / 1000
+}
+
 // ServeHTTP handles the /metrics endpoint
 func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.mu.RLock()
@@ -202,9 +442,12 @@ func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.metricsHandler.ServeHTTP(w, r)
 }
 
-// StartBackgroundUpdater starts a goroutine that periodically updates metrics
-func (m *MetricsCollector) StartBackgroundUpdater(ctx context.Context, interval time.Duration) {
+// StartBackgroundUpdater starts a goroutine that periodically updates metrics until ctx
+// is cancelled. The returned channel is closed once the goroutine has exited.
+func (m *MetricsCollector) StartBackgroundUpdater(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
@@ -222,6 +465,7 @@ func (m *MetricsCollector) StartBackgroundUpdater(ctx context.Context, interval
 			}
 		}
 	}()
+	return done
 }
 
 // Enable enables metrics collection
@@ -266,32 +510,27 @@ func (m *MetricsCollector) GetMetricsSnapshot(ctx context.Context) (map[string]f
 	}, nil
 }
 
-// GetPrometheusText returns metrics in Prometheus text format for Fiber
+// GetPrometheusText returns all karbos_ metrics in the Prometheus text exposition format
 func (m *MetricsCollector) GetPrometheusText() string {
-	// Use prometheus gatherer to collect metrics
-	gatherer := prometheus.DefaultGatherer
-	metrics, err := gatherer.Gather()
+	families, err := prometheus.DefaultGatherer.Gather()
 	if err != nil {
 		return fmt.Sprintf("# Error gathering metrics: %v\n", err)
 	}
 
-	// Format as Prometheus text
-	var result string
-	for _, metric := range metrics {
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
 		// Only include our karbos metrics
-		if metric.GetName() == "karbos_jobs_pending" ||
-			metric.GetName() == "karbos_jobs_running" ||
-			metric.GetName() == "karbos_co2_saved_total_grams" {
-			result += fmt.Sprintf("# HELP %s %s\n", metric.GetName(), metric.GetHelp())
-			result += fmt.Sprintf("# TYPE %s %s\n", metric.GetName(), metric.GetType())
-			for _, m := range metric.GetMetric() {
-				if m.GetGauge() != nil {
-					result += fmt.Sprintf("%s %f\n", metric.GetName(), m.GetGauge().GetValue())
-				} else if m.GetCounter() != nil {
-					result += fmt.Sprintf("%s %f\n", metric.GetName(), m.GetCounter().GetValue())
-				}
-			}
+		if !strings.HasPrefix(family.GetName(), "karbos_") {
+			continue
+		}
+		if err := encoder.Encode(family); err != nil {
+			return fmt.Sprintf("# Error encoding metric %s: %v\n", family.GetName(), err)
 		}
 	}
-	return result
+
+	return buf.String()
 }
+
+// PrometheusContentType is the Content-Type of GetPrometheusText output
+var PrometheusContentType = string(expfmt.NewFormat(expfmt.TypeTextPlain))
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
+	"github.com/Sambit-Mondal/karbos/server/internal/worker"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+var (
+	collectorOnce sync.Once
+	collector     *MetricsCollector
+)
+
+// sharedCollector returns a single collector for all tests, since metrics register globally
+func sharedCollector(t *testing.T) *MetricsCollector {
+	t.Helper()
+	collectorOnce.Do(func() {
+		collector = NewMetricsCollector(nil, nil, nil)
+	})
+	return collector
+}
+
+func TestMetricsCollector_JobDurationHistogram(t *testing.T) {
+	collector := sharedCollector(t)
+
+	// A job finishes
+	collector.ObserveJobDuration("COMPLETED", "EU-NORTH", 42*time.Second)
+	collector.ObserveJobDuration("FAILED", "", 3*time.Second)
+
+	text := collector.GetPrometheusText()
+
+	for _, want := range []string{
+		"# TYPE karbos_job_duration_seconds histogram",
+		`karbos_job_duration_seconds_bucket{region="EU-NORTH",status="COMPLETED",le="60"} 1`,
+		`karbos_job_duration_seconds_bucket{region="EU-NORTH",status="COMPLETED",le="30"} 0`,
+		`karbos_job_duration_seconds_bucket{region="unknown",status="FAILED",le="+Inf"} 1`,
+		`karbos_job_duration_seconds_count{region="EU-NORTH",status="COMPLETED"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected metrics output to contain %q\n%s", want, text)
+		}
+	}
+}
+
+func TestMetricsCollector_GetPrometheusTextIsValidExposition(t *testing.T) {
+	text := sharedCollector(t).GetPrometheusText()
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("output is not valid Prometheus text format: %v\n%s", err, text)
+	}
+
+	for _, name := range []string{"karbos_jobs_pending", "karbos_jobs_running", "karbos_co2_saved_total_grams", "karbos_job_duration_seconds", "karbos_slo_compliance_ratio"} {
+		if _, ok := families[name]; !ok {
+			t.Errorf("expected metric family %s in output", name)
+		}
+	}
+	for name := range families {
+		if !strings.HasPrefix(name, "karbos_") {
+			t.Errorf("unexpected non-karbos metric %s in output", name)
+		}
+	}
+}
+
+// fakeJobStarts reports fixed start-time SLO counts and the window start it was asked for
+type fakeJobStarts struct {
+	onTime, total int
+	since         time.Time
+}
+
+func (f *fakeJobStarts) CountStartSLO(ctx context.Context, since time.Time) (int, int, error) {
+	f.since = since
+	return f.onTime, f.total, nil
+}
+
+func TestMetricsCollector_SLOComplianceGauge(t *testing.T) {
+	collector := sharedCollector(t)
+	starts := &fakeJobStarts{onTime: 95, total: 100}
+	collector.SetStartSLO(starts, slo.Objective{StartThreshold: time.Minute, Target: 0.99, Window: time.Hour})
+	t.Cleanup(func() { collector.SetStartSLO(nil, slo.Objective{}) })
+
+	if err := collector.updateSLOCompliance(context.Background()); err != nil {
+		t.Fatalf("updateSLOCompliance: %v", err)
+	}
+	if window := time.Since(starts.since); window < time.Hour || window > time.Hour+time.Minute {
+		t.Errorf("expected compliance over the last hour, counted from %v", starts.since)
+	}
+	if !strings.Contains(collector.GetPrometheusText(), "karbos_slo_compliance_ratio 0.95") {
+		t.Errorf("expected karbos_slo_compliance_ratio 0.95, got:\n%s", collector.GetPrometheusText())
+	}
+}
+
+func TestMetricsCollector_JobsRunningTracksWorkerPool(t *testing.T) {
+	pool, err := worker.NewPool(worker.PoolConfig{
+		Size:          1,
+		Queue:         &queue.RedisQueue{},
+		JobRepo:       &database.JobRepository{},
+		ExecutionRepo: &database.ExecutionLogRepository{},
+		DockerService: &docker.Service{},
+	})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	collector := sharedCollector(t)
+	collector.workerPool = pool
+	t.Cleanup(func() { collector.workerPool = nil })
+
+	running := func() string {
+		t.Helper()
+		if err := collector.updateJobsRunning(); err != nil {
+			t.Fatalf("updateJobsRunning: %v", err)
+		}
+		return collector.GetPrometheusText()
+	}
+
+	pool.TrackJobStart("job-1")
+	pool.TrackJobStart("job-2")
+	if text := running(); !strings.Contains(text, "karbos_jobs_running 2") {
+		t.Errorf("expected karbos_jobs_running 2 with two tracked jobs, got:\n%s", text)
+	}
+
+	pool.TrackJobComplete("job-1")
+	pool.TrackJobComplete("job-2")
+	if text := running(); !strings.Contains(text, "karbos_jobs_running 0") {
+		t.Errorf("expected karbos_jobs_running 0 once jobs complete, got:\n%s", text)
+	}
+}
+
+func TestCO2SavedGrams(t *testing.T) {
+	power := 0.05 // 50W
+
+	// Submitted at 500 gCO2eq/kWh, shifted to a 200 gCO2eq/kWh window for two hours
+	if got := co2SavedGrams(500, 200, power, 2*time.Hour); got <= 0 {
+		t.Errorf("green-shifted job should save CO2, got %v", got)
+	} else if got != 30 {
+		t.Errorf("expected 30g saved, got %v", got)
+	}
+
+	// Grid got dirtier between submission and execution
+	if got := co2SavedGrams(200, 500, power, 2*time.Hour); got >= 0 {
+		t.Errorf("brown-shifted job should have negative savings, got %v", got)
+	}
+
+	if got := co2SavedGrams(300, 300, power, time.Hour); got != 0 {
+		t.Errorf("unchanged intensity should save nothing, got %v", got)
+	}
+}
+
+func TestMetricsCollector_QueueDepthByRegionAndPriority(t *testing.T) {
+	server := miniredis.RunT(t)
+	q, err := queue.NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("NewRedisQueue: %v", err)
+	}
+	defer q.Close()
+
+	ctx := context.Background()
+	q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: "a", Region: "US-EAST", Priority: 5})
+	q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: "b", Region: "US-EAST", Priority: 5})
+	q.EnqueueDelayed(ctx, &queue.QueueItem{JobID: "c", ScheduledTime: time.Now().Add(time.Hour)})
+
+	collector := sharedCollector(t)
+	collector.queue = q
+	t.Cleanup(func() { collector.queue = nil })
+
+	if err := collector.updateQueueDepth(ctx); err != nil {
+		t.Fatalf("updateQueueDepth: %v", err)
+	}
+	text := collector.GetPrometheusText()
+	for _, want := range []string{
+		`karbos_queue_depth{priority="5",queue="immediate",region="US-EAST"} 2`,
+		`karbos_queue_depth{priority="0",queue="delayed",region="unknown"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected metrics output to contain %q\n%s", want, text)
+		}
+	}
+
+	// Drained combinations disappear rather than reporting stale depths
+	q.DequeueImmediate(ctx)
+	q.DequeueImmediate(ctx)
+	if err := collector.updateQueueDepth(ctx); err != nil {
+		t.Fatalf("updateQueueDepth: %v", err)
+	}
+	if text := collector.GetPrometheusText(); strings.Contains(text, `queue="immediate"`) {
+		t.Errorf("expected no immediate queue depth once drained, got:\n%s", text)
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxCommandDecodeDepth bounds how many layers of JSON string encoding are unwrapped
+const maxCommandDecodeDepth = 3
+
+// DecodeCommand parses a stored job command into container arguments.
+// It accepts a JSON array of strings (`["echo","hi"]`), the same array encoded a second
+// time as a JSON string (legacy data), and a plain command string, which is run through
+// the shell. An empty command returns nil so the image's default command is used.
+func DecodeCommand(raw string) ([]string, error) {
+	value := strings.TrimSpace(raw)
+
+	for depth := 0; depth < maxCommandDecodeDepth; depth++ {
+		if value == "" {
+			return nil, nil
+		}
+
+		switch value[0] {
+		case '[':
+			var args []string
+			if err := json.Unmarshal([]byte(value), &args); err != nil {
+				return nil, fmt.Errorf("invalid command array %q: must be a JSON array of strings: %w", value, err)
+			}
+			if len(args) == 0 {
+				return nil, nil
+			}
+			return args, nil
+
+		case '"':
+			// JSON-encoded string: unwrap one layer and try again
+			var inner string
+			if err := json.Unmarshal([]byte(value), &inner); err != nil {
+				return nil, fmt.Errorf("invalid encoded command %q: %w", value, err)
+			}
+			value = strings.TrimSpace(inner)
+
+		default:
+			// Plain command string
+			return []string{"/bin/sh", "-c", value}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid command %q: too many levels of JSON encoding", raw)
+}
+
+// CommandArgs decodes the job's stored command (nil when no command is set)
+func (j *Job) CommandArgs() ([]string, error) {
+	if j.Command == nil {
+		return nil, nil
+	}
+	return DecodeCommand(*j.Command)
+}
+
+// ValidateCommand checks a submission's command before it is queued. A command may be
+// left out to use the image's default, but one that is given must be a non-empty array,
+// and no argument may contain a NUL byte, which can't be passed to a process.
+func ValidateCommand(command []string) error {
+	if command != nil && len(command) == 0 {
+		return fmt.Errorf("command must be a non-empty array of strings when provided")
+	}
+	for _, arg := range command {
+		if strings.ContainsRune(arg, 0) {
+			return fmt.Errorf("command must not contain NUL bytes")
+		}
+	}
+	return nil
+}
+
+// ValidateEntrypoint checks a submission's entrypoint override before it is queued. An
+// entrypoint must name an executable in its first element; the command is passed to it
+// as arguments. Like a command's, its elements may not contain NUL bytes.
+func ValidateEntrypoint(entrypoint []string) error {
+	if len(entrypoint) > 0 && strings.TrimSpace(entrypoint[0]) == "" {
+		return fmt.Errorf("entrypoint must start with an executable")
+	}
+	for _, arg := range entrypoint {
+		if strings.ContainsRune(arg, 0) {
+			return fmt.Errorf("entrypoint must not contain NUL bytes")
+		}
+	}
+	return nil
+}
+
+// maxJobSteps bounds how many steps a multi-step job may have
+const maxJobSteps = 20
+
+// ValidateSteps checks a multi-step submission's steps before it is queued. Each step is
+// a command that must be given in full, checked like a job's command; steps replace the
+// job's command, so the two can't both be set.
+func ValidateSteps(steps [][]string, command []string) error {
+	if steps == nil {
+		return nil
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("steps must be a non-empty array of commands when provided")
+	}
+	if len(steps) > maxJobSteps {
+		return fmt.Errorf("a job may have at most %d steps, got %d", maxJobSteps, len(steps))
+	}
+	if len(command) > 0 {
+		return fmt.Errorf("command and steps can't both be set")
+	}
+	for i, step := range steps {
+		if len(step) == 0 {
+			return fmt.Errorf("step %d must be a non-empty array of strings", i+1)
+		}
+		if err := ValidateCommand(step); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{name: "empty", raw: "", want: nil},
+		{name: "whitespace", raw: "   ", want: nil},
+		{name: "json array", raw: `["echo","hello world"]`, want: []string{"echo", "hello world"}},
+		{name: "empty array", raw: `[]`, want: nil},
+		{name: "double encoded array", raw: `"[\"python\",\"-c\",\"print(1)\"]"`, want: []string{"python", "-c", "print(1)"}},
+		{name: "plain string", raw: "echo hi && sleep 1", want: []string{"/bin/sh", "-c", "echo hi && sleep 1"}},
+		{name: "json encoded plain string", raw: `"echo hi"`, want: []string{"/bin/sh", "-c", "echo hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCommand(tt.raw)
+			if err != nil {
+				t.Fatalf("DecodeCommand(%q) returned error: %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeCommand(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCommand_Malformed(t *testing.T) {
+	malformed := []string{
+		`["echo", "unterminated`,
+		`["echo", 42]`,
+		`"unterminated`,
+		`"\"\\\"[\\\"deep\\\"]\\\"\""`,
+	}
+
+	for _, raw := range malformed {
+		if got, err := DecodeCommand(raw); err == nil {
+			t.Errorf("DecodeCommand(%q) = %q, expected an error", raw, got)
+		}
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	for _, command := range [][]string{nil, {"echo"}, {"echo", ""}} {
+		if err := ValidateCommand(command); err != nil {
+			t.Errorf("ValidateCommand(%q) returned error: %v", command, err)
+		}
+	}
+	for _, command := range [][]string{{}, {"echo\x00hi"}} {
+		if err := ValidateCommand(command); err == nil {
+			t.Errorf("ValidateCommand(%q): expected an error", command)
+		}
+	}
+}
+
+func TestValidateEntrypoint(t *testing.T) {
+	for _, entrypoint := range [][]string{nil, {"/bin/sh", "-c"}, {"python"}} {
+		if err := ValidateEntrypoint(entrypoint); err != nil {
+			t.Errorf("ValidateEntrypoint(%q) returned error: %v", entrypoint, err)
+		}
+	}
+	for _, entrypoint := range [][]string{{""}, {"  ", "-c"}, {"/bin/sh", "-c\x00"}} {
+		if err := ValidateEntrypoint(entrypoint); err == nil {
+			t.Errorf("ValidateEntrypoint(%q): expected an error", entrypoint)
+		}
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	if err := ValidateSteps(nil, []string{"echo"}); err != nil {
+		t.Errorf("ValidateSteps(nil) returned error: %v", err)
+	}
+	if err := ValidateSteps([][]string{{"make"}, {"make", "test"}}, nil); err != nil {
+		t.Errorf("ValidateSteps returned error for valid steps: %v", err)
+	}
+
+	tooMany := make([][]string, maxJobSteps+1)
+	for i := range tooMany {
+		tooMany[i] = []string{"true"}
+	}
+	for name, steps := range map[string][][]string{
+		"empty list":  {},
+		"empty step":  {{"make"}, {}},
+		"NUL byte":    {{"echo\x00hi"}},
+		"too many":    tooMany,
+		"and command": {{"make"}},
+	} {
+		var command []string
+		if name == "and command" {
+			command = []string{"echo"}
+		}
+		if err := ValidateSteps(steps, command); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Limits applied to submitted job metadata
+const (
+	maxMetadataBytes     = 16 << 10 // Raw JSON, including unknown fields
+	maxMetadataTags      = 32
+	maxMetadataTagLength = 64
+	maxMetadataFieldLen  = 256  // source and request_id
+	maxMetadataNotesLen  = 4096 // notes
+)
+
+// JobMetadata is free-form information a submitter attaches to a job. The known fields
+// are typed; any others are kept as they were sent, so clients that store their own keys
+// keep working and get them back unchanged.
+type JobMetadata struct {
+	Tags      []string `json:"tags,omitempty"`
+	Source    string   `json:"source,omitempty"`     // What submitted the job, e.g. "ci" or "airflow"
+	Notes     string   `json:"notes,omitempty"`      // Human-readable description
+	RequestID string   `json:"request_id,omitempty"` // Caller's own ID for the submission
+
+	Extra map[string]json.RawMessage `json:"-"` // Unknown fields, preserved verbatim
+}
+
+// jobMetadataFields mirrors JobMetadata without its methods, for the default JSON encoding
+type jobMetadataFields struct {
+	Tags      []string `json:"tags,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+// ParseJobMetadata decodes metadata JSON. Empty input and null give empty metadata;
+// anything but a JSON object, or known fields of the wrong type, is an error.
+func ParseJobMetadata(raw []byte) (JobMetadata, error) {
+	var m JobMetadata
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return m, nil
+	}
+	if trimmed[0] != '{' {
+		return m, fmt.Errorf("metadata must be a JSON object")
+	}
+	if err := json.Unmarshal(trimmed, &m); err != nil {
+		return JobMetadata{}, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return m, nil
+}
+
+// UnmarshalJSON decodes the known fields and keeps the rest in Extra
+func (m *JobMetadata) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var known jobMetadataFields
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+	*m = JobMetadata{
+		Tags:      known.Tags,
+		Source:    known.Source,
+		Notes:     known.Notes,
+		RequestID: known.RequestID,
+	}
+
+	for _, key := range []string{"tags", "source", "notes", "request_id"} {
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		m.Extra = fields
+	}
+	return nil
+}
+
+// MarshalJSON encodes the known fields alongside any preserved unknown ones
+func (m JobMetadata) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(jobMetadataFields{
+		Tags:      m.Tags,
+		Source:    m.Source,
+		Notes:     m.Notes,
+		RequestID: m.RequestID,
+	})
+	if err != nil || len(m.Extra) == 0 {
+		return known, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(m.Extra)+4)
+	for key, value := range m.Extra {
+		merged[key] = value
+	}
+	if err := json.Unmarshal(known, &merged); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+// Normalize trims the known fields, drops blank and repeated tags, and enforces the
+// size limits, so what is stored is what a later read will return
+func (m *JobMetadata) Normalize() error {
+	seen := make(map[string]bool, len(m.Tags))
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxMetadataTagLength {
+			return fmt.Errorf("metadata tag %q is longer than %d characters", tag, maxMetadataTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxMetadataTags {
+		return fmt.Errorf("metadata has %d tags, at most %d are allowed", len(tags), maxMetadataTags)
+	}
+	m.Tags = nil
+	if len(tags) > 0 {
+		m.Tags = tags
+	}
+
+	m.Source = strings.TrimSpace(m.Source)
+	m.RequestID = strings.TrimSpace(m.RequestID)
+	m.Notes = strings.TrimSpace(m.Notes)
+	if len(m.Source) > maxMetadataFieldLen || len(m.RequestID) > maxMetadataFieldLen {
+		return fmt.Errorf("metadata source and request_id must be at most %d characters", maxMetadataFieldLen)
+	}
+	if len(m.Notes) > maxMetadataNotesLen {
+		return fmt.Errorf("metadata notes must be at most %d characters", maxMetadataNotesLen)
+	}
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+	if len(encoded) > maxMetadataBytes {
+		return fmt.Errorf("metadata must be at most %d bytes", maxMetadataBytes)
+	}
+	return nil
+}
+
+// Value stores metadata as a JSON object, "{}" when empty
+func (m JobMetadata) Value() (driver.Value, error) {
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// Scan reads stored metadata. Rows written before metadata was validated may hold
+// anything, so unparsable values read as empty metadata rather than failing the query.
+func (m *JobMetadata) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into job metadata", src)
+	}
+
+	parsed, err := ParseJobMetadata(raw)
+	if err != nil {
+		parsed = JobMetadata{}
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseJobMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+		check   func(t *testing.T, m JobMetadata)
+	}{
+		{
+			name: "valid",
+			raw:  `{"tags": ["etl"], "source": "airflow", "notes": "nightly load", "request_id": "run-7"}`,
+			check: func(t *testing.T, m JobMetadata) {
+				if len(m.Tags) != 1 || m.Tags[0] != "etl" || m.Source != "airflow" || m.Notes != "nightly load" || m.RequestID != "run-7" {
+					t.Errorf("unexpected metadata %+v", m)
+				}
+			},
+		},
+		{
+			name: "unknown fields kept",
+			raw:  `{"source": "ci", "build": {"number": 42}}`,
+			check: func(t *testing.T, m JobMetadata) {
+				if m.Source != "ci" || string(m.Extra["build"]) != `{"number": 42}` {
+					t.Errorf("unexpected metadata %+v", m)
+				}
+			},
+		},
+		{name: "empty", raw: ""},
+		{name: "empty object", raw: "{}"},
+		{name: "null", raw: "null"},
+		{name: "string", raw: `"nightly"`, wantErr: true},
+		{name: "array", raw: `["nightly"]`, wantErr: true},
+		{name: "tags not a list", raw: `{"tags": "nightly"}`, wantErr: true},
+		{name: "truncated", raw: `{"source": "ci"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseJobMetadata([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJobMetadata(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, m)
+			} else if !tt.wantErr && (m.Tags != nil || m.Source != "" || m.Extra != nil) {
+				t.Errorf("expected empty metadata, got %+v", m)
+			}
+		})
+	}
+}
+
+func TestJobMetadata_RoundTrip(t *testing.T) {
+	m, err := ParseJobMetadata([]byte(`{"tags": ["etl"], "build": 42}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"build":42,"tags":["etl"]}` {
+		t.Errorf("encoded metadata = %s", encoded)
+	}
+
+	if value, _ := (JobMetadata{}).Value(); value != "{}" {
+		t.Errorf("empty metadata stored as %v, want {}", value)
+	}
+}
+
+func TestJobMetadata_Normalize(t *testing.T) {
+	m := JobMetadata{Tags: []string{" etl ", "etl", "", "nightly"}, Source: "  ci "}
+	if err := m.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(m.Tags, ",") != "etl,nightly" || m.Source != "ci" {
+		t.Errorf("normalized metadata = %+v", m)
+	}
+
+	for name, bad := range map[string]JobMetadata{
+		"long tag":   {Tags: []string{strings.Repeat("a", maxMetadataTagLength+1)}},
+		"long notes": {Notes: strings.Repeat("a", maxMetadataNotesLen+1)},
+		"too large":  {Extra: map[string]json.RawMessage{"blob": json.RawMessage(`"` + strings.Repeat("a", maxMetadataBytes) + `"`)}},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestJobMetadata_ScanToleratesLegacyValues(t *testing.T) {
+	var m JobMetadata
+	if err := m.Scan([]byte(`{"source": "ci"}`)); err != nil || m.Source != "ci" {
+		t.Fatalf("Scan = %+v, %v", m, err)
+	}
+	for _, legacy := range []interface{}{nil, "not json", `"a string"`} {
+		if err := m.Scan(legacy); err != nil || m.Source != "" {
+			t.Errorf("Scan(%v) = %+v, %v, want empty metadata", legacy, m, err)
+		}
+	}
+}
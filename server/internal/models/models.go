@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,23 +17,80 @@ const (
 	JobStatusRunning   JobStatus = "RUNNING"
 	JobStatusCompleted JobStatus = "COMPLETED"
 	JobStatusFailed    JobStatus = "FAILED"
+	JobStatusCancelled JobStatus = "CANCELLED"
+	JobStatusWaiting   JobStatus = "WAITING" // Held until the jobs it depends on complete
 )
 
+// ErrIllegalTransition is returned when a status change isn't allowed from the job's
+// current status, e.g. a duplicate queue message trying to re-run a finished job
+var ErrIllegalTransition = errors.New("illegal job status transition")
+
+// jobTransitions is the legal status graph. COMPLETED, FAILED and CANCELLED are terminal;
+// only an operator's dead-letter replay may move a job out of them.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusPending: {JobStatusDelayed, JobStatusRunning, JobStatusFailed, JobStatusCancelled},
+	JobStatusDelayed: {JobStatusPending, JobStatusRunning, JobStatusFailed, JobStatusCancelled},   // FAILED when its deadline passes before promotion
+	JobStatusRunning: {JobStatusCompleted, JobStatusFailed, JobStatusPending, JobStatusCancelled}, // PENDING when requeued for a retry
+	JobStatusWaiting: {JobStatusPending, JobStatusFailed, JobStatusCancelled},                     // FAILED when a dependency fails
+}
+
+// IsTerminal reports whether a job in status s has finished for good
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusCompleted || s == JobStatusFailed || s == JobStatusCancelled
+}
+
+// CanTransition reports whether a job may move from one status to another
+func CanTransition(from, to JobStatus) bool {
+	for _, next := range jobTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 // Job represents a job submission in the system
 type Job struct {
-	ID                uuid.UUID  `json:"id" db:"id"`
-	UserID            string     `json:"user_id" db:"user_id"`
-	DockerImage       string     `json:"docker_image" db:"docker_image"`
-	Command           *string    `json:"command,omitempty" db:"command"`
-	Status            JobStatus  `json:"status" db:"status"`
-	ScheduledTime     *time.Time `json:"scheduled_time,omitempty" db:"scheduled_time"`
-	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
-	StartedAt         *time.Time `json:"started_at,omitempty" db:"started_at"`
-	CompletedAt       *time.Time `json:"completed_at,omitempty" db:"completed_at"`
-	Deadline          time.Time  `json:"deadline" db:"deadline"`
-	EstimatedDuration *int       `json:"estimated_duration,omitempty" db:"estimated_duration"` // in seconds
-	Region            *string    `json:"region,omitempty" db:"region"`
-	Metadata          string     `json:"metadata,omitempty" db:"metadata"` // JSON stored as string
+	ID                uuid.UUID   `json:"id" db:"id"`
+	UserID            string      `json:"user_id" db:"user_id"`
+	DockerImage       string      `json:"docker_image" db:"docker_image"`
+	Command           *string     `json:"command,omitempty" db:"command"`
+	Status            JobStatus   `json:"status" db:"status"`
+	ScheduledTime     *time.Time  `json:"scheduled_time,omitempty" db:"scheduled_time"`
+	CreatedAt         time.Time   `json:"created_at" db:"created_at"`
+	StartedAt         *time.Time  `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt       *time.Time  `json:"completed_at,omitempty" db:"completed_at"`
+	Deadline          time.Time   `json:"deadline" db:"deadline"`
+	EstimatedDuration *int        `json:"estimated_duration,omitempty" db:"estimated_duration"` // in seconds
+	Region            *string     `json:"region,omitempty" db:"region"`
+	Metadata          JobMetadata `json:"metadata" db:"metadata"` // Stored as a JSONB object
+
+	SubmissionIntensity *float64 `json:"submission_intensity,omitempty" db:"submission_intensity"` // gCO2eq/kWh when the job was scheduled
+	CO2SavedGrams       *float64 `json:"co2_saved_grams,omitempty" db:"co2_saved_grams"`           // Set once the job completes; negative if it ran dirtier
+	ExpectedIntensity   *float64 `json:"expected_intensity,omitempty" db:"expected_intensity"`     // Forecast gCO2eq/kWh for the chosen window
+	CarbonSavings       *float64 `json:"carbon_savings,omitempty" db:"carbon_savings"`             // gCO2eq/kWh saved versus running at submission
+	CarbonOptOut        bool     `json:"carbon_opt_out" db:"carbon_opt_out"`                       // Submitted with carbon_aware=false: ran immediately, unscheduled
+
+	RequestID *string `json:"request_id,omitempty" db:"request_id"` // X-Request-ID of the submission, for correlating logs
+
+	StartDelaySeconds *int  `json:"start_delay_seconds,omitempty" db:"start_delay_seconds"` // How late the first run started after its scheduled time
+	SLOMet            *bool `json:"slo_met,omitempty" db:"slo_met"`                         // Whether that start met the start-time SLO; nil until it starts
+
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // When the job was soft-deleted or archived
+	Deleted   bool       `json:"deleted" db:"-"`                       // Soft-deleted: hidden from listings, still fetchable by ID
+
+	DependsOn []uuid.UUID `json:"depends_on,omitempty" db:"-"` // Jobs that must complete first; loaded from job_dependencies
+
+	Labels map[string]string `json:"labels,omitempty" db:"-"` // Tags set at submission; loaded from job_labels
+}
+
+// ScheduleWindow is an execution window the scheduler considered for a job
+type ScheduleWindow struct {
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	AvgIntensity float64   `json:"avg_intensity"`
+	CarbonCost   float64   `json:"carbon_cost,omitempty"`
+	Chosen       bool      `json:"chosen"` // The window the job was scheduled into
 }
 
 // ExecutionLog represents a log entry for job execution
@@ -40,12 +99,25 @@ type ExecutionLog struct {
 	JobID        uuid.UUID  `json:"job_id" db:"job_id"`
 	Output       string     `json:"output,omitempty" db:"output"`
 	ErrorMessage *string    `json:"error_message,omitempty" db:"error_output"`
-	ExitCode     int        `json:"exit_code,omitempty" db:"exit_code"`
-	Duration     int        `json:"duration,omitempty" db:"duration"` // in seconds
+	ExitCode     *int       `json:"exit_code,omitempty" db:"exit_code"` // nil when no container ran
+	Duration     *int       `json:"duration,omitempty" db:"duration"`   // in seconds
 	StartedAt    time.Time  `json:"started_at" db:"started_at"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
 	WorkerNodeID *string    `json:"worker_node_id,omitempty" db:"worker_node_id"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+
+	PeakMemoryBytes *int64   `json:"peak_memory_bytes,omitempty" db:"peak_memory_bytes"` // nil when no stats sample was taken
+	CPUSeconds      *float64 `json:"cpu_seconds,omitempty" db:"cpu_seconds"`
+	OutputTruncated bool     `json:"output_truncated" db:"output_truncated"` // Output went over DOCKER_MAX_OUTPUT_BYTES
+
+	Steps []StepRecord `json:"steps,omitempty" db:"steps"` // Per-step timings of a multi-step job, in run order
+}
+
+// StepRecord is how one step of a multi-step job went
+type StepRecord struct {
+	Name       string  `json:"name"`
+	DurationMs int64   `json:"duration_ms"`
+	Error      *string `json:"error,omitempty"` // Set on the step that failed or timed out
 }
 
 // CarbonCache represents cached carbon intensity data
@@ -57,6 +129,9 @@ type CarbonCache struct {
 	ForecastWindow *int      `json:"forecast_window,omitempty" db:"forecast_window"`
 	Source         *string   `json:"source,omitempty" db:"source"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+
+	RenewablePercentage *float64 `json:"renewable_percentage,omitempty" db:"renewable_percentage"`
+	FossilPercentage    *float64 `json:"fossil_percentage,omitempty" db:"fossil_percentage"`
 }
 
 // SubmitJobRequest represents the API request for job submission
@@ -64,21 +139,170 @@ type SubmitJobRequest struct {
 	UserID            string   `json:"user_id" validate:"required"`
 	DockerImage       string   `json:"docker_image" validate:"required"`
 	Command           []string `json:"command,omitempty"`
+	Entrypoint        []string `json:"entrypoint,omitempty"`         // Replaces the image's ENTRYPOINT; command becomes its arguments
 	Deadline          string   `json:"deadline" validate:"required"` // ISO 8601 format
 	EstimatedDuration *int     `json:"estimated_duration,omitempty"` // in seconds
 	Region            *string  `json:"region,omitempty"`
+	MemoryLimitMB     *int     `json:"memory_limit_mb,omitempty"` // Container memory limit in MB
+	CPUQuota          *int64   `json:"cpu_quota,omitempty"`       // Container CPU quota (100000 = one CPU)
+	Priority          *int     `json:"priority,omitempty"`        // 0 (default) to 10, higher runs first
+	CarbonAware       *bool    `json:"carbon_aware,omitempty"`    // false skips carbon-aware scheduling and runs now (default true)
+	MaxIntensity      *float64 `json:"max_intensity,omitempty"`   // Run immediately only below this gCO2eq/kWh (default: scheduler threshold)
+
+	SuccessOutputTailBytes *int `json:"success_output_tail_bytes,omitempty"` // Keep only this much output on success (0 = all, omit for worker default)
+
+	Steps [][]string `json:"steps,omitempty"` // Commands run one after another in the job's image, instead of command
+
+	Volumes []VolumeMount `json:"volumes,omitempty"` // Host paths or named volumes to mount; must be on DOCKER_VOLUME_ALLOWLIST
+
+	NetworkAccess bool `json:"network_access,omitempty"` // Run with network access (DOCKER_NETWORK_ACCESS_MODE) instead of isolated
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Same as the Idempotency-Key header, which takes precedence
+
+	DependsOn []string `json:"depends_on,omitempty"` // IDs of jobs that must complete before this one is queued
+
+	Labels map[string]string `json:"labels,omitempty"` // Tags such as {"team": "ml"}; job listings filter on them with ?label=team:ml
+
+	Metadata json.RawMessage `json:"metadata,omitempty"` // JSON object: tags, source, notes, request_id, plus any other keys
+}
+
+// VolumeMount mounts a host directory or a named Docker volume into a job's container
+type VolumeMount struct {
+	Source   string `json:"source"`              // Absolute host path, or the name of a Docker volume
+	Target   string `json:"target"`              // Absolute path inside the container
+	ReadOnly bool   `json:"read_only,omitempty"` // Mount without write access
+}
+
+// RecurringJob submits a job each time its cron schedule fires
+type RecurringJob struct {
+	ID             uuid.UUID        `json:"id"`
+	UserID         string           `json:"user_id"`
+	CronExpression string           `json:"cron_expression"`
+	Timezone       string           `json:"timezone"` // IANA zone the expression is evaluated in
+	JobSpec        SubmitJobRequest `json:"job_spec"` // Submitted on every run; the deadline is the following run
+	NextRunAt      time.Time        `json:"next_run_at"`
+	LastRunAt      *time.Time       `json:"last_run_at,omitempty"`
+	LastJobID      *uuid.UUID       `json:"last_job_id,omitempty"` // Job submitted by the last run
+	LastError      *string          `json:"last_error,omitempty"`  // Why the last run's submission failed
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// CreateRecurringJobRequest is a job spec, as for POST /api/submit but without a
+// deadline, plus the schedule to submit it on
+type CreateRecurringJobRequest struct {
+	SubmitJobRequest
+	Schedule string `json:"schedule"`           // Cron expression ("0 2 * * *") or descriptor ("@hourly")
+	Timezone string `json:"timezone,omitempty"` // IANA zone, default UTC
 }
 
+// Execution plans reported when a job is submitted
+const (
+	ExecutionPlanImmediate = "immediate" // Queued to run now
+	ExecutionPlanScheduled = "scheduled" // Deferred until scheduled_time
+	ExecutionPlanWaiting   = "waiting"   // Held until its dependencies complete, then queued to run
+)
+
 // SubmitJobResponse represents the API response for job submission
 type SubmitJobResponse struct {
 	JobID             string    `json:"job_id"`
 	Status            JobStatus `json:"status"`
 	CreatedAt         time.Time `json:"created_at"`
-	ScheduledTime     string    `json:"scheduled_time"`
+	ExecutionPlan     string    `json:"execution_plan"`
+	ScheduledTime     string    `json:"scheduled_time"` // Concrete start time (RFC 3339)
 	Immediate         bool      `json:"immediate"`
 	ExpectedIntensity float64   `json:"expected_intensity,omitempty"`
 	CarbonSavings     float64   `json:"carbon_savings,omitempty"`
+	IntensityScale    string    `json:"intensity_scale,omitempty"` // "relative" when the figures above are a provider index, not gCO2eq/kWh
+	CarbonAware       bool      `json:"carbon_aware"`              // false when the submission opted out of carbon-aware scheduling
+	DeferralCapped    bool      `json:"deferral_capped"`           // The start was kept within SCHEDULER_MAX_DEFERRAL of a more distant deadline
 	Message           string    `json:"message"`
+
+	AlternativeWindows []ScheduleWindow `json:"alternative_windows"` // Other low-carbon windows the job could have run in; empty when none
+
+	Estimate *ExecutionEstimate `json:"estimate,omitempty"` // Dry runs only
+
+	// Dry runs only: a real submission would be refused now, and when the next window opens
+	OutsideAcceptanceWindow bool       `json:"outside_acceptance_window,omitempty"`
+	NextAcceptanceWindow    *time.Time `json:"next_acceptance_window,omitempty"`
+}
+
+// ExecutionEstimate previews what a dry-run job would cost to run. Every figure is an
+// estimate made by the API from its own view of the worker configuration; the actual
+// run time, limits and emissions are only known once a worker has run the job.
+type ExecutionEstimate struct {
+	EstimatedDurationSeconds int      `json:"estimated_duration_seconds"`
+	DurationSource           string   `json:"duration_source"` // "request", "history" (the image's past runs) or "default"
+	MemoryLimitMB            int64    `json:"memory_limit_mb"` // 0 leaves the container unlimited
+	CPUQuota                 int64    `json:"cpu_quota"`       // 100000 = one CPU; 0 leaves it unlimited
+	TimeoutSeconds           int      `json:"timeout_seconds"` // Run time allowed before the container is stopped
+	AssumedPowerWatts        int      `json:"assumed_power_watts"`
+	ProjectedCO2Grams        *float64 `json:"projected_co2_grams"` // expected intensity × power × duration; null without a gCO2eq/kWh forecast
+}
+
+// BatchSubmitResult is the outcome of one job of a batch submission: the job's
+// submission response, or the error it was rejected with
+type BatchSubmitResult struct {
+	Index int                `json:"index"` // Position of the job in the submitted array
+	Job   *SubmitJobResponse `json:"job,omitempty"`
+	Error *ErrorResponse     `json:"error,omitempty"`
+}
+
+// BatchSubmitResponse represents the API response for a batch job submission
+type BatchSubmitResponse struct {
+	Results   []BatchSubmitResult `json:"results"` // In submission order
+	Submitted int                 `json:"submitted"`
+	Failed    int                 `json:"failed"`
+}
+
+// SimulateJob is a hypothetical job in a schedule simulation
+type SimulateJob struct {
+	DockerImage       string  `json:"docker_image"`
+	Region            *string `json:"region,omitempty"`
+	EstimatedDuration *int    `json:"estimated_duration,omitempty"` // in seconds
+	Deadline          string  `json:"deadline"`                     // ISO 8601 format
+}
+
+// SimulateScheduleRequest represents the API request to simulate scheduling a batch of jobs
+type SimulateScheduleRequest struct {
+	Jobs []SimulateJob `json:"jobs"`
+}
+
+// SimulatedJob is the scheduling decision for one simulated job
+type SimulatedJob struct {
+	DockerImage       string  `json:"docker_image"`
+	Region            string  `json:"region"`
+	ExecutionPlan     string  `json:"execution_plan,omitempty"`
+	ScheduledTime     string  `json:"scheduled_time,omitempty"` // Concrete start time (RFC 3339)
+	Immediate         bool    `json:"immediate"`
+	CurrentIntensity  float64 `json:"current_intensity"`
+	ExpectedIntensity float64 `json:"expected_intensity"`
+	CarbonSavings     float64 `json:"carbon_savings"`
+	IntensityScale    string  `json:"intensity_scale,omitempty"` // "relative" when the figures above are a provider index, not gCO2eq/kWh
+	DeferralCapped    bool    `json:"deferral_capped"`           // The start was kept within SCHEDULER_MAX_DEFERRAL of a more distant deadline
+	Error             string  `json:"error,omitempty"`           // Set when the job couldn't be scheduled
+}
+
+// SimulateScheduleResponse represents the API response for a schedule simulation
+type SimulateScheduleResponse struct {
+	Jobs               []SimulatedJob `json:"jobs"` // In request order
+	Immediate          int            `json:"immediate"`
+	Scheduled          int            `json:"scheduled"`
+	Failed             int            `json:"failed"`
+	TotalCarbonSavings float64        `json:"total_carbon_savings"` // Sum of gCO2eq/kWh savings; relative-index jobs are left out
+}
+
+// CancelJobResponse represents the API response for a cancellation request
+type CancelJobResponse struct {
+	JobID   string    `json:"job_id"`
+	Status  JobStatus `json:"status"` // CANCELLED, or RUNNING while the worker stops the container
+	Message string    `json:"message"`
+}
+
+// DeleteJobResponse represents the API response for a job deletion
+type DeleteJobResponse struct {
+	JobID     string    `json:"job_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Message   string    `json:"message"`
 }
 
 // ErrorResponse represents an API error response
@@ -98,10 +322,32 @@ type SystemHealthResponse struct {
 	Timestamp           time.Time `json:"timestamp"`
 }
 
+// StatsResponse is the aggregate view served by GET /api/stats
+type StatsResponse struct {
+	TotalJobs int `json:"total_jobs"`
+	Pending   int `json:"pending"`
+	Delayed   int `json:"delayed"`
+	Running   int `json:"running"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+	Waiting   int `json:"waiting"`
+
+	TotalCO2SavedGrams float64 `json:"total_co2_saved_grams"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds"` // Mean run time of completed jobs
+	CarbonCacheEntries int     `json:"carbon_cache_entries"`
+
+	ActiveWorkers       int       `json:"active_workers"`
+	QueueDepthImmediate int64     `json:"queue_depth_immediate"`
+	QueueDepthDelayed   int64     `json:"queue_depth_delayed"`
+	QueueDepthDead      int64     `json:"queue_depth_dead"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
 // ValidateStatus checks if the status is valid
 func (s JobStatus) IsValid() bool {
 	switch s {
-	case JobStatusPending, JobStatusDelayed, JobStatusRunning, JobStatusCompleted, JobStatusFailed:
+	case JobStatusPending, JobStatusDelayed, JobStatusRunning, JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusWaiting:
 		return true
 	}
 	return false
@@ -17,6 +17,8 @@ func TestJobStatus_IsValid(t *testing.T) {
 		{JobStatusRunning, true},
 		{JobStatusCompleted, true},
 		{JobStatusFailed, true},
+		{JobStatusCancelled, true},
+		{JobStatusWaiting, true},
 		{JobStatus("INVALID"), false},
 		{JobStatus(""), false},
 	}
@@ -30,6 +32,33 @@ func TestJobStatus_IsValid(t *testing.T) {
 	}
 }
 
+func TestCanTransition_Matrix(t *testing.T) {
+	statuses := []JobStatus{
+		JobStatusPending, JobStatusDelayed, JobStatusRunning,
+		JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusWaiting,
+	}
+	legal := map[JobStatus]map[JobStatus]bool{
+		JobStatusPending: {JobStatusDelayed: true, JobStatusRunning: true, JobStatusFailed: true, JobStatusCancelled: true},
+		JobStatusDelayed: {JobStatusPending: true, JobStatusRunning: true, JobStatusFailed: true, JobStatusCancelled: true},
+		JobStatusRunning: {JobStatusCompleted: true, JobStatusFailed: true, JobStatusPending: true, JobStatusCancelled: true},
+		JobStatusWaiting: {JobStatusPending: true, JobStatusFailed: true, JobStatusCancelled: true},
+		// COMPLETED, FAILED and CANCELLED are terminal
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := legal[from][to]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	if CanTransition(JobStatus("INVALID"), JobStatusRunning) || CanTransition(JobStatusPending, JobStatus("INVALID")) {
+		t.Error("expected unknown statuses to have no legal transitions")
+	}
+}
+
 func TestJob_Creation(t *testing.T) {
 	job := &Job{
 		ID:          uuid.New(),
@@ -38,7 +67,6 @@ func TestJob_Creation(t *testing.T) {
 		Status:      JobStatusPending,
 		Deadline:    time.Now().Add(24 * time.Hour),
 		CreatedAt:   time.Now(),
-		Metadata:    "{}",
 	}
 
 	if job.ID == uuid.Nil {
@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+)
+
+// Retry policy for Redis commands. A command that fails on a dropped connection (or on
+// a Redis still loading its data after a restart) is retried with exponential backoff,
+// so a restart of a few seconds is absorbed rather than failing jobs on the way.
+const (
+	commandMaxRetries      = 5
+	commandMinRetryBackoff = 100 * time.Millisecond
+	commandMaxRetryBackoff = 2 * time.Second
+)
+
+// Ping measures one round trip to Redis
+func (q *RedisQueue) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if err := q.client.Ping(ctx).Err(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// Connected reports whether Redis answered MonitorConnection's latest ping. It is true
+// until the monitor first sees a failure, and always true without a monitor.
+func (q *RedisQueue) Connected() bool {
+	return !q.disconnected.Load()
+}
+
+// MonitorConnection pings Redis every interval until ctx ends, logging when the
+// connection is lost and when it is back, with how long it was down. The client
+// reconnects on its own; this makes an outage visible instead of leaving queue calls
+// to fail one by one.
+func (q *RedisQueue) MonitorConnection(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lostAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		latency, err := q.Ping(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err != nil && !q.disconnected.Swap(true):
+			lostAt = time.Now()
+			slog.Error("Redis connection lost, retrying", logging.Err(err))
+		case err == nil && q.disconnected.Swap(false):
+			slog.Info("Redis connection restored", "downtime", time.Since(lostAt).Round(time.Second), "latency", latency)
+		}
+	}
+}
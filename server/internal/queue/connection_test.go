@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisQueue_RetriesTransientFailure(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "job-1", DockerImage: "alpine:latest"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	// Redis restarts; the first attempt fails on the dropped connection, and the retry
+	// (at least commandMinRetryBackoff later) finds it back
+	server.Close()
+	go func() {
+		time.Sleep(commandMinRetryBackoff / 4)
+		server.Restart()
+	}()
+
+	length, err := q.GetImmediateQueueLength(ctx)
+	if err != nil {
+		t.Fatalf("expected the command to be retried until Redis was back, got %v", err)
+	}
+	if length != 1 {
+		t.Errorf("immediate queue length = %d, want 1", length)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to log into from the monitor's goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRedisQueue_MonitorConnection(t *testing.T) {
+	q, server := newTestQueue(t)
+	logs := &syncBuffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.MonitorConnection(ctx, 20*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s; logs:\n%s", what, logs.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	server.Close()
+	waitFor("the connection loss", func() bool { return !q.Connected() })
+	if !strings.Contains(logs.String(), "Redis connection lost") {
+		t.Errorf("expected the loss to be logged, got:\n%s", logs.String())
+	}
+
+	server.Restart()
+	waitFor("the recovery", q.Connected)
+	if !strings.Contains(logs.String(), "Redis connection restored") {
+		t.Errorf("expected the recovery to be logged, got:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyKeyPrefix = "karbos:idempotency:"
+
+// IdempotencyRecord is what a submission's idempotency key holds: the job it created,
+// a fingerprint of the request, and, once the job is saved, the response sent back
+type IdempotencyRecord struct {
+	JobID       string          `json:"job_id"`
+	Fingerprint string          `json:"fingerprint"`
+	Response    json.RawMessage `json:"response,omitempty"` // Empty while the original submission is in flight
+}
+
+// idempotencyKey scopes key to userID. The user ID's length keeps "a:b"+"c" apart
+// from "a"+"b:c".
+func idempotencyKey(userID, key string) string {
+	return fmt.Sprintf("%s%d:%s:%s", idempotencyKeyPrefix, len(userID), userID, key)
+}
+
+// ClaimIdempotencyKey stores record under the user's key (SET NX with a TTL). It returns
+// nil if the key was free and is now held by record, or the record already stored.
+func (q *RedisQueue) ClaimIdempotencyKey(ctx context.Context, userID, key string, record *IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	redisKey := idempotencyKey(userID, key)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	claimed, err := q.client.SetNX(ctx, redisKey, data, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	stored, err := q.client.Get(ctx, redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Expired between the SET and the GET; try once more
+		return q.ClaimIdempotencyKey(ctx, userID, key, record, ttl)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+
+	var existing IdempotencyRecord
+	if err := json.Unmarshal(stored, &existing); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	return &existing, nil
+}
+
+// CompleteIdempotencyKey records the response of the submission holding the key,
+// keeping the TTL set when it was claimed
+func (q *RedisQueue) CompleteIdempotencyKey(ctx context.Context, userID, key string, record *IdempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	if err := q.client.SetArgs(ctx, idempotencyKey(userID, key), data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key whose submission failed, so the client's retry
+// can go through
+func (q *RedisQueue) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	if err := q.client.Del(ctx, idempotencyKey(userID, key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// Jobs are delivered at least once: ClaimImmediate moves a job from the immediate queue
+// into its owner's processing set in one step, and it stays there until AckProcessing.
+// Jobs a crashed worker node left behind are put back on the immediate queue, either by
+// the node itself when it restarts (ReclaimProcessing) or by the promoter once the
+// node's heartbeat has expired (RequeueStaleProcessing). A processing set is the record
+// of which node claimed each job.
+
+// processingKeyInfix separates the immediate queue key from a processing set's owner
+const processingKeyInfix = ":processing:"
+
+// claimImmediateScript pops the head of the immediate queue into a processing set,
+// scored by the claim time in milliseconds
+var claimImmediateScript = redis.NewScript(`
+local popped = redis.call("ZPOPMIN", KEYS[1], 1)
+if #popped == 0 then
+	return false
+end
+redis.call("ZADD", KEYS[2], ARGV[1], popped[1])
+return popped[1]
+`)
+
+// requeueProcessingScript moves one claimed job back to the immediate queue, unless
+// another reclaimer already did. The dead owner's processing lock on the job is
+// released with it so the job isn't skipped as a duplicate until the lock expires.
+var requeueProcessingScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[2])
+local holder = redis.call("GET", KEYS[3])
+if holder and (holder == ARGV[4] or string.sub(holder, 1, #ARGV[4] + 1) == ARGV[4] .. "/") then
+	redis.call("DEL", KEYS[3])
+end
+return 1
+`)
+
+// processingKey returns the processing set of a worker node (or lone consumer)
+func (q *RedisQueue) processingKey(owner string) string {
+	return q.immediateQueueKey + processingKeyInfix + owner
+}
+
+// ClaimImmediate retrieves the highest-priority job from the immediate queue and keeps
+// it in owner's processing set until AckProcessing, so a crash can't lose it
+func (q *RedisQueue) ClaimImmediate(ctx context.Context, owner string) (*QueueItem, error) {
+	result, err := claimImmediateScript.Run(ctx, q.client,
+		[]string{q.immediateQueueKey, q.processingKey(owner)},
+		time.Now().UnixMilli(),
+	).Result()
+	if err == redis.Nil {
+		return nil, nil // Queue is empty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim immediate job: %w", err)
+	}
+
+	data, ok := result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected immediate queue member type %T", result)
+	}
+
+	var item QueueItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue item: %w", err)
+	}
+	item.processingMember = data
+
+	return &item, nil
+}
+
+// AckProcessing removes a claimed job from owner's processing set once it is finished with
+func (q *RedisQueue) AckProcessing(ctx context.Context, owner string, item *QueueItem) error {
+	if item == nil || item.processingMember == "" {
+		return nil // Not claimed through ClaimImmediate
+	}
+	if err := q.client.ZRem(ctx, q.processingKey(owner), item.processingMember).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge job %s: %w", item.JobID, err)
+	}
+	return nil
+}
+
+// ReclaimProcessing puts every job left in owner's processing set back on the immediate
+// queue. A worker node calls it on startup to recover the jobs it held when it crashed.
+func (q *RedisQueue) ReclaimProcessing(ctx context.Context, owner string) (int, error) {
+	members, err := q.client.ZRange(ctx, q.processingKey(owner), 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read processing jobs for %s: %w", owner, err)
+	}
+	return q.requeueProcessing(ctx, owner, members, nil)
+}
+
+// RequeueStaleProcessing puts jobs claimed more than olderThan ago by worker nodes that
+// no longer heartbeat back on the immediate queue, returning how many were requeued.
+// beforeRequeue, if set, is called with each orphaned job while no worker can claim it yet.
+func (q *RedisQueue) RequeueStaleProcessing(ctx context.Context, olderThan time.Duration, beforeRequeue func(*QueueItem)) (int, error) {
+	workers, err := q.GetActiveWorkers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	alive := make(map[string]bool, len(workers))
+	for _, worker := range workers {
+		alive[worker] = true
+	}
+
+	prefix := q.processingKey("")
+	cutoff := time.Now().Add(-olderThan).UnixMilli()
+
+	requeued := 0
+	iter := q.client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		owner := strings.TrimPrefix(iter.Val(), prefix)
+		if alive[owner] {
+			continue
+		}
+
+		members, err := q.client.ZRangeByScore(ctx, iter.Val(), &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(cutoff, 10),
+		}).Result()
+		if err != nil {
+			return requeued, fmt.Errorf("failed to read processing jobs for %s: %w", owner, err)
+		}
+
+		n, err := q.requeueProcessing(ctx, owner, members, beforeRequeue)
+		requeued += n
+		if err != nil {
+			return requeued, err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return requeued, fmt.Errorf("failed to scan processing sets: %w", err)
+	}
+
+	return requeued, nil
+}
+
+// requeueProcessing moves claimed jobs from owner's processing set back to the
+// immediate queue, marked as reclaimed
+func (q *RedisQueue) requeueProcessing(ctx context.Context, owner string, members []string, beforeRequeue func(*QueueItem)) (int, error) {
+	requeued := 0
+	for _, member := range members {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(member), &item); err != nil {
+			return requeued, fmt.Errorf("failed to unmarshal processing item: %w", err)
+		}
+		if beforeRequeue != nil {
+			beforeRequeue(&item)
+		}
+		item.Reclaimed = true
+
+		data, err := json.Marshal(&item)
+		if err != nil {
+			return requeued, fmt.Errorf("failed to marshal queue item: %w", err)
+		}
+
+		moved, err := requeueProcessingScript.Run(ctx, q.client,
+			[]string{q.processingKey(owner), q.immediateQueueKey, jobLockKeyPrefix + item.JobID},
+			member, data, immediateScore(item.Priority, time.Now()), owner,
+		).Int()
+		if err != nil {
+			return requeued, fmt.Errorf("failed to requeue job %s: %w", item.JobID, err)
+		}
+		if moved == 1 {
+			requeued++
+			slog.Info("Requeued job left in processing", logging.KeyJobID, item.JobID, logging.KeyWorkerID, owner)
+		}
+	}
+	return requeued, nil
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRedisQueue_ClaimAndAck(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "job-1", DockerImage: "alpine:latest"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	item, err := q.ClaimImmediate(ctx, "node-a")
+	if err != nil || item == nil || item.JobID != "job-1" {
+		t.Fatalf("claim = %+v, %v; want job-1", item, err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("immediate queue has %d items, want 0", length)
+	}
+	if members, _ := server.ZMembers("test:immediate:processing:node-a"); len(members) != 1 {
+		t.Fatalf("processing set = %v, want the claimed job", members)
+	}
+
+	if err := q.AckProcessing(ctx, "node-a", item); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if server.Exists("test:immediate:processing:node-a") {
+		t.Error("expected the processing set to be empty after ack")
+	}
+
+	if item, err := q.ClaimImmediate(ctx, "node-a"); item != nil || err != nil {
+		t.Errorf("claim on empty queue = %+v, %v; want nil, nil", item, err)
+	}
+}
+
+func TestRedisQueue_ReclaimAfterCrash(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "job-1", DockerImage: "alpine:latest", Priority: 7}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := q.ClaimImmediate(ctx, "node-a"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if acquired, _ := q.AcquireJobLock(ctx, "job-1", "node-a/worker-1", time.Hour); !acquired {
+		t.Fatal("expected to acquire the job lock")
+	}
+	// The node crashes here, before the job finishes and is acknowledged
+
+	reclaimed, err := q.ReclaimProcessing(ctx, "node-a")
+	if err != nil || reclaimed != 1 {
+		t.Fatalf("reclaim = %d, %v; want 1", reclaimed, err)
+	}
+
+	item, err := q.ClaimImmediate(ctx, "node-a")
+	if err != nil || item == nil {
+		t.Fatalf("claim after reclaim = %+v, %v", item, err)
+	}
+	if item.JobID != "job-1" || item.Priority != 7 || !item.Reclaimed {
+		t.Errorf("reclaimed item = %+v, want job-1 with priority 7 marked reclaimed", item)
+	}
+	if acquired, _ := q.AcquireJobLock(ctx, "job-1", "node-a/worker-2", time.Hour); !acquired {
+		t.Error("expected the crashed worker's lock to be released")
+	}
+}
+
+func TestRedisQueue_RequeueStaleProcessingSkipsLiveNodes(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	for _, node := range []string{"live", "dead"} {
+		if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "job-" + node, DockerImage: "alpine:latest"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		if _, err := q.ClaimImmediate(ctx, "node-"+node); err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+	}
+	if err := q.SetWorkerHeartbeat(ctx, "node-live", 15); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+
+	// Claims younger than the threshold are left alone
+	if requeued, err := q.RequeueStaleProcessing(ctx, time.Hour, nil); err != nil || requeued != 0 {
+		t.Fatalf("requeue of recent claims = %d, %v; want 0", requeued, err)
+	}
+
+	var orphaned []string
+	requeued, err := q.RequeueStaleProcessing(ctx, 0, func(item *QueueItem) {
+		orphaned = append(orphaned, item.JobID)
+	})
+	if err != nil || requeued != 1 {
+		t.Fatalf("requeue = %d, %v; want 1", requeued, err)
+	}
+	item, _ := q.DequeueImmediate(ctx)
+	if item == nil || item.JobID != "job-dead" {
+		t.Errorf("requeued item = %+v, want job-dead", item)
+	}
+	if len(orphaned) != 1 || orphaned[0] != "job-dead" {
+		t.Errorf("beforeRequeue saw %v, want [job-dead]", orphaned)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,19 +19,73 @@ type RedisQueue struct {
 	client            *redis.Client
 	immediateQueueKey string
 	delayedSetKey     string
+	deadLetterKey     string
+
+	disconnected atomic.Bool // Set by MonitorConnection while Redis is unreachable
 }
 
 // QueueItem represents an item in the queue
 type QueueItem struct {
 	JobID         string    `json:"job_id"`
+	UserID        string    `json:"user_id,omitempty"` // Submitting user, for per-user dead-letter views
 	DockerImage   string    `json:"docker_image"`
 	Command       *string   `json:"command,omitempty"`
+	Entrypoint    []string  `json:"entrypoint,omitempty"` // Overrides the image's ENTRYPOINT when set
 	ScheduledTime time.Time `json:"scheduled_time"`
-	Priority      int       `json:"priority"`
+	Deadline      time.Time `json:"deadline"`                  // Zero on items queued before deadlines were carried
+	Priority      int       `json:"priority"`                  // MinPriority..MaxPriority, higher runs first
+	Region        string    `json:"region,omitempty"`          // Region the job was scheduled for
+	MemoryLimitMB int       `json:"memory_limit_mb,omitempty"` // Per-job memory limit (0 = worker default)
+	CPUQuota      int64     `json:"cpu_quota,omitempty"`       // Per-job CPU quota (0 = worker default)
+	Attempts      int       `json:"attempts,omitempty"`        // Number of failed execution attempts so far
+	Replays       int       `json:"replays,omitempty"`         // Times the job was replayed out of the dead-letter queue
+	RequestID     string    `json:"request_id,omitempty"`      // X-Request-ID of the submission, added to the worker's logs
+
+	SuccessOutputTailBytes *int `json:"success_output_tail_bytes,omitempty"` // Per-job override of the stored success output size (nil = worker default)
+
+	Steps [][]string `json:"steps,omitempty"` // Commands of a multi-step job, run in order instead of Command
+
+	Volumes []models.VolumeMount `json:"volumes,omitempty"` // Mounts for the job's container, checked against the worker's allowlist
+
+	NetworkAccess bool `json:"network_access,omitempty"` // Run on the worker's network access mode instead of the isolated one
+
+	Reclaimed bool `json:"reclaimed,omitempty"` // Recovered from a crashed worker; the job's stored status may still be RUNNING
+
+	processingMember string // Raw processing set member, set by ClaimImmediate
+}
+
+// Job priority bounds for the immediate queue
+const (
+	MinPriority = 0
+	MaxPriority = 10
+)
+
+// priorityScoreStep separates priority levels in the immediate queue score so that the
+// enqueue time in milliseconds only breaks ties within a level
+const priorityScoreStep = 1e13
+
+// immediateScore orders the immediate queue: higher priority first, then oldest first
+func immediateScore(priority int, enqueuedAt time.Time) float64 {
+	if priority < MinPriority {
+		priority = MinPriority
+	}
+	if priority > MaxPriority {
+		priority = MaxPriority
+	}
+	return float64(MaxPriority-priority)*priorityScoreStep + float64(enqueuedAt.UnixMilli())
+}
+
+// DeadLetterItem represents a job that exhausted its retries
+type DeadLetterItem struct {
+	Item     QueueItem `json:"item"`
+	Reason   string    `json:"reason"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+	Poisoned bool      `json:"poisoned,omitempty"` // Replayed too often; excluded from bulk replay (set by the API, not stored)
 }
 
 // NewRedisQueue creates a new Redis queue client
-func NewRedisQueue(addr, password string, db int, immediateKey, delayedKey string) (*RedisQueue, error) {
+func NewRedisQueue(addr, password string, db int, immediateKey, delayedKey, deadLetterKey string) (*RedisQueue, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:         addr,
 		Password:     password,
@@ -36,6 +94,11 @@ func NewRedisQueue(addr, password string, db int, immediateKey, delayedKey strin
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 3 * time.Second,
 		PoolSize:     10,
+
+		// Ride out brief connection drops instead of failing the command
+		MaxRetries:      commandMaxRetries,
+		MinRetryBackoff: commandMinRetryBackoff,
+		MaxRetryBackoff: commandMaxRetryBackoff,
 	})
 
 	// Test connection
@@ -46,34 +109,89 @@ func NewRedisQueue(addr, password string, db int, immediateKey, delayedKey strin
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Println("✓ Successfully connected to Redis")
+	slog.Info("Connected to Redis", "addr", addr)
 
-	return &RedisQueue{
+	q := &RedisQueue{
 		client:            client,
 		immediateQueueKey: immediateKey,
 		delayedSetKey:     delayedKey,
-	}, nil
+		deadLetterKey:     deadLetterKey,
+	}
+
+	if err := q.migrateImmediateList(ctx); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// migrateImmediateList converts an immediate queue left over from the FIFO list
+// layout into the priority sorted set, preserving the original order
+func (q *RedisQueue) migrateImmediateList(ctx context.Context) error {
+	keyType, err := q.client.Type(ctx, q.immediateQueueKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check immediate queue type: %w", err)
+	}
+	if keyType != "list" {
+		return nil
+	}
+
+	results, err := q.client.LRange(ctx, q.immediateQueueKey, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read legacy immediate queue: %w", err)
+	}
+
+	members := make([]redis.Z, 0, len(results))
+	now := time.Now()
+	for i, result := range results {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(result), &item); err != nil {
+			slog.Warn("Dropping unreadable legacy queue item", logging.Err(err))
+			continue
+		}
+		members = append(members, redis.Z{
+			Score:  immediateScore(item.Priority, now.Add(time.Duration(i)*time.Millisecond)),
+			Member: result,
+		})
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.Del(ctx, q.immediateQueueKey)
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, q.immediateQueueKey, members...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to migrate legacy immediate queue: %w", err)
+	}
+
+	slog.Info("Migrated legacy immediate queue list to priority queue", "jobs", len(members))
+	return nil
 }
 
 // Close closes the Redis connection
 func (q *RedisQueue) Close() error {
-	log.Println("Closing Redis connection...")
+	slog.Info("Closing Redis connection")
 	return q.client.Close()
 }
 
-// EnqueueImmediate adds a job to the immediate execution queue (FIFO List)
+// EnqueueImmediate adds a job to the immediate execution queue
+// (Sorted Set scored by priority, then submission time)
 func (q *RedisQueue) EnqueueImmediate(ctx context.Context, item *QueueItem) error {
 	data, err := json.Marshal(item)
 	if err != nil {
 		return fmt.Errorf("failed to marshal queue item: %w", err)
 	}
 
-	// Push to the right end of the list (FIFO)
-	if err := q.client.RPush(ctx, q.immediateQueueKey, data).Err(); err != nil {
+	member := redis.Z{
+		Score:  immediateScore(item.Priority, time.Now()),
+		Member: data,
+	}
+
+	if err := q.client.ZAdd(ctx, q.immediateQueueKey, member).Err(); err != nil {
 		return fmt.Errorf("failed to enqueue immediate job: %w", err)
 	}
 
-	log.Printf("✓ Enqueued immediate job: %s", item.JobID)
+	slog.Info("Enqueued immediate job", logging.KeyJobID, item.JobID, "priority", item.Priority)
 	return nil
 }
 
@@ -96,29 +214,82 @@ func (q *RedisQueue) EnqueueDelayed(ctx context.Context, item *QueueItem) error
 		return fmt.Errorf("failed to enqueue delayed job: %w", err)
 	}
 
-	log.Printf("✓ Enqueued delayed job: %s (scheduled for %s)", item.JobID, item.ScheduledTime.Format(time.RFC3339))
+	slog.Info("Enqueued delayed job", logging.KeyJobID, item.JobID, logging.KeyRegion, item.Region, "scheduled_time", item.ScheduledTime)
 	return nil
 }
 
-// DequeueImmediate retrieves and removes a job from the immediate queue
+// DequeueImmediate retrieves and removes the highest-priority job from the immediate queue
 func (q *RedisQueue) DequeueImmediate(ctx context.Context) (*QueueItem, error) {
-	// Pop from the left end of the list (FIFO)
-	result, err := q.client.LPop(ctx, q.immediateQueueKey).Result()
-	if err == redis.Nil {
-		return nil, nil // Queue is empty
-	}
+	// Pop the lowest score (highest priority, oldest within a priority)
+	results, err := q.client.ZPopMin(ctx, q.immediateQueueKey, 1).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to dequeue immediate job: %w", err)
 	}
+	if len(results) == 0 {
+		return nil, nil // Queue is empty
+	}
+
+	data, ok := results[0].Member.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected immediate queue member type %T", results[0].Member)
+	}
 
 	var item QueueItem
-	if err := json.Unmarshal([]byte(result), &item); err != nil {
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal queue item: %w", err)
 	}
 
 	return &item, nil
 }
 
+// PeekImmediate returns up to limit jobs from the head of the immediate queue without removing them
+func (q *RedisQueue) PeekImmediate(ctx context.Context, limit int64) ([]*QueueItem, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	results, err := q.client.ZRange(ctx, q.immediateQueueKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek immediate queue: %w", err)
+	}
+
+	items := make([]*QueueItem, 0, len(results))
+	for _, result := range results {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(result), &item); err != nil {
+			slog.Warn("Failed to unmarshal queue item", logging.Err(err))
+			continue
+		}
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// CountDelayedInSlot counts delayed jobs for a region scheduled within [start, end)
+func (q *RedisQueue) CountDelayedInSlot(ctx context.Context, region string, start, end time.Time) (int, error) {
+	results, err := q.client.ZRangeByScore(ctx, q.delayedSetKey, &redis.ZRangeBy{
+		Min: fmt.Sprintf("%d", start.Unix()),
+		Max: fmt.Sprintf("(%d", end.Unix()),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read delayed slot: %w", err)
+	}
+
+	count := 0
+	for _, result := range results {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(result), &item); err != nil {
+			continue
+		}
+		if item.Region == region {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
 // GetDueDelayedJobs retrieves jobs from delayed queue that are due for execution
 func (q *RedisQueue) GetDueDelayedJobs(ctx context.Context, limit int64) ([]*QueueItem, error) {
 	now := float64(time.Now().Unix())
@@ -142,7 +313,7 @@ func (q *RedisQueue) GetDueDelayedJobs(ctx context.Context, limit int64) ([]*Que
 	for _, result := range results {
 		var item QueueItem
 		if err := json.Unmarshal([]byte(result), &item); err != nil {
-			log.Printf("Warning: failed to unmarshal delayed job: %v", err)
+			slog.Warn("Failed to unmarshal delayed job", logging.Err(err))
 			continue
 		}
 		items = append(items, &item)
@@ -170,7 +341,7 @@ func (q *RedisQueue) RemoveDelayedJob(ctx context.Context, jobID string) error {
 			if err := q.client.ZRem(ctx, q.delayedSetKey, result).Err(); err != nil {
 				return fmt.Errorf("failed to remove delayed job: %w", err)
 			}
-			log.Printf("✓ Removed delayed job: %s", jobID)
+			slog.Info("Removed delayed job", logging.KeyJobID, jobID)
 			return nil
 		}
 	}
@@ -180,7 +351,7 @@ func (q *RedisQueue) RemoveDelayedJob(ctx context.Context, jobID string) error {
 
 // GetImmediateQueueLength returns the length of the immediate queue
 func (q *RedisQueue) GetImmediateQueueLength(ctx context.Context) (int64, error) {
-	length, err := q.client.LLen(ctx, q.immediateQueueKey).Result()
+	length, err := q.client.ZCard(ctx, q.immediateQueueKey).Result()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get immediate queue length: %w", err)
 	}
@@ -223,7 +394,7 @@ func (q *RedisQueue) GetReadyDelayedJobs(ctx context.Context, now time.Time) ([]
 	for _, result := range results {
 		var item QueueItem
 		if err := json.Unmarshal([]byte(result), &item); err != nil {
-			log.Printf("Warning: failed to unmarshal delayed job: %v", err)
+			slog.Warn("Failed to unmarshal delayed job", logging.Err(err))
 			continue
 		}
 		items = append(items, &item)
@@ -289,10 +460,85 @@ func (q *RedisQueue) GetDelayedJobsCount(ctx context.Context) (int64, error) {
 	return q.GetDelayedQueueLength(ctx)
 }
 
+// PeekDelayed returns up to limit jobs from the delayed queue, soonest scheduled first,
+// without removing them. Jobs already due but not yet promoted come first.
+func (q *RedisQueue) PeekDelayed(ctx context.Context, limit int64) ([]*QueueItem, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	results, err := q.client.ZRange(ctx, q.delayedSetKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek delayed queue: %w", err)
+	}
+
+	items := make([]*QueueItem, 0, len(results))
+	for _, result := range results {
+		var item QueueItem
+		if err := json.Unmarshal([]byte(result), &item); err != nil {
+			slog.Warn("Failed to unmarshal delayed job", logging.Err(err))
+			continue
+		}
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// QueueDepth is the number of jobs of one region and priority waiting in one queue
+type QueueDepth struct {
+	Queue    string // "immediate" or "delayed"
+	Region   string // Empty for jobs queued without a region
+	Priority int
+	Count    int64
+}
+
+// GetQueueDepths counts the jobs in the immediate and delayed queues by region and priority
+func (q *RedisQueue) GetQueueDepths(ctx context.Context) ([]QueueDepth, error) {
+	var depths []QueueDepth
+	for _, set := range []struct{ name, key string }{
+		{"immediate", q.immediateQueueKey},
+		{"delayed", q.delayedSetKey},
+	} {
+		results, err := q.client.ZRange(ctx, set.key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s queue: %w", set.name, err)
+		}
+
+		type bucket struct {
+			region   string
+			priority int
+		}
+		counts := make(map[bucket]int64)
+		var order []bucket
+		for _, result := range results {
+			var item QueueItem
+			if err := json.Unmarshal([]byte(result), &item); err != nil {
+				continue
+			}
+			b := bucket{item.Region, item.Priority}
+			if counts[b] == 0 {
+				order = append(order, b)
+			}
+			counts[b]++
+		}
+
+		for _, b := range order {
+			depths = append(depths, QueueDepth{Queue: set.name, Region: b.region, Priority: b.priority, Count: counts[b]})
+		}
+	}
+
+	return depths, nil
+}
+
+// workerHeartbeatKey returns the heartbeat key of a worker node
+func workerHeartbeatKey(workerID string) string {
+	return fmt.Sprintf("worker:%s", workerID)
+}
+
 // SetWorkerHeartbeat sets a worker heartbeat key with expiration
 func (q *RedisQueue) SetWorkerHeartbeat(ctx context.Context, workerID string, ttlSeconds int) error {
-	key := fmt.Sprintf("worker:%s", workerID)
-	return q.client.Set(ctx, key, "alive", time.Duration(ttlSeconds)*time.Second).Err()
+	return q.client.Set(ctx, workerHeartbeatKey(workerID), "alive", time.Duration(ttlSeconds)*time.Second).Err()
 }
 
 // GetActiveWorkers scans for active worker keys and returns their IDs
@@ -302,6 +548,10 @@ func (q *RedisQueue) GetActiveWorkers(ctx context.Context) ([]string, error) {
 	iter := q.client.Scan(ctx, 0, "worker:*", 0).Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
+		// Job locks share the "worker:" prefix but aren't workers
+		if strings.HasPrefix(key, jobLockKeyPrefix) {
+			continue
+		}
 		// Extract worker ID from "worker:{uuid}" format
 		if len(key) > 7 {
 			workerID := key[7:] // Remove "worker:" prefix
@@ -315,3 +565,324 @@ func (q *RedisQueue) GetActiveWorkers(ctx context.Context) ([]string, error) {
 
 	return workers, nil
 }
+
+// jobLockKeyPrefix prefixes the per-job processing locks held by workers
+const jobLockKeyPrefix = "worker:lock:"
+
+// releaseJobLockScript deletes a job lock only if it is still held by the given owner,
+// so a worker whose lock expired can't release a lock another worker has since taken
+var releaseJobLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireJobLock takes the processing lock for a job (SET NX with a TTL), returning
+// false if another worker already holds it. The lock expires after ttl in case its
+// holder dies without releasing it.
+func (q *RedisQueue) AcquireJobLock(ctx context.Context, jobID, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := q.client.SetNX(ctx, jobLockKeyPrefix+jobID, owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock for job %s: %w", jobID, err)
+	}
+	return acquired, nil
+}
+
+// ReleaseJobLock releases a job's processing lock if owner still holds it
+func (q *RedisQueue) ReleaseJobLock(ctx context.Context, jobID, owner string) error {
+	if err := releaseJobLockScript.Run(ctx, q.client, []string{jobLockKeyPrefix + jobID}, owner).Err(); err != nil {
+		return fmt.Errorf("failed to release lock for job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// IncrRateLimit counts one request against key in a fixed window shared by every API
+// replica. It returns the count so far in the current window and the time until the
+// window resets.
+func (q *RedisQueue) IncrRateLimit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	redisKey := fmt.Sprintf("karbos:ratelimit:%s", key)
+
+	pipe := q.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pttl := pipe.PTTL(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to increment rate limit counter: %w", err)
+	}
+
+	// A counter without a TTL starts a new window (also repairs one whose expire was lost)
+	ttl := pttl.Val()
+	if ttl < 0 {
+		if err := q.client.PExpire(ctx, redisKey, window).Err(); err != nil {
+			return 0, 0, fmt.Errorf("failed to set rate limit window: %w", err)
+		}
+		ttl = window
+	}
+
+	return incr.Val(), ttl, nil
+}
+
+// EnqueueDead moves a job that exhausted its retries to the dead-letter queue
+func (q *RedisQueue) EnqueueDead(ctx context.Context, item *QueueItem, reason string) error {
+	entry := DeadLetterItem{
+		Item:     *item,
+		Reason:   reason,
+		Attempts: item.Attempts,
+		FailedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead-letter item: %w", err)
+	}
+
+	// Push to the left end so the most recent failures come first
+	if err := q.client.LPush(ctx, q.deadLetterKey, data).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue dead-letter job: %w", err)
+	}
+
+	slog.Warn("Moved job to dead-letter queue", logging.KeyJobID, item.JobID, "attempts", item.Attempts, "reason", reason)
+	return nil
+}
+
+// PeekDead returns up to limit dead-letter items (most recent first) without removing them
+func (q *RedisQueue) PeekDead(ctx context.Context, limit int64) ([]*DeadLetterItem, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	results, err := q.client.LRange(ctx, q.deadLetterKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter queue: %w", err)
+	}
+
+	items := make([]*DeadLetterItem, 0, len(results))
+	for _, result := range results {
+		var entry DeadLetterItem
+		if err := json.Unmarshal([]byte(result), &entry); err != nil {
+			slog.Warn("Failed to unmarshal dead-letter item", logging.Err(err))
+			continue
+		}
+		items = append(items, &entry)
+	}
+
+	return items, nil
+}
+
+// ListDead returns every dead-letter item, most recent first
+func (q *RedisQueue) ListDead(ctx context.Context) ([]*DeadLetterItem, error) {
+	length, err := q.GetDeadQueueLength(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return []*DeadLetterItem{}, nil
+	}
+	return q.PeekDead(ctx, length)
+}
+
+// RemoveDead removes a job from the dead-letter queue without requeueing it and
+// returns the removed entry
+func (q *RedisQueue) RemoveDead(ctx context.Context, jobID string) (*DeadLetterItem, error) {
+	results, err := q.client.LRange(ctx, q.deadLetterKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter queue: %w", err)
+	}
+
+	for _, result := range results {
+		var entry DeadLetterItem
+		if err := json.Unmarshal([]byte(result), &entry); err != nil {
+			continue
+		}
+		if entry.Item.JobID != jobID {
+			continue
+		}
+
+		removed, err := q.client.LRem(ctx, q.deadLetterKey, 1, result).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove dead-letter job: %w", err)
+		}
+		if removed == 0 {
+			// Another caller removed it first
+			break
+		}
+		return &entry, nil
+	}
+
+	return nil, fmt.Errorf("job %s not found in dead-letter queue", jobID)
+}
+
+// RequeueDead removes a job from the dead-letter queue and pushes it back to the immediate queue
+// with its attempt count reset and its replay count incremented. It returns the requeued item.
+func (q *RedisQueue) RequeueDead(ctx context.Context, jobID string) (*QueueItem, error) {
+	entry, err := q.RemoveDead(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := entry.Item
+	item.Attempts = 0
+	item.Replays++
+	if err := q.EnqueueImmediate(ctx, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetDeadQueueLength returns the length of the dead-letter queue
+func (q *RedisQueue) GetDeadQueueLength(ctx context.Context) (int64, error) {
+	length, err := q.client.LLen(ctx, q.deadLetterKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dead-letter queue length: %w", err)
+	}
+	return length, nil
+}
+
+// LogMessage is a single message on a job's live log channel
+type LogMessage struct {
+	Type   string `json:"type"`             // "line" for output, "end" when the job finishes
+	Line   string `json:"line,omitempty"`   // Output line (Type == "line")
+	Status string `json:"status,omitempty"` // Final job status (Type == "end")
+}
+
+const (
+	LogMessageLine = "line"
+	LogMessageEnd  = "end"
+)
+
+// jobLogChannel returns the pub/sub channel used for a job's live output
+func jobLogChannel(jobID string) string {
+	return fmt.Sprintf("karbos:logs:%s", jobID)
+}
+
+// PublishJobLogLine publishes a single output line for a running job
+func (q *RedisQueue) PublishJobLogLine(ctx context.Context, jobID, line string) error {
+	return q.publishLogMessage(ctx, jobID, LogMessage{Type: LogMessageLine, Line: line})
+}
+
+// PublishJobLogEnd signals subscribers that a job has finished with the given status
+func (q *RedisQueue) PublishJobLogEnd(ctx context.Context, jobID, status string) error {
+	return q.publishLogMessage(ctx, jobID, LogMessage{Type: LogMessageEnd, Status: status})
+}
+
+func (q *RedisQueue) publishLogMessage(ctx context.Context, jobID string, msg LogMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log message: %w", err)
+	}
+
+	if err := q.client.Publish(ctx, jobLogChannel(jobID), data).Err(); err != nil {
+		return fmt.Errorf("failed to publish log message: %w", err)
+	}
+	return nil
+}
+
+// jobControlChannel returns the pub/sub channel used to control a running job
+func jobControlChannel(jobID string) string {
+	return fmt.Sprintf("karbos:control:%s", jobID)
+}
+
+// jobCancelMessage asks the worker running a job to stop it
+const jobCancelMessage = "cancel"
+
+// jobCancelKey marks a job as cancelled, for a worker that misses the published request
+func jobCancelKey(jobID string) string {
+	return fmt.Sprintf("karbos:cancel:%s", jobID)
+}
+
+// jobCancelTTL bounds how long an unclaimed cancel marker is kept; no job runs this long
+const jobCancelTTL = 24 * time.Hour
+
+// PublishJobCancel asks whichever worker is running a job to stop it, returning how many
+// subscribers received the request (0 when no worker is running the job). The request
+// is also recorded under karbos:cancel:<jobID>, which workers check while the job runs,
+// so it isn't lost if the message is.
+func (q *RedisQueue) PublishJobCancel(ctx context.Context, jobID string) (int64, error) {
+	var publish *redis.IntCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, jobCancelKey(jobID), jobCancelMessage, jobCancelTTL)
+		publish = pipe.Publish(ctx, jobControlChannel(jobID), jobCancelMessage)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish cancel for job %s: %w", jobID, err)
+	}
+	return publish.Val(), nil
+}
+
+// JobCancelRequested reports whether a job has an outstanding cancel request
+func (q *RedisQueue) JobCancelRequested(ctx context.Context, jobID string) (bool, error) {
+	n, err := q.client.Exists(ctx, jobCancelKey(jobID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check cancel for job %s: %w", jobID, err)
+	}
+	return n > 0, nil
+}
+
+// ClearJobCancel removes a job's cancel marker once its run has ended
+func (q *RedisQueue) ClearJobCancel(ctx context.Context, jobID string) error {
+	if err := q.client.Del(ctx, jobCancelKey(jobID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear cancel for job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// SubscribeJobCancel subscribes to cancellation requests for a job. The returned channel
+// receives a value when the job should be cancelled; call the returned function to
+// unsubscribe and release the connection.
+func (q *RedisQueue) SubscribeJobCancel(ctx context.Context, jobID string) (<-chan struct{}, func() error, error) {
+	pubsub := q.client.Subscribe(ctx, jobControlChannel(jobID))
+
+	// Wait for the subscription to be confirmed so a cancel published right after is seen
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to job control: %w", err)
+	}
+
+	cancelled := make(chan struct{}, 1)
+	go func() {
+		for msg := range pubsub.Channel() {
+			if msg.Payload != jobCancelMessage {
+				continue
+			}
+			select {
+			case cancelled <- struct{}{}:
+			default: // Already signalled
+			}
+		}
+	}()
+
+	return cancelled, pubsub.Close, nil
+}
+
+// SubscribeJobLogs subscribes to a job's live output.
+// The returned channel is closed when the subscription ends; call the returned
+// function to unsubscribe and release the connection.
+func (q *RedisQueue) SubscribeJobLogs(ctx context.Context, jobID string) (<-chan LogMessage, func() error, error) {
+	pubsub := q.client.Subscribe(ctx, jobLogChannel(jobID))
+
+	// Wait for the subscription to be confirmed so no messages are missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to job logs: %w", err)
+	}
+
+	messages := make(chan LogMessage)
+	go func() {
+		defer close(messages)
+		for raw := range pubsub.Channel() {
+			var msg LogMessage
+			if err := json.Unmarshal([]byte(raw.Payload), &msg); err != nil {
+				slog.Warn("Failed to unmarshal log message", logging.Err(err))
+				continue
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return messages, pubsub.Close, nil
+}
@@ -0,0 +1,457 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestQueue starts an in-memory Redis server and connects a queue to it
+func newTestQueue(t *testing.T) (*RedisQueue, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	q, err := NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+
+	return q, server
+}
+
+func TestRedisQueue_HigherPriorityDequeuedFirst(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "low", Priority: 0}); err != nil {
+		t.Fatalf("enqueue low: %v", err)
+	}
+	if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: "high", Priority: 10}); err != nil {
+		t.Fatalf("enqueue high: %v", err)
+	}
+
+	length, err := q.GetImmediateQueueLength(ctx)
+	if err != nil || length != 2 {
+		t.Fatalf("expected queue length 2, got %d (err %v)", length, err)
+	}
+
+	for _, want := range []string{"high", "low"} {
+		item, err := q.DequeueImmediate(ctx)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if item == nil || item.JobID != want {
+			t.Fatalf("expected %s, got %+v", want, item)
+		}
+	}
+
+	item, err := q.DequeueImmediate(ctx)
+	if err != nil || item != nil {
+		t.Errorf("expected empty queue, got %+v (err %v)", item, err)
+	}
+}
+
+func TestRedisQueue_SamePriorityIsFIFO(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"first", "second", "third"} {
+		if err := q.EnqueueImmediate(ctx, &QueueItem{JobID: id, Priority: 5}); err != nil {
+			t.Fatalf("enqueue %s: %v", id, err)
+		}
+		time.Sleep(2 * time.Millisecond) // Distinct submission times
+	}
+
+	for _, want := range []string{"first", "second", "third"} {
+		item, err := q.DequeueImmediate(ctx)
+		if err != nil || item == nil || item.JobID != want {
+			t.Fatalf("expected %s, got %+v (err %v)", want, item, err)
+		}
+	}
+}
+
+func TestNewRedisQueue_MigratesLegacyList(t *testing.T) {
+	server := miniredis.RunT(t)
+	for _, item := range []QueueItem{{JobID: "old-1"}, {JobID: "old-2"}, {JobID: "urgent", Priority: 9}} {
+		data, _ := json.Marshal(item)
+		server.RPush("test:immediate", string(data))
+	}
+
+	q, err := NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.Close()
+
+	ctx := context.Background()
+	for _, want := range []string{"urgent", "old-1", "old-2"} {
+		item, err := q.DequeueImmediate(ctx)
+		if err != nil || item == nil || item.JobID != want {
+			t.Fatalf("expected %s, got %+v (err %v)", want, item, err)
+		}
+	}
+}
+
+func TestRedisQueue_CountDelayedInSlot(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	slot := time.Now().Add(2 * time.Hour).Truncate(time.Hour)
+
+	items := []*QueueItem{
+		{JobID: "a", Region: "US-EAST", ScheduledTime: slot},
+		{JobID: "b", Region: "US-EAST", ScheduledTime: slot.Add(30 * time.Minute)},
+		{JobID: "c", Region: "EU-WEST", ScheduledTime: slot.Add(10 * time.Minute)},
+		{JobID: "d", Region: "US-EAST", ScheduledTime: slot.Add(time.Hour)}, // next slot
+	}
+	for _, item := range items {
+		if err := q.EnqueueDelayed(ctx, item); err != nil {
+			t.Fatalf("EnqueueDelayed(%s): %v", item.JobID, err)
+		}
+	}
+
+	count, err := q.CountDelayedInSlot(ctx, "US-EAST", slot, slot.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CountDelayedInSlot: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 US-EAST jobs in slot, got %d", count)
+	}
+}
+
+func TestQueueItem_JSONRoundTrip(t *testing.T) {
+	command := "echo hi"
+	item := QueueItem{
+		JobID:         "job-1",
+		DockerImage:   "alpine:latest",
+		Command:       &command,
+		ScheduledTime: time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC),
+		Priority:      7,
+		Region:        "EU-NORTH",
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var decoded QueueItem
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded.Region != item.Region || decoded.Priority != item.Priority || !decoded.ScheduledTime.Equal(item.ScheduledTime) ||
+		decoded.Command == nil || *decoded.Command != command {
+		t.Errorf("round trip changed the item: got %+v, want %+v", decoded, item)
+	}
+}
+
+func TestRedisQueue_PeekDelayedSoonestFirst(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, item := range []*QueueItem{
+		{JobID: "later", Region: "EU-NORTH", Priority: 2, ScheduledTime: now.Add(3 * time.Hour)},
+		{JobID: "sooner", Region: "US-EAST", Priority: 8, ScheduledTime: now.Add(time.Hour)},
+	} {
+		if err := q.EnqueueDelayed(ctx, item); err != nil {
+			t.Fatalf("EnqueueDelayed(%s): %v", item.JobID, err)
+		}
+	}
+
+	items, err := q.PeekDelayed(ctx, 10)
+	if err != nil {
+		t.Fatalf("PeekDelayed: %v", err)
+	}
+	if len(items) != 2 || items[0].JobID != "sooner" || items[1].JobID != "later" {
+		t.Fatalf("expected [sooner later], got %+v", items)
+	}
+	if items[0].Region != "US-EAST" || items[0].Priority != 8 {
+		t.Errorf("expected region and priority to survive the queue, got %+v", items[0])
+	}
+
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 2 {
+		t.Errorf("expected peeking to leave both jobs queued, got %d", length)
+	}
+}
+
+func TestRedisQueue_GetQueueDepths(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+
+	q.EnqueueImmediate(ctx, &QueueItem{JobID: "a", Region: "US-EAST", Priority: 5})
+	q.EnqueueImmediate(ctx, &QueueItem{JobID: "b", Region: "US-EAST", Priority: 5})
+	q.EnqueueImmediate(ctx, &QueueItem{JobID: "c", Priority: 0})
+	q.EnqueueDelayed(ctx, &QueueItem{JobID: "d", Region: "US-EAST", Priority: 5, ScheduledTime: later})
+	q.EnqueueDelayed(ctx, &QueueItem{JobID: "e", Region: "EU-NORTH", Priority: 5, ScheduledTime: later})
+
+	depths, err := q.GetQueueDepths(ctx)
+	if err != nil {
+		t.Fatalf("GetQueueDepths: %v", err)
+	}
+
+	got := make(map[QueueDepth]bool)
+	for _, depth := range depths {
+		got[depth] = true
+	}
+	for _, want := range []QueueDepth{
+		{Queue: "immediate", Region: "US-EAST", Priority: 5, Count: 2},
+		{Queue: "immediate", Region: "", Priority: 0, Count: 1},
+		{Queue: "delayed", Region: "US-EAST", Priority: 5, Count: 1},
+		{Queue: "delayed", Region: "EU-NORTH", Priority: 5, Count: 1},
+	} {
+		if !got[want] {
+			t.Errorf("expected depth %+v in %+v", want, depths)
+		}
+	}
+	if len(depths) != 4 {
+		t.Errorf("expected 4 depths, got %d", len(depths))
+	}
+}
+
+func TestRedisQueue_RemoveDeadLeavesOtherEntries(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"job-a", "job-b", "job-c"} {
+		if err := q.EnqueueDead(ctx, &QueueItem{JobID: id, UserID: "user-1", Attempts: 3}, "exit code 1"); err != nil {
+			t.Fatalf("EnqueueDead failed: %v", err)
+		}
+	}
+
+	entry, err := q.RemoveDead(ctx, "job-b")
+	if err != nil {
+		t.Fatalf("RemoveDead returned error: %v", err)
+	}
+	if entry.Item.UserID != "user-1" || entry.Reason != "exit code 1" {
+		t.Errorf("expected the removed entry to keep its owner and reason, got %+v", entry)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("RemoveDead should not requeue the job, immediate length %d", length)
+	}
+
+	remaining, err := q.ListDead(ctx)
+	if err != nil {
+		t.Fatalf("ListDead returned error: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].Item.JobID != "job-c" || remaining[1].Item.JobID != "job-a" {
+		t.Errorf("expected job-c and job-a to remain, got %+v", remaining)
+	}
+
+	if _, err := q.RemoveDead(ctx, "job-b"); err == nil {
+		t.Error("expected an error removing a job that is no longer dead-lettered")
+	}
+}
+
+func TestRedisQueue_RequeueDeadCountsReplays(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.EnqueueDead(ctx, &QueueItem{JobID: "job-a", Attempts: 3, Replays: 1}, "exit code 1"); err != nil {
+		t.Fatalf("EnqueueDead failed: %v", err)
+	}
+
+	item, err := q.RequeueDead(ctx, "job-a")
+	if err != nil {
+		t.Fatalf("RequeueDead returned error: %v", err)
+	}
+	if item.Attempts != 0 || item.Replays != 2 {
+		t.Errorf("expected attempts reset and replays incremented, got attempts=%d replays=%d", item.Attempts, item.Replays)
+	}
+
+	queued, err := q.DequeueImmediate(ctx)
+	if err != nil {
+		t.Fatalf("DequeueImmediate returned error: %v", err)
+	}
+	if queued.Replays != 2 {
+		t.Errorf("expected the replay count to travel with the queued job, got %d", queued.Replays)
+	}
+}
+
+func TestRedisQueue_IncrRateLimitResetsAfterWindow(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		count, ttl, err := q.IncrRateLimit(ctx, "ip:10.0.0.1", time.Minute)
+		if err != nil {
+			t.Fatalf("IncrRateLimit returned error: %v", err)
+		}
+		if count != want {
+			t.Errorf("expected count %d, got %d", want, count)
+		}
+		if ttl <= 0 || ttl > time.Minute {
+			t.Errorf("expected ttl within the window, got %s", ttl)
+		}
+	}
+
+	server.FastForward(time.Minute)
+
+	count, _, err := q.IncrRateLimit(ctx, "ip:10.0.0.1", time.Minute)
+	if err != nil {
+		t.Fatalf("IncrRateLimit returned error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected a fresh window after expiry, got count %d", count)
+	}
+}
+
+func TestRedisQueue_JobLock(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	if acquired, err := q.AcquireJobLock(ctx, "job-1", "owner-a", time.Minute); err != nil || !acquired {
+		t.Fatalf("first acquire: acquired=%v err=%v", acquired, err)
+	}
+	if acquired, _ := q.AcquireJobLock(ctx, "job-1", "owner-b", time.Minute); acquired {
+		t.Fatal("second acquire should fail while the lock is held")
+	}
+
+	// Locks are not workers
+	if workers, _ := q.GetActiveWorkers(ctx); len(workers) != 0 {
+		t.Errorf("active workers = %v, want none", workers)
+	}
+
+	// Only the holder can release it
+	if err := q.ReleaseJobLock(ctx, "job-1", "owner-b"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if !server.Exists("worker:lock:job-1") {
+		t.Fatal("lock released by a worker that doesn't hold it")
+	}
+	if err := q.ReleaseJobLock(ctx, "job-1", "owner-a"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	// An abandoned lock expires
+	if acquired, _ := q.AcquireJobLock(ctx, "job-2", "owner-a", time.Minute); !acquired {
+		t.Fatal("expected to acquire job-2")
+	}
+	server.FastForward(2 * time.Minute)
+	if acquired, _ := q.AcquireJobLock(ctx, "job-2", "owner-b", time.Minute); !acquired {
+		t.Error("expected the expired lock to be acquirable")
+	}
+	if acquired, _ := q.AcquireJobLock(ctx, "job-1", "owner-b", time.Minute); !acquired {
+		t.Error("expected the released lock to be acquirable")
+	}
+}
+
+func TestRedisQueue_JobCancelPubSub(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	if receivers, err := q.PublishJobCancel(ctx, "job-1"); err != nil || receivers != 0 {
+		t.Fatalf("publish without a worker = %d, %v; want 0 receivers", receivers, err)
+	}
+
+	cancelled, unsubscribe, err := q.SubscribeJobCancel(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	if receivers, err := q.PublishJobCancel(ctx, "job-1"); err != nil || receivers != 1 {
+		t.Fatalf("publish = %d, %v; want 1 receiver", receivers, err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("cancel request not received")
+	}
+}
+
+func TestRedisQueue_IdempotencyKey(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	first := &IdempotencyRecord{JobID: "job-1", Fingerprint: "abc"}
+	if existing, err := q.ClaimIdempotencyKey(ctx, "user-1", "key-1", first, time.Hour); existing != nil || err != nil {
+		t.Fatalf("first claim = %+v, %v; want nil, nil", existing, err)
+	}
+
+	// Another user's identical key is a separate claim
+	if existing, err := q.ClaimIdempotencyKey(ctx, "user-2", "key-1", &IdempotencyRecord{JobID: "job-2"}, time.Hour); existing != nil || err != nil {
+		t.Fatalf("other user's claim = %+v, %v; want nil, nil", existing, err)
+	}
+
+	first.Response = []byte(`{"job_id":"job-1"}`)
+	if err := q.CompleteIdempotencyKey(ctx, "user-1", "key-1", first); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	existing, err := q.ClaimIdempotencyKey(ctx, "user-1", "key-1", &IdempotencyRecord{JobID: "job-3", Fingerprint: "abc"}, time.Hour)
+	if err != nil || existing == nil {
+		t.Fatalf("repeat claim = %+v, %v; want the stored record", existing, err)
+	}
+	if existing.JobID != "job-1" || string(existing.Response) != `{"job_id":"job-1"}` {
+		t.Errorf("stored record = %+v, want job-1 with its response", existing)
+	}
+
+	// Completing keeps the claim's TTL
+	server.FastForward(time.Hour)
+	if existing, err := q.ClaimIdempotencyKey(ctx, "user-1", "key-1", &IdempotencyRecord{JobID: "job-4"}, time.Hour); existing != nil || err != nil {
+		t.Errorf("claim after expiry = %+v, %v; want nil, nil", existing, err)
+	}
+
+	if err := q.ReleaseIdempotencyKey(ctx, "user-1", "key-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if existing, err := q.ClaimIdempotencyKey(ctx, "user-1", "key-1", &IdempotencyRecord{JobID: "job-5"}, time.Hour); existing != nil || err != nil {
+		t.Errorf("claim after release = %+v, %v; want nil, nil", existing, err)
+	}
+}
+
+func TestRedisQueue_WaitingJobs(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	if err := q.HoldWaiting(ctx, &QueueItem{JobID: "job-1", DockerImage: "alpine:latest", Priority: 3}); err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+
+	item, err := q.TakeWaiting(ctx, "job-1")
+	if err != nil || item == nil {
+		t.Fatalf("take = %+v, %v; want the held item", item, err)
+	}
+	if item.DockerImage != "alpine:latest" || item.Priority != 3 {
+		t.Errorf("taken item = %+v, want the held one", item)
+	}
+
+	// Taking removes the item, so only one releaser gets it
+	if item, err := q.TakeWaiting(ctx, "job-1"); item != nil || err != nil {
+		t.Errorf("second take = %+v, %v; want nil, nil", item, err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("immediate queue length = %d, want 0 (held jobs aren't queued)", length)
+	}
+}
+
+func TestRedisQueue_JobCancelMarker(t *testing.T) {
+	q, server := newTestQueue(t)
+	ctx := context.Background()
+
+	if requested, err := q.JobCancelRequested(ctx, "job-1"); err != nil || requested {
+		t.Fatalf("before any cancel: requested=%v err=%v, want false", requested, err)
+	}
+
+	// No worker is subscribed, but the request is kept for the one running the job
+	if receivers, err := q.PublishJobCancel(ctx, "job-1"); err != nil || receivers != 0 {
+		t.Fatalf("PublishJobCancel: %d receivers, err %v; want 0 receivers", receivers, err)
+	}
+	if requested, err := q.JobCancelRequested(ctx, "job-1"); err != nil || !requested {
+		t.Errorf("after cancel: requested=%v err=%v, want true", requested, err)
+	}
+	if ttl := server.TTL(jobCancelKey("job-1")); ttl <= 0 || ttl > jobCancelTTL {
+		t.Errorf("cancel marker TTL = %v, want up to %v", ttl, jobCancelTTL)
+	}
+
+	if err := q.ClearJobCancel(ctx, "job-1"); err != nil {
+		t.Fatalf("ClearJobCancel: %v", err)
+	}
+	if requested, _ := q.JobCancelRequested(ctx, "job-1"); requested {
+		t.Error("expected the marker cleared once the run ended")
+	}
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// Jobs submitted with dependencies are held in a hash, keyed by job ID, until the jobs
+// they depend on have completed. They are then taken out and put on the immediate queue.
+
+// waitingKeySuffix names the hash of held jobs after the immediate queue key
+const waitingKeySuffix = ":waiting"
+
+// waitingKey returns the hash holding jobs that wait on dependencies
+func (q *RedisQueue) waitingKey() string {
+	return q.immediateQueueKey + waitingKeySuffix
+}
+
+// HoldWaiting keeps a job's queue item until its dependencies allow it to run
+func (q *RedisQueue) HoldWaiting(ctx context.Context, item *QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue item: %w", err)
+	}
+
+	if err := q.client.HSet(ctx, q.waitingKey(), item.JobID, data).Err(); err != nil {
+		return fmt.Errorf("failed to hold waiting job: %w", err)
+	}
+
+	slog.Info("Holding job until its dependencies complete", logging.KeyJobID, item.JobID)
+	return nil
+}
+
+// TakeWaiting removes and returns a held job's queue item, or nil if it isn't held
+func (q *RedisQueue) TakeWaiting(ctx context.Context, jobID string) (*QueueItem, error) {
+	var get *redis.StringCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.HGet(ctx, q.waitingKey(), jobID)
+		pipe.HDel(ctx, q.waitingKey(), jobID)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take waiting job: %w", err)
+	}
+
+	var item QueueItem
+	if err := json.Unmarshal([]byte(get.Val()), &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue item: %w", err)
+	}
+	return &item, nil
+}
@@ -0,0 +1,123 @@
+package recurring
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// store reads due recurring jobs and records their runs
+type store interface {
+	GetDueRecurringJobs(ctx context.Context, now time.Time, limit int) ([]*models.RecurringJob, error)
+	ClaimRecurringRun(ctx context.Context, id uuid.UUID, due, next, ranAt time.Time) (bool, error)
+	RecordRecurringRun(ctx context.Context, id uuid.UUID, jobID *uuid.UUID, runErr *string) error
+}
+
+// submitter places a job as POST /api/submit would
+type submitter interface {
+	Submit(ctx context.Context, req *models.SubmitJobRequest) (*models.SubmitJobResponse, error)
+}
+
+// dueBatchSize caps the recurring jobs fired per check; the rest wait for the next one
+const dueBatchSize = 100
+
+// Runner submits a job for every recurring schedule that has come due. Each job's
+// deadline is the schedule's following run, so the carbon scheduler can place it
+// anywhere in the interval before the next one is submitted.
+type Runner struct {
+	store     store
+	submitter submitter
+	interval  time.Duration
+}
+
+// NewRunner creates a runner that checks for due schedules every interval (a minute
+// when zero)
+func NewRunner(store store, submitter submitter, interval time.Duration) *Runner {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Runner{
+		store:     store,
+		submitter: submitter,
+		interval:  interval,
+	}
+}
+
+// Run fires due schedules every interval until ctx is cancelled
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if fired, err := r.RunDue(ctx, time.Now()); err != nil {
+			slog.Warn("Failed to check recurring jobs", logging.Err(err))
+		} else if fired > 0 {
+			slog.Info("Submitted recurring jobs", "jobs", fired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue submits one job for each schedule due at now and returns how many were
+// submitted. Runs missed while no API was up are not caught up: a schedule fires once
+// and moves on to its next run after now.
+func (r *Runner) RunDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := r.store.GetDueRecurringJobs(ctx, now, dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	for _, recurring := range due {
+		schedule, err := ParseSchedule(recurring.CronExpression, recurring.Timezone)
+		if err != nil {
+			slog.Warn("Skipping recurring job with an unusable schedule", "recurring_job_id", recurring.ID, logging.Err(err))
+			continue
+		}
+		next := schedule.Next(now)
+
+		// Advancing next_run_at first means only one API replica submits this run
+		claimed, err := r.store.ClaimRecurringRun(ctx, recurring.ID, recurring.NextRunAt, next, now)
+		if err != nil {
+			return fired, err
+		}
+		if !claimed {
+			continue
+		}
+
+		spec := recurring.JobSpec
+		spec.UserID = recurring.UserID
+		spec.Deadline = next.UTC().Format(time.RFC3339)
+		spec.IdempotencyKey = ""
+
+		var jobID *uuid.UUID
+		var runErr *string
+		response, err := r.submitter.Submit(ctx, &spec)
+		if err != nil {
+			message := err.Error()
+			runErr = &message
+			slog.Warn("Recurring job submission failed", "recurring_job_id", recurring.ID, logging.Err(err))
+		} else {
+			id, _ := uuid.Parse(response.JobID)
+			jobID = &id
+			fired++
+			slog.Info("Recurring job submitted", "recurring_job_id", recurring.ID, logging.KeyJobID, response.JobID,
+				"scheduled_time", response.ScheduledTime, "next_run_at", next)
+		}
+
+		if err := r.store.RecordRecurringRun(ctx, recurring.ID, jobID, runErr); err != nil {
+			slog.Warn("Failed to record recurring run", "recurring_job_id", recurring.ID, logging.Err(err))
+		}
+	}
+
+	return fired, nil
+}
@@ -0,0 +1,161 @@
+package recurring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// fakeStore holds recurring jobs in memory, claiming runs the way the repository does
+type fakeStore struct {
+	jobs     []*models.RecurringJob
+	lostRace bool // Another replica claims every run first
+	recorded map[uuid.UUID]*string
+}
+
+func (s *fakeStore) GetDueRecurringJobs(ctx context.Context, now time.Time, limit int) ([]*models.RecurringJob, error) {
+	var due []*models.RecurringJob
+	for _, job := range s.jobs {
+		if !job.NextRunAt.After(now) {
+			copied := *job
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) ClaimRecurringRun(ctx context.Context, id uuid.UUID, due, next, ranAt time.Time) (bool, error) {
+	if s.lostRace {
+		return false, nil
+	}
+	for _, job := range s.jobs {
+		if job.ID == id && job.NextRunAt.Equal(due) {
+			job.NextRunAt = next
+			job.LastRunAt = &ranAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeStore) RecordRecurringRun(ctx context.Context, id uuid.UUID, jobID *uuid.UUID, runErr *string) error {
+	if s.recorded == nil {
+		s.recorded = make(map[uuid.UUID]*string)
+	}
+	s.recorded[id] = runErr
+	for _, job := range s.jobs {
+		if job.ID == id {
+			job.LastJobID = jobID
+		}
+	}
+	return nil
+}
+
+// fakeSubmitter records submissions, failing them when err is set
+type fakeSubmitter struct {
+	submitted []models.SubmitJobRequest
+	err       error
+}
+
+func (s *fakeSubmitter) Submit(ctx context.Context, req *models.SubmitJobRequest) (*models.SubmitJobResponse, error) {
+	s.submitted = append(s.submitted, *req)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.SubmitJobResponse{JobID: uuid.New().String(), Status: models.JobStatusDelayed}, nil
+}
+
+func dailyJob(nextRun time.Time) *models.RecurringJob {
+	return &models.RecurringJob{
+		ID:             uuid.New(),
+		UserID:         "user-1",
+		CronExpression: "0 2 * * *",
+		Timezone:       "UTC",
+		JobSpec:        models.SubmitJobRequest{DockerImage: "alpine:latest", Command: []string{"echo", "nightly"}},
+		NextRunAt:      nextRun,
+	}
+}
+
+func TestRunner_RunDue_SubmitsDueSchedule(t *testing.T) {
+	now := time.Date(2025, 3, 10, 2, 0, 30, 0, time.UTC)
+	job := dailyJob(time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC))
+	store := &fakeStore{jobs: []*models.RecurringJob{job}}
+	submitter := &fakeSubmitter{}
+
+	fired, err := NewRunner(store, submitter, 0).RunDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("RunDue failed: %v", err)
+	}
+	if fired != 1 || len(submitter.submitted) != 1 {
+		t.Fatalf("Expected 1 submission, got fired=%d submitted=%d", fired, len(submitter.submitted))
+	}
+
+	// The job may run any time before the schedule's next run
+	nextRun := time.Date(2025, 3, 11, 2, 0, 0, 0, time.UTC)
+	spec := submitter.submitted[0]
+	if spec.UserID != "user-1" || spec.DockerImage != "alpine:latest" {
+		t.Errorf("Submitted spec = %+v, want the stored job spec for user-1", spec)
+	}
+	if spec.Deadline != nextRun.Format(time.RFC3339) {
+		t.Errorf("Deadline = %q, want %q", spec.Deadline, nextRun.Format(time.RFC3339))
+	}
+
+	if !job.NextRunAt.Equal(nextRun) {
+		t.Errorf("NextRunAt = %v, want %v", job.NextRunAt, nextRun)
+	}
+	if job.LastJobID == nil {
+		t.Error("Expected the submitted job ID to be recorded")
+	}
+
+	// Nothing is due again until tomorrow
+	fired, err = NewRunner(store, submitter, 0).RunDue(context.Background(), now.Add(time.Minute))
+	if err != nil || fired != 0 {
+		t.Errorf("Second check fired=%d err=%v, want nothing", fired, err)
+	}
+}
+
+func TestRunner_RunDue_SkipsNotDueAndUnclaimed(t *testing.T) {
+	now := time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC)
+	store := &fakeStore{jobs: []*models.RecurringJob{dailyJob(time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC))}}
+	submitter := &fakeSubmitter{}
+
+	if fired, err := NewRunner(store, submitter, 0).RunDue(context.Background(), now); err != nil || fired != 0 {
+		t.Errorf("Not-due check fired=%d err=%v, want nothing", fired, err)
+	}
+
+	store.lostRace = true
+	if fired, err := NewRunner(store, submitter, 0).RunDue(context.Background(), now.Add(2*time.Hour)); err != nil || fired != 0 {
+		t.Errorf("Unclaimed check fired=%d err=%v, want nothing", fired, err)
+	}
+	if len(submitter.submitted) != 0 {
+		t.Errorf("Expected no submissions, got %d", len(submitter.submitted))
+	}
+}
+
+func TestRunner_RunDue_RecordsSubmissionError(t *testing.T) {
+	now := time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC)
+	job := dailyJob(now)
+	store := &fakeStore{jobs: []*models.RecurringJob{job}}
+	submitter := &fakeSubmitter{err: errors.New("invalid_volume: path not allowed")}
+
+	fired, err := NewRunner(store, submitter, 0).RunDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("RunDue failed: %v", err)
+	}
+	if fired != 0 {
+		t.Errorf("fired = %d, want 0", fired)
+	}
+
+	runErr := store.recorded[job.ID]
+	if runErr == nil || *runErr != "invalid_volume: path not allowed" {
+		t.Errorf("Recorded error = %v, want the submission error", runErr)
+	}
+	// A failed run still moves the schedule on rather than retrying every minute
+	if !job.NextRunAt.After(now) {
+		t.Errorf("NextRunAt = %v, want a time after %v", job.NextRunAt, now)
+	}
+}
@@ -0,0 +1,72 @@
+// Package recurring submits jobs on cron schedules.
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Resolve IANA zones on hosts without a zoneinfo database
+
+	"github.com/robfig/cron/v3"
+)
+
+// MinInterval is the shortest gap allowed between two runs of a schedule. The runner
+// checks for due schedules once a minute, so anything tighter would only skip runs.
+const MinInterval = time.Minute
+
+// ErrInvalidSchedule is returned for cron expressions or timezones that can't be used
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule is a parsed cron expression evaluated in a timezone
+type Schedule struct {
+	spec     cron.Schedule
+	location *time.Location
+}
+
+// ParseSchedule parses a standard five-field cron expression ("0 2 * * *") or a
+// descriptor ("@daily", "@every 2h") in the IANA timezone tz ("" means UTC). The zone
+// goes in tz rather than a CRON_TZ= prefix, so it is stored in one place.
+func ParseSchedule(expr, tz string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, fmt.Errorf("%w: set the timezone separately instead of a TZ= prefix", ErrInvalidSchedule)
+	}
+
+	spec, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	if tz == "" {
+		tz = "UTC"
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, tz)
+	}
+
+	schedule := &Schedule{spec: spec, location: location}
+
+	// Reject expressions that never fire or fire too often for the runner to keep up
+	first := schedule.Next(time.Now())
+	if first.IsZero() {
+		return nil, fmt.Errorf("%w: %q never fires", ErrInvalidSchedule, expr)
+	}
+	if second := schedule.Next(first); !second.IsZero() && second.Sub(first) < MinInterval {
+		return nil, fmt.Errorf("%w: runs must be at least %s apart", ErrInvalidSchedule, MinInterval)
+	}
+
+	return schedule, nil
+}
+
+// Next returns the first fire time strictly after after, or the zero time if the
+// schedule never fires again
+func (s *Schedule) Next(after time.Time) time.Time {
+	return s.spec.Next(after.In(s.location))
+}
+
+// Location returns the timezone the schedule is evaluated in
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
@@ -0,0 +1,79 @@
+package recurring
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	after := time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		tz       string
+		expected time.Time
+	}{
+		{"daily at 02:00 UTC", "0 2 * * *", "", time.Date(2025, 3, 11, 2, 0, 0, 0, time.UTC)},
+		{"every 15 minutes", "*/15 * * * *", "UTC", time.Date(2025, 3, 10, 14, 45, 0, 0, time.UTC)},
+		{"descriptor", "@hourly", "", time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)},
+		{"weekdays only", "0 9 * * 1-5", "", time.Date(2025, 3, 11, 9, 0, 0, 0, time.UTC)},
+		// 02:00 in Kolkata (UTC+5:30) is 20:30 UTC the day before
+		{"in a timezone", "0 2 * * *", "Asia/Kolkata", time.Date(2025, 3, 10, 20, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr, tt.tz)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q, %q) failed: %v", tt.expr, tt.tz, err)
+			}
+			if next := schedule.Next(after); !next.Equal(tt.expected) {
+				t.Errorf("Next = %v, want %v", next.UTC(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next_FollowsDaylightSaving(t *testing.T) {
+	schedule, err := ParseSchedule("0 9 * * *", "America/New_York")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+
+	// US clocks went forward on 9 March 2025: 09:00 local moves from 14:00 to 13:00 UTC
+	before := schedule.Next(time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC))
+	after := schedule.Next(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+
+	if want := time.Date(2025, 3, 8, 14, 0, 0, 0, time.UTC); !before.Equal(want) {
+		t.Errorf("Next before the change = %v, want %v", before.UTC(), want)
+	}
+	if want := time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC); !after.Equal(want) {
+		t.Errorf("Next after the change = %v, want %v", after.UTC(), want)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		tz   string
+	}{
+		{"empty", "", ""},
+		{"garbage", "not a cron", ""},
+		{"too many fields", "0 0 2 * * *", ""},
+		{"unknown timezone", "0 2 * * *", "Mars/Olympus_Mons"},
+		{"inline timezone", "CRON_TZ=Europe/Berlin 0 2 * * *", ""},
+		{"never fires", "0 0 30 2 *", ""},
+		{"too frequent", "@every 10s", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSchedule(tt.expr, tt.tz); !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("ParseSchedule(%q, %q) error = %v, want ErrInvalidSchedule", tt.expr, tt.tz, err)
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ package scheduler
 import (
 	"context"
 	"fmt"
-	"math"
+	"log/slog"
+	"sort"
 	"time"
 
 	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 )
 
 // CarbonFetcher interface for retrieving carbon intensity data
@@ -15,6 +17,11 @@ type CarbonFetcher interface {
 	GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error)
 }
 
+// SlotOccupancy reports how many jobs are already scheduled into a region's time slot
+type SlotOccupancy interface {
+	CountDelayedInSlot(ctx context.Context, region string, start, end time.Time) (int, error)
+}
+
 // ScheduleRequest represents a job scheduling request
 type ScheduleRequest struct {
 	Region       string        // Geographic region for carbon intensity
@@ -22,15 +29,63 @@ type ScheduleRequest struct {
 	Deadline     time.Time     // Latest time job must complete
 	WindowSize   time.Duration // Time window to consider (default 24 hours)
 	MinStartTime time.Time     // Earliest time job can start (default now)
+	Explain      bool          // Attach a decision trace to the result
+	MaxIntensity float64       // Overrides the immediate-execution threshold in gCO2eq/kWh (0 = scheduler default)
 }
 
 // ScheduleResult contains the scheduling decision
 type ScheduleResult struct {
-	ScheduledTime      time.Time    // Optimal start time for job
-	ExpectedIntensity  float64      // Expected carbon intensity at scheduled time
-	Immediate          bool         // Whether to run immediately or schedule for later
-	CarbonSavings      float64      // Estimated carbon savings vs immediate execution
-	AlternativeWindows []TimeWindow // Other optimal windows
+	ScheduledTime      time.Time      // Optimal start time for job
+	ExpectedIntensity  float64        // Expected carbon intensity at scheduled time
+	CurrentIntensity   float64        // Carbon intensity at the time of scheduling
+	Immediate          bool           // Whether to run immediately or schedule for later
+	CarbonSavings      float64        // Estimated carbon savings vs immediate execution
+	AlternativeWindows []TimeWindow   // Other optimal windows
+	Trace              *DecisionTrace // Decision trace (only when ScheduleRequest.Explain is set)
+
+	IntensityScale carbon.IntensityScale // Relative when the intensities above are a provider index, not gCO2eq/kWh
+
+	DeferralCapped bool // The max deferral ended the search before the deadline and window size would have
+}
+
+// Decision conditions recorded in a DecisionTrace
+const (
+	ConditionNoForecast        = "no_forecast"        // No forecast data, fell back to current intensity
+	ConditionOptimalIsNow      = "optimal_window_now" // Best window starts now
+	ConditionNegligibleSavings = "negligible_savings" // Savings below the minimum percentage
+	ConditionBelowThreshold    = "below_threshold"    // Current intensity already below threshold
+	ConditionDelayForSavings   = "delay_for_savings"  // A later window is meaningfully greener
+)
+
+// DecisionTrace explains how the scheduler reached its decision
+type DecisionTrace struct {
+	Region            string             `json:"region"`
+	ForecastPoints    []ForecastPoint    `json:"forecast_points"`
+	EvaluatedWindows  []WindowEvaluation `json:"evaluated_windows"`
+	OptimalWindow     *WindowEvaluation  `json:"optimal_window,omitempty"`
+	FullWindows       []WindowEvaluation `json:"full_windows,omitempty"` // Greener windows skipped because their slot was at capacity
+	CurrentIntensity  float64            `json:"current_intensity"`
+	SavingsPercent    float64            `json:"savings_percent"`
+	Threshold         float64            `json:"threshold"`
+	IntensityScale    string             `json:"intensity_scale"`
+	DeferralCapped    bool               `json:"deferral_capped"` // Windows starting after the max deferral weren't considered
+	MinSavingsPercent float64            `json:"min_savings_percent"`
+	Immediate         bool               `json:"immediate"`
+	TriggeredBy       []string           `json:"triggered_by"`
+	Reason            string             `json:"reason"`
+}
+
+// ForecastPoint is a single forecast value considered by the scheduler
+type ForecastPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Intensity float64   `json:"intensity"`
+}
+
+// WindowEvaluation is a candidate execution window and its average intensity
+type WindowEvaluation struct {
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	AvgIntensity float64   `json:"avg_intensity"`
 }
 
 // TimeWindow represents a potential execution window
@@ -41,11 +96,31 @@ type TimeWindow struct {
 	CarbonCost   float64
 }
 
+// minSavingsPercent is the smallest saving worth delaying a job for
+const minSavingsPercent = 10.0
+
+// relativeThreshold replaces the gCO2eq/kWh threshold for providers that only report a
+// 0-100 index (WattTime's percentile of the past month); above it the grid is dirtier
+// than usual for the region
+const relativeThreshold = 50.0
+
+// MaxIntensityCeiling bounds a request's MaxIntensity; no grid runs dirtier than this,
+// so a higher threshold would only hide a unit mistake
+const MaxIntensityCeiling = 2000.0
+
 // CarbonScheduler implements the sliding window scheduling algorithm
 type CarbonScheduler struct {
 	fetcher      CarbonFetcher
-	slotDuration time.Duration // Duration of each time slot (default 1 hour)
+	slotDuration time.Duration // Duration of each time slot, and the longest a forecast point holds (default 1 hour)
 	threshold    float64       // Carbon intensity threshold for immediate execution
+
+	slotCap   int           // Max jobs per region+slot (0 = unlimited)
+	occupancy SlotOccupancy // Source of current slot occupancy when slotCap is set
+
+	maxAlternatives int     // Max alternative windows returned with a result
+	alternativeBand float64 // How far above the optimal average an alternative may be
+
+	maxDeferral time.Duration // Longest a job's start may be pushed past MinStartTime (0 = up to its deadline)
 }
 
 // NewCarbonScheduler creates a new carbon-aware scheduler
@@ -54,6 +129,9 @@ func NewCarbonScheduler(fetcher CarbonFetcher) *CarbonScheduler {
 		fetcher:      fetcher,
 		slotDuration: 1 * time.Hour,
 		threshold:    400.0, // Default threshold: 400 gCO2eq/kWh
+
+		maxAlternatives: 3,
+		alternativeBand: 10.0, // Within 10 gCO2eq/kWh of the optimal window
 	}
 }
 
@@ -78,30 +156,39 @@ func (s *CarbonScheduler) Schedule(ctx context.Context, req *ScheduleRequest) (*
 		endTime = req.Deadline
 	}
 
+	// However distant the deadline, the job must start within the max deferral
+	horizon := req.Deadline
+	var latestStart time.Time
+	capped := false
+	if s.maxDeferral > 0 {
+		if latest := req.MinStartTime.Add(s.maxDeferral + req.Duration); latest.Before(endTime) {
+			endTime, horizon, capped = latest, latest, true
+			latestStart = req.MinStartTime.Add(s.maxDeferral)
+		}
+	}
+
 	forecast, err := s.fetcher.GetCarbonForecast(ctx, req.Region, req.MinStartTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get carbon forecast: %w", err)
 	}
 
-	if len(forecast) == 0 {
-		// No forecast data available - use current intensity
-		current, err := s.fetcher.GetCurrentCarbonIntensity(ctx, req.Region)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get current carbon intensity: %w", err)
-		}
-		return &ScheduleResult{
-			ScheduledTime:     time.Now(),
-			ExpectedIntensity: current.Intensity,
-			Immediate:         true,
-			CarbonSavings:     0,
-		}, nil
+	// Only points from the earliest start to the horizon can be scheduled on; a forecast
+	// with none of them, e.g. one that ended before MinStartTime, counts as none at all
+	slots := s.buildTimeSlots(forecast, req.MinStartTime, horizon)
+	if len(slots) == 0 {
+		return s.scheduleAtCurrent(ctx, req, capped, len(forecast) > 0)
 	}
 
 	// Run sliding window algorithm
-	optimalWindow, alternativeWindows := s.findOptimalWindow(forecast, req.Duration, req.MinStartTime, req.Deadline)
+	optimalWindow, alternativeWindows, evaluated := s.findOptimalWindow(forecast, req.Duration, req.MinStartTime, latestStart, horizon)
+
+	// Spread jobs away from slots that are already at capacity for this region
+	optimalWindow, fullWindows := s.applySlotCap(ctx, req.Region, optimalWindow, evaluated)
 
 	// Get current intensity for comparison
-	currentIntensity := forecast[0].Intensity
+	currentIntensity := slots[0].Intensity
+	scale := scaleOf(slots[0])
+	threshold := s.requestThreshold(req, scale)
 
 	// Calculate carbon savings
 	carbonSavings := currentIntensity - optimalWindow.AvgIntensity
@@ -115,85 +202,306 @@ func (s *CarbonScheduler) Schedule(ctx context.Context, req *ScheduleRequest) (*
 	// 1. Current time is already optimal
 	// 2. Savings are negligible (< 10%)
 	// 3. Current intensity is below threshold
-	if time.Since(optimalWindow.StartTime) < 5*time.Minute ||
-		savingsPercent < 10.0 ||
-		currentIntensity < s.threshold {
+	var triggered []string
+	if time.Until(optimalWindow.StartTime) < 5*time.Minute {
+		triggered = append(triggered, ConditionOptimalIsNow)
+	}
+	if savingsPercent < minSavingsPercent {
+		triggered = append(triggered, ConditionNegligibleSavings)
+	}
+	if currentIntensity < threshold {
+		triggered = append(triggered, ConditionBelowThreshold)
+	}
+	if len(triggered) > 0 {
 		immediate = true
 		scheduledTime = time.Now()
 	}
 
-	return &ScheduleResult{
+	result := &ScheduleResult{
 		ScheduledTime:      scheduledTime,
 		ExpectedIntensity:  optimalWindow.AvgIntensity,
+		CurrentIntensity:   currentIntensity,
 		Immediate:          immediate,
 		CarbonSavings:      carbonSavings,
 		AlternativeWindows: alternativeWindows,
-	}, nil
+		IntensityScale:     scale,
+		DeferralCapped:     capped,
+	}
+
+	if req.Explain {
+		result.Trace = s.buildTrace(req.Region, scale, threshold, forecast, evaluated, optimalWindow, currentIntensity, savingsPercent, triggered)
+		result.Trace.DeferralCapped = capped
+		for _, window := range fullWindows {
+			result.Trace.FullWindows = append(result.Trace.FullWindows, WindowEvaluation{StartTime: window.StartTime, EndTime: window.EndTime, AvgIntensity: window.AvgIntensity})
+		}
+	}
+
+	slog.DebugContext(ctx, "Scheduling decision",
+		logging.KeyRegion, req.Region,
+		"immediate", immediate,
+		"scheduled_time", scheduledTime,
+		"current_intensity", currentIntensity,
+		"expected_intensity", optimalWindow.AvgIntensity,
+		"triggered_by", triggered,
+		"deferral_capped", capped,
+	)
+
+	return result, nil
+}
+
+// scheduleAtCurrent runs a job immediately at the region's current intensity, for when
+// the forecast has no points to schedule on. hadForecast tells the trace whether the
+// forecast was empty or only fell outside the scheduling window.
+func (s *CarbonScheduler) scheduleAtCurrent(ctx context.Context, req *ScheduleRequest, capped, hadForecast bool) (*ScheduleResult, error) {
+	current, err := s.fetcher.GetCurrentCarbonIntensity(ctx, req.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current carbon intensity: %w", err)
+	}
+	result := &ScheduleResult{
+		ScheduledTime:     time.Now(),
+		ExpectedIntensity: current.Intensity,
+		CurrentIntensity:  current.Intensity,
+		Immediate:         true,
+		CarbonSavings:     0,
+		IntensityScale:    scaleOf(*current),
+		DeferralCapped:    capped,
+	}
+	if req.Explain {
+		reason := "No forecast data available; running immediately at current intensity"
+		if hadForecast {
+			reason = "No forecast data within the scheduling window; running immediately at current intensity"
+		}
+		result.Trace = &DecisionTrace{
+			Region:            req.Region,
+			ForecastPoints:    []ForecastPoint{},
+			EvaluatedWindows:  []WindowEvaluation{},
+			CurrentIntensity:  current.Intensity,
+			Threshold:         s.requestThreshold(req, result.IntensityScale),
+			IntensityScale:    string(result.IntensityScale),
+			DeferralCapped:    capped,
+			MinSavingsPercent: minSavingsPercent,
+			Immediate:         true,
+			TriggeredBy:       []string{ConditionNoForecast},
+			Reason:            reason,
+		}
+	}
+	return result, nil
+}
+
+// applySlotCap returns the lowest-intensity window whose starting slot still has room
+// for this region, along with the better windows that were skipped because they were full.
+// Without a cap, or if every window is full, the optimal window is returned unchanged.
+func (s *CarbonScheduler) applySlotCap(ctx context.Context, region string, optimal TimeWindow, evaluated []TimeWindow) (TimeWindow, []TimeWindow) {
+	if s.slotCap <= 0 || s.occupancy == nil || len(evaluated) == 0 {
+		return optimal, nil
+	}
+	// Jobs that would start now go straight to the immediate queue and never occupy a slot
+	if time.Until(optimal.StartTime) < 5*time.Minute {
+		return optimal, nil
+	}
+
+	candidates := make([]TimeWindow, len(evaluated))
+	copy(candidates, evaluated)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].AvgIntensity < candidates[j].AvgIntensity
+	})
+
+	var full []TimeWindow
+	for _, window := range candidates {
+		count, err := s.occupancy.CountDelayedInSlot(ctx, region, window.StartTime, window.StartTime.Add(s.slotDuration))
+		if err != nil {
+			// Occupancy unknown: don't block scheduling on it
+			slog.WarnContext(ctx, "Failed to read slot occupancy, ignoring slot cap", logging.KeyRegion, region, logging.Err(err))
+			return optimal, nil
+		}
+		if count < s.slotCap {
+			return window, full
+		}
+		full = append(full, window)
+	}
+
+	return optimal, full
+}
+
+// buildTrace assembles the decision trace for an explained scheduling run
+func (s *CarbonScheduler) buildTrace(region string, scale carbon.IntensityScale, threshold float64, forecast []carbon.CarbonIntensity, evaluated []TimeWindow, optimal TimeWindow, currentIntensity, savingsPercent float64, triggered []string) *DecisionTrace {
+	trace := &DecisionTrace{
+		Region:            region,
+		ForecastPoints:    make([]ForecastPoint, 0, len(forecast)),
+		EvaluatedWindows:  make([]WindowEvaluation, 0, len(evaluated)),
+		OptimalWindow:     &WindowEvaluation{StartTime: optimal.StartTime, EndTime: optimal.EndTime, AvgIntensity: optimal.AvgIntensity},
+		CurrentIntensity:  currentIntensity,
+		SavingsPercent:    savingsPercent,
+		Threshold:         threshold,
+		IntensityScale:    string(scale),
+		MinSavingsPercent: minSavingsPercent,
+		Immediate:         len(triggered) > 0,
+		TriggeredBy:       triggered,
+	}
+
+	for _, point := range forecast {
+		trace.ForecastPoints = append(trace.ForecastPoints, ForecastPoint{Timestamp: point.Timestamp, Intensity: point.Intensity})
+	}
+	for _, window := range evaluated {
+		trace.EvaluatedWindows = append(trace.EvaluatedWindows, WindowEvaluation{StartTime: window.StartTime, EndTime: window.EndTime, AvgIntensity: window.AvgIntensity})
+	}
+
+	unit := "gCO2eq/kWh"
+	if scale == carbon.IntensityScaleRelative {
+		unit = "(relative index)"
+	}
+
+	switch {
+	case len(triggered) == 0:
+		trace.TriggeredBy = []string{ConditionDelayForSavings}
+		trace.Reason = fmt.Sprintf("Delaying to %s saves %.1f%% (current %.1f %s is at or above threshold %.1f)",
+			optimal.StartTime.Format(time.RFC3339), savingsPercent, currentIntensity, unit, trace.Threshold)
+	case triggered[0] == ConditionOptimalIsNow:
+		trace.Reason = "The lowest-carbon window starts now"
+	case triggered[0] == ConditionNegligibleSavings:
+		trace.Reason = fmt.Sprintf("Savings of %.1f%% are below the %.0f%% minimum", savingsPercent, minSavingsPercent)
+	default:
+		trace.Reason = fmt.Sprintf("Current intensity %.1f %s is below threshold %.1f", currentIntensity, unit, trace.Threshold)
+	}
+
+	return trace
 }
 
-// findOptimalWindow uses sliding window algorithm to find lowest carbon window
-func (s *CarbonScheduler) findOptimalWindow(forecast []carbon.CarbonIntensity, duration time.Duration, minStart, deadline time.Time) (TimeWindow, []TimeWindow) {
+// findOptimalWindow uses sliding window algorithm to find lowest carbon window.
+// It also returns every window that was evaluated, in start-time order.
+//
+// Windows are driven by the forecast's timestamps rather than a fixed slot count, so
+// 15-minute, hourly and irregular data all work: each window starts at a forecast point
+// and accumulates the following points until they cover the job's duration, weighting
+// each point's intensity by the time it holds within the window. A window can't span a
+// gap in the forecast. Windows starting after latestStart are skipped unless it is zero.
+// With no forecast points between minStart and deadline, nothing is evaluated.
+func (s *CarbonScheduler) findOptimalWindow(forecast []carbon.CarbonIntensity, duration time.Duration, minStart, latestStart, deadline time.Time) (TimeWindow, []TimeWindow, []TimeWindow) {
 	// Convert forecast to time-series data structure
 	slots := s.buildTimeSlots(forecast, minStart, deadline)
+	if len(slots) == 0 {
+		return TimeWindow{}, nil, nil
+	}
+	spans := s.pointSpans(slots)
 
-	// Calculate window size in slots
-	windowSlots := int(math.Ceil(float64(duration) / float64(s.slotDuration)))
+	// Sliding window algorithm
+	var evaluated []TimeWindow
+	for i := range slots {
+		if !latestStart.IsZero() && slots[i].Timestamp.After(latestStart) {
+			continue
+		}
+		avgIntensity, ok := windowAverage(slots[i:], spans[i:], duration)
+		if !ok {
+			continue
+		}
+		evaluated = append(evaluated, TimeWindow{
+			StartTime:    slots[i].Timestamp,
+			EndTime:      slots[i].Timestamp.Add(duration),
+			AvgIntensity: avgIntensity,
+			CarbonCost:   avgIntensity * duration.Hours(),
+		})
+	}
 
-	if windowSlots > len(slots) {
-		// Job duration exceeds forecast range - use entire range
+	if len(evaluated) == 0 {
+		// Job duration exceeds every stretch of forecast - use entire range
 		avgIntensity := s.calculateAverageIntensity(slots)
-		return TimeWindow{
+		last := len(slots) - 1
+		window := TimeWindow{
 			StartTime:    slots[0].Timestamp,
-			EndTime:      slots[len(slots)-1].Timestamp.Add(s.slotDuration),
+			EndTime:      slots[last].Timestamp.Add(spans[last]),
 			AvgIntensity: avgIntensity,
 			CarbonCost:   avgIntensity * duration.Hours(),
-		}, nil
+		}
+		return window, nil, []TimeWindow{window}
 	}
 
-	// Sliding window algorithm
-	var optimalWindow TimeWindow
-	var alternativeWindows []TimeWindow
-	minIntensity := math.MaxFloat64
-
-	for i := 0; i <= len(slots)-windowSlots; i++ {
-		windowEnd := i + windowSlots
-		windowSlice := slots[i:windowEnd]
+	optimalWindow, alternativeWindows := s.rankWindows(evaluated)
+	return optimalWindow, alternativeWindows, evaluated
+}
 
-		// Calculate average intensity for this window
-		avgIntensity := s.calculateAverageIntensity(windowSlice)
-		carbonCost := avgIntensity * duration.Hours()
+// pointSpans returns how long each forecast point's intensity holds: until the next
+// point, but never longer than slotDuration. A longer step to the next point is a gap.
+func (s *CarbonScheduler) pointSpans(slots []carbon.CarbonIntensity) []time.Duration {
+	spans := make([]time.Duration, len(slots))
+	for i := range slots {
+		spans[i] = s.slotDuration
+		if i+1 < len(slots) {
+			if step := slots[i+1].Timestamp.Sub(slots[i].Timestamp); step < s.slotDuration {
+				spans[i] = step
+			}
+		}
+	}
+	return spans
+}
 
-		window := TimeWindow{
-			StartTime:    windowSlice[0].Timestamp,
-			EndTime:      windowSlice[len(windowSlice)-1].Timestamp.Add(s.slotDuration),
-			AvgIntensity: avgIntensity,
-			CarbonCost:   carbonCost,
+// windowAverage is the time-weighted average intensity of a job of the given duration
+// starting at slots[0]. It reports false when the forecast ends or has a gap before
+// the job would finish.
+func windowAverage(slots []carbon.CarbonIntensity, spans []time.Duration, duration time.Duration) (float64, bool) {
+	var covered time.Duration
+	weighted := 0.0
+	for i, slot := range slots {
+		// Only the part of the last point's span the job still needs counts
+		held := spans[i]
+		if remaining := duration - covered; held > remaining {
+			held = remaining
 		}
+		weighted += slot.Intensity * float64(held)
+		covered += held
 
-		// Track optimal window
-		if avgIntensity < minIntensity {
-			minIntensity = avgIntensity
-			optimalWindow = window
-			alternativeWindows = []TimeWindow{} // Reset alternatives
-		} else if math.Abs(avgIntensity-minIntensity) < 10.0 {
-			// Track near-optimal windows (within 10 gCO2eq/kWh)
-			alternativeWindows = append(alternativeWindows, window)
+		if covered >= duration {
+			return weighted / float64(duration), true
+		}
+		if i+1 < len(slots) && slots[i+1].Timestamp.After(slot.Timestamp.Add(spans[i])) {
+			return 0, false // The next point is beyond this one's span
 		}
 	}
+	return 0, false
+}
 
-	// Limit alternative windows to top 3
-	if len(alternativeWindows) > 3 {
-		alternativeWindows = alternativeWindows[:3]
+// rankWindows returns the lowest-intensity window (the earliest on a tie) and, greenest
+// first, up to maxAlternatives other windows within alternativeBand of it. Alternatives
+// overlap neither the optimum nor each other, so a neighbour shifted by a slot doesn't
+// crowd out a genuinely different time to run.
+func (s *CarbonScheduler) rankWindows(evaluated []TimeWindow) (TimeWindow, []TimeWindow) {
+	ranked := make([]TimeWindow, len(evaluated))
+	copy(ranked, evaluated)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].AvgIntensity < ranked[j].AvgIntensity
+	})
+
+	optimal := ranked[0]
+	picked := []TimeWindow{optimal}
+	var alternatives []TimeWindow
+	for _, window := range ranked[1:] {
+		if len(alternatives) >= s.maxAlternatives || window.AvgIntensity-optimal.AvgIntensity > s.alternativeBand {
+			break
+		}
+		if overlapsAny(window, picked) {
+			continue
+		}
+		picked = append(picked, window)
+		alternatives = append(alternatives, window)
 	}
+	return optimal, alternatives
+}
 
-	return optimalWindow, alternativeWindows
+// overlapsAny reports whether window shares any time with one of windows
+func overlapsAny(window TimeWindow, windows []TimeWindow) bool {
+	for _, other := range windows {
+		if window.StartTime.Before(other.EndTime) && other.StartTime.Before(window.EndTime) {
+			return true
+		}
+	}
+	return false
 }
 
 // buildTimeSlots converts forecast data into time slots
 func (s *CarbonScheduler) buildTimeSlots(forecast []carbon.CarbonIntensity, minStart, deadline time.Time) []carbon.CarbonIntensity {
 	var slots []carbon.CarbonIntensity
 
-	for _, point := range forecast {
+	// A duplicated hour would otherwise be counted twice in window averages
+	for _, point := range carbon.DedupeForecast(forecast) {
 		// Filter by time constraints
 		if point.Timestamp.Before(minStart) || point.Timestamp.After(deadline) {
 			continue
@@ -204,18 +512,21 @@ func (s *CarbonScheduler) buildTimeSlots(forecast []carbon.CarbonIntensity, minS
 	return slots
 }
 
-// calculateAverageIntensity computes average carbon intensity for time slots
+// calculateAverageIntensity computes the time-weighted average carbon intensity for time
+// slots, each weighted by how long it holds
 func (s *CarbonScheduler) calculateAverageIntensity(slots []carbon.CarbonIntensity) float64 {
 	if len(slots) == 0 {
 		return 0
 	}
 
-	sum := 0.0
-	for _, slot := range slots {
-		sum += slot.Intensity
+	var total time.Duration
+	weighted := 0.0
+	for i, span := range s.pointSpans(slots) {
+		weighted += slots[i].Intensity * float64(span)
+		total += span
 	}
 
-	return sum / float64(len(slots))
+	return weighted / float64(total)
 }
 
 // validateRequest checks if scheduling request is valid
@@ -246,6 +557,39 @@ func (s *CarbonScheduler) SetThreshold(threshold float64) {
 	s.threshold = threshold
 }
 
+// SetSlotCap limits how many jobs can be scheduled into the same region and slot;
+// further jobs spill over to the next-best windows. A cap of 0 disables the limit.
+func (s *CarbonScheduler) SetSlotCap(slotCap int, occupancy SlotOccupancy) {
+	s.slotCap = slotCap
+	s.occupancy = occupancy
+}
+
+// SetMaxAlternatives sets how many alternative windows a result lists (0 = none)
+func (s *CarbonScheduler) SetMaxAlternatives(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.maxAlternatives = n
+}
+
+// SetAlternativeBand sets how far above the optimal window's average intensity an
+// alternative window may be, in the forecast's unit
+func (s *CarbonScheduler) SetAlternativeBand(band float64) {
+	if band < 0 {
+		band = 0
+	}
+	s.alternativeBand = band
+}
+
+// SetMaxDeferral bounds how long after submission a job may be scheduled to start,
+// however distant its deadline. A value of 0 lets jobs wait until their deadline.
+func (s *CarbonScheduler) SetMaxDeferral(maxDeferral time.Duration) {
+	if maxDeferral < 0 {
+		maxDeferral = 0
+	}
+	s.maxDeferral = maxDeferral
+}
+
 // SetSlotDuration updates the duration of each time slot
 func (s *CarbonScheduler) SetSlotDuration(duration time.Duration) {
 	s.slotDuration = duration
@@ -259,5 +603,32 @@ func (s *CarbonScheduler) ShouldSchedule(ctx context.Context, region string) (bo
 	}
 
 	// If current intensity is above threshold, scheduling is likely beneficial
-	return current.Intensity > s.threshold, nil
+	return current.Intensity > s.thresholdFor(scaleOf(*current)), nil
+}
+
+// thresholdFor returns the immediate-execution threshold matching an intensity scale;
+// a relative index can't be compared against the gCO2eq/kWh threshold
+func (s *CarbonScheduler) thresholdFor(scale carbon.IntensityScale) float64 {
+	if scale == carbon.IntensityScaleRelative {
+		return relativeThreshold
+	}
+	return s.threshold
+}
+
+// scaleOf returns the intensity scale of a data point, defaulting to absolute
+func scaleOf(point carbon.CarbonIntensity) carbon.IntensityScale {
+	if point.IsRelative() {
+		return carbon.IntensityScaleRelative
+	}
+	return carbon.IntensityScaleAbsolute
+}
+
+// requestThreshold returns the immediate-execution threshold for a request: its own
+// MaxIntensity when set, otherwise the scheduler's. A relative index always uses
+// relativeThreshold, since MaxIntensity is in gCO2eq/kWh.
+func (s *CarbonScheduler) requestThreshold(req *ScheduleRequest, scale carbon.IntensityScale) float64 {
+	if req.MaxIntensity > 0 && scale != carbon.IntensityScaleRelative {
+		return req.MaxIntensity
+	}
+	return s.thresholdFor(scale)
 }
@@ -0,0 +1,635 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/carbon"
+)
+
+// fakeFetcher returns a fixed hourly forecast
+type fakeFetcher struct {
+	forecast []carbon.CarbonIntensity
+}
+
+func (f *fakeFetcher) GetCarbonForecast(ctx context.Context, region string, startTime, endTime time.Time) ([]carbon.CarbonIntensity, error) {
+	return f.forecast, nil
+}
+
+func (f *fakeFetcher) GetCurrentCarbonIntensity(ctx context.Context, region string) (*carbon.CarbonIntensity, error) {
+	if len(f.forecast) == 0 {
+		return &carbon.CarbonIntensity{Region: region, Timestamp: time.Now(), Intensity: 300}, nil
+	}
+	return &f.forecast[0], nil
+}
+
+// hourlyForecast builds a forecast starting at start with one point per intensity value
+func hourlyForecast(start time.Time, intensities ...float64) []carbon.CarbonIntensity {
+	forecast := make([]carbon.CarbonIntensity, len(intensities))
+	for i, intensity := range intensities {
+		forecast[i] = carbon.CarbonIntensity{
+			Region:    "US-EAST",
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Intensity: intensity,
+			Unit:      "gCO2eq/kWh",
+		}
+	}
+	return forecast
+}
+
+func TestSchedule_ExplainDelayTrace(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	fetcher := &fakeFetcher{forecast: hourlyForecast(start, 600, 550, 200, 180, 500)}
+	s := NewCarbonScheduler(fetcher)
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     2 * time.Hour,
+		Deadline:     start.Add(6 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.Immediate {
+		t.Fatal("expected job to be delayed")
+	}
+
+	trace := result.Trace
+	if trace == nil {
+		t.Fatal("expected a decision trace in explain mode")
+	}
+	if len(trace.ForecastPoints) != 5 {
+		t.Errorf("expected 5 forecast points, got %d", len(trace.ForecastPoints))
+	}
+	if len(trace.EvaluatedWindows) != 4 {
+		t.Fatalf("expected 4 evaluated two-hour windows, got %d", len(trace.EvaluatedWindows))
+	}
+	if got := trace.EvaluatedWindows[2].AvgIntensity; got != 190 {
+		t.Errorf("expected window 3 average 190, got %.1f", got)
+	}
+	if trace.OptimalWindow == nil || trace.OptimalWindow.AvgIntensity != 190 {
+		t.Errorf("expected optimal window average 190, got %+v", trace.OptimalWindow)
+	}
+	if len(trace.TriggeredBy) != 1 || trace.TriggeredBy[0] != ConditionDelayForSavings {
+		t.Errorf("expected %q trigger, got %v", ConditionDelayForSavings, trace.TriggeredBy)
+	}
+	if trace.Threshold != 400 {
+		t.Errorf("expected threshold 400 in trace, got %.1f", trace.Threshold)
+	}
+}
+
+func TestSchedule_ExplainImmediateBelowThreshold(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	fetcher := &fakeFetcher{forecast: hourlyForecast(start, 250, 100, 120)}
+	s := NewCarbonScheduler(fetcher)
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(4 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !result.Immediate {
+		t.Fatal("expected immediate execution below threshold")
+	}
+
+	trace := result.Trace
+	if trace == nil {
+		t.Fatal("expected a decision trace in explain mode")
+	}
+	if len(trace.EvaluatedWindows) != 3 {
+		t.Errorf("expected 3 evaluated windows, got %d", len(trace.EvaluatedWindows))
+	}
+
+	found := false
+	for _, condition := range trace.TriggeredBy {
+		if condition == ConditionBelowThreshold {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %q among triggers, got %v", ConditionBelowThreshold, trace.TriggeredBy)
+	}
+}
+
+func TestSchedule_MaxIntensityOverridesThreshold(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	// 250 now is below the default 400 threshold, but a clean window follows
+	fetcher := &fakeFetcher{forecast: hourlyForecast(start, 250, 100, 120)}
+	s := NewCarbonScheduler(fetcher)
+
+	schedule := func(maxIntensity float64) *ScheduleResult {
+		t.Helper()
+		result, err := s.Schedule(context.Background(), &ScheduleRequest{
+			Region:       "US-EAST",
+			Duration:     time.Hour,
+			Deadline:     start.Add(4 * time.Hour),
+			MinStartTime: start,
+			Explain:      true,
+			MaxIntensity: maxIntensity,
+		})
+		if err != nil {
+			t.Fatalf("Schedule returned error: %v", err)
+		}
+		return result
+	}
+
+	if result := schedule(0); !result.Immediate {
+		t.Fatal("expected immediate execution under the default threshold")
+	}
+
+	strict := schedule(200)
+	if strict.Immediate {
+		t.Fatal("expected a strict threshold to defer the job")
+	}
+	if strict.Trace.Threshold != 200 || strict.ExpectedIntensity != 100 {
+		t.Errorf("expected deferral to the 100 window under threshold 200, got %+v", strict.Trace)
+	}
+
+	// A lenient threshold runs the job now even on a dirty grid
+	fetcher.forecast = hourlyForecast(start, 600, 550, 200, 180, 500)
+	if result := schedule(1000); !result.Immediate {
+		t.Error("expected a lenient threshold to run immediately")
+	}
+}
+
+func TestSchedule_NoTraceWithoutExplain(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: hourlyForecast(start, 500, 450)})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(3 * time.Hour),
+		MinStartTime: start,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.Trace != nil {
+		t.Error("trace should only be attached in explain mode")
+	}
+}
+
+func TestSchedule_DuplicateTimestampsCountedOnce(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	forecast := hourlyForecast(start, 500, 100, 500)
+	// Revised value for the second hour; the stale 100 must not be averaged in
+	forecast = append(forecast, carbon.CarbonIntensity{Region: "US-EAST", Timestamp: start.Add(time.Hour), Intensity: 300})
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     2 * time.Hour,
+		Deadline:     start.Add(4 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+
+	windows := result.Trace.EvaluatedWindows
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 two-hour windows over 3 unique hours, got %d", len(windows))
+	}
+	for i, want := range []float64{400, 400} {
+		if windows[i].AvgIntensity != want {
+			t.Errorf("window %d: expected average %.0f, got %.1f", i, want, windows[i].AvgIntensity)
+		}
+	}
+}
+
+func TestSchedule_ForecastBeforeMinStartRunsNow(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	// Every point ends before the job may start, e.g. a stale cached forecast
+	stale := hourlyForecast(start.Add(-6*time.Hour), 450, 100, 120)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: stale})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(4 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !result.Immediate || result.CurrentIntensity != 450 || result.CarbonSavings != 0 {
+		t.Errorf("expected an immediate run at the current intensity, got %+v", result)
+	}
+	if len(result.Trace.TriggeredBy) != 1 || result.Trace.TriggeredBy[0] != ConditionNoForecast {
+		t.Errorf("expected the no-forecast condition, got %v", result.Trace.TriggeredBy)
+	}
+
+	if optimal, alternatives, evaluated := s.findOptimalWindow(stale, time.Hour, start, time.Time{}, start.Add(4*time.Hour)); !optimal.StartTime.IsZero() || alternatives != nil || evaluated != nil {
+		t.Errorf("expected no windows without forecast points in range, got %+v / %v / %v", optimal, alternatives, evaluated)
+	}
+}
+
+func TestSchedule_CurrentIntensityFromFirstSlot(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	// The first point is before the job may start; the first schedulable one is current
+	forecast := append(hourlyForecast(start.Add(-time.Hour), 900), hourlyForecast(start, 500, 100, 500)...)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(3 * time.Hour),
+		MinStartTime: start,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.CurrentIntensity != 500 || result.CarbonSavings != 400 {
+		t.Errorf("expected current intensity 500 and savings 400, got %.1f and %.1f", result.CurrentIntensity, result.CarbonSavings)
+	}
+}
+
+// fakeOccupancy reports a fixed job count per slot start time
+type fakeOccupancy struct {
+	counts map[time.Time]int
+}
+
+func (f *fakeOccupancy) CountDelayedInSlot(ctx context.Context, region string, start, end time.Time) (int, error) {
+	return f.counts[start], nil
+}
+
+func TestSchedule_SlotCapSpillsToNextBestWindow(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	forecast := hourlyForecast(start, 600, 500, 100, 150, 550)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+	s.SetSlotCap(2, &fakeOccupancy{counts: map[time.Time]int{
+		forecast[2].Timestamp: 2,
+	}})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(5 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.Immediate {
+		t.Fatal("expected job to be delayed")
+	}
+	if !result.ScheduledTime.Equal(forecast[3].Timestamp) {
+		t.Errorf("expected spill to %v, got %v", forecast[3].Timestamp, result.ScheduledTime)
+	}
+	if result.ExpectedIntensity != 150 {
+		t.Errorf("expected intensity 150, got %v", result.ExpectedIntensity)
+	}
+	if len(result.Trace.FullWindows) != 1 || !result.Trace.FullWindows[0].StartTime.Equal(forecast[2].Timestamp) {
+		t.Errorf("expected the full 100 window in the trace, got %+v", result.Trace.FullWindows)
+	}
+}
+
+func TestSchedule_SlotCapAllWindowsFullKeepsOptimal(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	forecast := hourlyForecast(start, 600, 500, 100, 150, 550)
+	counts := make(map[time.Time]int)
+	for _, point := range forecast {
+		counts[point.Timestamp] = 5
+	}
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+	s.SetSlotCap(2, &fakeOccupancy{counts: counts})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(5 * time.Hour),
+		MinStartTime: start,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !result.ScheduledTime.Equal(forecast[2].Timestamp) {
+		t.Errorf("expected optimal window %v, got %v", forecast[2].Timestamp, result.ScheduledTime)
+	}
+}
+
+func TestSchedule_SlotCapSpillWithoutSavingsRunsNow(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	forecast := hourlyForecast(start, 600, 580, 100, 590, 595)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+	s.SetSlotCap(1, &fakeOccupancy{counts: map[time.Time]int{
+		forecast[2].Timestamp: 1,
+	}})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(5 * time.Hour),
+		MinStartTime: start,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !result.Immediate {
+		t.Errorf("expected immediate execution once the only green slot is full, got %v", result.ScheduledTime)
+	}
+}
+
+// relativeForecast marks a forecast as a 0-100 provider index
+func relativeForecast(forecast []carbon.CarbonIntensity) []carbon.CarbonIntensity {
+	for i := range forecast {
+		forecast[i].Unit = "percent"
+		forecast[i].IntensityScale = carbon.IntensityScaleRelative
+	}
+	return forecast
+}
+
+func TestSchedule_RelativeIndexIgnoresAbsoluteThreshold(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	// Every value is far below the 400 gCO2eq/kWh threshold, but 80 is a dirty hour on the index
+	fetcher := &fakeFetcher{forecast: relativeForecast(hourlyForecast(start, 80, 75, 10, 12, 70))}
+	s := NewCarbonScheduler(fetcher)
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     2 * time.Hour,
+		Deadline:     start.Add(6 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.Immediate {
+		t.Fatalf("expected a relative index to be delayed for savings, got triggers %v", result.Trace.TriggeredBy)
+	}
+	if result.IntensityScale != carbon.IntensityScaleRelative {
+		t.Errorf("expected relative scale on the result, got %q", result.IntensityScale)
+	}
+	if result.Trace.Threshold != relativeThreshold || result.Trace.IntensityScale != "relative" {
+		t.Errorf("expected the relative threshold in the trace, got %.1f (%q)", result.Trace.Threshold, result.Trace.IntensityScale)
+	}
+}
+
+func TestSchedule_RelativeIndexBelowMedianRunsNow(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: relativeForecast(hourlyForecast(start, 30, 5, 5))})
+
+	result, err := s.Schedule(context.Background(), &ScheduleRequest{
+		Region:       "US-EAST",
+		Duration:     time.Hour,
+		Deadline:     start.Add(4 * time.Hour),
+		MinStartTime: start,
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !result.Immediate {
+		t.Fatal("expected immediate execution below the relative threshold")
+	}
+
+	shouldSchedule, err := s.ShouldSchedule(context.Background(), "US-EAST")
+	if err != nil {
+		t.Fatalf("ShouldSchedule returned error: %v", err)
+	}
+	if shouldSchedule {
+		t.Error("expected ShouldSchedule to compare the index against the relative threshold")
+	}
+}
+
+// forecastAt builds a forecast with a point at each offset from start
+func forecastAt(start time.Time, points map[time.Duration]float64) []carbon.CarbonIntensity {
+	var forecast []carbon.CarbonIntensity
+	for offset, intensity := range points {
+		forecast = append(forecast, carbon.CarbonIntensity{Region: "US-EAST", Timestamp: start.Add(offset), Intensity: intensity})
+	}
+	return carbon.DedupeForecast(forecast) // Sorted by timestamp
+}
+
+func TestSchedule_MaxDeferralCapsWeekOutDeadline(t *testing.T) {
+	start := time.Now().Add(time.Minute)
+	intensities := make([]float64, 48)
+	for i := range intensities {
+		intensities[i] = 600
+	}
+	intensities[6] = 300  // Best start within 12 hours
+	intensities[13] = 50  // Just past the cap
+	intensities[30] = 100 // Best start before the deadline
+	fetcher := &fakeFetcher{forecast: hourlyForecast(start, intensities...)}
+
+	req := func() *ScheduleRequest {
+		return &ScheduleRequest{
+			Region:       "US-EAST",
+			Duration:     time.Hour,
+			Deadline:     start.Add(7 * 24 * time.Hour),
+			WindowSize:   48 * time.Hour,
+			MinStartTime: start,
+			Explain:      true,
+		}
+	}
+
+	s := NewCarbonScheduler(fetcher)
+	uncapped, err := s.Schedule(context.Background(), req())
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if uncapped.DeferralCapped || !uncapped.ScheduledTime.Equal(start.Add(13*time.Hour)) {
+		t.Fatalf("without a cap: scheduled %v (capped %v), want hour 13", uncapped.ScheduledTime.Sub(start), uncapped.DeferralCapped)
+	}
+
+	s.SetMaxDeferral(12 * time.Hour)
+	capped, err := s.Schedule(context.Background(), req())
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if !capped.DeferralCapped || !capped.Trace.DeferralCapped {
+		t.Error("expected the result and trace to report the deferral cap")
+	}
+	if capped.Immediate || !capped.ScheduledTime.Equal(start.Add(6*time.Hour)) {
+		t.Errorf("with a 12h cap: scheduled %v (immediate %v), want hour 6", capped.ScheduledTime.Sub(start), capped.Immediate)
+	}
+	for _, window := range capped.Trace.EvaluatedWindows {
+		if window.StartTime.After(start.Add(12 * time.Hour)) {
+			t.Errorf("evaluated a window starting %v after submission, past the 12h cap", window.StartTime.Sub(start))
+		}
+	}
+
+	// A deadline inside the cap is left alone
+	near := req()
+	near.Deadline = start.Add(8 * time.Hour)
+	result, err := s.Schedule(context.Background(), near)
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if result.DeferralCapped {
+		t.Error("expected no cap for a deadline within the max deferral")
+	}
+}
+
+func TestFindOptimalWindow_IrregularTimestamps(t *testing.T) {
+	start := time.Now().Add(time.Minute).Truncate(time.Minute)
+	// Quarter-hour points for the first hour, hourly after that
+	forecast := forecastAt(start, map[time.Duration]float64{
+		0:                400,
+		15 * time.Minute: 100,
+		30 * time.Minute: 100,
+		45 * time.Minute: 400,
+		time.Hour:        300,
+		2 * time.Hour:    300,
+		3 * time.Hour:    300,
+	})
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+
+	optimal, _, evaluated := s.findOptimalWindow(forecast, time.Hour, start, time.Time{}, start.Add(4*time.Hour))
+
+	// Each quarter-hour point holds 15 minutes of an hour-long job; an hourly point holds the rest
+	want := []float64{250, 225, 275, 325, 300, 300, 300}
+	if len(evaluated) != len(want) {
+		t.Fatalf("expected %d windows, got %+v", len(want), evaluated)
+	}
+	for i, intensity := range want {
+		if math.Abs(evaluated[i].AvgIntensity-intensity) > 1e-9 {
+			t.Errorf("window %d: expected %.1f, got %.1f", i, intensity, evaluated[i].AvgIntensity)
+		}
+	}
+	if !optimal.StartTime.Equal(start.Add(15*time.Minute)) || !optimal.EndTime.Equal(start.Add(75*time.Minute)) {
+		t.Errorf("expected the optimal hour to start at 0:15, got %v - %v", optimal.StartTime, optimal.EndTime)
+	}
+}
+
+func TestFindOptimalWindow_GapInForecast(t *testing.T) {
+	start := time.Now().Add(time.Minute).Truncate(time.Minute)
+	// Hours 2 and 3 are missing
+	forecast := forecastAt(start, map[time.Duration]float64{
+		0:             200,
+		time.Hour:     200,
+		4 * time.Hour: 100,
+		5 * time.Hour: 100,
+		6 * time.Hour: 500,
+	})
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+
+	optimal, _, evaluated := s.findOptimalWindow(forecast, 2*time.Hour, start, time.Time{}, start.Add(7*time.Hour))
+
+	// Windows starting at hour 1 would span the gap, and hour 6 runs past the forecast
+	wantStarts := []int{0, 4, 5}
+	wantAverages := []float64{200, 100, 300}
+	if len(evaluated) != len(wantStarts) {
+		t.Fatalf("expected %d windows, got %+v", len(wantStarts), evaluated)
+	}
+	for i := range wantStarts {
+		if !evaluated[i].StartTime.Equal(start.Add(time.Duration(wantStarts[i])*time.Hour)) || evaluated[i].AvgIntensity != wantAverages[i] {
+			t.Errorf("window %d: expected %.0f at hour %d, got %+v", i, wantAverages[i], wantStarts[i], evaluated[i])
+		}
+	}
+	if optimal.AvgIntensity != 100 || !optimal.StartTime.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("expected the optimum after the gap, got %+v", optimal)
+	}
+}
+
+func TestFindOptimalWindow_TopAlternatives(t *testing.T) {
+	start := time.Now().Add(time.Minute).Truncate(time.Minute)
+	// Near-optimal hours come both before and after the minimum (100 at hour 4)
+	forecast := hourlyForecast(start, 105, 300, 108, 250, 100, 103, 400, 109, 112)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+
+	optimal, alternatives, evaluated := s.findOptimalWindow(forecast, time.Hour, start, time.Time{}, start.Add(9*time.Hour))
+
+	if len(evaluated) != 9 {
+		t.Fatalf("expected 9 evaluated windows, got %d", len(evaluated))
+	}
+	if optimal.AvgIntensity != 100 || !optimal.StartTime.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("optimal = %.0f at %v, want 100 at hour 4", optimal.AvgIntensity, optimal.StartTime)
+	}
+
+	want := []float64{103, 105, 108}
+	if len(alternatives) != len(want) {
+		t.Fatalf("expected %d alternatives, got %+v", len(want), alternatives)
+	}
+	for i, intensity := range want {
+		if alternatives[i].AvgIntensity != intensity {
+			t.Errorf("alternative %d: expected %.0f, got %.0f", i, intensity, alternatives[i].AvgIntensity)
+		}
+	}
+}
+
+func TestFindOptimalWindow_AlternativesDoNotOverlap(t *testing.T) {
+	start := time.Now().Add(time.Minute).Truncate(time.Minute)
+	// Two-hour averages by start hour: 310, 110, 105, 255, 265, 127.5, 362.5, 352.5, 106.5
+	forecast := hourlyForecast(start, 500, 120, 100, 110, 400, 130, 125, 600, 105, 108)
+
+	tests := []struct {
+		name            string
+		duration        time.Duration
+		band            float64
+		maxAlternatives int
+		wantOptimal     int   // Start hour of the optimal window
+		wantStarts      []int // Start hours of the alternatives, greenest first
+	}{
+		{"neighbour of the optimum is skipped", 2 * time.Hour, 10, 3, 2, []int{8}},
+		{"wider band reaches past the neighbour", 2 * time.Hour, 50, 3, 2, []int{8, 5}},
+		{"max alternatives caps the list", 2 * time.Hour, 50, 1, 2, []int{8}},
+		{"adjacent one-hour windows don't overlap", time.Hour, 50, 3, 2, []int{8, 9, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+			s.SetAlternativeBand(tt.band)
+			s.SetMaxAlternatives(tt.maxAlternatives)
+
+			optimal, alternatives, _ := s.findOptimalWindow(forecast, tt.duration, start, time.Time{}, start.Add(10*time.Hour))
+			if !optimal.StartTime.Equal(start.Add(time.Duration(tt.wantOptimal) * time.Hour)) {
+				t.Errorf("optimal starts at %v, want hour %d", optimal.StartTime, tt.wantOptimal)
+			}
+
+			var got []int
+			for _, alt := range alternatives {
+				got = append(got, int(alt.StartTime.Sub(start)/time.Hour))
+			}
+			if len(got) != len(tt.wantStarts) {
+				t.Fatalf("alternatives start at hours %v, want %v", got, tt.wantStarts)
+			}
+			for i := range got {
+				if got[i] != tt.wantStarts[i] {
+					t.Errorf("alternatives start at hours %v, want %v", got, tt.wantStarts)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFindOptimalWindow_ConfigurableAlternatives(t *testing.T) {
+	start := time.Now().Add(time.Minute).Truncate(time.Minute)
+	forecast := hourlyForecast(start, 105, 300, 108, 250, 100, 103, 400, 109, 112)
+	s := NewCarbonScheduler(&fakeFetcher{forecast: forecast})
+	deadline := start.Add(9 * time.Hour)
+
+	s.SetMaxAlternatives(10)
+	_, alternatives, _ := s.findOptimalWindow(forecast, time.Hour, start, time.Time{}, deadline)
+	if len(alternatives) != 4 {
+		t.Errorf("expected the 4 windows within 10 of the optimum, got %+v", alternatives)
+	}
+
+	s.SetAlternativeBand(5)
+	_, alternatives, _ = s.findOptimalWindow(forecast, time.Hour, start, time.Time{}, deadline)
+	if len(alternatives) != 2 || alternatives[0].AvgIntensity != 103 || alternatives[1].AvgIntensity != 105 {
+		t.Errorf("expected 103 and 105 within a band of 5, got %+v", alternatives)
+	}
+
+	s.SetMaxAlternatives(0)
+	_, alternatives, _ = s.findOptimalWindow(forecast, time.Hour, start, time.Time{}, deadline)
+	if len(alternatives) != 0 {
+		t.Errorf("expected no alternatives, got %+v", alternatives)
+	}
+}
@@ -0,0 +1,106 @@
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Objective is a start-time service level objective: Target of the jobs started within
+// Window must begin no later than StartThreshold after their scheduled time, e.g.
+// "99% of jobs start within 1 minute of their scheduled time, over 24 hours".
+type Objective struct {
+	StartThreshold time.Duration
+	Target         float64 // Fraction of jobs that must meet the threshold, in (0, 1]
+	Window         time.Duration
+}
+
+// DefaultObjective is 99% of jobs starting within a minute, measured over 24 hours
+var DefaultObjective = Objective{StartThreshold: time.Minute, Target: 0.99, Window: 24 * time.Hour}
+
+// ParseObjective reads an objective from its configuration strings: two durations and
+// a target given either as a fraction (0.99) or a percentage (99)
+func ParseObjective(startThreshold, target, window string) (Objective, error) {
+	var o Objective
+	var err error
+
+	if o.StartThreshold, err = time.ParseDuration(startThreshold); err != nil || o.StartThreshold < 0 {
+		return Objective{}, fmt.Errorf("invalid SLO start threshold %q", startThreshold)
+	}
+	if o.Window, err = time.ParseDuration(window); err != nil || o.Window <= 0 {
+		return Objective{}, fmt.Errorf("invalid SLO window %q", window)
+	}
+
+	o.Target, err = strconv.ParseFloat(target, 64)
+	if err == nil && o.Target > 1 {
+		o.Target /= 100
+	}
+	if err != nil || o.Target <= 0 || o.Target > 1 {
+		return Objective{}, fmt.Errorf("invalid SLO target %q (want a fraction like 0.99 or a percentage like 99)", target)
+	}
+
+	return o, nil
+}
+
+// StartDelay is how late a job started relative to when it was due: its scheduled
+// time, or its creation for jobs without one. Early starts count as no delay.
+func StartDelay(scheduled *time.Time, created, started time.Time) time.Duration {
+	due := created
+	if scheduled != nil {
+		due = *scheduled
+	}
+	if delay := started.Sub(due); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Met reports whether a job that started delay after it was due meets the objective
+func (o Objective) Met(delay time.Duration) bool {
+	return delay <= o.StartThreshold
+}
+
+// Report is the objective's compliance over its window
+type Report struct {
+	StartThresholdSeconds float64 `json:"start_threshold_seconds"`
+	Target                float64 `json:"target"`
+	WindowSeconds         float64 `json:"window_seconds"`
+
+	Jobs       int     `json:"jobs"`        // Jobs started in the window
+	OnTime     int     `json:"on_time"`     // Of those, jobs that met the threshold
+	Late       int     `json:"late"`        // Of those, jobs that missed it
+	Compliance float64 `json:"compliance"`  // OnTime / Jobs; 1 when no job started
+	Met        bool    `json:"met"`         // Compliance is at or above the target
+	BudgetLeft float64 `json:"budget_left"` // Share of the error budget (allowed late jobs) not yet spent; negative once overspent
+}
+
+// Report computes compliance from the number of on-time jobs out of all jobs started
+// in the window. An empty window is fully compliant: no job has been late.
+func (o Objective) Report(onTime, jobs int) Report {
+	r := Report{
+		StartThresholdSeconds: o.StartThreshold.Seconds(),
+		Target:                o.Target,
+		WindowSeconds:         o.Window.Seconds(),
+		Jobs:                  jobs,
+		OnTime:                onTime,
+		Late:                  jobs - onTime,
+		Compliance:            1,
+		BudgetLeft:            1,
+	}
+
+	if jobs > 0 {
+		r.Compliance = float64(onTime) / float64(jobs)
+	}
+	r.Met = r.Compliance >= o.Target
+
+	// The budget is the fraction of jobs allowed to be late; a 100% target has none to spend
+	allowed := 1 - o.Target
+	switch {
+	case allowed > 0:
+		r.BudgetLeft = 1 - (1-r.Compliance)/allowed
+	case r.Late > 0:
+		r.BudgetLeft = -1
+	}
+
+	return r
+}
@@ -0,0 +1,98 @@
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseObjective(t *testing.T) {
+	tests := []struct {
+		threshold, target, window string
+		want                      Objective
+		wantErr                   bool
+	}{
+		{"1m", "0.99", "24h", Objective{time.Minute, 0.99, 24 * time.Hour}, false},
+		{"30s", "99.5", "1h", Objective{30 * time.Second, 0.995, time.Hour}, false},
+		{"0s", "1", "7h", Objective{0, 1, 7 * time.Hour}, false},
+		{"soon", "0.99", "24h", Objective{}, true},
+		{"1m", "0", "24h", Objective{}, true},
+		{"1m", "101", "24h", Objective{}, true},
+		{"1m", "most", "24h", Objective{}, true},
+		{"1m", "0.99", "0s", Objective{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseObjective(tt.threshold, tt.target, tt.window)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseObjective(%q, %q, %q) error = %v, wantErr %v", tt.threshold, tt.target, tt.window, err, tt.wantErr)
+			continue
+		}
+		if got.StartThreshold != tt.want.StartThreshold || got.Window != tt.want.Window || math.Abs(got.Target-tt.want.Target) > 1e-9 {
+			t.Errorf("ParseObjective(%q, %q, %q) = %+v, want %+v", tt.threshold, tt.target, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestStartDelay(t *testing.T) {
+	created := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	scheduled := created.Add(2 * time.Hour)
+
+	if got := StartDelay(&scheduled, created, scheduled.Add(90*time.Second)); got != 90*time.Second {
+		t.Errorf("delay after scheduled time = %v, want 90s", got)
+	}
+	if got := StartDelay(nil, created, created.Add(5*time.Second)); got != 5*time.Second {
+		t.Errorf("delay without a scheduled time = %v, want 5s from creation", got)
+	}
+	if got := StartDelay(&scheduled, created, scheduled.Add(-time.Minute)); got != 0 {
+		t.Errorf("early start delay = %v, want 0", got)
+	}
+}
+
+func TestObjective_Report(t *testing.T) {
+	o := Objective{StartThreshold: time.Minute, Target: 0.99, Window: 24 * time.Hour}
+
+	// Seed 200 jobs: 199 within the threshold (one exactly at it) and one late
+	delays := make([]time.Duration, 0, 200)
+	for i := 0; i < 198; i++ {
+		delays = append(delays, time.Duration(i%60)*time.Second)
+	}
+	delays = append(delays, time.Minute, 61*time.Second)
+
+	onTime := 0
+	for _, delay := range delays {
+		if o.Met(delay) {
+			onTime++
+		}
+	}
+	if onTime != 199 {
+		t.Fatalf("expected a delay of exactly the threshold to count as on time, got %d on time", onTime)
+	}
+
+	r := o.Report(onTime, len(delays))
+	if r.Jobs != 200 || r.OnTime != 199 || r.Late != 1 {
+		t.Errorf("unexpected counts %+v", r)
+	}
+	if math.Abs(r.Compliance-0.995) > 1e-9 || !r.Met {
+		t.Errorf("expected 99.5%% compliance meeting a 99%% target, got %v (met=%v)", r.Compliance, r.Met)
+	}
+	if math.Abs(r.BudgetLeft-0.5) > 1e-9 {
+		t.Errorf("expected half the error budget left, got %v", r.BudgetLeft)
+	}
+
+	// Three more late jobs overspend the budget
+	r = o.Report(onTime, len(delays)+3)
+	if r.Met || r.BudgetLeft >= 0 {
+		t.Errorf("expected a missed objective with an overspent budget, got %+v", r)
+	}
+
+	empty := o.Report(0, 0)
+	if empty.Compliance != 1 || !empty.Met || empty.BudgetLeft != 1 {
+		t.Errorf("expected an empty window to be fully compliant, got %+v", empty)
+	}
+
+	strict := Objective{StartThreshold: time.Minute, Target: 1, Window: time.Hour}
+	if r := strict.Report(9, 10); r.Met || r.BudgetLeft != -1 {
+		t.Errorf("expected a late job to exhaust a 100%% target's budget, got %+v", r)
+	}
+}
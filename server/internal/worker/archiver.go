@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+)
+
+// jobArchive soft-deletes finished jobs older than an age
+type jobArchive interface {
+	ArchiveOlderThan(ctx context.Context, age time.Duration) (int64, error)
+}
+
+// JobArchiver periodically hides old finished jobs from listings, so the job list
+// doesn't fill up with history nobody looks at. Archived jobs stay in the jobs table.
+type JobArchiver struct {
+	jobs     jobArchive
+	interval time.Duration
+	age      time.Duration
+}
+
+// NewJobArchiver creates an archiver that archives finished jobs created more than age
+// ago, checking every interval (default 1h)
+func NewJobArchiver(jobs jobArchive, interval, age time.Duration) *JobArchiver {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &JobArchiver{
+		jobs:     jobs,
+		interval: interval,
+		age:      age,
+	}
+}
+
+// ArchiveOnce archives the old finished jobs and returns how many there were
+func (a *JobArchiver) ArchiveOnce(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return a.jobs.ArchiveOlderThan(ctx, a.age)
+}
+
+// Run archives immediately and then on every interval until ctx is cancelled
+func (a *JobArchiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		archived, err := a.ArchiveOnce(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Job archival failed", logging.Err(err))
+			}
+		} else if archived > 0 {
+			slog.Info("Archived old jobs", "archived", archived, "older_than", a.age)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
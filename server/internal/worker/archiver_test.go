@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingArchive records the ages it was asked to archive
+type countingArchive struct {
+	mu   sync.Mutex
+	ages []time.Duration
+}
+
+func (a *countingArchive) ArchiveOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ages = append(a.ages, age)
+	return 1, nil
+}
+
+func (a *countingArchive) runs() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.ages)
+}
+
+func TestJobArchiver_RunArchivesOnEveryTick(t *testing.T) {
+	archive := &countingArchive{}
+	archiver := NewJobArchiver(archive, 10*time.Millisecond, 30*24*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		archiver.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for archive.runs() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if archive.runs() < 3 {
+		t.Fatalf("expected at least 3 archival runs, got %d", archive.runs())
+	}
+	if archive.ages[0] != 30*24*time.Hour {
+		t.Errorf("expected jobs older than 30 days to be archived, got %v", archive.ages[0])
+	}
+}
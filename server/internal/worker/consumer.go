@@ -2,14 +2,20 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
 	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
 
 	"github.com/google/uuid"
 )
@@ -23,10 +29,54 @@ type Consumer struct {
 	pool          *Pool // Reference to parent pool for job tracking
 	stopCh        chan struct{}
 	workerID      string
-	pollInterval  time.Duration
+	poll          pollBackoff
 	jobTimeout    time.Duration
+	maxRetries    int         // Failed attempts are retried this many times before dead-lettering
+	prefetcher    *Prefetcher // Optional: pulls upcoming images while a job runs
+	successTail   int         // Trailing output bytes stored for successful jobs (0 = all)
+	nodeID        string      // Worker node (process) this consumer belongs to; empty when unknown
+
+	imageProfiles ImageProfiles // Per-image defaults for timeout, limits and command
+
+	stepTimeout time.Duration // Per-step run time of multi-step jobs (0 = job timeout / step count)
+
+	dependencies *DependencyResolver // Optional: settles WAITING jobs when a job they depend on finishes
+
+	startSLO slo.Objective // Judges whether a job's first run started on time
+
+	limiter *ContainerLimiter // Optional: jobs run in the background, up to the limiter's cap
+	running sync.WaitGroup    // Background jobs started under the limiter
+
+	// statuses writes the status changes around a retry (jobRepo; replaced in tests)
+	statuses jobStatusUpdater
+
+	// execute runs a dequeued job once its lock is held (executeJob; replaced in tests)
+	execute func(ctx context.Context, jobID uuid.UUID, item *queue.QueueItem) error
+	// failEarly fails a job without running it (failWithoutRunning; replaced in tests)
+	failEarly func(ctx context.Context, jobID uuid.UUID, errorMsg string) error
+}
+
+// jobLockGrace is added to the job timeout for the per-job lock's TTL, covering the
+// image pull and status writes around the container run
+const jobLockGrace = 2 * time.Minute
+
+// lockedRetryDelay is how long a job whose lock is held by another worker waits in the
+// delayed queue before it is tried again
+const lockedRetryDelay = 5 * time.Second
+
+// deadlineExceededMsg is recorded on jobs that reach a worker, or the promoter, only
+// after their deadline has passed
+const deadlineExceededMsg = "deadline exceeded before execution"
+
+// pastDeadline reports whether a job with the given deadline is too late to start at
+// now. A zero deadline, as on queue items from before they carried one, never passes.
+func pastDeadline(deadline, now time.Time) bool {
+	return !deadline.IsZero() && now.After(deadline)
 }
 
+// errNoJobs is returned by processNextJob when the queue is empty
+var errNoJobs = errors.New("no jobs available")
+
 // NewConsumer creates a new worker consumer
 func NewConsumer(
 	queue *queue.RedisQueue,
@@ -35,7 +85,7 @@ func NewConsumer(
 	dockerService *docker.Service,
 	workerID string,
 ) *Consumer {
-	return &Consumer{
+	c := &Consumer{
 		queue:         queue,
 		jobRepo:       jobRepo,
 		executionRepo: executionRepo,
@@ -43,9 +93,17 @@ func NewConsumer(
 		pool:          nil, // Will be set by pool after creation
 		stopCh:        make(chan struct{}),
 		workerID:      workerID,
-		pollInterval:  2 * time.Second,  // Poll every 2 seconds
-		jobTimeout:    10 * time.Minute, // 10 minute timeout per job
+		poll:          pollBackoff{min: 2 * time.Second, max: 2 * time.Second}, // Poll every 2 seconds
+		jobTimeout:    10 * time.Minute,                                        // 10 minute timeout per job
+		maxRetries:    3,
+		startSLO:      slo.DefaultObjective,
+	}
+	if jobRepo != nil {
+		c.statuses = jobRepo
 	}
+	c.execute = c.executeJob
+	c.failEarly = c.failWithoutRunning
+	return c
 }
 
 // SetPool sets the parent pool reference (called by pool after consumer creation)
@@ -53,29 +111,57 @@ func (c *Consumer) SetPool(pool *Pool) {
 	c.pool = pool
 }
 
+// SetContainerLimiter makes the consumer run each job it claims in the background,
+// taking a slot from limiter first, so it can go on polling while the job runs
+func (c *Consumer) SetContainerLimiter(limiter *ContainerLimiter) {
+	c.limiter = limiter
+}
+
 // Start begins the consumer polling loop
 func (c *Consumer) Start(ctx context.Context) {
-	log.Printf("[Worker %s] Starting consumer...", c.workerID)
+	c.logger().Info("Starting consumer")
+	defer c.running.Wait()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("[Worker %s] Context cancelled, stopping consumer", c.workerID)
+			c.logger().Info("Context cancelled, stopping consumer")
 			return
 		case <-c.stopCh:
-			log.Printf("[Worker %s] Stop signal received, stopping consumer", c.workerID)
+			c.logger().Info("Stop signal received, stopping consumer")
 			return
 		default:
-			// Try to dequeue and process a job
-			if err := c.processNextJob(ctx); err != nil {
-				// Log error but continue polling
-				if err.Error() != "no jobs available" {
-					log.Printf("[Worker %s] Error processing job: %v", c.workerID, err)
-				}
+		}
+
+		// Try to dequeue and process a job
+		var err error
+		if c.limiter != nil {
+			err = c.dispatchNextJob(ctx)
+			if err == nil {
+				// Started in the background; look for more work right away
+				c.poll.next(true)
+				continue
 			}
+		} else {
+			err = c.processNextJob(ctx)
+		}
+		if err != nil && !errors.Is(err, errNoJobs) && ctx.Err() == nil {
+			// Log error but continue polling
+			c.logger().Error("Error processing job", logging.Err(err))
+		}
 
-			// Sleep before next poll
-			time.Sleep(c.pollInterval)
+		// Wait before the next poll, longer while the queue stays empty
+		timer := time.NewTimer(c.poll.next(err == nil))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.logger().Info("Context cancelled, stopping consumer")
+			return
+		case <-c.stopCh:
+			timer.Stop()
+			c.logger().Info("Stop signal received, stopping consumer")
+			return
+		case <-timer.C:
 		}
 	}
 }
@@ -87,37 +173,109 @@ func (c *Consumer) Stop() {
 
 // processNextJob attempts to dequeue and process one job
 func (c *Consumer) processNextJob(ctx context.Context) error {
+	queueItem, err := c.claimNextJob(ctx)
+	if err != nil {
+		return err
+	}
+	return c.processJob(ctx, queueItem)
+}
+
+// dispatchNextJob waits for a container slot, then claims a job and processes it in
+// the background. The slot is given back straight away when there is nothing to run.
+func (c *Consumer) dispatchNextJob(ctx context.Context) error {
+	if err := c.limiter.Acquire(ctx); err != nil {
+		return err
+	}
+
+	queueItem, err := c.claimNextJob(ctx)
+	if err != nil {
+		c.limiter.Release()
+		return err
+	}
+
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer c.limiter.Release()
+
+		if err := c.processJob(ctx, queueItem); err != nil {
+			c.logger().Error("Error processing job", logging.KeyJobID, queueItem.JobID, logging.Err(err))
+		}
+	}()
+	return nil
+}
+
+// claimNextJob takes the next due job off the queue, returning errNoJobs when it is empty
+func (c *Consumer) claimNextJob(ctx context.Context) (*queue.QueueItem, error) {
 	// Check if pool is draining - stop accepting new jobs
 	if c.pool != nil && c.pool.IsDraining() {
-		return fmt.Errorf("worker pool is draining, not accepting new jobs")
+		return nil, fmt.Errorf("worker pool is draining, not accepting new jobs")
 	}
 
-	// Dequeue from Redis
-	queueItem, err := c.queue.DequeueImmediate(ctx)
+	// Claim from Redis; the job stays in this node's processing set until it is finished
+	// with, so a crash before then leaves it to be requeued rather than lost
+	queueItem, err := c.queue.ClaimImmediate(ctx, c.processingOwner())
 	if err != nil {
-		return fmt.Errorf("failed to dequeue job: %w", err)
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
 	}
 
 	// Check if queue is empty
 	if queueItem == nil {
-		return fmt.Errorf("no jobs available")
+		return nil, errNoJobs
 	}
+	return queueItem, nil
+}
+
+// processJob runs a claimed job under its lock and acknowledges it afterwards
+func (c *Consumer) processJob(ctx context.Context, queueItem *queue.QueueItem) error {
+	// Tag everything logged while handling the job with the request that submitted it
+	ctx = logging.WithRequestID(ctx, queueItem.RequestID)
+	defer func() {
+		if err := c.queue.AckProcessing(context.WithoutCancel(ctx), c.processingOwner(), queueItem); err != nil {
+			c.logger().WarnContext(ctx, "Failed to acknowledge job", logging.KeyJobID, queueItem.JobID, logging.Err(err))
+		}
+	}()
 
 	jobID, err := uuid.Parse(queueItem.JobID)
 	if err != nil {
 		return fmt.Errorf("invalid job ID: %w", err)
 	}
 
-	log.Printf("[Worker %s] Processing job: %s", c.workerID, jobID)
+	// Hold the job's lock while it runs so that a duplicated queue entry (e.g. a promotion
+	// racing a manual requeue) is never executed by two workers at once
+	lockOwner := *c.workerNodeID()
+	acquired, err := c.queue.AcquireJobLock(ctx, queueItem.JobID, lockOwner, c.timeoutFor(queueItem)+jobLockGrace)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		// Try again shortly rather than acking the entry away: if it is the only one left
+		// for the job, the job would otherwise never run
+		c.logger().InfoContext(ctx, "Job locked by another worker, retrying later", logging.KeyJobID, jobID, "retry_in", lockedRetryDelay)
+		return c.deferLocked(ctx, queueItem)
+	}
+	defer func() {
+		if err := c.queue.ReleaseJobLock(context.WithoutCancel(ctx), queueItem.JobID, lockOwner); err != nil {
+			c.logger().WarnContext(ctx, "Failed to release job lock", logging.KeyJobID, jobID, logging.Err(err))
+		}
+	}()
+
+	// Workers saturated for long enough can pick a job up after its deadline; don't run it late
+	if pastDeadline(queueItem.Deadline, time.Now()) {
+		c.logger().WarnContext(ctx, "Job failed before running, deadline passed while queued", logging.KeyJobID, jobID, "deadline", queueItem.Deadline)
+		return c.failEarly(ctx, jobID, deadlineExceededMsg)
+	}
+
+	c.logger().InfoContext(ctx, "Processing job", logging.KeyJobID, jobID)
 
 	// Process the job
-	return c.executeJob(ctx, jobID)
+	return c.execute(ctx, jobID, queueItem)
 }
 
 // executeJob runs the complete job lifecycle
-func (c *Consumer) executeJob(ctx context.Context, jobID uuid.UUID) error {
+func (c *Consumer) executeJob(ctx context.Context, jobID uuid.UUID, item *queue.QueueItem) error {
 	// Create job-specific context with timeout
-	jobCtx, cancel := context.WithTimeout(ctx, c.jobTimeout)
+	jobCtx, cancel := context.WithTimeout(ctx, c.timeoutFor(item))
 	defer cancel()
 
 	// Fetch job details from database
@@ -126,13 +284,76 @@ func (c *Consumer) executeJob(ctx context.Context, jobID uuid.UUID) error {
 		return fmt.Errorf("failed to fetch job: %w", err)
 	}
 
-	// Update status to RUNNING
+	// Queue entries from before items carried a deadline are checked against the stored job
+	if pastDeadline(job.Deadline, time.Now()) {
+		c.logger().WarnContext(ctx, "Job failed before running, deadline passed while queued", logging.KeyJobID, jobID, "deadline", job.Deadline)
+		return c.failWithoutRunning(jobCtx, jobID, deadlineExceededMsg)
+	}
+
+	// Decode the stored command before starting; malformed commands can never succeed
+	command, err := job.CommandArgs()
+	if err != nil {
+		c.logger().ErrorContext(ctx, "Job failed before running", logging.KeyJobID, jobID, logging.Err(err))
+		return c.failWithoutRunning(jobCtx, jobID, err.Error())
+	}
+	profile := c.profileFor(item)
+	entrypoint := jobEntrypoint(item)
+	// A profile's command is written for the image's own entrypoint, not an override
+	if len(command) == 0 && len(entrypoint) == 0 && profile != nil {
+		command = profile.Command
+	}
+
+	// A job recovered from a crashed worker may still be marked RUNNING by it
+	if item != nil && item.Reclaimed && job.Status == models.JobStatusRunning {
+		if err := c.jobRepo.UpdateJobStatusChecked(jobCtx, jobID, models.JobStatusPending); err != nil {
+			return fmt.Errorf("failed to reset reclaimed job to PENDING: %w", err)
+		}
+		c.logger().InfoContext(ctx, "Job recovered from a crashed worker, running again", logging.KeyJobID, jobID)
+	}
+
+	// Listen for cancel requests before the job shows as RUNNING, so a cancel sent as soon
+	// as the API sees it running always reaches this worker
+	cancelRequests, unsubscribe, err := c.queue.SubscribeJobCancel(ctx, jobID.String())
+	if err != nil {
+		c.logger().WarnContext(ctx, "Job can't be cancelled while running", logging.KeyJobID, jobID, logging.Err(err))
+	} else {
+		defer unsubscribe()
+	}
+
+	// Update status to RUNNING. A job that already ran (duplicate or stale queue entry)
+	// can't make this transition, so it is never executed twice.
 	job.Status = models.JobStatusRunning
-	if err := c.jobRepo.UpdateJobStatus(jobCtx, jobID, models.JobStatusRunning); err != nil {
+	if err := c.jobRepo.UpdateJobStatusChecked(jobCtx, jobID, models.JobStatusRunning); err != nil {
+		if errors.Is(err, models.ErrIllegalTransition) {
+			c.logger().InfoContext(ctx, "Job skipped, already processed", logging.KeyJobID, jobID, logging.Err(err))
+		}
 		return fmt.Errorf("failed to update job status to RUNNING: %w", err)
 	}
 
-	log.Printf("[Worker %s] Job %s: Status updated to RUNNING", c.workerID, jobID)
+	// Only the run that owns the job clears its cancel request; a duplicate skipped above
+	// must leave a cancel meant for the job's real run in place
+	defer func() {
+		if err := c.queue.ClearJobCancel(context.WithoutCancel(ctx), jobID.String()); err != nil {
+			c.logger().WarnContext(ctx, "Failed to clear cancel request", logging.KeyJobID, jobID, logging.Err(err))
+		}
+	}()
+
+	c.logger().InfoContext(ctx, "Job status updated to RUNNING", logging.KeyJobID, jobID)
+
+	// A cancel requested before this run, e.g. while a crashed worker held the job,
+	// stops it before a container starts
+	if c.cancelMarked(ctx, jobID.String()) {
+		c.logger().InfoContext(ctx, "Job cancelled before its container started", logging.KeyJobID, jobID)
+		return c.finishWithoutRunning(ctx, jobID, models.JobStatusCancelled, "Job cancelled before its container started")
+	}
+
+	// Only the first run counts towards the start-time SLO; retries start late by design
+	if job.SLOMet == nil {
+		delay := slo.StartDelay(job.ScheduledTime, job.CreatedAt, time.Now())
+		if err := c.jobRepo.RecordStartSLO(jobCtx, jobID, delay, c.startSLO.Met(delay)); err != nil {
+			c.logger().WarnContext(ctx, "Failed to record start SLO", logging.KeyJobID, jobID, logging.Err(err))
+		}
+	}
 
 	// Track job start if pool is available
 	jobIDStr := jobID.String()
@@ -141,80 +362,385 @@ func (c *Consumer) executeJob(ctx context.Context, jobID uuid.UUID) error {
 		defer c.pool.TrackJobComplete(jobIDStr)
 	}
 
-	// Execute Docker container
+	// Pull the next jobs' images in the background while this one runs
+	if c.prefetcher != nil {
+		go c.prefetcher.Prefetch(ctx, job.DockerImage)
+	}
+
+	// Execute Docker container, relaying output lines to live log subscribers
 	startTime := time.Now()
-	result, err := c.dockerService.RunContainer(jobCtx, job.DockerImage, nil)
+	logLines := make(chan string, 64)
+	publishDone := make(chan struct{})
+	go c.publishLogLines(ctx, jobIDStr, logLines, publishDone)
+
+	runCtx, stopRun := context.WithCancel(jobCtx)
+	defer stopRun()
+	var cancelRequested atomic.Bool
+	go watchCancel(runCtx, cancelRequests, func(ctx context.Context) bool { return c.cancelMarked(ctx, jobIDStr) }, cancelCheckInterval, &cancelRequested, stopRun)
+
+	var result *docker.ContainerResult
+	var steps []StepResult
+	if item != nil && len(item.Steps) > 0 {
+		result, steps, err = c.runJobSteps(runCtx, job.DockerImage, item, profile, logLines)
+	} else {
+		result, err = c.dockerService.RunContainerStreaming(runCtx, job.DockerImage, command, entrypoint, ResourceLimits(item, profile), jobVolumes(item), item != nil && item.NetworkAccess, logLines)
+	}
+	<-publishDone
 
 	// Prepare execution log
 	executionLog := &models.ExecutionLog{
 		ID:        uuid.New(),
 		JobID:     jobID,
 		StartedAt: startTime,
-		ExitCode:  result.ExitCode,
-		Duration:  result.Duration,
+		ExitCode:  &result.ExitCode,
+		Duration:  &result.Duration,
+
+		WorkerNodeID:    c.workerNodeID(),
+		OutputTruncated: result.Truncated,
+		Steps:           stepRecords(steps),
+	}
+	if result.Usage != nil {
+		executionLog.PeakMemoryBytes = &result.Usage.PeakMemoryBytes
+		executionLog.CPUSeconds = &result.Usage.CPUSeconds
 	}
 
 	// Handle execution result
-	var finalStatus models.JobStatus
-	if err != nil || result.Error != nil {
-		// Job failed
-		finalStatus = models.JobStatusFailed
-		errorMsg := ""
-		if err != nil {
-			errorMsg = err.Error()
-		} else if result.Error != nil {
-			errorMsg = result.Error.Error()
-		}
-		executionLog.ErrorMessage = &errorMsg
-		executionLog.Output = result.Output
-
-		log.Printf("[Worker %s] Job %s: FAILED - %s", c.workerID, jobID, errorMsg)
-	} else if result.ExitCode != 0 {
-		// Container ran but exited with non-zero code
-		finalStatus = models.JobStatusFailed
-		errorMsg := fmt.Sprintf("Container exited with code %d", result.ExitCode)
+	finalStatus, errorMsg := evaluateResult(result, err)
+	// A cancel that arrives as the container finishes on its own doesn't change the outcome
+	if cancelRequested.Load() && errors.Is(err, docker.ErrCancelled) {
+		finalStatus, errorMsg = models.JobStatusCancelled, "Job cancelled: container was stopped"
+	}
+	executionLog.Output = storedOutput(result.Output, finalStatus, successTailBytes(item, c.successTail))
+	if finalStatus != models.JobStatusCompleted {
 		executionLog.ErrorMessage = &errorMsg
-		executionLog.Output = result.Output
-
-		log.Printf("[Worker %s] Job %s: FAILED - Exit code %d", c.workerID, jobID, result.ExitCode)
+		c.logger().WarnContext(ctx, "Job did not complete", logging.KeyJobID, jobID, "status", finalStatus,
+			logging.KeyDuration, time.Since(startTime), "exit_code", result.ExitCode, logging.KeyError, errorMsg)
 	} else {
-		// Success
-		finalStatus = models.JobStatusCompleted
-		executionLog.Output = result.Output
-
-		log.Printf("[Worker %s] Job %s: COMPLETED successfully", c.workerID, jobID)
+		c.logger().InfoContext(ctx, "Job completed successfully", logging.KeyJobID, jobID, logging.KeyDuration, time.Since(startTime))
 	}
 
 	// Set completion time
 	now := time.Now()
 	executionLog.CompletedAt = &now
 
-	// Save execution log to database
-	if err := c.executionRepo.CreateExecutionLog(jobCtx, executionLog); err != nil {
-		log.Printf("[Worker %s] Warning: Failed to save execution log for job %s: %v", c.workerID, jobID, err)
+	// Save execution log to database (on ctx: after a timeout jobCtx has already expired)
+	if err := c.executionRepo.CreateExecutionLog(ctx, executionLog); err != nil {
+		c.logger().WarnContext(ctx, "Failed to save execution log", logging.KeyJobID, jobID, logging.Err(err))
 	}
 
+	// Retry failed jobs until their attempts are exhausted, then dead-letter them
+	if finalStatus == models.JobStatusFailed && item != nil {
+		item.Attempts++
+		if shouldRetry(item.Attempts, c.maxRetries) {
+			if err := c.requeueForRetry(ctx, jobID, item); err != nil {
+				c.logger().WarnContext(ctx, "Failed to requeue job", logging.KeyJobID, jobID, logging.Err(err))
+			} else {
+				c.logger().InfoContext(ctx, "Job requeued for retry", logging.KeyJobID, jobID, "attempt", item.Attempts, "max_attempts", c.maxRetries+1)
+				c.recordProcessed(models.JobStatusPending)
+				// Another worker may already be running the next attempt, so the job's
+				// status is no longer this run's to set. It will stream again then.
+				return nil
+			}
+		} else if err := c.queue.EnqueueDead(ctx, item, errorMsg); err != nil {
+			c.logger().WarnContext(ctx, "Failed to dead-letter job", logging.KeyJobID, jobID, logging.Err(err))
+		}
+	}
+
+	c.recordProcessed(finalStatus)
+
 	// Update final job status
-	if err := c.jobRepo.UpdateJobStatus(jobCtx, jobID, finalStatus); err != nil {
+	if err := c.jobRepo.UpdateJobStatusChecked(ctx, jobID, finalStatus); err != nil {
 		return fmt.Errorf("failed to update final job status: %w", err)
 	}
 
-	log.Printf("[Worker %s] Job %s: Final status set to %s", c.workerID, jobID, finalStatus)
+	c.logger().InfoContext(ctx, "Job final status set", logging.KeyJobID, jobID, "status", finalStatus)
+
+	// Let live log subscribers know the job has finished
+	if err := c.queue.PublishJobLogEnd(ctx, jobIDStr, string(finalStatus)); err != nil {
+		c.logger().WarnContext(ctx, "Failed to publish log end", logging.KeyJobID, jobID, logging.Err(err))
+	}
+
+	c.settleDependents(ctx, jobID)
 
 	return nil
 }
 
+// requeueForRetry puts a failed job back on the immediate queue for another attempt. The
+// job is marked PENDING and its lock released before it is queued, so a worker claiming
+// it straight away can run it. If queueing fails the job is left PENDING for the caller
+// to fail.
+func (c *Consumer) requeueForRetry(ctx context.Context, jobID uuid.UUID, item *queue.QueueItem) error {
+	if err := c.statuses.UpdateJobStatusChecked(ctx, jobID, models.JobStatusPending); err != nil {
+		return fmt.Errorf("failed to mark job PENDING: %w", err)
+	}
+	if err := c.queue.ReleaseJobLock(ctx, item.JobID, *c.workerNodeID()); err != nil {
+		c.logger().WarnContext(ctx, "Failed to release job lock", logging.KeyJobID, jobID, logging.Err(err))
+	}
+	return c.queue.EnqueueImmediate(ctx, item)
+}
+
+// deferLocked moves a job whose lock another worker holds to the delayed queue, to be
+// promoted and tried again after lockedRetryDelay. A duplicate of a job that has since
+// finished is then skipped by its status check.
+func (c *Consumer) deferLocked(ctx context.Context, item *queue.QueueItem) error {
+	deferred := *item
+	deferred.ScheduledTime = time.Now().Add(lockedRetryDelay)
+	if err := c.queue.EnqueueDelayed(ctx, &deferred); err != nil {
+		return fmt.Errorf("failed to defer locked job: %w", err)
+	}
+	return nil
+}
+
+// failWithoutRunning records a job that was rejected before its container started
+func (c *Consumer) failWithoutRunning(ctx context.Context, jobID uuid.UUID, errorMsg string) error {
+	return c.finishWithoutRunning(ctx, jobID, models.JobStatusFailed, errorMsg)
+}
+
+// finishWithoutRunning ends a job in status, FAILED or CANCELLED, before its container
+// started, recording errorMsg as the reason
+func (c *Consumer) finishWithoutRunning(ctx context.Context, jobID uuid.UUID, status models.JobStatus, errorMsg string) error {
+	now := time.Now()
+	executionLog := &models.ExecutionLog{
+		ID:           uuid.New(),
+		JobID:        jobID,
+		StartedAt:    now,
+		CompletedAt:  &now,
+		ErrorMessage: &errorMsg,
+		WorkerNodeID: c.workerNodeID(),
+	}
+	if err := c.executionRepo.CreateExecutionLog(ctx, executionLog); err != nil {
+		c.logger().WarnContext(ctx, "Failed to save execution log", logging.KeyJobID, jobID, logging.Err(err))
+	}
+	c.recordProcessed(status)
+
+	if err := c.jobRepo.UpdateJobStatusChecked(ctx, jobID, status); err != nil {
+		return fmt.Errorf("failed to update final job status: %w", err)
+	}
+
+	if err := c.queue.PublishJobLogEnd(ctx, jobID.String(), string(status)); err != nil {
+		c.logger().WarnContext(ctx, "Failed to publish log end", logging.KeyJobID, jobID, logging.Err(err))
+	}
+
+	c.settleDependents(ctx, jobID)
+
+	return nil
+}
+
+// settleDependents queues or fails the jobs waiting on a job that has just finished
+func (c *Consumer) settleDependents(ctx context.Context, jobID uuid.UUID) {
+	if c.dependencies == nil {
+		return
+	}
+	if err := c.dependencies.JobFinished(ctx, jobID); err != nil {
+		c.logger().ErrorContext(ctx, "Failed to settle dependent jobs", logging.KeyJobID, jobID, logging.Err(err))
+	}
+}
+
+// cancelCheckInterval is how often a running job's cancel marker is checked, catching
+// a cancel whose published request this worker missed
+const cancelCheckInterval = 2 * time.Second
+
+// cancelMarked reports whether the job has an outstanding cancel request. Redis errors
+// count as no request; the next check or the published request still gets through.
+func (c *Consumer) cancelMarked(ctx context.Context, jobID string) bool {
+	marked, err := c.queue.JobCancelRequested(ctx, jobID)
+	if err != nil {
+		c.logger().WarnContext(ctx, "Failed to check for a cancel request", logging.KeyJobID, jobID, logging.Err(err))
+		return false
+	}
+	return marked
+}
+
+// watchCancel stops a job's run when a cancel request arrives, until ctx ends. Requests
+// arrive on the subscription (a nil channel never fires) or are found by marked, which
+// is called every checkEvery.
+func watchCancel(ctx context.Context, requests <-chan struct{}, marked func(context.Context) bool, checkEvery time.Duration, requested *atomic.Bool, stopRun context.CancelFunc) {
+	ticker := time.NewTicker(checkEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-requests:
+		case <-ticker.C:
+			if !marked(ctx) {
+				continue
+			}
+		case <-ctx.Done():
+			return
+		}
+		requested.Store(true)
+		stopRun()
+		return
+	}
+}
+
+// evaluateResult determines the final job status and error message from a container run
+func evaluateResult(result *docker.ContainerResult, err error) (models.JobStatus, string) {
+	switch {
+	case errors.Is(err, docker.ErrTimeoutExceeded):
+		// The container was stopped when the job timeout fired
+		return models.JobStatusFailed, "Job timeout exceeded: container was stopped"
+	case err != nil:
+		return models.JobStatusFailed, err.Error()
+	case result.Error != nil:
+		return models.JobStatusFailed, result.Error.Error()
+	case result.OOMKilled:
+		// Container exceeded its memory limit and was killed by the kernel
+		return models.JobStatusFailed, fmt.Sprintf("Container was OOM-killed (exit code %d): memory limit exceeded", result.ExitCode)
+	case result.ExitCode != 0:
+		// Container ran but exited with non-zero code
+		return models.JobStatusFailed, fmt.Sprintf("Container exited with code %d", result.ExitCode)
+	default:
+		return models.JobStatusCompleted, ""
+	}
+}
+
+// successTailBytes returns the stored success output size for a job, preferring its own setting
+func successTailBytes(item *queue.QueueItem, workerDefault int) int {
+	if item != nil && item.SuccessOutputTailBytes != nil {
+		return *item.SuccessOutputTailBytes
+	}
+	return workerDefault
+}
+
+// storedOutput trims successful jobs' output to its last tailBytes bytes. Failed jobs keep
+// their full output for debugging, as does any job when tailBytes is 0.
+func storedOutput(output string, status models.JobStatus, tailBytes int) string {
+	if status != models.JobStatusCompleted || tailBytes <= 0 || len(output) <= tailBytes {
+		return output
+	}
+
+	// Start the tail on a rune boundary so multi-byte characters aren't split
+	start := len(output) - tailBytes
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+
+	return fmt.Sprintf("[... %d bytes of output truncated ...]\n", start) + output[start:]
+}
+
+// shouldRetry reports whether a job that has failed the given number of attempts gets another try
+func shouldRetry(attempts, maxRetries int) bool {
+	return attempts <= maxRetries
+}
+
+// jobVolumes returns the mounts requested on a queue item
+func jobVolumes(item *queue.QueueItem) []models.VolumeMount {
+	if item == nil {
+		return nil
+	}
+	return item.Volumes
+}
+
+// jobEntrypoint returns the entrypoint override a job was queued with, if any
+func jobEntrypoint(item *queue.QueueItem) []string {
+	if item == nil {
+		return nil
+	}
+	return item.Entrypoint
+}
+
+// publishLogLines forwards container output lines to the job's live log channel
+func (c *Consumer) publishLogLines(ctx context.Context, jobID string, lines <-chan string, done chan<- struct{}) {
+	defer close(done)
+
+	warned := false
+	for line := range lines {
+		if err := c.queue.PublishJobLogLine(ctx, jobID, line); err != nil && !warned {
+			c.logger().WarnContext(ctx, "Failed to publish log line", logging.KeyJobID, jobID, logging.Err(err))
+			warned = true
+		}
+	}
+}
+
 // GetWorkerID returns the unique identifier for this worker
 func (c *Consumer) GetWorkerID() string {
 	return c.workerID
 }
 
-// SetPollInterval updates the polling interval
+// SetPollInterval updates the polling interval, the shortest wait between polls
 func (c *Consumer) SetPollInterval(interval time.Duration) {
-	c.pollInterval = interval
+	c.poll.min = interval
+}
+
+// SetPollMaxInterval lets the wait between polls grow up to maxInterval while the
+// queue stays empty (see PollBackoff). At or below the poll interval, polling is fixed.
+func (c *Consumer) SetPollMaxInterval(maxInterval time.Duration) {
+	c.poll.max = maxInterval
+}
+
+// SetPrefetcher enables image prefetching for upcoming jobs
+func (c *Consumer) SetPrefetcher(prefetcher *Prefetcher) {
+	c.prefetcher = prefetcher
+}
+
+// SetMaxRetries updates how many times a failed job is retried before it is dead-lettered
+func (c *Consumer) SetMaxRetries(maxRetries int) {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	c.maxRetries = maxRetries
+}
+
+// SetSuccessOutputTail limits stored output for successful jobs to the last tailBytes bytes (0 keeps all)
+func (c *Consumer) SetSuccessOutputTail(tailBytes int) {
+	if tailBytes < 0 {
+		tailBytes = 0
+	}
+	c.successTail = tailBytes
+}
+
+// SetStartSLO sets the objective a job's first start is judged against
+func (c *Consumer) SetStartSLO(objective slo.Objective) {
+	c.startSLO = objective
+}
+
+// SetNodeID records the worker node this consumer runs on, for tracing execution logs
+func (c *Consumer) SetNodeID(nodeID string) {
+	c.nodeID = nodeID
+}
+
+// workerNodeID identifies this consumer in execution logs as "<node-id>/<worker-id>",
+// matching the node ID the worker heartbeats under
+func (c *Consumer) workerNodeID() *string {
+	id := c.workerID
+	if c.nodeID != "" {
+		id = c.nodeID + "/" + c.workerID
+	}
+	return &id
+}
+
+// logger returns the default logger tagged with this consumer's worker ID
+func (c *Consumer) logger() *slog.Logger {
+	return slog.With(logging.KeyWorkerID, *c.workerNodeID())
+}
+
+// processingOwner identifies the processing set this consumer claims jobs into: its
+// worker node, shared by the node's consumers, or the consumer itself without one
+func (c *Consumer) processingOwner() string {
+	if c.nodeID != "" {
+		return c.nodeID
+	}
+	return c.workerID
+}
+
+// SetImageProfiles sets per-image defaults, used where a job doesn't set its own
+func (c *Consumer) SetImageProfiles(profiles ImageProfiles) {
+	c.imageProfiles = profiles
+}
+
+// SetDependencyResolver enables releasing the jobs that wait on this consumer's jobs
+func (c *Consumer) SetDependencyResolver(resolver *DependencyResolver) {
+	c.dependencies = resolver
 }
 
 // SetJobTimeout updates the job execution timeout
 func (c *Consumer) SetJobTimeout(timeout time.Duration) {
 	c.jobTimeout = timeout
 }
+
+// SetStepTimeout sets the run time allowed per step of a multi-step job. Zero divides
+// the job timeout evenly between its steps.
+func (c *Consumer) SetStepTimeout(timeout time.Duration) {
+	c.stepTimeout = timeout
+}
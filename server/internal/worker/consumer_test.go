@@ -0,0 +1,506 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/google/uuid"
+)
+
+func TestEvaluateResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *docker.ContainerResult
+		err        error
+		wantStatus models.JobStatus
+		wantMsg    string
+	}{
+		{"success", &docker.ContainerResult{ExitCode: 0}, nil, models.JobStatusCompleted, ""},
+		{"non-zero exit", &docker.ContainerResult{ExitCode: 2}, nil, models.JobStatusFailed, "exited with code 2"},
+		{"oom killed", &docker.ContainerResult{ExitCode: 137, OOMKilled: true}, nil, models.JobStatusFailed, "OOM-killed"},
+		{"run error", &docker.ContainerResult{}, errors.New("failed to pull image"), models.JobStatusFailed, "failed to pull image"},
+		{"timed out", &docker.ContainerResult{Error: docker.ErrTimeoutExceeded}, docker.ErrTimeoutExceeded, models.JobStatusFailed, "timeout exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := evaluateResult(tt.result, tt.err)
+			if status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("message = %q, want it to contain %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		attempts   int
+		maxRetries int
+		want       bool
+	}{
+		{attempts: 1, maxRetries: 3, want: true},
+		{attempts: 3, maxRetries: 3, want: true},
+		{attempts: 4, maxRetries: 3, want: false},
+		{attempts: 1, maxRetries: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := shouldRetry(tt.attempts, tt.maxRetries); got != tt.want {
+			t.Errorf("shouldRetry(%d, %d) = %v, want %v", tt.attempts, tt.maxRetries, got, tt.want)
+		}
+	}
+}
+
+func TestStoredOutput(t *testing.T) {
+	output := strings.Repeat("x", 100) + "last line\n"
+
+	tests := []struct {
+		name      string
+		status    models.JobStatus
+		tailBytes int
+		want      string
+	}{
+		{"success truncated to tail", models.JobStatusCompleted, 10, "[... 100 bytes of output truncated ...]\nlast line\n"},
+		{"success kept when disabled", models.JobStatusCompleted, 0, output},
+		{"success shorter than tail", models.JobStatusCompleted, 4096, output},
+		{"failure retains full output", models.JobStatusFailed, 10, output},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storedOutput(output, tt.status, tt.tailBytes); got != tt.want {
+				t.Errorf("storedOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoredOutput_KeepsRunesWhole(t *testing.T) {
+	got := storedOutput("done ✓", models.JobStatusCompleted, 2) // ✓ is 3 bytes
+	if !strings.HasSuffix(got, "\n") || strings.ContainsRune(got, utf8.RuneError) {
+		t.Errorf("expected the split rune to be dropped cleanly, got %q", got)
+	}
+}
+
+func TestSuccessTailBytes_PerJobOverride(t *testing.T) {
+	keepAll := 0
+	if got := successTailBytes(&queue.QueueItem{SuccessOutputTailBytes: &keepAll}, 4096); got != 0 {
+		t.Errorf("expected per-job 0 to keep all output, got %d", got)
+	}
+	if got := successTailBytes(&queue.QueueItem{}, 4096); got != 4096 {
+		t.Errorf("expected worker default, got %d", got)
+	}
+}
+
+func TestConsumer_WorkerNodeID(t *testing.T) {
+	c := &Consumer{workerID: "worker-2"}
+	if got := *c.workerNodeID(); got != "worker-2" {
+		t.Errorf("expected the bare worker ID without a node, got %q", got)
+	}
+
+	c.SetNodeID("3f1c9a52-7d4e-4b8a-9c61-0e2f5d7a8b90")
+	if got := *c.workerNodeID(); got != "3f1c9a52-7d4e-4b8a-9c61-0e2f5d7a8b90/worker-2" {
+		t.Errorf("expected node and worker ID, got %q", got)
+	}
+}
+
+func TestConsumer_DuplicatedJobRunsOnce(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	// The same job queued twice, as after a promotion racing a manual requeue
+	jobID := uuid.NewString()
+	for attempts := 0; attempts < 2; attempts++ {
+		if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: jobID, DockerImage: "alpine:latest", Attempts: attempts}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	var runs int32
+	release := make(chan struct{})
+	execute := func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	finished := make(chan struct{}, 2)
+	for _, workerID := range []string{"worker-1", "worker-2"} {
+		c := NewConsumer(q, nil, nil, nil, workerID)
+		c.execute = execute
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.processNextJob(ctx); err != nil {
+				t.Errorf("processNextJob: %v", err)
+			}
+			finished <- struct{}{}
+		}()
+	}
+
+	// The duplicate is skipped while the first copy is still running
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Error("expected one consumer to skip the duplicate without waiting")
+	}
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("job executed %d times, want 1", got)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("immediate queue has %d items, want both copies consumed", length)
+	}
+	// The copy that found the job locked is tried again later rather than dropped
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 1 {
+		t.Errorf("delayed queue has %d items, want the locked copy deferred", length)
+	}
+
+	// The lock is released once the job finishes
+	if acquired, err := q.AcquireJobLock(ctx, jobID, "next", time.Minute); err != nil || !acquired {
+		t.Errorf("expected lock to be free after completion, acquired=%v err=%v", acquired, err)
+	}
+}
+
+func TestConsumer_RetriedJobRunsOnAnotherConsumer(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	jobID := uuid.New()
+	jobs := fakeJobStatuses{jobID: models.JobStatusPending}
+	if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: jobID.String(), DockerImage: "alpine:latest"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	// worker-1's run fails and is requeued; it finishes up only after worker-2 has polled
+	first := NewConsumer(q, nil, nil, nil, "worker-1")
+	first.statuses = jobs
+	requeued := make(chan struct{})
+	secondDone := make(chan struct{})
+	first.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		if err := jobs.UpdateJobStatusChecked(ctx, id, models.JobStatusRunning); err != nil {
+			return err
+		}
+		item.Attempts++
+		if err := first.requeueForRetry(ctx, id, item); err != nil {
+			return err
+		}
+		close(requeued)
+		<-secondDone
+		return nil
+	}
+
+	var ran []*queue.QueueItem
+	second := NewConsumer(q, nil, nil, nil, "worker-2")
+	second.statuses = jobs
+	second.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		ran = append(ran, item)
+		return jobs.UpdateJobStatusChecked(ctx, id, models.JobStatusRunning)
+	}
+
+	firstErr := make(chan error, 1)
+	go func() { firstErr <- first.processNextJob(ctx) }()
+
+	<-requeued
+	if err := second.processNextJob(ctx); err != nil {
+		t.Errorf("second consumer: %v", err)
+	}
+	close(secondDone)
+	if err := <-firstErr; err != nil {
+		t.Fatalf("first consumer: %v", err)
+	}
+
+	if len(ran) != 1 || ran[0].Attempts != 1 {
+		t.Fatalf("second consumer ran %+v, want the retry (attempt 1)", ran)
+	}
+	if jobs[jobID] != models.JobStatusRunning {
+		t.Errorf("job status = %s, want RUNNING for the retry", jobs[jobID])
+	}
+	immediate, _ := q.GetImmediateQueueLength(ctx)
+	delayed, _ := q.GetDelayedQueueLength(ctx)
+	if immediate != 0 || delayed != 0 {
+		t.Errorf("queues hold %d immediate and %d delayed items, want none", immediate, delayed)
+	}
+}
+
+func TestConsumer_RecoversJobAfterCrash(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	jobID := uuid.NewString()
+	if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: jobID, DockerImage: "alpine:latest"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	// A worker on node-a dequeues the job and crashes before finishing it
+	if _, err := q.ClaimImmediate(ctx, "node-a"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	var ran []*queue.QueueItem
+	c := NewConsumer(q, nil, nil, nil, "worker-1")
+	c.SetNodeID("node-b")
+	c.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		ran = append(ran, item)
+		return nil
+	}
+
+	if err := c.processNextJob(ctx); err == nil || err.Error() != "no jobs available" {
+		t.Fatalf("expected nothing to run while the job is claimed, got %v", err)
+	}
+
+	// node-a never heartbeats again, so the promoter recovers its job
+	p := NewPromoterService(q, time.Second)
+	p.staleAfter = 0
+	if err := p.requeueStaleJobs(ctx); err != nil {
+		t.Fatalf("requeueStaleJobs: %v", err)
+	}
+
+	if err := c.processNextJob(ctx); err != nil {
+		t.Fatalf("processNextJob: %v", err)
+	}
+	if len(ran) != 1 || ran[0].JobID != jobID || !ran[0].Reclaimed {
+		t.Fatalf("ran = %+v, want the recovered job once", ran)
+	}
+	if n, _ := q.ReclaimProcessing(ctx, "node-b"); n != 0 {
+		t.Errorf("%d job(s) left in node-b's processing set after completion, want 0", n)
+	}
+}
+
+func TestWatchCancel_StopsRunOnRequest(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	requests, unsubscribe, err := q.SubscribeJobCancel(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+	var requested atomic.Bool
+	go watchCancel(runCtx, requests, func(context.Context) bool { return false }, time.Hour, &requested, stopRun)
+
+	// The API cancels the job from another process
+	if _, err := q.PublishJobCancel(ctx, "job-1"); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the run to be stopped promptly")
+	}
+	if !requested.Load() {
+		t.Error("expected the cancel to be recorded as requested")
+	}
+}
+
+func TestWatchCancel_StopsRunOnMissedRequest(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	// The worker's subscription dropped, so only the cancel marker reaches it
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+	var requested atomic.Bool
+	marked := func(ctx context.Context) bool {
+		cancelled, err := q.JobCancelRequested(ctx, "job-1")
+		return err == nil && cancelled
+	}
+	go watchCancel(runCtx, nil, marked, 10*time.Millisecond, &requested, stopRun)
+
+	if receivers, err := q.PublishJobCancel(ctx, "job-1"); err != nil || receivers != 0 {
+		t.Fatalf("publish: %d receivers, err %v; want 0 receivers", receivers, err)
+	}
+
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the run to be stopped promptly by the cancel marker")
+	}
+	if !requested.Load() {
+		t.Error("expected the cancel to be recorded as requested")
+	}
+}
+
+func TestWatchCancel_RunEndingFirstIsNotCancelled(t *testing.T) {
+	runCtx, stopRun := context.WithCancel(context.Background())
+	var requested atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		watchCancel(runCtx, nil, func(context.Context) bool { return false }, time.Millisecond, &requested, stopRun)
+		close(done)
+	}()
+
+	// The container finishes on its own before any cancel arrives
+	stopRun()
+	<-done
+	if requested.Load() {
+		t.Error("expected no cancel to be recorded")
+	}
+}
+
+// captureJSONLogs sends the default logger's output to a buffer as JSON for the test
+func captureJSONLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(logging.ContextHandler(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+	return &buf
+}
+
+// findLogRecord returns the first JSON record with the given message
+func findLogRecord(t *testing.T, logs *bytes.Buffer, msg string) map[string]any {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader(logs.Bytes()))
+	for decoder.More() {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("log output is not JSON: %v\n%s", err, logs.String())
+		}
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	t.Fatalf("no %q record in logs:\n%s", msg, logs.String())
+	return nil
+}
+
+func TestConsumer_LogsStructuredFields(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+	logs := captureJSONLogs(t)
+
+	jobID := uuid.NewString()
+	if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: jobID, DockerImage: "alpine:latest"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := q.AcquireJobLock(ctx, jobID, "node-b/worker-9", time.Minute); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	c := NewConsumer(q, nil, nil, nil, "worker-1")
+	c.SetNodeID("node-a")
+	if err := c.processNextJob(ctx); err != nil {
+		t.Fatalf("processNextJob: %v", err)
+	}
+
+	enqueued := findLogRecord(t, logs, "Enqueued immediate job")
+	if enqueued["job_id"] != jobID {
+		t.Errorf("enqueue record job_id = %v, want %s", enqueued["job_id"], jobID)
+	}
+	skipped := findLogRecord(t, logs, "Job locked by another worker, retrying later")
+	if skipped["job_id"] != jobID || skipped["worker_id"] != "node-a/worker-1" {
+		t.Errorf("skip record = %v, want job_id %s and worker_id node-a/worker-1", skipped, jobID)
+	}
+}
+
+func TestConsumer_CarriesRequestIDFromQueueToExecution(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+	logs := captureJSONLogs(t)
+
+	// The submitting request's ID rides on the queue item through Redis
+	jobID := uuid.NewString()
+	if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: jobID, DockerImage: "alpine:latest", RequestID: "req-7f3a"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var executionRequestID string
+	c := NewConsumer(q, nil, nil, nil, "worker-1")
+	c.SetNodeID("node-a")
+	c.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		executionRequestID = logging.RequestID(ctx)
+		return nil
+	}
+	if err := c.processNextJob(ctx); err != nil {
+		t.Fatalf("processNextJob: %v", err)
+	}
+
+	if executionRequestID != "req-7f3a" {
+		t.Errorf("execution context request ID = %q, want req-7f3a", executionRequestID)
+	}
+	processing := findLogRecord(t, logs, "Processing job")
+	if processing["request_id"] != "req-7f3a" || processing["job_id"] != jobID || processing["worker_id"] != "node-a/worker-1" {
+		t.Errorf("processing record = %v, want request_id req-7f3a, job_id %s and worker_id node-a/worker-1", processing, jobID)
+	}
+}
+
+func TestConsumer_FailsJobPickedUpAfterDeadline(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	// Workers were saturated until after the job's deadline
+	jobID := uuid.New()
+	item := &queue.QueueItem{JobID: jobID.String(), DockerImage: "alpine:latest", Deadline: time.Now().Add(-time.Second)}
+	if err := q.EnqueueImmediate(ctx, item); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var ran bool
+	var failedWith string
+	c := NewConsumer(q, nil, nil, nil, "worker-1")
+	c.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+		ran = true
+		return nil
+	}
+	c.failEarly = func(ctx context.Context, id uuid.UUID, errorMsg string) error {
+		if id == jobID {
+			failedWith = errorMsg
+		}
+		return nil
+	}
+	if err := c.processNextJob(ctx); err != nil {
+		t.Fatalf("processNextJob: %v", err)
+	}
+
+	if ran {
+		t.Error("expected the container run to be skipped for a job past its deadline")
+	}
+	if failedWith != deadlineExceededMsg {
+		t.Errorf("job failed with %q, want %q", failedWith, deadlineExceededMsg)
+	}
+}
+
+func TestPastDeadline(t *testing.T) {
+	now := time.Now()
+	if pastDeadline(time.Time{}, now) {
+		t.Error("expected a zero deadline never to pass")
+	}
+	if pastDeadline(now.Add(time.Minute), now) {
+		t.Error("expected a future deadline not to have passed")
+	}
+	if !pastDeadline(now.Add(-time.Minute), now) {
+		t.Error("expected a past deadline to have passed")
+	}
+}
@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/database"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/google/uuid"
+)
+
+// dependencyJobStore reads jobs and their dependencies and moves WAITING jobs on
+type dependencyJobStore interface {
+	GetJobByID(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetJobDependencies(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error)
+	GetDependentJobs(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error)
+	UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error
+	ReleaseWaitingJob(ctx context.Context, id uuid.UUID, scheduledTime time.Time) (bool, error)
+}
+
+// waitingQueue hands out held jobs and queues the released ones
+type waitingQueue interface {
+	TakeWaiting(ctx context.Context, jobID string) (*queue.QueueItem, error)
+	EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error
+	PublishJobLogEnd(ctx context.Context, jobID, status string) error
+}
+
+// executionLogWriter records why a WAITING job failed
+type executionLogWriter interface {
+	CreateExecutionLog(ctx context.Context, log *models.ExecutionLog) error
+}
+
+// DependencyResolver queues WAITING jobs once every job they depend on has completed,
+// and fails them when one of those jobs fails or is cancelled. Released jobs go on the
+// immediate queue: their dependencies' finish time is only known now, too late for the
+// carbon scheduler to have picked a window at submission.
+type DependencyResolver struct {
+	jobs  dependencyJobStore
+	queue waitingQueue
+	logs  executionLogWriter // Optional: records the dependency failure on the job
+}
+
+// NewDependencyResolver creates a dependency resolver
+func NewDependencyResolver(jobRepo *database.JobRepository, queue *queue.RedisQueue, executionRepo *database.ExecutionLogRepository) *DependencyResolver {
+	r := &DependencyResolver{
+		jobs:  jobRepo,
+		queue: queue,
+	}
+	if executionRepo != nil {
+		r.logs = executionRepo
+	}
+	return r
+}
+
+// JobFinished settles the jobs waiting on jobID, which has just reached a final status
+func (r *DependencyResolver) JobFinished(ctx context.Context, jobID uuid.UUID) error {
+	dependents, err := r.jobs.GetDependentJobs(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, dependent := range dependents {
+		if err := r.Check(ctx, dependent); err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", dependent, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Check queues a WAITING job whose dependencies have all completed, or fails it if one
+// of them failed or was cancelled. Jobs in any other status, or still waiting on a
+// dependency, are left alone.
+func (r *DependencyResolver) Check(ctx context.Context, jobID uuid.UUID) error {
+	job, err := r.jobs.GetJobByID(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status != models.JobStatusWaiting {
+		return nil
+	}
+
+	dependencies, err := r.jobs.GetJobDependencies(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	ready := true
+	for _, dependencyID := range dependencies {
+		dependency, err := r.jobs.GetJobByID(ctx, dependencyID)
+		if err != nil {
+			return err
+		}
+
+		switch dependency.Status {
+		case models.JobStatusCompleted:
+		case models.JobStatusFailed, models.JobStatusCancelled:
+			return r.fail(ctx, jobID, fmt.Sprintf("Dependency failed: job %s is %s", dependencyID, dependency.Status))
+		default:
+			ready = false
+		}
+	}
+	if !ready {
+		return nil
+	}
+
+	return r.release(ctx, jobID)
+}
+
+// release queues a WAITING job whose dependencies have all completed
+func (r *DependencyResolver) release(ctx context.Context, jobID uuid.UUID) error {
+	// The job is due now, not when it was submitted. The status change decides which
+	// resolver releases the job when two race.
+	now := time.Now()
+	released, err := r.jobs.ReleaseWaitingJob(ctx, jobID, now)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return nil
+	}
+
+	item, err := r.queue.TakeWaiting(ctx, jobID.String())
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		slog.ErrorContext(ctx, "Released job has no held queue entry", logging.KeyJobID, jobID)
+		return r.fail(ctx, jobID, "Job could not be queued after its dependencies completed")
+	}
+
+	item.ScheduledTime = now
+	if err := r.queue.EnqueueImmediate(ctx, item); err != nil {
+		return fmt.Errorf("failed to queue released job: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Dependencies completed, job queued", logging.KeyJobID, jobID)
+	return nil
+}
+
+// fail fails a job that can't run because of its dependencies, then the jobs waiting on it
+func (r *DependencyResolver) fail(ctx context.Context, jobID uuid.UUID, reason string) error {
+	if err := r.jobs.UpdateJobStatusChecked(ctx, jobID, models.JobStatusFailed); err != nil {
+		if errors.Is(err, models.ErrIllegalTransition) {
+			return nil
+		}
+		return fmt.Errorf("failed to fail job: %w", err)
+	}
+
+	if _, err := r.queue.TakeWaiting(ctx, jobID.String()); err != nil {
+		slog.WarnContext(ctx, "Failed to drop held queue entry", logging.KeyJobID, jobID, logging.Err(err))
+	}
+	r.recordFailure(ctx, jobID, reason)
+
+	slog.InfoContext(ctx, "Waiting job failed", logging.KeyJobID, jobID, "reason", reason)
+	return r.JobFinished(ctx, jobID)
+}
+
+// recordFailure stores reason as the job's execution log and ends its log stream
+func (r *DependencyResolver) recordFailure(ctx context.Context, jobID uuid.UUID, reason string) {
+	if r.logs != nil {
+		now := time.Now()
+		executionLog := &models.ExecutionLog{
+			ID:           uuid.New(),
+			JobID:        jobID,
+			StartedAt:    now,
+			CompletedAt:  &now,
+			ErrorMessage: &reason,
+		}
+		if err := r.logs.CreateExecutionLog(ctx, executionLog); err != nil {
+			slog.WarnContext(ctx, "Failed to save execution log", logging.KeyJobID, jobID, logging.Err(err))
+		}
+	}
+
+	if err := r.queue.PublishJobLogEnd(ctx, jobID.String(), string(models.JobStatusFailed)); err != nil {
+		slog.WarnContext(ctx, "Failed to publish log end", logging.KeyJobID, jobID, logging.Err(err))
+	}
+}
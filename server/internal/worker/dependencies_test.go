@@ -0,0 +1,223 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/google/uuid"
+)
+
+// fakeDependencyStore keeps jobs and their dependencies in memory
+type fakeDependencyStore struct {
+	jobs         map[uuid.UUID]*models.Job
+	dependencies map[uuid.UUID][]uuid.UUID
+}
+
+func newFakeDependencyStore() *fakeDependencyStore {
+	return &fakeDependencyStore{jobs: make(map[uuid.UUID]*models.Job), dependencies: make(map[uuid.UUID][]uuid.UUID)}
+}
+
+// addJob stores a job in status that depends on dependsOn
+func (f *fakeDependencyStore) addJob(status models.JobStatus, dependsOn ...uuid.UUID) *models.Job {
+	job := &models.Job{ID: uuid.New(), Status: status}
+	f.jobs[job.ID] = job
+	f.dependencies[job.ID] = dependsOn
+	return job
+}
+
+func (f *fakeDependencyStore) GetJobByID(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+func (f *fakeDependencyStore) GetJobDependencies(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	return f.dependencies[jobID], nil
+}
+
+func (f *fakeDependencyStore) GetDependentJobs(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	var dependents []uuid.UUID
+	for id, dependsOn := range f.dependencies {
+		for _, dependency := range dependsOn {
+			if dependency == jobID {
+				dependents = append(dependents, id)
+			}
+		}
+	}
+	return dependents, nil
+}
+
+func (f *fakeDependencyStore) UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
+	job, ok := f.jobs[id]
+	if !ok {
+		return errors.New("job not found")
+	}
+	if !models.CanTransition(job.Status, status) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, job.Status, status)
+	}
+	job.Status = status
+	return nil
+}
+
+func (f *fakeDependencyStore) ReleaseWaitingJob(ctx context.Context, id uuid.UUID, scheduledTime time.Time) (bool, error) {
+	job, ok := f.jobs[id]
+	if !ok || job.Status != models.JobStatusWaiting {
+		return false, nil
+	}
+	job.Status = models.JobStatusPending
+	job.ScheduledTime = &scheduledTime
+	return true, nil
+}
+
+// fakeWaitingQueue holds waiting jobs and records queued ones and ended log streams
+type fakeWaitingQueue struct {
+	held      map[string]*queue.QueueItem
+	immediate []*queue.QueueItem
+	logEnds   map[string]string
+}
+
+func (f *fakeWaitingQueue) hold(job *models.Job) {
+	if f.held == nil {
+		f.held = make(map[string]*queue.QueueItem)
+	}
+	f.held[job.ID.String()] = &queue.QueueItem{JobID: job.ID.String(), DockerImage: "alpine:latest"}
+}
+
+func (f *fakeWaitingQueue) TakeWaiting(ctx context.Context, jobID string) (*queue.QueueItem, error) {
+	item := f.held[jobID]
+	delete(f.held, jobID)
+	return item, nil
+}
+
+func (f *fakeWaitingQueue) EnqueueImmediate(ctx context.Context, item *queue.QueueItem) error {
+	f.immediate = append(f.immediate, item)
+	return nil
+}
+
+func (f *fakeWaitingQueue) PublishJobLogEnd(ctx context.Context, jobID, status string) error {
+	if f.logEnds == nil {
+		f.logEnds = make(map[string]string)
+	}
+	f.logEnds[jobID] = status
+	return nil
+}
+
+// fakeExecutionLogs records execution logs by job
+type fakeExecutionLogs map[uuid.UUID]*models.ExecutionLog
+
+func (f fakeExecutionLogs) CreateExecutionLog(ctx context.Context, log *models.ExecutionLog) error {
+	f[log.JobID] = log
+	return nil
+}
+
+func TestDependencyResolver_ReleasesChainInOrder(t *testing.T) {
+	store := newFakeDependencyStore()
+	q := &fakeWaitingQueue{}
+	r := &DependencyResolver{jobs: store, queue: q}
+	ctx := context.Background()
+
+	first := store.addJob(models.JobStatusRunning)
+	second := store.addJob(models.JobStatusWaiting, first.ID)
+	q.hold(second)
+
+	// Nothing moves while the first job runs
+	if err := r.Check(ctx, second.ID); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if second.Status != models.JobStatusWaiting || len(q.immediate) != 0 {
+		t.Fatalf("expected second job still waiting, got %s with %d queued", second.Status, len(q.immediate))
+	}
+
+	first.Status = models.JobStatusCompleted
+	before := time.Now()
+	if err := r.JobFinished(ctx, first.ID); err != nil {
+		t.Fatalf("JobFinished failed: %v", err)
+	}
+
+	if second.Status != models.JobStatusPending {
+		t.Errorf("second job status = %s, want PENDING", second.Status)
+	}
+	if len(q.immediate) != 1 || q.immediate[0].JobID != second.ID.String() {
+		t.Fatalf("expected second job on the immediate queue, got %v", q.immediate)
+	}
+	// The job is due from its release, so the wait doesn't count against the start SLO
+	if second.ScheduledTime == nil || second.ScheduledTime.Before(before) || q.immediate[0].ScheduledTime.Before(before) {
+		t.Errorf("expected scheduled time reset to the release, got %v", second.ScheduledTime)
+	}
+	if len(q.held) != 0 {
+		t.Errorf("expected the held entry taken, %d left", len(q.held))
+	}
+
+	// A second resolver racing for the same job doesn't queue it twice
+	if err := r.JobFinished(ctx, first.ID); err != nil {
+		t.Fatalf("JobFinished failed: %v", err)
+	}
+	if len(q.immediate) != 1 {
+		t.Errorf("expected the job queued once, got %d", len(q.immediate))
+	}
+}
+
+func TestDependencyResolver_WaitsForAllDependencies(t *testing.T) {
+	store := newFakeDependencyStore()
+	q := &fakeWaitingQueue{}
+	r := &DependencyResolver{jobs: store, queue: q}
+	ctx := context.Background()
+
+	done := store.addJob(models.JobStatusCompleted)
+	pending := store.addJob(models.JobStatusDelayed)
+	job := store.addJob(models.JobStatusWaiting, done.ID, pending.ID)
+	q.hold(job)
+
+	if err := r.JobFinished(ctx, done.ID); err != nil {
+		t.Fatalf("JobFinished failed: %v", err)
+	}
+	if job.Status != models.JobStatusWaiting || len(q.immediate) != 0 {
+		t.Errorf("expected job to keep waiting on its delayed dependency, got %s", job.Status)
+	}
+}
+
+func TestDependencyResolver_FailedDependencyFailsDependents(t *testing.T) {
+	store := newFakeDependencyStore()
+	q := &fakeWaitingQueue{}
+	logs := fakeExecutionLogs{}
+	r := &DependencyResolver{jobs: store, queue: q, logs: logs}
+	ctx := context.Background()
+
+	first := store.addJob(models.JobStatusFailed)
+	second := store.addJob(models.JobStatusWaiting, first.ID)
+	third := store.addJob(models.JobStatusWaiting, second.ID)
+	q.hold(second)
+	q.hold(third)
+
+	if err := r.JobFinished(ctx, first.ID); err != nil {
+		t.Fatalf("JobFinished failed: %v", err)
+	}
+
+	// The failure carries down the chain
+	for name, job := range map[string]*models.Job{"second": second, "third": third} {
+		if job.Status != models.JobStatusFailed {
+			t.Errorf("%s job status = %s, want FAILED", name, job.Status)
+		}
+		log := logs[job.ID]
+		if log == nil || log.ErrorMessage == nil || !strings.HasPrefix(*log.ErrorMessage, "Dependency failed") {
+			t.Errorf("%s job: expected a dependency failure reason, got %+v", name, log)
+		}
+		if q.logEnds[job.ID.String()] != string(models.JobStatusFailed) {
+			t.Errorf("%s job: expected its log stream ended as FAILED", name)
+		}
+	}
+	if !strings.Contains(*logs[second.ID].ErrorMessage, first.ID.String()) {
+		t.Errorf("expected the reason to name the failed job, got %q", *logs[second.ID].ErrorMessage)
+	}
+	if len(q.immediate) != 0 || len(q.held) != 0 {
+		t.Errorf("expected nothing queued and nothing held, got %d queued / %d held", len(q.immediate), len(q.held))
+	}
+}
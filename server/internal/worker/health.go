@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/version"
+)
+
+// healthCheckTimeout bounds one /healthz check, so a hung Docker daemon reads as unhealthy
+const healthCheckTimeout = 3 * time.Second
+
+// healthChecker reports whether the worker can still take jobs
+type healthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// NewHealthServer returns an HTTP server on addr exposing GET /healthz, which answers
+// 200 when the pool reaches Redis and the Docker daemon and 503 otherwise. The worker has
+// no other HTTP surface; this lets orchestrators restart a worker whose Docker is broken,
+// and the build info in the response shows which version each worker runs.
+func NewHealthServer(addr string, pool *Pool) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           healthMux(pool),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// healthMux routes GET /healthz to checker
+func healthMux(checker healthChecker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		status := http.StatusOK
+		body := map[string]any{
+			"healthy":   true,
+			"timestamp": time.Now().Format(time.RFC3339),
+			"build":     version.Get(),
+		}
+		if err := checker.HealthCheck(ctx); err != nil {
+			slog.WarnContext(ctx, "Worker health check failed", logging.Err(err))
+			status = http.StatusServiceUnavailable
+			body["healthy"] = false
+			body["error"] = err.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	})
+	return mux
+}
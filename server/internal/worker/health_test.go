@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/version"
+)
+
+// newFakeDockerDaemon answers the Docker API's ping with the given status
+func newFakeDockerDaemon(t *testing.T, status int) *docker.Service {
+	t.Helper()
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_ping") {
+			t.Errorf("unexpected Docker API call %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("API-Version", "1.43")
+		w.WriteHeader(status)
+		w.Write([]byte("OK"))
+	}))
+	t.Cleanup(daemon.Close)
+
+	t.Setenv("DOCKER_HOST", "tcp://"+daemon.Listener.Addr().String())
+	service, err := docker.NewDockerService(docker.ResourceLimits{}, docker.ResourceLimits{})
+	if err != nil {
+		t.Fatalf("NewDockerService returned error: %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+func TestHealthServer_ReportsPoolHealth(t *testing.T) {
+	tests := []struct {
+		name         string
+		dockerStatus int
+		wantStatus   int
+		wantError    string
+	}{
+		{"healthy", http.StatusOK, http.StatusOK, ""},
+		{"docker ping failing", http.StatusInternalServerError, http.StatusServiceUnavailable, "docker health check failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, _ := newPromoterTestRedis(t)
+			pool := &Pool{queue: queue, dockerService: newFakeDockerDaemon(t, tt.dockerStatus)}
+
+			server := httptest.NewServer(NewHealthServer("", pool).Handler)
+			t.Cleanup(server.Close)
+
+			resp, err := http.Get(server.URL + "/healthz")
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			var body struct {
+				Healthy bool              `json:"healthy"`
+				Error   string            `json:"error"`
+				Build   version.BuildInfo `json:"build"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Healthy != (tt.wantError == "") || !strings.Contains(body.Error, tt.wantError) {
+				t.Errorf("expected healthy=%v with error containing %q, got %+v", tt.wantError == "", tt.wantError, body)
+			}
+			if build := body.Build; build.Version == "" || build.Commit == "" || build.BuildTime == "" || build.GoVersion != runtime.Version() {
+				t.Errorf("expected the worker's build info, got %+v", build)
+			}
+		})
+	}
+}
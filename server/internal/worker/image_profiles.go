@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+)
+
+// ImageProfile holds defaults for jobs whose image matches Pattern. Zero fields leave
+// the worker's global default in place.
+type ImageProfile struct {
+	Pattern       string   `json:"pattern"`                   // Glob over the image name, e.g. "python:*" (* doesn't cross "/")
+	Command       []string `json:"command,omitempty"`         // Used when the job has no command of its own
+	Timeout       string   `json:"timeout,omitempty"`         // Go duration, e.g. "2h"
+	MemoryLimitMB int      `json:"memory_limit_mb,omitempty"` // Container memory limit in MB
+	CPUQuota      int64    `json:"cpu_quota,omitempty"`       // Container CPU quota (100000 = one CPU)
+
+	timeout time.Duration
+}
+
+// ImageProfiles are checked in order; the first matching pattern wins
+type ImageProfiles []ImageProfile
+
+// ParseImageProfiles reads profiles from a JSON array, as in WORKER_IMAGE_PROFILES
+func ParseImageProfiles(data string) (ImageProfiles, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var profiles ImageProfiles
+	if err := json.Unmarshal([]byte(data), &profiles); err != nil {
+		return nil, fmt.Errorf("invalid image profiles: %w", err)
+	}
+
+	for i := range profiles {
+		profile := &profiles[i]
+		if profile.Pattern == "" {
+			return nil, fmt.Errorf("image profile %d has no pattern", i)
+		}
+		if _, err := path.Match(profile.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("image profile %q: %w", profile.Pattern, err)
+		}
+		if profile.Timeout != "" {
+			timeout, err := time.ParseDuration(profile.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("image profile %q: timeout must be a positive duration, got %q", profile.Pattern, profile.Timeout)
+			}
+			profile.timeout = timeout
+		}
+		if profile.MemoryLimitMB < 0 || profile.CPUQuota < 0 {
+			return nil, fmt.Errorf("image profile %q: limits must not be negative", profile.Pattern)
+		}
+	}
+
+	return profiles, nil
+}
+
+// Match returns the first profile whose pattern matches image, or nil
+func (p ImageProfiles) Match(image string) *ImageProfile {
+	for i := range p {
+		if matched, _ := path.Match(p[i].Pattern, image); matched {
+			return &p[i]
+		}
+	}
+	return nil
+}
+
+// profileFor returns the profile for a queue item's image, or nil
+func (c *Consumer) profileFor(item *queue.QueueItem) *ImageProfile {
+	if item == nil {
+		return nil
+	}
+	return c.imageProfiles.Match(item.DockerImage)
+}
+
+// RunTimeout returns the run time the profile allows its jobs, or zero when it sets none
+func (p *ImageProfile) RunTimeout() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return p.timeout
+}
+
+// timeoutFor returns the run time allowed for a job: its image profile's timeout,
+// else the consumer's
+func (c *Consumer) timeoutFor(item *queue.QueueItem) time.Duration {
+	if timeout := c.profileFor(item).RunTimeout(); timeout > 0 {
+		return timeout
+	}
+	return c.jobTimeout
+}
+
+// ResourceLimits returns a job's container limits. Limits set on the job win over its
+// image profile's; nil (or a zero field) leaves the Docker service's defaults in place.
+func ResourceLimits(item *queue.QueueItem, profile *ImageProfile) *docker.ResourceLimits {
+	var memoryMB int
+	var cpuQuota int64
+	if profile != nil {
+		memoryMB, cpuQuota = profile.MemoryLimitMB, profile.CPUQuota
+	}
+	if item != nil && item.MemoryLimitMB > 0 {
+		memoryMB = item.MemoryLimitMB
+	}
+	if item != nil && item.CPUQuota > 0 {
+		cpuQuota = item.CPUQuota
+	}
+
+	if memoryMB <= 0 && cpuQuota <= 0 {
+		return nil
+	}
+	return &docker.ResourceLimits{
+		MemoryBytes: int64(memoryMB) * 1024 * 1024,
+		CPUQuota:    cpuQuota,
+	}
+}
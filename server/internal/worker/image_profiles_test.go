@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+)
+
+func TestParseImageProfiles(t *testing.T) {
+	profiles, err := ParseImageProfiles(`[
+		{"pattern": "python:*", "timeout": "2h", "memory_limit_mb": 2048},
+		{"pattern": "*", "timeout": "30s"}
+	]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if profile := profiles.Match("python:3.12"); profile == nil || profile.timeout != 2*time.Hour {
+		t.Errorf("python:3.12 matched %+v, want the python profile", profile)
+	}
+	if profile := profiles.Match("alpine:latest"); profile == nil || profile.Pattern != "*" {
+		t.Errorf("alpine:latest matched %+v, want the catch-all", profile)
+	}
+	// * doesn't cross a registry path separator
+	if profile := profiles.Match("ghcr.io/org/tool:1"); profile != nil {
+		t.Errorf("ghcr.io/org/tool:1 matched %+v, want nil", profile)
+	}
+
+	if profiles, err := ParseImageProfiles(""); profiles != nil || err != nil {
+		t.Errorf("empty config = %v, %v; want nil, nil", profiles, err)
+	}
+	for _, bad := range []string{
+		`not json`,
+		`[{"timeout": "1m"}]`,
+		`[{"pattern": "[", "timeout": "1m"}]`,
+		`[{"pattern": "alpine:*", "timeout": "soon"}]`,
+		`[{"pattern": "alpine:*", "memory_limit_mb": -1}]`,
+	} {
+		if _, err := ParseImageProfiles(bad); err == nil {
+			t.Errorf("ParseImageProfiles(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestResourceLimits_Precedence(t *testing.T) {
+	profile := &ImageProfile{Pattern: "python:*", MemoryLimitMB: 2048, CPUQuota: 100000}
+
+	tests := []struct {
+		name       string
+		item       *queue.QueueItem
+		profile    *ImageProfile
+		wantNil    bool
+		wantMemory int64
+		wantCPU    int64
+	}{
+		{name: "global defaults", item: &queue.QueueItem{}, wantNil: true},
+		{name: "job only", item: &queue.QueueItem{MemoryLimitMB: 64, CPUQuota: 25000}, wantMemory: 64 << 20, wantCPU: 25000},
+		{name: "profile only", item: &queue.QueueItem{}, profile: profile, wantMemory: 2048 << 20, wantCPU: 100000},
+		{name: "job beats profile", item: &queue.QueueItem{MemoryLimitMB: 64, CPUQuota: 25000}, profile: profile, wantMemory: 64 << 20, wantCPU: 25000},
+		{name: "job overrides one field", item: &queue.QueueItem{CPUQuota: 25000}, profile: profile, wantMemory: 2048 << 20, wantCPU: 25000},
+		{name: "profile without limits", item: &queue.QueueItem{}, profile: &ImageProfile{Pattern: "*"}, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := ResourceLimits(tt.item, tt.profile)
+			if tt.wantNil {
+				if limits != nil {
+					t.Errorf("limits = %+v, want nil (Docker service defaults)", limits)
+				}
+				return
+			}
+			if limits == nil || limits.MemoryBytes != tt.wantMemory || limits.CPUQuota != tt.wantCPU {
+				t.Errorf("limits = %+v, want memory %d and CPU %d", limits, tt.wantMemory, tt.wantCPU)
+			}
+		})
+	}
+}
+
+func TestConsumer_TimeoutForPrefersProfile(t *testing.T) {
+	profiles, err := ParseImageProfiles(`[{"pattern": "alpine:*", "timeout": "30s"}, {"pattern": "python:*", "memory_limit_mb": 512}]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c := &Consumer{jobTimeout: 10 * time.Minute}
+	c.SetImageProfiles(profiles)
+
+	if got := c.timeoutFor(&queue.QueueItem{DockerImage: "alpine:3.20"}); got != 30*time.Second {
+		t.Errorf("alpine timeout = %v, want 30s from its profile", got)
+	}
+	if got := c.timeoutFor(&queue.QueueItem{DockerImage: "python:3.12"}); got != 10*time.Minute {
+		t.Errorf("python timeout = %v, want the global 10m (profile sets none)", got)
+	}
+	if got := c.timeoutFor(&queue.QueueItem{DockerImage: "busybox"}); got != 10*time.Minute {
+		t.Errorf("unprofiled timeout = %v, want the global 10m", got)
+	}
+}
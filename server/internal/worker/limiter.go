@@ -0,0 +1,44 @@
+package worker
+
+import "context"
+
+// ContainerLimiter caps the containers a worker process runs at once, independently
+// of how many consumers poll the queue. A consumer takes a slot before claiming a job
+// and gives it back once the job is finished with, so jobs never sit claimed while
+// waiting for capacity.
+type ContainerLimiter struct {
+	slots chan struct{}
+}
+
+// NewContainerLimiter creates a limiter allowing limit containers at once
+func NewContainerLimiter(limit int) *ContainerLimiter {
+	ContainerLimit.Set(float64(limit))
+	return &ContainerLimiter{slots: make(chan struct{}, limit)}
+}
+
+// Acquire blocks until a slot is free or ctx is done
+func (l *ContainerLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		ContainersInFlight.Inc()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release gives back a slot taken by Acquire
+func (l *ContainerLimiter) Release() {
+	<-l.slots
+	ContainersInFlight.Dec()
+}
+
+// InFlight returns the number of slots in use
+func (l *ContainerLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Limit returns the number of containers allowed at once
+func (l *ContainerLimiter) Limit() int {
+	return cap(l.slots)
+}
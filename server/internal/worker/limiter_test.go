@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/google/uuid"
+)
+
+// concurrencyRecorder tracks how many jobs run at once and the most seen together
+type concurrencyRecorder struct {
+	running  atomic.Int32
+	peak     atomic.Int32
+	finished atomic.Int32
+}
+
+func (r *concurrencyRecorder) run(hold time.Duration) {
+	now := r.running.Add(1)
+	for {
+		peak := r.peak.Load()
+		if now <= peak || r.peak.CompareAndSwap(peak, now) {
+			break
+		}
+	}
+	time.Sleep(hold)
+	r.running.Add(-1)
+	r.finished.Add(1)
+}
+
+func TestContainerLimiter_NeverExceedsLimit(t *testing.T) {
+	limiter := NewContainerLimiter(3)
+	recorder := &concurrencyRecorder{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Acquire(context.Background()); err != nil {
+				t.Errorf("Acquire returned error: %v", err)
+				return
+			}
+			defer limiter.Release()
+			recorder.run(5 * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	if peak := recorder.peak.Load(); peak > 3 {
+		t.Errorf("%d jobs ran at once, want at most 3", peak)
+	}
+	if limiter.InFlight() != 0 {
+		t.Errorf("expected every slot to be given back, %d still in use", limiter.InFlight())
+	}
+
+	// A full limiter gives up when the context ends
+	for i := 0; i < 3; i++ {
+		limiter.Acquire(context.Background())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx); err == nil {
+		t.Error("expected Acquire on a full limiter to fail once the context is done")
+	}
+}
+
+func TestConsumer_ContainerLimitDecoupledFromPollers(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const jobs = 10
+	for i := 0; i < jobs; i++ {
+		if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: uuid.NewString(), DockerImage: "alpine:latest"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	// Two pollers feeding three container slots
+	limiter := NewContainerLimiter(3)
+	recorder := &concurrencyRecorder{}
+	var wg sync.WaitGroup
+	for _, workerID := range []string{"worker-1", "worker-2"} {
+		c := NewConsumer(q, nil, nil, nil, workerID)
+		c.SetPollInterval(5 * time.Millisecond)
+		c.SetContainerLimiter(limiter)
+		c.execute = func(ctx context.Context, id uuid.UUID, item *queue.QueueItem) error {
+			recorder.run(30 * time.Millisecond)
+			return nil
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Start(ctx)
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.finished.Load() < jobs && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if got := recorder.finished.Load(); got != jobs {
+		t.Fatalf("%d of %d jobs ran", got, jobs)
+	}
+	if peak := recorder.peak.Load(); peak != 3 {
+		t.Errorf("expected 2 pollers to keep 3 containers busy, peak was %d", peak)
+	}
+}
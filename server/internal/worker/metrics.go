@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"strings"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// outcomeRetried labels an attempt that failed and was put back on the queue
+const outcomeRetried = "retried"
+
+// JobsProcessedTotal counts finished job attempts by worker and outcome ("completed",
+// "failed", "cancelled" or "retried"). It is registered by the metrics collector.
+var JobsProcessedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "karbos_worker_jobs_processed_total",
+	Help: "Job attempts finished by this worker process, by worker and outcome",
+}, []string{"worker_id", "outcome"})
+
+// ContainersInFlight and ContainerLimit report the jobs running under a worker's
+// container cap (WORKER_MAX_CONCURRENT_CONTAINERS) and the cap itself. They are
+// registered by the metrics collector and stay at zero without a cap.
+var (
+	ContainersInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "karbos_worker_containers_in_flight",
+		Help: "Jobs holding one of this worker process's container slots",
+	})
+	ContainerLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "karbos_worker_container_limit",
+		Help: "Containers this worker process may run at once (0 = one per consumer)",
+	})
+)
+
+// recordProcessed counts a finished attempt; a job left PENDING was requeued for retry
+func (c *Consumer) recordProcessed(status models.JobStatus) {
+	outcome := strings.ToLower(string(status))
+	if status == models.JobStatusPending {
+		outcome = outcomeRetried
+	}
+	JobsProcessedTotal.WithLabelValues(c.workerID, outcome).Inc()
+}
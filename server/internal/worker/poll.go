@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+)
+
+// PollStrategy decides how long an idle consumer waits between polls of the queue
+type PollStrategy string
+
+const (
+	// PollFixed polls at the poll interval whether or not the queue has work
+	PollFixed PollStrategy = "fixed"
+	// PollBackoff doubles the wait after each empty poll, up to the max poll interval,
+	// and drops back to the poll interval as soon as a job is found
+	PollBackoff PollStrategy = "backoff"
+)
+
+// ParsePollStrategy reads a strategy name, as in WORKER_POLL_STRATEGY ("" = backoff)
+func ParsePollStrategy(name string) (PollStrategy, error) {
+	switch PollStrategy(name) {
+	case "", PollBackoff:
+		return PollBackoff, nil
+	case PollFixed:
+		return PollFixed, nil
+	default:
+		return "", fmt.Errorf("unknown poll strategy %q (want %q or %q)", name, PollBackoff, PollFixed)
+	}
+}
+
+// pollBackoff tracks a consumer's wait between polls. With max at or below min it
+// always waits min, which is the fixed strategy.
+type pollBackoff struct {
+	min   time.Duration
+	max   time.Duration
+	delay time.Duration // Wait chosen after the last poll
+}
+
+// next returns the wait before the next poll, given whether the last poll found a job
+func (b *pollBackoff) next(found bool) time.Duration {
+	if found || b.delay == 0 {
+		b.delay = b.min
+	} else {
+		b.delay = min(b.delay*2, max(b.max, b.min))
+	}
+	return b.delay
+}
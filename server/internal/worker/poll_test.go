@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollBackoff_EmptyPollsIncreaseDelay(t *testing.T) {
+	b := pollBackoff{min: 2 * time.Second, max: 10 * time.Second}
+
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		got = append(got, b.next(false))
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delays after consecutive empty polls = %v, want %v", got, want)
+		}
+	}
+
+	// Finding a job drops straight back to the poll interval
+	if delay := b.next(true); delay != 2*time.Second {
+		t.Errorf("delay after a job was found = %v, want 2s", delay)
+	}
+	if delay := b.next(false); delay != 4*time.Second {
+		t.Errorf("delay after the next empty poll = %v, want 4s", delay)
+	}
+}
+
+func TestPollBackoff_FixedWithoutMax(t *testing.T) {
+	b := pollBackoff{min: 2 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		if delay := b.next(false); delay != 2*time.Second {
+			t.Fatalf("empty poll %d: delay = %v, want the fixed 2s", i+1, delay)
+		}
+	}
+}
+
+func TestParsePollStrategy(t *testing.T) {
+	for name, want := range map[string]PollStrategy{"": PollBackoff, "backoff": PollBackoff, "fixed": PollFixed} {
+		got, err := ParsePollStrategy(name)
+		if err != nil || got != want {
+			t.Errorf("ParsePollStrategy(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParsePollStrategy("blpop"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -3,12 +3,15 @@ package worker
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/Sambit-Mondal/karbos/server/internal/database"
 	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/Sambit-Mondal/karbos/server/internal/slo"
 )
 
 // Pool manages multiple worker consumers running concurrently
@@ -19,6 +22,18 @@ type Pool struct {
 	jobRepo          *database.JobRepository
 	executionRepo    *database.ExecutionLogRepository
 	dockerService    *docker.Service
+	maxRetries       int
+	prefetcher       *Prefetcher // Shared by all consumers; nil when prefetching is disabled
+	successTail      int         // Default stored output size for successful jobs (0 = all)
+	nodeID           string      // Recorded on execution logs alongside each consumer's worker ID
+	jobTimeout       time.Duration
+	stepTimeout      time.Duration
+	pollInterval     time.Duration // Shortest wait between polls (0 = consumer default)
+	pollMaxInterval  time.Duration // Longest wait between polls while the queue is empty
+	imageProfiles    ImageProfiles
+	limiter          *ContainerLimiter // Shared by all consumers; nil runs one job per consumer
+	dependencies     *DependencyResolver
+	startSLO         slo.Objective
 	wg               sync.WaitGroup
 	ctx              context.Context
 	cancel           context.CancelFunc
@@ -35,6 +50,26 @@ type PoolConfig struct {
 	JobRepo       *database.JobRepository
 	ExecutionRepo *database.ExecutionLogRepository
 	DockerService *docker.Service
+	MaxRetries    int           // Retries before a failing job is dead-lettered
+	JobTimeout    time.Duration // Run time allowed per job (0 = consumer default of 10 minutes)
+	StepTimeout   time.Duration // Run time allowed per step of a multi-step job (0 = job timeout / step count)
+	PrefetchDepth int           // Upcoming jobs whose images are pre-pulled (0 disables prefetching)
+
+	PollInterval    time.Duration // Wait between polls of the queue (0 = consumer default of 2 seconds)
+	PollMaxInterval time.Duration // With PollBackoff, the longest wait while the queue stays empty
+	PollStrategy    PollStrategy  // How the wait grows while idle ("" = PollFixed)
+
+	SuccessOutputTailBytes int // Trailing output bytes kept for successful jobs (0 keeps all)
+
+	ImageProfiles ImageProfiles // Per-image timeout, limits and command, ahead of the global defaults
+
+	NodeID string // Unique ID of this worker node, as used for its heartbeat
+
+	StartSLO slo.Objective // Objective each job's first start is judged against (zero = slo.DefaultObjective)
+
+	// MaxConcurrentContainers caps the containers the pool runs at once, however many
+	// consumers poll (0 = each consumer runs one job at a time, so Size caps them)
+	MaxConcurrentContainers int
 }
 
 // NewPool creates a new worker pool
@@ -67,6 +102,15 @@ func NewPool(config PoolConfig) (*Pool, error) {
 		jobRepo:          config.JobRepo,
 		executionRepo:    config.ExecutionRepo,
 		dockerService:    config.DockerService,
+		maxRetries:       config.MaxRetries,
+		successTail:      config.SuccessOutputTailBytes,
+		nodeID:           config.NodeID,
+		jobTimeout:       config.JobTimeout,
+		stepTimeout:      config.StepTimeout,
+		pollInterval:     config.PollInterval,
+		imageProfiles:    config.ImageProfiles,
+		dependencies:     NewDependencyResolver(config.JobRepo, config.Queue, config.ExecutionRepo),
+		startSLO:         config.StartSLO,
 		consumers:        make([]*Consumer, 0, config.Size),
 		ctx:              ctx,
 		cancel:           cancel,
@@ -74,12 +118,41 @@ func NewPool(config PoolConfig) (*Pool, error) {
 		shutdownDraining: false,
 	}
 
+	if config.PollStrategy == PollBackoff {
+		pool.pollMaxInterval = config.PollMaxInterval
+	}
+
+	if pool.startSLO == (slo.Objective{}) {
+		pool.startSLO = slo.DefaultObjective
+	}
+
+	if config.MaxConcurrentContainers < 0 {
+		return nil, fmt.Errorf("max concurrent containers must not be negative")
+	}
+	if config.MaxConcurrentContainers > 0 {
+		pool.limiter = NewContainerLimiter(config.MaxConcurrentContainers)
+	}
+
+	if config.PrefetchDepth > 0 {
+		pool.prefetcher = NewPrefetcher(config.Queue, config.DockerService, config.PrefetchDepth)
+	}
+
 	return pool, nil
 }
 
 // Start initializes and starts all worker consumers in the pool
 func (p *Pool) Start() error {
-	log.Printf("Starting worker pool with %d workers...", p.size)
+	slog.Info("Starting worker pool", "workers", p.size, "max_containers", p.GetContainerLimit(), "node_id", p.nodeID)
+
+	// Requeue jobs this node was running when it last stopped without finishing them
+	if p.nodeID != "" {
+		reclaimed, err := p.queue.ReclaimProcessing(p.ctx, p.nodeID)
+		if err != nil {
+			slog.Warn("Failed to reclaim in-flight jobs from a previous run", logging.Err(err))
+		} else if reclaimed > 0 {
+			slog.Info("Requeued jobs left in flight by a previous run of this node", "jobs", reclaimed)
+		}
+	}
 
 	// Create and start each worker
 	for i := 0; i < p.size; i++ {
@@ -95,6 +168,22 @@ func (p *Pool) Start() error {
 
 		// Set pool reference for job tracking
 		consumer.SetPool(p)
+		consumer.SetMaxRetries(p.maxRetries)
+		consumer.SetPrefetcher(p.prefetcher)
+		consumer.SetSuccessOutputTail(p.successTail)
+		consumer.SetNodeID(p.nodeID)
+		consumer.SetStartSLO(p.startSLO)
+		consumer.SetImageProfiles(p.imageProfiles)
+		consumer.SetDependencyResolver(p.dependencies)
+		consumer.SetContainerLimiter(p.limiter)
+		if p.jobTimeout > 0 {
+			consumer.SetJobTimeout(p.jobTimeout)
+		}
+		consumer.SetStepTimeout(p.stepTimeout)
+		if p.pollInterval > 0 {
+			consumer.SetPollInterval(p.pollInterval)
+		}
+		consumer.SetPollMaxInterval(p.pollMaxInterval)
 
 		p.consumers = append(p.consumers, consumer)
 
@@ -102,19 +191,19 @@ func (p *Pool) Start() error {
 		p.wg.Add(1)
 		go func(c *Consumer, id string) {
 			defer p.wg.Done()
-			log.Printf("[%s] Worker started", id)
+			slog.Info("Worker started", logging.KeyWorkerID, id)
 			c.Start(p.ctx)
-			log.Printf("[%s] Worker stopped", id)
+			slog.Info("Worker stopped", logging.KeyWorkerID, id)
 		}(consumer, workerID)
 	}
 
-	log.Printf("Worker pool started successfully with %d workers", p.size)
+	slog.Info("Worker pool started", "workers", p.size)
 	return nil
 }
 
 // Stop gracefully shuts down all workers in the pool
 func (p *Pool) Stop() {
-	log.Println("Stopping worker pool...")
+	slog.Info("Stopping worker pool")
 
 	// Mark as draining - workers will stop accepting new jobs
 	p.runningJobsMu.Lock()
@@ -123,14 +212,14 @@ func (p *Pool) Stop() {
 	p.runningJobsMu.Unlock()
 
 	if activeJobCount > 0 {
-		log.Printf("⏳ Waiting for %d running container(s) to complete...", activeJobCount)
+		slog.Info("Waiting for running containers to complete", "containers", activeJobCount)
 	}
 
 	// Wait for all active jobs to complete (with timeout handled by caller)
 	p.runningJobsWg.Wait()
 
 	if activeJobCount > 0 {
-		log.Println("✓ All running containers completed")
+		slog.Info("All running containers completed")
 	}
 
 	// Now cancel context to stop worker polling loops
@@ -139,7 +228,7 @@ func (p *Pool) Stop() {
 	// Wait for all workers to finish
 	p.wg.Wait()
 
-	log.Println("Worker pool stopped successfully")
+	slog.Info("Worker pool stopped")
 }
 
 // TrackJobStart registers a job as currently running
@@ -150,7 +239,7 @@ func (p *Pool) TrackJobStart(jobID string) {
 	if !p.activeJobs[jobID] {
 		p.activeJobs[jobID] = true
 		p.runningJobsWg.Add(1)
-		log.Printf("📦 Container started for job: %s (active: %d)", jobID, len(p.activeJobs))
+		slog.Info("Container started", logging.KeyJobID, jobID, "active", len(p.activeJobs))
 	}
 }
 
@@ -162,7 +251,7 @@ func (p *Pool) TrackJobComplete(jobID string) {
 	if p.activeJobs[jobID] {
 		delete(p.activeJobs, jobID)
 		p.runningJobsWg.Done()
-		log.Printf("✓ Container completed for job: %s (active: %d)", jobID, len(p.activeJobs))
+		slog.Info("Container completed", logging.KeyJobID, jobID, "active", len(p.activeJobs))
 	}
 }
 
@@ -180,6 +269,14 @@ func (p *Pool) GetActiveJobCount() int {
 	return len(p.activeJobs)
 }
 
+// GetContainerLimit returns how many containers the pool may run at once
+func (p *Pool) GetContainerLimit() int {
+	if p.limiter == nil {
+		return p.size
+	}
+	return p.limiter.Limit()
+}
+
 // Wait blocks until all workers have stopped
 func (p *Pool) Wait() {
 	p.wg.Wait()
@@ -206,9 +303,10 @@ func (p *Pool) GetStatus() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"pool_size": p.size,
-		"workers":   workers,
-		"status":    "active",
+		"pool_size":      p.size,
+		"max_containers": p.GetContainerLimit(),
+		"workers":        workers,
+		"status":         "active",
 	}
 }
 
@@ -218,7 +316,7 @@ func (p *Pool) ScaleUp(count int) error {
 		return fmt.Errorf("scale count must be greater than 0")
 	}
 
-	log.Printf("Scaling up worker pool by %d workers...", count)
+	slog.Info("Scaling up worker pool", "added", count)
 
 	currentSize := len(p.consumers)
 
@@ -232,6 +330,22 @@ func (p *Pool) ScaleUp(count int) error {
 			p.dockerService,
 			workerID,
 		)
+		consumer.SetMaxRetries(p.maxRetries)
+		consumer.SetPrefetcher(p.prefetcher)
+		consumer.SetSuccessOutputTail(p.successTail)
+		consumer.SetNodeID(p.nodeID)
+		consumer.SetStartSLO(p.startSLO)
+		consumer.SetImageProfiles(p.imageProfiles)
+		consumer.SetDependencyResolver(p.dependencies)
+		consumer.SetContainerLimiter(p.limiter)
+		if p.jobTimeout > 0 {
+			consumer.SetJobTimeout(p.jobTimeout)
+		}
+		consumer.SetStepTimeout(p.stepTimeout)
+		if p.pollInterval > 0 {
+			consumer.SetPollInterval(p.pollInterval)
+		}
+		consumer.SetPollMaxInterval(p.pollMaxInterval)
 
 		p.consumers = append(p.consumers, consumer)
 		p.size++
@@ -240,13 +354,13 @@ func (p *Pool) ScaleUp(count int) error {
 		p.wg.Add(1)
 		go func(c *Consumer, id string) {
 			defer p.wg.Done()
-			log.Printf("[%s] Worker started", id)
+			slog.Info("Worker started", logging.KeyWorkerID, id)
 			c.Start(p.ctx)
-			log.Printf("[%s] Worker stopped", id)
+			slog.Info("Worker stopped", logging.KeyWorkerID, id)
 		}(consumer, workerID)
 	}
 
-	log.Printf("Scaled up to %d workers", p.size)
+	slog.Info("Scaled up worker pool", "workers", p.size)
 	return nil
 }
 
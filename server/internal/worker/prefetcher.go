@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+)
+
+// ImagePuller pulls container images ahead of execution
+type ImagePuller interface {
+	PullImage(ctx context.Context, imageName string) error
+}
+
+// UpcomingJobs exposes the head of the immediate queue without dequeuing
+type UpcomingJobs interface {
+	PeekImmediate(ctx context.Context, limit int64) ([]*queue.QueueItem, error)
+}
+
+// Prefetcher pulls the images of upcoming jobs while the current job runs.
+// It only peeks at the queue, so a job whose image was prefetched is still picked up
+// (or requeued) through the normal dequeue path; the worst case is an unused image pull.
+type Prefetcher struct {
+	jobs        UpcomingJobs
+	puller      ImagePuller
+	depth       int           // Number of upcoming jobs to look at
+	pullTimeout time.Duration // Upper bound for a single prefetch pull
+
+	mu       sync.Mutex
+	inFlight map[string]bool // Images currently being pulled, shared across consumers
+}
+
+// NewPrefetcher creates an image prefetcher that looks depth jobs ahead
+func NewPrefetcher(jobs UpcomingJobs, puller ImagePuller, depth int) *Prefetcher {
+	if depth < 1 {
+		depth = 1
+	}
+	return &Prefetcher{
+		jobs:        jobs,
+		puller:      puller,
+		depth:       depth,
+		pullTimeout: 5 * time.Minute,
+		inFlight:    make(map[string]bool),
+	}
+}
+
+// Prefetch pulls the images of the next queued jobs, skipping currentImage (already
+// present for the running job) and images another consumer is already pulling.
+// It blocks until the pulls finish and returns the images it pulled.
+func (p *Prefetcher) Prefetch(ctx context.Context, currentImage string) []string {
+	items, err := p.jobs.PeekImmediate(ctx, int64(p.depth))
+	if err != nil {
+		slog.Warn("Image prefetch failed to peek queue", logging.Err(err))
+		return nil
+	}
+
+	var pulled []string
+	seen := map[string]bool{currentImage: true}
+	for _, item := range items {
+		image := item.DockerImage
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+
+		if !p.claim(image) {
+			continue
+		}
+
+		pullCtx, cancel := context.WithTimeout(ctx, p.pullTimeout)
+		err := p.puller.PullImage(pullCtx, image)
+		cancel()
+		p.release(image)
+
+		if err != nil {
+			slog.Warn("Failed to prefetch image", "image", image, logging.KeyJobID, item.JobID, logging.Err(err))
+			continue
+		}
+		pulled = append(pulled, image)
+	}
+
+	return pulled
+}
+
+// claim marks an image as being pulled; it returns false if a pull is already in flight
+func (p *Prefetcher) claim(image string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight[image] {
+		return false
+	}
+	p.inFlight[image] = true
+	return true
+}
+
+// release clears the in-flight mark for an image
+func (p *Prefetcher) release(image string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inFlight, image)
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+)
+
+// fakeUpcomingJobs serves a fixed queue head
+type fakeUpcomingJobs struct {
+	items []*queue.QueueItem
+}
+
+func (f *fakeUpcomingJobs) PeekImmediate(ctx context.Context, limit int64) ([]*queue.QueueItem, error) {
+	if int64(len(f.items)) > limit {
+		return f.items[:limit], nil
+	}
+	return f.items, nil
+}
+
+// fakePuller records pulled images, optionally blocking until released
+type fakePuller struct {
+	mu      sync.Mutex
+	pulled  []string
+	block   chan struct{}
+	started chan string
+}
+
+func (f *fakePuller) PullImage(ctx context.Context, imageName string) error {
+	if f.started != nil {
+		f.started <- imageName
+	}
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulled = append(f.pulled, imageName)
+	return nil
+}
+
+func TestPrefetcher_PullsNextJobImage(t *testing.T) {
+	jobs := &fakeUpcomingJobs{items: []*queue.QueueItem{
+		{JobID: "next", DockerImage: "python:3.11"},
+		{JobID: "later", DockerImage: "node:20"},
+	}}
+	puller := &fakePuller{}
+
+	pulled := NewPrefetcher(jobs, puller, 1).Prefetch(context.Background(), "alpine:latest")
+
+	if want := []string{"python:3.11"}; !reflect.DeepEqual(pulled, want) || !reflect.DeepEqual(puller.pulled, want) {
+		t.Errorf("expected only the next job's image to be pulled, got %v (puller saw %v)", pulled, puller.pulled)
+	}
+	if len(jobs.items) != 2 {
+		t.Error("prefetching must not remove jobs from the queue")
+	}
+}
+
+func TestPrefetcher_SkipsCurrentAndDuplicateImages(t *testing.T) {
+	jobs := &fakeUpcomingJobs{items: []*queue.QueueItem{
+		{JobID: "a", DockerImage: "alpine:latest"},
+		{JobID: "b", DockerImage: "python:3.11"},
+		{JobID: "c", DockerImage: "python:3.11"},
+	}}
+	puller := &fakePuller{}
+
+	pulled := NewPrefetcher(jobs, puller, 3).Prefetch(context.Background(), "alpine:latest")
+
+	if want := []string{"python:3.11"}; !reflect.DeepEqual(pulled, want) {
+		t.Errorf("expected a single pull of python:3.11, got %v", pulled)
+	}
+}
+
+func TestPrefetcher_SkipsImageAlreadyInFlight(t *testing.T) {
+	jobs := &fakeUpcomingJobs{items: []*queue.QueueItem{{JobID: "next", DockerImage: "python:3.11"}}}
+	puller := &fakePuller{block: make(chan struct{}), started: make(chan string, 1)}
+	prefetcher := NewPrefetcher(jobs, puller, 1)
+
+	done := make(chan []string)
+	go func() { done <- prefetcher.Prefetch(context.Background(), "") }()
+
+	select {
+	case <-puller.started:
+	case <-time.After(time.Second):
+		t.Fatal("first prefetch never started pulling")
+	}
+
+	// A second consumer prefetching the same image must not start another pull
+	if pulled := prefetcher.Prefetch(context.Background(), ""); len(pulled) != 0 {
+		t.Errorf("expected in-flight image to be skipped, got %v", pulled)
+	}
+
+	close(puller.block)
+	if pulled := <-done; len(pulled) != 1 {
+		t.Errorf("expected first prefetch to pull one image, got %v", pulled)
+	}
+}
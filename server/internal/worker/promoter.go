@@ -2,21 +2,48 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"github.com/Sambit-Mondal/karbos/server/internal/acceptance"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
 	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/google/uuid"
 )
 
+// jobStatusUpdater is the subset of JobRepository the promoter uses to reschedule
+// jobs orphaned by dead workers
+type jobStatusUpdater interface {
+	UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error
+}
+
 // PromoterService moves delayed jobs to immediate queue when scheduled time arrives
 type PromoterService struct {
 	queue         *queue.RedisQueue
 	checkInterval time.Duration
 	stopChan      chan struct{}
 	doneChan      chan struct{}
+
+	paused atomic.Bool // Set while no workers are heartbeating
+
+	windows       *acceptance.Schedule // Optional: due jobs are only promoted inside these windows
+	outsideWindow atomic.Bool          // Set while promotion waits for the next window
+
+	staleAfter time.Duration    // Claimed jobs of dead worker nodes older than this are requeued
+	jobs       jobStatusUpdater // Optional: moves orphaned RUNNING jobs back to PENDING and fails expired ones
+
+	logs         executionLogWriter  // Optional: records why an expired job failed
+	dependencies *DependencyResolver // Optional: settles jobs waiting on an expired one
 }
 
+// defaultStaleAfter gives a dead node's claims time to show up as unacknowledged; a
+// node's heartbeat expires after 15 seconds
+const defaultStaleAfter = time.Minute
+
 // NewPromoterService creates a new delayed job promoter service
 func NewPromoterService(queue *queue.RedisQueue, checkInterval time.Duration) *PromoterService {
 	if checkInterval == 0 {
@@ -27,12 +54,37 @@ func NewPromoterService(queue *queue.RedisQueue, checkInterval time.Duration) *P
 		checkInterval: checkInterval,
 		stopChan:      make(chan struct{}),
 		doneChan:      make(chan struct{}),
+		staleAfter:    defaultStaleAfter,
 	}
 }
 
+// SetJobStore makes the promoter mark jobs it recovers from dead workers as PENDING,
+// instead of leaving them RUNNING until another worker picks them up. It also lets the
+// promoter fail delayed jobs whose deadline has passed rather than promote them.
+func (p *PromoterService) SetJobStore(jobs jobStatusUpdater) {
+	p.jobs = jobs
+}
+
+// SetExecutionLogs records the reason on delayed jobs failed for missing their deadline
+func (p *PromoterService) SetExecutionLogs(logs executionLogWriter) {
+	p.logs = logs
+}
+
+// SetDependencyResolver settles the jobs waiting on a delayed job failed for missing
+// its deadline
+func (p *PromoterService) SetDependencyResolver(resolver *DependencyResolver) {
+	p.dependencies = resolver
+}
+
+// SetPromotionWindows holds due delayed jobs in the delayed queue outside the
+// schedule's windows, promoting them once the next window opens
+func (p *PromoterService) SetPromotionWindows(schedule *acceptance.Schedule) {
+	p.windows = schedule
+}
+
 // Start begins the promoter service loop
 func (p *PromoterService) Start(ctx context.Context) error {
-	log.Printf("🚀 Starting delayed job promoter service (interval: %s)", p.checkInterval)
+	slog.Info("Starting delayed job promoter service", "interval", p.checkInterval)
 
 	go p.run(ctx)
 
@@ -41,15 +93,15 @@ func (p *PromoterService) Start(ctx context.Context) error {
 
 // Stop gracefully stops the promoter service
 func (p *PromoterService) Stop() {
-	log.Println("🛑 Stopping delayed job promoter service...")
+	slog.Info("Stopping delayed job promoter service")
 	close(p.stopChan)
 
 	// Wait for service to finish with timeout
 	select {
 	case <-p.doneChan:
-		log.Println("✓ Delayed job promoter service stopped")
+		slog.Info("Delayed job promoter service stopped")
 	case <-time.After(5 * time.Second):
-		log.Println("⚠ Delayed job promoter service stop timeout")
+		slog.Warn("Delayed job promoter service stop timed out")
 	}
 }
 
@@ -60,19 +112,22 @@ func (p *PromoterService) run(ctx context.Context) {
 	ticker := time.NewTicker(p.checkInterval)
 	defer ticker.Stop()
 
-	log.Println("✓ Delayed job promoter service started")
+	slog.Info("Delayed job promoter service started")
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Context cancelled, stopping promoter service")
+			slog.Info("Context cancelled, stopping promoter service")
 			return
 		case <-p.stopChan:
-			log.Println("Stop signal received, stopping promoter service")
+			slog.Info("Stop signal received, stopping promoter service")
 			return
 		case <-ticker.C:
 			if err := p.promoteReadyJobs(ctx); err != nil {
-				log.Printf("⚠ Error promoting jobs: %v", err)
+				slog.Error("Error promoting jobs", logging.Err(err))
+			}
+			if err := p.requeueStaleJobs(ctx); err != nil {
+				slog.Error("Error recovering jobs from dead workers", logging.Err(err))
 			}
 		}
 	}
@@ -80,8 +135,34 @@ func (p *PromoterService) run(ctx context.Context) {
 
 // promoteReadyJobs checks delayed queue and promotes jobs whose scheduled time has arrived
 func (p *PromoterService) promoteReadyJobs(ctx context.Context) error {
-	// Get all jobs from delayed queue that are ready (score <= current timestamp)
+	// Hold jobs in the delayed queue until a worker is around to run them
+	workers, err := p.queue.GetActiveWorkers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check active workers: %w", err)
+	}
+	if len(workers) == 0 {
+		if !p.paused.Swap(true) {
+			slog.Warn("No active workers, pausing promotion of delayed jobs until one heartbeats")
+		}
+		return nil
+	}
+	if p.paused.Swap(false) {
+		slog.Info("Active workers found, resuming promotion of delayed jobs", "workers", len(workers))
+	}
+
+	// Hold due jobs until the next promotion window
 	now := time.Now()
+	if !p.windows.Open(now) {
+		if !p.outsideWindow.Swap(true) {
+			slog.Warn("Outside the promotion window, deferring delayed jobs", "next_window", p.windows.NextOpen(now))
+		}
+		return nil
+	}
+	if p.outsideWindow.Swap(false) {
+		slog.Info("Promotion window open, resuming promotion of delayed jobs")
+	}
+
+	// Get all jobs from delayed queue that are ready (score <= current timestamp)
 	items, err := p.queue.GetReadyDelayedJobs(ctx, now)
 	if err != nil {
 		return fmt.Errorf("failed to get ready delayed jobs: %w", err)
@@ -91,27 +172,75 @@ func (p *PromoterService) promoteReadyJobs(ctx context.Context) error {
 		return nil // No jobs ready for promotion
 	}
 
-	log.Printf("⚡ Found %d jobs ready for promotion", len(items))
+	slog.Info("Found jobs ready for promotion", "jobs", len(items))
 
-	// Promote each ready job
+	// Promote each ready job, failing the ones already past their deadline
 	promoted := 0
+	expired := 0
 	failed := 0
 
 	for _, item := range items {
+		if p.jobs != nil && pastDeadline(item.Deadline, now) {
+			if err := p.expireJob(ctx, item); err != nil {
+				slog.WarnContext(logging.WithRequestID(ctx, item.RequestID), "Failed to fail expired job", logging.KeyJobID, item.JobID, logging.Err(err))
+				failed++
+			} else {
+				expired++
+			}
+			continue
+		}
 		if err := p.promoteJob(ctx, item); err != nil {
-			log.Printf("⚠ Failed to promote job %s: %v", item.JobID, err)
+			slog.WarnContext(logging.WithRequestID(ctx, item.RequestID), "Failed to promote job", logging.KeyJobID, item.JobID, logging.Err(err))
 			failed++
 		} else {
 			promoted++
 		}
 	}
 
-	log.Printf("✓ Promoted %d jobs, %d failed", promoted, failed)
+	slog.Info("Promoted delayed jobs", "promoted", promoted, "expired", expired, "failed", failed)
 	return nil
 }
 
+// requeueStaleJobs puts jobs claimed by worker nodes that stopped heartbeating back
+// on the immediate queue, marking the ones still RUNNING for re-execution first
+func (p *PromoterService) requeueStaleJobs(ctx context.Context) error {
+	var beforeRequeue func(*queue.QueueItem)
+	if p.jobs != nil {
+		beforeRequeue = func(item *queue.QueueItem) { p.markForRerun(ctx, item) }
+	}
+
+	requeued, err := p.queue.RequeueStaleProcessing(ctx, p.staleAfter, beforeRequeue)
+	if requeued > 0 {
+		slog.Info("Recovered jobs from dead workers", "jobs", requeued)
+	}
+	return err
+}
+
+// markForRerun moves an orphaned job from RUNNING back to PENDING. It runs before the job
+// is requeued, so no worker can have started it again yet. Jobs that never got to
+// RUNNING, or finished before their worker died, are left as they are.
+func (p *PromoterService) markForRerun(ctx context.Context, item *queue.QueueItem) {
+	ctx = logging.WithRequestID(ctx, item.RequestID)
+	jobID, err := uuid.Parse(item.JobID)
+	if err != nil {
+		slog.WarnContext(ctx, "Orphaned job has an invalid ID", logging.KeyJobID, item.JobID, logging.Err(err))
+		return
+	}
+	err = p.jobs.UpdateJobStatusChecked(ctx, jobID, models.JobStatusPending)
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "Job orphaned by a dead worker, marked PENDING for re-execution", logging.KeyJobID, item.JobID)
+	case errors.Is(err, models.ErrIllegalTransition):
+		// Not RUNNING; the consumer's status checks handle it when it runs again
+	default:
+		slog.WarnContext(ctx, "Failed to reset orphaned job to PENDING", logging.KeyJobID, item.JobID, logging.Err(err))
+	}
+}
+
 // promoteJob moves a single job from delayed queue to immediate queue
 func (p *PromoterService) promoteJob(ctx context.Context, item *queue.QueueItem) error {
+	ctx = logging.WithRequestID(ctx, item.RequestID)
+
 	// Add to immediate queue
 	if err := p.queue.EnqueueImmediate(ctx, item); err != nil {
 		return fmt.Errorf("failed to enqueue to immediate queue: %w", err)
@@ -120,10 +249,59 @@ func (p *PromoterService) promoteJob(ctx context.Context, item *queue.QueueItem)
 	// Remove from delayed queue
 	if err := p.queue.RemoveFromDelayed(ctx, item.JobID); err != nil {
 		// Log error but don't fail - job is already in immediate queue
-		log.Printf("⚠ Failed to remove job %s from delayed queue: %v", item.JobID, err)
+		slog.WarnContext(ctx, "Failed to remove job from delayed queue", logging.KeyJobID, item.JobID, logging.Err(err))
+	}
+
+	slog.InfoContext(ctx, "Promoted job from delayed to immediate queue", logging.KeyJobID, item.JobID)
+	return nil
+}
+
+// expireJob fails a delayed job whose deadline passed before it was due, instead of
+// promoting it to run late, then settles the jobs waiting on it
+func (p *PromoterService) expireJob(ctx context.Context, item *queue.QueueItem) error {
+	ctx = logging.WithRequestID(ctx, item.RequestID)
+	jobID, err := uuid.Parse(item.JobID)
+	if err != nil {
+		return fmt.Errorf("invalid job ID: %w", err)
+	}
+
+	err = p.jobs.UpdateJobStatusChecked(ctx, jobID, models.JobStatusFailed)
+	switch {
+	case errors.Is(err, models.ErrIllegalTransition):
+		// Cancelled or already finished; just drop the stale entry
+		return p.queue.RemoveFromDelayed(ctx, item.JobID)
+	case err != nil:
+		return fmt.Errorf("failed to mark job FAILED: %w", err)
+	}
+
+	if err := p.queue.RemoveFromDelayed(ctx, item.JobID); err != nil {
+		slog.WarnContext(ctx, "Failed to remove job from delayed queue", logging.KeyJobID, item.JobID, logging.Err(err))
+	}
+
+	if p.logs != nil {
+		now := time.Now()
+		reason := deadlineExceededMsg
+		executionLog := &models.ExecutionLog{
+			ID:           uuid.New(),
+			JobID:        jobID,
+			StartedAt:    now,
+			CompletedAt:  &now,
+			ErrorMessage: &reason,
+		}
+		if err := p.logs.CreateExecutionLog(ctx, executionLog); err != nil {
+			slog.WarnContext(ctx, "Failed to save execution log", logging.KeyJobID, item.JobID, logging.Err(err))
+		}
+	}
+	if err := p.queue.PublishJobLogEnd(ctx, item.JobID, string(models.JobStatusFailed)); err != nil {
+		slog.WarnContext(ctx, "Failed to publish log end", logging.KeyJobID, item.JobID, logging.Err(err))
+	}
+	if p.dependencies != nil {
+		if err := p.dependencies.JobFinished(ctx, jobID); err != nil {
+			slog.ErrorContext(ctx, "Failed to settle dependent jobs", logging.KeyJobID, item.JobID, logging.Err(err))
+		}
 	}
 
-	log.Printf("✓ Promoted job %s from delayed to immediate queue", item.JobID)
+	slog.WarnContext(ctx, "Delayed job failed, deadline passed before promotion", logging.KeyJobID, item.JobID, "deadline", item.Deadline)
 	return nil
 }
 
@@ -137,6 +315,8 @@ func (p *PromoterService) GetStatus(ctx context.Context) (map[string]interface{}
 
 	status := map[string]interface{}{
 		"running":        true,
+		"paused":         p.paused.Load(),
+		"outside_window": p.outsideWindow.Load(),
 		"check_interval": p.checkInterval.String(),
 		"delayed_jobs":   stats["total_delayed_jobs"],
 		"ready_jobs":     stats["ready_jobs"],
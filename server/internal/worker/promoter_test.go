@@ -0,0 +1,231 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/acceptance"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+)
+
+func newPromoterTestQueue(t *testing.T) *queue.RedisQueue {
+	t.Helper()
+
+	q, _ := newPromoterTestRedis(t)
+	return q
+}
+
+func newPromoterTestRedis(t *testing.T) (*queue.RedisQueue, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	q, err := queue.NewRedisQueue(server.Addr(), "", 0, "test:immediate", "test:delayed", "test:dead")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+
+	return q, server
+}
+
+// fakeJobStatuses is an in-memory job status table enforcing the status graph
+type fakeJobStatuses map[uuid.UUID]models.JobStatus
+
+func (f fakeJobStatuses) UpdateJobStatusChecked(ctx context.Context, id uuid.UUID, status models.JobStatus) error {
+	current, ok := f[id]
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	if !models.CanTransition(current, status) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, current, status)
+	}
+	f[id] = status
+	return nil
+}
+
+func TestPromoter_PausesWithoutActiveWorkers(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+	p := NewPromoterService(q, time.Second)
+
+	due := &queue.QueueItem{JobID: "due", DockerImage: "alpine:latest", ScheduledTime: time.Now().Add(-time.Minute)}
+	if err := q.EnqueueDelayed(ctx, due); err != nil {
+		t.Fatalf("enqueue delayed: %v", err)
+	}
+
+	if err := p.promoteReadyJobs(ctx); err != nil {
+		t.Fatalf("promoteReadyJobs returned error: %v", err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("expected no promotion without workers, immediate queue has %d", length)
+	}
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 1 {
+		t.Errorf("expected job to stay delayed, delayed queue has %d", length)
+	}
+	if !p.paused.Load() {
+		t.Error("expected promoter to report paused")
+	}
+
+	if err := q.SetWorkerHeartbeat(ctx, "worker-1", 15); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+
+	if err := p.promoteReadyJobs(ctx); err != nil {
+		t.Fatalf("promoteReadyJobs returned error: %v", err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 1 {
+		t.Errorf("expected job promoted once a worker is active, immediate queue has %d", length)
+	}
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 0 {
+		t.Errorf("expected delayed queue to be drained, has %d", length)
+	}
+	if p.paused.Load() {
+		t.Error("expected promoter to resume")
+	}
+}
+
+func TestPromoter_ReschedulesJobsOfDeadWorker(t *testing.T) {
+	q, server := newPromoterTestRedis(t)
+	ctx := context.Background()
+
+	running, finished := uuid.New(), uuid.New()
+	jobs := fakeJobStatuses{running: models.JobStatusRunning, finished: models.JobStatusCompleted}
+	p := NewPromoterService(q, time.Second)
+	p.SetJobStore(jobs)
+	p.staleAfter = 0
+
+	// node-a claims both jobs; one finished before the node died but was never acknowledged
+	if err := q.SetWorkerHeartbeat(ctx, "node-a", 15); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	for _, id := range []uuid.UUID{running, finished} {
+		if err := q.EnqueueImmediate(ctx, &queue.QueueItem{JobID: id.String(), DockerImage: "alpine:latest"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		if _, err := q.ClaimImmediate(ctx, "node-a"); err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+	}
+
+	// Jobs of a heartbeating worker are left alone
+	if err := p.requeueStaleJobs(ctx); err != nil {
+		t.Fatalf("requeueStaleJobs: %v", err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 || jobs[running] != models.JobStatusRunning {
+		t.Fatalf("live worker's job requeued: queue=%d status=%s", length, jobs[running])
+	}
+
+	// node-a dies and its heartbeat expires
+	server.FastForward(16 * time.Second)
+
+	if err := p.requeueStaleJobs(ctx); err != nil {
+		t.Fatalf("requeueStaleJobs: %v", err)
+	}
+	if jobs[running] != models.JobStatusPending {
+		t.Errorf("orphaned job status = %s, want PENDING", jobs[running])
+	}
+	if jobs[finished] != models.JobStatusCompleted {
+		t.Errorf("finished job status = %s, want COMPLETED", jobs[finished])
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 2 {
+		t.Errorf("immediate queue has %d items, want both orphaned jobs", length)
+	}
+}
+
+func TestPromoter_DefersPromotionOutsideWindow(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+	p := NewPromoterService(q, time.Second)
+
+	// The only promotion window opens two hours from now
+	now := time.Now().UTC()
+	closed, err := acceptance.Parse(fmt.Sprintf("* %s-%s",
+		now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04")), nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	p.SetPromotionWindows(closed)
+
+	if err := q.SetWorkerHeartbeat(ctx, "worker-1", 15); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	due := &queue.QueueItem{JobID: "due", DockerImage: "alpine:latest", ScheduledTime: now.Add(-time.Minute)}
+	if err := q.EnqueueDelayed(ctx, due); err != nil {
+		t.Fatalf("enqueue delayed: %v", err)
+	}
+
+	if err := p.promoteReadyJobs(ctx); err != nil {
+		t.Fatalf("promoteReadyJobs returned error: %v", err)
+	}
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 1 {
+		t.Errorf("expected the due job to wait outside the window, delayed queue has %d", length)
+	}
+	if !p.outsideWindow.Load() {
+		t.Error("expected promoter to report it is outside the window")
+	}
+
+	open, _ := acceptance.Parse("* 00:00-24:00", nil)
+	p.SetPromotionWindows(open)
+
+	if err := p.promoteReadyJobs(ctx); err != nil {
+		t.Fatalf("promoteReadyJobs returned error: %v", err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 1 {
+		t.Errorf("expected the job promoted inside the window, immediate queue has %d", length)
+	}
+	if p.outsideWindow.Load() {
+		t.Error("expected promoter to resume")
+	}
+}
+
+func TestPromoter_FailsJobsPastDeadline(t *testing.T) {
+	q := newPromoterTestQueue(t)
+	ctx := context.Background()
+
+	expired, onTime := uuid.New(), uuid.New()
+	jobs := fakeJobStatuses{expired: models.JobStatusDelayed, onTime: models.JobStatusDelayed}
+	logs := fakeExecutionLogs{}
+	p := NewPromoterService(q, time.Second)
+	p.SetJobStore(jobs)
+	p.SetExecutionLogs(logs)
+
+	if err := q.SetWorkerHeartbeat(ctx, "worker-1", 15); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	// Both are due, but the promoter only gets to one after its deadline
+	now := time.Now()
+	for id, deadline := range map[uuid.UUID]time.Time{expired: now.Add(-time.Minute), onTime: now.Add(time.Hour)} {
+		item := &queue.QueueItem{JobID: id.String(), DockerImage: "alpine:latest", ScheduledTime: now.Add(-2 * time.Minute), Deadline: deadline}
+		if err := q.EnqueueDelayed(ctx, item); err != nil {
+			t.Fatalf("enqueue delayed: %v", err)
+		}
+	}
+
+	if err := p.promoteReadyJobs(ctx); err != nil {
+		t.Fatalf("promoteReadyJobs returned error: %v", err)
+	}
+	if jobs[expired] != models.JobStatusFailed {
+		t.Errorf("expired job status = %s, want FAILED", jobs[expired])
+	}
+	if log := logs[expired]; log == nil || log.ErrorMessage == nil || *log.ErrorMessage != deadlineExceededMsg {
+		t.Errorf("expired job execution log = %+v, want %q", log, deadlineExceededMsg)
+	}
+	if jobs[onTime] != models.JobStatusDelayed {
+		t.Errorf("on-time job status = %s, want it left DELAYED", jobs[onTime])
+	}
+	if length, _ := q.GetDelayedQueueLength(ctx); length != 0 {
+		t.Errorf("delayed queue has %d items, want both removed", length)
+	}
+	item, err := q.DequeueImmediate(ctx)
+	if err != nil || item == nil || item.JobID != onTime.String() {
+		t.Errorf("immediate queue head = %+v (err %v), want only the on-time job", item, err)
+	}
+	if length, _ := q.GetImmediateQueueLength(ctx); length != 0 {
+		t.Errorf("immediate queue has %d more items, want the expired job not promoted", length)
+	}
+}
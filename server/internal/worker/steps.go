@@ -0,0 +1,206 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/logging"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+	"github.com/Sambit-Mondal/karbos/server/internal/queue"
+)
+
+// Step is one stage of a multi-step job. Run must return promptly once ctx is done
+// (container runs do, since they are bound to the context).
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// StepResult records how a single step went
+type StepResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// StepTimeoutError reports the step that exceeded its own timeout
+type StepTimeoutError struct {
+	Index   int // 1-based position of the step
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *StepTimeoutError) Error() string {
+	return fmt.Sprintf("step %d (%s) timed out after %s", e.Index, e.Name, e.Timeout)
+}
+
+// RunSteps runs steps in order, stopping at the first failure. Each step gets its own
+// timeout (stepTimeout, or jobTimeout divided by the number of steps when zero) so a hung
+// step fails the job fast instead of silently consuming the whole job timeout. A zero
+// jobTimeout means no job deadline; with no stepTimeout either, steps run unbounded.
+// The results cover every step that was started, including the failing one.
+func RunSteps(ctx context.Context, steps []Step, jobTimeout, stepTimeout time.Duration) ([]StepResult, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	if stepTimeout <= 0 && jobTimeout > 0 {
+		stepTimeout = jobTimeout / time.Duration(len(steps))
+	}
+
+	jobCtx, cancel := withOptionalTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	results := make([]StepResult, 0, len(steps))
+	for i, step := range steps {
+		stepCtx, cancelStep := withOptionalTimeout(jobCtx, stepTimeout)
+		start := time.Now()
+		err := step.Run(stepCtx)
+		stepErr := stepCtx.Err()
+		cancelStep()
+
+		results = append(results, StepResult{Name: step.Name, Duration: time.Since(start), Err: err})
+
+		// Only blame the step when its own deadline fired, not the job's or the caller's
+		if errors.Is(stepErr, context.DeadlineExceeded) && jobCtx.Err() == nil {
+			timeoutErr := &StepTimeoutError{Index: i + 1, Name: step.Name, Timeout: stepTimeout}
+			results[i].Err = timeoutErr
+			return results, timeoutErr
+		}
+		if err != nil {
+			return results, fmt.Errorf("step %d (%s) failed: %w", i+1, step.Name, err)
+		}
+	}
+
+	return results, nil
+}
+
+// withOptionalTimeout is context.WithTimeout, except that a zero or negative timeout
+// sets no deadline
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// maxStepNameLength bounds the step names recorded in logs and execution logs
+const maxStepNameLength = 64
+
+// stepRunner runs one step's command as a container, streaming its output lines to lines
+// and closing it on return, like docker.Service.RunContainerStreaming
+type stepRunner func(ctx context.Context, command []string, lines chan<- string) (*docker.ContainerResult, error)
+
+// runJobSteps runs a multi-step job: each of the item's steps is a container of the job's
+// image, run in order under RunSteps. The containers' results are combined into one.
+// Output lines of every step go to lines, which is closed on return.
+func (c *Consumer) runJobSteps(ctx context.Context, image string, item *queue.QueueItem, profile *ImageProfile, lines chan<- string) (*docker.ContainerResult, []StepResult, error) {
+	run := func(ctx context.Context, command []string, stepLines chan<- string) (*docker.ContainerResult, error) {
+		return c.dockerService.RunContainerStreaming(ctx, image, command, item.Entrypoint, ResourceLimits(item, profile), item.Volumes, item.NetworkAccess, stepLines)
+	}
+	result, steps, err := runCommandSteps(ctx, item.Steps, c.timeoutFor(item), c.stepTimeout, run, lines)
+
+	for i, step := range steps {
+		attrs := []any{logging.KeyJobID, item.JobID, "step", i + 1, "step_name", step.Name, logging.KeyDuration, step.Duration}
+		if step.Err != nil {
+			c.logger().WarnContext(ctx, "Job step failed", append(attrs, logging.Err(step.Err))...)
+			continue
+		}
+		c.logger().InfoContext(ctx, "Job step finished", attrs...)
+	}
+	return result, steps, err
+}
+
+// runCommandSteps runs each command with run, one after another, stopping at the first
+// that fails. A container that runs but fails (non-zero exit, OOM kill) stops the job like
+// an error does. The returned result combines the containers': output is concatenated,
+// durations and CPU time add up, peak memory is the highest seen, and the exit code is
+// the last step's.
+func runCommandSteps(ctx context.Context, commands [][]string, jobTimeout, stepTimeout time.Duration, run stepRunner, lines chan<- string) (*docker.ContainerResult, []StepResult, error) {
+	defer close(lines)
+
+	combined := &docker.ContainerResult{}
+	steps := make([]Step, len(commands))
+	for i, command := range commands {
+		steps[i] = Step{Name: stepName(command), Run: func(ctx context.Context) error {
+			stepLines := make(chan string, cap(lines))
+			forwarded := make(chan struct{})
+			go func() {
+				defer close(forwarded)
+				for line := range stepLines {
+					lines <- line
+				}
+			}()
+
+			result, err := run(ctx, command, stepLines)
+			<-forwarded
+			addStepResult(combined, result)
+			if err != nil {
+				return err
+			}
+			if status, message := evaluateResult(result, nil); status != models.JobStatusCompleted {
+				return errors.New(message)
+			}
+			return nil
+		}}
+	}
+
+	results, err := RunSteps(ctx, steps, jobTimeout, stepTimeout)
+	return combined, results, err
+}
+
+// addStepResult folds one step's container result into the job's combined result
+func addStepResult(total, step *docker.ContainerResult) {
+	if step == nil {
+		return
+	}
+	if total.StartedAt.IsZero() {
+		total.StartedAt = step.StartedAt
+	}
+	total.Output += step.Output
+	total.ExitCode = step.ExitCode
+	total.Duration += step.Duration
+	total.OOMKilled = step.OOMKilled
+	total.Truncated = total.Truncated || step.Truncated
+	total.Error = step.Error
+	if step.Usage != nil {
+		if total.Usage == nil {
+			total.Usage = &docker.ResourceUsage{}
+		}
+		total.Usage.PeakMemoryBytes = max(total.Usage.PeakMemoryBytes, step.Usage.PeakMemoryBytes)
+		total.Usage.CPUSeconds += step.Usage.CPUSeconds
+	}
+}
+
+// stepName names a step after its command, shortened for logs
+func stepName(command []string) string {
+	name := strings.Join(command, " ")
+	if len(name) > maxStepNameLength {
+		end := maxStepNameLength - 3
+		for end > 0 && !utf8.RuneStart(name[end]) {
+			end--
+		}
+		name = name[:end] + "..."
+	}
+	return name
+}
+
+// stepRecords converts step results into the form stored on an execution log
+func stepRecords(results []StepResult) []models.StepRecord {
+	if len(results) == 0 {
+		return nil
+	}
+	records := make([]models.StepRecord, len(results))
+	for i, result := range results {
+		records[i] = models.StepRecord{Name: result.Name, DurationMs: result.Duration.Milliseconds()}
+		if result.Err != nil {
+			message := result.Err.Error()
+			records[i].Error = &message
+		}
+	}
+	return records
+}
@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sambit-Mondal/karbos/server/internal/docker"
+	"github.com/Sambit-Mondal/karbos/server/internal/models"
+)
+
+func sleepStep(name string, d time.Duration) Step {
+	return Step{Name: name, Run: func(ctx context.Context) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+}
+
+func TestRunSteps_AttributesTimeoutToHungStep(t *testing.T) {
+	steps := []Step{
+		sleepStep("fetch", 5*time.Millisecond),
+		sleepStep("build", time.Hour), // hangs
+		sleepStep("publish", 5*time.Millisecond),
+	}
+
+	start := time.Now()
+	results, err := RunSteps(context.Background(), steps, 300*time.Millisecond, 0)
+	elapsed := time.Since(start)
+
+	var timeoutErr *StepTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a StepTimeoutError, got %v", err)
+	}
+	if timeoutErr.Index != 2 || timeoutErr.Name != "build" {
+		t.Errorf("timeout attributed to step %d (%s), want step 2 (build)", timeoutErr.Index, timeoutErr.Name)
+	}
+	if timeoutErr.Timeout != 100*time.Millisecond {
+		t.Errorf("default step timeout = %s, want job timeout / 3 steps = 100ms", timeoutErr.Timeout)
+	}
+	if elapsed >= 250*time.Millisecond {
+		t.Errorf("job took %s, expected it to fail fast on the step timeout", elapsed)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected results for the two started steps, got %+v", results)
+	}
+	if results[0].Err != nil || results[0].Duration <= 0 {
+		t.Errorf("unexpected result for step one: %+v", results[0])
+	}
+	if results[1].Err != timeoutErr || results[1].Duration < 100*time.Millisecond {
+		t.Errorf("unexpected result for step two: %+v", results[1])
+	}
+}
+
+func TestRunSteps_ExplicitStepTimeoutAndSuccess(t *testing.T) {
+	steps := []Step{sleepStep("a", time.Millisecond), sleepStep("b", time.Millisecond)}
+
+	results, err := RunSteps(context.Background(), steps, time.Second, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RunSteps returned error: %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "a" || results[1].Name != "b" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestRunSteps_StepErrorIsWrapped(t *testing.T) {
+	boom := errors.New("boom")
+	steps := []Step{{Name: "only", Run: func(ctx context.Context) error { return boom }}}
+
+	_, err := RunSteps(context.Background(), steps, time.Second, 0)
+	if !errors.Is(err, boom) {
+		t.Errorf("expected the step error to be wrapped, got %v", err)
+	}
+	var timeoutErr *StepTimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Errorf("a failing step must not be reported as a timeout")
+	}
+}
+
+func TestRunSteps_ZeroJobTimeoutSetsNoDeadline(t *testing.T) {
+	steps := []Step{sleepStep("a", 20*time.Millisecond), sleepStep("b", 20*time.Millisecond)}
+
+	results, err := RunSteps(context.Background(), steps, 0, 0)
+	if err != nil {
+		t.Fatalf("expected steps to run without a deadline, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected both steps to run, got %+v", results)
+	}
+
+	// A step timeout still applies on its own
+	_, err = RunSteps(context.Background(), []Step{sleepStep("hung", time.Hour)}, 0, 20*time.Millisecond)
+	var timeoutErr *StepTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Index != 1 {
+		t.Errorf("expected step 1 to time out under the step timeout, got %v", err)
+	}
+}
+
+// fakeStepRunner stands in for the Docker service: "hang" blocks until its context ends,
+// "fail" exits with code 1, and anything else prints its command and succeeds
+func fakeStepRunner(ctx context.Context, command []string, lines chan<- string) (*docker.ContainerResult, error) {
+	defer close(lines)
+	result := &docker.ContainerResult{Duration: 1, Usage: &docker.ResourceUsage{PeakMemoryBytes: int64(len(command[0])), CPUSeconds: 0.5}}
+
+	switch command[0] {
+	case "hang":
+		<-ctx.Done()
+		result.Error = docker.ErrTimeoutExceeded
+		return result, result.Error
+	case "fail":
+		result.ExitCode = 1
+	}
+	result.Output = strings.Join(command, " ") + "\n"
+	lines <- strings.Join(command, " ")
+	return result, nil
+}
+
+func TestRunCommandSteps_HungStepFailsJobAndIsRecorded(t *testing.T) {
+	lines := make(chan string, 64)
+	commands := [][]string{{"fetch", "src"}, {"hang"}, {"publish"}}
+
+	result, steps, err := runCommandSteps(context.Background(), commands, 300*time.Millisecond, 0, fakeStepRunner, lines)
+
+	var timeoutErr *StepTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Index != 2 || timeoutErr.Name != "hang" {
+		t.Fatalf("expected step 2 (hang) to time out, got %v", err)
+	}
+	if status, message := evaluateResult(result, err); status != models.JobStatusFailed || !strings.Contains(message, "step 2 (hang) timed out") {
+		t.Errorf("expected the job to fail naming the hung step, got %s %q", status, message)
+	}
+	if result.Output != "fetch src\n" || result.Duration != 2 || result.Usage.CPUSeconds != 1 || result.Usage.PeakMemoryBytes != 5 {
+		t.Errorf("unexpected combined result %+v (usage %+v)", result, result.Usage)
+	}
+
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	if len(got) != 1 || got[0] != "fetch src" {
+		t.Errorf("expected step output to be relayed and the channel closed, got %q", got)
+	}
+
+	records := stepRecords(steps)
+	if len(records) != 2 || records[0].Name != "fetch src" || records[0].Error != nil {
+		t.Fatalf("expected records for the two started steps, got %+v", records)
+	}
+	if records[1].Error == nil || records[1].DurationMs < 100 {
+		t.Errorf("expected the hung step recorded with its timeout, got %+v", records[1])
+	}
+}
+
+func TestRunCommandSteps_FailedContainerStopsLaterSteps(t *testing.T) {
+	lines := make(chan string, 64)
+	commands := [][]string{{"fail"}, {"publish"}}
+
+	result, steps, err := runCommandSteps(context.Background(), commands, time.Minute, 0, fakeStepRunner, lines)
+	if err == nil || !strings.Contains(err.Error(), "step 1 (fail) failed: Container exited with code 1") {
+		t.Fatalf("expected step 1's exit code to fail the job, got %v", err)
+	}
+	if len(steps) != 1 || result.ExitCode != 1 {
+		t.Errorf("expected only step 1 to run, got %+v (exit code %d)", steps, result.ExitCode)
+	}
+}
+
+func TestStepName_TruncatesLongCommands(t *testing.T) {
+	if got := stepName([]string{"go", "test", "./..."}); got != "go test ./..." {
+		t.Errorf("stepName = %q", got)
+	}
+	long := stepName([]string{strings.Repeat("é", 40)})
+	if len(long) > maxStepNameLength || !strings.HasSuffix(long, "...") || !utf8.ValidString(long) {
+		t.Errorf("expected a valid name of at most %d bytes, got %q", maxStepNameLength, long)
+	}
+}